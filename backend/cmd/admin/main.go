@@ -0,0 +1,436 @@
+// Пакет Main - консольная утилита администратора сервера GophKeeper.
+// Работает через API администратора на административном сервере (admin.address) с токеном admin.token
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// usage - описание команд
+const usage = `Usage: admin [-a address] [-t token] <command> [arguments]
+
+Commands:
+  users                 list accounts with entry counts and stored bytes
+  stats                 show server-wide statistics
+  disable <login>       disable account and end its sessions
+  enable <login>        enable account
+  logout <login>        end all sessions of the user
+  delete <login>        delete account with all its data (asks for confirmation, -y to skip)
+  quota <login> [-entries N] [-bytes N] [-reset]
+                        set individual storage quota (0 for unlimited) or return to the default one
+  audit [-login login] [-from RFC3339] [-to RFC3339]
+                        show administrator actions
+  invites               list invitation codes with their usage
+  invite [-uses N] [-ttl 168h] [-pattern regexp]
+                        issue invitation code for N registrations of logins matching the pattern
+  revoke-invite <code>  revoke invitation code
+
+Flags:
+  -a    admin server URL (env ADMIN_URL, default http://localhost:9090)
+  -t    admin token (env ADMIN_TOKEN or ADMIN_TOKEN_FILE)
+  -y    do not ask for confirmation
+`
+
+// requestTimeout - ограничение времени одного запроса к серверу
+const requestTimeout = 30 * time.Second
+
+// adminClient - клиент API администратора
+type adminClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// main - вызывается автоматически при запуске утилиты
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run - разобрать аргументы и выполнить команду
+func run(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	address := fs.String("a", envOrDefault("ADMIN_URL", "http://localhost:9090"), "admin server URL")
+	token := fs.String("t", "", "admin token")
+	yes := fs.Bool("y", false, "do not ask for confirmation")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *token == "" {
+		var err error
+		if *token, err = tokenFromEnv(); err != nil {
+			return err
+		}
+	}
+	if *token == "" {
+		return errors.New("admin token is required (-t, ADMIN_TOKEN or ADMIN_TOKEN_FILE)")
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("command is required")
+	}
+
+	client := &adminClient{
+		baseURL:    strings.TrimRight(*address, "/"),
+		token:      *token,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+
+	ctx := context.Background()
+	command, commandArgs := fs.Arg(0), fs.Args()[1:]
+
+	switch command {
+	case "users":
+		return client.users(ctx, out)
+	case "stats":
+		return client.stats(ctx, out)
+	case "disable", "enable", "logout":
+		login, err := loginArg(command, commandArgs)
+		if err != nil {
+			return err
+		}
+		return client.changeAccount(ctx, out, command, login)
+	case "delete":
+		login, err := loginArg(command, commandArgs)
+		if err != nil {
+			return err
+		}
+		if !*yes && !confirm(in, out, fmt.Sprintf("Delete account %s with all its data? Type the login to confirm: ", login), login) {
+			return errors.New("deletion cancelled")
+		}
+		return client.deleteAccount(ctx, out, login)
+	case "quota":
+		return client.quota(ctx, out, commandArgs)
+	case "audit":
+		return client.audit(ctx, out, commandArgs)
+	case "invites":
+		return client.invites(ctx, out)
+	case "invite":
+		return client.invite(ctx, out, commandArgs)
+	case "revoke-invite":
+		if len(commandArgs) != 1 || commandArgs[0] == "" {
+			return errors.New("usage: admin revoke-invite <code>")
+		}
+		return client.revokeInvite(ctx, out, commandArgs[0])
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// users - вывести учётные записи
+func (c *adminClient) users(ctx context.Context, out io.Writer) error {
+	var accounts []entities.Account
+	if err := c.do(ctx, http.MethodGet, "/api/admin/users", nil, http.StatusOK, &accounts); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOGIN\tSTATUS\tBINARIES\tCARDS\tCREDENTIALS\tTEXTS\tBYTES\tQUOTA")
+	for _, account := range accounts {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n", account.Login, accountStatus(account),
+			account.Usage.Binaries, account.Usage.Cards, account.Usage.Credentials, account.Usage.Texts, account.Usage.Bytes,
+			quotaDescription(account.Quota))
+	}
+
+	return tw.Flush()
+}
+
+// stats - вывести статистику сервера
+func (c *adminClient) stats(ctx context.Context, out io.Writer) error {
+	var stats entities.ServerStats
+	if err := c.do(ctx, http.MethodGet, "/api/admin/stats", nil, http.StatusOK, &stats); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Users:\t%d (%d disabled)\n", stats.Users, stats.DisabledUsers)
+	fmt.Fprintf(tw, "Entries:\t%d binaries, %d cards, %d credentials, %d texts\n",
+		stats.Usage.Binaries, stats.Usage.Cards, stats.Usage.Credentials, stats.Usage.Texts)
+	fmt.Fprintf(tw, "Stored bytes:\t%d\n", stats.Usage.Bytes)
+	fmt.Fprintf(tw, "Organizations:\t%d\n", stats.Organizations)
+	fmt.Fprintf(tw, "Shares:\t%d\n", stats.Shares)
+	fmt.Fprintf(tw, "Emergency access:\t%d\n", stats.EmergencyAccess)
+	fmt.Fprintf(tw, "Task queue:\t%d of %d\n", stats.QueueLength, stats.QueueCapacity)
+
+	return tw.Flush()
+}
+
+// quota - задать индивидуальную квоту пользователя или вернуть квоту по умолчанию
+func (c *adminClient) quota(ctx context.Context, out io.Writer, args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New("usage: admin quota <login> [-entries N] [-bytes N] [-reset]")
+	}
+	login := args[0]
+
+	fs := flag.NewFlagSet("quota", flag.ContinueOnError)
+	var quota entities.Quota
+	fs.IntVar(&quota.MaxEntries, "entries", 0, "max number of entries of each type (0 for unlimited)")
+	fs.Int64Var(&quota.MaxBytes, "bytes", 0, "max total size of entries in bytes (0 for unlimited)")
+	reset := fs.Bool("reset", false, "return to the default quota")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	path := "/api/admin/users/" + url.PathEscape(login) + "/quota"
+
+	var account entities.Account
+	if *reset {
+		if err := c.do(ctx, http.MethodDelete, path, nil, http.StatusOK, &account); err != nil {
+			return err
+		}
+	} else {
+		body, err := json.Marshal(quota)
+		if err != nil {
+			return err
+		}
+		if err := c.do(ctx, http.MethodPut, path, body, http.StatusOK, &account); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "%s: quota %s\n", account.Login, quotaDescription(account.Quota))
+	return nil
+}
+
+// changeAccount - заблокировать, разблокировать учётную запись или завершить её сессии
+func (c *adminClient) changeAccount(ctx context.Context, out io.Writer, action, login string) error {
+	var account entities.Account
+	if err := c.do(ctx, http.MethodPost, "/api/admin/users/"+url.PathEscape(login)+"/"+action, nil, http.StatusOK, &account); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s: %s\n", account.Login, accountStatus(account))
+	return nil
+}
+
+// deleteAccount - удалить учётную запись со всеми данными
+func (c *adminClient) deleteAccount(ctx context.Context, out io.Writer, login string) error {
+	if err := c.do(ctx, http.MethodDelete, "/api/admin/users/"+url.PathEscape(login), nil, http.StatusGone, nil); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s: deleted\n", login)
+	return nil
+}
+
+// audit - вывести журнал действий администраторов
+func (c *adminClient) audit(ctx context.Context, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	login := fs.String("login", "", "account login")
+	from := fs.String("from", "", "start of period (RFC3339)")
+	to := fs.String("to", "", "end of period (RFC3339)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	for name, value := range map[string]string{"login": *login, "from": *from, "to": *to} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	path := "/api/admin/audit"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var events []entities.AuditEvent
+	if err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, &events); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tACTION\tACCOUNT\tIP")
+	for _, event := range events {
+		fmt.Fprintf(tw, "%s\t%s %s\t%s\t%s\n", event.CreatedAt.Local().Format(time.DateTime),
+			event.Action, event.EntityType, event.UserID, event.IP)
+	}
+
+	return tw.Flush()
+}
+
+// invites - вывести коды приглашений
+func (c *adminClient) invites(ctx context.Context, out io.Writer) error {
+	var invites []entities.Invite
+	if err := c.do(ctx, http.MethodGet, "/api/admin/invites", nil, http.StatusOK, &invites); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE	USES	EXPIRES	LOGIN PATTERN")
+	for _, invite := range invites {
+		fmt.Fprintf(tw, "%s	%d of %d	%s	%s\n", invite.Code, invite.Uses, invite.MaxUses,
+			invite.ExpiresAt.Local().Format(time.DateTime), invite.LoginPattern)
+	}
+
+	return tw.Flush()
+}
+
+// invite - выпустить код приглашения
+func (c *adminClient) invite(ctx context.Context, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("invite", flag.ContinueOnError)
+	uses := fs.Int("uses", 1, "number of registrations")
+	ttl := fs.Duration("ttl", 7*24*time.Hour, "lifetime of the code")
+	pattern := fs.String("pattern", "", "regular expression the whole login must match")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *ttl <= 0 {
+		return errors.New("-ttl must be positive")
+	}
+
+	body, err := json.Marshal(dtos.NewInvite{
+		MaxUses:      *uses,
+		LoginPattern: *pattern,
+		ExpiresAt:    time.Now().Add(*ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	var invite entities.Invite
+	if err := c.do(ctx, http.MethodPost, "/api/admin/invites", body, http.StatusCreated, &invite); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s (%d uses, expires %s)\n", invite.Code, invite.MaxUses, invite.ExpiresAt.Local().Format(time.DateTime))
+	return nil
+}
+
+// revokeInvite - отозвать код приглашения
+func (c *adminClient) revokeInvite(ctx context.Context, out io.Writer, code string) error {
+	if err := c.do(ctx, http.MethodDelete, "/api/admin/invites/"+url.PathEscape(code), nil, http.StatusGone, nil); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s: revoked\n", code)
+	return nil
+}
+
+// do - выполнить запрос к API администратора с телом body в JSON (nil - без тела) и разобрать ответ в result (nil - тело не нужно)
+func (c *adminClient) do(ctx context.Context, method, path string, body []byte, wantStatus int, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s failed with status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// accountStatus - состояние учётной записи для вывода
+func accountStatus(account entities.Account) string {
+	if account.Disabled {
+		return "disabled"
+	}
+	return "active"
+}
+
+// quotaDescription - индивидуальная квота для вывода
+func quotaDescription(quota *entities.Quota) string {
+	if quota == nil {
+		return "default"
+	}
+	return fmt.Sprintf("%s entries, %s bytes", limitDescription(int64(quota.MaxEntries)), limitDescription(quota.MaxBytes))
+}
+
+// limitDescription - ограничение квоты для вывода (0 - без ограничения)
+func limitDescription(limit int64) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(limit, 10)
+}
+
+// loginArg - логин из аргументов команды
+func loginArg(command string, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("usage: admin %s <login>", command)
+	}
+	return args[0], nil
+}
+
+// confirm - запросить подтверждение: пользователь должен ввести expected
+func confirm(in io.Reader, out io.Writer, prompt, expected string) bool {
+	fmt.Fprint(out, prompt)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	return strings.TrimSpace(answer) == expected
+}
+
+// tokenFromEnv - токен из ADMIN_TOKEN или файла ADMIN_TOKEN_FILE
+func tokenFromEnv() (string, error) {
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	filePath := os.Getenv("ADMIN_TOKEN_FILE")
+	if filePath == "" {
+		return "", nil
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ADMIN_TOKEN_FILE: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// envOrDefault - значение переменной окружения или значение по умолчанию
+func envOrDefault(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
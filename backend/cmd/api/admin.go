@@ -0,0 +1,98 @@
+// Пакет Main
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/handlers"
+	"github.com/JustScorpio/GophKeeper/backend/internal/health"
+	"github.com/JustScorpio/GophKeeper/backend/internal/metrics"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/auth"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/clientinfo"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/requestid"
+	"github.com/JustScorpio/GophKeeper/backend/internal/services"
+	"github.com/go-chi/chi"
+)
+
+const (
+	// readinessTimeout - ограничение времени на все проверки одного запроса /readyz
+	readinessTimeout = 3 * time.Second
+	// queueReadyThreshold - доля заполнения очереди задач, начиная с которой сервер считается неготовым
+	queueReadyThreshold = 0.9
+)
+
+// newReadinessChecker - создать проверки готовности: база данных (проверка с именем её типа), очередь задач и состояние сервиса
+func newReadinessChecker(storageService *services.StorageService, database string, pingDatabase func(ctx context.Context) error) *health.Checker {
+	checker := health.NewChecker(readinessTimeout)
+
+	checker.Add(database, pingDatabase)
+
+	checker.Add("storage_service", func(ctx context.Context) error {
+		if storageService.IsShuttingDown() {
+			return errors.New("service is shutting down")
+		}
+
+		length, capacity := storageService.QueueLength(), storageService.QueueCapacity()
+		if float64(length) >= float64(capacity)*queueReadyThreshold {
+			return fmt.Errorf("task queue is almost full: %d of %d", length, capacity)
+		}
+
+		return nil
+	})
+
+	return checker
+}
+
+// createAdminServer - создать административный сервер (проверки, метрики, профилирование и, если задан токен, API администратора).
+// Слушает отдельный адрес, чтобы служебные эндпоинты не были доступны вместе с API
+func createAdminServer(addr string, checker *health.Checker, adminHandler *handlers.AdminHandler, token string) *http.Server {
+	mux := http.NewServeMux()
+
+	if token != "" {
+		mux.Handle("/api/admin/", newAdminAPIRouter(adminHandler, token))
+	}
+
+	mux.HandleFunc("/healthz", health.LivenessHandler)
+	mux.HandleFunc("/readyz", checker.ReadinessHandler)
+	mux.Handle("/metrics", metrics.Handler())
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+}
+
+// newAdminAPIRouter - маршруты API администратора. Все запросы требуют токена администратора
+func newAdminAPIRouter(adminHandler *handlers.AdminHandler, token string) http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(requestid.RequestIDMiddleware())
+	r.Use(clientinfo.ClientInfoMiddleware())
+	r.Use(auth.AdminMiddleware(token))
+
+	r.Get("/api/admin/users", adminHandler.GetAccounts)
+	r.Post("/api/admin/users/{login}/disable", adminHandler.DisableAccount)
+	r.Post("/api/admin/users/{login}/enable", adminHandler.EnableAccount)
+	r.Post("/api/admin/users/{login}/logout", adminHandler.RevokeSessions)
+	r.Put("/api/admin/users/{login}/quota", adminHandler.SetQuota)
+	r.Delete("/api/admin/users/{login}/quota", adminHandler.ResetQuota)
+	r.Delete("/api/admin/users/{login}", adminHandler.DeleteAccount)
+	r.Post("/api/admin/invites", adminHandler.CreateInvite)
+	r.Get("/api/admin/invites", adminHandler.GetInvites)
+	r.Delete("/api/admin/invites/{code}", adminHandler.DeleteInvite)
+	r.Get("/api/admin/stats", adminHandler.GetStats)
+	r.Get("/api/admin/audit", adminHandler.GetAuditLog)
+
+	return r
+}
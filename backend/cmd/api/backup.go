@@ -0,0 +1,275 @@
+// Пакет Main
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"filippo.io/age"
+	"github.com/JustScorpio/GophKeeper/backend/internal/backup"
+	"github.com/JustScorpio/GophKeeper/backend/internal/config"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/postgres"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/postgres/migrations"
+	"github.com/jackc/pgx/v5"
+)
+
+// backupUsage - описание подкоманд backup и restore
+const backupUsage = `Usage: api [flags] backup [-recipient age1...] FILE
+       api [flags] restore [-identity FILE] [-user LOGIN] [-verify] FILE
+
+Commands (PostgreSQL storage selected by -d):
+  backup   write a consistent copy of all tables to a new archive FILE while the server keeps running
+  restore  load an archive into an empty database (migrations are applied up to the archive's schema version)
+
+Flags:
+  -recipient  age X25519 recipient to encrypt the archive for (may be repeated)
+  -identity   age identity file to decrypt the archive with (may be repeated)
+  -user       restore only this user: account, keys, quota and personal entries with history
+  -verify     only check the archive (checksum, row counts) and list its tables
+`
+
+// stringList - флаг, который можно указать несколько раз
+type stringList []string
+
+// String - значения через запятую
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set - добавить значение
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runBackup - записать резервную копию базы данных PostgreSQL в новый файл архива
+func runBackup(args []string, db config.DatabaseConfig, out io.Writer) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var recipientKeys stringList
+	fs.Var(&recipientKeys, "recipient", "age X25519 recipient to encrypt the archive for")
+
+	path, err := parseFileArgs(fs, args)
+	if err != nil {
+		fmt.Fprint(out, backupUsage)
+		return err
+	}
+
+	recipients := make([]age.Recipient, 0, len(recipientKeys))
+	for _, key := range recipientKeys {
+		recipient, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", key, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if err := requirePostgres(db); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup file %s already exists", path)
+	}
+
+	ctx := context.Background()
+	// Записи всех пользователей видит только владелец таблиц
+	conn, err := pgx.Connect(ctx, db.SchemaOwnerDSN())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	// Архив пишется во временный файл рядом и переименовывается только целиком
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	w, err := backup.NewWriter(file, recipients...)
+	if err != nil {
+		return err
+	}
+	if err := postgres.Backup(ctx, conn, w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	printTables(out, w.Tables())
+	fmt.Fprintf(out, "Backup written to %s\n", path)
+	return nil
+}
+
+// runRestore - проверить архив и восстановить из него базу данных PostgreSQL целиком или одного пользователя.
+// Архив читается дважды: сначала проверяется целиком, затем загружается в одной транзакции
+func runRestore(args []string, db config.DatabaseConfig, out io.Writer) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var identityFiles stringList
+	fs.Var(&identityFiles, "identity", "age identity file to decrypt the archive with")
+	login := fs.String("user", "", "restore only this user")
+	verifyOnly := fs.Bool("verify", false, "only check the archive")
+
+	path, err := parseFileArgs(fs, args)
+	if err != nil {
+		fmt.Fprint(out, backupUsage)
+		return err
+	}
+
+	var identities []age.Identity
+	for _, identityFile := range identityFiles {
+		parsed, err := readIdentities(identityFile)
+		if err != nil {
+			return err
+		}
+		identities = append(identities, parsed...)
+	}
+
+	header, tables, err := verifyArchive(path, identities)
+	if err != nil {
+		return err
+	}
+
+	if *verifyOnly {
+		printTables(out, tables)
+		fmt.Fprintf(out, "Backup is valid: %s storage, schema version %d, created at %s\n",
+			header.Storage, header.SchemaVersion(), header.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+		return nil
+	}
+
+	if err := requirePostgres(db); err != nil {
+		return err
+	}
+	if header.Storage != config.StoragePostgres {
+		return fmt.Errorf("backup of %s storage cannot be restored to PostgreSQL", header.Storage)
+	}
+
+	ctx := context.Background()
+	conn, err := postgres.InitDatabase(db.SchemaOwnerDSN())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	// В новую базу применяются миграции той версии схемы, с которой снята копия. Более новые сервер применит при запуске
+	if _, err := migrations.NewMigrator(conn).UpTo(ctx, header.SchemaVersion(), false); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	r, err := backup.NewReader(file, identities...)
+	if err != nil {
+		return err
+	}
+
+	result, err := postgres.Restore(ctx, conn, r, *login)
+	if err != nil {
+		return err
+	}
+
+	printTables(out, result.Tables)
+	if *login != "" {
+		fmt.Fprintf(out, "User %s restored from %s\n", *login, path)
+	} else {
+		fmt.Fprintf(out, "Database restored from %s\n", path)
+	}
+	return nil
+}
+
+// verifyArchive - проверить архив целиком: контрольную сумму, число строк таблиц и завершённость
+func verifyArchive(path string, identities []age.Identity) (*backup.Header, []backup.TableSummary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	return backup.Verify(file, identities...)
+}
+
+// readIdentities - ключи age из файла (например, созданного age-keygen)
+func readIdentities(path string) ([]age.Identity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open identity file: %w", err)
+	}
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity file %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// requirePostgres - резервные копии поддерживаются только для PostgreSQL
+func requirePostgres(db config.DatabaseConfig) error {
+	if db.DSN == "" {
+		return errors.New("database.dsn: required (set DATABASE_URI, DATABASE_URI_FILE, -d or database.dsn in the config file)")
+	}
+
+	switch backend := db.Backend(); backend {
+	case config.StoragePostgres:
+		return nil
+	case config.StorageSQLite, config.StorageMemory:
+		return fmt.Errorf("backup and restore support PostgreSQL storage only: copy the %s data while the server is stopped", backend)
+	default:
+		return fmt.Errorf("unknown database type %q", backend)
+	}
+}
+
+// parseFileArgs - разобрать флаги до и после единственного аргумента - пути к файлу архива
+func parseFileArgs(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() == 0 {
+		return "", errors.New("backup file is required")
+	}
+
+	path, rest := fs.Arg(0), fs.Args()[1:]
+
+	// Флаги после файла
+	if err := fs.Parse(rest); err != nil {
+		return "", err
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	return path, nil
+}
+
+// printTables - вывести таблицы и число строк в них
+func printTables(out io.Writer, tables []backup.TableSummary) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tROWS")
+	for _, table := range tables {
+		fmt.Fprintf(w, "%s\t%d\n", table.Name, table.Rows)
+	}
+	w.Flush()
+}
@@ -0,0 +1,106 @@
+// Пакет Main
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/config"
+	"github.com/JustScorpio/GophKeeper/backend/internal/envelope"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/inmemory"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/postgres"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/sqlite"
+)
+
+// keysUsage - описание подкоманды keys
+const keysUsage = `Usage: api [flags] keys <command>
+
+Commands (server-side encryption, see -ek and -ep):
+  generate FILE  write a new random master key to FILE (the file must not exist)
+  rotate         re-wrap data keys of all users with the current master key;
+                 keys wrapped with a previous master key are read with -ep files
+`
+
+// runKeys - создать мастер-ключ или перешифровать ключи данных пользователей текущим мастер-ключом
+func runKeys(args []string, cfg *config.Config, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(out, keysUsage)
+		return errors.New("keys command is required")
+	}
+
+	switch command := args[0]; {
+	case command == "generate" && len(args) == 2:
+		if err := envelope.GenerateKeyFile(args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Master key written to %s\n", args[1])
+		return nil
+	case command == "rotate" && len(args) == 1:
+		return rotateKeys(cfg, out)
+	default:
+		fmt.Fprint(out, keysUsage)
+		return fmt.Errorf("invalid keys command %q", strings.Join(args, " "))
+	}
+}
+
+// rotateKeys - перешифровать ключи данных в базе данных, выбранной настройками.
+// Экземпляры сервера к этому моменту должны знать новый мастер-ключ (и прежний - среди прежних ключей),
+// иначе они не расшифруют перешифрованные ключи. Хранилище в памяти перешифровывается при остановленном сервере
+func rotateKeys(cfg *config.Config, out io.Writer) error {
+	if cfg.Encryption.MasterKeyFile == "" {
+		return errors.New("encryption.master_key_file: required (set ENCRYPTION_MASTER_KEY_FILE, -ek or encryption.master_key_file in the config file)")
+	}
+	if cfg.Database.DSN == "" {
+		return errors.New("database.dsn: required (set DATABASE_URI, DATABASE_URI_FILE, -d or database.dsn in the config file)")
+	}
+
+	keys, err := envelope.LoadLocalKeyManager(cfg.Encryption.MasterKeyFile, cfg.Encryption.PreviousKeyFiles)
+	if err != nil {
+		return err
+	}
+
+	rotate := func(repo repositories.IDataKeyRepository) error {
+		rotated, err := envelope.Rotate(context.Background(), keys, repo)
+		fmt.Fprintf(out, "Data keys rotated: %d\n", rotated)
+		return err
+	}
+
+	switch backend := cfg.Database.Backend(); backend {
+	case config.StoragePostgres:
+		dbManager, err := postgres.NewDatabaseManager(cfg.Database.DSN, cfg.Database.OwnerDSN)
+		if err != nil {
+			return err
+		}
+		defer dbManager.DB.Close(context.Background())
+
+		return rotate(dbManager.DataKeysRepo)
+	case config.StorageSQLite:
+		dbManager, err := sqlite.NewDatabaseManager(cfg.Database.DSN)
+		if err != nil {
+			return err
+		}
+		defer dbManager.DB.Close()
+
+		return rotate(dbManager.DataKeysRepo)
+	case config.StorageMemory:
+		dir := strings.TrimPrefix(strings.TrimPrefix(cfg.Database.DSN, "memory://"), "memory:")
+		dbManager, err := inmemory.OpenDatabaseManager(dir)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := dbManager.Close(); err != nil {
+				log.Printf("failed to write final snapshot: %v", err)
+			}
+		}()
+
+		return rotate(dbManager.DataKeys)
+	default:
+		return fmt.Errorf("unknown database type %q", backend)
+	}
+}
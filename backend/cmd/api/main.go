@@ -4,22 +4,29 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/JustScorpio/GophKeeper/backend/internal/config"
 	"github.com/JustScorpio/GophKeeper/backend/internal/handlers"
+	"github.com/JustScorpio/GophKeeper/backend/internal/metrics"
 	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/auth"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/clientinfo"
 	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/gzipencoder"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/httpmetrics"
 	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/logger"
-	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/postgres"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/requestid"
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
 	"github.com/JustScorpio/GophKeeper/backend/internal/services"
-
-	_ "net/http/pprof"
+	"github.com/JustScorpio/GophKeeper/backend/internal/tracing"
 
 	"github.com/go-chi/chi"
 )
@@ -32,63 +39,111 @@ var (
 
 // main - вызывается автоматически при запуске приложения
 func main() {
+	// обрабатываем файл конфигурации, переменные окружения и аргументы командной строки
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		config.Usage(os.Stderr)
+		fmt.Fprint(os.Stderr, "\n"+migrateUsage)
+		fmt.Fprint(os.Stderr, "\n"+keysUsage)
+		fmt.Fprint(os.Stderr, "\n"+backupUsage)
+		return
+	}
+
+	if cfg != nil && cfg.PrintConfig {
+		if printErr := cfg.Print(os.Stdout); printErr != nil {
+			log.Fatal(printErr)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Подкоманды (migrate, keys, backup, restore) не запускают сервер, поэтому им достаточно части настроек
+	if cfg != nil && len(cfg.Command) > 0 {
+		if err := runCommand(cfg, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// вывести аргументы
 	fmt.Printf("Build version: %s\nBuild date: %s\n", buildVersion, buildDate)
 
-	// обрабатываем аргументы командной строки
-	parseFlags()
-
-	if err := run(); err != nil {
+	if err := run(cfg); err != nil {
 		log.Fatal(err)
 	}
 }
 
 // run - функция полезна при инициализации зависимостей сервера перед запуском
-func run() error {
-	// Строка подключения к базе данных postgres
-	if envDBAddr, hasEnv := os.LookupEnv("DATABASE_URI"); hasEnv {
-		databaseConnStr = envDBAddr
-	}
+func run(cfg *config.Config) error {
+	// Ключ и время жизни токенов
+	auth.Init(cfg.Auth.SecretKey)
+	auth.SetTokenLifeTime(cfg.Auth.TokenLifetime)
 
-	// Получение ключа для генерации токенов
-	if envSecretKey, hasEnv := os.LookupEnv("AUTH_SECRET_KEY"); hasEnv {
-		secretKey = envSecretKey
-		auth.Init(secretKey)
+	// Трассировка (инициализируется до подключения к БД, чтобы запросы миграций тоже попадали в трассы)
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing.OTLPEndpoint, "gophkeeper-backend", buildVersion)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			fmt.Printf("Tracing shutdown error: %v\n", err)
+		}
+	}()
+
+	// Контекст живёт до завершения run (используется фоновыми задачами, например слежением за сертификатом)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Рассылка событий об изменениях подключённым клиентам
+	hub := notifications.NewHub()
 
-	//Инициализация репозиториев
-	dbManager, err := postgres.NewDatabaseManager(databaseConnStr)
+	//Инициализация репозиториев и сервисов
+	store, err := openStorage(ctx, cfg, hub)
 	if err != nil {
 		return err
 	}
-	defer dbManager.DB.Close(context.Background())
+	defer store.close()
+	storageService := store.service
 
-	// Инициализация сервисов
-	storageService := services.NewStorageService(dbManager.UsersRepo, dbManager.BinariesRepo, dbManager.CardsRepo, dbManager.CredentialsRepo, dbManager.TextsRepo)
+	if err := metrics.RegisterQueueLength(storageService.QueueLength); err != nil {
+		return err
+	}
 
-	//При наличии переменной окружения или флага - запускаем на HTTPS
-	_, hasEnv := os.LookupEnv("ENABLE_HTTPS")
-	enableHTTPS = hasEnv || enableHTTPS
+	// Заблокированные пользователи и отозванные администратором сессии отклоняются при каждом запросе
+	auth.SetSessionChecker(storageService.CheckSession)
 
 	//Сертификат для HTTPS
 	var tlsConfig *tls.Config
-	if enableHTTPS {
-		// Чтение пути до сертификата и его ключа
-		if envTlsCertPath, hasEnv := os.LookupEnv("TLS_CERT_PATH"); hasEnv {
-			tlsCertPath = envTlsCertPath
-		}
-		if envKeyCertPath, hasEnv := os.LookupEnv("TLS_KEY_PATH"); hasEnv {
-			tlsKeyPath = envKeyCertPath
+	if cfg.Server.EnableHTTPS {
+		tlsCertPath, tlsKeyPath := cfg.Server.TLS.CertPath, cfg.Server.TLS.KeyPath
+
+		// Генерация сертификатов для разработки
+		if cfg.Server.TLS.Generate {
+			tlsCertPath, tlsKeyPath, err = EnsureDevCertificates(cfg.Server.TLS.Dir, cfg.Server.Address)
+			if err != nil {
+				return err
+			}
+			fmt.Println("Using development certificate signed by", filepath.Join(cfg.Server.TLS.Dir, "ca.pem"))
 		}
 
-		tlsConfig, err = GetTLSConfigFromFiles(tlsCertPath, tlsKeyPath)
+		tlsConfig, err = GetTLSConfigFromFiles(ctx, tlsCertPath, tlsKeyPath)
 		if err != nil {
 			return err
 		}
 	}
 
 	// Инициализация обработчиков
-	handler := handlers.NewGophkeeperHandler(storageService)
+	handler := handlers.NewGophkeeperHandler(storageService,
+		handlers.WithDataLimits(cfg.Limits.MaxBinarySize, cfg.Limits.MaxTextSize),
+		handlers.WithNotifications(hub))
 
 	// Инициализация логгера
 	zapLogger, err := logger.NewLogger("Info", true)
@@ -97,11 +152,6 @@ func run() error {
 	}
 	defer zapLogger.Sync()
 
-	// Берём адрес сервера из переменной окружения. Иначе - из аргумента
-	if envServerAddr, hasEnv := os.LookupEnv("SERVER_ADDRESS"); hasEnv {
-		routerAddr = envServerAddr
-	}
-
 	// Канал для получения сигналов ОС
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
@@ -110,8 +160,12 @@ func run() error {
 	r := chi.NewRouter()
 
 	//Базовые middleware
+	r.Use(requestid.RequestIDMiddleware())
+	r.Use(tracing.HTTPMiddleware())
+	r.Use(httpmetrics.HTTPMetricsMiddleware())
 	r.Use(logger.LoggingMiddleware(zapLogger))
 	r.Use(gzipencoder.GZIPEncodingMiddleware())
+	r.Use(clientinfo.ClientInfoMiddleware())
 
 	//Публичные маршруты
 	r.Group(func(r chi.Router) {
@@ -145,10 +199,53 @@ func run() error {
 		r.Get("/api/user/texts", handler.GetAllTexts)
 		r.Put("/api/user/texts", handler.UpdateText)
 		r.Delete("/api/user/texts/{id}", handler.DeleteText)
+
+		r.Get("/api/user/trash", handler.GetTrash)
+		r.Post("/api/user/trash/{type}/{id}/restore", handler.RestoreTrashItem)
+		r.Delete("/api/user/trash/{type}/{id}", handler.DeleteTrashItem)
+
+		r.Get("/api/user/{type}/{id}/history", handler.GetHistory)
+		r.Post("/api/user/{type}/{id}/history/{rev}/restore", handler.RestoreRevision)
+
+		r.Get("/api/user/audit", handler.GetAuditLog)
+		r.Get("/api/user/events", handler.Events)
+		r.Get("/api/user/usage", handler.GetUsage)
+		r.Get("/api/user/export", handler.ExportAccount)
+		r.Delete("/api/user", handler.DeleteAccount)
+
+		r.Put("/api/user/keys", handler.SetUserKeys)
+		r.Get("/api/user/keys", handler.GetUserKeys)
+		r.Get("/api/user/keys/{login}", handler.GetUserKeys)
+
+		r.Post("/api/user/shares", handler.CreateShare)
+		r.Get("/api/user/shares", handler.GetShares)
+		r.Post("/api/user/shares/{id}/accept", handler.AcceptShare)
+		r.Delete("/api/user/shares/{id}", handler.DeleteShare)
+
+		r.Post("/api/user/orgs", handler.CreateOrganization)
+		r.Get("/api/user/orgs", handler.GetOrganizations)
+		r.Get("/api/user/orgs/{id}/members", handler.GetOrgMembers)
+		r.Post("/api/user/orgs/{id}/members", handler.InviteMember)
+		r.Post("/api/user/orgs/{id}/members/accept", handler.AcceptInvite)
+		r.Delete("/api/user/orgs/{id}/members/{login}", handler.RemoveMember)
+		r.Get("/api/user/orgs/{id}/collections", handler.GetCollections)
+		r.Post("/api/user/orgs/{id}/collections", handler.CreateCollection)
+		r.Post("/api/user/collections/{id}/rotate", handler.RotateCollectionKey)
+
+		r.Post("/api/user/emergency", handler.InviteEmergencyContact)
+		r.Get("/api/user/emergency", handler.GetEmergencyAccess)
+		r.Post("/api/user/emergency/{id}/accept", handler.AcceptEmergencyAccess)
+		r.Post("/api/user/emergency/{id}/request", handler.RequestEmergencyAccess)
+		r.Post("/api/user/emergency/{id}/approve", handler.ApproveEmergencyAccess)
+		r.Post("/api/user/emergency/{id}/reject", handler.RejectEmergencyAccess)
+		r.Delete("/api/user/emergency/{id}", handler.DeleteEmergencyAccess)
+		r.Get("/api/user/emergency/{id}/vault", handler.OpenEmergencyVault)
 	})
 
-	server := createHTTPServer(routerAddr, r, tlsConfig)
-	fmt.Println("Running server on", routerAddr)
+	server := createHTTPServer(cfg.Server.Address, r, tlsConfig)
+	// Открытые потоки событий не дают серверу завершиться - закрываем их в начале остановки
+	server.RegisterOnShutdown(hub.Close)
+	fmt.Println("Running server on", cfg.Server.Address)
 
 	// Запуск сервера в горутине
 	serverErr := make(chan error, 1)
@@ -156,6 +253,20 @@ func run() error {
 		serverErr <- runHTTPServer(server)
 	}()
 
+	// Административный сервер (проверки, метрики, pprof)
+	var adminServer *http.Server
+	if cfg.Admin.Address != "" {
+		adminServer = createAdminServer(cfg.Admin.Address, newReadinessChecker(storageService, cfg.Database.Backend(), store.ping),
+			handlers.NewAdminHandler(storageService), cfg.Admin.Token)
+		fmt.Println("Running admin server on", cfg.Admin.Address)
+
+		go func() {
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serverErr <- fmt.Errorf("admin server: %w", err)
+			}
+		}()
+	}
+
 	// Ожидание сигнала остановки или ошибки сервера
 	select {
 	case <-stop:
@@ -164,7 +275,7 @@ func run() error {
 		fmt.Printf("Server error: %v\n", err)
 	}
 
-	return gracefulShutdown(storageService, server)
+	return gracefulShutdown(storageService, server, adminServer)
 }
 
 // createServer - создает и настраивает HTTP сервер
@@ -190,7 +301,7 @@ func runHTTPServer(server *http.Server) error {
 }
 
 // gracefulShutdown - graceful shutdown приложения
-func gracefulShutdown(service *services.StorageService, server *http.Server) error {
+func gracefulShutdown(service *services.StorageService, server *http.Server, adminServer *http.Server) error {
 	fmt.Println("Starting graceful shutdown...")
 
 	// Останавливаем прием новых соединений
@@ -210,5 +321,15 @@ func gracefulShutdown(service *services.StorageService, server *http.Server) err
 	}
 
 	fmt.Println("HTTP server shutdown completed")
+
+	// Административный сервер останавливаем последним, чтобы /readyz до конца сообщал о завершении работы
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			fmt.Printf("Admin server shutdown error: %v\n", err)
+			return err
+		}
+		fmt.Println("Admin server shutdown completed")
+	}
+
 	return nil
 }
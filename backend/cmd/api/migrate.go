@@ -0,0 +1,219 @@
+// Пакет Main
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/config"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/postgres"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/postgres/migrations"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/sqlite"
+	sqlitemigrations "github.com/JustScorpio/GophKeeper/backend/internal/repositories/sqlite/migrations"
+)
+
+// migrateUsage - описание подкоманды migrate
+const migrateUsage = `Usage: api [flags] migrate <command> [-dry-run]
+
+Commands (for the database selected by -d and -storage):
+  up        apply all pending migrations
+  down N    roll back the last N applied migrations
+  status    list migrations and whether they are applied
+
+Flags:
+  -dry-run  only list migrations that would be applied or rolled back
+`
+
+// runCommand - выполнить подкоманду вместо запуска сервера
+func runCommand(cfg *config.Config, out io.Writer) error {
+	switch cfg.Command[0] {
+	case "migrate":
+		return runMigrate(cfg.Command[1:], cfg.Database, out)
+	case "keys":
+		return runKeys(cfg.Command[1:], cfg, out)
+	case "backup":
+		return runBackup(cfg.Command[1:], cfg.Database, out)
+	case "restore":
+		return runRestore(cfg.Command[1:], cfg.Database, out)
+	default:
+		return fmt.Errorf("unknown command %q (available: migrate, keys, backup, restore)", cfg.Command[0])
+	}
+}
+
+// runMigrate - применить, откатить или показать миграции базы данных db (PostgreSQL или SQLite).
+// Экземпляры сервера, запущенные одновременно, применяют миграции PostgreSQL по очереди (мигратор берёт блокировку в PostgreSQL)
+func runMigrate(args []string, db config.DatabaseConfig, out io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "only list migrations that would be applied or rolled back")
+
+	command, steps, err := parseMigrateArgs(fs, args)
+	if err != nil {
+		fmt.Fprint(out, migrateUsage)
+		return err
+	}
+
+	if db.DSN == "" {
+		return errors.New("database.dsn: required (set DATABASE_URI, DATABASE_URI_FILE, -d or database.dsn in the config file)")
+	}
+
+	ctx := context.Background()
+
+	switch backend := db.Backend(); backend {
+	case config.StoragePostgres:
+		// Схему меняет её владелец (database.owner_dsn), если сервер работает под отдельной ролью
+		conn, err := postgres.InitDatabase(db.SchemaOwnerDSN())
+		if err != nil {
+			return err
+		}
+		defer conn.Close(context.Background())
+
+		return runMigrator(ctx, migrations.NewMigrator(conn), command, steps, *dryRun, out,
+			func(m migrations.Migration) string { return m.Name },
+			func(s migrations.Status) migrationStatus {
+				return migrationStatus{s.Version, s.Name, s.Applied, s.AppliedAt, s.Reversible()}
+			})
+	case config.StorageSQLite:
+		conn, err := sqlite.Open(db.DSN)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		return runMigrator(ctx, sqlitemigrations.NewMigrator(conn), command, steps, *dryRun, out,
+			func(m sqlitemigrations.Migration) string { return m.Name },
+			func(s sqlitemigrations.Status) migrationStatus {
+				return migrationStatus{s.Version, s.Name, s.Applied, s.AppliedAt, s.Reversible()}
+			})
+	case config.StorageMemory:
+		return errors.New("memory storage has no schema migrations: its snapshot is read on startup")
+	default:
+		return fmt.Errorf("unknown database type %q", backend)
+	}
+}
+
+// migrator - мигратор базы данных одного из типов: M - миграция, S - миграция со сведениями о применении
+type migrator[M, S any] interface {
+	Up(ctx context.Context, dryRun bool) ([]M, error)
+	Down(ctx context.Context, steps int, dryRun bool) ([]M, error)
+	Status(ctx context.Context) ([]S, error)
+}
+
+// migrationStatus - миграция и сведения о её применении для вывода (общие для всех типов базы данных)
+type migrationStatus struct {
+	Version    int
+	Name       string
+	Applied    bool
+	AppliedAt  time.Time
+	Reversible bool
+}
+
+// runMigrator - выполнить команду migrate мигратором m и вывести результат.
+// name - имя миграции, status - сведения о миграции для вывода
+func runMigrator[M, S any](ctx context.Context, m migrator[M, S], command string, steps int, dryRun bool, out io.Writer,
+	name func(M) string, status func(S) migrationStatus) error {
+	names := func(list []M) []string {
+		result := make([]string, 0, len(list))
+		for _, migration := range list {
+			result = append(result, name(migration))
+		}
+		return result
+	}
+
+	switch command {
+	case "up":
+		applied, err := m.Up(ctx, dryRun)
+		printMigrations(out, names(applied), dryRun, "apply", "applied")
+		return err
+	case "down":
+		rolledBack, err := m.Down(ctx, steps, dryRun)
+		printMigrations(out, names(rolledBack), dryRun, "roll back", "rolled back")
+		return err
+	default:
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		rows := make([]migrationStatus, 0, len(statuses))
+		for _, s := range statuses {
+			rows = append(rows, status(s))
+		}
+		printStatus(out, rows)
+		return nil
+	}
+}
+
+// parseMigrateArgs - разобрать подкоманду migrate: команду, число миграций для down и флаги до или после команды
+func parseMigrateArgs(fs *flag.FlagSet, args []string) (string, int, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", 0, err
+	}
+	if fs.NArg() == 0 {
+		return "", 0, errors.New("migrate command is required")
+	}
+
+	command, rest := fs.Arg(0), fs.Args()[1:]
+
+	var steps int
+	switch command {
+	case "up", "status":
+	case "down":
+		if len(rest) == 0 {
+			return "", 0, errors.New("number of migrations to roll back is required")
+		}
+		n, err := strconv.Atoi(rest[0])
+		if err != nil || n <= 0 {
+			return "", 0, fmt.Errorf("invalid number of migrations %q", rest[0])
+		}
+		steps, rest = n, rest[1:]
+	default:
+		return "", 0, fmt.Errorf("unknown migrate command %q", command)
+	}
+
+	// Флаги после команды
+	if err := fs.Parse(rest); err != nil {
+		return "", 0, err
+	}
+	if fs.NArg() > 0 {
+		return "", 0, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	return command, steps, nil
+}
+
+// printMigrations - вывести применённые или откаченные миграции (при dryRun - те, что были бы затронуты)
+func printMigrations(out io.Writer, names []string, dryRun bool, action, done string) {
+	if len(names) == 0 {
+		fmt.Fprintf(out, "Nothing to %s\n", action)
+		return
+	}
+
+	for _, name := range names {
+		if dryRun {
+			fmt.Fprintf(out, "Would %s: %s\n", action, name)
+		} else {
+			fmt.Fprintf(out, "Migration %s: %s\n", done, name)
+		}
+	}
+}
+
+// printStatus - вывести миграции с отметкой о применении
+func printStatus(out io.Writer, statuses []migrationStatus) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT\tREVERSIBLE")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\n", status.Version, status.Name, state, appliedAt, status.Reversible)
+	}
+	w.Flush()
+}
@@ -0,0 +1,193 @@
+// Пакет Main
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/config"
+	"github.com/JustScorpio/GophKeeper/backend/internal/envelope"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/inmemory"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/postgres"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/sqlite"
+	"github.com/JustScorpio/GophKeeper/backend/internal/services"
+)
+
+// storage - сервис хранения поверх базы данных выбранного типа
+type storage struct {
+	service *services.StorageService
+	// ping - проверка доступности базы данных для /readyz
+	ping func(ctx context.Context) error
+	// close - закрыть подключение к базе данных (после остановки сервиса)
+	close func()
+}
+
+// openStorage - подключиться к базе данных выбранного типа, применить миграции и создать сервис хранения.
+// События об изменениях рассылаются подключённым клиентам через hub
+func openStorage(ctx context.Context, cfg *config.Config, hub *notifications.Hub) (*storage, error) {
+	switch backend := cfg.Database.Backend(); backend {
+	case config.StoragePostgres:
+		return openPostgres(ctx, cfg, hub)
+	case config.StorageSQLite:
+		return openSQLite(cfg, hub)
+	case config.StorageMemory:
+		return openMemory(ctx, cfg, hub)
+	default:
+		return nil, fmt.Errorf("unknown database type %q", backend)
+	}
+}
+
+// openPostgres - сервис хранения на PostgreSQL со всеми возможностями
+func openPostgres(ctx context.Context, cfg *config.Config, hub *notifications.Hub) (*storage, error) {
+	dbManager, err := postgres.NewDatabaseManager(cfg.Database.DSN, cfg.Database.OwnerDSN)
+	if err != nil {
+		return nil, err
+	}
+
+	// Политики строк - страховка на случай запроса без условия на владельца - действуют только для роли, не владеющей таблицами
+	if cfg.Database.OwnerDSN == "" {
+		log.Printf("postgres storage: the server connects as the schema owner, which can bypass row-level security; set database.owner_dsn and run the server as a separate role")
+	}
+
+	// События проходят через PostgreSQL (NOTIFY/LISTEN), поэтому клиенты получают изменения, сделанные через любой экземпляр сервера.
+	// После разрыва подключения слушателя потоки клиентов закрываются, чтобы они синхронизировали пропущенное
+	sealer, err := openSealer(cfg.Encryption, dbManager.DataKeysRepo)
+	if err != nil {
+		dbManager.DB.Close(context.Background())
+		return nil, err
+	}
+
+	changeListener := postgres.NewChangeListener(cfg.Database.DSN, hub.Publish, hub.DisconnectAll)
+	go changeListener.Run(ctx)
+
+	// Поля записей и хэши паролей шифруются, если задан мастер-ключ
+	storageService := services.NewStorageService(
+		envelope.Users(sealer, dbManager.UsersRepo),
+		envelope.Binaries(sealer, dbManager.BinariesRepo, dbManager.AccessRepo),
+		envelope.Cards(sealer, dbManager.CardsRepo, dbManager.AccessRepo),
+		envelope.Credentials(sealer, dbManager.CredentialsRepo, dbManager.AccessRepo),
+		envelope.Texts(sealer, dbManager.TextsRepo, dbManager.AccessRepo),
+		dbManager.AccessRepo,
+		services.WithAuditRepo(dbManager.AuditRepo),
+		services.WithNotifier(postgres.NewPgChangeNotifier(dbManager.DB)),
+		services.WithSharing(dbManager.UserKeysRepo, dbManager.ShareRepo),
+		services.WithOrganizations(dbManager.OrgRepo),
+		services.WithEmergencyAccess(dbManager.EmergencyRepo, cfg.Storage.EmergencyCheckInterval),
+		services.WithAccounts(envelope.Accounts(sealer, dbManager.AccountRepo)),
+		services.WithRegistration(cfg.Auth.Registration, dbManager.InviteRepo),
+		services.WithTrash(envelope.Trash(sealer, dbManager.TrashRepo), cfg.Storage.TrashRetention, cfg.Storage.TrashPurgeInterval),
+		services.WithHistory(envelope.History(sealer, dbManager.HistoryRepo, dbManager.AccessRepo), cfg.Storage.HistoryMaxVersions, cfg.Storage.HistoryMaxAge),
+		services.WithQuota(entities.Quota{MaxEntries: cfg.Quota.MaxEntries, MaxBytes: cfg.Quota.MaxBytes}),
+		services.WithTransactor(dbManager),
+		services.WithQueueSize(cfg.Storage.QueueSize))
+
+	return &storage{
+		service: storageService,
+		ping: func(ctx context.Context) error {
+			return postgres.Ping(ctx, cfg.Database.DSN)
+		},
+		close: func() { dbManager.DB.Close(context.Background()) },
+	}, nil
+}
+
+// openSQLite - сервис хранения на SQLite для одного экземпляра сервера: только личные записи пользователей.
+// Общий доступ, организации, экстренный доступ, журнал аудита, учётные записи и квоты, приглашения, корзина и история отключены:
+// их настройки не проходят проверку конфигурации, а о недоступных возможностях сервер сообщает при запуске
+func openSQLite(cfg *config.Config, hub *notifications.Hub) (*storage, error) {
+	dbManager, err := sqlite.NewDatabaseManager(cfg.Database.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("sqlite storage: sharing, organizations, emergency access, audit log, admin API, quotas, invites, trash and version history are not available (their endpoints respond 501 Not Implemented)")
+
+	sealer, err := openSealer(cfg.Encryption, dbManager.DataKeysRepo)
+	if err != nil {
+		dbManager.DB.Close()
+		return nil, err
+	}
+
+	// Экземпляр сервера единственный, поэтому события рассылаются клиентам напрямую
+	storageService := services.NewStorageService(
+		envelope.Users(sealer, dbManager.UsersRepo),
+		envelope.Binaries(sealer, dbManager.BinariesRepo, dbManager.AccessRepo),
+		envelope.Cards(sealer, dbManager.CardsRepo, dbManager.AccessRepo),
+		envelope.Credentials(sealer, dbManager.CredentialsRepo, dbManager.AccessRepo),
+		envelope.Texts(sealer, dbManager.TextsRepo, dbManager.AccessRepo),
+		dbManager.AccessRepo,
+		services.WithNotifier(hub),
+		services.WithRegistration(cfg.Auth.Registration, nil),
+		services.WithQueueSize(cfg.Storage.QueueSize))
+
+	return &storage{
+		service: storageService,
+		ping:    dbManager.DB.PingContext,
+		close:   func() { dbManager.DB.Close() },
+	}, nil
+}
+
+// openMemory - сервис хранения в памяти со всеми возможностями для демонстраций и локальной разработки.
+// Состояние сохраняется в директории данных: изменения дописываются в журнал, периодически записывается снимок
+func openMemory(ctx context.Context, cfg *config.Config, hub *notifications.Hub) (*storage, error) {
+	dir := strings.TrimPrefix(strings.TrimPrefix(cfg.Database.DSN, "memory://"), "memory:")
+	dbManager, err := inmemory.OpenDatabaseManager(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sealer, err := openSealer(cfg.Encryption, dbManager.DataKeys)
+	if err != nil {
+		dbManager.Close()
+		return nil, err
+	}
+
+	go dbManager.RunSnapshots(ctx, cfg.Storage.SnapshotInterval)
+
+	// Экземпляр сервера единственный, поэтому события рассылаются клиентам напрямую
+	storageService := services.NewStorageService(
+		envelope.Users(sealer, dbManager.Users),
+		envelope.Binaries(sealer, dbManager.Binaries, dbManager.Access),
+		envelope.Cards(sealer, dbManager.Cards, dbManager.Access),
+		envelope.Credentials(sealer, dbManager.Credentials, dbManager.Access),
+		envelope.Texts(sealer, dbManager.Texts, dbManager.Access),
+		dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
+		services.WithNotifier(hub),
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithOrganizations(dbManager.Orgs),
+		services.WithEmergencyAccess(dbManager.Emergency, cfg.Storage.EmergencyCheckInterval),
+		services.WithAccounts(envelope.Accounts(sealer, dbManager.Accounts)),
+		services.WithRegistration(cfg.Auth.Registration, dbManager.Invites),
+		services.WithTrash(envelope.Trash(sealer, dbManager.Trash), cfg.Storage.TrashRetention, cfg.Storage.TrashPurgeInterval),
+		services.WithHistory(envelope.History(sealer, dbManager.History, dbManager.Access), cfg.Storage.HistoryMaxVersions, cfg.Storage.HistoryMaxAge),
+		services.WithQuota(entities.Quota{MaxEntries: cfg.Quota.MaxEntries, MaxBytes: cfg.Quota.MaxBytes}),
+		services.WithQueueSize(cfg.Storage.QueueSize))
+
+	return &storage{
+		service: storageService,
+		ping:    dbManager.Ping,
+		close: func() {
+			if err := dbManager.Close(); err != nil {
+				log.Printf("failed to write final snapshot: %v", err)
+			}
+		},
+	}, nil
+}
+
+// openSealer - шифрование хранимых полей ключами данных из repo (nil, если мастер-ключ не задан и шифрование выключено)
+func openSealer(cfg config.EncryptionConfig, repo repositories.IDataKeyRepository) (*envelope.Sealer, error) {
+	if cfg.MasterKeyFile == "" {
+		return nil, nil
+	}
+
+	keys, err := envelope.LoadLocalKeyManager(cfg.MasterKeyFile, cfg.PreviousKeyFiles)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.NewSealer(keys, repo), nil
+}
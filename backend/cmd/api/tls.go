@@ -2,29 +2,45 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
-	"errors"
-	"os"
+	"net"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/certificates"
 )
 
-// GetTLSConfigFromFiles - загрузить TLS-конфигурацию из файлов сертификата и ключа
-func GetTLSConfigFromFiles(certPath, keyPath string) (tlsConfig *tls.Config, err error) {
-	// Проверяем файлы
-	if _, err := os.Stat(certPath); os.IsNotExist(err) {
-		return nil, errors.New("файл сертификата не найден: " + certPath)
-	}
-	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
-		return nil, errors.New("файл ключа не найден: " + keyPath)
-	}
+// certReloadInterval - период проверки файлов сертификата на изменения
+const certReloadInterval = 10 * time.Second
 
-	// Загружаем сертификат и ключ из файлов
-	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+// GetTLSConfigFromFiles - загрузить TLS-конфигурацию из файлов сертификата и ключа.
+// Файлы отслеживаются до отмены контекста, обновлённый сертификат подхватывается без перезапуска сервера
+func GetTLSConfigFromFiles(ctx context.Context, certPath, keyPath string) (tlsConfig *tls.Config, err error) {
+	reloader, err := certificates.NewReloader(certPath, keyPath)
 	if err != nil {
 		return nil, err
 	}
 
+	go reloader.Watch(ctx, certReloadInterval)
+
 	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
 	}, nil
 }
+
+// EnsureDevCertificates - выпустить (при отсутствии) локальный CA и сертификат сервера для адреса сервера.
+// Возвращает пути до сертификата и ключа сервера
+func EnsureDevCertificates(dir, serverAddr string) (certPath, keyPath string, err error) {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(serverAddr); err == nil && host != "" {
+		hosts = append(hosts, host)
+	}
+
+	paths, err := certificates.EnsureDevCertificates(dir, hosts)
+	if err != nil {
+		return "", "", err
+	}
+
+	return paths.ServerCert, paths.ServerKey, nil
+}
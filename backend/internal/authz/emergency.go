@@ -0,0 +1,65 @@
+// Пакет authz содержит политику доступа: решения о том, может ли пользователь выполнить действие над записью или организацией
+package authz
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// Действия над экстренным доступом (кроме чтения и удаления)
+const (
+	ActionEmergencyAccept  Action = "emergency_accept"  // доверенное лицо соглашается
+	ActionEmergencyRequest Action = "emergency_request" // доверенное лицо запрашивает доступ
+	ActionEmergencyApprove Action = "emergency_approve" // доверитель предоставляет доступ, не дожидаясь окончания ожидания
+	ActionEmergencyReject  Action = "emergency_reject"  // доверитель отклоняет запрос или закрывает предоставленный доступ
+	ActionEmergencyView    Action = "emergency_view"    // доверенное лицо открывает хранилище доверителя
+)
+
+// emergencyTransition - переход состояния экстренного доступа
+type emergencyTransition struct {
+	byGrantee bool     // действие выполняет доверенное лицо (иначе - доверитель)
+	from      []string // состояния, в которых действие допустимо
+	to        string
+}
+
+// emergencyTransitions - переходы, выполняемые пользователями. Переход из запроса в предоставленный доступ
+// по истечении ожидания выполняет сервер, а не пользователь
+var emergencyTransitions = map[Action]emergencyTransition{
+	ActionEmergencyAccept:  {byGrantee: true, from: []string{entities.EmergencyStatusInvited}, to: entities.EmergencyStatusAccepted},
+	ActionEmergencyRequest: {byGrantee: true, from: []string{entities.EmergencyStatusAccepted}, to: entities.EmergencyStatusRequested},
+	ActionEmergencyApprove: {from: []string{entities.EmergencyStatusRequested}, to: entities.EmergencyStatusGranted},
+	ActionEmergencyReject:  {from: []string{entities.EmergencyStatusRequested, entities.EmergencyStatusGranted}, to: entities.EmergencyStatusAccepted},
+	ActionEmergencyView:    {byGrantee: true, from: []string{entities.EmergencyStatusGranted}, to: entities.EmergencyStatusGranted},
+}
+
+var errEmergencyNotFound = customerrors.NewNotFoundError(errors.New("emergency access not found"))
+
+// Emergency - может ли пользователь userID выполнить действие над экстренным доступом; возвращает состояние после действия.
+// Чтение и удаление доступны обеим сторонам в любом состоянии. Посторонний пользователь получает 404,
+// действие не той стороны - 403, действие в неподходящем состоянии - 409
+func (p *Policy) Emergency(userID string, access *entities.EmergencyAccess, action Action) (string, error) {
+	if access == nil || (access.GrantorID != userID && access.GranteeID != userID) {
+		return "", errEmergencyNotFound
+	}
+
+	switch action {
+	case ActionRead, ActionDelete:
+		return access.Status, nil
+	}
+
+	transition, known := emergencyTransitions[action]
+	if !known || transition.byGrantee != (access.GranteeID == userID) {
+		return "", customerrors.ForbiddenError
+	}
+
+	for _, from := range transition.from {
+		if access.Status == from {
+			return transition.to, nil
+		}
+	}
+
+	return "", customerrors.NewAlreadyExistsError(fmt.Errorf("emergency access is %s", access.Status))
+}
@@ -0,0 +1,152 @@
+// Пакет authz содержит политику доступа: решения о том, может ли пользователь выполнить действие над записью или организацией
+package authz
+
+import (
+	"errors"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// Action - действие, на которое запрашивается разрешение
+type Action string
+
+// Действия над записями и организациями
+const (
+	ActionRead              Action = "read"
+	ActionUpdate            Action = "update"
+	ActionDelete            Action = "delete"
+	ActionShare             Action = "share"        // выдать доступ к личной записи или сменить её ключ
+	ActionCreateEntry       Action = "create_entry" // создать запись в коллекции
+	ActionViewMembers       Action = "view_members"
+	ActionManageMembers     Action = "manage_members"
+	ActionManageCollections Action = "manage_collections"
+)
+
+// actionSet - набор разрешённых действий
+type actionSet map[Action]bool
+
+var (
+	// ownerActions - действия владельца над личной записью
+	ownerActions = actionSet{ActionRead: true, ActionUpdate: true, ActionDelete: true, ActionShare: true}
+
+	// permissionActions - действия над чужой личной записью по принятому приглашению
+	permissionActions = map[string]actionSet{
+		entities.PermissionRead:  {ActionRead: true},
+		entities.PermissionWrite: {ActionRead: true, ActionUpdate: true},
+	}
+
+	// roleActions - действия участника организации над ней и над записями её коллекций
+	roleActions = map[string]actionSet{
+		entities.RoleOwner: {ActionRead: true, ActionUpdate: true, ActionDelete: true, ActionCreateEntry: true,
+			ActionViewMembers: true, ActionManageMembers: true, ActionManageCollections: true},
+		entities.RoleAdmin: {ActionRead: true, ActionUpdate: true, ActionDelete: true, ActionCreateEntry: true,
+			ActionViewMembers: true, ActionManageMembers: true, ActionManageCollections: true},
+		entities.RoleMember:   {ActionRead: true, ActionUpdate: true, ActionCreateEntry: true, ActionViewMembers: true},
+		entities.RoleReadOnly: {ActionRead: true, ActionViewMembers: true},
+	}
+)
+
+var (
+	// ErrNotFound - запись пользователю не видна (её нет или у пользователя нет к ней никакого отношения)
+	ErrNotFound             = customerrors.NewNotFoundError(errors.New("entry not found"))
+	errOrganizationNotFound = customerrors.NewNotFoundError(errors.New("organization not found"))
+)
+
+// Policy - политика доступа. Решения принимаются только по переданным отношениям пользователя,
+// их загрузка из хранилища - забота вызывающего
+type Policy struct{}
+
+// NewPolicy - политика доступа по умолчанию
+func NewPolicy() *Policy {
+	return &Policy{}
+}
+
+// Entry - может ли пользователь userID выполнить действие над записью.
+// Если у пользователя нет никакого отношения к записи, возвращается 404, чтобы не раскрывать её существование
+func (p *Policy) Entry(userID string, access *entities.EntryAccess, action Action) error {
+	if access == nil {
+		return ErrNotFound
+	}
+
+	allowed := p.entryActions(userID, access)
+	if allowed == nil {
+		return ErrNotFound
+	}
+
+	if !allowed[action] {
+		return customerrors.ForbiddenError
+	}
+
+	return nil
+}
+
+// entryActions - действия пользователя над записью (nil - запись пользователю не видна).
+// Записями коллекций распоряжается организация, поэтому для них учитывается только роль
+func (p *Policy) entryActions(userID string, access *entities.EntryAccess) actionSet {
+	if access.CollectionID != "" {
+		return roleActions[access.OrgRole]
+	}
+
+	if access.OwnerID == userID {
+		return ownerActions
+	}
+
+	return permissionActions[access.Permission]
+}
+
+// Organization - может ли участник организации с ролью role выполнить действие (пустая роль - не участник)
+func (p *Policy) Organization(role string, action Action) error {
+	allowed, member := roleActions[role]
+	if !member {
+		return errOrganizationNotFound
+	}
+
+	if !allowed[action] {
+		return customerrors.ForbiddenError
+	}
+
+	return nil
+}
+
+// AssignRole - может ли участник с ролью role пригласить пользователя с ролью target.
+// Владелец у организации один, администраторов назначает только он
+func (p *Policy) AssignRole(role, target string) error {
+	if err := p.Organization(role, ActionManageMembers); err != nil {
+		return err
+	}
+
+	switch target {
+	case entities.RoleAdmin:
+		if role != entities.RoleOwner {
+			return customerrors.ForbiddenError
+		}
+	case entities.RoleMember, entities.RoleReadOnly:
+	default:
+		return customerrors.ForbiddenError
+	}
+
+	return nil
+}
+
+// RemoveMember - может ли участник userID с ролью role исключить участника target.
+// Любой участник, кроме владельца, может выйти сам; администраторов исключает только владелец, владельца - никто
+func (p *Policy) RemoveMember(userID, role string, target *entities.OrgMember) error {
+	if _, member := roleActions[role]; !member || target == nil {
+		return errOrganizationNotFound
+	}
+
+	if target.Role == entities.RoleOwner {
+		return customerrors.ForbiddenError
+	}
+
+	if target.Login == userID {
+		return nil
+	}
+
+	if target.Role == entities.RoleAdmin && role != entities.RoleOwner {
+		return customerrors.ForbiddenError
+	}
+
+	return p.Organization(role, ActionManageMembers)
+}
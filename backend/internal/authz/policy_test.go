@@ -0,0 +1,133 @@
+// policy_test.go
+package authz_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/authz"
+	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyEntry(t *testing.T) {
+	policy := authz.NewPolicy()
+
+	personal := &entities.EntryAccess{OwnerID: "owner"}
+	reader := &entities.EntryAccess{OwnerID: "owner", Permission: entities.PermissionRead}
+	writer := &entities.EntryAccess{OwnerID: "owner", Permission: entities.PermissionWrite}
+
+	tests := []struct {
+		name   string
+		userID string
+		access *entities.EntryAccess
+		action authz.Action
+		want   error
+	}{
+		{"Владелец делится записью", "owner", personal, authz.ActionShare, nil},
+		{"Владелец удаляет запись", "owner", personal, authz.ActionDelete, nil},
+		{"Запись не существует", "owner", nil, authz.ActionRead, authz.ErrNotFound},
+		{"Посторонний не видит запись", "stranger", personal, authz.ActionRead, authz.ErrNotFound},
+		{"Чтение по праву чтения", "reader", reader, authz.ActionRead, nil},
+		{"Изменение по праву чтения", "reader", reader, authz.ActionUpdate, customerrors.ForbiddenError},
+		{"Изменение по праву записи", "writer", writer, authz.ActionUpdate, nil},
+		{"Получатель не делится чужой записью", "writer", writer, authz.ActionShare, customerrors.ForbiddenError},
+		{"Получатель не удаляет чужую запись", "writer", writer, authz.ActionDelete, customerrors.ForbiddenError},
+		{"Участник изменяет запись коллекции", "member",
+			&entities.EntryAccess{OwnerID: "owner", CollectionID: "1", OrgRole: entities.RoleMember}, authz.ActionUpdate, nil},
+		{"Участник не удаляет запись коллекции", "member",
+			&entities.EntryAccess{OwnerID: "owner", CollectionID: "1", OrgRole: entities.RoleMember}, authz.ActionDelete, customerrors.ForbiddenError},
+		{"Только чтение коллекции", "reader",
+			&entities.EntryAccess{OwnerID: "owner", CollectionID: "1", OrgRole: entities.RoleReadOnly}, authz.ActionUpdate, customerrors.ForbiddenError},
+		{"Автор записи вне организации", "owner",
+			&entities.EntryAccess{OwnerID: "owner", CollectionID: "1"}, authz.ActionRead, authz.ErrNotFound},
+		{"Записью коллекции не делятся лично", "admin",
+			&entities.EntryAccess{OwnerID: "owner", CollectionID: "1", OrgRole: entities.RoleAdmin}, authz.ActionShare, customerrors.ForbiddenError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Entry(tt.userID, tt.access, tt.action)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.want), err)
+		})
+	}
+}
+
+func TestPolicyMembers(t *testing.T) {
+	policy := authz.NewPolicy()
+
+	t.Run("Назначение ролей", func(t *testing.T) {
+		assert.NoError(t, policy.AssignRole(entities.RoleOwner, entities.RoleAdmin))
+		assert.NoError(t, policy.AssignRole(entities.RoleAdmin, entities.RoleReadOnly))
+		assert.Error(t, policy.AssignRole(entities.RoleAdmin, entities.RoleAdmin))
+		assert.Error(t, policy.AssignRole(entities.RoleOwner, entities.RoleOwner))
+		assert.Error(t, policy.AssignRole(entities.RoleMember, entities.RoleMember))
+		assert.Error(t, policy.AssignRole("", entities.RoleMember))
+	})
+
+	t.Run("Исключение участников", func(t *testing.T) {
+		admin := &entities.OrgMember{Login: "admin", Role: entities.RoleAdmin}
+		member := &entities.OrgMember{Login: "member", Role: entities.RoleMember}
+		owner := &entities.OrgMember{Login: "owner", Role: entities.RoleOwner}
+
+		assert.NoError(t, policy.RemoveMember("owner", entities.RoleOwner, admin))
+		assert.NoError(t, policy.RemoveMember("admin", entities.RoleAdmin, member))
+		assert.NoError(t, policy.RemoveMember("member", entities.RoleMember, member), "self-leave")
+		assert.Error(t, policy.RemoveMember("admin", entities.RoleAdmin, &entities.OrgMember{Login: "other", Role: entities.RoleAdmin}))
+		assert.Error(t, policy.RemoveMember("member", entities.RoleMember, admin))
+		assert.Error(t, policy.RemoveMember("owner", entities.RoleOwner, owner))
+		assert.Error(t, policy.RemoveMember("stranger", "", member))
+	})
+}
+
+func TestPolicyEmergency(t *testing.T) {
+	policy := authz.NewPolicy()
+
+	access := func(status string) *entities.EmergencyAccess {
+		return &entities.EmergencyAccess{GrantorID: "grantor", GranteeID: "trustee", Status: status}
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		access *entities.EmergencyAccess
+		action authz.Action
+		want   string
+		code   int
+	}{
+		{"Доверенное лицо соглашается", "trustee", access(entities.EmergencyStatusInvited), authz.ActionEmergencyAccept, entities.EmergencyStatusAccepted, 0},
+		{"Доверитель не соглашается за доверенное лицо", "grantor", access(entities.EmergencyStatusInvited), authz.ActionEmergencyAccept, "", 403},
+		{"Запрос до согласия", "trustee", access(entities.EmergencyStatusInvited), authz.ActionEmergencyRequest, "", 409},
+		{"Запрос доступа", "trustee", access(entities.EmergencyStatusAccepted), authz.ActionEmergencyRequest, entities.EmergencyStatusRequested, 0},
+		{"Доверитель одобряет запрос", "grantor", access(entities.EmergencyStatusRequested), authz.ActionEmergencyApprove, entities.EmergencyStatusGranted, 0},
+		{"Доверенное лицо не одобряет само", "trustee", access(entities.EmergencyStatusRequested), authz.ActionEmergencyApprove, "", 403},
+		{"Доверитель отклоняет запрос", "grantor", access(entities.EmergencyStatusRequested), authz.ActionEmergencyReject, entities.EmergencyStatusAccepted, 0},
+		{"Доверитель закрывает доступ", "grantor", access(entities.EmergencyStatusGranted), authz.ActionEmergencyReject, entities.EmergencyStatusAccepted, 0},
+		{"Хранилище открыто после предоставления", "trustee", access(entities.EmergencyStatusGranted), authz.ActionEmergencyView, entities.EmergencyStatusGranted, 0},
+		{"Хранилище закрыто во время ожидания", "trustee", access(entities.EmergencyStatusRequested), authz.ActionEmergencyView, "", 409},
+		{"Любая сторона может отменить доступ", "trustee", access(entities.EmergencyStatusGranted), authz.ActionDelete, entities.EmergencyStatusGranted, 0},
+		{"Посторонний не видит доступ", "stranger", access(entities.EmergencyStatusGranted), authz.ActionRead, "", 404},
+		{"Доступ не существует", "trustee", nil, authz.ActionRead, "", 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.Emergency(tt.userID, tt.access, tt.action)
+			if tt.code == 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+
+			var httpErr *customerrors.HTTPError
+			if assert.True(t, errors.As(err, &httpErr), err) {
+				assert.Equal(t, tt.code, httpErr.Code)
+			}
+		})
+	}
+}
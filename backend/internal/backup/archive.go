@@ -0,0 +1,353 @@
+// Пакет backup - формат архива резервной копии сервера.
+// Архив - поток JSON-строк, сжатый gzip и при необходимости зашифрованный age для X25519-получателей:
+// заголовок с версией схемы, затем строки таблиц по порядку восстановления и завершающая запись
+// с контрольной суммой SHA-256 всех предыдущих строк. Архив без завершающей записи считается обрезанным
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"filippo.io/age"
+)
+
+// Format - признак архива GophKeeper в заголовке
+const Format = "gophkeeper-backup"
+
+// Version - версия формата архива. Архив более новой версии не читается
+const Version = 1
+
+// maxLineSize - наибольшая длина строки архива (строка таблицы с двоичными данными в hex)
+const maxLineSize = 256 << 20
+
+// ageMagic - начало файла, зашифрованного age
+var ageMagic = []byte("age-encryption.org/")
+
+// ErrEncrypted - архив зашифрован, а ключ для расшифровки не передан
+var ErrEncrypted = errors.New("backup is encrypted: identity is required")
+
+// Header - заголовок архива: откуда и когда снята копия, какие миграции схемы были применены
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Storage   string    `json:"storage"`
+	// Migrations - версии применённых миграций по возрастанию. Копия восстанавливается только в базу с теми же миграциями
+	Migrations []int `json:"migrations"`
+}
+
+// SchemaVersion - версия схемы: последняя применённая миграция
+func (h *Header) SchemaVersion() int {
+	if len(h.Migrations) == 0 {
+		return 0
+	}
+	return h.Migrations[len(h.Migrations)-1]
+}
+
+// TableSummary - таблица в архиве и число её строк
+type TableSummary struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// record - строка архива. Type: header, table (начало таблицы), row, table_end (число строк таблицы), end
+type record struct {
+	Type   string          `json:"type"`
+	Header *Header         `json:"header,omitempty"`
+	Table  string          `json:"table,omitempty"`
+	Row    json.RawMessage `json:"row,omitempty"`
+	Rows   int64           `json:"rows,omitempty"`
+	Tables int             `json:"tables,omitempty"`
+	SHA256 string          `json:"sha256,omitempty"`
+}
+
+// Writer - запись архива. Порядок вызовов: Begin, затем для каждой таблицы Table и Row, в конце Close
+type Writer struct {
+	encrypted io.WriteCloser // шифрование age (nil - архив не шифруется)
+	gz        *gzip.Writer
+	sum       hash.Hash
+
+	tables []TableSummary
+	open   bool // начата таблица, для которой ещё не записано число строк
+}
+
+// NewWriter - архив, который пишется в w. С recipients архив шифруется для каждого из них
+func NewWriter(w io.Writer, recipients ...age.Recipient) (*Writer, error) {
+	writer := &Writer{sum: sha256.New()}
+
+	if len(recipients) > 0 {
+		encrypted, err := age.Encrypt(w, recipients...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+		writer.encrypted, w = encrypted, encrypted
+	}
+
+	writer.gz = gzip.NewWriter(w)
+	return writer, nil
+}
+
+// Begin - записать заголовок архива
+func (w *Writer) Begin(header Header) error {
+	header.Format, header.Version = Format, Version
+	return w.write(record{Type: "header", Header: &header})
+}
+
+// Table - начать таблицу name: следующие строки относятся к ней
+func (w *Writer) Table(name string) error {
+	if err := w.endTable(); err != nil {
+		return err
+	}
+
+	w.tables = append(w.tables, TableSummary{Name: name})
+	w.open = true
+	return w.write(record{Type: "table", Table: name})
+}
+
+// Row - записать строку текущей таблицы (JSON-объект со значениями столбцов)
+func (w *Writer) Row(row json.RawMessage) error {
+	if !w.open {
+		return errors.New("row written outside of a table")
+	}
+
+	w.tables[len(w.tables)-1].Rows++
+	return w.write(record{Type: "row", Row: row})
+}
+
+// Tables - записанные таблицы и число их строк
+func (w *Writer) Tables() []TableSummary {
+	return w.tables
+}
+
+// Close - записать контрольную сумму и завершить архив. Без Close архив останется обрезанным
+func (w *Writer) Close() error {
+	if err := w.endTable(); err != nil {
+		return err
+	}
+
+	end := record{Type: "end", Tables: len(w.tables), SHA256: hex.EncodeToString(w.sum.Sum(nil))}
+	if err := w.write(end); err != nil {
+		return err
+	}
+
+	if err := w.gz.Close(); err != nil {
+		return err
+	}
+	if w.encrypted != nil {
+		return w.encrypted.Close()
+	}
+	return nil
+}
+
+// endTable - записать число строк начатой таблицы
+func (w *Writer) endTable() error {
+	if !w.open {
+		return nil
+	}
+
+	w.open = false
+	current := w.tables[len(w.tables)-1]
+	return w.write(record{Type: "table_end", Table: current.Name, Rows: current.Rows})
+}
+
+// write - записать строку архива и учесть её в контрольной сумме
+func (w *Writer) write(r record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.sum.Write(line)
+	_, err = w.gz.Write(line)
+	return err
+}
+
+// Reader - чтение архива с проверкой по ходу чтения: порядок записей, число строк таблиц и контрольная сумма.
+// Порядок вызовов: Header, затем Next до io.EOF и Row до io.EOF для каждой таблицы
+type Reader struct {
+	lines  *bufio.Scanner
+	sum    hash.Hash
+	header Header
+
+	table  string // текущая таблица
+	rows   int64  // прочитано строк текущей таблицы
+	tables []TableSummary
+	done   bool // прочитана завершающая запись
+}
+
+// NewReader - чтение архива из r. Зашифрованный архив расшифровывается одним из identities
+func NewReader(r io.Reader, identities ...age.Identity) (*Reader, error) {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(len(ageMagic)); bytes.Equal(magic, ageMagic) {
+		if len(identities) == 0 {
+			return nil, ErrEncrypted
+		}
+
+		decrypted, err := age.Decrypt(buffered, identities...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt backup: %w", err)
+		}
+		r = decrypted
+	} else {
+		r = buffered
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+
+	lines := bufio.NewScanner(gz)
+	lines.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	reader := &Reader{lines: lines, sum: sha256.New()}
+
+	first, err := reader.read()
+	if err != nil {
+		return nil, err
+	}
+	if first.Type != "header" || first.Header == nil || first.Header.Format != Format {
+		return nil, errors.New("not a backup archive: header is missing")
+	}
+	if first.Header.Version > Version {
+		return nil, fmt.Errorf("backup format version %d is newer than supported %d", first.Header.Version, Version)
+	}
+
+	reader.header = *first.Header
+	return reader, nil
+}
+
+// Header - заголовок архива
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next - перейти к следующей таблице (непрочитанные строки текущей пропускаются) и вернуть её имя.
+// После последней таблицы проверяет контрольную сумму архива и возвращает io.EOF
+func (r *Reader) Next() (string, error) {
+	if r.done {
+		return "", io.EOF
+	}
+
+	// Дочитать текущую таблицу: её число строк проверяется в Row
+	for r.table != "" {
+		if _, err := r.Row(); err != nil && err != io.EOF {
+			return "", err
+		}
+	}
+
+	// Контрольная сумма не включает завершающую запись
+	expected := hex.EncodeToString(r.sum.Sum(nil))
+
+	next, err := r.read()
+	if err != nil {
+		return "", err
+	}
+
+	switch next.Type {
+	case "table":
+		r.table, r.rows = next.Table, 0
+		r.tables = append(r.tables, TableSummary{Name: next.Table})
+		return next.Table, nil
+	case "end":
+		if next.SHA256 != expected {
+			return "", errors.New("backup checksum mismatch")
+		}
+		if next.Tables != len(r.tables) {
+			return "", fmt.Errorf("backup is incomplete: %d of %d tables", len(r.tables), next.Tables)
+		}
+		if r.lines.Scan() {
+			return "", errors.New("unexpected data after the end of backup")
+		}
+		if err := r.lines.Err(); err != nil {
+			return "", err
+		}
+
+		r.done = true
+		return "", io.EOF
+	default:
+		return "", fmt.Errorf("unexpected %s record between tables", next.Type)
+	}
+}
+
+// Row - следующая строка текущей таблицы. В конце таблицы проверяет число строк и возвращает io.EOF
+func (r *Reader) Row() (json.RawMessage, error) {
+	if r.table == "" {
+		return nil, io.EOF
+	}
+
+	next, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case next.Type == "row":
+		r.rows++
+		r.tables[len(r.tables)-1].Rows = r.rows
+		return next.Row, nil
+	case next.Type == "table_end" && next.Table == r.table:
+		if next.Rows != r.rows {
+			return nil, fmt.Errorf("table %s: %d rows in backup, %d expected", r.table, r.rows, next.Rows)
+		}
+		r.table = ""
+		return nil, io.EOF
+	default:
+		return nil, fmt.Errorf("table %s: unexpected %s record", r.table, next.Type)
+	}
+}
+
+// Tables - прочитанные таблицы и число их строк
+func (r *Reader) Tables() []TableSummary {
+	return r.tables
+}
+
+// read - прочитать строку архива и учесть её в контрольной сумме
+func (r *Reader) read() (*record, error) {
+	if !r.lines.Scan() {
+		if err := r.lines.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		return nil, errors.New("backup is truncated")
+	}
+
+	line := r.lines.Bytes()
+	var next record
+	if err := json.Unmarshal(line, &next); err != nil {
+		return nil, fmt.Errorf("malformed backup record: %w", err)
+	}
+
+	if next.Type != "end" {
+		r.sum.Write(line)
+		r.sum.Write([]byte{'\n'})
+	}
+	return &next, nil
+}
+
+// Verify - прочитать архив целиком и проверить его. Возвращает заголовок и таблицы с числом строк
+func Verify(r io.Reader, identities ...age.Identity) (*Header, []TableSummary, error) {
+	reader, err := NewReader(r, identities...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		if _, err := reader.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	header := reader.Header()
+	return &header, reader.Tables(), nil
+}
@@ -0,0 +1,213 @@
+package backup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/JustScorpio/GophKeeper/backend/internal/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTables - таблицы тестового архива и их строки
+var testTables = []struct {
+	name string
+	rows []string
+}{
+	{name: "users", rows: []string{`{"login":"alice"}`, `{"login":"bob"}`}},
+	{name: "audit_log", rows: nil},
+	{name: "binaries", rows: []string{`{"id":"1","data":"\\x00ff","ownerid":"alice"}`}},
+}
+
+// writeArchive - архив с testTables
+func writeArchive(t *testing.T, recipients ...age.Recipient) []byte {
+	var buf bytes.Buffer
+	w, err := backup.NewWriter(&buf, recipients...)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Begin(backup.Header{CreatedAt: time.Now(), Storage: "postgres", Migrations: []int{1, 2, 3}}))
+	for _, table := range testTables {
+		require.NoError(t, w.Table(table.name))
+		for _, row := range table.rows {
+			require.NoError(t, w.Row(json.RawMessage(row)))
+		}
+	}
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+// rewrite - распаковать архив, изменить его строки и упаковать снова
+func rewrite(t *testing.T, archive []byte, change func(lines []string) []string) []byte {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	lines := change(strings.Split(strings.TrimSuffix(string(plain), "\n"), "\n"))
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestArchive(t *testing.T) {
+	archive := writeArchive(t)
+
+	t.Run("Чтение таблиц и строк", func(t *testing.T) {
+		r, err := backup.NewReader(bytes.NewReader(archive))
+		require.NoError(t, err)
+
+		header := r.Header()
+		assert.Equal(t, backup.Format, header.Format)
+		assert.Equal(t, backup.Version, header.Version)
+		assert.Equal(t, 3, header.SchemaVersion())
+
+		for _, table := range testTables {
+			name, err := r.Next()
+			require.NoError(t, err)
+			assert.Equal(t, table.name, name)
+
+			var rows []string
+			for {
+				row, err := r.Row()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				rows = append(rows, string(row))
+			}
+			assert.Equal(t, table.rows, rows)
+		}
+
+		_, err = r.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("Проверка архива", func(t *testing.T) {
+		header, tables, err := backup.Verify(bytes.NewReader(archive))
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, header.Migrations)
+		assert.Equal(t, []backup.TableSummary{{Name: "users", Rows: 2}, {Name: "audit_log", Rows: 0}, {Name: "binaries", Rows: 1}}, tables)
+	})
+
+	t.Run("Пропуск непрочитанных строк", func(t *testing.T) {
+		r, err := backup.NewReader(bytes.NewReader(archive))
+		require.NoError(t, err)
+
+		_, err = r.Next()
+		require.NoError(t, err)
+		_, err = r.Row()
+		require.NoError(t, err)
+
+		name, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "audit_log", name)
+	})
+}
+
+func TestArchive_Damaged(t *testing.T) {
+	archive := writeArchive(t)
+
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr string
+	}{
+		{
+			name:    "Не архив",
+			archive: []byte("not a backup"),
+			wantErr: "not a backup archive",
+		},
+		{
+			name:    "Обрезанный архив",
+			archive: rewrite(t, archive, func(lines []string) []string { return lines[:len(lines)-1] }),
+			wantErr: "truncated",
+		},
+		{
+			name: "Изменённая строка",
+			archive: rewrite(t, archive, func(lines []string) []string {
+				lines[2] = strings.Replace(lines[2], "alice", "mallory", 1)
+				return lines
+			}),
+			wantErr: "checksum mismatch",
+		},
+		{
+			name: "Удалённая строка",
+			archive: rewrite(t, archive, func(lines []string) []string {
+				return append(lines[:2:2], lines[3:]...)
+			}),
+			wantErr: "table users",
+		},
+		{
+			name: "Данные после конца архива",
+			archive: rewrite(t, archive, func(lines []string) []string {
+				return append(lines, `{"type":"row","row":{}}`)
+			}),
+			wantErr: "after the end",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := backup.Verify(bytes.NewReader(tt.archive))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("Повреждённое сжатие", func(t *testing.T) {
+		damaged := bytes.Clone(archive)
+		damaged[len(damaged)/2] ^= 0xff
+
+		_, _, err := backup.Verify(bytes.NewReader(damaged))
+		assert.Error(t, err)
+	})
+
+	t.Run("Более новая версия формата", func(t *testing.T) {
+		newer := rewrite(t, archive, func(lines []string) []string {
+			lines[0] = strings.Replace(lines[0], `"version":1`, `"version":99`, 1)
+			return lines
+		})
+
+		_, err := backup.NewReader(bytes.NewReader(newer))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "newer")
+	})
+}
+
+func TestArchive_Encrypted(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	archive := writeArchive(t, identity.Recipient())
+
+	assert.False(t, bytes.Contains(archive, []byte("alice")))
+
+	t.Run("Расшифровка", func(t *testing.T) {
+		_, tables, err := backup.Verify(bytes.NewReader(archive), identity)
+		require.NoError(t, err)
+		assert.Len(t, tables, len(testTables))
+	})
+
+	t.Run("Без ключа", func(t *testing.T) {
+		_, err := backup.NewReader(bytes.NewReader(archive))
+		assert.ErrorIs(t, err, backup.ErrEncrypted)
+	})
+
+	t.Run("Чужой ключ", func(t *testing.T) {
+		other, err := age.GenerateX25519Identity()
+		require.NoError(t, err)
+
+		_, err = backup.NewReader(bytes.NewReader(archive), other)
+		assert.Error(t, err)
+	})
+}
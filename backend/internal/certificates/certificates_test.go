@@ -0,0 +1,151 @@
+// certificates_test - тесты выпуска и перезагрузки сертификатов
+package certificates_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/certificates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadCertificate - прочитать первый сертификат из цепочки
+func loadCertificate(t *testing.T, certPath, keyPath string) *x509.Certificate {
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	return cert
+}
+
+func TestEnsureDevCertificates(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Генерация CA и сертификата сервера", func(t *testing.T) {
+		paths, err := certificates.EnsureDevCertificates(dir, []string{"localhost", "127.0.0.1", "::1"})
+		require.NoError(t, err)
+
+		caCert := loadCertificate(t, paths.CACert, paths.CAKey)
+		assert.True(t, caCert.IsCA)
+
+		serverCert := loadCertificate(t, paths.ServerCert, paths.ServerKey)
+		assert.Contains(t, serverCert.DNSNames, "localhost")
+		assert.Len(t, serverCert.IPAddresses, 2)
+
+		// Сертификат сервера должен проверяться по сгенерированному CA
+		roots := x509.NewCertPool()
+		roots.AddCert(caCert)
+		_, err = serverCert.Verify(x509.VerifyOptions{DNSName: "localhost", Roots: roots})
+		assert.NoError(t, err)
+
+		keyInfo, err := os.Stat(paths.ServerKey)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), keyInfo.Mode().Perm())
+	})
+
+	t.Run("Повторный запуск не перевыпускает сертификаты", func(t *testing.T) {
+		paths := certificates.PathsInDir(dir)
+		before, err := os.ReadFile(paths.CACert)
+		require.NoError(t, err)
+
+		_, err = certificates.EnsureDevCertificates(dir, []string{"localhost"})
+		require.NoError(t, err)
+
+		after, err := os.ReadFile(paths.CACert)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("Новый CA перевыпускает сертификат сервера", func(t *testing.T) {
+		paths := certificates.PathsInDir(dir)
+		before, err := os.ReadFile(paths.ServerCert)
+		require.NoError(t, err)
+
+		require.NoError(t, os.Remove(paths.CACert))
+		_, err = certificates.EnsureDevCertificates(dir, []string{"localhost"})
+		require.NoError(t, err)
+
+		after, err := os.ReadFile(paths.ServerCert)
+		require.NoError(t, err)
+		assert.NotEqual(t, before, after)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(loadCertificate(t, paths.CACert, paths.CAKey))
+		_, err = loadCertificate(t, paths.ServerCert, paths.ServerKey).Verify(x509.VerifyOptions{DNSName: "localhost", Roots: roots})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Сертификат сервера от другого CA перевыпускается", func(t *testing.T) {
+		paths := certificates.PathsInDir(dir)
+		other, err := certificates.EnsureDevCertificates(t.TempDir(), []string{"localhost"})
+		require.NoError(t, err)
+
+		for from, to := range map[string]string{other.ServerCert: paths.ServerCert, other.ServerKey: paths.ServerKey} {
+			data, err := os.ReadFile(from)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(to, data, 0600))
+		}
+
+		_, err = certificates.EnsureDevCertificates(dir, []string{"localhost"})
+		require.NoError(t, err)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(loadCertificate(t, paths.CACert, paths.CAKey))
+		_, err = loadCertificate(t, paths.ServerCert, paths.ServerKey).Verify(x509.VerifyOptions{DNSName: "localhost", Roots: roots})
+		assert.NoError(t, err)
+	})
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := certificates.EnsureDevCertificates(dir, []string{"localhost"})
+	require.NoError(t, err)
+
+	t.Run("Отсутствующие файлы", func(t *testing.T) {
+		_, err := certificates.NewReloader(dir+"/missing.pem", paths.ServerKey)
+		assert.Error(t, err)
+	})
+
+	reloader, err := certificates.NewReloader(paths.ServerCert, paths.ServerKey)
+	require.NoError(t, err)
+
+	initial, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	require.NotNil(t, initial)
+
+	t.Run("Подхват нового сертификата после изменения файлов", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go reloader.Watch(ctx, 10*time.Millisecond)
+
+		// Удаляем сертификат сервера и выпускаем новый тем же CA
+		require.NoError(t, os.Remove(paths.ServerCert))
+		require.NoError(t, os.Remove(paths.ServerKey))
+		time.Sleep(20 * time.Millisecond)
+		_, err := certificates.EnsureDevCertificates(dir, []string{"localhost"})
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			current, err := reloader.GetCertificate(nil)
+			return err == nil && string(current.Certificate[0]) != string(initial.Certificate[0])
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Битый файл не заменяет рабочий сертификат", func(t *testing.T) {
+		current, err := reloader.GetCertificate(nil)
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(paths.ServerCert, []byte("garbage"), 0644))
+		assert.Error(t, reloader.Reload())
+
+		afterFailure, err := reloader.GetCertificate(nil)
+		require.NoError(t, err)
+		assert.Equal(t, current, afterFailure)
+	})
+}
@@ -0,0 +1,246 @@
+// Пакет certificates содержит функции для выпуска и горячей перезагрузки TLS-сертификатов
+package certificates
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// Имена файлов, которые создаются в директории с сертификатами
+	caCertFileName     = "ca.pem"
+	caKeyFileName      = "ca-key.pem"
+	serverCertFileName = "server.pem"
+	serverKeyFileName  = "server-key.pem"
+
+	// Срок действия корневого сертификата
+	caLifeTime = 10 * 365 * 24 * time.Hour
+	// Срок действия сертификата сервера (максимум, который принимают браузеры и macOS)
+	serverLifeTime = 825 * 24 * time.Hour
+)
+
+// Paths - пути до файлов сгенерированных сертификатов
+type Paths struct {
+	CACert     string
+	CAKey      string
+	ServerCert string
+	ServerKey  string
+}
+
+// PathsInDir - получить пути до файлов сертификатов в директории
+func PathsInDir(dir string) Paths {
+	return Paths{
+		CACert:     filepath.Join(dir, caCertFileName),
+		CAKey:      filepath.Join(dir, caKeyFileName),
+		ServerCert: filepath.Join(dir, serverCertFileName),
+		ServerKey:  filepath.Join(dir, serverKeyFileName),
+	}
+}
+
+// EnsureDevCertificates - создать локальный CA и подписанный им сертификат сервера, если их ещё нет в директории.
+// Уже существующие файлы не перезаписываются, поэтому клиенту достаточно один раз добавить CA в доверенные.
+// Сертификат сервера перевыпускается, если CA создан заново или сертификат подписан не им
+func EnsureDevCertificates(dir string, hosts []string) (Paths, error) {
+	paths := PathsInDir(dir)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return paths, fmt.Errorf("failed to create certificates directory: %w", err)
+	}
+
+	// Корневой сертификат
+	caCreated := false
+	if !fileExists(paths.CACert) || !fileExists(paths.CAKey) {
+		if err := generateCA(paths.CACert, paths.CAKey); err != nil {
+			return paths, fmt.Errorf("failed to generate CA: %w", err)
+		}
+		caCreated = true
+	}
+
+	ca, err := tls.LoadX509KeyPair(paths.CACert, paths.CAKey)
+	if err != nil {
+		return paths, fmt.Errorf("failed to load CA: %w", err)
+	}
+
+	// Сертификат сервера
+	if caCreated || !fileExists(paths.ServerCert) || !fileExists(paths.ServerKey) || !signedBy(paths.ServerCert, ca) {
+		if err := generateServerCertificate(ca, hosts, paths.ServerCert, paths.ServerKey); err != nil {
+			return paths, fmt.Errorf("failed to generate server certificate: %w", err)
+		}
+	}
+
+	return paths, nil
+}
+
+// signedBy - проверить, что сертификат в файле подписан корневым сертификатом ca
+func signedBy(certPath string, ca tls.Certificate) bool {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return false
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return false
+	}
+
+	return cert.CheckSignatureFrom(caCert) == nil
+}
+
+// generateCA - выпустить самоподписанный корневой сертификат
+func generateCA(certPath, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"GophKeeper development CA"},
+			CommonName:   "GophKeeper development CA",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caLifeTime),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	return writeKeyPair(certPath, keyPath, der, key)
+}
+
+// generateServerCertificate - выпустить сертификат сервера, подписанный корневым сертификатом
+func generateServerCertificate(ca tls.Certificate, hosts []string, certPath, keyPath string) error {
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"GophKeeper development certificate"},
+		},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(serverLifeTime),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	if len(template.DNSNames) > 0 {
+		template.Subject.CommonName = template.DNSNames[0]
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	return writeKeyPair(certPath, keyPath, der, key)
+}
+
+// writeKeyPair - записать сертификат и ключ в PEM-файлы
+func writeKeyPair(certPath, keyPath string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Ключ пишем первым и с ограниченными правами
+	if err := writePEM(keyPath, "PRIVATE KEY", keyDER, 0600); err != nil {
+		return err
+	}
+
+	return writePEM(certPath, "CERTIFICATE", der, 0644)
+}
+
+// writePEM - атомарно записать PEM-блок в файл (через временный файл и переименование)
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := pem.Encode(tmp, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// randomSerial - сгенерировать случайный серийный номер сертификата
+func randomSerial() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	return rand.Int(rand.Reader, limit)
+}
+
+// fileExists - проверить существование файла
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
@@ -0,0 +1,123 @@
+// Пакет certificates содержит функции для выпуска и горячей перезагрузки TLS-сертификатов
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader - держит актуальную пару сертификат/ключ и перечитывает её при изменении файлов.
+// Используется через tls.Config.GetCertificate, поэтому новые рукопожатия получают обновлённый сертификат,
+// а уже установленные соединения не разрываются
+type Reloader struct {
+	certPath string
+	keyPath  string
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewReloader - создать Reloader и загрузить сертификат
+func NewReloader(certPath, keyPath string) (*Reloader, error) {
+	// Проверяем файлы
+	if _, err := os.Stat(certPath); os.IsNotExist(err) {
+		return nil, errors.New("файл сертификата не найден: " + certPath)
+	}
+	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+		return nil, errors.New("файл ключа не найден: " + keyPath)
+	}
+
+	reloader := &Reloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// Reload - перечитать сертификат и ключ с диска. При ошибке продолжает использоваться прежний сертификат
+func (r *Reloader) Reload() error {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat certificate key: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	r.mu.Unlock()
+
+	return nil
+}
+
+// GetCertificate - реализация tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// Watch - периодически проверять файлы и перезагружать сертификат при их изменении (до отмены контекста)
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+
+			// Файлы могут обновляться не одновременно: если пара ещё не согласована - попробуем на следующем тике
+			if err := r.Reload(); err != nil {
+				log.Printf("TLS certificate reload failed: %v", err)
+				continue
+			}
+
+			log.Printf("TLS certificate reloaded: %s", r.certPath)
+		}
+	}
+}
+
+// changed - проверить, изменились ли файлы с момента последней загрузки
+func (r *Reloader) changed() bool {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime)
+}
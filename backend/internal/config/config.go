@@ -0,0 +1,642 @@
+// Пакет config содержит конфигурацию сервера и её загрузку.
+//
+// Источники настроек в порядке возрастания приоритета:
+//  1. значения по умолчанию;
+//  2. файл конфигурации в формате YAML (флаг -c или переменная окружения CONFIG);
+//  3. переменные окружения. Для секретов поддерживаются варианты *_FILE с путём до файла со значением;
+//  4. флаги командной строки.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config - конфигурация сервера
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Storage    StorageConfig    `yaml:"storage"`
+	Limits     LimitsConfig     `yaml:"limits"`
+	Quota      QuotaConfig      `yaml:"quota"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+
+	// PrintConfig - вывести итоговую конфигурацию (секреты скрыты) и завершить работу
+	PrintConfig bool `yaml:"-"`
+	// Command - подкоманда с аргументами (всё, что указано после флагов). Пустая - запуск сервера
+	Command []string `yaml:"-"`
+
+	// explicit - настройки, заданные явно в файле, окружении или флагами (ключи вида storage.trash_retention)
+	explicit map[string]bool
+}
+
+// ServerConfig - настройки HTTP-сервера API
+type ServerConfig struct {
+	Address     string    `yaml:"address"`
+	EnableHTTPS bool      `yaml:"enable_https"`
+	TLS         TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig - настройки сертификатов
+type TLSConfig struct {
+	CertPath string `yaml:"cert_path"`
+	KeyPath  string `yaml:"key_path"`
+	Generate bool   `yaml:"generate"`
+	Dir      string `yaml:"dir"`
+}
+
+// DatabaseConfig - настройки подключения к базе данных
+type DatabaseConfig struct {
+	// Storage - тип базы данных: postgres, sqlite или memory (пустой - по схеме строки подключения)
+	Storage string `yaml:"storage"`
+	DSN     string `yaml:"dsn"`
+	// OwnerDSN - подключение PostgreSQL от имени владельца схемы для миграций, резервного копирования и ротации ключей.
+	// Если задано, сервер работает под ролью из DSN, которая не владеет таблицами и не может обойти политики строк
+	OwnerDSN string `yaml:"owner_dsn,omitempty"`
+}
+
+// Типы базы данных сервера
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+	StorageMemory   = "memory"
+)
+
+// Backend - тип базы данных: заданный явно или определённый по строке подключения
+// (sqlite://путь и file:путь - SQLite, memory://директория - данные в памяти, остальные - PostgreSQL)
+func (c DatabaseConfig) Backend() string {
+	if c.Storage != "" {
+		return c.Storage
+	}
+	if strings.HasPrefix(c.DSN, "sqlite:") || strings.HasPrefix(c.DSN, "file:") {
+		return StorageSQLite
+	}
+	if strings.HasPrefix(c.DSN, "memory:") {
+		return StorageMemory
+	}
+	return StoragePostgres
+}
+
+// SchemaOwnerDSN - подключение для работы со схемой: OwnerDSN, а если оно не задано - DSN
+func (c DatabaseConfig) SchemaOwnerDSN() string {
+	if c.OwnerDSN != "" {
+		return c.OwnerDSN
+	}
+	return c.DSN
+}
+
+// AuthConfig - настройки аутентификации
+type AuthConfig struct {
+	SecretKey     string        `yaml:"secret_key"`
+	TokenLifetime time.Duration `yaml:"token_lifetime"`
+	// Registration - режим регистрации: open (свободная), invite (по приглашениям) или closed (закрыта)
+	Registration string `yaml:"registration"`
+}
+
+// StorageConfig - настройки сервиса хранения
+type StorageConfig struct {
+	QueueSize int `yaml:"queue_size"`
+	// EmergencyCheckInterval - как часто проверять запросы экстренного доступа с истёкшим периодом ожидания
+	EmergencyCheckInterval time.Duration `yaml:"emergency_check_interval"`
+	// TrashRetention - сколько удалённые записи хранятся в корзине до окончательного удаления
+	TrashRetention time.Duration `yaml:"trash_retention"`
+	// TrashPurgeInterval - как часто удалять из корзины записи, срок хранения которых истёк
+	TrashPurgeInterval time.Duration `yaml:"trash_purge_interval"`
+	// HistoryMaxVersions - сколько прежних версий каждой записи хранить (0 - без ограничения)
+	HistoryMaxVersions int `yaml:"history_max_versions"`
+	// HistoryMaxAge - сколько хранить прежнюю версию записи после её замены (0 - без ограничения)
+	HistoryMaxAge time.Duration `yaml:"history_max_age"`
+	// SnapshotInterval - как часто записывать снимок состояния на диск при хранении данных в памяти
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"`
+}
+
+// LimitsConfig - ограничения на размер хранимых данных (в байтах)
+type LimitsConfig struct {
+	MaxBinarySize int64 `yaml:"max_binary_size"`
+	MaxTextSize   int64 `yaml:"max_text_size"`
+}
+
+// QuotaConfig - квота хранилища пользователя по умолчанию (0 - без ограничения).
+// Индивидуальные квоты задаёт администратор
+type QuotaConfig struct {
+	// MaxEntries - максимальное количество записей каждого типа
+	MaxEntries int `yaml:"max_entries"`
+	// MaxBytes - максимальный общий объём записей в байтах
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+// AdminConfig - настройки административного сервера
+type AdminConfig struct {
+	Address string `yaml:"address"`
+	// Token - токен API администратора (пустой - API администратора отключено)
+	Token string `yaml:"token"`
+}
+
+// TracingConfig - настройки трассировки
+type TracingConfig struct {
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+}
+
+// EncryptionConfig - шифрование хранимых полей на сервере ключами данных пользователей, зашифрованными мастер-ключом
+type EncryptionConfig struct {
+	// MasterKeyFile - файл с текущим мастер-ключом (пустой - шифрование выключено)
+	MasterKeyFile string `yaml:"master_key_file"`
+	// PreviousKeyFiles - файлы прежних мастер-ключей: нужны только для расшифровки ключей данных до их перешифрования
+	PreviousKeyFiles []string `yaml:"previous_key_files"`
+}
+
+// sqliteUnsupported - настройки возможностей, которых нет при хранении в SQLite. Заданная явно настройка
+// такой возможности - ошибка конфигурации, а не молча выключенная возможность
+var sqliteUnsupported = []struct {
+	key     string
+	feature string
+}{
+	{key: "storage.emergency_check_interval", feature: "emergency access"},
+	{key: "storage.trash_retention", feature: "trash"},
+	{key: "storage.trash_purge_interval", feature: "trash"},
+	{key: "storage.history_max_versions", feature: "version history"},
+	{key: "storage.history_max_age", feature: "version history"},
+	{key: "quota.max_entries", feature: "storage quota"},
+	{key: "quota.max_bytes", feature: "storage quota"},
+	{key: "admin.token", feature: "admin API"},
+}
+
+// Минимальная длина ключа подписи токенов
+const minSecretKeyLength = 16
+
+// Default - конфигурация по умолчанию. Секреты (строка подключения к БД и ключ токенов) значений по умолчанию не имеют
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address:     "localhost:8080",
+			EnableHTTPS: true,
+			TLS: TLSConfig{
+				CertPath: "../tls/localhost+2.pem",
+				KeyPath:  "../tls/localhost+2-key.pem",
+				Dir:      "../tls",
+			},
+		},
+		Auth: AuthConfig{
+			TokenLifetime: 3 * time.Hour,
+			Registration:  "open",
+		},
+		Storage: StorageConfig{
+			QueueSize:              256,
+			EmergencyCheckInterval: time.Minute,
+			TrashRetention:         30 * 24 * time.Hour,
+			TrashPurgeInterval:     time.Hour,
+			HistoryMaxVersions:     20,
+			HistoryMaxAge:          90 * 24 * time.Hour,
+			SnapshotInterval:       time.Minute,
+		},
+		Limits: LimitsConfig{
+			MaxBinarySize: 10 * 1024 * 1024,
+			MaxTextSize:   1 * 1024 * 1024,
+		},
+		Quota: QuotaConfig{
+			MaxEntries: 10000,
+			MaxBytes:   1024 * 1024 * 1024,
+		},
+		Admin: AdminConfig{
+			Address: "localhost:9090",
+		},
+	}
+}
+
+// setting - описание настройки, задаваемой переменной окружения и флагом
+type setting struct {
+	key    string // путь в файле конфигурации (для сообщений об ошибках)
+	env    string
+	flag   string
+	usage  string
+	isBool bool
+	secret bool // поддерживается вариант <env>_FILE
+	apply  func(c *Config, value string) error
+}
+
+// settings - все настройки, которые можно переопределить окружением и флагами
+var settings = []setting{
+	{key: "server.address", env: "SERVER_ADDRESS", flag: "a", usage: "address and port to run server", apply: setString(func(c *Config) *string { return &c.Server.Address })},
+	{key: "database.dsn", env: "DATABASE_URI", flag: "d", usage: "postgresql connection string, sqlite database path (sqlite://path) or data directory for memory storage (memory://dir)", secret: true, apply: setString(func(c *Config) *string { return &c.Database.DSN })},
+	{key: "database.owner_dsn", env: "DATABASE_OWNER_URI", flag: "do", usage: "postgresql connection string of the schema owner for migrations, backups and key rotation (the server then runs as the non-owner role from -d)", secret: true, apply: setString(func(c *Config) *string { return &c.Database.OwnerDSN })},
+	{key: "database.storage", env: "DATABASE_STORAGE", flag: "storage", usage: "database type: postgres, sqlite or memory (default: detected from the connection string)", apply: setString(func(c *Config) *string { return &c.Database.Storage })},
+	{key: "server.enable_https", env: "ENABLE_HTTPS", flag: "s", usage: "enable https", isBool: true, apply: setBool(func(c *Config) *bool { return &c.Server.EnableHTTPS })},
+	{key: "auth.secret_key", env: "AUTH_SECRET_KEY", flag: "k", usage: "secret key for token creation", secret: true, apply: setString(func(c *Config) *string { return &c.Auth.SecretKey })},
+	{key: "auth.token_lifetime", env: "TOKEN_LIFETIME", flag: "tl", usage: "lifetime of authentication tokens, e.g. 3h", apply: setDuration(func(c *Config) *time.Duration { return &c.Auth.TokenLifetime })},
+	{key: "auth.registration", env: "REGISTRATION_MODE", flag: "rm", usage: "registration mode: open, invite or closed", apply: setString(func(c *Config) *string { return &c.Auth.Registration })},
+	{key: "server.tls.cert_path", env: "TLS_CERT_PATH", flag: "cp", usage: "path to tls certificate", apply: setString(func(c *Config) *string { return &c.Server.TLS.CertPath })},
+	{key: "server.tls.key_path", env: "TLS_KEY_PATH", flag: "kp", usage: "path to tls certificate key", apply: setString(func(c *Config) *string { return &c.Server.TLS.KeyPath })},
+	{key: "server.tls.generate", env: "TLS_GENERATE", flag: "gt", usage: "generate development CA and server certificate if missing (overrides -cp and -kp)", isBool: true, apply: setBool(func(c *Config) *bool { return &c.Server.TLS.Generate })},
+	{key: "server.tls.dir", env: "TLS_DIR", flag: "td", usage: "directory for generated tls certificates", apply: setString(func(c *Config) *string { return &c.Server.TLS.Dir })},
+	{key: "storage.queue_size", env: "QUEUE_SIZE", flag: "qs", usage: "capacity of storage service task queue", apply: setInt(func(c *Config) *int { return &c.Storage.QueueSize })},
+	{key: "storage.emergency_check_interval", env: "EMERGENCY_CHECK_INTERVAL", flag: "ei", usage: "how often to grant emergency access requests whose waiting period has elapsed, e.g. 1m", apply: setDuration(func(c *Config) *time.Duration { return &c.Storage.EmergencyCheckInterval })},
+	{key: "storage.trash_retention", env: "TRASH_RETENTION", flag: "tr", usage: "how long deleted entries stay in trash before permanent deletion, e.g. 720h", apply: setDuration(func(c *Config) *time.Duration { return &c.Storage.TrashRetention })},
+	{key: "storage.trash_purge_interval", env: "TRASH_PURGE_INTERVAL", flag: "tp", usage: "how often to purge trash entries whose retention has elapsed, e.g. 1h", apply: setDuration(func(c *Config) *time.Duration { return &c.Storage.TrashPurgeInterval })},
+	{key: "storage.history_max_versions", env: "HISTORY_MAX_VERSIONS", flag: "hv", usage: "max number of previous versions kept per entry (0 for unlimited)", apply: setInt(func(c *Config) *int { return &c.Storage.HistoryMaxVersions })},
+	{key: "storage.history_max_age", env: "HISTORY_MAX_AGE", flag: "ha", usage: "how long previous versions of entries are kept, e.g. 2160h (0 for unlimited)", apply: setDuration(func(c *Config) *time.Duration { return &c.Storage.HistoryMaxAge })},
+	{key: "storage.snapshot_interval", env: "SNAPSHOT_INTERVAL", flag: "si", usage: "how often to write a snapshot of memory storage to its data directory, e.g. 1m", apply: setDuration(func(c *Config) *time.Duration { return &c.Storage.SnapshotInterval })},
+	{key: "limits.max_binary_size", env: "MAX_BINARY_SIZE", flag: "mb", usage: "max size of binary data in bytes", apply: setInt64(func(c *Config) *int64 { return &c.Limits.MaxBinarySize })},
+	{key: "limits.max_text_size", env: "MAX_TEXT_SIZE", flag: "mt", usage: "max size of text data in bytes", apply: setInt64(func(c *Config) *int64 { return &c.Limits.MaxTextSize })},
+	{key: "quota.max_entries", env: "QUOTA_MAX_ENTRIES", flag: "qe", usage: "default max number of entries of each type per user (0 for unlimited)", apply: setInt(func(c *Config) *int { return &c.Quota.MaxEntries })},
+	{key: "quota.max_bytes", env: "QUOTA_MAX_BYTES", flag: "qb", usage: "default max total size of user entries in bytes (0 for unlimited)", apply: setInt64(func(c *Config) *int64 { return &c.Quota.MaxBytes })},
+	{key: "admin.address", env: "ADMIN_ADDRESS", flag: "aa", usage: "address of admin server with health checks, metrics and pprof (empty to disable)", apply: setString(func(c *Config) *string { return &c.Admin.Address })},
+	{key: "admin.token", env: "ADMIN_TOKEN", flag: "at", usage: "bearer token of admin API on the admin server (empty to disable)", secret: true, apply: setString(func(c *Config) *string { return &c.Admin.Token })},
+	{key: "encryption.master_key_file", env: "ENCRYPTION_MASTER_KEY_FILE", flag: "ek", usage: "path to master key file for server-side encryption of stored fields (empty to disable)", apply: setString(func(c *Config) *string { return &c.Encryption.MasterKeyFile })},
+	{key: "encryption.previous_key_files", env: "ENCRYPTION_PREVIOUS_KEY_FILES", flag: "ep", usage: "comma-separated previous master key files, used only to decrypt data keys until they are rotated", apply: setList(func(c *Config) *[]string { return &c.Encryption.PreviousKeyFiles })},
+	{key: "tracing.otlp_endpoint", env: "OTEL_EXPORTER_OTLP_ENDPOINT", flag: "ot", usage: "OpenTelemetry OTLP/HTTP endpoint for traces, e.g. http://localhost:4318 (empty to disable)", apply: setString(func(c *Config) *string { return &c.Tracing.OTLPEndpoint })},
+}
+
+// Load - загрузить конфигурацию из всех источников и проверить её.
+// args - аргументы командной строки без имени программы, lookupEnv - функция чтения окружения (os.LookupEnv).
+// Если не прошла только проверка, вместе с ошибкой возвращается загруженная конфигурация (её можно вывести для диагностики)
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	// Флаги разбираем первыми (путь к файлу конфигурации задаётся флагом), но применяем последними
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var configPath string
+	fs.StringVar(&configPath, "c", "", "path to YAML configuration file")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print effective configuration with secrets redacted and exit")
+
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+
+	for _, s := range settings {
+		s := s
+		record := func(value string) error {
+			flagValues = append(flagValues, flagValue{setting: s, value: value})
+			return nil
+		}
+		if s.isBool {
+			fs.BoolFunc(s.flag, s.usage, record)
+		} else {
+			fs.Func(s.flag, s.usage, record)
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("invalid command line: %w", err)
+	}
+	cfg.Command = fs.Args()
+
+	// Файл конфигурации
+	if configPath == "" {
+		configPath, _ = lookupEnv("CONFIG")
+	}
+	if configPath != "" {
+		if err := loadFile(cfg, configPath); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.explicit == nil {
+		cfg.explicit = make(map[string]bool)
+	}
+
+	// Переменные окружения
+	for _, s := range settings {
+		value, ok, err := lookupSetting(s, lookupEnv)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		// Логическую переменную окружения достаточно объявить: значение, которое не разбирается как bool
+		// (например, ENABLE_HTTPS=yes), включает настройку, как и раньше. Отключают её false, 0 и т.п.
+		if s.isBool {
+			if _, err := strconv.ParseBool(value); err != nil {
+				value = ""
+			}
+		}
+		if err := s.apply(cfg, value); err != nil {
+			return nil, fmt.Errorf("invalid %s (%s): %w", s.env, s.key, err)
+		}
+		cfg.explicit[s.key] = true
+	}
+
+	// Флаги
+	for _, fv := range flagValues {
+		if err := fv.setting.apply(cfg, fv.value); err != nil {
+			return nil, fmt.Errorf("invalid flag -%s (%s): %w", fv.setting.flag, fv.setting.key, err)
+		}
+		cfg.explicit[fv.setting.key] = true
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Usage - вывести описание флагов
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Flags (override environment variables, which override the configuration file):")
+	fmt.Fprintln(w, "  -c string\n    \tpath to YAML configuration file (env CONFIG)")
+	fmt.Fprintln(w, "  -print-config\n    \tprint effective configuration with secrets redacted and exit")
+	for _, s := range settings {
+		env := s.env
+		if s.secret {
+			env += ", " + s.env + "_FILE"
+		}
+		fmt.Fprintf(w, "  -%s\n    \t%s (env %s, file %s)\n", s.flag, s.usage, env, s.key)
+	}
+}
+
+// loadFile - прочитать настройки из YAML-файла поверх текущих значений
+func loadFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true) // опечатка в имени настройки - ошибка, а не молча проигнорированное значение
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	// Какие настройки заданы в файле, видно только по его ключам: значение может совпадать со значением по умолчанию
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	cfg.explicit = make(map[string]bool)
+	collectKeys(&root, "", cfg.explicit)
+
+	return nil
+}
+
+// collectKeys - добавить в keys пути всех ключей YAML-документа (например, storage.trash_retention)
+func collectKeys(node *yaml.Node, prefix string, keys map[string]bool) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			collectKeys(child, prefix, keys)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			keys[key] = true
+			collectKeys(node.Content[i+1], key, keys)
+		}
+	}
+}
+
+// lookupSetting - получить значение настройки из окружения (для секретов - также из файла <env>_FILE)
+func lookupSetting(s setting, lookupEnv func(string) (string, bool)) (string, bool, error) {
+	value, ok := lookupEnv(s.env)
+	if !s.secret {
+		return value, ok, nil
+	}
+
+	filePath, hasFile := lookupEnv(s.env + "_FILE")
+	if !hasFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("both %s and %s_FILE are set", s.env, s.env)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s_FILE: %w", s.env, err)
+	}
+
+	// Перевод строки в конце файла не является частью секрета
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+// Validate - проверить конфигурацию. Возвращает все найденные ошибки сразу
+func (c *Config) Validate() error {
+	var errs []error
+
+	if err := validateAddress(c.Server.Address); err != nil {
+		errs = append(errs, fmt.Errorf("server.address: %w", err))
+	}
+
+	if c.Server.EnableHTTPS && !c.Server.TLS.Generate {
+		if c.Server.TLS.CertPath == "" || c.Server.TLS.KeyPath == "" {
+			errs = append(errs, errors.New("server.tls: cert_path and key_path are required when https is enabled without certificate generation"))
+		}
+	}
+	if c.Server.TLS.Generate && c.Server.TLS.Dir == "" {
+		errs = append(errs, errors.New("server.tls.dir: required for certificate generation"))
+	}
+
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn: required (set DATABASE_URI, DATABASE_URI_FILE, -d or database.dsn in the config file)"))
+	}
+	switch c.Database.Storage {
+	case "", StoragePostgres, StorageSQLite, StorageMemory:
+	default:
+		errs = append(errs, fmt.Errorf("database.storage: unknown database type %q (use postgres, sqlite or memory)", c.Database.Storage))
+	}
+	if c.Database.OwnerDSN != "" && c.Database.Backend() != StoragePostgres {
+		errs = append(errs, errors.New("database.owner_dsn: only supported by postgres storage"))
+	}
+
+	if len(c.Auth.SecretKey) < minSecretKeyLength {
+		errs = append(errs, fmt.Errorf("auth.secret_key: at least %d characters required (set AUTH_SECRET_KEY, AUTH_SECRET_KEY_FILE, -k or auth.secret_key in the config file)", minSecretKeyLength))
+	}
+	if c.Auth.TokenLifetime <= 0 {
+		errs = append(errs, errors.New("auth.token_lifetime: must be positive"))
+	}
+	switch c.Auth.Registration {
+	case "open", "invite", "closed":
+	default:
+		errs = append(errs, fmt.Errorf("auth.registration: unknown mode %q (use open, invite or closed)", c.Auth.Registration))
+	}
+	// Приглашения хранятся в PostgreSQL и в памяти, но не в SQLite
+	if c.Auth.Registration == "invite" && c.Database.Backend() == StorageSQLite {
+		errs = append(errs, errors.New("auth.registration: invite mode is not supported by sqlite storage"))
+	}
+	if c.Database.Backend() == StorageSQLite {
+		for _, unsupported := range sqliteUnsupported {
+			if c.explicit[unsupported.key] {
+				errs = append(errs, fmt.Errorf("%s: %s is not supported by sqlite storage (use postgres or memory storage)", unsupported.key, unsupported.feature))
+			}
+		}
+	}
+
+	if c.Storage.QueueSize <= 0 {
+		errs = append(errs, errors.New("storage.queue_size: must be positive"))
+	}
+	if c.Storage.EmergencyCheckInterval <= 0 {
+		errs = append(errs, errors.New("storage.emergency_check_interval: must be positive"))
+	}
+	if c.Storage.TrashRetention <= 0 {
+		errs = append(errs, errors.New("storage.trash_retention: must be positive"))
+	}
+	if c.Storage.TrashPurgeInterval <= 0 {
+		errs = append(errs, errors.New("storage.trash_purge_interval: must be positive"))
+	}
+	if c.Storage.HistoryMaxVersions < 0 {
+		errs = append(errs, errors.New("storage.history_max_versions: must not be negative"))
+	}
+	if c.Storage.HistoryMaxAge < 0 {
+		errs = append(errs, errors.New("storage.history_max_age: must not be negative"))
+	}
+	if c.Storage.SnapshotInterval <= 0 {
+		errs = append(errs, errors.New("storage.snapshot_interval: must be positive"))
+	}
+
+	if c.Limits.MaxBinarySize <= 0 {
+		errs = append(errs, errors.New("limits.max_binary_size: must be positive"))
+	}
+	if c.Limits.MaxTextSize <= 0 {
+		errs = append(errs, errors.New("limits.max_text_size: must be positive"))
+	}
+	if c.Quota.MaxEntries < 0 {
+		errs = append(errs, errors.New("quota.max_entries: must not be negative"))
+	}
+	if c.Quota.MaxBytes < 0 {
+		errs = append(errs, errors.New("quota.max_bytes: must not be negative"))
+	}
+
+	if c.Admin.Address != "" {
+		if err := validateAddress(c.Admin.Address); err != nil {
+			errs = append(errs, fmt.Errorf("admin.address: %w", err))
+		} else if c.Admin.Address == c.Server.Address {
+			errs = append(errs, errors.New("admin.address: must differ from server.address"))
+		}
+	}
+	if c.Admin.Token != "" {
+		if c.Admin.Address == "" {
+			errs = append(errs, errors.New("admin.token: admin API requires admin.address"))
+		}
+		if len(c.Admin.Token) < minSecretKeyLength {
+			errs = append(errs, fmt.Errorf("admin.token: at least %d characters required", minSecretKeyLength))
+		}
+	}
+
+	if len(c.Encryption.PreviousKeyFiles) > 0 && c.Encryption.MasterKeyFile == "" {
+		errs = append(errs, errors.New("encryption.previous_key_files: requires encryption.master_key_file"))
+	}
+
+	if c.Tracing.OTLPEndpoint != "" {
+		endpoint, err := url.Parse(c.Tracing.OTLPEndpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			errs = append(errs, errors.New("tracing.otlp_endpoint: http(s) URL expected"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// validateAddress - проверить адрес вида host:port
+func validateAddress(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("host:port expected: %w", err)
+	}
+
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+// setString - применить строковое значение
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+// setBool - применить логическое значение. Пустое значение означает true (достаточно объявить переменную окружения)
+func setBool(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		if value == "" {
+			*field(c) = true
+			return nil
+		}
+
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+// setInt - применить целочисленное значение
+func setInt(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+// setInt64 - применить целочисленное значение (int64)
+func setInt64(field func(c *Config) *int64) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+// setDuration - применить длительность (например, 90m или 3h)
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+// setList - применить список значений через запятую (пустые элементы пропускаются)
+func setList(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
@@ -0,0 +1,303 @@
+// config_test - тесты загрузки конфигурации
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Ключ, удовлетворяющий требованию к длине
+const testSecretKey = "0123456789abcdef-test"
+
+// env - окружение для тестов
+type env map[string]string
+
+// lookup - реализация os.LookupEnv поверх map
+func (e env) lookup(key string) (string, bool) {
+	value, ok := e[key]
+	return value, ok
+}
+
+// writeFile - создать файл во временной директории
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Run("Секреты обязательны", func(t *testing.T) {
+		_, err := config.Load(nil, env{}.lookup)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "database.dsn")
+		assert.Contains(t, err.Error(), "auth.secret_key")
+	})
+
+	t.Run("Значения по умолчанию", func(t *testing.T) {
+		cfg, err := config.Load(nil, env{"DATABASE_URI": "host=db", "AUTH_SECRET_KEY": testSecretKey}.lookup)
+		require.NoError(t, err)
+
+		assert.Equal(t, "localhost:8080", cfg.Server.Address)
+		assert.True(t, cfg.Server.EnableHTTPS)
+		assert.Equal(t, 3*time.Hour, cfg.Auth.TokenLifetime)
+		assert.Equal(t, "open", cfg.Auth.Registration)
+		assert.Equal(t, 256, cfg.Storage.QueueSize)
+		assert.Equal(t, time.Minute, cfg.Storage.EmergencyCheckInterval)
+		assert.Equal(t, 720*time.Hour, cfg.Storage.TrashRetention)
+		assert.Equal(t, time.Hour, cfg.Storage.TrashPurgeInterval)
+		assert.Equal(t, 20, cfg.Storage.HistoryMaxVersions)
+		assert.Equal(t, 2160*time.Hour, cfg.Storage.HistoryMaxAge)
+		assert.Equal(t, int64(10*1024*1024), cfg.Limits.MaxBinarySize)
+		assert.Equal(t, int64(1024*1024), cfg.Limits.MaxTextSize)
+		assert.Equal(t, 10000, cfg.Quota.MaxEntries)
+		assert.Equal(t, int64(1024*1024*1024), cfg.Quota.MaxBytes)
+		assert.Equal(t, "localhost:9090", cfg.Admin.Address)
+	})
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	configPath := writeFile(t, "config.yaml", `
+server:
+  address: "file:1000"
+  enable_https: false
+database:
+  dsn: "host=file"
+auth:
+  secret_key: "file-secret-key-0123"
+  token_lifetime: 1h
+storage:
+  queue_size: 10
+limits:
+  max_text_size: 2048
+`)
+
+	t.Run("Файл поверх значений по умолчанию", func(t *testing.T) {
+		cfg, err := config.Load([]string{"-c", configPath}, env{}.lookup)
+		require.NoError(t, err)
+
+		assert.Equal(t, "file:1000", cfg.Server.Address)
+		assert.False(t, cfg.Server.EnableHTTPS)
+		assert.Equal(t, time.Hour, cfg.Auth.TokenLifetime)
+		assert.Equal(t, 10, cfg.Storage.QueueSize)
+		assert.Equal(t, int64(2048), cfg.Limits.MaxTextSize)
+		// Не указанное в файле остаётся по умолчанию
+		assert.Equal(t, int64(10*1024*1024), cfg.Limits.MaxBinarySize)
+	})
+
+	t.Run("Окружение поверх файла, флаги поверх окружения", func(t *testing.T) {
+		environment := env{
+			"CONFIG":         configPath,
+			"SERVER_ADDRESS": "env:2000",
+			"QUEUE_SIZE":     "20",
+			"TOKEN_LIFETIME": "30m",
+		}
+
+		cfg, err := config.Load([]string{"-a", "flag:3000", "-tl", "15m"}, environment.lookup)
+		require.NoError(t, err)
+
+		assert.Equal(t, "flag:3000", cfg.Server.Address)
+		assert.Equal(t, 20, cfg.Storage.QueueSize)
+		assert.Equal(t, 15*time.Minute, cfg.Auth.TokenLifetime)
+		assert.Equal(t, "host=file", cfg.Database.DSN)
+	})
+
+	t.Run("Логическая переменная окружения без значения включает настройку", func(t *testing.T) {
+		cfg, err := config.Load(nil, env{"CONFIG": configPath, "ENABLE_HTTPS": ""}.lookup)
+		require.NoError(t, err)
+		assert.True(t, cfg.Server.EnableHTTPS)
+
+		cfg, err = config.Load([]string{"-s=false"}, env{"CONFIG": configPath, "ENABLE_HTTPS": ""}.lookup)
+		require.NoError(t, err)
+		assert.False(t, cfg.Server.EnableHTTPS)
+
+		cfg, err = config.Load(nil, env{"CONFIG": configPath, "ENABLE_HTTPS": "yes"}.lookup)
+		require.NoError(t, err)
+		assert.True(t, cfg.Server.EnableHTTPS)
+
+		cfg, err = config.Load(nil, env{"CONFIG": configPath, "ENABLE_HTTPS": "false"}.lookup)
+		require.NoError(t, err)
+		assert.False(t, cfg.Server.EnableHTTPS)
+	})
+
+	t.Run("Список через запятую", func(t *testing.T) {
+		environment := env{"CONFIG": configPath, "ENCRYPTION_MASTER_KEY_FILE": "master.key", "ENCRYPTION_PREVIOUS_KEY_FILES": "old.key, older.key"}
+		cfg, err := config.Load(nil, environment.lookup)
+		require.NoError(t, err)
+		assert.Equal(t, "master.key", cfg.Encryption.MasterKeyFile)
+		assert.Equal(t, []string{"old.key", "older.key"}, cfg.Encryption.PreviousKeyFiles)
+	})
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	dsnPath := writeFile(t, "dsn", "host=db password=from-file\n")
+	keyPath := writeFile(t, "key", testSecretKey+"\n")
+
+	t.Run("Секреты из файлов", func(t *testing.T) {
+		cfg, err := config.Load(nil, env{"DATABASE_URI_FILE": dsnPath, "AUTH_SECRET_KEY_FILE": keyPath}.lookup)
+		require.NoError(t, err)
+
+		assert.Equal(t, "host=db password=from-file", cfg.Database.DSN)
+		assert.Equal(t, testSecretKey, cfg.Auth.SecretKey)
+	})
+
+	t.Run("Одновременно значение и файл", func(t *testing.T) {
+		_, err := config.Load(nil, env{"AUTH_SECRET_KEY": testSecretKey, "AUTH_SECRET_KEY_FILE": keyPath}.lookup)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "both AUTH_SECRET_KEY and AUTH_SECRET_KEY_FILE are set")
+	})
+
+	t.Run("Файл не найден", func(t *testing.T) {
+		_, err := config.Load(nil, env{"AUTH_SECRET_KEY_FILE": filepath.Join(t.TempDir(), "missing")}.lookup)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "AUTH_SECRET_KEY_FILE")
+	})
+}
+
+func TestLoad_Validation(t *testing.T) {
+	base := env{"DATABASE_URI": "host=db", "AUTH_SECRET_KEY": testSecretKey}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "Некорректный адрес", args: []string{"-a", "localhost"}, wantErr: "server.address"},
+		{name: "Некорректный порт", args: []string{"-a", "localhost:99999"}, wantErr: "server.address"},
+		{name: "Короткий ключ", args: []string{"-k", "short"}, wantErr: "auth.secret_key"},
+		{name: "Неизвестный режим регистрации", args: []string{"-rm", "invite-only"}, wantErr: "auth.registration"},
+		{name: "Неизвестный тип базы данных", args: []string{"-storage", "mysql"}, wantErr: "database.storage"},
+		{name: "Приглашения без PostgreSQL", args: []string{"-d", "sqlite://gophkeeper.db", "-rm", "invite"}, wantErr: "auth.registration"},
+		{name: "Корзина в SQLite", args: []string{"-d", "sqlite://gophkeeper.db", "-tr", "24h"}, wantErr: "storage.trash_retention: trash is not supported by sqlite storage"},
+		{name: "История версий в SQLite", args: []string{"-d", "sqlite://gophkeeper.db", "-hv", "0"}, wantErr: "storage.history_max_versions"},
+		{name: "Квота в SQLite", args: []string{"-d", "sqlite://gophkeeper.db", "-qe", "100"}, wantErr: "quota.max_entries"},
+		{name: "Экстренный доступ в SQLite", args: []string{"-d", "sqlite://gophkeeper.db", "-ei", "1m"}, wantErr: "storage.emergency_check_interval"},
+		{name: "API администратора в SQLite", args: []string{"-d", "sqlite://gophkeeper.db", "-at", testSecretKey}, wantErr: "admin.token: admin API"},
+		{name: "Владелец схемы вне PostgreSQL", args: []string{"-d", "memory://data", "-do", "host=db user=owner"}, wantErr: "database.owner_dsn"},
+		{name: "Нулевой срок хранения корзины", args: []string{"-tr", "0s"}, wantErr: "storage.trash_retention"},
+		{name: "Отрицательное число версий в истории", args: []string{"-hv", "-1"}, wantErr: "storage.history_max_versions"},
+		{name: "Нулевой интервал снимков", args: []string{"-si", "0s"}, wantErr: "storage.snapshot_interval"},
+		{name: "Нулевая очередь", args: []string{"-qs", "0"}, wantErr: "storage.queue_size"},
+		{name: "Отрицательный лимит", args: []string{"-mb", "-1"}, wantErr: "limits.max_binary_size"},
+		{name: "Отрицательная квота", args: []string{"-qb", "-1"}, wantErr: "quota.max_bytes"},
+		{name: "Админ-сервер на адресе API", args: []string{"-aa", "localhost:8080"}, wantErr: "admin.address"},
+		{name: "Короткий токен администратора", args: []string{"-at", "short"}, wantErr: "admin.token"},
+		{name: "Токен администратора без админ-сервера", args: []string{"-aa", "", "-at", testSecretKey}, wantErr: "admin.token"},
+		{name: "Некорректный адрес трассировки", args: []string{"-ot", "localhost:4318"}, wantErr: "tracing.otlp_endpoint"},
+		{name: "HTTPS без сертификата", args: []string{"-cp", ""}, wantErr: "server.tls"},
+		{name: "Прежние мастер-ключи без текущего", args: []string{"-ep", "old.key"}, wantErr: "encryption.previous_key_files"},
+		{name: "Нечисловое значение", args: []string{"-qs", "many"}, wantErr: "-qs"},
+		{name: "Неизвестный флаг", args: []string{"-unknown"}, wantErr: "invalid command line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Load(tt.args, base.lookup)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("Неизвестная настройка в файле", func(t *testing.T) {
+		configPath := writeFile(t, "config.yaml", "server:\n  adress: \"typo:1\"\n")
+		_, err := config.Load([]string{"-c", configPath}, base.lookup)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "adress")
+	})
+
+	t.Run("Настройка из файла, не поддерживаемая SQLite", func(t *testing.T) {
+		configPath := writeFile(t, "config.yaml", "database:\n  dsn: sqlite://gophkeeper.db\nquota:\n  max_bytes: 1073741824\n")
+		_, err := config.Load([]string{"-c", configPath}, env{"AUTH_SECRET_KEY": testSecretKey}.lookup)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "quota.max_bytes: storage quota is not supported by sqlite storage")
+	})
+
+	t.Run("SQLite с настройками по умолчанию", func(t *testing.T) {
+		_, err := config.Load([]string{"-d", "sqlite://gophkeeper.db"}, env{"AUTH_SECRET_KEY": testSecretKey}.lookup)
+		assert.NoError(t, err)
+
+		// В PostgreSQL те же настройки допустимы
+		_, err = config.Load([]string{"-tr", "24h", "-qe", "100"}, base.lookup)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Отключение HTTPS снимает требование сертификата", func(t *testing.T) {
+		_, err := config.Load([]string{"-s=false", "-cp", ""}, base.lookup)
+		assert.NoError(t, err)
+	})
+}
+
+func TestDatabaseConfig_Backend(t *testing.T) {
+	tests := []struct {
+		name     string
+		database config.DatabaseConfig
+		want     string
+	}{
+		{name: "Строка подключения PostgreSQL", database: config.DatabaseConfig{DSN: "host=db dbname=gophkeeperdb"}, want: config.StoragePostgres},
+		{name: "URL PostgreSQL", database: config.DatabaseConfig{DSN: "postgres://user@db/gophkeeperdb"}, want: config.StoragePostgres},
+		{name: "Схема sqlite", database: config.DatabaseConfig{DSN: "sqlite://data/gophkeeper.db"}, want: config.StorageSQLite},
+		{name: "Схема file", database: config.DatabaseConfig{DSN: "file:gophkeeper.db?cache=shared"}, want: config.StorageSQLite},
+		{name: "Схема memory", database: config.DatabaseConfig{DSN: "memory://data"}, want: config.StorageMemory},
+		{name: "Тип задан явно", database: config.DatabaseConfig{Storage: config.StorageSQLite, DSN: "gophkeeper.db"}, want: config.StorageSQLite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.database.Backend())
+		})
+	}
+}
+
+func TestLoad_Command(t *testing.T) {
+	t.Run("Аргументы после флагов - подкоманда", func(t *testing.T) {
+		cfg, err := config.Load([]string{"-d", "host=db", "migrate", "down", "2"}, env{}.lookup)
+		require.Error(t, err, "подкоманде достаточно части настроек, проверка остаётся прежней")
+		require.NotNil(t, cfg)
+		assert.Equal(t, []string{"migrate", "down", "2"}, cfg.Command)
+		assert.Equal(t, "host=db", cfg.Database.DSN)
+	})
+
+	t.Run("Без подкоманды", func(t *testing.T) {
+		cfg, err := config.Load(nil, env{"DATABASE_URI": "host=db", "AUTH_SECRET_KEY": testSecretKey}.lookup)
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Command)
+	})
+}
+
+func TestConfig_Print(t *testing.T) {
+	environment := env{
+		"DATABASE_URI":    "host=db user=gophkeeper password=top-secret dbname=gophkeeperdb",
+		"AUTH_SECRET_KEY": testSecretKey,
+		"ADMIN_TOKEN":     "admin-token-secret",
+
+		"DATABASE_OWNER_URI": "host=db user=gophkeeper_owner password=owner-secret dbname=gophkeeperdb",
+	}
+
+	cfg, err := config.Load([]string{"--print-config"}, environment.lookup)
+	require.NoError(t, err)
+	require.True(t, cfg.PrintConfig)
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+
+	assert.NotContains(t, out.String(), "top-secret")
+	assert.NotContains(t, out.String(), testSecretKey)
+	assert.NotContains(t, out.String(), "admin-token-secret")
+	assert.NotContains(t, out.String(), "owner-secret")
+	assert.Contains(t, out.String(), "password=REDACTED")
+	assert.Contains(t, out.String(), "host=db")
+	assert.Contains(t, out.String(), "queue_size: 256")
+
+	t.Run("Строка подключения в формате URL", func(t *testing.T) {
+		cfg.Database.DSN = "postgres://gophkeeper:top-secret@db:5432/gophkeeperdb"
+		assert.Equal(t, "postgres://gophkeeper:REDACTED@db:5432/gophkeeperdb", cfg.Redacted().Database.DSN)
+		// Исходная конфигурация не меняется
+		assert.Contains(t, cfg.Database.DSN, "top-secret")
+	})
+}
@@ -9,6 +9,11 @@ type contextKey int
 // Кастомные типы ключей
 const (
 	userIDKey contextKey = iota
+	sessionIDKey
+	deviceIDKey
+	clientIPKey
+	requestIDKey
+	adminKey
 )
 
 // WithUserID - добавить в контекст информацию о пользователе
@@ -25,3 +30,60 @@ func GetUserID(ctx context.Context) string {
 
 	return userID.(string)
 }
+
+// WithSessionID - добавить в контекст идентификатор сессии (выдаётся вместе с токеном)
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// GetSessionID - извлечь из контекста идентификатор сессии
+func GetSessionID(ctx context.Context) string {
+	return getString(ctx, sessionIDKey)
+}
+
+// WithDeviceID - добавить в контекст идентификатор устройства клиента
+func WithDeviceID(ctx context.Context, deviceID string) context.Context {
+	return context.WithValue(ctx, deviceIDKey, deviceID)
+}
+
+// GetDeviceID - извлечь из контекста идентификатор устройства клиента
+func GetDeviceID(ctx context.Context) string {
+	return getString(ctx, deviceIDKey)
+}
+
+// WithClientIP - добавить в контекст IP-адрес клиента
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// GetClientIP - извлечь из контекста IP-адрес клиента
+func GetClientIP(ctx context.Context) string {
+	return getString(ctx, clientIPKey)
+}
+
+// WithRequestID - добавить в контекст идентификатор запроса
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// GetRequestID - извлечь из контекста идентификатор запроса
+func GetRequestID(ctx context.Context) string {
+	return getString(ctx, requestIDKey)
+}
+
+// WithAdmin - отметить в контексте, что запрос выполняет администратор
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey, true)
+}
+
+// IsAdmin - запрос выполняет администратор
+func IsAdmin(ctx context.Context) bool {
+	isAdmin, _ := ctx.Value(adminKey).(bool)
+	return isAdmin
+}
+
+// getString - извлечь из контекста строковое значение (пустая строка, если значения нет)
+func getString(ctx context.Context, key contextKey) string {
+	value, _ := ctx.Value(key).(string)
+	return value
+}
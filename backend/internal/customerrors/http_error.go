@@ -18,6 +18,11 @@ func (err *HTTPError) Error() string {
 	return fmt.Sprintf("%v", err.Err)
 }
 
+// Unwrap - исходная ошибка (для errors.Is и errors.As)
+func (err *HTTPError) Unwrap() error {
+	return err.Err
+}
+
 // NewHTTPError - создать HTTP-ошибку
 func NewHTTPError(err error, code int) error {
 	return &HTTPError{
@@ -74,6 +79,14 @@ func NewForbiddenError(err error) error {
 	}
 }
 
+// NewNotImplementedError - создать ошибку с кодом 501
+func NewNotImplementedError(err error) error {
+	return &HTTPError{
+		Code: http.StatusNotImplemented,
+		Err:  err,
+	}
+}
+
 var (
 	//AlreadyExistsError - entity already exists
 	AlreadyExistsError = NewAlreadyExistsError(errors.New("entity already exists"))
@@ -0,0 +1,32 @@
+package customerrors
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// QuotaError - превышение квоты хранилища пользователя
+type QuotaError struct {
+	Resource  string `json:"resource"`  // binaries, cards, credentials, texts или bytes
+	Limit     int64  `json:"limit"`     // значение квоты
+	Used      int64  `json:"used"`      // текущее использование
+	Requested int64  `json:"requested"` // сколько добавляет операция
+}
+
+// Error - Реализация интерфейса error
+func (err *QuotaError) Error() string {
+	return fmt.Sprintf("storage quota exceeded: %s %d of %d used, %d requested", err.Resource, err.Used, err.Limit, err.Requested)
+}
+
+// NewQuotaError - создать ошибку превышения квоты с кодом 403. Исходная QuotaError доступна через errors.As
+func NewQuotaError(resource string, limit, used, requested int64) error {
+	return &HTTPError{
+		Code: http.StatusForbidden,
+		Err: &QuotaError{
+			Resource:  resource,
+			Limit:     limit,
+			Used:      used,
+			Requested: requested,
+		},
+	}
+}
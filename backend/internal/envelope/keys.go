@@ -0,0 +1,198 @@
+// Пакет envelope - шифрование хранимых полей на сервере (envelope encryption).
+// Поля записей и хэши паролей шифруются ключом данных своего пользователя, а ключи данных хранятся в базе
+// зашифрованными мастер-ключом. Мастер-ключ в базу не попадает: его держит менеджер ключей (файл на сервере или KMS)
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyManager - менеджер мастер-ключа: шифрует и расшифровывает ключи данных пользователей.
+// Локальная реализация - LocalKeyManager; внешний KMS подключается реализацией этого интерфейса
+type KeyManager interface {
+	// WrapKey - зашифровать ключ данных пользователя login текущим мастер-ключом
+	WrapKey(ctx context.Context, login string, key []byte) ([]byte, error)
+	// UnwrapKey - расшифровать ключ данных пользователя login. Ключ, зашифрованный для другого пользователя, не расшифровывается
+	UnwrapKey(ctx context.Context, login string, wrapped []byte) ([]byte, error)
+}
+
+// masterKeySize - длина мастер-ключа (AES-256)
+const masterKeySize = 32
+
+// keyIDSize - длина идентификатора мастер-ключа в зашифрованном ключе данных
+const keyIDSize = 8
+
+// ErrUnknownMasterKey - ключ данных зашифрован мастер-ключом, которого нет среди загруженных
+var ErrUnknownMasterKey = errors.New("data key is wrapped with an unknown master key")
+
+// LocalKeyManager - менеджер ключей с мастер-ключами в памяти процесса (загружаются из файлов).
+// Ключи данных шифруются текущим мастер-ключом; прежние нужны только для расшифровки, пока ключи данных не перешифрованы
+type LocalKeyManager struct {
+	current  masterKey
+	previous []masterKey
+}
+
+// masterKey - мастер-ключ и его идентификатор (начало SHA-256 ключа)
+type masterKey struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// NewLocalKeyManager - менеджер ключей с текущим мастер-ключом current и прежними previous (по 32 байта)
+func NewLocalKeyManager(current []byte, previous ...[]byte) (*LocalKeyManager, error) {
+	currentKey, err := newMasterKey(current)
+	if err != nil {
+		return nil, err
+	}
+
+	manager := &LocalKeyManager{current: currentKey}
+	for _, key := range previous {
+		previousKey, err := newMasterKey(key)
+		if err != nil {
+			return nil, err
+		}
+		manager.previous = append(manager.previous, previousKey)
+	}
+
+	return manager, nil
+}
+
+// LoadLocalKeyManager - менеджер ключей с мастер-ключами из файлов: path - текущий, previousPaths - прежние
+func LoadLocalKeyManager(path string, previousPaths []string) (*LocalKeyManager, error) {
+	current, err := ReadKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make([][]byte, 0, len(previousPaths))
+	for _, previousPath := range previousPaths {
+		key, err := ReadKeyFile(previousPath)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+
+	return NewLocalKeyManager(current, previous...)
+}
+
+// newMasterKey - подготовить мастер-ключ к использованию
+func newMasterKey(key []byte) (masterKey, error) {
+	if len(key) != masterKeySize {
+		return masterKey{}, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(key))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return masterKey{}, err
+	}
+
+	sum := sha256.Sum256(key)
+	return masterKey{id: sum[:keyIDSize], aead: aead}, nil
+}
+
+// WrapKey - зашифровать ключ данных текущим мастер-ключом. Результат: идентификатор мастер-ключа, nonce и шифротекст
+func (m *LocalKeyManager) WrapKey(ctx context.Context, login string, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, m.current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	wrapped := append(append([]byte{}, m.current.id...), nonce...)
+	return m.current.aead.Seal(wrapped, nonce, key, wrapAAD(login)), nil
+}
+
+// UnwrapKey - расшифровать ключ данных мастер-ключом, которым он зашифрован (текущим или одним из прежних)
+func (m *LocalKeyManager) UnwrapKey(ctx context.Context, login string, wrapped []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	nonceSize := m.current.aead.NonceSize()
+	if len(wrapped) < keyIDSize+nonceSize {
+		return nil, errors.New("wrapped data key is too short")
+	}
+	id, nonce, ciphertext := wrapped[:keyIDSize], wrapped[keyIDSize:keyIDSize+nonceSize], wrapped[keyIDSize+nonceSize:]
+
+	for _, master := range append([]masterKey{m.current}, m.previous...) {
+		if !bytes.Equal(master.id, id) {
+			continue
+		}
+
+		key, err := master.aead.Open(nil, nonce, ciphertext, wrapAAD(login))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key of %s: %w", login, err)
+		}
+		return key, nil
+	}
+
+	return nil, ErrUnknownMasterKey
+}
+
+// wrapAAD - дополнительные данные шифрования ключа данных: ключ привязан к своему пользователю
+func wrapAAD(login string) []byte {
+	return []byte("gophkeeper data key:" + login)
+}
+
+// newAEAD - AES-GCM с ключом key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadKeyFile - прочитать мастер-ключ из файла: 32 байта в base64 или hex (перевод строки в конце допускается)
+func ReadKeyFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+
+	encoded := strings.TrimSpace(string(content))
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == masterKeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == masterKeySize {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("master key file %s must contain %d bytes encoded in base64 or hex", path, masterKeySize)
+}
+
+// GenerateKeyFile - создать файл с новым случайным мастер-ключом (в base64, доступен только владельцу).
+// Существующий файл не перезаписывается
+func GenerateKeyFile(path string) error {
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate master key: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create master key file: %w", err)
+	}
+
+	if _, err := file.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write master key file: %w", err)
+	}
+
+	return file.Close()
+}
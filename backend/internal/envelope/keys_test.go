@@ -0,0 +1,112 @@
+package envelope_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/envelope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMasterKey - мастер-ключ из одного повторяющегося байта
+func testMasterKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestLocalKeyManager(t *testing.T) {
+	ctx := context.Background()
+	dataKey := bytes.Repeat([]byte{7}, 32)
+
+	current, err := envelope.NewLocalKeyManager(testMasterKey(1))
+	require.NoError(t, err)
+
+	wrapped, err := current.WrapKey(ctx, "alice", dataKey)
+	require.NoError(t, err)
+	assert.NotContains(t, string(wrapped), string(dataKey))
+
+	t.Run("Расшифровка", func(t *testing.T) {
+		key, err := current.UnwrapKey(ctx, "alice", wrapped)
+		require.NoError(t, err)
+		assert.Equal(t, dataKey, key)
+	})
+
+	t.Run("Ключ другого пользователя", func(t *testing.T) {
+		_, err := current.UnwrapKey(ctx, "bob", wrapped)
+		assert.Error(t, err)
+	})
+
+	t.Run("Прежний мастер-ключ", func(t *testing.T) {
+		rotated, err := envelope.NewLocalKeyManager(testMasterKey(2), testMasterKey(1))
+		require.NoError(t, err)
+
+		key, err := rotated.UnwrapKey(ctx, "alice", wrapped)
+		require.NoError(t, err)
+		assert.Equal(t, dataKey, key)
+	})
+
+	t.Run("Неизвестный мастер-ключ", func(t *testing.T) {
+		other, err := envelope.NewLocalKeyManager(testMasterKey(2))
+		require.NoError(t, err)
+
+		_, err = other.UnwrapKey(ctx, "alice", wrapped)
+		assert.ErrorIs(t, err, envelope.ErrUnknownMasterKey)
+	})
+
+	t.Run("Короткий мастер-ключ", func(t *testing.T) {
+		_, err := envelope.NewLocalKeyManager([]byte("short"))
+		assert.Error(t, err)
+	})
+}
+
+func TestKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Созданный ключ читается", func(t *testing.T) {
+		path := filepath.Join(dir, "master.key")
+		require.NoError(t, envelope.GenerateKeyFile(path))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		key, err := envelope.ReadKeyFile(path)
+		require.NoError(t, err)
+		assert.Len(t, key, 32)
+
+		assert.Error(t, envelope.GenerateKeyFile(path), "существующий ключ не перезаписывается")
+	})
+
+	t.Run("Ключ в hex", func(t *testing.T) {
+		path := filepath.Join(dir, "hex.key")
+		require.NoError(t, os.WriteFile(path, []byte(hex.EncodeToString(testMasterKey(3))+"\n"), 0600))
+
+		key, err := envelope.ReadKeyFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, testMasterKey(3), key)
+	})
+
+	t.Run("Ключ неверной длины", func(t *testing.T) {
+		path := filepath.Join(dir, "short.key")
+		require.NoError(t, os.WriteFile(path, []byte("c2hvcnQ="), 0600))
+
+		_, err := envelope.ReadKeyFile(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("Ключи из файлов", func(t *testing.T) {
+		current, previous := filepath.Join(dir, "current.key"), filepath.Join(dir, "previous.key")
+		require.NoError(t, envelope.GenerateKeyFile(current))
+		require.NoError(t, envelope.GenerateKeyFile(previous))
+
+		_, err := envelope.LoadLocalKeyManager(current, []string{previous})
+		assert.NoError(t, err)
+
+		_, err = envelope.LoadLocalKeyManager(current, []string{filepath.Join(dir, "missing.key")})
+		assert.Error(t, err)
+	})
+}
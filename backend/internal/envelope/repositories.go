@@ -0,0 +1,513 @@
+// Пакет envelope - шифрование хранимых полей на сервере
+package envelope
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories"
+)
+
+// Обёртки репозиториев шифруют поля перед записью и расшифровывают после чтения, поэтому сервис хранения
+// и реализации репозиториев работают с открытыми значениями. Ключ выбирается по владельцу записи,
+// так что записи, которыми поделились, и записи коллекций расшифровываются для любого пользователя, которому они видны.
+// С nil Sealer обёртки возвращают репозиторий без изменений (шифрование выключено)
+
+// fieldCipher - шифрование или расшифровка полей одной сущности ключом её владельца. Первая ошибка сохраняется в err.
+// measure - вместо шифрования заменить значения заглушками той же длины, что у зашифрованных (ключ не нужен)
+type fieldCipher struct {
+	ctx     context.Context
+	sealer  *Sealer
+	owner   string
+	seal    bool
+	measure bool
+	err     error
+}
+
+// text - зашифровать или расшифровать строковое поле
+func (c *fieldCipher) text(value *string) {
+	if c.err != nil {
+		return
+	}
+	if c.measure {
+		*value = strings.Repeat("=", sealedStringSize(len(*value)))
+		return
+	}
+	if c.seal {
+		*value, c.err = c.sealer.SealString(c.ctx, c.owner, *value)
+	} else {
+		*value, c.err = c.sealer.OpenString(c.ctx, c.owner, *value)
+	}
+}
+
+// bytes - зашифровать или расшифровать двоичное поле
+func (c *fieldCipher) bytes(value *[]byte) {
+	if c.err != nil {
+		return
+	}
+	if c.measure {
+		*value = make([]byte, sealedBytesSize(len(*value)))
+		return
+	}
+	if c.seal {
+		*value, c.err = c.sealer.SealBytes(c.ctx, c.owner, *value)
+	} else {
+		*value, c.err = c.sealer.OpenBytes(c.ctx, c.owner, *value)
+	}
+}
+
+// sealedRepo - репозиторий сущностей E (создаваемых из D) с зашифрованными полями
+type sealedRepo[E, D any] struct {
+	repo   repositories.IRepository[E, D]
+	sealer *Sealer
+	owner  func(*E) string
+	// dtoOwner - владелец создаваемой сущности
+	dtoOwner func(ctx context.Context, dto *D) string
+	// storedOwner - владелец изменяемой сущности по данным хранилища (пусто - сущности нет)
+	storedOwner func(ctx context.Context, entity *E) (string, error)
+	// entityFields и dtoFields - применить шифрование к хранимым полям
+	entityFields func(c *fieldCipher, entity *E)
+	dtoFields    func(c *fieldCipher, dto *D)
+}
+
+// GetAll - получить все сущности и расшифровать их
+func (r *sealedRepo[E, D]) GetAll(ctx context.Context) ([]E, error) {
+	list, err := r.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		if err := r.open(ctx, &list[i]); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Get - получить сущность и расшифровать её
+func (r *sealedRepo[E, D]) Get(ctx context.Context, id string) (*E, error) {
+	return r.opened(ctx)(r.repo.Get(ctx, id))
+}
+
+// Create - зашифровать поля копии dto и создать сущность
+func (r *sealedRepo[E, D]) Create(ctx context.Context, dto *D) (*E, error) {
+	sealed := *dto
+	c := &fieldCipher{ctx: ctx, sealer: r.sealer, owner: r.dtoOwner(ctx, dto), seal: true}
+	r.dtoFields(c, &sealed)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return r.opened(ctx)(r.repo.Create(ctx, &sealed))
+}
+
+// Update - зашифровать поля копии сущности ключом её владельца и изменить сущность.
+// Владелец берётся из хранилища: изменять запись может и не владелец
+func (r *sealedRepo[E, D]) Update(ctx context.Context, entity *E) (*E, error) {
+	owner, err := r.storedOwner(ctx, entity)
+	if err != nil || owner == "" {
+		return nil, err
+	}
+
+	sealed := *entity
+	c := &fieldCipher{ctx: ctx, sealer: r.sealer, owner: owner, seal: true}
+	r.entityFields(c, &sealed)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return r.opened(ctx)(r.repo.Update(ctx, &sealed))
+}
+
+// Delete - удалить сущность и вернуть её расшифрованной
+func (r *sealedRepo[E, D]) Delete(ctx context.Context, id string) (*E, error) {
+	return r.opened(ctx)(r.repo.Delete(ctx, id))
+}
+
+// StoredSize - объём сущности (*E) или DTO (*D) с открытыми полями после шифрования. Использование хранилища
+// считается по хранимым, то есть зашифрованным, полям, и квота проверяется в тех же единицах
+func (r *sealedRepo[E, D]) StoredSize(value interface{}) int64 {
+	c := &fieldCipher{measure: true}
+
+	var measured interface{}
+	switch v := value.(type) {
+	case *E:
+		entity := *v
+		r.entityFields(c, &entity)
+		measured = &entity
+	case *D:
+		dto := *v
+		r.dtoFields(c, &dto)
+		measured = &dto
+	}
+
+	if sized, ok := measured.(interface{ Size() int64 }); ok {
+		return sized.Size()
+	}
+	return 0
+}
+
+// open - расшифровать поля сущности
+func (r *sealedRepo[E, D]) open(ctx context.Context, entity *E) error {
+	c := &fieldCipher{ctx: ctx, sealer: r.sealer, owner: r.owner(entity)}
+	r.entityFields(c, entity)
+	return c.err
+}
+
+// opened - расшифровать результат операции репозитория (nil и ошибки передаются как есть)
+func (r *sealedRepo[E, D]) opened(ctx context.Context) func(*E, error) (*E, error) {
+	return func(entity *E, err error) (*E, error) {
+		if err != nil || entity == nil {
+			return entity, err
+		}
+		if err := r.open(ctx, entity); err != nil {
+			return nil, err
+		}
+		return entity, nil
+	}
+}
+
+// entryOwner - владелец создаваемой записи - текущий пользователь
+func entryOwner[D any](ctx context.Context, _ *D) string {
+	return customcontext.GetUserID(ctx)
+}
+
+// sealedEntries - репозиторий записей типа entityType с зашифрованными описанием и полями своего типа.
+// Владельца изменяемой записи сообщает access: он известен, даже если запись текущему пользователю не видна
+func sealedEntries[E, D any](sealer *Sealer, repo repositories.IRepository[E, D], access repositories.IAccessRepository, entityType string,
+	secure func(*E) *entities.SecureEntity, entityFields func(c *fieldCipher, entity *E), dtoFields func(c *fieldCipher, dto *D)) *sealedRepo[E, D] {
+	return &sealedRepo[E, D]{
+		repo:     repo,
+		sealer:   sealer,
+		owner:    func(e *E) string { return secure(e).OwnerID },
+		dtoOwner: entryOwner[D],
+		storedOwner: func(ctx context.Context, e *E) (string, error) {
+			found, err := access.EntryAccess(ctx, entityType, secure(e).ID)
+			if err != nil || found == nil {
+				return "", err
+			}
+			return found.OwnerID, nil
+		},
+		entityFields: func(c *fieldCipher, e *E) { c.text(&secure(e).Metadata); entityFields(c, e) },
+		dtoFields:    dtoFields,
+	}
+}
+
+// Binaries - репозиторий двоичных данных с зашифрованными данными и описанием
+func Binaries(sealer *Sealer, repo repositories.IRepository[entities.BinaryData, dtos.NewBinaryData], access repositories.IAccessRepository) repositories.IRepository[entities.BinaryData, dtos.NewBinaryData] {
+	if sealer == nil {
+		return repo
+	}
+	return sealedEntries(sealer, repo, access, "binary", func(e *entities.BinaryData) *entities.SecureEntity { return &e.SecureEntity },
+		func(c *fieldCipher, e *entities.BinaryData) { c.bytes(&e.Data) },
+		func(c *fieldCipher, d *dtos.NewBinaryData) { c.text(&d.Metadata); c.bytes(&d.Data) })
+}
+
+// Cards - репозиторий карт с зашифрованными полями карты и описанием
+func Cards(sealer *Sealer, repo repositories.IRepository[entities.CardInformation, dtos.NewCardInformation], access repositories.IAccessRepository) repositories.IRepository[entities.CardInformation, dtos.NewCardInformation] {
+	if sealer == nil {
+		return repo
+	}
+	return sealedEntries(sealer, repo, access, "card", func(e *entities.CardInformation) *entities.SecureEntity { return &e.SecureEntity },
+		func(c *fieldCipher, e *entities.CardInformation) {
+			c.text(&e.Number)
+			c.text(&e.CardHolder)
+			c.text(&e.ExpirationDate)
+			c.text(&e.CVV)
+		},
+		func(c *fieldCipher, d *dtos.NewCardInformation) {
+			c.text(&d.Metadata)
+			c.text(&d.Number)
+			c.text(&d.CardHolder)
+			c.text(&d.ExpirationDate)
+			c.text(&d.CVV)
+		})
+}
+
+// Credentials - репозиторий учётных данных с зашифрованными логином, паролем и описанием
+func Credentials(sealer *Sealer, repo repositories.IRepository[entities.Credentials, dtos.NewCredentials], access repositories.IAccessRepository) repositories.IRepository[entities.Credentials, dtos.NewCredentials] {
+	if sealer == nil {
+		return repo
+	}
+	return sealedEntries(sealer, repo, access, "credentials", func(e *entities.Credentials) *entities.SecureEntity { return &e.SecureEntity },
+		func(c *fieldCipher, e *entities.Credentials) { c.text(&e.Login); c.text(&e.Password) },
+		func(c *fieldCipher, d *dtos.NewCredentials) {
+			c.text(&d.Metadata)
+			c.text(&d.Login)
+			c.text(&d.Password)
+		})
+}
+
+// Texts - репозиторий текстов с зашифрованными текстом и описанием
+func Texts(sealer *Sealer, repo repositories.IRepository[entities.TextData, dtos.NewTextData], access repositories.IAccessRepository) repositories.IRepository[entities.TextData, dtos.NewTextData] {
+	if sealer == nil {
+		return repo
+	}
+	return sealedEntries(sealer, repo, access, "text", func(e *entities.TextData) *entities.SecureEntity { return &e.SecureEntity },
+		func(c *fieldCipher, e *entities.TextData) { c.text(&e.Data) },
+		func(c *fieldCipher, d *dtos.NewTextData) { c.text(&d.Metadata); c.text(&d.Data) })
+}
+
+// sealedUsers - репозиторий пользователей, который удаляет ключ данных вместе с пользователем
+type sealedUsers struct {
+	*sealedRepo[entities.User, dtos.NewUser]
+}
+
+// Users - репозиторий пользователей с зашифрованными хэшами паролей. Ключ данных пользователя удаляется вместе с ним
+func Users(sealer *Sealer, repo repositories.IRepository[entities.User, dtos.NewUser]) repositories.IRepository[entities.User, dtos.NewUser] {
+	if sealer == nil {
+		return repo
+	}
+	return sealedUsers{&sealedRepo[entities.User, dtos.NewUser]{
+		repo:     repo,
+		sealer:   sealer,
+		owner:    func(u *entities.User) string { return u.Login },
+		dtoOwner: func(_ context.Context, d *dtos.NewUser) string { return d.Login },
+		storedOwner: func(ctx context.Context, u *entities.User) (string, error) {
+			existing, err := repo.Get(ctx, u.Login)
+			if err != nil || existing == nil {
+				return "", err
+			}
+			return existing.Login, nil
+		},
+		entityFields: func(c *fieldCipher, u *entities.User) { c.text(&u.Password) },
+		dtoFields:    func(c *fieldCipher, d *dtos.NewUser) { c.text(&d.Password) },
+	}}
+}
+
+// Delete - удалить пользователя и его ключ данных
+func (r sealedUsers) Delete(ctx context.Context, login string) (*entities.User, error) {
+	user, err := r.sealedRepo.Delete(ctx, login)
+	if err != nil || user == nil {
+		return user, err
+	}
+
+	if err := r.sealer.Forget(ctx, login); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// sealedAccounts - учётные записи: удаление пользователя удаляет и его ключ данных
+type sealedAccounts struct {
+	repositories.IAccountRepository
+	sealer *Sealer
+}
+
+// Accounts - репозиторий учётных записей, который удаляет ключ данных вместе с пользователем
+func Accounts(sealer *Sealer, repo repositories.IAccountRepository) repositories.IAccountRepository {
+	if sealer == nil {
+		return repo
+	}
+	return sealedAccounts{IAccountRepository: repo, sealer: sealer}
+}
+
+// Delete - удалить пользователя вместе с его данными и ключом данных
+func (r sealedAccounts) Delete(ctx context.Context, login string) (*entities.User, error) {
+	user, err := r.IAccountRepository.Delete(ctx, login)
+	if err != nil || user == nil {
+		return user, err
+	}
+
+	if user.Password, err = r.sealer.OpenString(ctx, login, user.Password); err != nil {
+		return nil, err
+	}
+	if err := r.sealer.Forget(ctx, login); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// sealedTrash - корзина с расшифровкой описаний записей
+type sealedTrash struct {
+	repositories.ITrashRepository
+	sealer *Sealer
+}
+
+// Trash - корзина, которая возвращает описания записей расшифрованными
+func Trash(sealer *Sealer, repo repositories.ITrashRepository) repositories.ITrashRepository {
+	if sealer == nil {
+		return repo
+	}
+	return sealedTrash{ITrashRepository: repo, sealer: sealer}
+}
+
+// GetAll - записи в корзине
+func (r sealedTrash) GetAll(ctx context.Context) ([]entities.TrashItem, error) {
+	return r.openAll(ctx)(r.ITrashRepository.GetAll(ctx))
+}
+
+// Get - запись в корзине
+func (r sealedTrash) Get(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	return r.open(ctx)(r.ITrashRepository.Get(ctx, entityType, id))
+}
+
+// Restore - вернуть запись из корзины
+func (r sealedTrash) Restore(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	return r.open(ctx)(r.ITrashRepository.Restore(ctx, entityType, id))
+}
+
+// Delete - окончательно удалить запись из корзины
+func (r sealedTrash) Delete(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	return r.open(ctx)(r.ITrashRepository.Delete(ctx, entityType, id))
+}
+
+// Purge - окончательно удалить записи, попавшие в корзину раньше before
+func (r sealedTrash) Purge(ctx context.Context, before time.Time) ([]entities.TrashItem, error) {
+	return r.openAll(ctx)(r.ITrashRepository.Purge(ctx, before))
+}
+
+// open - расшифровать описание записи в результате операции
+func (r sealedTrash) open(ctx context.Context) func(*entities.TrashItem, error) (*entities.TrashItem, error) {
+	return func(item *entities.TrashItem, err error) (*entities.TrashItem, error) {
+		if err != nil || item == nil {
+			return item, err
+		}
+		if item.Metadata, err = r.sealer.OpenString(ctx, item.OwnerID, item.Metadata); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+}
+
+// openAll - расшифровать описания записей в результате операции
+func (r sealedTrash) openAll(ctx context.Context) func([]entities.TrashItem, error) ([]entities.TrashItem, error) {
+	return func(items []entities.TrashItem, err error) ([]entities.TrashItem, error) {
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			if items[i].Metadata, err = r.sealer.OpenString(ctx, items[i].OwnerID, items[i].Metadata); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+}
+
+// sealedHistory - история записей с расшифровкой версий
+type sealedHistory struct {
+	repositories.IHistoryRepository
+	sealer *Sealer
+	access repositories.IAccessRepository
+}
+
+// History - история записей, которая возвращает версии расшифрованными. Версии хранятся в том виде, в каком были
+// записи, поэтому шифруются ключом владельца записи, которого сообщает access
+func History(sealer *Sealer, repo repositories.IHistoryRepository, access repositories.IAccessRepository) repositories.IHistoryRepository {
+	if sealer == nil {
+		return repo
+	}
+	return sealedHistory{IHistoryRepository: repo, sealer: sealer, access: access}
+}
+
+// GetAll - версии записи
+func (r sealedHistory) GetAll(ctx context.Context, entityType, id string) ([]entities.Revision, error) {
+	revisions, err := r.IHistoryRepository.GetAll(ctx, entityType, id)
+	if err != nil || len(revisions) == 0 {
+		return revisions, err
+	}
+
+	owner, err := r.owner(ctx, entityType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range revisions {
+		if err := r.open(ctx, owner, &revisions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+// Get - версия записи
+func (r sealedHistory) Get(ctx context.Context, entityType, id string, revision int) (*entities.Revision, error) {
+	found, err := r.IHistoryRepository.Get(ctx, entityType, id, revision)
+	if err != nil || found == nil {
+		return found, err
+	}
+
+	owner, err := r.owner(ctx, entityType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.open(ctx, owner, found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// owner - владелец записи
+func (r sealedHistory) owner(ctx context.Context, entityType, id string) (string, error) {
+	access, err := r.access.EntryAccess(ctx, entityType, id)
+	if err != nil {
+		return "", err
+	}
+	if access == nil {
+		return "", fmt.Errorf("owner of %s %s not found", entityType, id)
+	}
+	return access.OwnerID, nil
+}
+
+// open - расшифровать описание и поля версии. Поля хранятся в JSON строками, двоичные данные - в base64
+func (r sealedHistory) open(ctx context.Context, owner string, revision *entities.Revision) error {
+	var err error
+	if revision.Metadata, err = r.sealer.OpenString(ctx, owner, revision.Metadata); err != nil {
+		return err
+	}
+
+	if len(revision.Content) == 0 {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(revision.Content, &fields); err != nil {
+		return fmt.Errorf("failed to decode revision: %w", err)
+	}
+
+	for name, raw := range fields {
+		var value string
+		if json.Unmarshal(raw, &value) != nil {
+			continue // Не строка - значение не шифруется
+		}
+
+		opened, err := r.openField(ctx, owner, value)
+		if err != nil {
+			return err
+		}
+		if fields[name], err = json.Marshal(opened); err != nil {
+			return err
+		}
+	}
+
+	revision.Content, err = json.Marshal(fields)
+	return err
+}
+
+// openField - расшифровать строковое поле версии или двоичное поле в base64
+func (r sealedHistory) openField(ctx context.Context, owner, value string) (string, error) {
+	if strings.HasPrefix(value, sealedPrefix) {
+		return r.sealer.OpenString(ctx, owner, value)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || !strings.HasPrefix(string(decoded), sealedPrefix) {
+		return value, nil
+	}
+
+	opened, err := r.sealer.OpenBytes(ctx, owner, decoded)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(opened), nil
+}
@@ -0,0 +1,218 @@
+package envelope_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/envelope"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/inmemory"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSealer - шифрование ключами данных из менеджера репозиториев в памяти
+func newTestSealer(t *testing.T, manager *inmemory.DatabaseManager) *envelope.Sealer {
+	keys, err := envelope.NewLocalKeyManager(testMasterKey(1))
+	require.NoError(t, err)
+	return envelope.NewSealer(keys, manager.DataKeys)
+}
+
+func TestSealer(t *testing.T) {
+	ctx := context.Background()
+	sealer := newTestSealer(t, inmemory.NewDatabaseManager())
+
+	sealed, err := sealer.SealString(ctx, "alice", "secret")
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "secret")
+
+	t.Run("Строка", func(t *testing.T) {
+		opened, err := sealer.OpenString(ctx, "alice", sealed)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", opened)
+	})
+
+	t.Run("Двоичные данные", func(t *testing.T) {
+		sealedBytes, err := sealer.SealBytes(ctx, "alice", []byte{0, 1, 2})
+		require.NoError(t, err)
+
+		opened, err := sealer.OpenBytes(ctx, "alice", sealedBytes)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0, 1, 2}, opened)
+	})
+
+	t.Run("Значение, записанное до включения шифрования", func(t *testing.T) {
+		opened, err := sealer.OpenString(ctx, "alice", "plain")
+		require.NoError(t, err)
+		assert.Equal(t, "plain", opened)
+	})
+
+	t.Run("Значение другого пользователя", func(t *testing.T) {
+		_, err := sealer.SealString(ctx, "bob", "")
+		require.NoError(t, err)
+
+		_, err = sealer.OpenString(ctx, "bob", sealed)
+		assert.Error(t, err)
+	})
+
+	t.Run("Ключ удалён", func(t *testing.T) {
+		require.NoError(t, sealer.Forget(ctx, "alice"))
+
+		_, err := sealer.OpenString(ctx, "alice", sealed)
+		assert.Error(t, err)
+	})
+}
+
+func TestConformance_Sealed(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		manager := inmemory.NewDatabaseManager()
+		sealer := newTestSealer(t, manager)
+		return repotest.Repos{
+			Users:       envelope.Users(sealer, manager.Users),
+			Binaries:    envelope.Binaries(sealer, manager.Binaries, manager.Access),
+			Cards:       envelope.Cards(sealer, manager.Cards, manager.Access),
+			Credentials: envelope.Credentials(sealer, manager.Credentials, manager.Access),
+			Texts:       envelope.Texts(sealer, manager.Texts, manager.Access),
+		}
+	}, repotest.Options{})
+}
+
+func TestSealedRepositories(t *testing.T) {
+	manager := inmemory.NewDatabaseManager()
+	sealer := newTestSealer(t, manager)
+	alice := customcontext.WithUserID(context.Background(), "alice")
+
+	users := envelope.Users(sealer, manager.Users)
+	binaries := envelope.Binaries(sealer, manager.Binaries, manager.Access)
+	texts := envelope.Texts(sealer, manager.Texts, manager.Access)
+
+	_, err := users.Create(alice, &dtos.NewUser{Login: "alice", Password: "hash"})
+	require.NoError(t, err)
+
+	t.Run("Хранятся зашифрованными, читаются открытыми", func(t *testing.T) {
+		stored, err := manager.Users.Get(alice, "alice")
+		require.NoError(t, err)
+		assert.NotEqual(t, "hash", stored.Password)
+
+		user, err := users.Get(alice, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, "hash", user.Password)
+
+		id := uuid.NewString()
+		created, err := binaries.Create(alice, &dtos.NewBinaryData{NewSecureEntity: dtos.NewSecureEntity{ID: id, Metadata: "photo"}, Data: []byte("image")})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("image"), created.Data)
+		assert.Equal(t, "photo", created.Metadata)
+
+		raw, err := manager.Binaries.Get(alice, id)
+		require.NoError(t, err)
+		assert.False(t, bytes.Contains(raw.Data, []byte("image")))
+		assert.NotEqual(t, "photo", raw.Metadata)
+	})
+
+	t.Run("Объём хранимых полей", func(t *testing.T) {
+		type sizer interface{ StoredSize(value interface{}) int64 }
+		cards := envelope.Cards(sealer, manager.Cards, manager.Access)
+
+		binaryDTO := &dtos.NewBinaryData{NewSecureEntity: dtos.NewSecureEntity{ID: uuid.NewString(), Metadata: "scan"}, Data: []byte("document")}
+		binary, err := binaries.Create(alice, binaryDTO)
+		require.NoError(t, err)
+		rawBinary, err := manager.Binaries.Get(alice, binary.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, rawBinary.Size(), binaries.(sizer).StoredSize(binaryDTO))
+		assert.Equal(t, rawBinary.Size(), binaries.(sizer).StoredSize(binary))
+		assert.Greater(t, rawBinary.Size(), binary.Size())
+
+		cardDTO := &dtos.NewCardInformation{NewSecureEntity: dtos.NewSecureEntity{ID: uuid.NewString()}, Number: "4111111111111111",
+			CardHolder: "ALICE", ExpirationDate: "12/30", CVV: "123"}
+		card, err := cards.Create(alice, cardDTO)
+		require.NoError(t, err)
+		rawCard, err := manager.Cards.Get(alice, card.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, rawCard.Size(), cards.(sizer).StoredSize(cardDTO))
+		assert.Equal(t, rawCard.Size(), cards.(sizer).StoredSize(card))
+	})
+
+	id := uuid.NewString()
+	created, err := texts.Create(alice, &dtos.NewTextData{NewSecureEntity: dtos.NewSecureEntity{ID: id, Metadata: "v1 note"}, Data: "first"})
+	require.NoError(t, err)
+
+	t.Run("История", func(t *testing.T) {
+		require.NoError(t, manager.History.Append(alice, "text", id, 0, time.Time{}, func(context.Context) error { return nil }))
+
+		created.Data, created.Metadata = "second", "v2 note"
+		updated, err := texts.Update(alice, created)
+		require.NoError(t, err)
+		assert.Equal(t, "second", updated.Data)
+
+		raw, err := manager.History.GetAll(alice, "text", id)
+		require.NoError(t, err)
+		require.Len(t, raw, 1)
+		assert.NotContains(t, string(raw[0].Content), "first")
+
+		revisions, err := envelope.History(sealer, manager.History, manager.Access).GetAll(alice, "text", id)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "v1 note", revisions[0].Metadata)
+
+		var content struct {
+			Data string `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(revisions[0].Content, &content))
+		assert.Equal(t, "first", content.Data)
+	})
+
+	t.Run("Корзина", func(t *testing.T) {
+		_, err := texts.Delete(alice, id)
+		require.NoError(t, err)
+
+		items, err := envelope.Trash(sealer, manager.Trash).GetAll(alice)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "v2 note", items[0].Metadata)
+	})
+
+	t.Run("Удаление пользователя удаляет ключ данных", func(t *testing.T) {
+		_, err := users.Delete(alice, "alice")
+		require.NoError(t, err)
+
+		key, err := manager.DataKeys.Get(alice, "alice")
+		require.NoError(t, err)
+		assert.Nil(t, key)
+	})
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	manager := inmemory.NewDatabaseManager()
+
+	old, err := envelope.NewLocalKeyManager(testMasterKey(1))
+	require.NoError(t, err)
+	sealed, err := envelope.NewSealer(old, manager.DataKeys).SealString(ctx, "alice", "secret")
+	require.NoError(t, err)
+
+	rotated, err := envelope.NewLocalKeyManager(testMasterKey(2), testMasterKey(1))
+	require.NoError(t, err)
+	count, err := envelope.Rotate(ctx, rotated, manager.DataKeys)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// Прежний мастер-ключ больше не нужен
+	current, err := envelope.NewLocalKeyManager(testMasterKey(2))
+	require.NoError(t, err)
+	opened, err := envelope.NewSealer(current, manager.DataKeys).OpenString(ctx, "alice", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+
+	_, err = envelope.NewSealer(old, manager.DataKeys).OpenString(ctx, "alice", sealed)
+	assert.True(t, err != nil && strings.Contains(err.Error(), "unknown master key"))
+}
@@ -0,0 +1,240 @@
+// Пакет envelope - шифрование хранимых полей на сервере
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories"
+)
+
+// sealedPrefix - признак зашифрованного значения. Значения без него записаны до включения шифрования и читаются как есть
+const sealedPrefix = "gkenc1:"
+
+// dataKeySize - длина ключа данных пользователя (AES-256)
+const dataKeySize = 32
+
+// sealOverhead - на сколько байт шифротекст длиннее значения: nonce и тег аутентификации AES-GCM
+const sealOverhead = 12 + 16
+
+// sealedStringSize - длина строки из n байт после SealString
+func sealedStringSize(n int) int {
+	return len(sealedPrefix) + base64.StdEncoding.EncodedLen(n+sealOverhead)
+}
+
+// sealedBytesSize - длина данных из n байт после SealBytes
+func sealedBytesSize(n int) int {
+	return len(sealedPrefix) + n + sealOverhead
+}
+
+// Sealer - шифрует и расшифровывает хранимые поля ключами данных их владельцев.
+// Ключ данных создаётся при первом шифровании значения пользователя; расшифрованные ключи кэшируются в памяти
+type Sealer struct {
+	keys KeyManager
+	repo repositories.IDataKeyRepository
+
+	mu    sync.Mutex
+	aeads map[string]cipher.AEAD // ключи данных по логину
+}
+
+// NewSealer - шифрование полей ключами данных из repo, зашифрованными менеджером keys
+func NewSealer(keys KeyManager, repo repositories.IDataKeyRepository) *Sealer {
+	return &Sealer{
+		keys:  keys,
+		repo:  repo,
+		aeads: make(map[string]cipher.AEAD),
+	}
+}
+
+// SealString - зашифровать строку ключом данных владельца
+func (s *Sealer) SealString(ctx context.Context, owner, value string) (string, error) {
+	sealed, err := s.seal(ctx, owner, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenString - расшифровать строку, зашифрованную SealString (незашифрованная возвращается без изменений)
+func (s *Sealer) OpenString(ctx context.Context, owner, value string) (string, error) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(value[len(sealedPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("malformed sealed value: %w", err)
+	}
+
+	opened, err := s.open(ctx, owner, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(opened), nil
+}
+
+// SealBytes - зашифровать данные ключом данных владельца
+func (s *Sealer) SealBytes(ctx context.Context, owner string, value []byte) ([]byte, error) {
+	sealed, err := s.seal(ctx, owner, value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(sealedPrefix), sealed...), nil
+}
+
+// OpenBytes - расшифровать данные, зашифрованные SealBytes (незашифрованные возвращаются без изменений)
+func (s *Sealer) OpenBytes(ctx context.Context, owner string, value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, []byte(sealedPrefix)) {
+		return value, nil
+	}
+	return s.open(ctx, owner, value[len(sealedPrefix):])
+}
+
+// Forget - удалить ключ данных пользователя (вместе с пользователем): зашифрованные им значения больше не расшифровать
+func (s *Sealer) Forget(ctx context.Context, owner string) error {
+	s.mu.Lock()
+	delete(s.aeads, owner)
+	s.mu.Unlock()
+
+	return s.repo.Delete(ctx, owner)
+}
+
+// seal - зашифровать значение: nonce и шифротекст. Значение привязано к владельцу
+func (s *Sealer) seal(ctx context.Context, owner string, value []byte) ([]byte, error) {
+	aead, err := s.dataKey(ctx, owner, true)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, value, []byte(owner)), nil
+}
+
+// open - расшифровать значение, зашифрованное seal
+func (s *Sealer) open(ctx context.Context, owner string, sealed []byte) ([]byte, error) {
+	aead, err := s.dataKey(ctx, owner, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("malformed sealed value")
+	}
+
+	opened, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(owner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value of %s: %w", owner, err)
+	}
+	return opened, nil
+}
+
+// dataKey - ключ данных пользователя из кэша или из репозитория. create - создать ключ, если его ещё нет
+func (s *Sealer) dataKey(ctx context.Context, owner string, create bool) (cipher.AEAD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if aead, cached := s.aeads[owner]; cached {
+		return aead, nil
+	}
+
+	stored, err := s.repo.Get(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	created := false
+	if stored == nil {
+		if !create {
+			return nil, fmt.Errorf("data key of %s not found", owner)
+		}
+		if stored, err = s.createDataKey(ctx, owner); err != nil {
+			return nil, err
+		}
+		created = true
+	}
+
+	key, err := s.keys.UnwrapKey(ctx, owner, stored.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Новый ключ попадает в кэш только после повторного чтения: транзакция, в которой он создан, может откатиться,
+	// и данные, зашифрованные ключом из кэша, уже нельзя было бы расшифровать
+	if !created {
+		s.aeads[owner] = aead
+	}
+	return aead, nil
+}
+
+// createDataKey - создать ключ данных пользователя. Если другой экземпляр сервера успел создать ключ раньше, возвращается его ключ
+func (s *Sealer) createDataKey(ctx context.Context, owner string) (*entities.DataKey, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := s.keys.WrapKey(ctx, owner, key)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.Create(ctx, &entities.DataKey{Login: owner, WrappedKey: wrapped})
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("data key of %s was not saved", owner)
+	}
+
+	return stored, nil
+}
+
+// Rotate - перешифровать ключи данных всех пользователей текущим мастер-ключом менеджера keys.
+// Сами ключи данных и зашифрованные ими поля не меняются. Возвращает число перешифрованных ключей
+func Rotate(ctx context.Context, keys KeyManager, repo repositories.IDataKeyRepository) (int, error) {
+	dataKeys, err := repo.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, dataKey := range dataKeys {
+		key, err := keys.UnwrapKey(ctx, dataKey.Login, dataKey.WrappedKey)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to unwrap data key of %s: %w", dataKey.Login, err)
+		}
+
+		wrapped, err := keys.WrapKey(ctx, dataKey.Login, key)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to wrap data key of %s: %w", dataKey.Login, err)
+		}
+
+		updated, err := repo.Update(ctx, &entities.DataKey{Login: dataKey.Login, WrappedKey: wrapped})
+		if err != nil {
+			return rotated, err
+		}
+		// Ключ удалили вместе с пользователем, пока шла ротация
+		if updated != nil {
+			rotated++
+		}
+	}
+
+	return rotated, nil
+}
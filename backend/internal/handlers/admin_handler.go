@@ -0,0 +1,269 @@
+// handlers - пакет с обработчиками входящих запросов
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/services"
+	"github.com/go-chi/chi"
+)
+
+// AdminHandler - обработчики API администратора (управление учётными записями и статистика сервера).
+// Аутентификацию администратора выполняет auth.AdminMiddleware
+type AdminHandler struct {
+	service *services.StorageService
+}
+
+// NewAdminHandler - создать обработчики API администратора
+func NewAdminHandler(service *services.StorageService) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+// GetAccounts - получить учётные записи с количеством и объёмом записей
+func (h *AdminHandler) GetAccounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	accounts, err := h.service.GetAccounts(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// DisableAccount - заблокировать учётную запись и отозвать её сессии
+func (h *AdminHandler) DisableAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeAccount(w, r, http.MethodPost, h.service.DisableAccount)
+}
+
+// EnableAccount - разблокировать учётную запись
+func (h *AdminHandler) EnableAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeAccount(w, r, http.MethodPost, h.service.EnableAccount)
+}
+
+// RevokeSessions - завершить все сессии пользователя
+func (h *AdminHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	h.changeAccount(w, r, http.MethodPost, h.service.RevokeSessions)
+}
+
+// changeAccount - выполнить действие над учётной записью из пути запроса и вернуть её новое состояние
+func (h *AdminHandler) changeAccount(w http.ResponseWriter, r *http.Request, method string,
+	change func(ctx context.Context, login string) (*entities.Account, error)) {
+	if r.Method != method {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := chi.URLParam(r, "login")
+	if login == "" {
+		http.Error(w, "Login parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	account, err := change(r.Context(), login)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(account)
+}
+
+// SetQuota - задать индивидуальную квоту пользователя
+func (h *AdminHandler) SetQuota(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	if r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var quota entities.Quota
+	if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	h.changeAccount(w, r, http.MethodPut, func(ctx context.Context, login string) (*entities.Account, error) {
+		return h.service.SetQuota(ctx, login, quota)
+	})
+}
+
+// ResetQuota - вернуть пользователю квоту по умолчанию
+func (h *AdminHandler) ResetQuota(w http.ResponseWriter, r *http.Request) {
+	h.changeAccount(w, r, http.MethodDelete, h.service.ResetQuota)
+}
+
+// DeleteAccount - удалить учётную запись со всеми данными
+func (h *AdminHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := chi.URLParam(r, "login")
+	if login == "" {
+		http.Error(w, "Login parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.service.DeleteAccount(r.Context(), login); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusGone)
+}
+
+// CreateInvite - выпустить код приглашения для регистрации
+func (h *AdminHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	if r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.NewInvite
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	invite, err := h.service.CreateInvite(r.Context(), &req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+// GetInvites - получить все приглашения с числом использований
+func (h *AdminHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	invites, err := h.service.GetInvites(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if invites == nil {
+		invites = []entities.Invite{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invites)
+}
+
+// DeleteInvite - отозвать приглашение
+func (h *AdminHandler) DeleteInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	code := chi.URLParam(r, "code")
+	if code == "" {
+		http.Error(w, "Code parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.service.DeleteInvite(r.Context(), code); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusGone)
+}
+
+// GetStats - получить статистику сервера
+func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := h.service.GetServerStats(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetAuditLog - получить журнал действий администраторов (параметры from и to в RFC3339, login - учётная запись)
+func (h *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := dtos.AuditFilter{UserID: query.Get("login")}
+
+	if from := query.Get("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			http.Error(w, "Invalid 'from' parameter, RFC3339 expected", http.StatusBadRequest)
+			return
+		}
+		filter.From = parsed
+	}
+
+	if to := query.Get("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			http.Error(w, "Invalid 'to' parameter, RFC3339 expected", http.StatusBadRequest)
+			return
+		}
+		filter.To = parsed
+	}
+
+	events, err := h.service.GetAuditLog(r.Context(), &filter)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if events == nil {
+		events = []entities.AuditEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
@@ -2,16 +2,23 @@
 package handlers
 
 import (
+	"archive/zip"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
 	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
 	"github.com/JustScorpio/GophKeeper/backend/internal/hash"
+	"github.com/JustScorpio/GophKeeper/backend/internal/metrics"
 	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/auth" //В файле c middleware не только middleware, но и ауфные функции
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
 	"github.com/JustScorpio/GophKeeper/backend/internal/services"
 	"github.com/go-chi/chi"
 )
@@ -19,13 +26,53 @@ import (
 // GophkeeperHandler - главный сервис
 type GophkeeperHandler struct {
 	service *services.StorageService
+
+	maxBinarySize int64 // максимальный размер бинарных данных в байтах
+	maxTextSize   int64 // максимальный размер текстовых данных в байтах
+
+	notifications *notifications.Hub // необязательная, без неё поток событий недоступен
+	keepAlive     time.Duration      // период отправки комментариев в поток событий, чтобы прокси не закрывали соединение
+}
+
+// Option - необязательная настройка обработчиков
+type Option func(*GophkeeperHandler)
+
+// WithDataLimits - задать максимальные размеры бинарных и текстовых данных в байтах
+func WithDataLimits(maxBinarySize, maxTextSize int64) Option {
+	return func(h *GophkeeperHandler) {
+		h.maxBinarySize = maxBinarySize
+		h.maxTextSize = maxTextSize
+	}
+}
+
+// WithNotifications - раздавать клиентам события об изменениях из указанной рассылки
+func WithNotifications(hub *notifications.Hub) Option {
+	return func(h *GophkeeperHandler) {
+		h.notifications = hub
+	}
+}
+
+// WithKeepAlive - задать период отправки комментариев в поток событий
+func WithKeepAlive(period time.Duration) Option {
+	return func(h *GophkeeperHandler) {
+		h.keepAlive = period
+	}
 }
 
 // GophkeeperHandler - создать главный сервис
-func NewGophkeeperHandler(service *services.StorageService) *GophkeeperHandler {
-	return &GophkeeperHandler{
-		service: service,
+func NewGophkeeperHandler(service *services.StorageService, opts ...Option) *GophkeeperHandler {
+	handler := &GophkeeperHandler{
+		service:       service,
+		maxBinarySize: 10 * 1024 * 1024, // 10MB
+		maxTextSize:   1 * 1024 * 1024,  // 1MB
+		keepAlive:     30 * time.Second,
 	}
+
+	for _, opt := range opts {
+		opt(handler)
+	}
+
+	return handler
 }
 
 // Register - регистрация пользователя
@@ -78,7 +125,7 @@ func (h *GophkeeperHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Устанавливаем JWT с логином
-	if err := auth.SetJWTCookie(w, user.Login); err != nil {
+	if err := auth.SetJWTCookie(w, user.Login, user.SessionVersion); err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -120,18 +167,27 @@ func (h *GophkeeperHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Проверяем пользователя
 	user, err := h.service.GetUser(r.Context(), req.Login)
 	if err != nil || user == nil {
+		metrics.AuthFailures.WithLabelValues(metrics.AuthReasonInvalidCredentials).Inc()
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
 	// Проверяем пароль
 	if !hash.CheckPasswordHash(req.Password, user.Password) {
+		metrics.AuthFailures.WithLabelValues(metrics.AuthReasonInvalidCredentials).Inc()
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
+	// Заблокированному пользователю токен не выдаётся
+	if user.Disabled {
+		metrics.AuthFailures.WithLabelValues(metrics.AuthReasonDisabledAccount).Inc()
+		http.Error(w, "Account is disabled", http.StatusForbidden)
+		return
+	}
+
 	// Устанавливаем JWT токен с логином
-	if err := auth.SetJWTCookie(w, user.Login); err != nil {
+	if err := auth.SetJWTCookie(w, user.Login, user.SessionVersion); err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -172,21 +228,14 @@ func (h *GophkeeperHandler) CreateBinary(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if len(req.Data) > 10*1024*1024 { // 10MB limit
+	if int64(len(req.Data)) > h.maxBinarySize {
 		http.Error(w, "Data too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
 	binary, err := h.service.CreateBinary(r.Context(), &req)
 	if err != nil {
-		var statusCode = http.StatusInternalServerError
-
-		var httpErr *customerrors.HTTPError
-		if errors.As(err, &httpErr) {
-			statusCode = httpErr.Code
-		}
-
-		http.Error(w, err.Error(), statusCode)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -312,21 +361,14 @@ func (h *GophkeeperHandler) UpdateBinary(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if len(req.Data) > 10*1024*1024 { // 10MB limit
+	if int64(len(req.Data)) > h.maxBinarySize {
 		http.Error(w, "Data too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
 	updatedBinary, err := h.service.UpdateBinary(r.Context(), &req)
 	if err != nil {
-		var statusCode = http.StatusInternalServerError
-
-		var httpErr *customerrors.HTTPError
-		if errors.As(err, &httpErr) {
-			statusCode = httpErr.Code
-		}
-
-		http.Error(w, err.Error(), statusCode)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -413,14 +455,7 @@ func (h *GophkeeperHandler) CreateCard(w http.ResponseWriter, r *http.Request) {
 
 	card, err := h.service.CreateCard(r.Context(), &req)
 	if err != nil {
-		var statusCode = http.StatusInternalServerError
-
-		var httpErr *customerrors.HTTPError
-		if errors.As(err, &httpErr) {
-			statusCode = httpErr.Code
-		}
-
-		http.Error(w, err.Error(), statusCode)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -545,14 +580,7 @@ func (h *GophkeeperHandler) UpdateCard(w http.ResponseWriter, r *http.Request) {
 
 	updatedCard, err := h.service.UpdateCard(r.Context(), &req)
 	if err != nil {
-		var statusCode = http.StatusInternalServerError
-
-		var httpErr *customerrors.HTTPError
-		if errors.As(err, &httpErr) {
-			statusCode = httpErr.Code
-		}
-
-		http.Error(w, err.Error(), statusCode)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -638,14 +666,7 @@ func (h *GophkeeperHandler) CreateCredentials(w http.ResponseWriter, r *http.Req
 
 	credentials, err := h.service.CreateCredentials(r.Context(), &req)
 	if err != nil {
-		var statusCode = http.StatusInternalServerError
-
-		var httpErr *customerrors.HTTPError
-		if errors.As(err, &httpErr) {
-			statusCode = httpErr.Code
-		}
-
-		http.Error(w, err.Error(), statusCode)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -770,14 +791,7 @@ func (h *GophkeeperHandler) UpdateCredentials(w http.ResponseWriter, r *http.Req
 
 	updatedCredentials, err := h.service.UpdateCredentials(r.Context(), &req)
 	if err != nil {
-		var statusCode = http.StatusInternalServerError
-
-		var httpErr *customerrors.HTTPError
-		if errors.As(err, &httpErr) {
-			statusCode = httpErr.Code
-		}
-
-		http.Error(w, err.Error(), statusCode)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -861,21 +875,14 @@ func (h *GophkeeperHandler) CreateText(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Data) > 1*1024*1024 { // 1MB limit для текста
+	if int64(len(req.Data)) > h.maxTextSize {
 		http.Error(w, "Text too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
 	text, err := h.service.CreateText(r.Context(), &req)
 	if err != nil {
-		var statusCode = http.StatusInternalServerError
-
-		var httpErr *customerrors.HTTPError
-		if errors.As(err, &httpErr) {
-			statusCode = httpErr.Code
-		}
-
-		http.Error(w, err.Error(), statusCode)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -998,21 +1005,14 @@ func (h *GophkeeperHandler) UpdateText(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Data) > 1*1024*1024 { // 1MB limit для текста
+	if int64(len(req.Data)) > h.maxTextSize {
 		http.Error(w, "Text too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
 	updatedText, err := h.service.UpdateText(r.Context(), &req)
 	if err != nil {
-		var statusCode = http.StatusInternalServerError
-
-		var httpErr *customerrors.HTTPError
-		if errors.As(err, &httpErr) {
-			statusCode = httpErr.Code
-		}
-
-		http.Error(w, err.Error(), statusCode)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -1063,3 +1063,1406 @@ func (h *GophkeeperHandler) DeleteText(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusGone)
 }
+
+// GetAuditLog - получить журнал операций пользователя.
+// Параметры запроса (необязательные): from, to - границы периода в формате RFC3339, type - тип сущности
+func (h *GophkeeperHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var filter dtos.AuditFilter
+	query := r.URL.Query()
+
+	if from := query.Get("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			http.Error(w, "Invalid 'from' parameter, RFC3339 expected", http.StatusBadRequest)
+			return
+		}
+		filter.From = parsed
+	}
+
+	if to := query.Get("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			http.Error(w, "Invalid 'to' parameter, RFC3339 expected", http.StatusBadRequest)
+			return
+		}
+		filter.To = parsed
+	}
+
+	if entityType := query.Get("type"); entityType != "" {
+		if _, ok := services.ParseEntityType(entityType); !ok {
+			http.Error(w, "Unknown entity type", http.StatusBadRequest)
+			return
+		}
+		filter.EntityType = entityType
+	}
+
+	events, err := h.service.GetAuditLog(r.Context(), &filter)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if events == nil {
+		events = []entities.AuditEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
+
+// Events - поток событий об изменении данных пользователя (Server-Sent Events).
+// Каждое событие отправляется как "event: change" с JSON-описанием изменения. Изменения, сделанные в той же сессии, не отправляются
+func (h *GophkeeperHandler) Events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if h.notifications == nil {
+		http.Error(w, "Notifications are disabled", http.StatusNotImplemented)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+
+	events, unsubscribe := h.notifications.Subscribe(login, customcontext.GetSessionID(r.Context()))
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Комментарий сразу после подключения - клиент узнаёт, что подписка оформлена
+	fmt.Fprint(w, ": connected\n\n")
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case event, ok := <-events:
+			if !ok {
+				// Рассылка закрыта (остановка сервера) или клиент не успевал получать события
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: change\ndata: %s\n\n", data)
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// SetUserKeys - опубликовать пару ключей текущего пользователя (открытый ключ и закрытый, зашифрованный ключом хранилища)
+func (h *GophkeeperHandler) SetUserKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req entities.UserKeys
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация
+	if req.PublicKey == "" || req.EncryptedPrivateKey == "" {
+		http.Error(w, "Public key and encrypted private key are required", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.service.SetUserKeys(r.Context(), &req)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// GetUserKeys - получить ключи пользователя. Без параметра login - собственные ключи (вместе с зашифрованным закрытым ключом),
+// с параметром - открытый ключ другого пользователя
+func (h *GophkeeperHandler) GetUserKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	target := chi.URLParam(r, "login")
+	if target == "" {
+		target = login
+	}
+
+	keys, err := h.service.GetUserKeys(r.Context(), target)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if keys == nil {
+		http.Error(w, "Keys not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateShare - поделиться собственной записью с другим пользователем
+func (h *GophkeeperHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req dtos.NewShareGrant
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация
+	entityType, ok := services.ParseEntityType(req.EntityType)
+	if !ok || (entityType != services.EntityBinary && entityType != services.EntityCard &&
+		entityType != services.EntityCredentials && entityType != services.EntityText) {
+		http.Error(w, "Unknown entity type", http.StatusBadRequest)
+		return
+	}
+
+	if req.EntityID == "" || req.RecipientID == "" {
+		http.Error(w, "Entity ID and recipient are required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Permission != entities.PermissionRead && req.Permission != entities.PermissionWrite {
+		http.Error(w, "Permission must be 'read' or 'write'", http.StatusBadRequest)
+		return
+	}
+
+	if req.EntryKey == "" {
+		http.Error(w, "Entry key is required", http.StatusBadRequest)
+		return
+	}
+
+	grant, err := h.service.ShareEntry(r.Context(), &req)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if grant == nil {
+		http.Error(w, "Entry not found or not prepared for sharing", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(grant)
+}
+
+// GetShares - получить права, выданные текущим пользователем и выданные ему
+func (h *GophkeeperHandler) GetShares(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	grants, err := h.service.GetShares(r.Context())
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if grants == nil {
+		grants = []entities.ShareGrant{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(grants)
+}
+
+// AcceptShare - принять приглашение к чужой записи
+func (h *GophkeeperHandler) AcceptShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	grant, err := h.service.AcceptShare(r.Context(), id)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if grant == nil {
+		http.Error(w, "Share not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(grant)
+}
+
+// DeleteShare - отозвать право на запись (владелец) или отказаться от него (получатель)
+func (h *GophkeeperHandler) DeleteShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	deletedGrant, err := h.service.DeleteShare(r.Context(), id)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if deletedGrant == nil {
+		http.Error(w, "Share not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusGone)
+}
+
+// CreateOrganization - создать организацию, текущий пользователь становится её владельцем
+func (h *GophkeeperHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req dtos.NewOrganization
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация
+	if req.Name == "" {
+		http.Error(w, "Organization name is required", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.service.CreateOrganization(r.Context(), &req)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+// GetOrganizations - получить организации текущего пользователя (в том числе те, куда он приглашён)
+func (h *GophkeeperHandler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	orgs, err := h.service.GetOrganizations(r.Context())
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if orgs == nil {
+		orgs = []entities.Organization{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orgs)
+}
+
+// GetOrgMembers - получить участников организации
+func (h *GophkeeperHandler) GetOrgMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	orgID := chi.URLParam(r, "id")
+	if orgID == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	members, err := h.service.GetOrgMembers(r.Context(), orgID)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if members == nil {
+		members = []entities.OrgMember{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(members)
+}
+
+// InviteMember - пригласить пользователя в организацию, передав ему ключи всех коллекций
+func (h *GophkeeperHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	orgID := chi.URLParam(r, "id")
+	if orgID == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.NewOrgMember
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	req.OrgID = orgID
+
+	// Валидация
+	if req.Login == "" {
+		http.Error(w, "Login is required", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Role {
+	case entities.RoleAdmin, entities.RoleMember, entities.RoleReadOnly:
+	default:
+		http.Error(w, "Role must be 'admin', 'member' or 'readonly'", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.service.InviteMember(r.Context(), &req)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(member)
+}
+
+// AcceptInvite - принять приглашение в организацию
+func (h *GophkeeperHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	orgID := chi.URLParam(r, "id")
+	if orgID == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.service.AcceptInvite(r.Context(), orgID)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if member == nil {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(member)
+}
+
+// RemoveMember - исключить участника из организации или выйти из неё. Ключи коллекций после этого нужно заменить
+func (h *GophkeeperHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	orgID := chi.URLParam(r, "id")
+	if orgID == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	target := chi.URLParam(r, "login")
+	if target == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	removed, err := h.service.RemoveMember(r.Context(), &entities.OrgMember{OrgID: orgID, Login: target})
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if removed == nil {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusGone)
+}
+
+// CreateCollection - создать коллекцию организации с ключом, зашифрованным для каждого участника
+func (h *GophkeeperHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	orgID := chi.URLParam(r, "id")
+	if orgID == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.NewCollection
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	req.OrgID = orgID
+
+	// Валидация
+	if req.Name == "" {
+		http.Error(w, "Collection name is required", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.service.CreateCollection(r.Context(), &req)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// GetCollections - получить коллекции организации с ключами, зашифрованными для текущего пользователя
+func (h *GophkeeperHandler) GetCollections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	orgID := chi.URLParam(r, "id")
+	if orgID == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	collections, err := h.service.GetCollections(r.Context(), orgID)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if collections == nil {
+		collections = []entities.Collection{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(collections)
+}
+
+// RotateCollectionKey - заменить ключ коллекции следующей версией
+func (h *GophkeeperHandler) RotateCollectionKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	collectionID := chi.URLParam(r, "id")
+	if collectionID == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.CollectionRotation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	req.CollectionID = collectionID
+
+	collection, err := h.service.RotateCollectionKey(r.Context(), &req)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// InviteEmergencyContact - назначить доверенное лицо для экстренного доступа к хранилищу текущего пользователя
+func (h *GophkeeperHandler) InviteEmergencyContact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req dtos.NewEmergencyAccess
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация
+	if req.GranteeID == "" {
+		http.Error(w, "Emergency contact is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.EncryptedKey == "" {
+		http.Error(w, "Encrypted vault key is required", http.StatusBadRequest)
+		return
+	}
+
+	access, err := h.service.InviteEmergencyContact(r.Context(), &req)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(access)
+}
+
+// GetEmergencyAccess - получить экстренные доступы, в которых текущий пользователь доверитель или доверенное лицо
+func (h *GophkeeperHandler) GetEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	accesses, err := h.service.GetEmergencyAccess(r.Context())
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if accesses == nil {
+		accesses = []entities.EmergencyAccess{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(accesses)
+}
+
+// AcceptEmergencyAccess - согласиться быть доверенным лицом
+func (h *GophkeeperHandler) AcceptEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	h.moveEmergencyAccess(w, r, h.service.AcceptEmergencyAccess)
+}
+
+// RequestEmergencyAccess - запросить доступ к хранилищу доверителя (начинается период ожидания)
+func (h *GophkeeperHandler) RequestEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	h.moveEmergencyAccess(w, r, h.service.RequestEmergencyAccess)
+}
+
+// ApproveEmergencyAccess - предоставить запрошенный доступ, не дожидаясь окончания ожидания
+func (h *GophkeeperHandler) ApproveEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	h.moveEmergencyAccess(w, r, h.service.ApproveEmergencyAccess)
+}
+
+// RejectEmergencyAccess - отклонить запрос доступа или закрыть предоставленный доступ
+func (h *GophkeeperHandler) RejectEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	h.moveEmergencyAccess(w, r, h.service.RejectEmergencyAccess)
+}
+
+// moveEmergencyAccess - перевести экстренный доступ из пути запроса в следующее состояние
+func (h *GophkeeperHandler) moveEmergencyAccess(w http.ResponseWriter, r *http.Request,
+	move func(ctx context.Context, id string) (*entities.EmergencyAccess, error)) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	access, err := move(r.Context(), id)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if access == nil {
+		http.Error(w, "Emergency access not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(access)
+}
+
+// DeleteEmergencyAccess - отменить экстренный доступ (доверитель или доверенное лицо)
+func (h *GophkeeperHandler) DeleteEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.service.DeleteEmergencyAccess(r.Context(), id)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if deleted == nil {
+		http.Error(w, "Emergency access not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusGone)
+}
+
+// OpenEmergencyVault - открыть хранилище доверителя по предоставленному экстренному доступу
+func (h *GophkeeperHandler) OpenEmergencyVault(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	vault, err := h.service.OpenEmergencyVault(r.Context(), id)
+	if err != nil {
+		var statusCode = http.StatusInternalServerError
+
+		var httpErr *customerrors.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+		}
+
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if vault == nil {
+		http.Error(w, "Emergency access not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(vault)
+}
+
+// GetUsage - получить использование хранилища пользователем и действующую для него квоту
+func (h *GophkeeperHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	usage, err := h.service.GetUsage(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(usage)
+}
+
+// DeleteAccount - удалить учётную запись текущего пользователя со всеми данными после повторного ввода пароля
+func (h *GophkeeperHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Только Content-Type: JSON
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Password == "" {
+		http.Error(w, "Password is required", http.StatusBadRequest)
+		return
+	}
+
+	// Повторно проверяем пароль: украденной куки недостаточно, чтобы удалить учётную запись
+	user, err := h.service.GetUser(r.Context(), login)
+	if err != nil || user == nil || !hash.CheckPasswordHash(req.Password, user.Password) {
+		metrics.AuthFailures.WithLabelValues(metrics.AuthReasonInvalidCredentials).Inc()
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := h.service.DeleteOwnAccount(r.Context()); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	auth.ClearJWTCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExportAccount - выгрузить учётную запись текущего пользователя zip-архивом: account.json со сведениями об учётной записи,
+// ключами и выданными правами и по файлу JSON на каждый тип записей (поля записей остаются зашифрованными)
+func (h *GophkeeperHandler) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.service.ExportAccount(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"account.json", struct {
+			Account    entities.Account      `json:"account"`
+			ExportedAt time.Time             `json:"exported_at"`
+			Keys       *entities.UserKeys    `json:"keys,omitempty"`
+			Shares     []entities.ShareGrant `json:"shares"`
+		}{export.Account, export.ExportedAt, export.Keys, export.Shares}},
+		{"binaries.json", export.Binaries},
+		{"cards.json", export.Cards},
+		{"credentials.json", export.Credentials},
+		{"texts.json", export.Texts},
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gophkeeper-%s.zip"`, export.ExportedAt.Format("20060102-150405")))
+	w.WriteHeader(http.StatusOK)
+
+	// Архив пишется прямо в ответ: заголовки уже отправлены, поэтому об ошибке записи клиент узнает по оборванному архиву
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return
+		}
+
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return
+		}
+	}
+	archive.Close()
+}
+
+// GetTrash - получить записи в корзине, которые пользователь может восстановить
+func (h *GophkeeperHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	items, err := h.service.GetTrash(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if items == nil {
+		items = []entities.TrashItem{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(items)
+}
+
+// RestoreTrashItem - вернуть запись из корзины
+func (h *GophkeeperHandler) RestoreTrashItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	entityType, id, ok := entryTarget(w, r)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.RestoreTrashItem(r.Context(), entityType, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if item == nil {
+		http.Error(w, "Entry not found in trash", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(item)
+}
+
+// DeleteTrashItem - окончательно удалить запись из корзины
+func (h *GophkeeperHandler) DeleteTrashItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	entityType, id, ok := entryTarget(w, r)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.PurgeTrashItem(r.Context(), entityType, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if item == nil {
+		http.Error(w, "Entry not found in trash", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusGone)
+}
+
+// GetHistory - получить прежние версии записи, которые пользователь может расшифровать
+func (h *GophkeeperHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	entityType, id, ok := entryTarget(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.service.GetHistory(r.Context(), entityType, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if revisions == nil {
+		revisions = []entities.Revision{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// RestoreRevision - вернуть запись к прежней версии
+func (h *GophkeeperHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	entityType, id, ok := entryTarget(w, r)
+	if !ok {
+		return
+	}
+
+	revision, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil || revision <= 0 {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	entity, err := h.service.RestoreRevision(r.Context(), entityType, id, revision)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entity)
+}
+
+// entryTarget - прочитать из пути тип и идентификатор записи (в корзине или с историей). При ошибке ответ уже отправлен
+func entryTarget(w http.ResponseWriter, r *http.Request) (services.EntityType, string, bool) {
+	login := customcontext.GetUserID(r.Context())
+	if login == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return 0, "", false
+	}
+
+	entityType, ok := services.ParseEntityType(chi.URLParam(r, "type"))
+	if !ok || (entityType != services.EntityBinary && entityType != services.EntityCard &&
+		entityType != services.EntityCredentials && entityType != services.EntityText) {
+		http.Error(w, "Unknown entity type", http.StatusBadRequest)
+		return 0, "", false
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		return 0, "", false
+	}
+
+	return entityType, id, true
+}
+
+// quotaErrorResponse - тело ответа о превышении квоты
+type quotaErrorResponse struct {
+	Error string                   `json:"error"`
+	Quota *customerrors.QuotaError `json:"quota"`
+}
+
+// writeServiceError - ответить ошибкой сервиса (код из customerrors.HTTPError, иначе 500).
+// О превышении квоты отвечает JSON с её ресурсом, значением и использованием, чтобы клиент мог их показать
+func writeServiceError(w http.ResponseWriter, err error) {
+	var statusCode = http.StatusInternalServerError
+
+	var httpErr *customerrors.HTTPError
+	if errors.As(err, &httpErr) {
+		statusCode = httpErr.Code
+	}
+
+	var quotaErr *customerrors.QuotaError
+	if errors.As(err, &quotaErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(quotaErrorResponse{Error: quotaErr.Error(), Quota: quotaErr})
+		return
+	}
+
+	http.Error(w, err.Error(), statusCode)
+}
@@ -2,20 +2,29 @@
 package handlers_test
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
 	"github.com/JustScorpio/GophKeeper/backend/internal/handlers"
 	"github.com/JustScorpio/GophKeeper/backend/internal/hash"
 	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/auth"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/gzipencoder"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/logger"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
 	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/inmemory"
 	"github.com/JustScorpio/GophKeeper/backend/internal/services"
 	"github.com/go-chi/chi"
@@ -75,6 +84,13 @@ func createTestHandlerAndRouter() (*chi.Mux, *inmemory.DatabaseManager) {
 		dbManager.Cards,
 		dbManager.Credentials,
 		dbManager.Texts,
+		dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithOrganizations(dbManager.Orgs),
+		services.WithEmergencyAccess(dbManager.Emergency, 0),
+		services.WithTrash(dbManager.Trash, time.Hour, 0),
+		services.WithHistory(dbManager.History, 0, 0),
 	)
 	handler := handlers.NewGophkeeperHandler(service)
 
@@ -125,6 +141,45 @@ func createTestHandlerAndRouter() (*chi.Mux, *inmemory.DatabaseManager) {
 		r.Get("/texts/{id}", handler.GetText)
 		r.Put("/texts", handler.UpdateText)
 		r.Delete("/texts/{id}", handler.DeleteText)
+
+		// Audit log endpoint
+		r.Get("/audit", handler.GetAuditLog)
+
+		// Sharing endpoints
+		r.Put("/keys", handler.SetUserKeys)
+		r.Get("/keys", handler.GetUserKeys)
+		r.Get("/keys/{login}", handler.GetUserKeys)
+		r.Post("/shares", handler.CreateShare)
+		r.Get("/shares", handler.GetShares)
+		r.Post("/shares/{id}/accept", handler.AcceptShare)
+		r.Delete("/shares/{id}", handler.DeleteShare)
+
+		// Organization endpoints
+		r.Post("/orgs", handler.CreateOrganization)
+		r.Get("/orgs", handler.GetOrganizations)
+		r.Get("/orgs/{id}/members", handler.GetOrgMembers)
+		r.Post("/orgs/{id}/members", handler.InviteMember)
+		r.Post("/orgs/{id}/members/accept", handler.AcceptInvite)
+		r.Delete("/orgs/{id}/members/{login}", handler.RemoveMember)
+		r.Get("/orgs/{id}/collections", handler.GetCollections)
+		r.Post("/orgs/{id}/collections", handler.CreateCollection)
+		r.Post("/collections/{id}/rotate", handler.RotateCollectionKey)
+
+		r.Post("/emergency", handler.InviteEmergencyContact)
+		r.Get("/emergency", handler.GetEmergencyAccess)
+		r.Post("/emergency/{id}/accept", handler.AcceptEmergencyAccess)
+		r.Post("/emergency/{id}/request", handler.RequestEmergencyAccess)
+		r.Post("/emergency/{id}/approve", handler.ApproveEmergencyAccess)
+		r.Post("/emergency/{id}/reject", handler.RejectEmergencyAccess)
+		r.Delete("/emergency/{id}", handler.DeleteEmergencyAccess)
+		r.Get("/emergency/{id}/vault", handler.OpenEmergencyVault)
+
+		r.Get("/trash", handler.GetTrash)
+		r.Post("/trash/{type}/{id}/restore", handler.RestoreTrashItem)
+		r.Delete("/trash/{type}/{id}", handler.DeleteTrashItem)
+
+		r.Get("/{type}/{id}/history", handler.GetHistory)
+		r.Post("/{type}/{id}/history/{rev}/restore", handler.RestoreRevision)
 	})
 
 	return router, dbManager
@@ -1246,6 +1301,33 @@ func TestDataSizeLimits(t *testing.T) {
 
 		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
 	})
+
+	t.Run("Лимиты из конфигурации", func(t *testing.T) {
+		dbManager := inmemory.NewDatabaseManager()
+		service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access)
+		handler := handlers.NewGophkeeperHandler(service, handlers.WithDataLimits(16, 8))
+
+		limitedRouter := chi.NewRouter()
+		limitedRouter.Post("/register", handler.Register)
+		limitedRouter.Post("/api/user/binaries", handler.CreateBinary)
+		limitedRouter.Post("/api/user/texts", handler.CreateText)
+		registerTestUser(t, limitedRouter, "limituser", "password123")
+
+		req := createTestRequest("POST", "/api/user/binaries", dtos.NewBinaryData{Data: make([]byte, 17)}, true, "limituser")
+		w := httptest.NewRecorder()
+		limitedRouter.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		req = createTestRequest("POST", "/api/user/binaries", dtos.NewBinaryData{Data: make([]byte, 16)}, true, "limituser")
+		w = httptest.NewRecorder()
+		limitedRouter.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		req = createTestRequest("POST", "/api/user/texts", dtos.NewTextData{Data: "123456789"}, true, "limituser")
+		w = httptest.NewRecorder()
+		limitedRouter.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
 }
 
 // TestCookieHandling - тесты работы с cookies
@@ -1595,3 +1677,953 @@ func TestInvalidJSON(t *testing.T) {
 		})
 	}
 }
+
+// TestAuditLog - журнал операций пользователя
+func TestAuditLog(t *testing.T) {
+	router, _ := createTestHandlerAndRouter()
+	testData := getTestData()
+
+	registerTestUser(t, router, "user1", testUsers["user1"])
+	registerTestUser(t, router, "user2", testUsers["user2"])
+
+	created := createCredentials(t, router, "user1", testData.credentials)
+	createText(t, router, "user1", testData.text)
+	createText(t, router, "user2", testData.text)
+
+	t.Run("Получение собственной истории", func(t *testing.T) {
+		req := createTestRequest("GET", "/api/user/audit", nil, true, "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var events []entities.AuditEvent
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+
+		// Регистрация и две созданные записи
+		require.Len(t, events, 3)
+		assert.Equal(t, "user", events[0].EntityType)
+		assert.Equal(t, "credentials", events[1].EntityType)
+		assert.Equal(t, created.ID, events[1].EntityID)
+		for _, event := range events {
+			assert.Equal(t, "user1", event.UserID)
+		}
+	})
+
+	t.Run("Фильтр по типу сущности", func(t *testing.T) {
+		req := createTestRequest("GET", "/api/user/audit?type=text", nil, true, "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var events []entities.AuditEvent
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+		require.Len(t, events, 1)
+		assert.Equal(t, "create", events[0].Action)
+	})
+
+	t.Run("Фильтр по периоду", func(t *testing.T) {
+		from := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		req := createTestRequest("GET", "/api/user/audit?from="+from, nil, true, "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, "[]", w.Body.String())
+	})
+
+	t.Run("Некорректные параметры", func(t *testing.T) {
+		for _, query := range []string{"from=yesterday", "to=2024-13-01", "type=unknown"} {
+			req := createTestRequest("GET", "/api/user/audit?"+query, nil, true, "user1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("Без аутентификации", func(t *testing.T) {
+		req := createTestRequest("GET", "/api/user/audit", nil, false, "")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+// TestSharing - обмен записями между пользователями
+func TestSharing(t *testing.T) {
+	router, _ := createTestHandlerAndRouter()
+	testData := getTestData()
+
+	registerTestUser(t, router, "user1", testUsers["user1"])
+	registerTestUser(t, router, "user2", testUsers["user2"])
+
+	for _, login := range []string{"user1", "user2"} {
+		keys := entities.UserKeys{PublicKey: login + "-public", EncryptedPrivateKey: login + "-private"}
+		req := createTestRequest("PUT", "/api/user/keys", keys, true, login)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	t.Run("Ключи", func(t *testing.T) {
+		req := createTestRequest("GET", "/api/user/keys", nil, true, "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var own entities.UserKeys
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &own))
+		assert.Equal(t, "user1", own.Login)
+		assert.Equal(t, "user1-private", own.EncryptedPrivateKey)
+
+		req = createTestRequest("GET", "/api/user/keys/user1", nil, true, "user2")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "user1-private")
+
+		req = createTestRequest("GET", "/api/user/keys/unknown", nil, true, "user2")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		req = createTestRequest("PUT", "/api/user/keys", entities.UserKeys{PublicKey: "public"}, true, "user1")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	credentials := createCredentials(t, router, "user1", testData.credentials)
+	credentials.EntryKey = "user1-envelope"
+	req := createTestRequest("PUT", "/api/user/credentials", credentials, true, "user1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	share := dtos.NewShareGrant{EntityType: "credentials", EntityID: credentials.ID, RecipientID: "user2", Permission: entities.PermissionRead, EntryKey: "user2-envelope"}
+
+	t.Run("Валидация", func(t *testing.T) {
+		invalid := []dtos.NewShareGrant{
+			{EntityType: "audit", EntityID: credentials.ID, RecipientID: "user2", Permission: "read", EntryKey: "key"},
+			{EntityType: "credentials", RecipientID: "user2", Permission: "read", EntryKey: "key"},
+			{EntityType: "credentials", EntityID: credentials.ID, RecipientID: "user2", Permission: "admin", EntryKey: "key"},
+			{EntityType: "credentials", EntityID: credentials.ID, RecipientID: "user2", Permission: "read"},
+			{EntityType: "credentials", EntityID: credentials.ID, RecipientID: "user1", Permission: "read", EntryKey: "key"},
+		}
+		for _, grant := range invalid {
+			req := createTestRequest("POST", "/api/user/shares", grant, true, "user1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, grant)
+		}
+
+		// Чужая запись
+		foreign := share
+		foreign.RecipientID = "user1"
+		req := createTestRequest("POST", "/api/user/shares", foreign, true, "user2")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	var grant entities.ShareGrant
+	t.Run("Приглашение и принятие", func(t *testing.T) {
+		req := createTestRequest("POST", "/api/user/shares", share, true, "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &grant))
+		assert.Equal(t, entities.ShareStatusPending, grant.Status)
+
+		req = createTestRequest("GET", "/api/user/shares", nil, true, "user2")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var grants []entities.ShareGrant
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &grants))
+		require.Len(t, grants, 1)
+
+		req = createTestRequest("POST", "/api/user/shares/"+grant.ID+"/accept", nil, true, "user1")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		req = createTestRequest("POST", "/api/user/shares/"+grant.ID+"/accept", nil, true, "user2")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		req = createTestRequest("GET", "/api/user/credentials/"+credentials.ID, nil, true, "user2")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var shared entities.Credentials
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+		assert.Equal(t, "user2-envelope", shared.EntryKey)
+		assert.Equal(t, entities.PermissionRead, shared.Permission)
+	})
+
+	t.Run("Отзыв", func(t *testing.T) {
+		req := createTestRequest("DELETE", "/api/user/shares/"+grant.ID, nil, true, "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusGone, w.Code)
+
+		req = createTestRequest("GET", "/api/user/credentials/"+credentials.ID, nil, true, "user2")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		req = createTestRequest("DELETE", "/api/user/shares/"+grant.ID, nil, true, "user1")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// TestOrganizations - организации, участники и коллекции
+func TestOrganizations(t *testing.T) {
+	router, _ := createTestHandlerAndRouter()
+
+	// serve - выполнить запрос от имени пользователя
+	serve := func(method, url string, body interface{}, login string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest(method, url, body, true, login))
+		return w
+	}
+
+	for _, login := range []string{"user1", "user2"} {
+		registerTestUser(t, router, login, testUsers[login])
+		keys := entities.UserKeys{PublicKey: login + "-public", EncryptedPrivateKey: login + "-private"}
+		require.Equal(t, http.StatusOK, serve("PUT", "/api/user/keys", keys, login).Code)
+	}
+
+	w := serve("POST", "/api/user/orgs", dtos.NewOrganization{Name: "team"}, "user1")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var org entities.Organization
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &org))
+	assert.Equal(t, entities.RoleOwner, org.Role)
+
+	newCollection := dtos.NewCollection{Name: "shared", Keys: []entities.CollectionKey{{Login: "user1", Version: 1, EncryptedKey: "user1-key"}}}
+	w = serve("POST", "/api/user/orgs/"+org.ID+"/collections", newCollection, "user1")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var collection entities.Collection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+
+	t.Run("Валидация", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/user/orgs", dtos.NewOrganization{}, "user1").Code)
+		assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/user/orgs/"+org.ID+"/collections", dtos.NewCollection{}, "user1").Code)
+		assert.Equal(t, http.StatusConflict, serve("POST", "/api/user/orgs/"+org.ID+"/collections", newCollection, "user1").Code)
+
+		invalid := []dtos.NewOrgMember{
+			{Role: entities.RoleMember},
+			{Login: "user2", Role: entities.RoleOwner},
+			{Login: "user2", Role: "guest"},
+		}
+		for _, member := range invalid {
+			assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/user/orgs/"+org.ID+"/members", member, "user1").Code, member)
+		}
+
+		// Без ключа коллекции приглашённый не сможет читать записи
+		member := dtos.NewOrgMember{Login: "user2", Role: entities.RoleMember}
+		assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/user/orgs/"+org.ID+"/members", member, "user1").Code)
+
+		// Посторонний не видит организацию
+		assert.Equal(t, http.StatusNotFound, serve("GET", "/api/user/orgs/"+org.ID+"/members", nil, "user2").Code)
+	})
+
+	t.Run("Приглашение и запись коллекции", func(t *testing.T) {
+		member := dtos.NewOrgMember{Login: "user2", Role: entities.RoleReadOnly,
+			Keys: []entities.CollectionKey{{CollectionID: collection.ID, Login: "user2", Version: 1, EncryptedKey: "user2-key"}}}
+		require.Equal(t, http.StatusCreated, serve("POST", "/api/user/orgs/"+org.ID+"/members", member, "user1").Code)
+
+		w := serve("GET", "/api/user/orgs", nil, "user2")
+		require.Equal(t, http.StatusOK, w.Code)
+		var orgs []entities.Organization
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &orgs))
+		require.Len(t, orgs, 1)
+		assert.Equal(t, entities.MemberStatusInvited, orgs[0].Status)
+
+		require.Equal(t, http.StatusOK, serve("POST", "/api/user/orgs/"+org.ID+"/members/accept", nil, "user2").Code)
+		assert.Equal(t, http.StatusNotFound, serve("POST", "/api/user/orgs/"+org.ID+"/members/accept", nil, "user2").Code)
+
+		text := dtos.NewTextData{Data: "team secret", NewSecureEntity: dtos.NewSecureEntity{CollectionID: collection.ID, KeyVersion: 1}}
+		assert.Equal(t, http.StatusForbidden, serve("POST", "/api/user/texts", text, "user2").Code)
+		require.Equal(t, http.StatusCreated, serve("POST", "/api/user/texts", text, "user1").Code)
+
+		w = serve("GET", "/api/user/texts", nil, "user2")
+		require.Equal(t, http.StatusOK, w.Code)
+		var texts []entities.TextData
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &texts))
+		require.Len(t, texts, 1)
+		assert.Equal(t, "user2-key", texts[0].EntryKey)
+
+		w = serve("GET", "/api/user/orgs/"+org.ID+"/collections", nil, "user2")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "user2-key")
+	})
+
+	t.Run("Исключение и замена ключа", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve("DELETE", "/api/user/orgs/"+org.ID+"/members/user1", nil, "user2").Code)
+		require.Equal(t, http.StatusGone, serve("DELETE", "/api/user/orgs/"+org.ID+"/members/user2", nil, "user1").Code)
+		assert.Equal(t, http.StatusNotFound, serve("DELETE", "/api/user/orgs/"+org.ID+"/members/user2", nil, "user1").Code)
+
+		rotation := dtos.CollectionRotation{Version: 2, Keys: []entities.CollectionKey{{CollectionID: collection.ID, Login: "user1", Version: 2, EncryptedKey: "user1-key-2"}}}
+		w := serve("POST", "/api/user/collections/"+collection.ID+"/rotate", rotation, "user1")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var rotated entities.Collection
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+		assert.Equal(t, 2, rotated.KeyVersion)
+		assert.Equal(t, "user1-key-2", rotated.EncryptedKey)
+
+		assert.Equal(t, http.StatusConflict, serve("POST", "/api/user/collections/"+collection.ID+"/rotate", rotation, "user1").Code)
+	})
+}
+
+// TestEvents - поток событий об изменениях
+func TestEvents(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	hub := notifications.NewHub()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithNotifier(hub))
+	defer service.Shutdown()
+	handler := handlers.NewGophkeeperHandler(service, handlers.WithNotifications(hub), handlers.WithKeepAlive(50*time.Millisecond))
+
+	zapLogger, err := logger.NewLogger("Error", false)
+	require.NoError(t, err)
+
+	// Поток проходит через middleware, оборачивающие ResponseWriter (сжатие и логирование)
+	router := chi.NewRouter()
+	router.Use(logger.LoggingMiddleware(zapLogger))
+	router.Use(gzipencoder.GZIPEncodingMiddleware())
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := customcontext.WithUserID(r.Context(), r.Header.Get("X-Test-User"))
+			ctx = customcontext.WithSessionID(ctx, r.Header.Get("X-Test-Session"))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	router.Post("/texts", handler.CreateText)
+	router.Get("/events", handler.Events)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+	server.Config.RegisterOnShutdown(hub.Close)
+
+	// newRequest - запрос от имени пользователя в указанной сессии
+	newRequest := func(method, path string, body interface{}, user, session string) *http.Request {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &payload)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		req.Header.Set("X-Test-Session", session)
+		return req
+	}
+
+	// readEvent - прочитать из потока следующее событие (комментарии пропускаются)
+	readEvent := func(reader *bufio.Reader) (string, string) {
+		var name, data string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimRight(line, "\n")
+
+			switch {
+			case line == "" && name != "":
+				return name, data
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+
+	t.Run("Изменение на другом устройстве", func(t *testing.T) {
+		resp, err := http.DefaultClient.Do(newRequest("GET", "/events", nil, "user1", "phone"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		reader := bufio.NewReader(resp.Body)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, ": connected\n", line)
+
+		createResp, err := http.DefaultClient.Do(newRequest("POST", "/texts", dtos.NewTextData{Data: "note"}, "user1", "laptop"))
+		require.NoError(t, err)
+		var created entities.TextData
+		require.NoError(t, json.NewDecoder(createResp.Body).Decode(&created))
+		createResp.Body.Close()
+
+		name, data := readEvent(reader)
+		assert.Equal(t, "change", name)
+
+		var event notifications.Event
+		require.NoError(t, json.Unmarshal([]byte(data), &event))
+		assert.Equal(t, "create", event.Action)
+		assert.Equal(t, "text", event.EntityType)
+		assert.Equal(t, created.ID, event.EntityID)
+		assert.NotContains(t, data, "laptop", "session ID must not be exposed")
+	})
+
+	t.Run("Без аутентификации", func(t *testing.T) {
+		resp, err := http.DefaultClient.Do(newRequest("GET", "/events", nil, "", ""))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Уведомления отключены", func(t *testing.T) {
+		plainHandler := handlers.NewGophkeeperHandler(service)
+		req := createTestRequest("GET", "/events", nil, true, "user1")
+		w := httptest.NewRecorder()
+		plainHandler.Events(w, req)
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+
+	t.Run("Остановка сервера завершает поток", func(t *testing.T) {
+		resp, err := http.DefaultClient.Do(newRequest("GET", "/events", nil, "user1", "phone"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Eventually(t, func() bool { return hub.Subscribers("user1") == 1 }, time.Second, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		require.NoError(t, server.Config.Shutdown(ctx))
+
+		_, err = io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+	})
+}
+
+func TestEmergencyAccess(t *testing.T) {
+	router, _ := createTestHandlerAndRouter()
+
+	// serve - выполнить запрос от имени пользователя
+	serve := func(method, url string, body interface{}, login string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest(method, url, body, true, login))
+		return w
+	}
+
+	for _, login := range []string{"user1", "user2"} {
+		registerTestUser(t, router, login, testUsers[login])
+		keys := entities.UserKeys{PublicKey: login + "-public", EncryptedPrivateKey: login + "-private"}
+		require.Equal(t, http.StatusOK, serve("PUT", "/api/user/keys", keys, login).Code)
+	}
+
+	createText(t, router, "user1", dtos.NewTextData{Data: "will", NewSecureEntity: dtos.NewSecureEntity{Metadata: "meta"}})
+
+	t.Run("Валидация", func(t *testing.T) {
+		invalid := []dtos.NewEmergencyAccess{
+			{WaitDays: 7, EncryptedKey: "key"},
+			{GranteeID: "user2", WaitDays: 7},
+			{GranteeID: "user2", WaitDays: 365, EncryptedKey: "key"},
+			{GranteeID: "user1", WaitDays: 7, EncryptedKey: "key"},
+		}
+		for _, access := range invalid {
+			assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/user/emergency", access, "user1").Code, access)
+		}
+	})
+
+	w := serve("POST", "/api/user/emergency", dtos.NewEmergencyAccess{GranteeID: "user2", WaitDays: 3, EncryptedKey: "vault-key"}, "user1")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var access entities.EmergencyAccess
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &access))
+	assert.Equal(t, entities.EmergencyStatusInvited, access.Status)
+
+	url := "/api/user/emergency/" + access.ID
+
+	assert.Equal(t, http.StatusConflict, serve("POST", url+"/request", nil, "user2").Code)
+	assert.Equal(t, http.StatusForbidden, serve("POST", url+"/accept", nil, "user1").Code)
+	assert.Equal(t, http.StatusOK, serve("POST", url+"/accept", nil, "user2").Code)
+	assert.Equal(t, http.StatusOK, serve("POST", url+"/request", nil, "user2").Code)
+	assert.Equal(t, http.StatusConflict, serve("GET", url+"/vault", nil, "user2").Code)
+	assert.Equal(t, http.StatusOK, serve("POST", url+"/approve", nil, "user1").Code)
+
+	w = serve("GET", url+"/vault", nil, "user2")
+	require.Equal(t, http.StatusOK, w.Code)
+	var vault entities.EmergencyVault
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vault))
+	assert.Equal(t, "vault-key", vault.Access.EncryptedKey)
+	assert.Equal(t, "user1-private", vault.EncryptedPrivateKey)
+	assert.Len(t, vault.Texts, 1)
+
+	// Ключ хранилища видит только доверенное лицо
+	w = serve("GET", "/api/user/emergency", nil, "user1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var accesses []entities.EmergencyAccess
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accesses))
+	require.Len(t, accesses, 1)
+	assert.Empty(t, accesses[0].EncryptedKey)
+
+	assert.Equal(t, http.StatusOK, serve("POST", url+"/reject", nil, "user1").Code)
+	assert.Equal(t, http.StatusConflict, serve("GET", url+"/vault", nil, "user2").Code)
+	assert.Equal(t, http.StatusGone, serve("DELETE", url, nil, "user2").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", url+"/vault", nil, "user2").Code)
+}
+
+func TestTrash(t *testing.T) {
+	router, _ := createTestHandlerAndRouter()
+
+	// serve - выполнить запрос от имени пользователя
+	serve := func(method, url string, body interface{}, login string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest(method, url, body, true, login))
+		return w
+	}
+
+	registerTestUser(t, router, "user1", testUsers["user1"])
+	text := createText(t, router, "user1", dtos.NewTextData{Data: "note", NewSecureEntity: dtos.NewSecureEntity{Metadata: "meta"}})
+
+	// getTrash - содержимое корзины пользователя
+	getTrash := func(login string) []entities.TrashItem {
+		w := serve("GET", "/api/user/trash", nil, login)
+		require.Equal(t, http.StatusOK, w.Code)
+		var items []entities.TrashItem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		return items
+	}
+
+	assert.Empty(t, getTrash("user1"))
+	assert.Equal(t, http.StatusGone, serve("DELETE", "/api/user/texts/"+text.ID, nil, "user1").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/api/user/texts/"+text.ID, nil, "user1").Code)
+
+	items := getTrash("user1")
+	require.Len(t, items, 1)
+	assert.Equal(t, "text", items[0].EntityType)
+	assert.Equal(t, "meta", items[0].Metadata)
+	assert.Empty(t, getTrash("user2"))
+
+	url := "/api/user/trash/text/" + text.ID
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/user/trash/user/"+text.ID+"/restore", nil, "user1").Code)
+	assert.Equal(t, http.StatusNotFound, serve("POST", url+"/restore", nil, "user2").Code)
+	assert.Equal(t, http.StatusOK, serve("POST", url+"/restore", nil, "user1").Code)
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/user/texts/"+text.ID, nil, "user1").Code)
+	assert.Equal(t, http.StatusNotFound, serve("POST", url+"/restore", nil, "user1").Code)
+
+	assert.Equal(t, http.StatusGone, serve("DELETE", "/api/user/texts/"+text.ID, nil, "user1").Code)
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", url, nil, "user2").Code)
+	assert.Equal(t, http.StatusGone, serve("DELETE", url, nil, "user1").Code)
+	assert.Empty(t, getTrash("user1"))
+	assert.Equal(t, http.StatusNotFound, serve("POST", url+"/restore", nil, "user1").Code)
+}
+
+func TestHistory(t *testing.T) {
+	router, _ := createTestHandlerAndRouter()
+
+	// serve - выполнить запрос от имени пользователя
+	serve := func(method, url string, body interface{}, login string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest(method, url, body, true, login))
+		return w
+	}
+
+	registerTestUser(t, router, "user1", testUsers["user1"])
+	creds := createCredentials(t, router, "user1", dtos.NewCredentials{Login: "login", Password: "old", NewSecureEntity: dtos.NewSecureEntity{Metadata: "meta"}})
+	url := "/api/user/credentials/" + creds.ID + "/history"
+
+	// getHistory - версии записи в представлении пользователя
+	getHistory := func(login string) []entities.Revision {
+		w := serve("GET", url, nil, login)
+		require.Equal(t, http.StatusOK, w.Code)
+		var revisions []entities.Revision
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revisions))
+		return revisions
+	}
+
+	assert.Empty(t, getHistory("user1"))
+
+	creds.Password = "new"
+	require.Equal(t, http.StatusOK, serve("PUT", "/api/user/credentials", creds, "user1").Code)
+
+	revisions := getHistory("user1")
+	require.Len(t, revisions, 1)
+	assert.Equal(t, 1, revisions[0].Revision)
+	assert.JSONEq(t, `{"login":"login","password":"old"}`, string(revisions[0].Content))
+
+	assert.Equal(t, http.StatusNotFound, serve("GET", url, nil, "user2").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/api/user/user/"+creds.ID+"/history", nil, "user1").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("POST", url+"/first/restore", nil, "user1").Code)
+	assert.Equal(t, http.StatusNotFound, serve("POST", url+"/7/restore", nil, "user1").Code)
+
+	w := serve("POST", url+"/1/restore", nil, "user1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var restored entities.Credentials
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &restored))
+	assert.Equal(t, "old", restored.Password)
+	assert.Len(t, getHistory("user1"), 2)
+
+	// Маршрут истории не мешает маршрутам самих записей
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/user/credentials/"+creds.ID, nil, "user1").Code)
+}
+
+func TestAdminAPI(t *testing.T) {
+	const adminToken = "admin-token-0123456789"
+
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
+		services.WithAccounts(dbManager.Accounts))
+	defer service.Shutdown()
+	handler := handlers.NewGophkeeperHandler(service)
+	adminHandler := handlers.NewAdminHandler(service)
+
+	auth.Init("649f7b24-76ea-4f15-bd32-31fab91d63f6")
+
+	router := chi.NewRouter()
+	router.Post("/register", handler.Register)
+	router.Post("/login", handler.Login)
+	router.Route("/api/admin", func(r chi.Router) {
+		r.Use(auth.AdminMiddleware(adminToken))
+		r.Get("/users", adminHandler.GetAccounts)
+		r.Post("/users/{login}/disable", adminHandler.DisableAccount)
+		r.Post("/users/{login}/enable", adminHandler.EnableAccount)
+		r.Post("/users/{login}/logout", adminHandler.RevokeSessions)
+		r.Delete("/users/{login}", adminHandler.DeleteAccount)
+		r.Get("/stats", adminHandler.GetStats)
+		r.Get("/audit", adminHandler.GetAuditLog)
+	})
+
+	// serve - выполнить запрос администратора с указанным токеном
+	serve := func(method, url, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	registerTestUser(t, router, "user1", testUsers["user1"])
+	registerTestUser(t, router, "user2", testUsers["user2"])
+
+	t.Run("Токен администратора", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve("GET", "/api/admin/users", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve("GET", "/api/admin/users", "wrong-token").Code)
+		assert.Equal(t, http.StatusOK, serve("GET", "/api/admin/users", adminToken).Code)
+	})
+
+	t.Run("Список учётных записей", func(t *testing.T) {
+		w := serve("GET", "/api/admin/users", adminToken)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var accounts []entities.Account
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accounts))
+		require.Len(t, accounts, 2)
+		assert.Equal(t, "user1", accounts[0].Login)
+	})
+
+	t.Run("Заблокированный пользователь не входит", func(t *testing.T) {
+		w := serve("POST", "/api/admin/users/user1/disable", adminToken)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var account entities.Account
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+		assert.True(t, account.Disabled)
+
+		login := createTestRequest("POST", "/login", dtos.NewUser{Login: "user1", Password: testUsers["user1"]}, false, "")
+		lw := httptest.NewRecorder()
+		router.ServeHTTP(lw, login)
+		assert.Equal(t, http.StatusForbidden, lw.Code)
+
+		require.Equal(t, http.StatusOK, serve("POST", "/api/admin/users/user1/enable", adminToken).Code)
+
+		login = createTestRequest("POST", "/login", dtos.NewUser{Login: "user1", Password: testUsers["user1"]}, false, "")
+		lw = httptest.NewRecorder()
+		router.ServeHTTP(lw, login)
+		assert.Equal(t, http.StatusOK, lw.Code)
+	})
+
+	t.Run("Завершение сессий и удаление", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("POST", "/api/admin/users/user2/logout", adminToken).Code)
+		assert.Equal(t, http.StatusNotFound, serve("POST", "/api/admin/users/nobody/logout", adminToken).Code)
+
+		assert.Equal(t, http.StatusGone, serve("DELETE", "/api/admin/users/user2", adminToken).Code)
+		assert.Equal(t, http.StatusNotFound, serve("DELETE", "/api/admin/users/user2", adminToken).Code)
+	})
+
+	t.Run("Статистика и журнал", func(t *testing.T) {
+		w := serve("GET", "/api/admin/stats", adminToken)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var stats entities.ServerStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		assert.Equal(t, 1, stats.Users)
+
+		w = serve("GET", "/api/admin/audit?login=user2", adminToken)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var events []entities.AuditEvent
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+		require.Len(t, events, 2)
+		assert.Equal(t, "revoke", events[0].Action)
+		assert.Equal(t, "delete", events[1].Action)
+
+		assert.Equal(t, http.StatusBadRequest, serve("GET", "/api/admin/audit?from=yesterday", adminToken).Code)
+	})
+}
+
+func TestQuotas(t *testing.T) {
+	const adminToken = "admin-token-0123456789"
+
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithAccounts(dbManager.Accounts),
+		services.WithQuota(entities.Quota{MaxEntries: 1, MaxBytes: 1024}))
+	defer service.Shutdown()
+	handler := handlers.NewGophkeeperHandler(service)
+	adminHandler := handlers.NewAdminHandler(service)
+
+	router := chi.NewRouter()
+	router.Post("/api/user/texts", handler.CreateText)
+	router.Get("/api/user/usage", handler.GetUsage)
+	router.Route("/api/admin", func(r chi.Router) {
+		r.Use(auth.AdminMiddleware(adminToken))
+		r.Put("/users/{login}/quota", adminHandler.SetQuota)
+		r.Delete("/users/{login}/quota", adminHandler.ResetQuota)
+	})
+
+	_, err := service.CreateUser(context.Background(), dtos.NewUser{Login: "user1", Password: testUsers["user1"]})
+	require.NoError(t, err)
+
+	// serveAdmin - выполнить запрос администратора
+	serveAdmin := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		req := createTestRequest(method, url, body, false, "")
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	createText(t, router, "user1", dtos.NewTextData{Data: "note"})
+
+	t.Run("Превышение квоты", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("POST", "/api/user/texts", dtos.NewTextData{Data: "second"}, true, "user1"))
+		require.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var body struct {
+			Error string                  `json:"error"`
+			Quota customerrors.QuotaError `json:"quota"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, customerrors.QuotaError{Resource: "texts", Limit: 1, Used: 1, Requested: 1}, body.Quota)
+		assert.Contains(t, body.Error, "quota exceeded")
+	})
+
+	t.Run("Использование хранилища", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("GET", "/api/user/usage", nil, true, "user1"))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var usage entities.QuotaUsage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+		assert.Equal(t, 1, usage.Usage.Texts)
+		assert.Equal(t, int64(len("note")), usage.Usage.Bytes)
+		assert.Equal(t, entities.Quota{MaxEntries: 1, MaxBytes: 1024}, usage.Quota)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("GET", "/api/user/usage", nil, false, ""))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Индивидуальная квота", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serveAdmin("PUT", "/api/admin/users/user1/quota", entities.Quota{MaxBytes: -1}).Code)
+		assert.Equal(t, http.StatusNotFound, serveAdmin("PUT", "/api/admin/users/nobody/quota", entities.Quota{}).Code)
+
+		w := serveAdmin("PUT", "/api/admin/users/user1/quota", entities.Quota{MaxEntries: 5})
+		require.Equal(t, http.StatusOK, w.Code)
+		var account entities.Account
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+		require.NotNil(t, account.Quota)
+		assert.Equal(t, 5, account.Quota.MaxEntries)
+
+		createText(t, router, "user1", dtos.NewTextData{Data: "second"})
+
+		w = serveAdmin("DELETE", "/api/admin/users/user1/quota", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		account = entities.Account{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+		assert.Nil(t, account.Quota)
+	})
+}
+
+// TestInvites - тесты регистрации по приглашениям
+func TestInvites(t *testing.T) {
+	const adminToken = "admin-token-0123456789"
+
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithRegistration(entities.RegistrationInvite, dbManager.Invites))
+	defer service.Shutdown()
+	handler := handlers.NewGophkeeperHandler(service)
+	adminHandler := handlers.NewAdminHandler(service)
+
+	router := chi.NewRouter()
+	router.Post("/register", handler.Register)
+	router.Route("/api/admin", func(r chi.Router) {
+		r.Use(auth.AdminMiddleware(adminToken))
+		r.Post("/invites", adminHandler.CreateInvite)
+		r.Get("/invites", adminHandler.GetInvites)
+		r.Delete("/invites/{code}", adminHandler.DeleteInvite)
+	})
+
+	// serveAdmin - выполнить запрос администратора
+	serveAdmin := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		req := createTestRequest(method, url, body, false, "")
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// register - зарегистрировать пользователя с кодом приглашения
+	register := func(login, code string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("POST", "/register", dtos.NewUser{Login: login, Password: testUsers["user1"], InviteCode: code}, false, ""))
+		return w.Code
+	}
+
+	t.Run("Список пуст", func(t *testing.T) {
+		w := serveAdmin("GET", "/api/admin/invites", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, "[]", w.Body.String())
+	})
+
+	var invite entities.Invite
+	t.Run("Выпуск приглашения", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serveAdmin("POST", "/api/admin/invites", dtos.NewInvite{MaxUses: -1}).Code)
+
+		w := serveAdmin("POST", "/api/admin/invites", dtos.NewInvite{MaxUses: 1, LoginPattern: "user[0-9]"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invite))
+		assert.NotEmpty(t, invite.Code)
+		assert.Equal(t, 1, invite.MaxUses)
+	})
+
+	t.Run("Регистрация по приглашению", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, register("user1", ""))
+		assert.Equal(t, http.StatusForbidden, register("admin", invite.Code))
+		assert.Equal(t, http.StatusOK, register("user1", invite.Code))
+		assert.Equal(t, http.StatusForbidden, register("user2", invite.Code))
+
+		w := serveAdmin("GET", "/api/admin/invites", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var invites []entities.Invite
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invites))
+		require.Len(t, invites, 1)
+		assert.Equal(t, 1, invites[0].Uses)
+	})
+
+	t.Run("Отзыв приглашения", func(t *testing.T) {
+		assert.Equal(t, http.StatusGone, serveAdmin("DELETE", "/api/admin/invites/"+invite.Code, nil).Code)
+		assert.Equal(t, http.StatusNotFound, serveAdmin("DELETE", "/api/admin/invites/"+invite.Code, nil).Code)
+	})
+}
+
+// TestOwnAccount - тесты выгрузки и удаления пользователем собственной учётной записи
+func TestOwnAccount(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithAccounts(dbManager.Accounts))
+	defer service.Shutdown()
+	handler := handlers.NewGophkeeperHandler(service)
+
+	router := chi.NewRouter()
+	router.Post("/register", handler.Register)
+	router.Post("/api/user/texts", handler.CreateText)
+	router.Get("/api/user/export", handler.ExportAccount)
+	router.Delete("/api/user", handler.DeleteAccount)
+
+	registerTestUser(t, router, "user1", testUsers["user1"])
+	text := createText(t, router, "user1", dtos.NewTextData{Data: "encrypted", NewSecureEntity: dtos.NewSecureEntity{Metadata: "note"}})
+
+	t.Run("Выгрузка архивом", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("GET", "/api/user/export", nil, true, "user1"))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+
+		files := make(map[string][]byte)
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			require.NoError(t, err)
+			files[file.Name], err = io.ReadAll(reader)
+			require.NoError(t, err)
+			reader.Close()
+		}
+		assert.Len(t, files, 5)
+
+		var account struct {
+			Account entities.Account `json:"account"`
+		}
+		require.NoError(t, json.Unmarshal(files["account.json"], &account))
+		assert.Equal(t, "user1", account.Account.Login)
+		assert.Equal(t, 1, account.Account.Usage.Texts)
+
+		var texts []entities.TextData
+		require.NoError(t, json.Unmarshal(files["texts.json"], &texts))
+		require.Len(t, texts, 1)
+		assert.Equal(t, text.ID, texts[0].ID)
+		assert.Equal(t, "encrypted", texts[0].Data)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("GET", "/api/user/export", nil, false, ""))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Удаление с неверным паролем", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("DELETE", "/api/user", map[string]string{"password": "wrong"}, true, "user1"))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("DELETE", "/api/user", map[string]string{}, true, "user1"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		user, err := service.GetUser(context.Background(), "user1")
+		require.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+
+	t.Run("Удаление учётной записи", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createTestRequest("DELETE", "/api/user", map[string]string{"password": testUsers["user1"]}, true, "user1"))
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Empty(t, cookies[0].Value)
+		assert.Negative(t, cookies[0].MaxAge)
+
+		user, err := service.GetUser(context.Background(), "user1")
+		require.NoError(t, err)
+		assert.Nil(t, user)
+
+		usage, err := dbManager.Accounts.Usage(context.Background(), "user1")
+		require.NoError(t, err)
+		assert.Equal(t, entities.StorageUsage{}, *usage)
+	})
+}
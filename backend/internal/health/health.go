@@ -0,0 +1,94 @@
+// Пакет health содержит обработчики проверок живости и готовности сервера
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check - проверка готовности зависимости (nil - зависимость готова)
+type Check func(ctx context.Context) error
+
+// namedCheck - проверка с именем, под которым она выводится в ответе
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker - набор проверок готовности сервера
+type Checker struct {
+	mu      sync.RWMutex
+	checks  []namedCheck
+	timeout time.Duration
+}
+
+// Response - ответ /readyz
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Статусы проверок
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// NewChecker - создать набор проверок. timeout ограничивает время выполнения всех проверок одного запроса
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add - добавить проверку
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Run - выполнить все проверки
+func (c *Checker) Run(ctx context.Context) Response {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	response := Response{Status: StatusOK, Checks: make(map[string]string, len(checks))}
+	for _, named := range checks {
+		if err := named.check(ctx); err != nil {
+			response.Status = StatusFail
+			response.Checks[named.name] = err.Error()
+			continue
+		}
+		response.Checks[named.name] = StatusOK
+	}
+
+	return response
+}
+
+// ReadinessHandler - обработчик /readyz: 200, если все проверки прошли, иначе 503
+func (c *Checker) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	response := c.Run(r.Context())
+
+	status := http.StatusOK
+	if response.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// LivenessHandler - обработчик /healthz: процесс запущен и обрабатывает запросы
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(StatusOK))
+}
@@ -0,0 +1,71 @@
+// health_test - тесты проверок живости и готовности
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readyz - выполнить запрос /readyz и разобрать ответ
+func readyz(t *testing.T, checker *health.Checker) (int, health.Response) {
+	w := httptest.NewRecorder()
+	checker.ReadinessHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var response health.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func TestLivenessHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	health.LivenessHandler(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestReadinessHandler(t *testing.T) {
+	t.Run("Все проверки пройдены", func(t *testing.T) {
+		checker := health.NewChecker(time.Second)
+		checker.Add("postgres", func(ctx context.Context) error { return nil })
+		checker.Add("storage_service", func(ctx context.Context) error { return nil })
+
+		code, response := readyz(t, checker)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusOK, response.Status)
+		assert.Equal(t, map[string]string{"postgres": "ok", "storage_service": "ok"}, response.Checks)
+	})
+
+	t.Run("Одна из проверок не пройдена", func(t *testing.T) {
+		checker := health.NewChecker(time.Second)
+		checker.Add("postgres", func(ctx context.Context) error { return errors.New("connection refused") })
+		checker.Add("storage_service", func(ctx context.Context) error { return nil })
+
+		code, response := readyz(t, checker)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusFail, response.Status)
+		assert.Equal(t, "connection refused", response.Checks["postgres"])
+		assert.Equal(t, "ok", response.Checks["storage_service"])
+	})
+
+	t.Run("Зависшая проверка прерывается по таймауту", func(t *testing.T) {
+		checker := health.NewChecker(20 * time.Millisecond)
+		checker.Add("postgres", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		code, response := readyz(t, checker)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, context.DeadlineExceeded.Error(), response.Checks["postgres"])
+	})
+}
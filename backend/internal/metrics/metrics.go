@@ -0,0 +1,81 @@
+// Пакет metrics содержит метрики Prometheus, которые собирает сервер
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Пространство имён всех метрик приложения
+const namespace = "gophkeeper"
+
+// Registry - реестр метрик приложения (отдаётся на /metrics административного сервера)
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration - время обработки HTTP-запросов по маршрутам
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// TaskDuration - время обработки задач StorageService (без учёта ожидания в очереди)
+	TaskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "task_duration_seconds",
+		Help:      "Duration of storage service tasks by task type and entity type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"task_type", "entity_type", "result"})
+
+	// AuthFailures - неудачные попытки аутентификации
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "failures_total",
+		Help:      "Number of failed authentication attempts by reason.",
+	}, []string{"reason"})
+)
+
+// Причины неудачной аутентификации
+const (
+	AuthReasonMissingToken       = "missing_token"
+	AuthReasonInvalidToken       = "invalid_token"
+	AuthReasonInvalidCredentials = "invalid_credentials"
+	AuthReasonRevokedSession     = "revoked_session"
+	AuthReasonDisabledAccount    = "disabled_account"
+	AuthReasonInvalidAdminToken  = "invalid_admin_token"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		TaskDuration,
+		AuthFailures,
+	)
+}
+
+// RegisterQueueLength - публиковать длину очереди задач, получаемую вызовом функции при каждом сборе метрик
+func RegisterQueueLength(length func() int) error {
+	return Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "queue_length",
+		Help:      "Number of tasks waiting in the storage service queue.",
+	}, func() float64 {
+		return float64(length())
+	}))
+}
+
+// Handler - HTTP-обработчик, отдающий метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
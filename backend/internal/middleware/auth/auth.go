@@ -2,42 +2,75 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
+	"github.com/JustScorpio/GophKeeper/backend/internal/metrics"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-const (
-	// Имя куки с JWT-токеном
-	jwtCookieName = "jwt_token"
+// Имя куки с JWT-токеном
+const jwtCookieName = "jwt_token"
+
+var (
+	// Ключ для генерации и расшифровки токена (задаётся конфигурацией)
+	secretKey string
 	//Время жизни токена
 	tokenLifeTime = time.Hour * 3
+	// Проверка учётной записи при каждом запросе (nil - не проверять)
+	sessionChecker SessionChecker
 )
 
-// Ключ для генерации и расшифровки токена (задаётся с помощью флага либо переменной окружения)
-var secretKey string
-
-// Claims — структура утверждений, которая включает стандартные утверждения и одно пользовательское UserID
+// Claims — структура утверждений, которая включает стандартные утверждения, логин пользователя, идентификатор и версию сессии
 type Claims struct {
 	jwt.RegisteredClaims
-	Login string `json:"login"`
+	Login          string `json:"login"`
+	SessionID      string `json:"sid"`
+	SessionVersion int    `json:"ver"`
 }
 
+// SessionChecker - проверить, что учётная запись не заблокирована и версия сессий из токена не отозвана.
+// Ошибка customerrors.HTTPError отдаётся клиенту со своим кодом
+type SessionChecker func(ctx context.Context, login string, sessionVersion int) error
+
+// Init - задать ключ для генерации и расшифровки токенов
 func Init(key string) {
 	secretKey = key
 }
 
-// NewJWTString - создаёт токен с логином пользователя
-func NewJWTString(login string) (string, error) {
+// SetTokenLifeTime - задать время жизни выдаваемых токенов
+func SetTokenLifeTime(lifeTime time.Duration) {
+	tokenLifeTime = lifeTime
+}
+
+// SetSessionChecker - проверять учётную запись при каждом запросе с токеном
+func SetSessionChecker(checker SessionChecker) {
+	sessionChecker = checker
+}
+
+// NewJWTString - создаёт токен с логином пользователя и версией его сессий (каждый токен открывает новую сессию)
+func NewJWTString(login string, sessionVersion int) (string, error) {
+	sessionID, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenLifeTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
-		Login: login, // Сохраняем логин в токене
+		Login:          login, // Сохраняем логин в токене
+		SessionID:      sessionID,
+		SessionVersion: sessionVersion,
 	})
 
 	tokenString, err := token.SignedString([]byte(secretKey))
@@ -49,8 +82,8 @@ func NewJWTString(login string) (string, error) {
 }
 
 // SetJWTCookie - устанавливает JWT куку
-func SetJWTCookie(w http.ResponseWriter, userID string) error {
-	newToken, err := NewJWTString(userID)
+func SetJWTCookie(w http.ResponseWriter, userID string, sessionVersion int) error {
+	newToken, err := NewJWTString(userID, sessionVersion)
 	if err != nil {
 		return err
 	}
@@ -67,6 +100,17 @@ func SetJWTCookie(w http.ResponseWriter, userID string) error {
 	return nil
 }
 
+// ClearJWTCookie - удаляет JWT куку (например, после удаления учётной записи)
+func ClearJWTCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     jwtCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
 // GetLoginFromToken - извлекает логин из JWT токена
 func GetLoginFromToken(tokenString string) (string, error) {
 	claims := &Claims{}
@@ -93,6 +137,7 @@ func AuthMiddleware() func(http.Handler) http.Handler {
 
 			cookie, err := r.Cookie(jwtCookieName)
 			if err != nil {
+				metrics.AuthFailures.WithLabelValues(metrics.AuthReasonMissingToken).Inc()
 				http.Error(w, "Authentication required", http.StatusUnauthorized)
 				return
 			}
@@ -103,19 +148,67 @@ func AuthMiddleware() func(http.Handler) http.Handler {
 			})
 
 			if err != nil || !token.Valid {
+				metrics.AuthFailures.WithLabelValues(metrics.AuthReasonInvalidToken).Inc()
 				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 				return
 			}
 
 			login = claims.Login
 			if login == "" {
+				metrics.AuthFailures.WithLabelValues(metrics.AuthReasonInvalidToken).Inc()
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
 				return
 			}
 
-			// Добавляем логин в контекст
+			// Учётная запись могла быть заблокирована, а её сессии - отозваны после выдачи токена
+			if sessionChecker != nil {
+				if err := sessionChecker(r.Context(), login, claims.SessionVersion); err != nil {
+					var httpErr *customerrors.HTTPError
+					if !errors.As(err, &httpErr) {
+						http.Error(w, "Failed to check session", http.StatusInternalServerError)
+						return
+					}
+
+					reason := metrics.AuthReasonRevokedSession
+					if httpErr.Code == http.StatusForbidden {
+						reason = metrics.AuthReasonDisabledAccount
+					}
+					metrics.AuthFailures.WithLabelValues(reason).Inc()
+					http.Error(w, err.Error(), httpErr.Code)
+					return
+				}
+			}
+
+			// Добавляем логин и сессию в контекст
 			ctx := customcontext.WithUserID(r.Context(), login)
+			ctx = customcontext.WithSessionID(ctx, claims.SessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// AdminMiddleware - middleware для проверки токена администратора (заголовок Authorization: Bearer <token>)
+func AdminMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided, hasBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !hasBearer || token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				metrics.AuthFailures.WithLabelValues(metrics.AuthReasonInvalidAdminToken).Inc()
+				http.Error(w, "Admin authentication required", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(customcontext.WithAdmin(r.Context())))
+		})
+	}
+}
+
+// newSessionID - сгенерировать случайный идентификатор сессии
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
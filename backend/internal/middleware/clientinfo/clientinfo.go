@@ -0,0 +1,38 @@
+// Пакет clientinfo содержит middleware, сохраняющее в контексте сведения о клиенте (IP-адрес и устройство)
+package clientinfo
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+)
+
+const (
+	// DeviceIDHeader - заголовок, в котором клиент передаёт идентификатор устройства
+	DeviceIDHeader = "X-Device-ID"
+
+	// Ограничение длины идентификатора устройства (значение приходит от клиента)
+	maxDeviceIDLength = 128
+)
+
+// ClientInfoMiddleware - middleware для сохранения IP-адреса и идентификатора устройства клиента в контексте
+func ClientInfoMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+
+			deviceID := r.Header.Get(DeviceIDHeader)
+			if len(deviceID) > maxDeviceIDLength {
+				deviceID = deviceID[:maxDeviceIDLength]
+			}
+
+			ctx := customcontext.WithClientIP(r.Context(), ip)
+			ctx = customcontext.WithDeviceID(ctx, deviceID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
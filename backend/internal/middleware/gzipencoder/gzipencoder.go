@@ -62,3 +62,11 @@ func (w gzipWriter) Write(b []byte) (int, error) {
 	// w.Writer будет отвечать за gzip-сжатие, поэтому пишем в него
 	return w.Writer.Write(b)
 }
+
+// Flush - отправить клиенту уже сжатые данные (нужно для потоковых ответов, например потока событий)
+func (w gzipWriter) Flush() {
+	if gz, ok := w.Writer.(*gzip.Writer); ok {
+		gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
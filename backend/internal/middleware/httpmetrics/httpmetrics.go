@@ -0,0 +1,55 @@
+// Пакет httpmetrics содержит middleware для сбора метрик HTTP-запросов
+package httpmetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/metrics"
+	"github.com/go-chi/chi"
+)
+
+// Метка маршрута для запросов, не попавших ни в один маршрут (не плодим метки по произвольным путям)
+const unknownRoute = "unmatched"
+
+// HTTPMetricsMiddleware - middleware для замера времени обработки запросов по маршрутам
+func HTTPMetricsMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			// Шаблон маршрута (например, /api/user/cards/{id}) известен только после маршрутизации
+			route := unknownRoute
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil {
+				if pattern := routeCtx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+
+			metrics.HTTPRequestDuration.
+				WithLabelValues(route, r.Method, strconv.Itoa(rw.status)).
+				Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// responseWriter - обертка (встраивание) для ResponseWriter, захватывающая код ответа
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader - отправить HTTP-заголовок и захватить код статуса
+func (r *responseWriter) WriteHeader(statusCode int) {
+	r.ResponseWriter.WriteHeader(statusCode)
+	r.status = statusCode
+}
+
+// Unwrap - исходный ResponseWriter (позволяет http.ResponseController добраться до Flush и других возможностей)
+func (r *responseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
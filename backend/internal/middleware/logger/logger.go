@@ -61,6 +61,7 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 				zap.Int("size", rw.size),
 				// zap.String("body", rw.body), //private information!
 				zap.String("auth-token", userID),
+				zap.String("request-id", customcontext.GetRequestID(r.Context())),
 			)
 		})
 	}
@@ -89,3 +90,8 @@ func (r *responseWriter) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 	r.status = statusCode // захватываем код статуса
 }
+
+// Unwrap - исходный ResponseWriter (позволяет http.ResponseController добраться до Flush и других возможностей)
+func (r *responseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,59 @@
+// Пакет requestid содержит middleware, присваивающее каждому запросу идентификатор для сквозной корреляции
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+)
+
+const (
+	// Header - заголовок с идентификатором запроса (принимается от клиента и возвращается в ответе)
+	Header = "X-Request-ID"
+
+	// Ограничение длины идентификатора, присланного клиентом
+	maxRequestIDLength = 64
+)
+
+// RequestIDMiddleware - middleware для присвоения запросу идентификатора.
+// Корректный идентификатор клиента сохраняется, иначе генерируется новый
+func RequestIDMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(Header)
+			if !isValid(requestID) {
+				requestID = New()
+			}
+
+			w.Header().Set(Header, requestID)
+
+			ctx := customcontext.WithRequestID(r.Context(), requestID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// New - сгенерировать идентификатор запроса
+func New() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// isValid - проверить идентификатор клиента (попадает в логи и заголовки, поэтому только безопасные символы)
+func isValid(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		isAllowed := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+		if !isAllowed {
+			return false
+		}
+	}
+
+	return true
+}
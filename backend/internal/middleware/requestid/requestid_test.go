@@ -0,0 +1,53 @@
+// requestid_test - тесты middleware идентификатора запроса
+package requestid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/requestid"
+	"github.com/stretchr/testify/assert"
+)
+
+// serve - выполнить запрос через middleware и вернуть идентификатор из контекста и из заголовка ответа
+func serve(incoming string) (fromContext, fromHeader string) {
+	handler := requestid.RequestIDMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = customcontext.GetRequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if incoming != "" {
+		req.Header.Set(requestid.Header, incoming)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	return fromContext, w.Header().Get(requestid.Header)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	t.Run("Генерация идентификатора", func(t *testing.T) {
+		fromContext, fromHeader := serve("")
+		assert.Len(t, fromContext, 32)
+		assert.Equal(t, fromContext, fromHeader)
+	})
+
+	t.Run("Идентификатор клиента сохраняется", func(t *testing.T) {
+		fromContext, fromHeader := serve("client-42.retry_1")
+		assert.Equal(t, "client-42.retry_1", fromContext)
+		assert.Equal(t, "client-42.retry_1", fromHeader)
+	})
+
+	t.Run("Некорректный идентификатор клиента заменяется", func(t *testing.T) {
+		for _, incoming := range []string{"bad id\nwith newline", strings.Repeat("a", 65)} {
+			fromContext, fromHeader := serve(incoming)
+			assert.NotEqual(t, incoming, fromContext)
+			assert.Len(t, fromContext, 32)
+			assert.Equal(t, fromContext, fromHeader)
+		}
+	})
+}
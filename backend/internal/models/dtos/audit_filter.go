@@ -0,0 +1,13 @@
+// dtos содержит объекты для транспортировки данных
+package dtos
+
+import "time"
+
+// AuditFilter - параметры выборки из журнала аудита (пустые поля не ограничивают выборку)
+type AuditFilter struct {
+	UserID     string
+	Actor      string
+	From       time.Time
+	To         time.Time
+	EntityType string
+}
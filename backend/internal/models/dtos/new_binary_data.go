@@ -6,3 +6,8 @@ type NewBinaryData struct {
 	NewSecureEntity
 	Data []byte `json:"data"`
 }
+
+// Size - объём хранимых полей записи в байтах (как у entities.BinaryData)
+func (dto *NewBinaryData) Size() int64 {
+	return int64(len(dto.Data) + len(dto.Metadata))
+}
@@ -9,3 +9,8 @@ type NewCardInformation struct {
 	ExpirationDate string `json:"expiration_date"`
 	CVV            string `json:"cvv"`
 }
+
+// Size - объём хранимых полей записи в байтах (как у entities.CardInformation)
+func (dto *NewCardInformation) Size() int64 {
+	return int64(len(dto.Number) + len(dto.CardHolder) + len(dto.CVV) + len(dto.Metadata))
+}
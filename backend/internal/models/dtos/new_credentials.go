@@ -7,3 +7,8 @@ type NewCredentials struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
 }
+
+// Size - объём хранимых полей записи в байтах (как у entities.Credentials)
+func (dto *NewCredentials) Size() int64 {
+	return int64(len(dto.Login) + len(dto.Password) + len(dto.Metadata))
+}
@@ -0,0 +1,9 @@
+// dtos содержит объекты для транспортировки данных
+package dtos
+
+// NewEmergencyAccess - назначить доверенное лицо для экстренного доступа к хранилищу текущего пользователя
+type NewEmergencyAccess struct {
+	GranteeID    string `json:"grantee_id"`
+	WaitDays     int    `json:"wait_days"`
+	EncryptedKey string `json:"encrypted_key"` // ключ хранилища, зашифрованный открытым ключом доверенного лица
+}
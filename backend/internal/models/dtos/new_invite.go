@@ -0,0 +1,11 @@
+// dtos содержит объекты для транспортировки данных
+package dtos
+
+import "time"
+
+// NewInvite - выпустить код приглашения (0 использований - одноразовый, нулевой срок - на неделю)
+type NewInvite struct {
+	MaxUses      int       `json:"max_uses"`
+	LoginPattern string    `json:"login_pattern,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -3,5 +3,10 @@ package dtos
 
 // NewSecureEntity - хранимая в менеджере паролей сущность (dto - новая запись)
 type NewSecureEntity struct {
+	// ID - идентификатор записи (UUIDv7), выбранный клиентом. Пустой - идентификатор выдаёт сервер
+	ID       string `json:"id,omitempty"`
 	Metadata string `json:"metadata"`
+	// CollectionID - создать запись в коллекции организации (поля зашифрованы ключом коллекции версии KeyVersion)
+	CollectionID string `json:"collection_id,omitempty"`
+	KeyVersion   int    `json:"key_version,omitempty"`
 }
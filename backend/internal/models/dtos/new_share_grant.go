@@ -0,0 +1,11 @@
+// dtos содержит объекты для транспортировки данных
+package dtos
+
+// NewShareGrant - предоставить пользователю доступ к записи (dto - новая запись)
+type NewShareGrant struct {
+	EntityType  string `json:"entity_type"`
+	EntityID    string `json:"entity_id"`
+	RecipientID string `json:"recipient_id"`
+	Permission  string `json:"permission"`
+	EntryKey    string `json:"entry_key"` // ключ записи, зашифрованный открытым ключом получателя
+}
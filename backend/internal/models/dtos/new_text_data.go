@@ -6,3 +6,8 @@ type NewTextData struct {
 	NewSecureEntity
 	Data string `json:"data"`
 }
+
+// Size - объём хранимых полей записи в байтах (как у entities.TextData)
+func (dto *NewTextData) Size() int64 {
+	return int64(len(dto.Data) + len(dto.Metadata))
+}
@@ -5,4 +5,6 @@ package dtos
 type NewUser struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	// InviteCode - код приглашения (обязателен, если регистрация только по приглашениям)
+	InviteCode string `json:"invite_code,omitempty"`
 }
@@ -0,0 +1,10 @@
+// dtos содержит объекты для транспортировки данных
+package dtos
+
+import "github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+
+// QuotaChange - индивидуальная квота пользователя, задаваемая администратором (Quota nil - вернуть квоту по умолчанию)
+type QuotaChange struct {
+	Login string
+	Quota *entities.Quota
+}
@@ -0,0 +1,53 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+// StorageUsage - количество записей пользователя по типам и объём их хранимых полей в байтах
+type StorageUsage struct {
+	Binaries    int   `json:"binaries"`
+	Cards       int   `json:"cards"`
+	Credentials int   `json:"credentials"`
+	Texts       int   `json:"texts"`
+	Bytes       int64 `json:"bytes"`
+}
+
+// Add - прибавить использование другого пользователя (для итогов по серверу)
+func (u *StorageUsage) Add(other StorageUsage) {
+	u.Binaries += other.Binaries
+	u.Cards += other.Cards
+	u.Credentials += other.Credentials
+	u.Texts += other.Texts
+	u.Bytes += other.Bytes
+}
+
+// Quota - ограничения хранилища пользователя: количество записей каждого типа и общий объём в байтах (0 - без ограничения)
+type Quota struct {
+	MaxEntries int   `json:"max_entries"`
+	MaxBytes   int64 `json:"max_bytes"`
+}
+
+// QuotaUsage - использование хранилища пользователем и действующая для него квота
+type QuotaUsage struct {
+	Usage StorageUsage `json:"usage"`
+	Quota Quota        `json:"quota"`
+}
+
+// Account - учётная запись пользователя для администрирования (без хэша пароля)
+type Account struct {
+	Login          string       `json:"login"`
+	Disabled       bool         `json:"disabled"`
+	SessionVersion int          `json:"session_version"`
+	Usage          StorageUsage `json:"usage"`
+	Quota          *Quota       `json:"quota,omitempty"` // индивидуальная квота (nil - действует квота по умолчанию)
+}
+
+// ServerStats - статистика сервера
+type ServerStats struct {
+	Users           int          `json:"users"`
+	DisabledUsers   int          `json:"disabled_users"`
+	Organizations   int          `json:"organizations"`
+	Shares          int          `json:"shares"`
+	EmergencyAccess int          `json:"emergency_access"`
+	Usage           StorageUsage `json:"usage"`
+	QueueLength     int          `json:"queue_length"`
+	QueueCapacity   int          `json:"queue_capacity"`
+}
@@ -0,0 +1,17 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// AccountExport - выгрузка учётной записи пользователя: сведения об учётной записи, ключи, выданные права
+// и собственные записи пользователя в том виде, в каком они хранятся на сервере (зашифрованными)
+type AccountExport struct {
+	Account     Account           `json:"account"`
+	ExportedAt  time.Time         `json:"exported_at"`
+	Keys        *UserKeys         `json:"keys,omitempty"`
+	Shares      []ShareGrant      `json:"shares"`
+	Binaries    []BinaryData      `json:"binaries"`
+	Cards       []CardInformation `json:"cards"`
+	Credentials []Credentials     `json:"credentials"`
+	Texts       []TextData        `json:"texts"`
+}
@@ -0,0 +1,19 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// AuditEvent - запись журнала аудита (операция пользователя над сущностью).
+// Действия администратора отмечаются Actor, UserID у них - пользователь, над учётной записью которого выполнено действие
+type AuditEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Actor      string    `json:"actor,omitempty"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	SessionID  string    `json:"session_id"`
+	DeviceID   string    `json:"device_id"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -6,3 +6,8 @@ type BinaryData struct {
 	SecureEntity
 	Data []byte `json:"data"`
 }
+
+// Size - объём хранимых полей записи в байтах
+func (entity *BinaryData) Size() int64 {
+	return int64(len(entity.Data) + len(entity.Metadata))
+}
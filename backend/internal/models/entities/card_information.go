@@ -9,3 +9,8 @@ type CardInformation struct {
 	ExpirationDate string `json:"expiration_date"`
 	CVV            string `json:"cvv"`
 }
+
+// Size - объём хранимых полей записи в байтах (срок действия хранится датой и не учитывается)
+func (entity *CardInformation) Size() int64 {
+	return int64(len(entity.Number) + len(entity.CardHolder) + len(entity.CVV) + len(entity.Metadata))
+}
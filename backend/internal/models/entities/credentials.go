@@ -7,3 +7,8 @@ type Credentials struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
 }
+
+// Size - объём хранимых полей записи в байтах
+func (entity *Credentials) Size() int64 {
+	return int64(len(entity.Login) + len(entity.Password) + len(entity.Metadata))
+}
@@ -0,0 +1,9 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+// DataKey - ключ данных пользователя, которым сервер шифрует хранимые поля его записей и его учётной записи.
+// WrappedKey - ключ, зашифрованный мастер-ключом (формат определяет менеджер ключей)
+type DataKey struct {
+	Login      string `json:"login"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
@@ -0,0 +1,14 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+// EntryAccess - отношение пользователя к записи, по которому политика доступа принимает решение
+type EntryAccess struct {
+	EntityType   string
+	EntityID     string
+	OwnerID      string
+	CollectionID string
+	// OrgRole - роль пользователя в организации, которой принадлежит коллекция записи (пусто - не участник)
+	OrgRole string
+	// Permission - права пользователя по принятому приглашению к записи (пусто - приглашения нет)
+	Permission string
+}
@@ -0,0 +1,27 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// Режимы регистрации пользователей
+const (
+	RegistrationOpen   = "open"   // зарегистрироваться может любой
+	RegistrationInvite = "invite" // только по коду приглашения, выданному администратором
+	RegistrationClosed = "closed" // регистрация отключена
+)
+
+// Invite - код приглашения для регистрации. Действует до ExpiresAt и не более MaxUses раз;
+// если задан LoginPattern, логин нового пользователя должен целиком ему соответствовать
+type Invite struct {
+	Code         string    `json:"code"`
+	MaxUses      int       `json:"max_uses"`
+	Uses         int       `json:"uses"`
+	LoginPattern string    `json:"login_pattern,omitempty"` // регулярное выражение
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Usable - можно ли зарегистрироваться по приглашению в момент now
+func (i *Invite) Usable(now time.Time) bool {
+	return i.Uses < i.MaxUses && now.Before(i.ExpiresAt)
+}
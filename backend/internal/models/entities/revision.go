@@ -0,0 +1,24 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision - прежнее состояние записи, сохранённое при её изменении
+type Revision struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	Revision   int    `json:"revision"`
+	// Content - поля записи своего типа (для карты - number, card_holder, expiration_date и cvv) в зашифрованном виде
+	Content  json.RawMessage `json:"content"`
+	Metadata string          `json:"metadata"`
+	// EntryKey и KeyVersion - ключ, которым зашифрована версия, в представлении текущего пользователя (как у самой записи)
+	EntryKey   string `json:"entry_key,omitempty"`
+	KeyVersion int    `json:"key_version,omitempty"`
+	// Restorable - версия зашифрована текущим ключом записи, и сервер может её восстановить
+	Restorable bool      `json:"restorable"`
+	ReplacedBy string    `json:"replaced_by"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
@@ -1,9 +1,24 @@
 // entities содержит модели сущностей которые хранятся в БД
 package entities
 
+// Права доступа к записи, которой поделился владелец
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+)
+
 // SecureEntity - хранимая в менеджере паролей сущность
 type SecureEntity struct {
 	ID       string `json:"id"`
 	Metadata string `json:"metadata"`
 	OwnerID  string `json:"owner_id"`
+	// EntryKey - ключ записи, зашифрованный открытым ключом запрашивающего пользователя.
+	// Пустой - поля зашифрованы ключом хранилища владельца (записью ещё не делились)
+	EntryKey string `json:"entry_key,omitempty"`
+	// Permission - права запрашивающего пользователя на чужую запись (пусто для собственных записей)
+	Permission string `json:"permission,omitempty"`
+	// CollectionID - коллекция организации, в которой лежит запись (пусто для личных записей).
+	// Поля такой записи зашифрованы ключом коллекции версии KeyVersion, а EntryKey - этот ключ, зашифрованный для пользователя
+	CollectionID string `json:"collection_id,omitempty"`
+	KeyVersion   int    `json:"key_version,omitempty"`
 }
@@ -0,0 +1,23 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// Состояния приглашения к записи
+const (
+	ShareStatusPending  = "pending"
+	ShareStatusAccepted = "accepted"
+)
+
+// ShareGrant - право пользователя RecipientID на запись владельца OwnerID
+type ShareGrant struct {
+	ID          string    `json:"id"`
+	EntityType  string    `json:"entity_type"`
+	EntityID    string    `json:"entity_id"`
+	OwnerID     string    `json:"owner_id"`
+	RecipientID string    `json:"recipient_id"`
+	Permission  string    `json:"permission"`
+	Status      string    `json:"status"`
+	EntryKey    string    `json:"entry_key"` // ключ записи, зашифрованный открытым ключом получателя
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -6,3 +6,8 @@ type TextData struct {
 	SecureEntity
 	Data string `json:"data"`
 }
+
+// Size - объём хранимых полей записи в байтах
+func (entity *TextData) Size() int64 {
+	return int64(len(entity.Data) + len(entity.Metadata))
+}
@@ -0,0 +1,25 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// TrashItem - запись в корзине. Возвращаются только общие поля записи: описания достаточно, чтобы её узнать,
+// а содержимое снова станет доступно после восстановления
+type TrashItem struct {
+	SecureEntity
+	EntityType string    `json:"entity_type"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	// OrgRole - роль текущего пользователя в организации, которой принадлежит коллекция записи (для политики доступа)
+	OrgRole string `json:"-"`
+}
+
+// Access - отношение пользователя к записи в корзине, по которому политика доступа принимает решение
+func (i *TrashItem) Access() *EntryAccess {
+	return &EntryAccess{
+		EntityType:   i.EntityType,
+		EntityID:     i.ID,
+		OwnerID:      i.OwnerID,
+		CollectionID: i.CollectionID,
+		OrgRole:      i.OrgRole,
+	}
+}
@@ -5,4 +5,8 @@ package entities
 type User struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	// Disabled - учётная запись заблокирована администратором: вход и запросы с выданными токенами отклоняются
+	Disabled bool `json:"disabled"`
+	// SessionVersion - версия сессий, записывается в токен. Увеличивается при отзыве сессий, после чего выданные токены недействительны
+	SessionVersion int `json:"session_version"`
 }
@@ -0,0 +1,9 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+// UserKeys - пара ключей X25519 пользователя. Закрытый ключ хранится только зашифрованным ключом хранилища пользователя
+type UserKeys struct {
+	Login               string `json:"login"`
+	PublicKey           string `json:"public_key"`                      // base64
+	EncryptedPrivateKey string `json:"encrypted_private_key,omitempty"` // отдаётся только владельцу ключей
+}
@@ -0,0 +1,150 @@
+// Пакет notifications содержит рассылку событий об изменении данных пользователя его подключённым клиентам
+package notifications
+
+import (
+	"sync"
+	"time"
+)
+
+// Действия над сущностями
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// subscriberBuffer - сколько событий может ожидать отправки одному подписчику
+const subscriberBuffer = 64
+
+// Event - событие об изменении сущности
+type Event struct {
+	Action     string    `json:"action"`      // create, update или delete
+	EntityType string    `json:"entity_type"` // binary, card, credentials или text
+	EntityID   string    `json:"entity_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+
+	SessionID string `json:"-"` // сессия, в которой произошло изменение (ей событие не отправляется)
+}
+
+// subscriber - подписка одного клиента
+type subscriber struct {
+	sessionID string
+	events    chan Event
+}
+
+// Hub - рассылка событий подписчикам по пользователям
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*subscriber]struct{}
+	closed      bool
+}
+
+// NewHub - создать рассылку событий
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe - подписаться на события пользователя. События, произошедшие в сессии sessionID, не отправляются.
+// Канал закрывается при отписке, закрытии рассылки или если подписчик не успевает получать события
+// (в этом случае клиент должен переподключиться и синхронизировать данные полностью)
+func (h *Hub) Subscribe(userID, sessionID string) (<-chan Event, func()) {
+	sub := &subscriber{
+		sessionID: sessionID,
+		events:    make(chan Event, subscriberBuffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(sub.events)
+		return sub.events, func() {}
+	}
+
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*subscriber]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(userID, sub)
+	}
+
+	return sub.events, unsubscribe
+}
+
+// Publish - разослать событие подписчикам пользователя
+func (h *Hub) Publish(userID string, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[userID] {
+		if event.SessionID != "" && sub.sessionID == event.SessionID {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			// Подписчик не успевает - отключаем, чтобы он не пропустил изменения незаметно
+			h.remove(userID, sub)
+		}
+	}
+}
+
+// Subscribers - количество подписчиков пользователя
+func (h *Hub) Subscribers(userID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers[userID])
+}
+
+// DisconnectAll - закрыть все текущие подписки, не запрещая новые.
+// Используется, когда события могли быть потеряны: клиенты переподключатся и синхронизируют данные полностью
+func (h *Hub) DisconnectAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeAll()
+}
+
+// Close - закрыть все подписки (вызывается при остановке сервера, чтобы завершить открытые потоки событий)
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	h.removeAll()
+}
+
+// removeAll - удалить всех подписчиков. Вызывается под h.mu
+func (h *Hub) removeAll() {
+	for userID, subs := range h.subscribers {
+		for sub := range subs {
+			h.remove(userID, sub)
+		}
+	}
+}
+
+// remove - удалить подписчика и закрыть его канал. Вызывается под h.mu
+func (h *Hub) remove(userID string, sub *subscriber) {
+	subs, ok := h.subscribers[userID]
+	if !ok {
+		return
+	}
+
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	close(sub.events)
+
+	if len(subs) == 0 {
+		delete(h.subscribers, userID)
+	}
+}
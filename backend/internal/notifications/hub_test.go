@@ -0,0 +1,110 @@
+// notifications_test - тесты рассылки событий
+package notifications_test
+
+import (
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_Publish(t *testing.T) {
+	hub := notifications.NewHub()
+
+	first, unsubscribeFirst := hub.Subscribe("user1", "session-1")
+	second, unsubscribeSecond := hub.Subscribe("user1", "session-2")
+	other, unsubscribeOther := hub.Subscribe("user2", "session-3")
+	defer unsubscribeFirst()
+	defer unsubscribeSecond()
+	defer unsubscribeOther()
+
+	assert.Equal(t, 2, hub.Subscribers("user1"))
+
+	event := notifications.Event{Action: notifications.ActionUpdate, EntityType: "text", EntityID: "42", SessionID: "session-1"}
+	hub.Publish("user1", event)
+
+	t.Run("Событие получают другие сессии пользователя", func(t *testing.T) {
+		require.Len(t, second, 1)
+		assert.Equal(t, event, <-second)
+	})
+
+	t.Run("Сессия-источник события его не получает", func(t *testing.T) {
+		assert.Empty(t, first)
+	})
+
+	t.Run("Другие пользователи событие не получают", func(t *testing.T) {
+		assert.Empty(t, other)
+	})
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := notifications.NewHub()
+
+	events, unsubscribe := hub.Subscribe("user1", "session-1")
+	unsubscribe()
+	unsubscribe() // повторная отписка безопасна
+
+	_, ok := <-events
+	assert.False(t, ok, "channel must be closed after unsubscribe")
+	assert.Equal(t, 0, hub.Subscribers("user1"))
+
+	// Публикация без подписчиков не блокируется
+	hub.Publish("user1", notifications.Event{Action: notifications.ActionCreate})
+}
+
+func TestHub_SlowSubscriber(t *testing.T) {
+	hub := notifications.NewHub()
+
+	events, unsubscribe := hub.Subscribe("user1", "")
+	defer unsubscribe()
+
+	// Подписчик не читает события - после заполнения буфера он отключается
+	for i := 0; i < 1000; i++ {
+		hub.Publish("user1", notifications.Event{Action: notifications.ActionCreate})
+	}
+	assert.Equal(t, 0, hub.Subscribers("user1"))
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Positive(t, received)
+	assert.Less(t, received, 1000)
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := notifications.NewHub()
+
+	events, unsubscribe := hub.Subscribe("user1", "session-1")
+	defer unsubscribe()
+
+	hub.Close()
+
+	_, ok := <-events
+	assert.False(t, ok, "channel must be closed after hub close")
+
+	// После закрытия новые подписки сразу закрыты
+	late, _ := hub.Subscribe("user1", "session-2")
+	_, ok = <-late
+	assert.False(t, ok)
+}
+
+func TestHub_DisconnectAll(t *testing.T) {
+	hub := notifications.NewHub()
+
+	events, unsubscribe := hub.Subscribe("user1", "session-1")
+	defer unsubscribe()
+
+	hub.DisconnectAll()
+
+	_, ok := <-events
+	assert.False(t, ok, "channel must be closed after disconnect")
+
+	// Новые подписки работают как обычно
+	again, unsubscribeAgain := hub.Subscribe("user1", "session-1")
+	defer unsubscribeAgain()
+
+	hub.Publish("user1", notifications.Event{Action: notifications.ActionDelete})
+	require.Len(t, again, 1)
+}
@@ -0,0 +1,82 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// InMemoryAccessRepo - отношения пользователей к записям в памяти (по данным репозиториев записей, прав и организаций)
+type InMemoryAccessRepo struct {
+	mu     *sync.Mutex
+	shares *InMemoryShareRepo
+	orgs   *InMemoryOrganizationRepo
+}
+
+// NewInMemoryAccessRepo - инициализация репозитория
+func NewInMemoryAccessRepo(shares *InMemoryShareRepo, orgs *InMemoryOrganizationRepo) *InMemoryAccessRepo {
+	return &InMemoryAccessRepo{mu: &sync.Mutex{}, shares: shares, orgs: orgs}
+}
+
+// EntryAccess - отношение текущего пользователя к записи (nil, если записи нет или она в корзине)
+func (r *InMemoryAccessRepo) EntryAccess(ctx context.Context, entityType, id string) (*entities.EntryAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	repo, known := r.shares.entries[entityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	entry, exists := repo.entry(id)
+	if !exists || r.shares.trash.contains(entityType, id) {
+		return nil, nil
+	}
+
+	access := entities.EntryAccess{
+		EntityType:   entityType,
+		EntityID:     id,
+		OwnerID:      entry.OwnerID,
+		CollectionID: entry.CollectionID,
+	}
+
+	if entry.CollectionID != "" {
+		access.OrgRole = r.orgs.activeRole(entry.CollectionID, userID)
+	}
+
+	if grant := r.shares.find(entityType, id, userID); grant != nil && grant.Status == entities.ShareStatusAccepted {
+		access.Permission = grant.Permission
+	}
+
+	return &access, nil
+}
+
+// EntryExists - занят ли идентификатор записью указанного типа (в том числе записью в корзине или чужой записью)
+func (r *InMemoryAccessRepo) EntryExists(ctx context.Context, entityType, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	repo, known := r.shares.entries[entityType]
+	if !known {
+		return false, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	_, exists := repo.entry(id)
+	return exists, nil
+}
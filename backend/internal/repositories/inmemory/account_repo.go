@@ -0,0 +1,239 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// InMemoryAccountRepo - учётные записи пользователей целиком в памяти (работает поверх остальных репозиториев)
+type InMemoryAccountRepo struct {
+	mu      *sync.Mutex
+	manager *DatabaseManager
+	quotas  map[string]entities.Quota
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryAccountRepo - инициализация репозитория учётных записей
+func NewInMemoryAccountRepo(manager *DatabaseManager) *InMemoryAccountRepo {
+	return &InMemoryAccountRepo{
+		mu:      &sync.Mutex{},
+		manager: manager,
+		quotas:  make(map[string]entities.Quota),
+	}
+}
+
+// Usage - количество и объём записей, созданных пользователем (включая записи в корзине)
+func (r *InMemoryAccountRepo) Usage(ctx context.Context, login string) (*entities.StorageUsage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usage := r.allUsage()[login]
+	return &usage, nil
+}
+
+// AllUsage - количество и объём записей по логинам создавших их пользователей
+func (r *InMemoryAccountRepo) AllUsage(ctx context.Context) (map[string]entities.StorageUsage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.allUsage(), nil
+}
+
+// Stats - количество пользователей, организаций, прав и экстренных доступов, итоговый объём записей
+func (r *InMemoryAccountRepo) Stats(ctx context.Context) (*entities.ServerStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := entities.ServerStats{
+		Users:           len(r.manager.Users.storage),
+		Organizations:   len(r.manager.Orgs.orgs),
+		Shares:          len(r.manager.Shares.storage),
+		EmergencyAccess: len(r.manager.Emergency.storage),
+	}
+
+	for _, user := range r.manager.Users.storage {
+		if user.Disabled {
+			stats.DisabledUsers++
+		}
+	}
+
+	for _, usage := range r.allUsage() {
+		stats.Usage.Add(usage)
+	}
+
+	return &stats, nil
+}
+
+// Delete - удалить пользователя вместе с его записями, правами, ключами, организациями и экстренными доступами
+func (r *InMemoryAccountRepo) Delete(ctx context.Context, login string) (*entities.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.manager
+
+	user, exists := m.Users.storage[login]
+	if !exists {
+		return nil, nil
+	}
+
+	// Учётная запись удаляется последней: если запись в журнал не удастся, повторное удаление продолжит с того же места
+	if err := m.Shares.removeUser(login); err != nil {
+		return nil, err
+	}
+	if err := m.Emergency.removeUser(login); err != nil {
+		return nil, err
+	}
+	removedCollections, err := m.Orgs.removeUser(login)
+	if err != nil {
+		return nil, err
+	}
+
+	err = deleteEntries(m.Binaries.storage, func(e *entities.BinaryData) *entities.SecureEntity { return &e.SecureEntity }, m.Shares, r.journal, "binary", login, removedCollections)
+	if err != nil {
+		return nil, err
+	}
+	err = deleteEntries(m.Cards.storage, func(e *entities.CardInformation) *entities.SecureEntity { return &e.SecureEntity }, m.Shares, r.journal, "card", login, removedCollections)
+	if err != nil {
+		return nil, err
+	}
+	err = deleteEntries(m.Credentials.storage, func(e *entities.Credentials) *entities.SecureEntity { return &e.SecureEntity }, m.Shares, r.journal, "credentials", login, removedCollections)
+	if err != nil {
+		return nil, err
+	}
+	err = deleteEntries(m.Texts.storage, func(e *entities.TextData) *entities.SecureEntity { return &e.SecureEntity }, m.Shares, r.journal, "text", login, removedCollections)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes changeSet
+	changes.remove(tableUsers, login)
+	changes.remove(tableUserKeys, login)
+	changes.remove(tableQuotas, login)
+	if err := r.journal.commit(&changes); err != nil {
+		return nil, err
+	}
+	delete(m.Users.storage, login)
+	delete(m.UserKeys.storage, login)
+	delete(r.quotas, login)
+
+	return &user, nil
+}
+
+// GetQuota - индивидуальная квота пользователя (nil, если действует квота по умолчанию)
+func (r *InMemoryAccountRepo) GetQuota(ctx context.Context, login string) (*entities.Quota, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quota, exists := r.quotas[login]
+	if !exists {
+		return nil, nil
+	}
+	return &quota, nil
+}
+
+// AllQuotas - индивидуальные квоты по логинам пользователей
+func (r *InMemoryAccountRepo) AllQuotas(ctx context.Context) (map[string]entities.Quota, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make(map[string]entities.Quota, len(r.quotas))
+	for login, quota := range r.quotas {
+		result[login] = quota
+	}
+	return result, nil
+}
+
+// SetQuota - задать индивидуальную квоту пользователя (nil - вернуть квоту по умолчанию)
+func (r *InMemoryAccountRepo) SetQuota(ctx context.Context, login string, quota *entities.Quota) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if quota == nil {
+		if err := r.journal.remove(tableQuotas, login); err != nil {
+			return err
+		}
+		delete(r.quotas, login)
+		return nil
+	}
+
+	if err := r.journal.put(tableQuotas, quota, login); err != nil {
+		return err
+	}
+	r.quotas[login] = *quota
+	return nil
+}
+
+// allUsage - посчитать количество и объём записей по пользователям (включая записи в корзине)
+func (r *InMemoryAccountRepo) allUsage() map[string]entities.StorageUsage {
+	m := r.manager
+	result := make(map[string]entities.StorageUsage)
+
+	for _, binary := range m.Binaries.storage {
+		usage := result[binary.OwnerID]
+		usage.Binaries++
+		usage.Bytes += binary.Size()
+		result[binary.OwnerID] = usage
+	}
+	for _, card := range m.Cards.storage {
+		usage := result[card.OwnerID]
+		usage.Cards++
+		usage.Bytes += card.Size()
+		result[card.OwnerID] = usage
+	}
+	for _, credentials := range m.Credentials.storage {
+		usage := result[credentials.OwnerID]
+		usage.Credentials++
+		usage.Bytes += credentials.Size()
+		result[credentials.OwnerID] = usage
+	}
+	for _, text := range m.Texts.storage {
+		usage := result[text.OwnerID]
+		usage.Texts++
+		usage.Bytes += text.Size()
+		result[text.OwnerID] = usage
+	}
+
+	return result
+}
+
+// deleteEntries - удалить записи пользователя и записи удалённых коллекций вместе с правами на них
+func deleteEntries[T any](storage map[string]T, secure func(*T) *entities.SecureEntity, shares *InMemoryShareRepo,
+	journal *journal, entityType, login string, removedCollections map[string]bool) error {
+	for id, entry := range storage {
+		entity := secure(&entry)
+		if entity.OwnerID == login || removedCollections[entity.CollectionID] {
+			if err := journal.remove(entityType, id); err != nil {
+				return err
+			}
+			delete(storage, id)
+			if err := shares.revokeAll(entityType, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
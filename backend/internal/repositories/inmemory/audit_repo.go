@@ -0,0 +1,80 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// InMemoryAuditRepo - журнал аудита в памяти
+type InMemoryAuditRepo struct {
+	mu      *sync.Mutex
+	storage []entities.AuditEvent
+	idSeq   int64
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryAuditRepo - инициализация журнала аудита
+func NewInMemoryAuditRepo() *InMemoryAuditRepo {
+	return &InMemoryAuditRepo{mu: &sync.Mutex{}}
+}
+
+// Append - добавить запись в журнал
+func (r *InMemoryAuditRepo) Append(ctx context.Context, event *entities.AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event == nil {
+		return errors.New("event cannot be nil")
+	}
+
+	r.idSeq++
+	event.ID = fmt.Sprintf("%d", r.idSeq)
+	event.CreatedAt = time.Now()
+
+	if err := r.journal.put(tableAudit, event, event.ID); err != nil {
+		return err
+	}
+	r.storage = append(r.storage, *event)
+	return nil
+}
+
+// Query - получить записи журнала, удовлетворяющие фильтру
+func (r *InMemoryAuditRepo) Query(ctx context.Context, filter *dtos.AuditFilter) ([]entities.AuditEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []entities.AuditEvent
+	for _, event := range r.storage {
+		if filter.UserID != "" && event.UserID != filter.UserID {
+			continue
+		}
+		if filter.Actor != "" && event.Actor != filter.Actor {
+			continue
+		}
+		if !filter.From.IsZero() && event.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && event.CreatedAt.After(filter.To) {
+			continue
+		}
+		if filter.EntityType != "" && event.EntityType != filter.EntityType {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -3,8 +3,11 @@ package inmemory
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
@@ -13,25 +16,29 @@ import (
 
 // InMemoryBinariesRepo - репозиторий бинарных данных в памяти
 type InMemoryBinariesRepo struct {
+	mu      *sync.Mutex
 	storage map[string]entities.BinaryData
-	idSeq   int64
+	shares  *InMemoryShareRepo // права на записи (nil - записями не делятся)
+	trash   *InMemoryTrashRepo // корзина (nil - записи удаляются сразу)
+	journal *journal           // журнал изменений (nil - состояние не сохраняется)
 }
 
 // NewInMemoryBinariesRepo - инициализация репозитория бинарных данных
 func NewInMemoryBinariesRepo() *InMemoryBinariesRepo {
 	return &InMemoryBinariesRepo{
+		mu:      &sync.Mutex{},
 		storage: make(map[string]entities.BinaryData),
 	}
 }
 
-// generateID - генерация уникального ID
-func (r *InMemoryBinariesRepo) generateID() string {
-	r.idSeq++
-	return fmt.Sprintf("%d", r.idSeq)
-}
-
-// GetAll - получить все сущности (при наличии прав у текущего пользователя)
+// GetAll - получить все сущности (собственные и те, которыми поделились с текущим пользователем)
 func (r *InMemoryBinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
@@ -39,7 +46,11 @@ func (r *InMemoryBinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryDat
 
 	var binaries []entities.BinaryData
 	for _, binary := range r.storage {
-		if binary.OwnerID == userID {
+		if r.trash.contains("binary", binary.ID) {
+			continue
+		}
+		if entry, ok := r.shares.view("binary", binary.SecureEntity, userID); ok {
+			binary.SecureEntity = entry
 			binaries = append(binaries, binary)
 		}
 	}
@@ -49,25 +60,39 @@ func (r *InMemoryBinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryDat
 
 // Get - получить сущность по ИД (при наличии прав у текущего пользователя)
 func (r *InMemoryBinariesRepo) Get(ctx context.Context, id string) (*entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
 
 	binary, exists := r.storage[id]
-	if !exists {
+	if !exists || r.trash.contains("binary", id) {
 		return nil, nil
 	}
 
-	if binary.OwnerID != userID {
+	entry, ok := r.shares.view("binary", binary.SecureEntity, userID)
+	if !ok {
 		return nil, nil
 	}
 
+	binary.SecureEntity = entry
 	return &binary, nil
 }
 
 // Create - создать сущность
 func (r *InMemoryBinariesRepo) Create(ctx context.Context, dto *dtos.NewBinaryData) (*entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if dto == nil {
 		return nil, errors.New("dto cannot be nil")
 	}
@@ -77,18 +102,34 @@ func (r *InMemoryBinariesRepo) Create(ctx context.Context, dto *dtos.NewBinaryDa
 		return nil, errors.New("user ID is required")
 	}
 
-	id := r.generateID()
+	id := dto.ID
+	if _, exists := r.storage[id]; exists {
+		return nil, fmt.Errorf("binary with ID %s already exists", id)
+	}
+
 	binary := entities.BinaryData{
 		Data:         dto.Data,
-		SecureEntity: entities.SecureEntity{ID: id, Metadata: dto.Metadata, OwnerID: userID},
+		SecureEntity: entities.SecureEntity{ID: id, Metadata: dto.Metadata, OwnerID: userID, CollectionID: dto.CollectionID, KeyVersion: dto.KeyVersion},
 	}
 
+	if err := r.journal.put("binary", binary, id); err != nil {
+		return nil, err
+	}
 	r.storage[id] = binary
+
+	// Запись коллекции возвращается с ключом коллекции, зашифрованным для создателя
+	binary.SecureEntity, _ = r.shares.view("binary", binary.SecureEntity, userID)
 	return &binary, nil
 }
 
-// Update - изменить сущность
+// Update - изменить сущность (права проверяются политикой доступа до вызова)
 func (r *InMemoryBinariesRepo) Update(ctx context.Context, entity *entities.BinaryData) (*entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -98,34 +139,87 @@ func (r *InMemoryBinariesRepo) Update(ctx context.Context, entity *entities.Bina
 		return nil, errors.New("user ID is required")
 	}
 
-	// Проверяем существование и права
 	existing, exists := r.storage[entity.ID]
-	if !exists {
+	if !exists || r.trash.contains("binary", entity.ID) {
 		return nil, nil
 	}
 
-	if existing.OwnerID != userID {
-		return nil, nil
+	updated := *entity
+	updated.OwnerID = existing.OwnerID
+	updated.CollectionID = existing.CollectionID
+	updated.Permission = ""
+	// Пустой ключ записи оставляет прежний; у записей коллекций ключ не хранится, но меняется версия ключа коллекции
+	if updated.EntryKey == "" || existing.CollectionID != "" {
+		updated.EntryKey = existing.EntryKey
 	}
+	if existing.CollectionID == "" {
+		updated.KeyVersion = existing.KeyVersion
+	}
+	if err := r.journal.put("binary", updated, entity.ID); err != nil {
+		return nil, err
+	}
+	r.storage[entity.ID] = updated
 
-	entity.OwnerID = userID
-	r.storage[entity.ID] = *entity
-
-	return entity, nil
+	updated.SecureEntity, _ = r.shares.view("binary", updated.SecureEntity, userID)
+	return &updated, nil
 }
 
-// Delete - удалить сущность
+// Delete - переместить сущность в корзину (права проверяются политикой доступа до вызова)
 func (r *InMemoryBinariesRepo) Delete(ctx context.Context, id string) (*entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
 
 	binary, exists := r.storage[id]
-	if !exists || binary.OwnerID != userID {
+	if !exists || r.trash.contains("binary", id) {
 		return nil, nil
 	}
 
-	delete(r.storage, id)
+	// Без корзины запись удаляется сразу вместе с правами на неё
+	if r.trash == nil {
+		if err := r.remove(id); err != nil {
+			return nil, err
+		}
+		return &binary, nil
+	}
+
+	if err := r.trash.put("binary", id, time.Now()); err != nil {
+		return nil, err
+	}
 	return &binary, nil
 }
+
+// entry - общие поля записи по ИД (для проверки прав при выдаче доступа)
+func (r *InMemoryBinariesRepo) entry(id string) (entities.SecureEntity, bool) {
+	binary, exists := r.storage[id]
+	return binary.SecureEntity, exists
+}
+
+// content - поля записи своего типа в виде JSON (для сохранения версии в истории)
+func (r *InMemoryBinariesRepo) content(id string) (json.RawMessage, bool) {
+	binary, exists := r.storage[id]
+	if !exists {
+		return nil, false
+	}
+
+	content, err := json.Marshal(struct {
+		Data []byte `json:"data"`
+	}{binary.Data})
+	return content, err == nil
+}
+
+// remove - окончательно удалить запись вместе с правами на неё
+func (r *InMemoryBinariesRepo) remove(id string) error {
+	if err := r.journal.remove("binary", id); err != nil {
+		return err
+	}
+	delete(r.storage, id)
+	return r.shares.revokeAll("binary", id)
+}
@@ -3,8 +3,11 @@ package inmemory
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
@@ -13,25 +16,29 @@ import (
 
 // InMemoryCardsRepo - репозиторий банковских карт в памяти
 type InMemoryCardsRepo struct {
+	mu      *sync.Mutex
 	storage map[string]entities.CardInformation
-	idSeq   int64
+	shares  *InMemoryShareRepo // права на записи (nil - записями не делятся)
+	trash   *InMemoryTrashRepo // корзина (nil - записи удаляются сразу)
+	journal *journal           // журнал изменений (nil - состояние не сохраняется)
 }
 
 // NewInMemoryCardsRepo - инициализация репозитория банковских карт
 func NewInMemoryCardsRepo() *InMemoryCardsRepo {
 	return &InMemoryCardsRepo{
+		mu:      &sync.Mutex{},
 		storage: make(map[string]entities.CardInformation),
 	}
 }
 
-// generateID - генерация уникального ID
-func (r *InMemoryCardsRepo) generateID() string {
-	r.idSeq++
-	return fmt.Sprintf("%d", r.idSeq)
-}
-
-// GetAll - получить все сущности (при наличии прав у текущего пользователя)
+// GetAll - получить все сущности (собственные и те, которыми поделились с текущим пользователем)
 func (r *InMemoryCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
@@ -39,7 +46,11 @@ func (r *InMemoryCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformat
 
 	var cards []entities.CardInformation
 	for _, card := range r.storage {
-		if card.OwnerID == userID {
+		if r.trash.contains("card", card.ID) {
+			continue
+		}
+		if entry, ok := r.shares.view("card", card.SecureEntity, userID); ok {
+			card.SecureEntity = entry
 			cards = append(cards, card)
 		}
 	}
@@ -49,25 +60,39 @@ func (r *InMemoryCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformat
 
 // Get - получить сущность по ИД (при наличии прав у текущего пользователя)
 func (r *InMemoryCardsRepo) Get(ctx context.Context, id string) (*entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
 
 	card, exists := r.storage[id]
-	if !exists {
+	if !exists || r.trash.contains("card", id) {
 		return nil, nil
 	}
 
-	if card.OwnerID != userID {
+	entry, ok := r.shares.view("card", card.SecureEntity, userID)
+	if !ok {
 		return nil, nil
 	}
 
+	card.SecureEntity = entry
 	return &card, nil
 }
 
 // Create - создать сущность
 func (r *InMemoryCardsRepo) Create(ctx context.Context, dto *dtos.NewCardInformation) (*entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if dto == nil {
 		return nil, errors.New("dto cannot be nil")
 	}
@@ -77,21 +102,37 @@ func (r *InMemoryCardsRepo) Create(ctx context.Context, dto *dtos.NewCardInforma
 		return nil, errors.New("user ID is required")
 	}
 
-	id := r.generateID()
+	id := dto.ID
+	if _, exists := r.storage[id]; exists {
+		return nil, fmt.Errorf("card with ID %s already exists", id)
+	}
+
 	card := entities.CardInformation{
 		Number:         dto.Number,
 		CardHolder:     dto.CardHolder,
 		ExpirationDate: dto.ExpirationDate,
 		CVV:            dto.CVV,
-		SecureEntity:   entities.SecureEntity{ID: id, Metadata: dto.Metadata, OwnerID: userID},
+		SecureEntity:   entities.SecureEntity{ID: id, Metadata: dto.Metadata, OwnerID: userID, CollectionID: dto.CollectionID, KeyVersion: dto.KeyVersion},
 	}
 
+	if err := r.journal.put("card", card, id); err != nil {
+		return nil, err
+	}
 	r.storage[id] = card
+
+	// Запись коллекции возвращается с ключом коллекции, зашифрованным для создателя
+	card.SecureEntity, _ = r.shares.view("card", card.SecureEntity, userID)
 	return &card, nil
 }
 
-// Update - изменить сущность
+// Update - изменить сущность (права проверяются политикой доступа до вызова)
 func (r *InMemoryCardsRepo) Update(ctx context.Context, entity *entities.CardInformation) (*entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -101,34 +142,90 @@ func (r *InMemoryCardsRepo) Update(ctx context.Context, entity *entities.CardInf
 		return nil, errors.New("user ID is required")
 	}
 
-	// Проверяем существование и права
 	existing, exists := r.storage[entity.ID]
-	if !exists {
+	if !exists || r.trash.contains("card", entity.ID) {
 		return nil, nil
 	}
 
-	if existing.OwnerID != userID {
-		return nil, nil
+	updated := *entity
+	updated.OwnerID = existing.OwnerID
+	updated.CollectionID = existing.CollectionID
+	updated.Permission = ""
+	// Пустой ключ записи оставляет прежний; у записей коллекций ключ не хранится, но меняется версия ключа коллекции
+	if updated.EntryKey == "" || existing.CollectionID != "" {
+		updated.EntryKey = existing.EntryKey
 	}
+	if existing.CollectionID == "" {
+		updated.KeyVersion = existing.KeyVersion
+	}
+	if err := r.journal.put("card", updated, entity.ID); err != nil {
+		return nil, err
+	}
+	r.storage[entity.ID] = updated
 
-	entity.OwnerID = userID
-	r.storage[entity.ID] = *entity
-
-	return entity, nil
+	updated.SecureEntity, _ = r.shares.view("card", updated.SecureEntity, userID)
+	return &updated, nil
 }
 
-// Delete - удалить сущность
+// Delete - переместить сущность в корзину (права проверяются политикой доступа до вызова)
 func (r *InMemoryCardsRepo) Delete(ctx context.Context, id string) (*entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
 
 	card, exists := r.storage[id]
-	if !exists || card.OwnerID != userID {
+	if !exists || r.trash.contains("card", id) {
 		return nil, nil
 	}
 
-	delete(r.storage, id)
+	// Без корзины запись удаляется сразу вместе с правами на неё
+	if r.trash == nil {
+		if err := r.remove(id); err != nil {
+			return nil, err
+		}
+		return &card, nil
+	}
+
+	if err := r.trash.put("card", id, time.Now()); err != nil {
+		return nil, err
+	}
 	return &card, nil
 }
+
+// entry - общие поля записи по ИД (для проверки прав при выдаче доступа)
+func (r *InMemoryCardsRepo) entry(id string) (entities.SecureEntity, bool) {
+	card, exists := r.storage[id]
+	return card.SecureEntity, exists
+}
+
+// content - поля записи своего типа в виде JSON (для сохранения версии в истории)
+func (r *InMemoryCardsRepo) content(id string) (json.RawMessage, bool) {
+	card, exists := r.storage[id]
+	if !exists {
+		return nil, false
+	}
+
+	content, err := json.Marshal(struct {
+		Number         string `json:"number"`
+		CardHolder     string `json:"card_holder"`
+		ExpirationDate string `json:"expiration_date"`
+		CVV            string `json:"cvv"`
+	}{card.Number, card.CardHolder, card.ExpirationDate, card.CVV})
+	return content, err == nil
+}
+
+// remove - окончательно удалить запись вместе с правами на неё
+func (r *InMemoryCardsRepo) remove(id string) error {
+	if err := r.journal.remove("card", id); err != nil {
+		return err
+	}
+	delete(r.storage, id)
+	return r.shares.revokeAll("card", id)
+}
@@ -3,8 +3,11 @@ package inmemory
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
@@ -13,25 +16,29 @@ import (
 
 // InMemoryCredentialsRepo - репозиторий учетных данных в памяти
 type InMemoryCredentialsRepo struct {
+	mu      *sync.Mutex
 	storage map[string]entities.Credentials
-	idSeq   int64
+	shares  *InMemoryShareRepo // права на записи (nil - записями не делятся)
+	trash   *InMemoryTrashRepo // корзина (nil - записи удаляются сразу)
+	journal *journal           // журнал изменений (nil - состояние не сохраняется)
 }
 
 // NewInMemoryCredentialsRepo - инициализация репозитория учетных данных
 func NewInMemoryCredentialsRepo() *InMemoryCredentialsRepo {
 	return &InMemoryCredentialsRepo{
+		mu:      &sync.Mutex{},
 		storage: make(map[string]entities.Credentials),
 	}
 }
 
-// generateID - генерация уникального ID
-func (r *InMemoryCredentialsRepo) generateID() string {
-	r.idSeq++
-	return fmt.Sprintf("%d", r.idSeq)
-}
-
-// GetAll - получить все сущности (при наличии прав у текущего пользователя)
+// GetAll - получить все сущности (собственные и те, которыми поделились с текущим пользователем)
 func (r *InMemoryCredentialsRepo) GetAll(ctx context.Context) ([]entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
@@ -39,7 +46,11 @@ func (r *InMemoryCredentialsRepo) GetAll(ctx context.Context) ([]entities.Creden
 
 	var creds []entities.Credentials
 	for _, cred := range r.storage {
-		if cred.OwnerID == userID {
+		if r.trash.contains("credentials", cred.ID) {
+			continue
+		}
+		if entry, ok := r.shares.view("credentials", cred.SecureEntity, userID); ok {
+			cred.SecureEntity = entry
 			creds = append(creds, cred)
 		}
 	}
@@ -49,25 +60,39 @@ func (r *InMemoryCredentialsRepo) GetAll(ctx context.Context) ([]entities.Creden
 
 // Get - получить сущность по ИД (при наличии прав у текущего пользователя)
 func (r *InMemoryCredentialsRepo) Get(ctx context.Context, id string) (*entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
 
 	cred, exists := r.storage[id]
-	if !exists {
+	if !exists || r.trash.contains("credentials", id) {
 		return nil, nil
 	}
 
-	if cred.OwnerID != userID {
+	entry, ok := r.shares.view("credentials", cred.SecureEntity, userID)
+	if !ok {
 		return nil, nil
 	}
 
+	cred.SecureEntity = entry
 	return &cred, nil
 }
 
 // Create - создать сущность
 func (r *InMemoryCredentialsRepo) Create(ctx context.Context, dto *dtos.NewCredentials) (*entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if dto == nil {
 		return nil, errors.New("dto cannot be nil")
 	}
@@ -77,19 +102,35 @@ func (r *InMemoryCredentialsRepo) Create(ctx context.Context, dto *dtos.NewCrede
 		return nil, errors.New("user ID is required")
 	}
 
-	id := r.generateID()
+	id := dto.ID
+	if _, exists := r.storage[id]; exists {
+		return nil, fmt.Errorf("credentials with ID %s already exists", id)
+	}
+
 	cred := entities.Credentials{
 		Login:        dto.Login,
 		Password:     dto.Password,
-		SecureEntity: entities.SecureEntity{ID: id, Metadata: dto.Metadata, OwnerID: userID},
+		SecureEntity: entities.SecureEntity{ID: id, Metadata: dto.Metadata, OwnerID: userID, CollectionID: dto.CollectionID, KeyVersion: dto.KeyVersion},
 	}
 
+	if err := r.journal.put("credentials", cred, id); err != nil {
+		return nil, err
+	}
 	r.storage[id] = cred
+
+	// Запись коллекции возвращается с ключом коллекции, зашифрованным для создателя
+	cred.SecureEntity, _ = r.shares.view("credentials", cred.SecureEntity, userID)
 	return &cred, nil
 }
 
-// Update - изменить сущность
+// Update - изменить сущность (права проверяются политикой доступа до вызова)
 func (r *InMemoryCredentialsRepo) Update(ctx context.Context, entity *entities.Credentials) (*entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -99,34 +140,88 @@ func (r *InMemoryCredentialsRepo) Update(ctx context.Context, entity *entities.C
 		return nil, errors.New("user ID is required")
 	}
 
-	// Проверяем существование и права
 	existing, exists := r.storage[entity.ID]
-	if !exists {
+	if !exists || r.trash.contains("credentials", entity.ID) {
 		return nil, nil
 	}
 
-	if existing.OwnerID != userID {
-		return nil, nil
+	updated := *entity
+	updated.OwnerID = existing.OwnerID
+	updated.CollectionID = existing.CollectionID
+	updated.Permission = ""
+	// Пустой ключ записи оставляет прежний; у записей коллекций ключ не хранится, но меняется версия ключа коллекции
+	if updated.EntryKey == "" || existing.CollectionID != "" {
+		updated.EntryKey = existing.EntryKey
 	}
+	if existing.CollectionID == "" {
+		updated.KeyVersion = existing.KeyVersion
+	}
+	if err := r.journal.put("credentials", updated, entity.ID); err != nil {
+		return nil, err
+	}
+	r.storage[entity.ID] = updated
 
-	entity.OwnerID = userID
-	r.storage[entity.ID] = *entity
-
-	return entity, nil
+	updated.SecureEntity, _ = r.shares.view("credentials", updated.SecureEntity, userID)
+	return &updated, nil
 }
 
-// Delete - удалить сущность
+// Delete - переместить сущность в корзину (права проверяются политикой доступа до вызова)
 func (r *InMemoryCredentialsRepo) Delete(ctx context.Context, id string) (*entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
 
 	cred, exists := r.storage[id]
-	if !exists || cred.OwnerID != userID {
+	if !exists || r.trash.contains("credentials", id) {
 		return nil, nil
 	}
 
-	delete(r.storage, id)
+	// Без корзины запись удаляется сразу вместе с правами на неё
+	if r.trash == nil {
+		if err := r.remove(id); err != nil {
+			return nil, err
+		}
+		return &cred, nil
+	}
+
+	if err := r.trash.put("credentials", id, time.Now()); err != nil {
+		return nil, err
+	}
 	return &cred, nil
 }
+
+// entry - общие поля записи по ИД (для проверки прав при выдаче доступа)
+func (r *InMemoryCredentialsRepo) entry(id string) (entities.SecureEntity, bool) {
+	cred, exists := r.storage[id]
+	return cred.SecureEntity, exists
+}
+
+// content - поля записи своего типа в виде JSON (для сохранения версии в истории)
+func (r *InMemoryCredentialsRepo) content(id string) (json.RawMessage, bool) {
+	cred, exists := r.storage[id]
+	if !exists {
+		return nil, false
+	}
+
+	content, err := json.Marshal(struct {
+		Login    string `json:"login"`
+		Password string `json:"password"`
+	}{cred.Login, cred.Password})
+	return content, err == nil
+}
+
+// remove - окончательно удалить запись вместе с правами на неё
+func (r *InMemoryCredentialsRepo) remove(id string) error {
+	if err := r.journal.remove("credentials", id); err != nil {
+		return err
+	}
+	delete(r.storage, id)
+	return r.shares.revokeAll("credentials", id)
+}
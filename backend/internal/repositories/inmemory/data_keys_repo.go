@@ -0,0 +1,120 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// InMemoryDataKeysRepo - ключи данных пользователей в памяти (зашифрованные мастер-ключом)
+type InMemoryDataKeysRepo struct {
+	mu      *sync.Mutex
+	storage map[string]entities.DataKey
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryDataKeysRepo - инициализация репозитория ключей данных
+func NewInMemoryDataKeysRepo() *InMemoryDataKeysRepo {
+	return &InMemoryDataKeysRepo{
+		mu:      &sync.Mutex{},
+		storage: make(map[string]entities.DataKey),
+	}
+}
+
+// Get - ключ данных пользователя
+func (r *InMemoryDataKeysRepo) Get(ctx context.Context, login string) (*entities.DataKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, exists := r.storage[login]
+	if !exists {
+		return nil, nil
+	}
+
+	return &key, nil
+}
+
+// GetAll - ключи данных всех пользователей (по логину)
+func (r *InMemoryDataKeysRepo) GetAll(ctx context.Context) ([]entities.DataKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]entities.DataKey, 0, len(r.storage))
+	for _, key := range r.storage {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Login < keys[j].Login })
+
+	return keys, nil
+}
+
+// Create - сохранить ключ данных, если у пользователя его ещё нет (иначе вернуть существующий)
+func (r *InMemoryDataKeysRepo) Create(ctx context.Context, key *entities.DataKey) (*entities.DataKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key == nil {
+		return nil, errors.New("data key cannot be nil")
+	}
+
+	if existing, exists := r.storage[key.Login]; exists {
+		return &existing, nil
+	}
+
+	if err := r.journal.put(tableDataKeys, key, key.Login); err != nil {
+		return nil, err
+	}
+	r.storage[key.Login] = *key
+	return key, nil
+}
+
+// Update - заменить зашифрованный ключ данных
+func (r *InMemoryDataKeysRepo) Update(ctx context.Context, key *entities.DataKey) (*entities.DataKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.storage[key.Login]; !exists {
+		return nil, nil
+	}
+
+	if err := r.journal.put(tableDataKeys, key, key.Login); err != nil {
+		return nil, err
+	}
+	r.storage[key.Login] = *key
+	return key, nil
+}
+
+// Delete - удалить ключ данных пользователя
+func (r *InMemoryDataKeysRepo) Delete(ctx context.Context, login string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.storage[login]; !exists {
+		return nil
+	}
+
+	if err := r.journal.remove(tableDataKeys, login); err != nil {
+		return err
+	}
+	delete(r.storage, login)
+	return nil
+}
@@ -1,6 +1,13 @@
 // inmemory содержит репозиторий который хранит данные в оперативной памяти
 package inmemory
 
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
 // InMemoryRepositories - структура содержащая все репозитории
 type DatabaseManager struct {
 	Users       *InMemoryUsersRepo
@@ -8,15 +15,158 @@ type DatabaseManager struct {
 	Cards       *InMemoryCardsRepo
 	Credentials *InMemoryCredentialsRepo
 	Texts       *InMemoryTextsRepo
+	Audit       *InMemoryAuditRepo
+	UserKeys    *InMemoryUserKeysRepo
+	Shares      *InMemoryShareRepo
+	Orgs        *InMemoryOrganizationRepo
+	Access      *InMemoryAccessRepo
+	Emergency   *InMemoryEmergencyAccessRepo
+	Accounts    *InMemoryAccountRepo
+	Invites     *InMemoryInviteRepo
+	Trash       *InMemoryTrashRepo
+	History     *InMemoryHistoryRepo
+	DataKeys    *InMemoryDataKeysRepo
+
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
 }
 
 // NewDatabaseManager - создание менеджера репозиториев
 func NewDatabaseManager() *DatabaseManager {
-	return &DatabaseManager{
+	manager := &DatabaseManager{
 		Users:       NewInMemoryUsersRepo(),
 		Binaries:    NewInMemoryBinariesRepo(),
 		Cards:       NewInMemoryCardsRepo(),
 		Credentials: NewInMemoryCredentialsRepo(),
 		Texts:       NewInMemoryTextsRepo(),
+		Audit:       NewInMemoryAuditRepo(),
+		UserKeys:    NewInMemoryUserKeysRepo(),
+		Shares:      NewInMemoryShareRepo(),
+		Orgs:        NewInMemoryOrganizationRepo(),
+		Emergency:   NewInMemoryEmergencyAccessRepo(),
+		Invites:     NewInMemoryInviteRepo(),
+		DataKeys:    NewInMemoryDataKeysRepo(),
 	}
+	manager.Access = NewInMemoryAccessRepo(manager.Shares, manager.Orgs)
+	manager.Trash = NewInMemoryTrashRepo(manager.Shares, manager.Orgs)
+	manager.History = NewInMemoryHistoryRepo(manager.Shares, manager.Orgs, manager.Trash)
+	manager.Accounts = NewInMemoryAccountRepo(manager)
+
+	// Репозитории читают и меняют данные друг друга, поэтому все операции выполняются под одной блокировкой
+	mu := &sync.Mutex{}
+	manager.Users.mu = mu
+	manager.Binaries.mu = mu
+	manager.Cards.mu = mu
+	manager.Credentials.mu = mu
+	manager.Texts.mu = mu
+	manager.Audit.mu = mu
+	manager.UserKeys.mu = mu
+	manager.Shares.mu = mu
+	manager.Orgs.mu = mu
+	manager.Access.mu = mu
+	manager.Emergency.mu = mu
+	manager.Accounts.mu = mu
+	manager.Invites.mu = mu
+	manager.Trash.mu = mu
+	manager.History.mu = mu
+	manager.DataKeys.mu = mu
+
+	// Репозитории записей и прав ссылаются друг на друга: права проверяются при чтении записей, владелец - при выдаче прав
+	manager.Binaries.shares = manager.Shares
+	manager.Cards.shares = manager.Shares
+	manager.Credentials.shares = manager.Shares
+	manager.Texts.shares = manager.Shares
+	manager.Shares.orgs = manager.Orgs
+	manager.Shares.trash = manager.Trash
+	manager.Shares.register("binary", manager.Binaries)
+	manager.Shares.register("card", manager.Cards)
+	manager.Shares.register("credentials", manager.Credentials)
+	manager.Shares.register("text", manager.Texts)
+
+	// Удалённые записи попадают в корзину и остаются в репозиториях своих типов до очистки
+	manager.Binaries.trash = manager.Trash
+	manager.Cards.trash = manager.Trash
+	manager.Credentials.trash = manager.Trash
+	manager.Texts.trash = manager.Trash
+	manager.Trash.register("binary", manager.Binaries)
+	manager.Trash.register("card", manager.Cards)
+	manager.Trash.register("credentials", manager.Credentials)
+	manager.Trash.register("text", manager.Texts)
+
+	// История хранит прежние версии записей и читает текущие, чтобы выбрать ключ для пользователя
+	manager.History.register("binary", manager.Binaries)
+	manager.History.register("card", manager.Cards)
+	manager.History.register("credentials", manager.Credentials)
+	manager.History.register("text", manager.Texts)
+
+	return manager
+}
+
+// OpenDatabaseManager - менеджер репозиториев, состояние которых сохраняется в директории dir: восстанавливается
+// из снимка и журнала изменений при открытии, а каждое изменение дописывается в журнал
+func OpenDatabaseManager(dir string) (*DatabaseManager, error) {
+	j, err := openJournal(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	manager := NewDatabaseManager()
+	if err := manager.restore(j); err != nil {
+		j.wal.Close()
+		return nil, err
+	}
+
+	manager.journal = j
+	manager.Users.journal = j
+	manager.Binaries.journal = j
+	manager.Cards.journal = j
+	manager.Credentials.journal = j
+	manager.Texts.journal = j
+	manager.Audit.journal = j
+	manager.UserKeys.journal = j
+	manager.Shares.journal = j
+	manager.Orgs.journal = j
+	manager.Emergency.journal = j
+	manager.Accounts.journal = j
+	manager.Invites.journal = j
+	manager.Trash.journal = j
+	manager.History.journal = j
+	manager.DataKeys.journal = j
+
+	return manager, nil
+}
+
+// Snapshot - атомарно записать снимок состояния и очистить журнал изменений.
+// Может вызываться из любой горутины: снимок пишется из копии состояния, которую ведёт журнал
+func (m *DatabaseManager) Snapshot() error {
+	if m.journal == nil {
+		return nil
+	}
+	return m.journal.snapshot()
+}
+
+// RunSnapshots - фоновая задача: записывать снимок состояния каждые interval до отмены контекста
+func (m *DatabaseManager) RunSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Snapshot(); err != nil {
+				log.Printf("failed to write snapshot: %v", err)
+			}
+		}
+	}
+}
+
+// Ping - ошибка журнала изменений, из-за которой репозитории не принимают изменений до следующего снимка (nil - журнал исправен)
+func (m *DatabaseManager) Ping(ctx context.Context) error {
+	return m.journal.lastError()
+}
+
+// Close - записать последний снимок и закрыть журнал изменений (после остановки сервиса хранения)
+func (m *DatabaseManager) Close() error {
+	return m.journal.close()
 }
@@ -0,0 +1,212 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// InMemoryEmergencyAccessRepo - экстренный доступ доверенных лиц в памяти
+type InMemoryEmergencyAccessRepo struct {
+	mu      *sync.Mutex
+	storage map[string]entities.EmergencyAccess
+	idSeq   int64
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryEmergencyAccessRepo - инициализация репозитория экстренного доступа
+func NewInMemoryEmergencyAccessRepo() *InMemoryEmergencyAccessRepo {
+	return &InMemoryEmergencyAccessRepo{
+		mu:      &sync.Mutex{},
+		storage: make(map[string]entities.EmergencyAccess),
+	}
+}
+
+// generateID - генерация уникального ID
+func (r *InMemoryEmergencyAccessRepo) generateID() string {
+	r.idSeq++
+	return fmt.Sprintf("%d", r.idSeq)
+}
+
+// Create - назначить доверенное лицо от имени текущего пользователя (nil, если оно уже назначено)
+func (r *InMemoryEmergencyAccessRepo) Create(ctx context.Context, dto *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dto == nil {
+		return nil, errors.New("dto cannot be nil")
+	}
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	for _, existing := range r.storage {
+		if existing.GrantorID == userID && existing.GranteeID == dto.GranteeID {
+			return nil, nil
+		}
+	}
+
+	access := entities.EmergencyAccess{
+		ID:           r.generateID(),
+		GrantorID:    userID,
+		GranteeID:    dto.GranteeID,
+		Status:       entities.EmergencyStatusInvited,
+		WaitDays:     dto.WaitDays,
+		EncryptedKey: dto.EncryptedKey,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := r.journal.put(tableEmergency, access, access.ID); err != nil {
+		return nil, err
+	}
+	r.storage[access.ID] = access
+	return &access, nil
+}
+
+// GetAll - получить экстренные доступы, в которых текущий пользователь доверитель или доверенное лицо (в порядке создания)
+func (r *InMemoryEmergencyAccessRepo) GetAll(ctx context.Context) ([]entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	var result []entities.EmergencyAccess
+	for _, access := range r.storage {
+		if access.GrantorID == userID || access.GranteeID == userID {
+			result = append(result, access)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// Get - получить экстренный доступ по ИД (nil, если его нет)
+func (r *InMemoryEmergencyAccessRepo) Get(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	access, exists := r.storage[id]
+	if !exists {
+		return nil, nil
+	}
+
+	return &access, nil
+}
+
+// SetStatus - перевести доступ из состояния from в состояние to (nil, если он уже не в состоянии from)
+func (r *InMemoryEmergencyAccessRepo) SetStatus(ctx context.Context, id, from, to string) (*entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	access, exists := r.storage[id]
+	if !exists || access.Status != from {
+		return nil, nil
+	}
+
+	access.Status = to
+	switch to {
+	case entities.EmergencyStatusRequested:
+		now := time.Now()
+		access.RequestedAt = &now
+	case entities.EmergencyStatusAccepted:
+		access.RequestedAt = nil
+	}
+
+	if err := r.journal.put(tableEmergency, access, id); err != nil {
+		return nil, err
+	}
+	r.storage[id] = access
+	return &access, nil
+}
+
+// GrantElapsed - предоставить доступ по запросам, период ожидания которых истёк к моменту now
+func (r *InMemoryEmergencyAccessRepo) GrantElapsed(ctx context.Context, now time.Time) ([]entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Доступы предоставляются одной записью журнала: все или ни один
+	var granted []entities.EmergencyAccess
+	var changes changeSet
+	for _, access := range r.storage {
+		if access.Status != entities.EmergencyStatusRequested || access.GrantAt().After(now) {
+			continue
+		}
+
+		access.Status = entities.EmergencyStatusGranted
+		changes.put(tableEmergency, access, access.ID)
+		granted = append(granted, access)
+	}
+	if err := r.journal.commit(&changes); err != nil {
+		return nil, err
+	}
+	for _, access := range granted {
+		r.storage[access.ID] = access
+	}
+
+	return granted, nil
+}
+
+// Delete - удалить экстренный доступ
+func (r *InMemoryEmergencyAccessRepo) Delete(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	access, exists := r.storage[id]
+	if !exists {
+		return nil, nil
+	}
+
+	if err := r.journal.remove(tableEmergency, id); err != nil {
+		return nil, err
+	}
+	delete(r.storage, id)
+	return &access, nil
+}
+
+// removeUser - удалить экстренные доступы, где пользователь доверитель или доверенное лицо (при удалении учётной записи)
+func (r *InMemoryEmergencyAccessRepo) removeUser(login string) error {
+	for id, access := range r.storage {
+		if access.GrantorID == login || access.GranteeID == login {
+			if err := r.journal.remove(tableEmergency, id); err != nil {
+				return err
+			}
+			delete(r.storage, id)
+		}
+	}
+	return nil
+}
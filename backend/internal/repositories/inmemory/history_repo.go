@@ -0,0 +1,234 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// historyEntries - репозиторий записей, изменения которых сохраняются в истории
+type historyEntries interface {
+	sharableEntries
+	// content - поля записи своего типа в виде JSON
+	content(id string) (json.RawMessage, bool)
+}
+
+// InMemoryHistoryRepo - история записей в памяти. Версии хранятся с ключом, которым они были зашифрованы
+type InMemoryHistoryRepo struct {
+	mu        *sync.Mutex
+	revisions map[entryRef][]entities.Revision // версии записи в порядке сохранения
+	entries   map[string]historyEntries        // репозитории записей по типу сущности
+	shares    *InMemoryShareRepo
+	orgs      *InMemoryOrganizationRepo
+	trash     *InMemoryTrashRepo
+	journal   *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryHistoryRepo - инициализация репозитория истории
+func NewInMemoryHistoryRepo(shares *InMemoryShareRepo, orgs *InMemoryOrganizationRepo, trash *InMemoryTrashRepo) *InMemoryHistoryRepo {
+	return &InMemoryHistoryRepo{
+		mu:        &sync.Mutex{},
+		revisions: make(map[entryRef][]entities.Revision),
+		entries:   make(map[string]historyEntries),
+		shares:    shares,
+		orgs:      orgs,
+		trash:     trash,
+	}
+}
+
+// register - подключить репозиторий записей указанного типа
+func (r *InMemoryHistoryRepo) register(entityType string, entries historyEntries) {
+	r.entries[entityType] = entries
+}
+
+// Append - сохранить текущее состояние записи как новую версию от имени текущего пользователя, изменить запись и удалить
+// версии сверх ограничений. Версия добавляется только после успешного изменения записи
+func (r *InMemoryHistoryRepo) Append(ctx context.Context, entityType, id string, maxVersions int, notBefore time.Time, update func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	revision, archived, err := r.current(entityType, id)
+	if err != nil {
+		return err
+	}
+
+	// Репозитории записей защищены тем же мьютексом, поэтому изменение выполняется без него
+	if err := update(ctx); err != nil {
+		return err
+	}
+	if !archived {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ref := entryRef{entityType: entityType, id: id}
+	revisions := r.revisions[ref]
+
+	revision.Revision = 1
+	if len(revisions) > 0 {
+		revision.Revision = revisions[len(revisions)-1].Revision + 1
+	}
+	revision.ReplacedBy = customcontext.GetUserID(ctx)
+	revision.ReplacedAt = time.Now()
+	revisions = append(revisions, revision)
+
+	// Новая версия всегда моложе notBefore, поэтому хотя бы она остаётся. Версии отбираются в новый срез:
+	// если журнал не примет изменение, сохранённые версии не должны измениться
+	kept := make([]entities.Revision, 0, len(revisions))
+	for _, stored := range revisions {
+		if maxVersions > 0 && stored.Revision <= revision.Revision-maxVersions {
+			continue
+		}
+		if stored.ReplacedAt.Before(notBefore) {
+			continue
+		}
+		kept = append(kept, stored)
+	}
+	if err := r.journal.put(tableHistory, kept, entityType, id); err != nil {
+		return err
+	}
+	r.revisions[ref] = kept
+
+	return nil
+}
+
+// current - текущее состояние записи в виде версии без номера (false, если записи нет или она в корзине)
+func (r *InMemoryHistoryRepo) current(entityType, id string) (entities.Revision, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	repo, known := r.entries[entityType]
+	if !known {
+		return entities.Revision{}, false, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	entry, exists := repo.entry(id)
+	if !exists || r.trash.contains(entityType, id) {
+		return entities.Revision{}, false, nil
+	}
+	content, _ := repo.content(id)
+
+	return entities.Revision{
+		EntityType: entityType,
+		EntityID:   id,
+		Content:    content,
+		Metadata:   entry.Metadata,
+		EntryKey:   entry.EntryKey,
+		KeyVersion: entry.KeyVersion,
+	}, true, nil
+}
+
+// GetAll - версии записи, которые текущий пользователь может расшифровать (сначала последние)
+func (r *InMemoryHistoryRepo) GetAll(ctx context.Context, entityType, id string) ([]entities.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	if _, known := r.entries[entityType]; !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	var revisions []entities.Revision
+	for _, revision := range r.revisions[entryRef{entityType: entityType, id: id}] {
+		if viewed, ok := r.view(revision, userID); ok {
+			revisions = append(revisions, viewed)
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+
+	return revisions, nil
+}
+
+// Get - версия записи в представлении текущего пользователя (nil, если версии нет или пользователь не может её расшифровать)
+func (r *InMemoryHistoryRepo) Get(ctx context.Context, entityType, id string, revision int) (*entities.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	if _, known := r.entries[entityType]; !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	for _, stored := range r.revisions[entryRef{entityType: entityType, id: id}] {
+		if stored.Revision != revision {
+			continue
+		}
+		if viewed, ok := r.view(stored, userID); ok {
+			return &viewed, nil
+		}
+		break
+	}
+
+	return nil, nil
+}
+
+// view - версия в представлении пользователя userID: владельцу личной записи - с ключом, которым она зашифрована,
+// получателю - с ключом из приглашения, если версия зашифрована текущим ключом записи, участнику организации -
+// с ключом коллекции нужной версии. ok=false - записи нет или расшифровать версию пользователю нечем
+func (r *InMemoryHistoryRepo) view(revision entities.Revision, userID string) (entities.Revision, bool) {
+	ref := entryRef{entityType: revision.EntityType, id: revision.EntityID}
+	entry, exists := r.entries[ref.entityType].entry(ref.id)
+	if !exists {
+		// Запись удалена из корзины, вместе с учётной записью владельца или организацией.
+		// Версии, которые не удалось убрать из журнала, удалятся при следующем чтении
+		if r.journal.remove(tableHistory, ref.entityType, ref.id) == nil {
+			delete(r.revisions, ref)
+		}
+		return revision, false
+	}
+	if r.trash.contains(ref.entityType, ref.id) {
+		return revision, false
+	}
+
+	switch {
+	case entry.CollectionID != "":
+		_, key, ok := r.orgs.memberKey(entry.CollectionID, userID, revision.KeyVersion)
+		if !ok {
+			return revision, false
+		}
+		revision.Restorable = revision.KeyVersion == entry.KeyVersion
+		revision.EntryKey = key
+	case entry.OwnerID == userID:
+		revision.Restorable = revision.EntryKey == entry.EntryKey
+	default:
+		if revision.EntryKey == "" || revision.EntryKey != entry.EntryKey {
+			return revision, false
+		}
+		viewed, ok := r.shares.view(ref.entityType, entry, userID)
+		if !ok {
+			return revision, false
+		}
+		revision.Restorable = true
+		revision.EntryKey = viewed.EntryKey
+	}
+
+	return revision, true
+}
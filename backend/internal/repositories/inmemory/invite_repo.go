@@ -0,0 +1,135 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// InMemoryInviteRepo - коды приглашений в памяти
+type InMemoryInviteRepo struct {
+	mu      *sync.Mutex
+	storage map[string]entities.Invite
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryInviteRepo - инициализация репозитория приглашений
+func NewInMemoryInviteRepo() *InMemoryInviteRepo {
+	return &InMemoryInviteRepo{
+		mu:      &sync.Mutex{},
+		storage: make(map[string]entities.Invite),
+	}
+}
+
+// Create - сохранить приглашение с кодом, выпущенным сервисом
+func (r *InMemoryInviteRepo) Create(ctx context.Context, code string, dto *dtos.NewInvite) (*entities.Invite, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dto == nil {
+		return nil, errors.New("dto cannot be nil")
+	}
+	if _, exists := r.storage[code]; exists {
+		return nil, errors.New("invite code already exists")
+	}
+
+	invite := entities.Invite{
+		Code:         code,
+		MaxUses:      dto.MaxUses,
+		LoginPattern: dto.LoginPattern,
+		ExpiresAt:    dto.ExpiresAt,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := r.journal.put(tableInvites, invite, code); err != nil {
+		return nil, err
+	}
+	r.storage[code] = invite
+	return &invite, nil
+}
+
+// GetAll - получить все приглашения (в порядке выпуска)
+func (r *InMemoryInviteRepo) GetAll(ctx context.Context) ([]entities.Invite, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invites := make([]entities.Invite, 0, len(r.storage))
+	for _, invite := range r.storage {
+		invites = append(invites, invite)
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+
+	return invites, nil
+}
+
+// Get - получить приглашение по коду
+func (r *InMemoryInviteRepo) Get(ctx context.Context, code string) (*entities.Invite, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite, exists := r.storage[code]
+	if !exists {
+		return nil, nil
+	}
+
+	return &invite, nil
+}
+
+// Use - засчитать использование приглашения, если оно не истекло и не исчерпано
+func (r *InMemoryInviteRepo) Use(ctx context.Context, code string, now time.Time) (*entities.Invite, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite, exists := r.storage[code]
+	if !exists || !invite.Usable(now) {
+		return nil, nil
+	}
+
+	invite.Uses++
+	if err := r.journal.put(tableInvites, invite, code); err != nil {
+		return nil, err
+	}
+	r.storage[code] = invite
+	return &invite, nil
+}
+
+// Delete - отозвать приглашение
+func (r *InMemoryInviteRepo) Delete(ctx context.Context, code string) (*entities.Invite, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite, exists := r.storage[code]
+	if !exists {
+		return nil, nil
+	}
+
+	if err := r.journal.remove(tableInvites, code); err != nil {
+		return nil, err
+	}
+	delete(r.storage, code)
+	return &invite, nil
+}
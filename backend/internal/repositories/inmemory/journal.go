@@ -0,0 +1,368 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Файлы состояния в директории данных
+const (
+	snapshotFile = "snapshot.json" // снимок состояния
+	walFile      = "wal.log"       // изменения после снимка, по одному в строке
+)
+
+// Таблицы журнала. Записи хранятся в таблицах с именами их типов (binary, card, credentials, text)
+const (
+	tableUsers          = "users"
+	tableUserKeys       = "user_keys"
+	tableQuotas         = "quotas"
+	tableInvites        = "invites"
+	tableAudit          = "audit"
+	tableShares         = "shares"
+	tableEmergency      = "emergency_access"
+	tableOrgs           = "organizations"
+	tableOrgMembers     = "org_members" // ключ: ИД организации, логин
+	tableCollections    = "collections"
+	tableCollectionKeys = "collection_keys" // ключ: ИД коллекции, логин, версия
+	tableTrash          = "trash"           // ключ: тип записи, ИД
+	tableHistory        = "history"         // ключ: тип записи, ИД
+	tableDataKeys       = "data_keys"
+)
+
+// row - строка таблицы журнала. В журнале изменений Value == nil означает удаление строки
+type row struct {
+	Table string          `json:"table"`
+	Key   []string        `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// journal - журнал изменений репозиториев в памяти. Каждое изменение дописывается в файл журнала (WAL),
+// а последнее состояние строк хранится отдельно от репозиториев, чтобы снимок можно было записать из другой горутины.
+// Методы nil-журнала ничего не делают (состояние не сохраняется)
+type journal struct {
+	mu     sync.Mutex
+	dir    string
+	wal    *os.File
+	rows   map[string]map[string]row // строки по таблице и ключу
+	size   int64                     // длина журнала изменений до последнего подтверждённого изменения
+	err    error                     // журнал повреждён неудачной записью и не принимает изменений до снимка
+	closed bool                      // журнал закрыт, последний снимок записан
+}
+
+// openJournal - восстановить состояние из снимка и журнала изменений в директории dir и записать новый снимок
+func openJournal(dir string) (*journal, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	j := &journal{dir: dir, rows: make(map[string]map[string]row)}
+	if err := j.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := j.replay(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	j.wal = wal
+
+	// Снимок сразу после восстановления убирает из журнала применённые изменения и недописанную последнюю строку
+	if err := j.snapshot(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// put - записать новое значение строки
+func (j *journal) put(table string, value any, key ...string) error {
+	var changes changeSet
+	changes.put(table, value, key...)
+	return j.commit(&changes)
+}
+
+// remove - удалить строку
+func (j *journal) remove(table string, key ...string) error {
+	var changes changeSet
+	changes.remove(table, key...)
+	return j.commit(&changes)
+}
+
+// changeSet - изменения нескольких строк, которые записываются в журнал вместе (см. commit)
+type changeSet struct {
+	rows []row
+	err  error // первая ошибка кодирования строки
+}
+
+// put - добавить запись нового значения строки
+func (c *changeSet) put(table string, value any, key ...string) {
+	if c.err != nil {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.err = fmt.Errorf("failed to encode %s row: %w", table, err)
+		return
+	}
+	c.rows = append(c.rows, row{Table: table, Key: key, Value: data})
+}
+
+// remove - добавить удаление строки
+func (c *changeSet) remove(table string, key ...string) {
+	c.rows = append(c.rows, row{Table: table, Key: key})
+}
+
+// commit - записать изменения одной строкой журнала: после сбоя восстанавливаются все они или ни одно
+func (j *journal) commit(changes *changeSet) error {
+	if j == nil || len(changes.rows) == 0 {
+		return nil
+	}
+	if changes.err != nil {
+		return changes.err
+	}
+
+	return j.write(changes.rows...)
+}
+
+// write - дописать изменения в журнал одной строкой, сбросить журнал на диск и применить изменения к состоянию строк.
+// Изменения подтверждаются вызывающему только после fsync, поэтому сбой ОС не теряет подтверждённые изменения,
+// а несколько изменений одной строки восстанавливаются все или ни одно.
+// При ошибке изменения не применяются, и вызывающий не должен менять свои данные
+func (j *journal) write(changes ...row) error {
+	var line []byte
+	var err error
+	if len(changes) == 1 {
+		line, err = json.Marshal(changes[0])
+	} else {
+		line, err = json.Marshal(changes)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode %s change: %w", changes[0].Table, err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.err != nil {
+		return j.err
+	}
+
+	if _, err := j.wal.Write(append(line, '\n')); err != nil {
+		return j.discard(fmt.Errorf("failed to append to write-ahead log: %w", err))
+	}
+	if err := j.wal.Sync(); err != nil {
+		return j.discard(fmt.Errorf("failed to sync write-ahead log: %w", err))
+	}
+
+	j.size += int64(len(line)) + 1
+	for _, change := range changes {
+		j.apply(change)
+	}
+	return nil
+}
+
+// discard - отрезать от журнала недописанное изменение, чтобы оно не применилось при восстановлении.
+// Если отрезать не удалось, журнал не принимает изменений до следующего снимка
+func (j *journal) discard(err error) error {
+	if truncErr := j.wal.Truncate(j.size); truncErr != nil {
+		j.err = fmt.Errorf("write-ahead log is inconsistent until the next snapshot: %w", errors.Join(err, truncErr))
+	}
+	return err
+}
+
+// apply - применить изменение к состоянию строк
+func (j *journal) apply(change row) {
+	key := strings.Join(change.Key, "\x00")
+	if change.Value == nil {
+		delete(j.rows[change.Table], key)
+		return
+	}
+
+	table, exists := j.rows[change.Table]
+	if !exists {
+		table = make(map[string]row)
+		j.rows[change.Table] = table
+	}
+	table[key] = change
+}
+
+// table - строки таблицы (для восстановления репозиториев)
+func (j *journal) table(name string) map[string]row {
+	return j.rows[name]
+}
+
+// snapshot - атомарно записать снимок состояния (через временный файл) и очистить журнал изменений
+func (j *journal) snapshot() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return nil
+	}
+
+	// Строки упорядочены, чтобы снимок одного и того же состояния не менялся
+	rows := make([]row, 0)
+	for _, table := range j.rows {
+		for _, r := range table {
+			rows = append(rows, r)
+		}
+	}
+	sort.Slice(rows, func(a, b int) bool {
+		if rows[a].Table != rows[b].Table {
+			return rows[a].Table < rows[b].Table
+		}
+		return strings.Join(rows[a].Key, "\x00") < strings.Join(rows[b].Key, "\x00")
+	})
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	path := filepath.Join(j.dir, snapshotFile)
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	// Если процесс прервётся до очистки, повторное применение журнала к новому снимку даст то же состояние
+	if err := j.wal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+	}
+
+	j.size = 0
+	j.err = nil
+	return nil
+}
+
+// lastError - ошибка, из-за которой журнал не принимает изменений до следующего снимка (nil - журнал исправен)
+func (j *journal) lastError() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// close - записать снимок и закрыть журнал изменений
+func (j *journal) close() error {
+	if j == nil {
+		return nil
+	}
+
+	err := j.snapshot()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.closed = true
+	return errors.Join(err, j.wal.Close())
+}
+
+// loadSnapshot - прочитать снимок состояния (если он есть)
+func (j *journal) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(j.dir, snapshotFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var rows []row
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	for _, r := range rows {
+		j.apply(r)
+	}
+	return nil
+}
+
+// replay - применить изменения из журнала. Строка без перевода строки в конце файла не была дописана
+// до остановки процесса и пропускается
+func (j *journal) replay() error {
+	file, err := os.Open(filepath.Join(j.dir, walFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read write-ahead log: %w", err)
+		}
+
+		// Изменения, записанные вместе, хранятся в строке массивом
+		var changes []row
+		if bytes.HasPrefix(line, []byte("[")) {
+			err = json.Unmarshal(line, &changes)
+		} else {
+			changes = make([]row, 1)
+			err = json.Unmarshal(line, &changes[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode write-ahead log line %d: %w", lineNumber, err)
+		}
+		for _, change := range changes {
+			j.apply(change)
+		}
+	}
+}
+
+// writeFileAtomic - записать файл целиком: данные пишутся во временный файл, который затем заменяет прежний
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Переименование сохраняется на диске вместе с директорией
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,204 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crash - закрыть журнал изменений без снимка, как при аварийной остановке процесса
+func crash(t *testing.T, manager *DatabaseManager) {
+	require.NoError(t, manager.journal.wal.Close())
+}
+
+func TestOpenDatabaseManager(t *testing.T) {
+	dir := t.TempDir()
+	alice := customcontext.WithUserID(context.Background(), "alice")
+	bob := customcontext.WithUserID(context.Background(), "bob")
+
+	manager, err := OpenDatabaseManager(dir)
+	require.NoError(t, err)
+
+	for _, login := range []string{"alice", "bob"} {
+		_, err := manager.Users.Create(context.Background(), &dtos.NewUser{Login: login, Password: "hash"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, manager.Accounts.SetQuota(context.Background(), "bob", &entities.Quota{MaxEntries: 5}))
+	_, err = manager.DataKeys.Create(context.Background(), &entities.DataKey{Login: "alice", WrappedKey: []byte("wrapped")})
+	require.NoError(t, err)
+
+	card, err := manager.Cards.Create(alice, &dtos.NewCardInformation{Number: "4111", NewSecureEntity: dtos.NewSecureEntity{ID: "card-1"}})
+	require.NoError(t, err)
+	card.EntryKey = "alice-key"
+	_, err = manager.Cards.Update(alice, card)
+	require.NoError(t, err)
+	require.NoError(t, manager.History.Append(alice, "card", card.ID, 10, time.Time{}, func(context.Context) error { return nil }))
+
+	grant, err := manager.Shares.Create(alice, &dtos.NewShareGrant{EntityType: "card", EntityID: card.ID, RecipientID: "bob", Permission: entities.PermissionRead, EntryKey: "bob-key"})
+	require.NoError(t, err)
+	_, err = manager.Shares.Accept(bob, grant.ID)
+	require.NoError(t, err)
+
+	// Снимок в середине работы: дальнейшие изменения восстанавливаются из журнала поверх него
+	require.NoError(t, manager.Snapshot())
+
+	org, err := manager.Orgs.Create(alice, &dtos.NewOrganization{Name: "team"})
+	require.NoError(t, err)
+	collection, err := manager.Orgs.CreateCollection(alice, &dtos.NewCollection{OrgID: org.ID, Name: "shared",
+		Keys: []entities.CollectionKey{{Login: "alice", EncryptedKey: "collection-key"}}})
+	require.NoError(t, err)
+
+	text, err := manager.Texts.Create(bob, &dtos.NewTextData{Data: "note", NewSecureEntity: dtos.NewSecureEntity{ID: "text-1"}})
+	require.NoError(t, err)
+	_, err = manager.Texts.Delete(bob, text.ID)
+	require.NoError(t, err)
+
+	_, err = manager.DataKeys.Update(context.Background(), &entities.DataKey{Login: "alice", WrappedKey: []byte("rewrapped")})
+	require.NoError(t, err)
+
+	for _, action := range []string{"create", "update"} {
+		require.NoError(t, manager.Audit.Append(context.Background(), &entities.AuditEvent{UserID: "alice", Action: action}))
+	}
+
+	crash(t, manager)
+
+	restored, err := OpenDatabaseManager(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { restored.Close() })
+
+	t.Run("Пользователи и квоты", func(t *testing.T) {
+		users, err := restored.Users.GetAll(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+
+		quota, err := restored.Accounts.GetQuota(context.Background(), "bob")
+		require.NoError(t, err)
+		require.NotNil(t, quota)
+		assert.Equal(t, 5, quota.MaxEntries)
+	})
+
+	t.Run("Записи, права и история", func(t *testing.T) {
+		shared, err := restored.Cards.Get(bob, card.ID)
+		require.NoError(t, err)
+		require.NotNil(t, shared, "принятое приглашение восстановлено")
+		assert.Equal(t, "bob-key", shared.EntryKey)
+		assert.Equal(t, "4111", shared.Number)
+
+		revisions, err := restored.History.GetAll(alice, "card", card.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "alice-key", revisions[0].EntryKey)
+	})
+
+	t.Run("Изменения после снимка", func(t *testing.T) {
+		collections, err := restored.Orgs.GetCollections(alice, org.ID)
+		require.NoError(t, err)
+		require.Len(t, collections, 1)
+		assert.Equal(t, "collection-key", collections[0].EncryptedKey)
+
+		trash, err := restored.Trash.GetAll(bob)
+		require.NoError(t, err)
+		require.Len(t, trash, 1)
+		assert.Equal(t, text.ID, trash[0].ID)
+
+		events, err := restored.Audit.Query(context.Background(), &dtos.AuditFilter{UserID: "alice"})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, []string{"create", "update"}, []string{events[0].Action, events[1].Action})
+
+		key, err := restored.DataKeys.Get(context.Background(), "alice")
+		require.NoError(t, err)
+		require.NotNil(t, key)
+		assert.Equal(t, []byte("rewrapped"), key.WrappedKey)
+	})
+
+	t.Run("Идентификаторы продолжают восстановленные", func(t *testing.T) {
+		next, err := restored.Orgs.CreateCollection(alice, &dtos.NewCollection{OrgID: org.ID, Name: "other"})
+		require.NoError(t, err)
+		assert.NotEqual(t, collection.ID, next.ID)
+		assert.NotEqual(t, org.ID, next.ID)
+
+		event := entities.AuditEvent{UserID: "alice", Action: "delete"}
+		require.NoError(t, restored.Audit.Append(context.Background(), &event))
+		assert.Equal(t, "3", event.ID)
+	})
+}
+
+func TestOpenDatabaseManager_TornWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	manager, err := OpenDatabaseManager(dir)
+	require.NoError(t, err)
+	_, err = manager.Users.Create(context.Background(), &dtos.NewUser{Login: "alice", Password: "hash"})
+	require.NoError(t, err)
+	crash(t, manager)
+
+	// Последняя строка журнала не была дописана до остановки процесса
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = wal.WriteString(`{"table":"users","key":["bob"],"val`)
+	require.NoError(t, err)
+	require.NoError(t, wal.Close())
+
+	restored, err := OpenDatabaseManager(dir)
+	require.NoError(t, err)
+
+	users, err := restored.Users.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "alice", users[0].Login)
+
+	require.NoError(t, restored.Close())
+	info, err := os.Stat(filepath.Join(dir, walFile))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "после снимка журнал изменений пуст")
+}
+
+func TestOpenDatabaseManager_WriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	manager, err := OpenDatabaseManager(dir)
+	require.NoError(t, err)
+	_, err = manager.Users.Create(ctx, &dtos.NewUser{Login: "alice", Password: "hash"})
+	require.NoError(t, err)
+
+	// Журнал, открытый только для чтения, не принимает ни изменения, ни отката недописанной строки
+	wal := manager.journal.wal
+	readOnly, err := os.Open(filepath.Join(dir, walFile))
+	require.NoError(t, err)
+	defer readOnly.Close()
+	manager.journal.wal = readOnly
+
+	_, err = manager.Users.Create(ctx, &dtos.NewUser{Login: "bob", Password: "hash"})
+	assert.Error(t, err)
+	assert.Error(t, manager.Ping(ctx))
+
+	users, err := manager.Users.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 1, "изменение, не попавшее в журнал, не применяется")
+
+	// После снимка журнал снова принимает изменения
+	manager.journal.wal = wal
+	require.NoError(t, manager.Snapshot())
+	require.NoError(t, manager.Ping(ctx))
+	_, err = manager.Users.Create(ctx, &dtos.NewUser{Login: "bob", Password: "hash"})
+	require.NoError(t, err)
+	crash(t, manager)
+
+	restored, err := OpenDatabaseManager(dir)
+	require.NoError(t, err)
+	users, err = restored.Users.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	require.NoError(t, restored.Close())
+}
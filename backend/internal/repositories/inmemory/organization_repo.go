@@ -0,0 +1,492 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// collectionKeyID - ключ коллекции версии version для участника login
+type collectionKeyID struct {
+	collectionID string
+	login        string
+	version      int
+}
+
+// journalKey - ключ строки в таблице журнала
+func (id collectionKeyID) journalKey() []string {
+	return []string{id.collectionID, id.login, strconv.Itoa(id.version)}
+}
+
+// InMemoryOrganizationRepo - организации, их участники и коллекции в памяти
+type InMemoryOrganizationRepo struct {
+	mu          *sync.Mutex
+	orgs        map[string]entities.Organization
+	members     map[string]map[string]entities.OrgMember // участники по ИД организации и логину
+	collections map[string]entities.Collection
+	keys        map[collectionKeyID]string
+	idSeq       int64
+	journal     *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryOrganizationRepo - инициализация репозитория организаций
+func NewInMemoryOrganizationRepo() *InMemoryOrganizationRepo {
+	return &InMemoryOrganizationRepo{
+		mu:          &sync.Mutex{},
+		orgs:        make(map[string]entities.Organization),
+		members:     make(map[string]map[string]entities.OrgMember),
+		collections: make(map[string]entities.Collection),
+		keys:        make(map[collectionKeyID]string),
+	}
+}
+
+// generateID - генерация уникального ID
+func (r *InMemoryOrganizationRepo) generateID() string {
+	r.idSeq++
+	return fmt.Sprintf("%d", r.idSeq)
+}
+
+// Create - создать организацию, текущий пользователь становится её владельцем
+func (r *InMemoryOrganizationRepo) Create(ctx context.Context, dto *dtos.NewOrganization) (*entities.Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dto == nil {
+		return nil, errors.New("dto cannot be nil")
+	}
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	org := entities.Organization{
+		ID:        r.generateID(),
+		Name:      dto.Name,
+		OwnerID:   userID,
+		CreatedAt: time.Now(),
+	}
+	owner := entities.OrgMember{OrgID: org.ID, Login: userID, Role: entities.RoleOwner, Status: entities.MemberStatusActive, CreatedAt: org.CreatedAt}
+	var changes changeSet
+	changes.put(tableOrgs, org, org.ID)
+	changes.put(tableOrgMembers, owner, org.ID, userID)
+	if err := r.journal.commit(&changes); err != nil {
+		return nil, err
+	}
+	r.orgs[org.ID] = org
+	r.members[org.ID] = map[string]entities.OrgMember{userID: owner}
+
+	org.Role = entities.RoleOwner
+	org.Status = entities.MemberStatusActive
+	return &org, nil
+}
+
+// GetAll - получить организации, в которых состоит или куда приглашён текущий пользователь (в порядке создания)
+func (r *InMemoryOrganizationRepo) GetAll(ctx context.Context) ([]entities.Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	var orgs []entities.Organization
+	for id, org := range r.orgs {
+		if member, ok := r.members[id][userID]; ok {
+			org.Role = member.Role
+			org.Status = member.Status
+			orgs = append(orgs, org)
+		}
+	}
+
+	sort.Slice(orgs, func(i, j int) bool {
+		return orgs[i].CreatedAt.Before(orgs[j].CreatedAt)
+	})
+
+	return orgs, nil
+}
+
+// GetMember - получить участника организации
+func (r *InMemoryOrganizationRepo) GetMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, ok := r.members[orgID][login]
+	if !ok {
+		return nil, nil
+	}
+
+	return &member, nil
+}
+
+// GetMembers - получить участников организации (в порядке приглашения)
+func (r *InMemoryOrganizationRepo) GetMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var members []entities.OrgMember
+	for _, member := range r.members[orgID] {
+		members = append(members, member)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].CreatedAt.Before(members[j].CreatedAt)
+	})
+
+	return members, nil
+}
+
+// AddMember - пригласить пользователя и сохранить для него ключи коллекций
+func (r *InMemoryOrganizationRepo) AddMember(ctx context.Context, dto *dtos.NewOrgMember) (*entities.OrgMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dto == nil {
+		return nil, errors.New("dto cannot be nil")
+	}
+
+	members, exists := r.members[dto.OrgID]
+	if !exists {
+		return nil, fmt.Errorf("organization %s not found", dto.OrgID)
+	}
+
+	if _, isMember := members[dto.Login]; isMember {
+		return nil, nil
+	}
+
+	member := entities.OrgMember{
+		OrgID:     dto.OrgID,
+		Login:     dto.Login,
+		Role:      dto.Role,
+		Status:    entities.MemberStatusInvited,
+		CreatedAt: time.Now(),
+	}
+	var changes changeSet
+	changes.put(tableOrgMembers, member, dto.OrgID, dto.Login)
+	for _, key := range dto.Keys {
+		changes.put(tableCollectionKeys, key.EncryptedKey, collectionKeyID{collectionID: key.CollectionID, login: dto.Login, version: key.Version}.journalKey()...)
+	}
+	if err := r.journal.commit(&changes); err != nil {
+		return nil, err
+	}
+
+	members[dto.Login] = member
+	for _, key := range dto.Keys {
+		r.keys[collectionKeyID{collectionID: key.CollectionID, login: dto.Login, version: key.Version}] = key.EncryptedKey
+	}
+
+	return &member, nil
+}
+
+// ActivateMember - принять приглашение в организацию
+func (r *InMemoryOrganizationRepo) ActivateMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, ok := r.members[orgID][login]
+	if !ok || member.Status != entities.MemberStatusInvited {
+		return nil, nil
+	}
+
+	member.Status = entities.MemberStatusActive
+	if err := r.journal.put(tableOrgMembers, member, orgID, login); err != nil {
+		return nil, err
+	}
+	r.members[orgID][login] = member
+	return &member, nil
+}
+
+// RemoveMember - исключить участника: удалить его ключи коллекций и отметить коллекции организации для замены ключа
+func (r *InMemoryOrganizationRepo) RemoveMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.removeMember(orgID, login)
+}
+
+// removeMember - исключить участника (без блокировки)
+func (r *InMemoryOrganizationRepo) removeMember(orgID, login string) (*entities.OrgMember, error) {
+	member, ok := r.members[orgID][login]
+	if !ok {
+		return nil, nil
+	}
+
+	var changes changeSet
+	changes.remove(tableOrgMembers, orgID, login)
+
+	var rotated []entities.Collection
+	var removedKeys []collectionKeyID
+	for id, collection := range r.collections {
+		if collection.OrgID != orgID {
+			continue
+		}
+
+		collection.RotationRequired = true
+		changes.put(tableCollections, collection, id)
+		rotated = append(rotated, collection)
+
+		for key := range r.keys {
+			if key.collectionID == id && key.login == login {
+				changes.remove(tableCollectionKeys, key.journalKey()...)
+				removedKeys = append(removedKeys, key)
+			}
+		}
+	}
+
+	if err := r.journal.commit(&changes); err != nil {
+		return nil, err
+	}
+
+	delete(r.members[orgID], login)
+	for _, collection := range rotated {
+		r.collections[collection.ID] = collection
+	}
+	for _, key := range removedKeys {
+		delete(r.keys, key)
+	}
+
+	return &member, nil
+}
+
+// CreateCollection - создать коллекцию с ключом версии 1, зашифрованным для каждого участника (nil, если имя занято)
+func (r *InMemoryOrganizationRepo) CreateCollection(ctx context.Context, dto *dtos.NewCollection) (*entities.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dto == nil {
+		return nil, errors.New("dto cannot be nil")
+	}
+
+	for _, existing := range r.collections {
+		if existing.OrgID == dto.OrgID && existing.Name == dto.Name {
+			return nil, nil
+		}
+	}
+
+	collection := entities.Collection{
+		ID:         r.generateID(),
+		OrgID:      dto.OrgID,
+		Name:       dto.Name,
+		KeyVersion: 1,
+		CreatedAt:  time.Now(),
+	}
+	var changes changeSet
+	changes.put(tableCollections, collection, collection.ID)
+	putKeys(&changes, collection.ID, collection.KeyVersion, dto.Keys)
+	if err := r.journal.commit(&changes); err != nil {
+		return nil, err
+	}
+	r.collections[collection.ID] = collection
+	r.storeKeys(collection.ID, collection.KeyVersion, dto.Keys)
+
+	return r.withUserKey(ctx, collection), nil
+}
+
+// GetCollections - получить коллекции организации (в порядке создания)
+func (r *InMemoryOrganizationRepo) GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var collections []entities.Collection
+	for _, collection := range r.collections {
+		if collection.OrgID == orgID {
+			collections = append(collections, *r.withUserKey(ctx, collection))
+		}
+	}
+
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].CreatedAt.Before(collections[j].CreatedAt)
+	})
+
+	return collections, nil
+}
+
+// GetCollection - получить коллекцию по ИД
+func (r *InMemoryOrganizationRepo) GetCollection(ctx context.Context, id string) (*entities.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	collection, exists := r.collections[id]
+	if !exists {
+		return nil, nil
+	}
+
+	return r.withUserKey(ctx, collection), nil
+}
+
+// RotateCollectionKey - заменить ключ коллекции следующей версией и снять отметку о необходимости замены
+func (r *InMemoryOrganizationRepo) RotateCollectionKey(ctx context.Context, dto *dtos.CollectionRotation) (*entities.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dto == nil {
+		return nil, errors.New("dto cannot be nil")
+	}
+
+	collection, exists := r.collections[dto.CollectionID]
+	if !exists || dto.Version != collection.KeyVersion+1 {
+		return nil, nil
+	}
+
+	collection.KeyVersion = dto.Version
+	collection.RotationRequired = false
+	var changes changeSet
+	changes.put(tableCollections, collection, collection.ID)
+	putKeys(&changes, collection.ID, collection.KeyVersion, dto.Keys)
+	if err := r.journal.commit(&changes); err != nil {
+		return nil, err
+	}
+	r.collections[collection.ID] = collection
+	r.storeKeys(collection.ID, collection.KeyVersion, dto.Keys)
+
+	return r.withUserKey(ctx, collection), nil
+}
+
+// putKeys - добавить к изменениям ключ коллекции указанной версии для участников
+func putKeys(changes *changeSet, collectionID string, version int, keys []entities.CollectionKey) {
+	for _, key := range keys {
+		changes.put(tableCollectionKeys, key.EncryptedKey, collectionKeyID{collectionID: collectionID, login: key.Login, version: version}.journalKey()...)
+	}
+}
+
+// storeKeys - сохранить ключ коллекции указанной версии для участников (после записи в журнал, см. putKeys)
+func (r *InMemoryOrganizationRepo) storeKeys(collectionID string, version int, keys []entities.CollectionKey) {
+	for _, key := range keys {
+		r.keys[collectionKeyID{collectionID: collectionID, login: key.Login, version: version}] = key.EncryptedKey
+	}
+}
+
+// withUserKey - коллекция с текущим ключом, зашифрованным для текущего пользователя
+func (r *InMemoryOrganizationRepo) withUserKey(ctx context.Context, collection entities.Collection) *entities.Collection {
+	collection.EncryptedKey = r.keys[collectionKeyID{collectionID: collection.ID, login: customcontext.GetUserID(ctx), version: collection.KeyVersion}]
+	return &collection
+}
+
+// memberKey - роль активного участника в организации коллекции и ключ коллекции указанной версии, зашифрованный для него.
+// ok=false - пользователь не участник или ключа этой версии у него нет
+func (r *InMemoryOrganizationRepo) memberKey(collectionID, login string, version int) (role, key string, ok bool) {
+	if r == nil {
+		return "", "", false
+	}
+
+	role = r.activeRole(collectionID, login)
+	key, hasKey := r.keys[collectionKeyID{collectionID: collectionID, login: login, version: version}]
+	return role, key, role != "" && hasKey
+}
+
+// activeRole - роль активного участника в организации коллекции (пусто - не участник)
+func (r *InMemoryOrganizationRepo) activeRole(collectionID, login string) string {
+	if r == nil {
+		return ""
+	}
+
+	collection, exists := r.collections[collectionID]
+	if !exists {
+		return ""
+	}
+
+	member, ok := r.members[collection.OrgID][login]
+	if !ok || member.Status != entities.MemberStatusActive {
+		return ""
+	}
+
+	return member.Role
+}
+
+// removeUser - удалить организации пользователя и исключить его из остальных организаций (при удалении учётной записи).
+// Возвращает ИД удалённых коллекций: их записи тоже удаляются. Каждая организация удаляется одной записью журнала
+func (r *InMemoryOrganizationRepo) removeUser(login string) (map[string]bool, error) {
+	removedCollections := make(map[string]bool)
+	if r == nil {
+		return removedCollections, nil
+	}
+
+	for orgID, org := range r.orgs {
+		if org.OwnerID != login {
+			if _, isMember := r.members[orgID][login]; isMember {
+				if _, err := r.removeMember(orgID, login); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		var changes changeSet
+		changes.remove(tableOrgs, orgID)
+		for memberLogin := range r.members[orgID] {
+			changes.remove(tableOrgMembers, orgID, memberLogin)
+		}
+		collections := make(map[string]bool)
+		for id, collection := range r.collections {
+			if collection.OrgID == orgID {
+				changes.remove(tableCollections, id)
+				collections[id] = true
+			}
+		}
+		var removedKeys []collectionKeyID
+		for key := range r.keys {
+			if collections[key.collectionID] {
+				changes.remove(tableCollectionKeys, key.journalKey()...)
+				removedKeys = append(removedKeys, key)
+			}
+		}
+		if err := r.journal.commit(&changes); err != nil {
+			return nil, err
+		}
+
+		delete(r.orgs, orgID)
+		delete(r.members, orgID)
+		for id := range collections {
+			delete(r.collections, id)
+			removedCollections[id] = true
+		}
+		for _, key := range removedKeys {
+			delete(r.keys, key)
+		}
+	}
+
+	return removedCollections, nil
+}
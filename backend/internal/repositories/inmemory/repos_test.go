@@ -0,0 +1,35 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/repotest"
+	"github.com/stretchr/testify/require"
+)
+
+// conformanceRepos - проверяемые репозитории менеджера
+func conformanceRepos(manager *DatabaseManager) repotest.Repos {
+	return repotest.Repos{
+		Users:       manager.Users,
+		Binaries:    manager.Binaries,
+		Cards:       manager.Cards,
+		Credentials: manager.Credentials,
+		Texts:       manager.Texts,
+	}
+}
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		return conformanceRepos(NewDatabaseManager())
+	}, repotest.Options{})
+}
+
+func TestConformance_Journal(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		manager, err := OpenDatabaseManager(t.TempDir())
+		require.NoError(t, err)
+		t.Cleanup(func() { manager.Close() })
+		return conformanceRepos(manager)
+	}, repotest.Options{})
+}
@@ -0,0 +1,180 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// restore - заполнить репозитории строками журнала и продолжить счётчики идентификаторов с наибольших восстановленных
+func (m *DatabaseManager) restore(j *journal) error {
+	err := restoreTable(j, tableUsers, 1, func(key []string, user entities.User) {
+		m.Users.storage[key[0]] = user
+	})
+	if err != nil {
+		return err
+	}
+
+	err = restoreTable(j, tableUserKeys, 1, func(key []string, keys entities.UserKeys) {
+		m.UserKeys.storage[key[0]] = keys
+	})
+	if err != nil {
+		return err
+	}
+
+	err = restoreTable(j, tableDataKeys, 1, func(key []string, dataKey entities.DataKey) {
+		m.DataKeys.storage[key[0]] = dataKey
+	})
+	if err != nil {
+		return err
+	}
+
+	err = restoreTable(j, tableQuotas, 1, func(key []string, quota entities.Quota) {
+		m.Accounts.quotas[key[0]] = quota
+	})
+	if err != nil {
+		return err
+	}
+
+	err = restoreTable(j, tableInvites, 1, func(key []string, invite entities.Invite) {
+		m.Invites.storage[key[0]] = invite
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := restoreEntries(j, "binary", m.Binaries.storage); err != nil {
+		return err
+	}
+	if err := restoreEntries(j, "card", m.Cards.storage); err != nil {
+		return err
+	}
+	if err := restoreEntries(j, "credentials", m.Credentials.storage); err != nil {
+		return err
+	}
+	if err := restoreEntries(j, "text", m.Texts.storage); err != nil {
+		return err
+	}
+
+	// Журнал аудита хранится в порядке добавления, который совпадает с порядком идентификаторов
+	err = restoreTable(j, tableAudit, 1, func(key []string, event entities.AuditEvent) {
+		m.Audit.storage = append(m.Audit.storage, event)
+		m.Audit.idSeq = max(m.Audit.idSeq, parseSeq(event.ID))
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(m.Audit.storage, func(a, b int) bool {
+		return parseSeq(m.Audit.storage[a].ID) < parseSeq(m.Audit.storage[b].ID)
+	})
+
+	err = restoreTable(j, tableShares, 1, func(key []string, grant entities.ShareGrant) {
+		m.Shares.storage[key[0]] = grant
+		m.Shares.idSeq = max(m.Shares.idSeq, parseSeq(grant.ID))
+	})
+	if err != nil {
+		return err
+	}
+
+	err = restoreTable(j, tableEmergency, 1, func(key []string, access entities.EmergencyAccess) {
+		m.Emergency.storage[key[0]] = access
+		m.Emergency.idSeq = max(m.Emergency.idSeq, parseSeq(access.ID))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Организации и коллекции получают идентификаторы из общего счётчика
+	err = restoreTable(j, tableOrgs, 1, func(key []string, org entities.Organization) {
+		m.Orgs.orgs[key[0]] = org
+		m.Orgs.idSeq = max(m.Orgs.idSeq, parseSeq(org.ID))
+	})
+	if err != nil {
+		return err
+	}
+
+	err = restoreTable(j, tableOrgMembers, 2, func(key []string, member entities.OrgMember) {
+		members, exists := m.Orgs.members[key[0]]
+		if !exists {
+			members = make(map[string]entities.OrgMember)
+			m.Orgs.members[key[0]] = members
+		}
+		members[key[1]] = member
+	})
+	if err != nil {
+		return err
+	}
+
+	err = restoreTable(j, tableCollections, 1, func(key []string, collection entities.Collection) {
+		m.Orgs.collections[key[0]] = collection
+		m.Orgs.idSeq = max(m.Orgs.idSeq, parseSeq(collection.ID))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range j.table(tableCollectionKeys) {
+		if len(r.Key) != 3 {
+			return fmt.Errorf("invalid %s row key %q", tableCollectionKeys, r.Key)
+		}
+		version, err := strconv.Atoi(r.Key[2])
+		if err != nil {
+			return fmt.Errorf("invalid %s row key %q: %w", tableCollectionKeys, r.Key, err)
+		}
+
+		var encryptedKey string
+		if err := json.Unmarshal(r.Value, &encryptedKey); err != nil {
+			return fmt.Errorf("failed to decode %s row: %w", tableCollectionKeys, err)
+		}
+		m.Orgs.keys[collectionKeyID{collectionID: r.Key[0], login: r.Key[1], version: version}] = encryptedKey
+	}
+
+	err = restoreTable(j, tableTrash, 2, func(key []string, deletedAt time.Time) {
+		m.Trash.deleted[entryRef{entityType: key[0], id: key[1]}] = deletedAt
+	})
+	if err != nil {
+		return err
+	}
+
+	return restoreTable(j, tableHistory, 2, func(key []string, revisions []entities.Revision) {
+		m.History.revisions[entryRef{entityType: key[0], id: key[1]}] = revisions
+	})
+}
+
+// restoreTable - прочитать строки таблицы с ключом из keyLen частей
+func restoreTable[T any](j *journal, table string, keyLen int, set func(key []string, value T)) error {
+	for _, r := range j.table(table) {
+		if len(r.Key) != keyLen {
+			return fmt.Errorf("invalid %s row key %q", table, r.Key)
+		}
+
+		var value T
+		if err := json.Unmarshal(r.Value, &value); err != nil {
+			return fmt.Errorf("failed to decode %s row: %w", table, err)
+		}
+		set(r.Key, value)
+	}
+
+	return nil
+}
+
+// restoreEntries - прочитать записи указанного типа
+func restoreEntries[T any](j *journal, entityType string, storage map[string]T) error {
+	return restoreTable(j, entityType, 1, func(key []string, entry T) {
+		storage[key[0]] = entry
+	})
+}
+
+// parseSeq - числовое значение идентификатора из счётчика (0 для идентификаторов другого вида)
+func parseSeq(id string) int64 {
+	seq, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return seq
+}
@@ -0,0 +1,267 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// sharableEntries - репозиторий записей, которыми можно поделиться
+type sharableEntries interface {
+	// entry - общие поля записи по ИД
+	entry(id string) (entities.SecureEntity, bool)
+}
+
+// InMemoryShareRepo - права пользователей на чужие записи в памяти
+type InMemoryShareRepo struct {
+	mu      *sync.Mutex
+	storage map[string]entities.ShareGrant
+	idSeq   int64
+	entries map[string]sharableEntries // репозитории записей по типу сущности
+	orgs    *InMemoryOrganizationRepo  // организации, через коллекции которых доступны записи (nil - организаций нет)
+	trash   *InMemoryTrashRepo         // корзина: записями из неё не делятся
+	journal *journal                   // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryShareRepo - инициализация репозитория прав
+func NewInMemoryShareRepo() *InMemoryShareRepo {
+	return &InMemoryShareRepo{
+		mu:      &sync.Mutex{},
+		storage: make(map[string]entities.ShareGrant),
+		entries: make(map[string]sharableEntries),
+	}
+}
+
+// register - подключить репозиторий записей указанного типа
+func (r *InMemoryShareRepo) register(entityType string, entries sharableEntries) {
+	r.entries[entityType] = entries
+}
+
+// generateID - генерация уникального ID
+func (r *InMemoryShareRepo) generateID() string {
+	r.idSeq++
+	return fmt.Sprintf("%d", r.idSeq)
+}
+
+// Create - поделиться записью от имени её владельца (право проверяется политикой доступа до вызова).
+// Повторная выдача права тому же получателю заменяет права и ключ
+func (r *InMemoryShareRepo) Create(ctx context.Context, dto *dtos.NewShareGrant) (*entities.ShareGrant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if dto == nil {
+		return nil, errors.New("dto cannot be nil")
+	}
+
+	repo, known := r.entries[dto.EntityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", dto.EntityType)
+	}
+
+	// Делиться можно только личной записью не из корзины, уже переведённой на ключ записи
+	entry, exists := repo.entry(dto.EntityID)
+	if !exists || entry.CollectionID != "" || entry.EntryKey == "" || r.trash.contains(dto.EntityType, dto.EntityID) {
+		return nil, nil
+	}
+
+	if existing := r.find(dto.EntityType, dto.EntityID, dto.RecipientID); existing != nil {
+		existing.Permission = dto.Permission
+		existing.EntryKey = dto.EntryKey
+		if err := r.journal.put(tableShares, existing, existing.ID); err != nil {
+			return nil, err
+		}
+		r.storage[existing.ID] = *existing
+		return existing, nil
+	}
+
+	grant := entities.ShareGrant{
+		ID:          r.generateID(),
+		EntityType:  dto.EntityType,
+		EntityID:    dto.EntityID,
+		OwnerID:     entry.OwnerID,
+		RecipientID: dto.RecipientID,
+		Permission:  dto.Permission,
+		Status:      entities.ShareStatusPending,
+		EntryKey:    dto.EntryKey,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := r.journal.put(tableShares, grant, grant.ID); err != nil {
+		return nil, err
+	}
+	r.storage[grant.ID] = grant
+	return &grant, nil
+}
+
+// GetAll - получить права, выданные текущим пользователем и выданные ему (в порядке создания)
+func (r *InMemoryShareRepo) GetAll(ctx context.Context) ([]entities.ShareGrant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	var grants []entities.ShareGrant
+	for _, grant := range r.storage {
+		if grant.OwnerID == userID || grant.RecipientID == userID {
+			grants = append(grants, grant)
+		}
+	}
+
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].CreatedAt.Before(grants[j].CreatedAt)
+	})
+
+	return grants, nil
+}
+
+// Accept - принять приглашение, адресованное текущему пользователю
+func (r *InMemoryShareRepo) Accept(ctx context.Context, id string) (*entities.ShareGrant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	grant, exists := r.storage[id]
+	if !exists || grant.RecipientID != userID {
+		return nil, nil
+	}
+
+	grant.Status = entities.ShareStatusAccepted
+	if err := r.journal.put(tableShares, grant, id); err != nil {
+		return nil, err
+	}
+	r.storage[id] = grant
+	return &grant, nil
+}
+
+// Delete - отозвать право (владелец) или отказаться от него (получатель)
+func (r *InMemoryShareRepo) Delete(ctx context.Context, id string) (*entities.ShareGrant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	grant, exists := r.storage[id]
+	if !exists || (grant.OwnerID != userID && grant.RecipientID != userID) {
+		return nil, nil
+	}
+
+	if err := r.journal.remove(tableShares, id); err != nil {
+		return nil, err
+	}
+	delete(r.storage, id)
+	return &grant, nil
+}
+
+// find - право получателя на запись
+func (r *InMemoryShareRepo) find(entityType, entityID, recipientID string) *entities.ShareGrant {
+	for _, grant := range r.storage {
+		if grant.EntityType == entityType && grant.EntityID == entityID && grant.RecipientID == recipientID {
+			return &grant
+		}
+	}
+
+	return nil
+}
+
+// view - представление записи для пользователя: запись коллекции - с ключом коллекции и правами по роли в организации,
+// собственная личная запись - как есть, чужая - с ключом записи и правами из принятого приглашения. ok=false - доступа к записи нет
+func (r *InMemoryShareRepo) view(entityType string, entry entities.SecureEntity, userID string) (entities.SecureEntity, bool) {
+	if entry.CollectionID != "" {
+		if r == nil {
+			return entry, false
+		}
+
+		role, key, ok := r.orgs.memberKey(entry.CollectionID, userID, entry.KeyVersion)
+		if !ok {
+			return entry, false
+		}
+
+		entry.EntryKey = key
+		entry.Permission = entities.PermissionWrite
+		if role == entities.RoleReadOnly {
+			entry.Permission = entities.PermissionRead
+		}
+		return entry, true
+	}
+
+	if entry.OwnerID == userID {
+		return entry, true
+	}
+
+	if r == nil {
+		return entry, false
+	}
+
+	grant := r.find(entityType, entry.ID, userID)
+	if grant == nil || grant.Status != entities.ShareStatusAccepted {
+		return entry, false
+	}
+
+	entry.EntryKey = grant.EntryKey
+	entry.Permission = grant.Permission
+	return entry, true
+}
+
+// revokeAll - удалить все права на запись (при её удалении)
+func (r *InMemoryShareRepo) revokeAll(entityType, entityID string) error {
+	if r == nil {
+		return nil
+	}
+
+	for id, grant := range r.storage {
+		if grant.EntityType == entityType && grant.EntityID == entityID {
+			if err := r.journal.remove(tableShares, id); err != nil {
+				return err
+			}
+			delete(r.storage, id)
+		}
+	}
+	return nil
+}
+
+// removeUser - удалить права, выданные пользователем и выданные ему (при удалении учётной записи)
+func (r *InMemoryShareRepo) removeUser(login string) error {
+	if r == nil {
+		return nil
+	}
+
+	for id, grant := range r.storage {
+		if grant.OwnerID == login || grant.RecipientID == login {
+			if err := r.journal.remove(tableShares, id); err != nil {
+				return err
+			}
+			delete(r.storage, id)
+		}
+	}
+	return nil
+}
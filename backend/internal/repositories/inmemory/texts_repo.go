@@ -3,8 +3,11 @@ package inmemory
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
@@ -13,25 +16,29 @@ import (
 
 // InMemoryTextsRepo - репозиторий текстовых данных в памяти
 type InMemoryTextsRepo struct {
+	mu      *sync.Mutex
 	storage map[string]entities.TextData
-	idSeq   int64
+	shares  *InMemoryShareRepo // права на записи (nil - записями не делятся)
+	trash   *InMemoryTrashRepo // корзина (nil - записи удаляются сразу)
+	journal *journal           // журнал изменений (nil - состояние не сохраняется)
 }
 
 // NewInMemoryTextsRepo - инициализация репозитория текстовых данных
 func NewInMemoryTextsRepo() *InMemoryTextsRepo {
 	return &InMemoryTextsRepo{
+		mu:      &sync.Mutex{},
 		storage: make(map[string]entities.TextData),
 	}
 }
 
-// generateID - генерация уникального ID
-func (r *InMemoryTextsRepo) generateID() string {
-	r.idSeq++
-	return fmt.Sprintf("%d", r.idSeq)
-}
-
-// GetAll - получить все сущности (при наличии прав у текущего пользователя)
+// GetAll - получить все сущности (собственные и те, которыми поделились с текущим пользователем)
 func (r *InMemoryTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
@@ -39,7 +46,11 @@ func (r *InMemoryTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, er
 
 	var texts []entities.TextData
 	for _, text := range r.storage {
-		if text.OwnerID == userID {
+		if r.trash.contains("text", text.ID) {
+			continue
+		}
+		if entry, ok := r.shares.view("text", text.SecureEntity, userID); ok {
+			text.SecureEntity = entry
 			texts = append(texts, text)
 		}
 	}
@@ -49,25 +60,39 @@ func (r *InMemoryTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, er
 
 // Get - получить сущность по ИД (при наличии прав у текущего пользователя)
 func (r *InMemoryTextsRepo) Get(ctx context.Context, id string) (*entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
 
 	text, exists := r.storage[id]
-	if !exists {
+	if !exists || r.trash.contains("text", id) {
 		return nil, nil
 	}
 
-	if text.OwnerID != userID {
+	entry, ok := r.shares.view("text", text.SecureEntity, userID)
+	if !ok {
 		return nil, nil
 	}
 
+	text.SecureEntity = entry
 	return &text, nil
 }
 
 // Create - создать сущность
 func (r *InMemoryTextsRepo) Create(ctx context.Context, dto *dtos.NewTextData) (*entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if dto == nil {
 		return nil, errors.New("dto cannot be nil")
 	}
@@ -77,18 +102,34 @@ func (r *InMemoryTextsRepo) Create(ctx context.Context, dto *dtos.NewTextData) (
 		return nil, errors.New("user ID is required")
 	}
 
-	id := r.generateID()
+	id := dto.ID
+	if _, exists := r.storage[id]; exists {
+		return nil, fmt.Errorf("text with ID %s already exists", id)
+	}
+
 	text := entities.TextData{
 		Data:         dto.Data,
-		SecureEntity: entities.SecureEntity{ID: id, Metadata: dto.Metadata, OwnerID: userID},
+		SecureEntity: entities.SecureEntity{ID: id, Metadata: dto.Metadata, OwnerID: userID, CollectionID: dto.CollectionID, KeyVersion: dto.KeyVersion},
 	}
 
+	if err := r.journal.put("text", text, id); err != nil {
+		return nil, err
+	}
 	r.storage[id] = text
+
+	// Запись коллекции возвращается с ключом коллекции, зашифрованным для создателя
+	text.SecureEntity, _ = r.shares.view("text", text.SecureEntity, userID)
 	return &text, nil
 }
 
-// Update - изменить сущность
+// Update - изменить сущность (права проверяются политикой доступа до вызова)
 func (r *InMemoryTextsRepo) Update(ctx context.Context, entity *entities.TextData) (*entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -98,34 +139,87 @@ func (r *InMemoryTextsRepo) Update(ctx context.Context, entity *entities.TextDat
 		return nil, errors.New("user ID is required")
 	}
 
-	// Проверяем существование и права
 	existing, exists := r.storage[entity.ID]
-	if !exists {
+	if !exists || r.trash.contains("text", entity.ID) {
 		return nil, nil
 	}
 
-	if existing.OwnerID != userID {
-		return nil, nil
+	updated := *entity
+	updated.OwnerID = existing.OwnerID
+	updated.CollectionID = existing.CollectionID
+	updated.Permission = ""
+	// Пустой ключ записи оставляет прежний; у записей коллекций ключ не хранится, но меняется версия ключа коллекции
+	if updated.EntryKey == "" || existing.CollectionID != "" {
+		updated.EntryKey = existing.EntryKey
 	}
+	if existing.CollectionID == "" {
+		updated.KeyVersion = existing.KeyVersion
+	}
+	if err := r.journal.put("text", updated, entity.ID); err != nil {
+		return nil, err
+	}
+	r.storage[entity.ID] = updated
 
-	entity.OwnerID = userID
-	r.storage[entity.ID] = *entity
-
-	return entity, nil
+	updated.SecureEntity, _ = r.shares.view("text", updated.SecureEntity, userID)
+	return &updated, nil
 }
 
-// Delete - удалить сущность
+// Delete - переместить сущность в корзину (права проверяются политикой доступа до вызова)
 func (r *InMemoryTextsRepo) Delete(ctx context.Context, id string) (*entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	userID := customcontext.GetUserID(ctx)
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
 
 	text, exists := r.storage[id]
-	if !exists || text.OwnerID != userID {
+	if !exists || r.trash.contains("text", id) {
 		return nil, nil
 	}
 
-	delete(r.storage, id)
+	// Без корзины запись удаляется сразу вместе с правами на неё
+	if r.trash == nil {
+		if err := r.remove(id); err != nil {
+			return nil, err
+		}
+		return &text, nil
+	}
+
+	if err := r.trash.put("text", id, time.Now()); err != nil {
+		return nil, err
+	}
 	return &text, nil
 }
+
+// entry - общие поля записи по ИД (для проверки прав при выдаче доступа)
+func (r *InMemoryTextsRepo) entry(id string) (entities.SecureEntity, bool) {
+	text, exists := r.storage[id]
+	return text.SecureEntity, exists
+}
+
+// content - поля записи своего типа в виде JSON (для сохранения версии в истории)
+func (r *InMemoryTextsRepo) content(id string) (json.RawMessage, bool) {
+	text, exists := r.storage[id]
+	if !exists {
+		return nil, false
+	}
+
+	content, err := json.Marshal(struct {
+		Data string `json:"data"`
+	}{text.Data})
+	return content, err == nil
+}
+
+// remove - окончательно удалить запись вместе с правами на неё
+func (r *InMemoryTextsRepo) remove(id string) error {
+	if err := r.journal.remove("text", id); err != nil {
+		return err
+	}
+	delete(r.storage, id)
+	return r.shares.revokeAll("text", id)
+}
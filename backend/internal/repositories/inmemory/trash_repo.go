@@ -0,0 +1,243 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// trashableEntries - репозиторий записей, которые удаляются через корзину
+type trashableEntries interface {
+	sharableEntries
+	// remove - окончательно удалить запись
+	remove(id string) error
+}
+
+// entryRef - ссылка на запись любого типа (в корзине или в истории)
+type entryRef struct {
+	entityType string
+	id         string
+}
+
+// InMemoryTrashRepo - корзина в памяти. Записи остаются в репозиториях своих типов, корзина хранит только время их удаления
+type InMemoryTrashRepo struct {
+	mu      *sync.Mutex
+	deleted map[entryRef]time.Time
+	entries map[string]trashableEntries // репозитории записей по типу сущности
+	shares  *InMemoryShareRepo
+	orgs    *InMemoryOrganizationRepo
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryTrashRepo - инициализация корзины
+func NewInMemoryTrashRepo(shares *InMemoryShareRepo, orgs *InMemoryOrganizationRepo) *InMemoryTrashRepo {
+	return &InMemoryTrashRepo{
+		mu:      &sync.Mutex{},
+		deleted: make(map[entryRef]time.Time),
+		entries: make(map[string]trashableEntries),
+		shares:  shares,
+		orgs:    orgs,
+	}
+}
+
+// register - подключить репозиторий записей указанного типа
+func (r *InMemoryTrashRepo) register(entityType string, entries trashableEntries) {
+	r.entries[entityType] = entries
+}
+
+// GetAll - записи в корзине, созданные текущим пользователем лично или лежащие в коллекциях его организаций
+func (r *InMemoryTrashRepo) GetAll(ctx context.Context) ([]entities.TrashItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	var items []entities.TrashItem
+	for key := range r.deleted {
+		item, ok := r.item(key, userID)
+		if !ok {
+			continue
+		}
+		if (item.CollectionID == "" && item.OwnerID == userID) || item.OrgRole != "" {
+			items = append(items, *item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+// Get - запись в корзине с ролью текущего пользователя в организации её коллекции (nil, если записи в корзине нет)
+func (r *InMemoryTrashRepo) Get(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.get(ctx, entityType, id)
+}
+
+// get - запись в корзине в представлении текущего пользователя (без блокировки)
+func (r *InMemoryTrashRepo) get(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	userID := customcontext.GetUserID(ctx)
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	if _, known := r.entries[entityType]; !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	item, ok := r.item(entryRef{entityType: entityType, id: id}, userID)
+	if !ok {
+		return nil, nil
+	}
+	return item, nil
+}
+
+// Restore - вернуть запись из корзины. Права на неё не удалялись и снова начинают действовать
+func (r *InMemoryTrashRepo) Restore(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, err := r.get(ctx, entityType, id)
+	if err != nil || item == nil {
+		return nil, err
+	}
+
+	if err := r.journal.remove(tableTrash, entityType, id); err != nil {
+		return nil, err
+	}
+	delete(r.deleted, entryRef{entityType: entityType, id: id})
+	return item, nil
+}
+
+// Delete - окончательно удалить запись из корзины вместе с выданными на неё правами
+func (r *InMemoryTrashRepo) Delete(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, err := r.get(ctx, entityType, id)
+	if err != nil || item == nil {
+		return nil, err
+	}
+
+	if err := r.remove(entryRef{entityType: entityType, id: id}); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Purge - окончательно удалить записи всех пользователей, попавшие в корзину раньше before
+func (r *InMemoryTrashRepo) Purge(ctx context.Context, before time.Time) ([]entities.TrashItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged []entities.TrashItem
+	for key, deletedAt := range r.deleted {
+		if !deletedAt.Before(before) {
+			continue
+		}
+
+		if item, ok := r.item(key, ""); ok {
+			item.EntryKey = ""
+			purged = append(purged, *item)
+		}
+		if err := r.remove(key); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(purged, func(i, j int) bool {
+		return purged[i].DeletedAt.Before(purged[j].DeletedAt)
+	})
+
+	return purged, nil
+}
+
+// put - переместить запись в корзину
+func (r *InMemoryTrashRepo) put(entityType, id string, deletedAt time.Time) error {
+	if err := r.journal.put(tableTrash, deletedAt, entityType, id); err != nil {
+		return err
+	}
+	r.deleted[entryRef{entityType: entityType, id: id}] = deletedAt
+	return nil
+}
+
+// contains - находится ли запись в корзине
+func (r *InMemoryTrashRepo) contains(entityType, id string) bool {
+	if r == nil {
+		return false
+	}
+
+	_, deleted := r.deleted[entryRef{entityType: entityType, id: id}]
+	return deleted
+}
+
+// item - запись в корзине в представлении пользователя userID (ok=false, если записи в корзине нет).
+// Ключ записи коллекции возвращается, только если пользователь - активный участник организации
+func (r *InMemoryTrashRepo) item(key entryRef, userID string) (*entities.TrashItem, bool) {
+	deletedAt, deleted := r.deleted[key]
+	if !deleted {
+		return nil, false
+	}
+
+	entry, exists := r.entries[key.entityType].entry(key.id)
+	if !exists {
+		// Запись удалена вместе с учётной записью владельца или организацией. Если строку не удалось убрать
+		// из журнала, она останется до следующего чтения
+		if r.journal.remove(tableTrash, key.entityType, key.id) == nil {
+			delete(r.deleted, key)
+		}
+		return nil, false
+	}
+
+	item := entities.TrashItem{SecureEntity: entry, EntityType: key.entityType, DeletedAt: deletedAt}
+	item.Permission = ""
+	if entry.CollectionID != "" {
+		role, collectionKey, _ := r.orgs.memberKey(entry.CollectionID, userID, entry.KeyVersion)
+		item.OrgRole = role
+		item.EntryKey = collectionKey
+	}
+
+	return &item, true
+}
+
+// remove - окончательно удалить запись из корзины вместе с правами на неё.
+// Запись уходит из корзины последней: иначе при ошибке журнала она вернулась бы к владельцу
+func (r *InMemoryTrashRepo) remove(key entryRef) error {
+	if err := r.entries[key.entityType].remove(key.id); err != nil {
+		return err
+	}
+	if err := r.journal.remove(tableTrash, key.entityType, key.id); err != nil {
+		return err
+	}
+	delete(r.deleted, key)
+	return nil
+}
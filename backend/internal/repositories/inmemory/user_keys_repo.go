@@ -0,0 +1,60 @@
+// inmemory содержит репозиторий который хранит данные в оперативной памяти
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// InMemoryUserKeysRepo - ключи пользователей в памяти
+type InMemoryUserKeysRepo struct {
+	mu      *sync.Mutex
+	storage map[string]entities.UserKeys
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
+}
+
+// NewInMemoryUserKeysRepo - инициализация репозитория ключей
+func NewInMemoryUserKeysRepo() *InMemoryUserKeysRepo {
+	return &InMemoryUserKeysRepo{
+		mu:      &sync.Mutex{},
+		storage: make(map[string]entities.UserKeys),
+	}
+}
+
+// Set - сохранить (или заменить) ключи пользователя
+func (r *InMemoryUserKeysRepo) Set(ctx context.Context, keys *entities.UserKeys) (*entities.UserKeys, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if keys == nil {
+		return nil, errors.New("keys cannot be nil")
+	}
+
+	if err := r.journal.put(tableUserKeys, keys, keys.Login); err != nil {
+		return nil, err
+	}
+	r.storage[keys.Login] = *keys
+	return keys, nil
+}
+
+// Get - получить ключи пользователя по логину
+func (r *InMemoryUserKeysRepo) Get(ctx context.Context, login string) (*entities.UserKeys, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys, exists := r.storage[login]
+	if !exists {
+		return nil, nil
+	}
+
+	return &keys, nil
+}
@@ -5,26 +5,35 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
-	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
 )
 
 // InMemoryUsersRepo - репозиторий пользователей в памяти
 type InMemoryUsersRepo struct {
+	mu      *sync.Mutex
 	storage map[string]entities.User
+	journal *journal // журнал изменений (nil - состояние не сохраняется)
 }
 
 // NewInMemoryUsersRepo - инициализация репозитория пользователей
 func NewInMemoryUsersRepo() *InMemoryUsersRepo {
 	return &InMemoryUsersRepo{
+		mu:      &sync.Mutex{},
 		storage: make(map[string]entities.User),
 	}
 }
 
 // GetAll - получить все сущности
 func (r *InMemoryUsersRepo) GetAll(ctx context.Context) ([]entities.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	users := make([]entities.User, 0, len(r.storage))
 	for _, user := range r.storage {
 		users = append(users, user)
@@ -35,6 +44,12 @@ func (r *InMemoryUsersRepo) GetAll(ctx context.Context) ([]entities.User, error)
 
 // Get - получить сущность по логину
 func (r *InMemoryUsersRepo) Get(ctx context.Context, login string) (*entities.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	user, exists := r.storage[login]
 	if !exists {
 		return nil, nil
@@ -45,6 +60,12 @@ func (r *InMemoryUsersRepo) Get(ctx context.Context, login string) (*entities.Us
 
 // Create - создать сущность
 func (r *InMemoryUsersRepo) Create(ctx context.Context, dto *dtos.NewUser) (*entities.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if dto == nil {
 		return nil, errors.New("dto cannot be nil")
 	}
@@ -59,12 +80,21 @@ func (r *InMemoryUsersRepo) Create(ctx context.Context, dto *dtos.NewUser) (*ent
 		Password: dto.Password,
 	}
 
+	if err := r.journal.put(tableUsers, user, dto.Login); err != nil {
+		return nil, err
+	}
 	r.storage[dto.Login] = user
 	return &user, nil
 }
 
 // Update - изменить сущность
 func (r *InMemoryUsersRepo) Update(ctx context.Context, entity *entities.User) (*entities.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -74,18 +104,29 @@ func (r *InMemoryUsersRepo) Update(ctx context.Context, entity *entities.User) (
 		return nil, nil
 	}
 
+	if err := r.journal.put(tableUsers, entity, entity.Login); err != nil {
+		return nil, err
+	}
 	r.storage[entity.Login] = *entity
 	return entity, nil
 }
 
-// Delete - удалить сущность
+// Delete - удалить сущность (права проверяются сервисом до вызова)
 func (r *InMemoryUsersRepo) Delete(ctx context.Context, login string) (*entities.User, error) {
-	curUserID := customcontext.GetUserID(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	user, exists := r.storage[login]
-	if !exists || curUserID != login {
+	if !exists {
 		return nil, nil
 	}
 
+	if err := r.journal.remove(tableUsers, login); err != nil {
+		return nil, err
+	}
 	delete(r.storage, login)
 	return &user, nil
 }
@@ -3,18 +3,188 @@ package repositories
 
 import (
 	"context"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
 )
 
-// Interface реализация паттерна "репозиторий"
+// Interface реализация паттерна "репозиторий".
+// Записи видны только пользователю из контекста (владельцу или получателю прав), а менять и удалять их можно
+// после проверки политикой доступа. Операция прерывается при отмене контекста. Поведение проверяет общий набор тестов repotest
 type IRepository[Entity any, DTO any] interface {
 	// GetAll - получить все сущности
 	GetAll(ctx context.Context) ([]Entity, error)
-	// Get - получить сущность по ИД
+	// Get - получить сущность по ИД (nil, если её нет)
 	Get(ctx context.Context, id string) (*Entity, error)
-	// Create - создать сущность
+	// Create - создать сущность (ошибка, если ИД занят)
 	Create(ctx context.Context, dto *DTO) (*Entity, error)
-	// Update - изменить сущность
+	// Update - изменить сущность (nil, если её нет). Владелец записи не меняется
 	Update(ctx context.Context, entity *Entity) (*Entity, error)
-	// Delete - удалить сущность
+	// Delete - удалить сущность (nil, если её нет)
 	Delete(ctx context.Context, id string) (*Entity, error)
 }
+
+// IAuditRepository - журнал аудита. Записи только добавляются: изменение и удаление не предусмотрены
+type IAuditRepository interface {
+	// Append - добавить запись в журнал
+	Append(ctx context.Context, event *entities.AuditEvent) error
+	// Query - получить записи журнала, удовлетворяющие фильтру (в порядке создания)
+	Query(ctx context.Context, filter *dtos.AuditFilter) ([]entities.AuditEvent, error)
+}
+
+// IUserKeysRepository - пары ключей пользователей для обмена записями
+type IUserKeysRepository interface {
+	// Set - сохранить (или заменить) ключи пользователя
+	Set(ctx context.Context, keys *entities.UserKeys) (*entities.UserKeys, error)
+	// Get - получить ключи пользователя по логину (nil, если ключей нет)
+	Get(ctx context.Context, login string) (*entities.UserKeys, error)
+}
+
+// IShareRepository - права пользователей на чужие записи. Все операции выполняются от имени текущего пользователя
+type IShareRepository interface {
+	// Create - поделиться записью от имени её владельца (nil, если записи нет или она ещё не переведена на ключ записи)
+	Create(ctx context.Context, grant *dtos.NewShareGrant) (*entities.ShareGrant, error)
+	// GetAll - получить права, выданные текущим пользователем и выданные ему
+	GetAll(ctx context.Context) ([]entities.ShareGrant, error)
+	// Accept - принять приглашение, адресованное текущему пользователю
+	Accept(ctx context.Context, id string) (*entities.ShareGrant, error)
+	// Delete - отозвать право (владелец) или отказаться от него (получатель)
+	Delete(ctx context.Context, id string) (*entities.ShareGrant, error)
+}
+
+// IAccessRepository - отношения пользователей к записям, по которым политика доступа принимает решения
+type IAccessRepository interface {
+	// EntryAccess - отношение текущего пользователя к записи (nil, если записи нет)
+	EntryAccess(ctx context.Context, entityType, id string) (*entities.EntryAccess, error)
+	// EntryExists - занят ли идентификатор записью указанного типа (в том числе записью в корзине или чужой записью)
+	EntryExists(ctx context.Context, entityType, id string) (bool, error)
+}
+
+// IOrganizationRepository - организации, их участники и коллекции.
+// Права не проверяются: решения о допустимости операций принимает политика доступа
+type IOrganizationRepository interface {
+	// Create - создать организацию, текущий пользователь становится её владельцем
+	Create(ctx context.Context, org *dtos.NewOrganization) (*entities.Organization, error)
+	// GetAll - получить организации, в которых состоит или куда приглашён текущий пользователь
+	GetAll(ctx context.Context) ([]entities.Organization, error)
+	// GetMember - получить участника организации (nil, если пользователь в ней не состоит)
+	GetMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error)
+	// GetMembers - получить участников организации (в порядке приглашения)
+	GetMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error)
+	// AddMember - пригласить пользователя и сохранить ключи коллекций для него (nil, если пользователь уже участник)
+	AddMember(ctx context.Context, member *dtos.NewOrgMember) (*entities.OrgMember, error)
+	// ActivateMember - принять приглашение (nil, если приглашения нет)
+	ActivateMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error)
+	// RemoveMember - исключить участника: удалить его ключи коллекций и отметить коллекции для замены ключа
+	RemoveMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error)
+	// CreateCollection - создать коллекцию с ключом версии 1
+	CreateCollection(ctx context.Context, collection *dtos.NewCollection) (*entities.Collection, error)
+	// GetCollections - получить коллекции организации с текущим ключом, зашифрованным для текущего пользователя
+	GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error)
+	// GetCollection - получить коллекцию по ИД (nil, если её нет)
+	GetCollection(ctx context.Context, id string) (*entities.Collection, error)
+	// RotateCollectionKey - заменить ключ коллекции следующей версией (nil, если версия не следующая за текущей)
+	RotateCollectionKey(ctx context.Context, rotation *dtos.CollectionRotation) (*entities.Collection, error)
+}
+
+// IEmergencyAccessRepository - экстренный доступ доверенных лиц к хранилищам пользователей.
+// Права не проверяются: допустимость переходов между состояниями определяет политика доступа
+type IEmergencyAccessRepository interface {
+	// Create - назначить доверенное лицо от имени текущего пользователя (nil, если оно уже назначено)
+	Create(ctx context.Context, access *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error)
+	// GetAll - получить экстренные доступы, в которых текущий пользователь доверитель или доверенное лицо
+	GetAll(ctx context.Context) ([]entities.EmergencyAccess, error)
+	// Get - получить экстренный доступ по ИД (nil, если его нет)
+	Get(ctx context.Context, id string) (*entities.EmergencyAccess, error)
+	// SetStatus - перевести доступ из состояния from в состояние to (nil, если он уже не в состоянии from).
+	// При запросе доступа запоминается время запроса, при возврате к согласию оно сбрасывается
+	SetStatus(ctx context.Context, id, from, to string) (*entities.EmergencyAccess, error)
+	// GrantElapsed - предоставить доступ по запросам, период ожидания которых истёк к моменту now
+	GrantElapsed(ctx context.Context, now time.Time) ([]entities.EmergencyAccess, error)
+	// Delete - удалить экстренный доступ
+	Delete(ctx context.Context, id string) (*entities.EmergencyAccess, error)
+}
+
+// IAccountRepository - учётные записи пользователей целиком: объём хранимых данных, статистика и удаление со всеми данными
+type IAccountRepository interface {
+	// Usage - количество и объём записей, созданных пользователем
+	Usage(ctx context.Context, login string) (*entities.StorageUsage, error)
+	// AllUsage - количество и объём записей по логинам создавших их пользователей
+	AllUsage(ctx context.Context) (map[string]entities.StorageUsage, error)
+	// Stats - количество пользователей, организаций, прав и экстренных доступов, итоговый объём записей
+	Stats(ctx context.Context) (*entities.ServerStats, error)
+	// Delete - в одной транзакции удалить пользователя вместе с его записями, правами, ключами, организациями
+	// и экстренными доступами (nil, если пользователя нет). Журнал аудита сохраняется
+	Delete(ctx context.Context, login string) (*entities.User, error)
+	// GetQuota - индивидуальная квота пользователя (nil, если действует квота по умолчанию)
+	GetQuota(ctx context.Context, login string) (*entities.Quota, error)
+	// AllQuotas - индивидуальные квоты по логинам пользователей
+	AllQuotas(ctx context.Context) (map[string]entities.Quota, error)
+	// SetQuota - задать индивидуальную квоту пользователя (nil - вернуть квоту по умолчанию)
+	SetQuota(ctx context.Context, login string, quota *entities.Quota) error
+}
+
+// IInviteRepository - коды приглашений для регистрации
+type IInviteRepository interface {
+	// Create - сохранить приглашение с кодом, выпущенным сервисом
+	Create(ctx context.Context, code string, invite *dtos.NewInvite) (*entities.Invite, error)
+	// GetAll - получить все приглашения (в порядке выпуска)
+	GetAll(ctx context.Context) ([]entities.Invite, error)
+	// Get - получить приглашение по коду (nil, если его нет)
+	Get(ctx context.Context, code string) (*entities.Invite, error)
+	// Use - засчитать использование приглашения, если к моменту now оно не истекло и не исчерпано (иначе nil)
+	Use(ctx context.Context, code string, now time.Time) (*entities.Invite, error)
+	// Delete - отозвать приглашение (nil, если его нет)
+	Delete(ctx context.Context, code string) (*entities.Invite, error)
+}
+
+// ITrashRepository - корзина: удалённые записи всех типов до восстановления или окончательного удаления.
+// Права не проверяются: решения о допустимости операций принимает политика доступа
+type ITrashRepository interface {
+	// GetAll - записи в корзине, созданные текущим пользователем лично или лежащие в коллекциях его организаций
+	// (сначала удалённые последними)
+	GetAll(ctx context.Context) ([]entities.TrashItem, error)
+	// Get - запись в корзине с ролью текущего пользователя в организации её коллекции (nil, если записи в корзине нет)
+	Get(ctx context.Context, entityType, id string) (*entities.TrashItem, error)
+	// Restore - вернуть запись из корзины вместе с выданными на неё правами (nil, если записи в корзине нет)
+	Restore(ctx context.Context, entityType, id string) (*entities.TrashItem, error)
+	// Delete - окончательно удалить запись из корзины вместе с выданными на неё правами (nil, если записи в корзине нет)
+	Delete(ctx context.Context, entityType, id string) (*entities.TrashItem, error)
+	// Purge - окончательно удалить записи всех пользователей, попавшие в корзину раньше before
+	Purge(ctx context.Context, before time.Time) ([]entities.TrashItem, error)
+}
+
+// IHistoryRepository - история записей всех типов. Права не проверяются: решения о допустимости операций принимает политика доступа
+type IHistoryRepository interface {
+	// Append - сохранить текущее состояние записи как новую версию от имени текущего пользователя, изменить запись
+	// функцией update и удалить версии сверх maxVersions (0 - без ограничения) и заменённые раньше notBefore
+	// (нулевое время - без ограничения). Если update вернула ошибку, история не меняется
+	Append(ctx context.Context, entityType, id string, maxVersions int, notBefore time.Time, update func(ctx context.Context) error) error
+	// GetAll - версии записи, которые текущий пользователь может расшифровать (сначала последние)
+	GetAll(ctx context.Context, entityType, id string) ([]entities.Revision, error)
+	// Get - версия записи в представлении текущего пользователя (nil, если версии нет или пользователь не может её расшифровать)
+	Get(ctx context.Context, entityType, id string, revision int) (*entities.Revision, error)
+}
+
+// IDataKeyRepository - ключи данных пользователей для шифрования хранимых полей на сервере.
+// Ключи хранятся только зашифрованными мастер-ключом
+type IDataKeyRepository interface {
+	// Get - ключ данных пользователя (nil, если ключа ещё нет)
+	Get(ctx context.Context, login string) (*entities.DataKey, error)
+	// GetAll - ключи данных всех пользователей
+	GetAll(ctx context.Context) ([]entities.DataKey, error)
+	// Create - сохранить ключ данных, если у пользователя его ещё нет. Возвращает действующий ключ пользователя
+	Create(ctx context.Context, key *entities.DataKey) (*entities.DataKey, error)
+	// Update - заменить зашифрованный ключ данных (nil, если ключа нет)
+	Update(ctx context.Context, key *entities.DataKey) (*entities.DataKey, error)
+	// Delete - удалить ключ данных пользователя: поля, зашифрованные им, больше не расшифровать
+	Delete(ctx context.Context, login string) error
+}
+
+// ITransactor - выполнение нескольких операций репозиториев как одного целого
+type ITransactor interface {
+	// InTransaction - выполнить fn в одной транзакции: изменения фиксируются, если fn вернула nil, иначе откатываются.
+	// Репозитории, вызванные с контекстом fn, работают в этой транзакции
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,69 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// PgAccessRepo - отношения пользователей к записям для политики доступа
+type PgAccessRepo struct {
+	db *tenantConn
+}
+
+// NewPgAccessRepo - инициализация репозитория
+func NewPgAccessRepo(db *pgx.Conn) (*PgAccessRepo, error) {
+	return &PgAccessRepo{db: newTenantConn(db)}, nil
+}
+
+// EntryAccess - владелец и коллекция записи, роль текущего пользователя в организации коллекции
+// и его права по принятому приглашению (nil, если записи нет или она в корзине)
+func (r *PgAccessRepo) EntryAccess(ctx context.Context, entityType, id string) (*entities.EntryAccess, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	table, known := sharableTables[entityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	query := `
+		SELECT e.ownerid, COALESCE(e.collectionid::text, ''),
+			COALESCE((SELECT m.role FROM collections c JOIN org_members m ON m.org_id = c.org_id
+				WHERE c.id = e.collectionid AND m.login = $2 AND m.status = 'active'), ''),
+			COALESCE((SELECT g.permission FROM share_grants g
+				WHERE g.entity_type = $3 AND g.entity_id = e.id AND g.recipient_id = $2 AND g.status = 'accepted'), '')
+		FROM ` + table + ` e WHERE e.id = $1 AND e.deleted_at IS NULL`
+
+	access := entities.EntryAccess{EntityType: entityType, EntityID: id}
+	err := r.db.QueryRow(ctx, query, id, userID, entityType).Scan(&access.OwnerID, &access.CollectionID, &access.OrgRole, &access.Permission)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, fmt.Errorf("failed to get entry access: %w", err)
+	}
+
+	return &access, nil
+}
+
+// EntryExists - занят ли идентификатор записью указанного типа (в том числе записью в корзине или чужой записью)
+func (r *PgAccessRepo) EntryExists(ctx context.Context, entityType, id string) (bool, error) {
+	if _, known := sharableTables[entityType]; !known {
+		return false, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	// ИД уникален среди записей всех пользователей, а политики строк чужих записей не покажут:
+	// проверку выполняет функция владельца таблиц
+	var exists bool
+	err := r.db.QueryRow(ctx, "SELECT entry_id_taken($1, $2)", entityType, id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check entry id: %w", err)
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,188 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// usageCounters - счётчик записей в объёме данных пользователя по типу сущности
+var usageCounters = map[string]func(usage *entities.StorageUsage) *int{
+	"binary":      func(usage *entities.StorageUsage) *int { return &usage.Binaries },
+	"card":        func(usage *entities.StorageUsage) *int { return &usage.Cards },
+	"credentials": func(usage *entities.StorageUsage) *int { return &usage.Credentials },
+	"text":        func(usage *entities.StorageUsage) *int { return &usage.Texts },
+}
+
+// PgAccountRepo - учётные записи пользователей целиком
+type PgAccountRepo struct {
+	db *tenantConn
+}
+
+// NewPgAccountRepo - инициализация репозитория
+func NewPgAccountRepo(db *pgx.Conn) (*PgAccountRepo, error) {
+	return &PgAccountRepo{db: newTenantConn(db)}, nil
+}
+
+// Usage - количество и объём записей, созданных пользователем (включая записи в корзине)
+func (r *PgAccountRepo) Usage(ctx context.Context, login string) (*entities.StorageUsage, error) {
+	usage, err := r.queryUsage(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	result := usage[login]
+	return &result, nil
+}
+
+// AllUsage - количество и объём записей по логинам создавших их пользователей
+func (r *PgAccountRepo) AllUsage(ctx context.Context) (map[string]entities.StorageUsage, error) {
+	return r.queryUsage(ctx, "")
+}
+
+// Stats - количество пользователей, организаций, прав и экстренных доступов, итоговый объём записей
+func (r *PgAccountRepo) Stats(ctx context.Context) (*entities.ServerStats, error) {
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE disabled),
+			(SELECT COUNT(*) FROM organizations), (SELECT COUNT(*) FROM share_grants), (SELECT COUNT(*) FROM emergency_access)
+		FROM users`
+
+	var stats entities.ServerStats
+	err := r.db.QueryRow(ctx, query).Scan(&stats.Users, &stats.DisabledUsers, &stats.Organizations, &stats.Shares, &stats.EmergencyAccess)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server stats: %w", err)
+	}
+
+	usage, err := r.AllUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, userUsage := range usage {
+		stats.Usage.Add(userUsage)
+	}
+
+	return &stats, nil
+}
+
+// Delete - одним запросом удалить пользователя. Записи, их история, выданные и полученные права, ключи, квота, организации
+// пользователя, членство в чужих организациях и экстренные доступы удаляются каскадно,
+// коллекции организаций, где он состоял, отмечаются для замены ключа
+func (r *PgAccountRepo) Delete(ctx context.Context, login string) (*entities.User, error) {
+	query := `
+		WITH deleted AS (
+			DELETE FROM users WHERE login = $1 RETURNING ` + userColumns + `
+		), flagged AS (
+			UPDATE collections c SET rotation_required = TRUE
+			FROM org_members m, organizations o, deleted d
+			WHERE c.org_id = m.org_id AND o.id = m.org_id AND m.login = d.login AND o.owner_id <> d.login
+		)
+		SELECT ` + userColumns + ` FROM deleted`
+
+	user, err := scanUser(r.db.QueryRow(ctx, query, login))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Пользователя нет
+		}
+		return nil, fmt.Errorf("failed to delete account: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetQuota - индивидуальная квота пользователя (nil, если действует квота по умолчанию)
+func (r *PgAccountRepo) GetQuota(ctx context.Context, login string) (*entities.Quota, error) {
+	var quota entities.Quota
+	err := r.db.QueryRow(ctx, "SELECT max_entries, max_bytes FROM user_quotas WHERE login = $1", login).Scan(&quota.MaxEntries, &quota.MaxBytes)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get quota: %w", err)
+	}
+
+	return &quota, nil
+}
+
+// AllQuotas - индивидуальные квоты по логинам пользователей
+func (r *PgAccountRepo) AllQuotas(ctx context.Context) (map[string]entities.Quota, error) {
+	rows, err := r.db.Query(ctx, "SELECT login, max_entries, max_bytes FROM user_quotas")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quotas: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]entities.Quota)
+	for rows.Next() {
+		var login string
+		var quota entities.Quota
+		if err := rows.Scan(&login, &quota.MaxEntries, &quota.MaxBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan quota: %w", err)
+		}
+		result[login] = quota
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SetQuota - задать индивидуальную квоту пользователя (nil - вернуть квоту по умолчанию)
+func (r *PgAccountRepo) SetQuota(ctx context.Context, login string, quota *entities.Quota) error {
+	var err error
+	if quota == nil {
+		_, err = r.db.Exec(ctx, "DELETE FROM user_quotas WHERE login = $1", login)
+	} else {
+		_, err = r.db.Exec(ctx, `
+			INSERT INTO user_quotas (login, max_entries, max_bytes) VALUES ($1, $2, $3)
+			ON CONFLICT (login) DO UPDATE SET max_entries = EXCLUDED.max_entries, max_bytes = EXCLUDED.max_bytes`,
+			login, quota.MaxEntries, quota.MaxBytes)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set quota: %w", err)
+	}
+
+	return nil
+}
+
+// queryUsage - посчитать количество и объём записей пользователя login (пустой - всех пользователей).
+// Объём считается и по чужим записям (квота владельца общей записи, статистика сервера), поэтому его считает
+// функция владельца таблиц storage_usage
+func (r *PgAccountRepo) queryUsage(ctx context.Context, login string) (map[string]entities.StorageUsage, error) {
+	rows, err := r.db.Query(ctx, "SELECT owner, entity_type, entries, bytes FROM storage_usage(NULLIF($1, ''))", login)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]entities.StorageUsage)
+	for rows.Next() {
+		var owner, entityType string
+		var count int
+		var bytes int64
+		if err := rows.Scan(&owner, &entityType, &count, &bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan storage usage: %w", err)
+		}
+
+		counter, known := usageCounters[entityType]
+		if !known {
+			return nil, fmt.Errorf("unknown entity type %s", entityType)
+		}
+
+		usage := result[owner]
+		*counter(&usage) += count
+		usage.Bytes += bytes
+		result[owner] = usage
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,89 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// PgAuditRepo - журнал аудита
+type PgAuditRepo struct {
+	db *tenantConn
+}
+
+// NewPgAuditRepo - инициализация репозитория
+func NewPgAuditRepo(db *pgx.Conn) (*PgAuditRepo, error) {
+	return &PgAuditRepo{db: newTenantConn(db)}, nil
+}
+
+// Append - добавить запись в журнал. Ошибка записи не прерывает транзакцию задачи, в которой вызван метод
+func (r *PgAuditRepo) Append(ctx context.Context, event *entities.AuditEvent) error {
+	err := r.db.isolated(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, "INSERT INTO audit_log (userid, actor, action, entity_type, entity_id, session_id, device_id, ip) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at", event.UserID, event.Actor, event.Action, event.EntityType, event.EntityID, event.SessionID, event.DeviceID, event.IP).Scan(&event.ID, &event.CreatedAt)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+
+	return nil
+}
+
+// Query - получить записи журнала, удовлетворяющие фильтру
+func (r *PgAuditRepo) Query(ctx context.Context, filter *dtos.AuditFilter) ([]entities.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("userid = $%d", len(args)))
+	}
+	if filter.Actor != "" {
+		args = append(args, filter.Actor)
+		conditions = append(conditions, fmt.Sprintf("actor = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+	if filter.EntityType != "" {
+		args = append(args, filter.EntityType)
+		conditions = append(conditions, fmt.Sprintf("entity_type = $%d", len(args)))
+	}
+
+	query := "SELECT id, userid, actor, action, entity_type, entity_id, session_id, device_id, ip, created_at FROM audit_log"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at, id"
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var events []entities.AuditEvent
+	for rows.Next() {
+		var event entities.AuditEvent
+		err := rows.Scan(&event.ID, &event.UserID, &event.Actor, &event.Action, &event.EntityType, &event.EntityID, &event.SessionID, &event.DeviceID, &event.IP, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
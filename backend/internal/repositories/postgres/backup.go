@@ -0,0 +1,413 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/backup"
+	"github.com/jackc/pgx/v5"
+)
+
+// migrationsTable - таблица учёта миграций: в копию не входит, версия схемы записывается в заголовок архива
+const migrationsTable = "migrations"
+
+// restoreBatchRows и restoreBatchBytes - сколько строк (и не больше скольких байт) вставляется одним запросом при восстановлении
+const (
+	restoreBatchRows  = 500
+	restoreBatchBytes = 16 << 20
+)
+
+// userTables - таблицы с данными одного пользователя для частичного восстановления и столбец с его логином.
+// Записи коллекций организаций, права, выданные другим пользователям, и экстренные доступы связывают нескольких
+// пользователей, поэтому при частичном восстановлении не переносятся
+var userTables = map[string]string{
+	"users":         "login",
+	"user_keys":     "login",
+	"user_quotas":   "login",
+	"data_keys":     "login",
+	"binaries":      "ownerid",
+	"cards":         "ownerid",
+	"credentials":   "ownerid",
+	"texts":         "ownerid",
+	"entry_history": "owner_id",
+}
+
+// RestoreResult - восстановленные таблицы и число строк в каждой
+type RestoreResult struct {
+	Tables []backup.TableSummary
+}
+
+// Backup - записать в w согласованную копию всех таблиц базы данных: все таблицы читаются в одной транзакции
+// REPEATABLE READ, поэтому копия соответствует одному моменту даже при работающем сервере. Таблицы идут в порядке
+// восстановления: таблица после тех, на которые ссылается
+func Backup(ctx context.Context, conn *pgx.Conn, w *backup.Writer) error {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	// Копия включает записи всех пользователей
+	if err := bypassRLS(ctx, tx); err != nil {
+		return err
+	}
+
+	migrations, err := appliedMigrations(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return errors.New("database has no applied migrations")
+	}
+
+	tables, err := restoreOrder(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Begin(backup.Header{CreatedAt: time.Now().UTC(), Storage: "postgres", Migrations: migrations}); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if err := backupTable(ctx, tx, w, table); err != nil {
+			return fmt.Errorf("failed to back up table %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// backupTable - записать строки таблицы как JSON-объекты (двоичные данные - в hex, как их выводит PostgreSQL)
+func backupTable(ctx context.Context, tx pgx.Tx, w *backup.Writer, table string) error {
+	if err := w.Table(table); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT to_jsonb(t)::text FROM "+pgx.Identifier{table}.Sanitize()+" t")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := w.Row(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Restore - загрузить таблицы из архива r в одной транзакции: при любой ошибке база остаётся прежней.
+// Миграции базы должны совпадать с миграциями из заголовка архива.
+// Пустой login - полное восстановление в базу без данных (строки всех таблиц, затем счётчики идентификаторов).
+// Иначе восстанавливается только пользователь login: учётная запись, ключи, квота и личные записи с историей;
+// такого пользователя в базе быть не должно
+func Restore(ctx context.Context, conn *pgx.Conn, r *backup.Reader, login string) (*RestoreResult, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := bypassRLS(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	migrations, err := appliedMigrations(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if header := r.Header(); !slices.Equal(migrations, header.Migrations) {
+		return nil, fmt.Errorf("database schema does not match the backup: backup has migrations up to %d, database has %v", header.SchemaVersion(), migrations)
+	}
+
+	if login != "" {
+		var exists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE login = $1)", login).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("user %s already exists: delete it before restoring", login)
+		}
+	}
+
+	result := &RestoreResult{}
+	for {
+		table, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Полное восстановление переносит все строки в пустые таблицы, частичное - только строки пользователя
+		var keep func(map[string]json.RawMessage) bool
+		if login != "" {
+			ownerColumn, userTable := userTables[table]
+			if !userTable {
+				continue
+			}
+			keep = func(row map[string]json.RawMessage) bool { return ownedBy(row, ownerColumn, login) }
+		}
+
+		restored, err := restoreTable(ctx, tx, r, table, keep)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore table %s: %w", table, err)
+		}
+		result.Tables = append(result.Tables, backup.TableSummary{Name: table, Rows: restored})
+	}
+
+	if login != "" {
+		if err := restoreUserCleanup(ctx, tx, login, result); err != nil {
+			return nil, err
+		}
+	} else if err := resetSequences(ctx, tx, result.Tables); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return result, nil
+}
+
+// restoreTable - вставить строки таблицы, которые пропускает keep, пачками.
+// Без keep вставляются все строки, и таблица в базе должна быть пустой
+func restoreTable(ctx context.Context, tx pgx.Tx, r *backup.Reader, table string, keep func(map[string]json.RawMessage) bool) (int64, error) {
+	name := pgx.Identifier{table}.Sanitize()
+
+	if keep == nil {
+		var hasRows bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM "+name+")").Scan(&hasRows); err != nil {
+			return 0, err
+		}
+		if hasRows {
+			return 0, errors.New("table is not empty: full restore requires an empty database")
+		}
+	}
+
+	// Строки разбираются самим PostgreSQL по типам столбцов таблицы
+	query := "INSERT INTO " + name + " SELECT * FROM jsonb_populate_recordset(NULL::" + name + ", $1::jsonb)"
+
+	var restored int64
+	var batchBytes int
+	batch := make([]json.RawMessage, 0, restoreBatchRows)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		rows, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, query, string(rows)); err != nil {
+			return err
+		}
+		restored += int64(len(batch))
+		batch, batchBytes = batch[:0], 0
+		return nil
+	}
+
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if keep != nil {
+			var columns map[string]json.RawMessage
+			if err := json.Unmarshal(row, &columns); err != nil {
+				return 0, fmt.Errorf("malformed row: %w", err)
+			}
+			if !keep(columns) {
+				continue
+			}
+		}
+
+		batch, batchBytes = append(batch, row), batchBytes+len(row)
+		if len(batch) == restoreBatchRows || batchBytes >= restoreBatchBytes {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
+
+// ownedBy - принадлежит ли строка пользователю login. Записи коллекций принадлежат организации
+func ownedBy(row map[string]json.RawMessage, ownerColumn, login string) bool {
+	var owner string
+	if json.Unmarshal(row[ownerColumn], &owner) != nil || owner != login {
+		return false
+	}
+
+	collection, inCollection := row["collectionid"]
+	return !inCollection || string(collection) == "null"
+}
+
+// restoreUserCleanup - проверить, что пользователь был в копии, и удалить версии истории записей,
+// которые не восстановлены (записи коллекций)
+func restoreUserCleanup(ctx context.Context, tx pgx.Tx, login string, result *RestoreResult) error {
+	for _, table := range result.Tables {
+		if table.Name == "users" && table.Rows == 0 {
+			return fmt.Errorf("user %s not found in the backup", login)
+		}
+	}
+
+	query := `
+		DELETE FROM entry_history h WHERE h.owner_id = $1 AND NOT CASE h.entity_type
+			WHEN 'binary' THEN EXISTS (SELECT 1 FROM Binaries e WHERE e.id = h.entity_id)
+			WHEN 'card' THEN EXISTS (SELECT 1 FROM Cards e WHERE e.id = h.entity_id)
+			WHEN 'credentials' THEN EXISTS (SELECT 1 FROM Credentials e WHERE e.id = h.entity_id)
+			ELSE EXISTS (SELECT 1 FROM Texts e WHERE e.id = h.entity_id)
+		END`
+	tag, err := tx.Exec(ctx, query, login)
+	if err != nil {
+		return fmt.Errorf("failed to clean up entry history: %w", err)
+	}
+
+	for i := range result.Tables {
+		if result.Tables[i].Name == "entry_history" {
+			result.Tables[i].Rows -= tag.RowsAffected()
+		}
+	}
+	return nil
+}
+
+// resetSequences - продолжить счётчики идентификаторов (SERIAL) восстановленных таблиц после наибольшего идентификатора
+func resetSequences(ctx context.Context, tx pgx.Tx, tables []backup.TableSummary) error {
+	for _, table := range tables {
+		rows, err := tx.Query(ctx, `
+			SELECT a.attname, pg_get_serial_sequence($1::text, a.attname)
+			FROM pg_attribute a
+			WHERE a.attrelid = $1::text::regclass AND a.attnum > 0 AND NOT a.attisdropped
+				AND pg_get_serial_sequence($1::text, a.attname) IS NOT NULL`, pgx.Identifier{table.Name}.Sanitize())
+		if err != nil {
+			return err
+		}
+
+		sequences := make(map[string]string)
+		for rows.Next() {
+			var column, sequence string
+			if err := rows.Scan(&column, &sequence); err != nil {
+				rows.Close()
+				return err
+			}
+			sequences[column] = sequence
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for column, sequence := range sequences {
+			query := "SELECT setval($1, COALESCE((SELECT MAX(" + pgx.Identifier{column}.Sanitize() + ") FROM " + pgx.Identifier{table.Name}.Sanitize() + "), 0) + 1, FALSE)"
+			if _, err := tx.Exec(ctx, query, sequence); err != nil {
+				return fmt.Errorf("failed to reset sequence %s: %w", sequence, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// appliedMigrations - версии применённых миграций по возрастанию
+func appliedMigrations(ctx context.Context, tx pgx.Tx) ([]int, error) {
+	rows, err := tx.Query(ctx, "SELECT version FROM "+migrationsTable+" ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+// restoreOrder - таблицы схемы в порядке восстановления: каждая после таблиц, на которые ссылается внешними ключами
+// (при равенстве - по имени, чтобы порядок не зависел от каталога)
+func restoreOrder(ctx context.Context, tx pgx.Tx) ([]string, error) {
+	rows, err := tx.Query(ctx, "SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> $1", migrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT child.relname, parent.relname
+		FROM pg_constraint c
+		JOIN pg_class child ON child.oid = c.conrelid
+		JOIN pg_class parent ON parent.oid = c.confrelid
+		WHERE c.contype = 'f' AND c.connamespace = current_schema()::regnamespace`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+
+	// Таблица -> таблицы, на которые она ссылается
+	references := make(map[string][]string)
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if child != parent {
+			references[child] = append(references[child], parent)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(tables)
+	order := make([]string, 0, len(tables))
+	placed := make(map[string]bool, len(tables))
+	for len(order) < len(tables) {
+		progress := false
+		for _, table := range tables {
+			if placed[table] {
+				continue
+			}
+
+			ready := true
+			for _, parent := range references[table] {
+				if !placed[parent] && slices.Contains(tables, parent) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				order = append(order, table)
+				placed[table] = true
+				progress = true
+			}
+		}
+
+		if !progress {
+			return nil, errors.New("foreign keys between tables form a cycle")
+		}
+	}
+
+	return order, nil
+}
@@ -0,0 +1,168 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/backup"
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backupOf - архив с копией базы менеджера
+func backupOf(t *testing.T, manager *DatabaseManager) []byte {
+	var buf bytes.Buffer
+	w, err := backup.NewWriter(&buf)
+	require.NoError(t, err)
+	require.NoError(t, Backup(context.Background(), ownerConn(t, manager), w))
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// restoreTo - восстановить архив в базу менеджера (пустой login - целиком)
+func restoreTo(t *testing.T, manager *DatabaseManager, archive []byte, login string) (*RestoreResult, error) {
+	r, err := backup.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	return Restore(context.Background(), ownerConn(t, manager), r, login)
+}
+
+func TestBackupRestore(t *testing.T) {
+	source := newTestManager(t)
+	ctx := context.Background()
+	alice := customcontext.WithUserID(ctx, "alice")
+	bob := customcontext.WithUserID(ctx, "bob")
+
+	for _, login := range []string{"alice", "bob"} {
+		_, err := source.UsersRepo.Create(ctx, &dtos.NewUser{Login: login, Password: "hash of " + login})
+		require.NoError(t, err)
+	}
+
+	binaryID, cardID, textID := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	_, err := source.BinariesRepo.Create(alice, &dtos.NewBinaryData{NewSecureEntity: dtos.NewSecureEntity{ID: binaryID, Metadata: "photo"}, Data: []byte{0, 1, 0xff}})
+	require.NoError(t, err)
+	card, err := source.CardsRepo.Create(alice, &dtos.NewCardInformation{NewSecureEntity: dtos.NewSecureEntity{ID: cardID}, Number: "4111", ExpirationDate: "12/30"})
+	require.NoError(t, err)
+	require.NoError(t, source.HistoryRepo.Append(alice, "card", card.ID, 0, time.Time{}, func(context.Context) error { return nil }))
+	_, err = source.TextsRepo.Create(bob, &dtos.NewTextData{NewSecureEntity: dtos.NewSecureEntity{ID: textID}, Data: "note"})
+	require.NoError(t, err)
+	require.NoError(t, source.AuditRepo.Append(ctx, &entities.AuditEvent{UserID: "alice", Action: "create", EntityType: "card"}))
+
+	archive := backupOf(t, source)
+
+	t.Run("Заголовок", func(t *testing.T) {
+		header, tables, err := backup.Verify(bytes.NewReader(archive))
+		require.NoError(t, err)
+		assert.Equal(t, "postgres", header.Storage)
+		assert.NotZero(t, header.SchemaVersion())
+
+		names := make([]string, 0, len(tables))
+		for _, table := range tables {
+			names = append(names, table.Name)
+		}
+		assert.NotContains(t, names, migrationsTable)
+		assert.Less(t, indexOf(names, "users"), indexOf(names, "binaries"), "таблица восстанавливается после тех, на которые ссылается")
+	})
+
+	t.Run("Полное восстановление", func(t *testing.T) {
+		target := newTestManager(t)
+		_, err := restoreTo(t, target, archive, "")
+		require.NoError(t, err)
+
+		user, err := target.UsersRepo.Get(ctx, "bob")
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		assert.Equal(t, "hash of bob", user.Password)
+
+		binary, err := target.BinariesRepo.Get(alice, binaryID)
+		require.NoError(t, err)
+		require.NotNil(t, binary)
+		assert.Equal(t, []byte{0, 1, 0xff}, binary.Data)
+		assert.Equal(t, "photo", binary.Metadata)
+
+		revisions, err := target.HistoryRepo.GetAll(alice, "card", cardID)
+		require.NoError(t, err)
+		assert.Len(t, revisions, 1)
+
+		// Счётчики идентификаторов продолжают восстановленные
+		event := entities.AuditEvent{UserID: "bob", Action: "create", EntityType: "text"}
+		require.NoError(t, target.AuditRepo.Append(ctx, &event))
+
+		_, err = restoreTo(t, target, archive, "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not empty")
+	})
+
+	t.Run("Восстановление одного пользователя", func(t *testing.T) {
+		target := newTestManager(t)
+		_, err := target.UsersRepo.Create(ctx, &dtos.NewUser{Login: "bob", Password: "new hash"})
+		require.NoError(t, err)
+
+		result, err := restoreTo(t, target, archive, "alice")
+		require.NoError(t, err)
+		assert.Contains(t, result.Tables, backup.TableSummary{Name: "users", Rows: 1})
+
+		restored, err := target.CardsRepo.Get(alice, cardID)
+		require.NoError(t, err)
+		require.NotNil(t, restored)
+		assert.Equal(t, "4111", restored.Number)
+
+		// Данные других пользователей не меняются
+		user, err := target.UsersRepo.Get(ctx, "bob")
+		require.NoError(t, err)
+		assert.Equal(t, "new hash", user.Password)
+		texts, err := target.TextsRepo.GetAll(bob)
+		require.NoError(t, err)
+		assert.Empty(t, texts)
+
+		_, err = restoreTo(t, target, archive, "alice")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already exists")
+
+		_, err = restoreTo(t, target, archive, "carol")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not found in the backup")
+	})
+
+	t.Run("Копия от имени роли сервера", func(t *testing.T) {
+		if testServer.role == "" {
+			t.Skip("roles cannot be created on the test server")
+		}
+
+		// Роль сервера не видит чужих записей, и неполная копия не должна получиться молча
+		w, err := backup.NewWriter(io.Discard)
+		require.NoError(t, err)
+		err = Backup(ctx, source.DB, w)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "database.owner_dsn")
+	})
+
+	t.Run("Другая версия схемы", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := backup.NewWriter(&buf)
+		require.NoError(t, err)
+		require.NoError(t, w.Begin(backup.Header{Storage: "postgres", Migrations: []int{1}}))
+		require.NoError(t, w.Close())
+
+		_, err = restoreTo(t, newTestManager(t), buf.Bytes(), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "schema does not match")
+	})
+}
+
+// indexOf - позиция строки в списке (-1, если её нет)
+func indexOf(list []string, value string) int {
+	for i, item := range list {
+		if item == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -14,32 +14,37 @@ import (
 
 // PgBinariesRepo - репозиторий с бинарными данными
 type PgBinariesRepo struct {
-	db *pgx.Conn
+	db *tenantConn
 }
 
 // NewPgBinariesRepo - инициализация репозитория
 func NewPgBinariesRepo(db *pgx.Conn) (*PgBinariesRepo, error) {
-	// Создание таблицы Binaries, если её нет
-	_, err := db.Exec(context.Background(), `
-		CREATE TABLE IF NOT EXISTS Binaries (
-			id SERIAL PRIMARY KEY,
-			data BYTEA NOT NULL,
-			metadata TEXT,
-			ownerid TEXT NOT NULL
-		)
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
-	}
-
-	return &PgBinariesRepo{db: db}, nil
+	return &PgBinariesRepo{db: newTenantConn(db)}, nil
 }
 
-// GetAll - получить все сущности (при наличии прав у текущего пользователя)
+// binariesVisibleQuery - бинарные данные, доступные пользователю $1 (кроме записей в корзине): собственные личные записи, чужие записи, которыми с ним поделились
+// (приглашение принято), и записи коллекций организаций, в которых он состоит. Для чужих записей возвращаются ключ,
+// зашифрованный для пользователя (ключ записи или ключ коллекции нужной версии), и права пользователя
+const binariesVisibleQuery = `
+	SELECT id, data, metadata, ownerid, COALESCE(entrykey, '') AS entrykey, '' AS permission, '' AS collectionid, keyversion
+	FROM Binaries WHERE ownerid = $1 AND collectionid IS NULL AND deleted_at IS NULL
+	UNION ALL
+	SELECT b.id, b.data, b.metadata, b.ownerid, g.entrykey, g.permission, '', b.keyversion
+	FROM Binaries b JOIN share_grants g ON g.entity_type = 'binary' AND g.entity_id = b.id
+	WHERE g.recipient_id = $1 AND g.status = 'accepted' AND b.deleted_at IS NULL
+	UNION ALL
+	SELECT b.id, b.data, b.metadata, b.ownerid, k.encrypted_key,
+		CASE WHEN m.role = 'readonly' THEN 'read' ELSE 'write' END, b.collectionid::text, b.keyversion
+	FROM Binaries b JOIN collections col ON col.id = b.collectionid
+	JOIN org_members m ON m.org_id = col.org_id AND m.login = $1 AND m.status = 'active'
+	JOIN collection_keys k ON k.collection_id = col.id AND k.login = $1 AND k.version = b.keyversion
+	WHERE b.deleted_at IS NULL`
+
+// GetAll - получить все доступные текущему пользователю сущности
 func (r *PgBinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryData, error) {
 	userID := customcontext.GetUserID((ctx))
 
-	rows, err := r.db.Query(ctx, "SELECT id, data, metadata, ownerid FROM Binaries WHERE ownerid = $1", userID)
+	rows, err := r.db.Query(ctx, binariesVisibleQuery, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entities: %w", err)
 	}
@@ -52,23 +57,23 @@ func (r *PgBinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryData, err
 
 	var binaries []entities.BinaryData
 	for rows.Next() {
-		var binaryData entities.BinaryData
-		err := rows.Scan(&binaryData.ID, &binaryData.Data, &binaryData.Metadata, &binaryData.OwnerID)
+		var binary entities.BinaryData
+		err := rows.Scan(&binary.ID, &binary.Data, &binary.Metadata, &binary.OwnerID, &binary.EntryKey, &binary.Permission, &binary.CollectionID, &binary.KeyVersion)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan entity: %w", err)
 		}
-		binaries = append(binaries, binaryData)
+		binaries = append(binaries, binary)
 	}
 
 	return binaries, nil
 }
 
-// Get - получить сущность по ИД (при наличии прав у текущего пользователя)
+// Get - получить сущность по ИД (если она доступна текущему пользователю)
 func (r *PgBinariesRepo) Get(ctx context.Context, id string) (*entities.BinaryData, error) {
 	userID := customcontext.GetUserID((ctx))
 
-	var binaryData entities.BinaryData
-	err := r.db.QueryRow(ctx, "SELECT id, data, metadata, ownerid FROM Binaries WHERE id = $1 AND ownerid = $2", id, userID).Scan(&binaryData.ID, &binaryData.Data, &binaryData.Metadata, &binaryData.OwnerID)
+	var binary entities.BinaryData
+	err := r.db.QueryRow(ctx, "SELECT * FROM ("+binariesVisibleQuery+") AS visible WHERE id = $2", userID, id).Scan(&binary.ID, &binary.Data, &binary.Metadata, &binary.OwnerID, &binary.EntryKey, &binary.Permission, &binary.CollectionID, &binary.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -77,28 +82,36 @@ func (r *PgBinariesRepo) Get(ctx context.Context, id string) (*entities.BinaryDa
 		return nil, fmt.Errorf("failed to get entity: %w", err)
 	}
 
-	return &binaryData, nil
+	return &binary, nil
 }
 
-// Create - создать сущность
+// Create - создать сущность (личную или в коллекции организации) и вернуть её в представлении текущего пользователя
 func (r *PgBinariesRepo) Create(ctx context.Context, binaryData *dtos.NewBinaryData) (*entities.BinaryData, error) {
 	userID := customcontext.GetUserID(ctx)
 
-	var entity entities.BinaryData
-	err := r.db.QueryRow(ctx, "INSERT INTO Binaries (data, metadata, ownerid) VALUES ($1, $2, $3) RETURNING id, data, metadata, ownerid", binaryData.Data, binaryData.Metadata, userID).Scan(&entity.ID, &entity.Data, &entity.Metadata, &entity.OwnerID)
+	query := "INSERT INTO Binaries (id, data, metadata, ownerid, collectionid, keyversion) VALUES ($1, $2, $3, $4, NULLIF($5, '')::integer, $6) RETURNING id"
 
+	var id string
+	err := r.db.QueryRow(ctx, query, binaryData.ID, binaryData.Data, binaryData.Metadata, userID, binaryData.CollectionID, binaryData.KeyVersion).Scan(&id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create entity: %w", err)
 	}
-	return &entity, nil
+
+	return r.Get(ctx, id)
 }
 
-// Update - изменить сущность
+// Update - изменить сущность. Права проверяются политикой доступа до вызова:
+// пустой ключ записи оставляет прежний, версия ключа меняется только у записей коллекций
 func (r *PgBinariesRepo) Update(ctx context.Context, binaryData *entities.BinaryData) (*entities.BinaryData, error) {
-	userID := customcontext.GetUserID(ctx)
+	query := `
+		UPDATE Binaries SET data = $2, metadata = $3,
+			entrykey = COALESCE(NULLIF($4, ''), entrykey),
+			keyversion = CASE WHEN collectionid IS NULL THEN keyversion ELSE $5 END
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id`
 
-	var updatedEntity entities.BinaryData
-	err := r.db.QueryRow(ctx, "UPDATE Binaries SET data = $2, metadata = $3 WHERE id = $1 AND ownerid = $4 RETURNING id, data, metadata, ownerid", binaryData.ID, binaryData.Data, binaryData.Metadata, userID).Scan(&updatedEntity.ID, &updatedEntity.Data, &updatedEntity.Metadata, &updatedEntity.OwnerID)
+	var id string
+	err := r.db.QueryRow(ctx, query, binaryData.ID, binaryData.Data, binaryData.Metadata, binaryData.EntryKey, binaryData.KeyVersion).Scan(&id)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -107,15 +120,18 @@ func (r *PgBinariesRepo) Update(ctx context.Context, binaryData *entities.Binary
 		return nil, err
 	}
 
-	return &updatedEntity, nil
+	return r.Get(ctx, id)
 }
 
-// Delete - удалить сущность
+// Delete - переместить сущность в корзину. Выданные на неё права сохраняются, но не действуют, пока запись в корзине.
+// Права проверяются политикой доступа до вызова
 func (r *PgBinariesRepo) Delete(ctx context.Context, id string) (*entities.BinaryData, error) {
-	userID := customcontext.GetUserID((ctx))
+	query := `
+		UPDATE Binaries SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, data, metadata, ownerid, COALESCE(entrykey, '') AS entrykey, '' AS permission, COALESCE(collectionid::text, '') AS collectionid, keyversion`
 
-	var deletedBinary entities.BinaryData
-	err := r.db.QueryRow(ctx, "DELETE FROM Binaries WHERE id = $1 AND ownerid = $2 RETURNING id, data, metadata, ownerid", id, userID).Scan(&deletedBinary.ID, &deletedBinary.Data, &deletedBinary.Metadata, &deletedBinary.OwnerID)
+	var deleted entities.BinaryData
+	err := r.db.QueryRow(ctx, query, id).Scan(&deleted.ID, &deleted.Data, &deleted.Metadata, &deleted.OwnerID, &deleted.EntryKey, &deleted.Permission, &deleted.CollectionID, &deleted.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -124,5 +140,5 @@ func (r *PgBinariesRepo) Delete(ctx context.Context, id string) (*entities.Binar
 		return nil, err
 	}
 
-	return &deletedBinary, err
+	return &deleted, nil
 }
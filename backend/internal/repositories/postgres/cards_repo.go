@@ -14,21 +14,39 @@ import (
 
 // PgCardsRepo - репозиторий с данными банковских карт
 type PgCardsRepo struct {
-	db *pgx.Conn
+	db *tenantConn
 }
 
 // NewPgCardsRepo - инициализация репозитория
 func NewPgCardsRepo(db *pgx.Conn) (*PgCardsRepo, error) {
-	return &PgCardsRepo{db: db}, nil
+	return &PgCardsRepo{db: newTenantConn(db)}, nil
 }
 
-// GetAll - получить все сущности (при наличии прав у текущего пользователя)
+// cardsVisibleQuery - карты, доступные пользователю $1 (кроме записей в корзине): собственные личные записи, чужие записи, которыми с ним поделились
+// (приглашение принято), и записи коллекций организаций, в которых он состоит. Для чужих записей возвращаются ключ,
+// зашифрованный для пользователя (ключ записи или ключ коллекции нужной версии), и права пользователя
+const cardsVisibleQuery = `
+	SELECT id, number, cardholder, expirationdate, cvv, metadata, ownerid, COALESCE(entrykey, '') AS entrykey, '' AS permission, '' AS collectionid, keyversion
+	FROM Cards WHERE ownerid = $1 AND collectionid IS NULL AND deleted_at IS NULL
+	UNION ALL
+	SELECT c.id, c.number, c.cardholder, c.expirationdate, c.cvv, c.metadata, c.ownerid, g.entrykey, g.permission, '', c.keyversion
+	FROM Cards c JOIN share_grants g ON g.entity_type = 'card' AND g.entity_id = c.id
+	WHERE g.recipient_id = $1 AND g.status = 'accepted' AND c.deleted_at IS NULL
+	UNION ALL
+	SELECT c.id, c.number, c.cardholder, c.expirationdate, c.cvv, c.metadata, c.ownerid, k.encrypted_key,
+		CASE WHEN m.role = 'readonly' THEN 'read' ELSE 'write' END, c.collectionid::text, c.keyversion
+	FROM Cards c JOIN collections col ON col.id = c.collectionid
+	JOIN org_members m ON m.org_id = col.org_id AND m.login = $1 AND m.status = 'active'
+	JOIN collection_keys k ON k.collection_id = col.id AND k.login = $1 AND k.version = c.keyversion
+	WHERE c.deleted_at IS NULL`
+
+// GetAll - получить все доступные текущему пользователю сущности
 func (r *PgCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, error) {
 	userID := customcontext.GetUserID((ctx))
 
-	rows, err := r.db.Query(ctx, "SELECT id, number, cardholder, expirationdate, cvv, metadata, ownerid FROM Cards WHERE ownerid = $1", userID)
+	rows, err := r.db.Query(ctx, cardsVisibleQuery, userID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get cards: %w", err)
 	}
 
 	defer rows.Close()
@@ -40,7 +58,7 @@ func (r *PgCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, e
 	var cards []entities.CardInformation
 	for rows.Next() {
 		var card entities.CardInformation
-		err := rows.Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata, &card.OwnerID)
+		err := rows.Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata, &card.OwnerID, &card.EntryKey, &card.Permission, &card.CollectionID, &card.KeyVersion)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan card: %w", err)
 		}
@@ -50,42 +68,50 @@ func (r *PgCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, e
 	return cards, nil
 }
 
-// Get - получить сущность по ИД (при наличии прав у текущего пользователя)
+// Get - получить сущность по ИД (если она доступна текущему пользователю)
 func (r *PgCardsRepo) Get(ctx context.Context, id string) (*entities.CardInformation, error) {
 	userID := customcontext.GetUserID((ctx))
 
 	var card entities.CardInformation
-	err := r.db.QueryRow(ctx, "SELECT id, number, cardholder, expirationdate, cvv, metadata, ownerid FROM Cards WHERE id = $1 AND ownerid = $2", id, userID).Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata, &card.OwnerID)
+	err := r.db.QueryRow(ctx, "SELECT * FROM ("+cardsVisibleQuery+") AS visible WHERE id = $2", userID, id).Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata, &card.OwnerID, &card.EntryKey, &card.Permission, &card.CollectionID, &card.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil // Запись не найдена
 		}
-		return nil, fmt.Errorf("failed to get entity: %w", err)
+		return nil, fmt.Errorf("failed to get card: %w", err)
 	}
 
 	return &card, nil
 }
 
-// Create - создать сущность
+// Create - создать сущность (личную или в коллекции организации) и вернуть её в представлении текущего пользователя
 func (r *PgCardsRepo) Create(ctx context.Context, card *dtos.NewCardInformation) (*entities.CardInformation, error) {
 	userID := customcontext.GetUserID(ctx)
 
-	var entity entities.CardInformation
-	err := r.db.QueryRow(ctx, "INSERT INTO Cards (number, cardholder, expirationdate, cvv, metadata, ownerid) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, number, cardholder, expirationdate, cvv, metadata, ownerid", card.Number, card.CardHolder, card.ExpirationDate, card.CVV, card.Metadata, userID).Scan(&entity.ID, &entity.Number, &entity.CardHolder, &entity.ExpirationDate, &entity.CVV, &entity.Metadata, &entity.OwnerID)
+	query := "INSERT INTO Cards (id, number, cardholder, expirationdate, cvv, metadata, ownerid, collectionid, keyversion) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::integer, $9) RETURNING id"
 
+	var id string
+	err := r.db.QueryRow(ctx, query, card.ID, card.Number, card.CardHolder, card.ExpirationDate, card.CVV, card.Metadata, userID, card.CollectionID, card.KeyVersion).Scan(&id)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create card: %w", err)
 	}
-	return &entity, nil
+
+	return r.Get(ctx, id)
 }
 
-// Update - изменить сущность
+// Update - изменить сущность. Права проверяются политикой доступа до вызова:
+// пустой ключ записи оставляет прежний, версия ключа меняется только у записей коллекций
 func (r *PgCardsRepo) Update(ctx context.Context, card *entities.CardInformation) (*entities.CardInformation, error) {
-	userID := customcontext.GetUserID(ctx)
+	query := `
+		UPDATE Cards SET number = $2, cardholder = $3, expirationdate = $4, cvv = $5, metadata = $6,
+			entrykey = COALESCE(NULLIF($7, ''), entrykey),
+			keyversion = CASE WHEN collectionid IS NULL THEN keyversion ELSE $8 END
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id`
 
-	var updatedEntity entities.CardInformation
-	err := r.db.QueryRow(ctx, "UPDATE Cards SET number = $2, cardholder = $3, expirationdate = $4, cvv = $5, metadata = $6 WHERE id = $1 AND ownerid = $7 RETURNING id, number, cardholder, expirationdate, cvv, metadata, ownerid", card.ID, card.Number, card.CardHolder, card.ExpirationDate, card.CVV, card.Metadata, userID).Scan(&updatedEntity.ID, &updatedEntity.Number, &updatedEntity.CardHolder, &updatedEntity.ExpirationDate, &updatedEntity.CVV, &updatedEntity.Metadata, &updatedEntity.OwnerID)
+	var id string
+	err := r.db.QueryRow(ctx, query, card.ID, card.Number, card.CardHolder, card.ExpirationDate, card.CVV, card.Metadata, card.EntryKey, card.KeyVersion).Scan(&id)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -94,15 +120,18 @@ func (r *PgCardsRepo) Update(ctx context.Context, card *entities.CardInformation
 		return nil, err
 	}
 
-	return &updatedEntity, nil
+	return r.Get(ctx, id)
 }
 
-// Delete - удалить сущность
+// Delete - переместить сущность в корзину. Выданные на неё права сохраняются, но не действуют, пока запись в корзине.
+// Права проверяются политикой доступа до вызова
 func (r *PgCardsRepo) Delete(ctx context.Context, id string) (*entities.CardInformation, error) {
-	userID := customcontext.GetUserID((ctx))
+	query := `
+		UPDATE Cards SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, number, cardholder, expirationdate, cvv, metadata, ownerid, COALESCE(entrykey, '') AS entrykey, '' AS permission, COALESCE(collectionid::text, '') AS collectionid, keyversion`
 
-	var deletedCard entities.CardInformation
-	err := r.db.QueryRow(ctx, "DELETE FROM Cards WHERE id = $1 AND ownerid = $2 RETURNING id, number, cardholder, expirationdate, cvv, metadata, ownerid", id, userID).Scan(&deletedCard.ID, &deletedCard.Number, &deletedCard.CardHolder, &deletedCard.ExpirationDate, &deletedCard.CVV, &deletedCard.Metadata, &deletedCard.OwnerID)
+	var deleted entities.CardInformation
+	err := r.db.QueryRow(ctx, query, id).Scan(&deleted.ID, &deleted.Number, &deleted.CardHolder, &deleted.ExpirationDate, &deleted.CVV, &deleted.Metadata, &deleted.OwnerID, &deleted.EntryKey, &deleted.Permission, &deleted.CollectionID, &deleted.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -111,5 +140,5 @@ func (r *PgCardsRepo) Delete(ctx context.Context, id string) (*entities.CardInfo
 		return nil, err
 	}
 
-	return &deletedCard, err
+	return &deleted, nil
 }
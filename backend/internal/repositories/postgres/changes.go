@@ -0,0 +1,181 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
+	"github.com/jackc/pgx/v5"
+)
+
+// ChangesChannel - канал LISTEN/NOTIFY, через который экземпляры сервера обмениваются событиями об изменениях
+const ChangesChannel = "gophkeeper_changes"
+
+// Задержки перед повторным подключением слушателя
+const (
+	minListenRetryDelay = time.Second
+	maxListenRetryDelay = 30 * time.Second
+)
+
+// notifyTimeout - ограничение на отправку одного уведомления
+const notifyTimeout = 5 * time.Second
+
+// changePayload - событие об изменении в том виде, в котором оно передаётся через pg_notify
+type changePayload struct {
+	UserID     string    `json:"user_id"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// encodeChange - сериализовать событие для pg_notify
+func encodeChange(userID string, event notifications.Event) (string, error) {
+	payload, err := json.Marshal(changePayload{
+		UserID:     userID,
+		SessionID:  event.SessionID,
+		Action:     event.Action,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		OccurredAt: event.OccurredAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(payload), nil
+}
+
+// decodeChange - разобрать событие, полученное через LISTEN
+func decodeChange(payload string) (string, notifications.Event, error) {
+	var change changePayload
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return "", notifications.Event{}, err
+	}
+
+	if change.UserID == "" {
+		return "", notifications.Event{}, fmt.Errorf("user_id is missing")
+	}
+
+	return change.UserID, notifications.Event{
+		Action:     change.Action,
+		EntityType: change.EntityType,
+		EntityID:   change.EntityID,
+		OccurredAt: change.OccurredAt,
+		SessionID:  change.SessionID,
+	}, nil
+}
+
+// PgChangeNotifier - отправляет события об изменениях всем экземплярам сервера через pg_notify.
+// Использует основное подключение, поэтому должен вызываться из того же потока, что и репозитории (очередь задач сервиса)
+type PgChangeNotifier struct {
+	db *pgx.Conn
+}
+
+// NewPgChangeNotifier - создать отправителя событий
+func NewPgChangeNotifier(db *pgx.Conn) *PgChangeNotifier {
+	return &PgChangeNotifier{db: db}
+}
+
+// Publish - отправить событие (реализация services.ChangeNotifier)
+func (n *PgChangeNotifier) Publish(userID string, event notifications.Event) {
+	payload, err := encodeChange(userID, event)
+	if err != nil {
+		log.Printf("failed to encode change event: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	// Уведомление доставляется и слушателю этого же экземпляра - локальные клиенты получают событие тем же путём
+	if _, err := n.db.Exec(ctx, "SELECT pg_notify($1, $2)", ChangesChannel, payload); err != nil {
+		log.Printf("failed to publish change event: %v", err)
+	}
+}
+
+// ChangeListener - получает события об изменениях от всех экземпляров сервера через отдельное подключение (LISTEN)
+// и передаёт их обработчику, например рассылке подключённым клиентам
+type ChangeListener struct {
+	connStr string
+	handle  func(userID string, event notifications.Event)
+	onGap   func()
+}
+
+// NewChangeListener - создать слушателя событий.
+// onGap (может быть nil) вызывается после восстановления потерянного подключения: события за время разрыва могли быть пропущены,
+// поэтому зависящие от них данные (открытые потоки клиентов, кэши) нужно сбросить
+func NewChangeListener(connStr string, handle func(userID string, event notifications.Event), onGap func()) *ChangeListener {
+	return &ChangeListener{
+		connStr: connStr,
+		handle:  handle,
+		onGap:   onGap,
+	}
+}
+
+// Run - слушать события до отмены контекста, переподключаясь при ошибках
+func (l *ChangeListener) Run(ctx context.Context) {
+	delay := minListenRetryDelay
+	subscribedBefore := false
+
+	for {
+		subscribed := false
+		err := l.listen(ctx, func() {
+			if subscribedBefore && l.onGap != nil {
+				l.onGap()
+			}
+			subscribedBefore, subscribed = true, true
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("change listener: %v", err)
+
+		// Подписка работала - переподключаемся с минимальной задержкой
+		if subscribed {
+			delay = minListenRetryDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxListenRetryDelay)
+	}
+}
+
+// listen - подключиться, подписаться на канал и обрабатывать уведомления до ошибки
+func (l *ChangeListener) listen(ctx context.Context, onSubscribed func()) error {
+	conn, err := pgx.Connect(ctx, l.connStr)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{ChangesChannel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	onSubscribed()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("connection lost: %w", err)
+		}
+
+		userID, event, err := decodeChange(notification.Payload)
+		if err != nil {
+			log.Printf("change listener: skipping malformed notification: %v", err)
+			continue
+		}
+
+		l.handle(userID, event)
+	}
+}
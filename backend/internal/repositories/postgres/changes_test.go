@@ -0,0 +1,45 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePayload(t *testing.T) {
+	event := notifications.Event{
+		Action:     notifications.ActionUpdate,
+		EntityType: "card",
+		EntityID:   "42",
+		OccurredAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		SessionID:  "session-1",
+	}
+
+	t.Run("Событие передаётся между экземплярами без потерь", func(t *testing.T) {
+		payload, err := encodeChange("user1", event)
+		require.NoError(t, err)
+
+		userID, decoded, err := decodeChange(payload)
+		require.NoError(t, err)
+		assert.Equal(t, "user1", userID)
+		assert.Equal(t, event, decoded)
+	})
+
+	t.Run("Ограничение размера pg_notify", func(t *testing.T) {
+		payload, err := encodeChange("user1", event)
+		require.NoError(t, err)
+		assert.Less(t, len(payload), 8000)
+	})
+
+	t.Run("Некорректные уведомления", func(t *testing.T) {
+		_, _, err := decodeChange("not json")
+		assert.Error(t, err)
+
+		_, _, err = decodeChange(`{"action":"create","entity_type":"text","entity_id":"1"}`)
+		assert.Error(t, err)
+	})
+}
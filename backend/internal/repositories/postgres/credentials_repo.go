@@ -14,19 +14,37 @@ import (
 
 // PgCredentialsRepo - репозиторий с учётными данными
 type PgCredentialsRepo struct {
-	db *pgx.Conn
+	db *tenantConn
 }
 
 // NewPgCredentialsRepo - инициализация репозитория
 func NewPgCredentialsRepo(db *pgx.Conn) (*PgCredentialsRepo, error) {
-	return &PgCredentialsRepo{db: db}, nil
+	return &PgCredentialsRepo{db: newTenantConn(db)}, nil
 }
 
-// GetAll - получить все сущности (при наличии прав у текущего пользователя)
+// credentialsVisibleQuery - учётные данные, доступные пользователю $1 (кроме записей в корзине): собственные личные записи, чужие записи, которыми с ним поделились
+// (приглашение принято), и записи коллекций организаций, в которых он состоит. Для чужих записей возвращаются ключ,
+// зашифрованный для пользователя (ключ записи или ключ коллекции нужной версии), и права пользователя
+const credentialsVisibleQuery = `
+	SELECT id, login, password, metadata, ownerid, COALESCE(entrykey, '') AS entrykey, '' AS permission, '' AS collectionid, keyversion
+	FROM Credentials WHERE ownerid = $1 AND collectionid IS NULL AND deleted_at IS NULL
+	UNION ALL
+	SELECT c.id, c.login, c.password, c.metadata, c.ownerid, g.entrykey, g.permission, '', c.keyversion
+	FROM Credentials c JOIN share_grants g ON g.entity_type = 'credentials' AND g.entity_id = c.id
+	WHERE g.recipient_id = $1 AND g.status = 'accepted' AND c.deleted_at IS NULL
+	UNION ALL
+	SELECT c.id, c.login, c.password, c.metadata, c.ownerid, k.encrypted_key,
+		CASE WHEN m.role = 'readonly' THEN 'read' ELSE 'write' END, c.collectionid::text, c.keyversion
+	FROM Credentials c JOIN collections col ON col.id = c.collectionid
+	JOIN org_members m ON m.org_id = col.org_id AND m.login = $1 AND m.status = 'active'
+	JOIN collection_keys k ON k.collection_id = col.id AND k.login = $1 AND k.version = c.keyversion
+	WHERE c.deleted_at IS NULL`
+
+// GetAll - получить все доступные текущему пользователю сущности
 func (r *PgCredentialsRepo) GetAll(ctx context.Context) ([]entities.Credentials, error) {
 	userID := customcontext.GetUserID((ctx))
 
-	rows, err := r.db.Query(ctx, "SELECT id, login, password, metadata, ownerid FROM Credentials WHERE ownerid = $1", userID)
+	rows, err := r.db.Query(ctx, credentialsVisibleQuery, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get credentials: %w", err)
 	}
@@ -39,23 +57,23 @@ func (r *PgCredentialsRepo) GetAll(ctx context.Context) ([]entities.Credentials,
 
 	var credentials []entities.Credentials
 	for rows.Next() {
-		var cred entities.Credentials
-		err := rows.Scan(&cred.ID, &cred.Login, &cred.Password, &cred.Metadata, &cred.OwnerID)
+		var creds entities.Credentials
+		err := rows.Scan(&creds.ID, &creds.Login, &creds.Password, &creds.Metadata, &creds.OwnerID, &creds.EntryKey, &creds.Permission, &creds.CollectionID, &creds.KeyVersion)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan credentials: %w", err)
 		}
-		credentials = append(credentials, cred)
+		credentials = append(credentials, creds)
 	}
 
 	return credentials, nil
 }
 
-// Get - получить сущность по ИД (при наличии прав у текущего пользователя)
+// Get - получить сущность по ИД (если она доступна текущему пользователю)
 func (r *PgCredentialsRepo) Get(ctx context.Context, id string) (*entities.Credentials, error) {
 	userID := customcontext.GetUserID((ctx))
 
-	var credentials entities.Credentials
-	err := r.db.QueryRow(ctx, "SELECT id, login, password, metadata, ownerid FROM Credentials WHERE id = $1 AND ownerID = $2", id, userID).Scan(&credentials.ID, &credentials.Login, &credentials.Password, &credentials.Metadata, &credentials.OwnerID)
+	var creds entities.Credentials
+	err := r.db.QueryRow(ctx, "SELECT * FROM ("+credentialsVisibleQuery+") AS visible WHERE id = $2", userID, id).Scan(&creds.ID, &creds.Login, &creds.Password, &creds.Metadata, &creds.OwnerID, &creds.EntryKey, &creds.Permission, &creds.CollectionID, &creds.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -64,28 +82,36 @@ func (r *PgCredentialsRepo) Get(ctx context.Context, id string) (*entities.Crede
 		return nil, fmt.Errorf("failed to get credentials: %w", err)
 	}
 
-	return &credentials, nil
+	return &creds, nil
 }
 
-// Create - создать сущность
+// Create - создать сущность (личную или в коллекции организации) и вернуть её в представлении текущего пользователя
 func (r *PgCredentialsRepo) Create(ctx context.Context, credentials *dtos.NewCredentials) (*entities.Credentials, error) {
 	userID := customcontext.GetUserID(ctx)
 
-	var entity entities.Credentials
-	err := r.db.QueryRow(ctx, "INSERT INTO Credentials (login, password, metadata, ownerid) VALUES ($1, $2, $3, $4) RETURNING id, login, password, metadata, ownerid", credentials.Login, credentials.Password, credentials.Metadata, userID).Scan(&entity.ID, &entity.Login, &entity.Password, &entity.Metadata, &entity.OwnerID)
+	query := "INSERT INTO Credentials (id, login, password, metadata, ownerid, collectionid, keyversion) VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::integer, $7) RETURNING id"
 
+	var id string
+	err := r.db.QueryRow(ctx, query, credentials.ID, credentials.Login, credentials.Password, credentials.Metadata, userID, credentials.CollectionID, credentials.KeyVersion).Scan(&id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create credentials: %w", err)
 	}
-	return &entity, nil
+
+	return r.Get(ctx, id)
 }
 
-// Update - изменить сущность
+// Update - изменить сущность. Права проверяются политикой доступа до вызова:
+// пустой ключ записи оставляет прежний, версия ключа меняется только у записей коллекций
 func (r *PgCredentialsRepo) Update(ctx context.Context, credentials *entities.Credentials) (*entities.Credentials, error) {
-	userID := customcontext.GetUserID(ctx)
+	query := `
+		UPDATE Credentials SET login = $2, password = $3, metadata = $4,
+			entrykey = COALESCE(NULLIF($5, ''), entrykey),
+			keyversion = CASE WHEN collectionid IS NULL THEN keyversion ELSE $6 END
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id`
 
-	var updatedEntity entities.Credentials
-	err := r.db.QueryRow(ctx, "UPDATE Credentials SET login = $2, password = $3, metadata = $4 WHERE id = $1 AND ownerid = $5 RETURNING id, login, password, metadata, ownerid", credentials.ID, credentials.Login, credentials.Password, credentials.Metadata, userID).Scan(&updatedEntity.ID, &updatedEntity.Login, &updatedEntity.Password, &updatedEntity.Metadata, &updatedEntity.OwnerID)
+	var id string
+	err := r.db.QueryRow(ctx, query, credentials.ID, credentials.Login, credentials.Password, credentials.Metadata, credentials.EntryKey, credentials.KeyVersion).Scan(&id)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -94,15 +120,18 @@ func (r *PgCredentialsRepo) Update(ctx context.Context, credentials *entities.Cr
 		return nil, err
 	}
 
-	return &updatedEntity, nil
+	return r.Get(ctx, id)
 }
 
-// Delete - удалить сущность
+// Delete - переместить сущность в корзину. Выданные на неё права сохраняются, но не действуют, пока запись в корзине.
+// Права проверяются политикой доступа до вызова
 func (r *PgCredentialsRepo) Delete(ctx context.Context, id string) (*entities.Credentials, error) {
-	userID := customcontext.GetUserID((ctx))
+	query := `
+		UPDATE Credentials SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, login, password, metadata, ownerid, COALESCE(entrykey, '') AS entrykey, '' AS permission, COALESCE(collectionid::text, '') AS collectionid, keyversion`
 
-	var deletedCredentials entities.Credentials
-	err := r.db.QueryRow(ctx, "DELETE FROM Credentials WHERE id = $1 AND ownerid = $2 RETURNING id, login, password, metadata, ownerid", id, userID).Scan(&deletedCredentials.ID, &deletedCredentials.Login, &deletedCredentials.Password, &deletedCredentials.Metadata, &deletedCredentials.OwnerID)
+	var deleted entities.Credentials
+	err := r.db.QueryRow(ctx, query, id).Scan(&deleted.ID, &deleted.Login, &deleted.Password, &deleted.Metadata, &deleted.OwnerID, &deleted.EntryKey, &deleted.Permission, &deleted.CollectionID, &deleted.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -111,5 +140,5 @@ func (r *PgCredentialsRepo) Delete(ctx context.Context, id string) (*entities.Cr
 		return nil, err
 	}
 
-	return &deletedCredentials, err
+	return &deleted, nil
 }
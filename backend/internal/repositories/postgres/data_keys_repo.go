@@ -0,0 +1,94 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// PgDataKeysRepo - ключи данных пользователей, зашифрованные мастер-ключом
+type PgDataKeysRepo struct {
+	db *tenantConn
+}
+
+// NewPgDataKeysRepo - инициализация репозитория
+func NewPgDataKeysRepo(db *pgx.Conn) (*PgDataKeysRepo, error) {
+	return &PgDataKeysRepo{db: newTenantConn(db)}, nil
+}
+
+// Get - ключ данных пользователя
+func (r *PgDataKeysRepo) Get(ctx context.Context, login string) (*entities.DataKey, error) {
+	var key entities.DataKey
+	err := r.db.QueryRow(ctx, "SELECT login, wrapped_key FROM data_keys WHERE login = $1", login).Scan(&key.Login, &key.WrappedKey)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Ключа ещё нет
+		}
+		return nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// GetAll - ключи данных всех пользователей
+func (r *PgDataKeysRepo) GetAll(ctx context.Context) ([]entities.DataKey, error) {
+	rows, err := r.db.Query(ctx, "SELECT login, wrapped_key FROM data_keys ORDER BY login")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []entities.DataKey
+	for rows.Next() {
+		var key entities.DataKey
+		if err := rows.Scan(&key.Login, &key.WrappedKey); err != nil {
+			return nil, fmt.Errorf("failed to scan data key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Create - сохранить ключ данных, если у пользователя его ещё нет (иначе вернуть существующий)
+func (r *PgDataKeysRepo) Create(ctx context.Context, key *entities.DataKey) (*entities.DataKey, error) {
+	query := `
+		INSERT INTO data_keys (login, wrapped_key) VALUES ($1, $2)
+		ON CONFLICT (login) DO NOTHING`
+
+	if _, err := r.db.Exec(ctx, query, key.Login, key.WrappedKey); err != nil {
+		return nil, fmt.Errorf("failed to create data key: %w", err)
+	}
+
+	return r.Get(ctx, key.Login)
+}
+
+// Update - заменить зашифрованный ключ данных
+func (r *PgDataKeysRepo) Update(ctx context.Context, key *entities.DataKey) (*entities.DataKey, error) {
+	var updated entities.DataKey
+	err := r.db.QueryRow(ctx, "UPDATE data_keys SET wrapped_key = $2, rotated_at = NOW() WHERE login = $1 RETURNING login, wrapped_key",
+		key.Login, key.WrappedKey).Scan(&updated.Login, &updated.WrappedKey)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Ключа нет
+		}
+		return nil, fmt.Errorf("failed to update data key: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// Delete - удалить ключ данных пользователя
+func (r *PgDataKeysRepo) Delete(ctx context.Context, login string) error {
+	if _, err := r.db.Exec(ctx, "DELETE FROM data_keys WHERE login = $1", login); err != nil {
+		return fmt.Errorf("failed to delete data key: %w", err)
+	}
+
+	return nil
+}
@@ -25,6 +25,23 @@ type DatabaseManager struct {
 	CredentialsRepo *PgCredentialsRepo
 	TextsRepo       *PgTextsRepo
 	UsersRepo       *PgUsersRepo
+	AuditRepo       *PgAuditRepo
+	UserKeysRepo    *PgUserKeysRepo
+	ShareRepo       *PgShareRepo
+	AccessRepo      *PgAccessRepo
+	OrgRepo         *PgOrganizationRepo
+	EmergencyRepo   *PgEmergencyAccessRepo
+	AccountRepo     *PgAccountRepo
+	InviteRepo      *PgInviteRepo
+	TrashRepo       *PgTrashRepo
+	HistoryRepo     *PgHistoryRepo
+	DataKeysRepo    *PgDataKeysRepo
+}
+
+// InTransaction - выполнить fn в одной транзакции: репозитории менеджера, вызванные с контекстом fn, работают в ней,
+// а пользователь для политик строк записывается в транзакцию один раз
+func (m *DatabaseManager) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return inTransaction(ctx, m.DB, fn)
 }
 
 func InitDatabase(connStr string) (*pgx.Conn, error) {
@@ -54,8 +71,18 @@ func InitDatabase(connStr string) (*pgx.Conn, error) {
 		}
 	}
 
-	// Подключение к базе данных
-	db, err := pgx.Connect(context.Background(), connStr)
+	return connect(connStr)
+}
+
+// connect - подключиться к существующей базе данных (каждый запрос трассируется)
+func connect(connStr string) (*pgx.Conn, error) {
+	connConfig, err := pgx.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	connConfig.Tracer = queryTracer{}
+
+	db, err := pgx.ConnectConfig(context.Background(), connConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -68,16 +95,31 @@ func InitDatabase(connStr string) (*pgx.Conn, error) {
 	return db, nil
 }
 
-func NewDatabaseManager(connStr string) (*DatabaseManager, error) {
-	db, err := InitDatabase(connStr)
+// Ping - проверить доступность базы данных через отдельное кратковременное подключение.
+// Основное подключение обслуживает очередь задач и не допускает конкурентного использования
+func Ping(ctx context.Context, connStr string) error {
+	conn, err := pgx.Connect(ctx, connStr)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
+	defer conn.Close(context.Background())
 
-	// Запускаем миграции
-	migrator := migrations.NewMigrator(db)
-	if err := migrator.Migrate(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	return conn.Ping(ctx)
+}
+
+// NewDatabaseManager - подключиться к базе данных, применить миграции и создать репозитории.
+// Если задан ownerConnStr, базу создаёт и мигрирует владелец схемы, а репозитории работают от имени роли из connStr,
+// которой выдаются права на данные: такая роль не может обойти политики строк
+func NewDatabaseManager(connStr, ownerConnStr string) (*DatabaseManager, error) {
+	var db *pgx.Conn
+	var err error
+	if ownerConnStr == "" {
+		db, err = migrateDatabase(connStr)
+	} else {
+		db, err = initTenantDatabase(connStr, ownerConnStr)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	binariesRepo, err := NewPgBinariesRepo(db)
@@ -100,6 +142,50 @@ func NewDatabaseManager(connStr string) (*DatabaseManager, error) {
 	if err != nil {
 		return nil, err
 	}
+	auditRepo, err := NewPgAuditRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	userKeysRepo, err := NewPgUserKeysRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	shareRepo, err := NewPgShareRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	accessRepo, err := NewPgAccessRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	orgRepo, err := NewPgOrganizationRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	emergencyRepo, err := NewPgEmergencyAccessRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	accountRepo, err := NewPgAccountRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	inviteRepo, err := NewPgInviteRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	trashRepo, err := NewPgTrashRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	historyRepo, err := NewPgHistoryRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	dataKeysRepo, err := NewPgDataKeysRepo(db)
+	if err != nil {
+		return nil, err
+	}
 
 	dbManager := DatabaseManager{
 		DB:              db,
@@ -108,11 +194,108 @@ func NewDatabaseManager(connStr string) (*DatabaseManager, error) {
 		CredentialsRepo: credentialsRepo,
 		TextsRepo:       textsRepo,
 		UsersRepo:       usersRepo,
+		AuditRepo:       auditRepo,
+		UserKeysRepo:    userKeysRepo,
+		ShareRepo:       shareRepo,
+		AccessRepo:      accessRepo,
+		OrgRepo:         orgRepo,
+		EmergencyRepo:   emergencyRepo,
+		AccountRepo:     accountRepo,
+		InviteRepo:      inviteRepo,
+		TrashRepo:       trashRepo,
+		HistoryRepo:     historyRepo,
+		DataKeysRepo:    dataKeysRepo,
 	}
 
 	return &dbManager, nil
 }
 
+// initTenantDatabase - создать и мигрировать базу от имени владельца схемы, выдать роли connStr права на данные
+// и подключиться от её имени
+func initTenantDatabase(connStr, ownerConnStr string) (*pgx.Conn, error) {
+	ctx := context.Background()
+
+	owner, err := migrateDatabase(ownerConnStr)
+	if err != nil {
+		return nil, err
+	}
+	defer owner.Close(ctx)
+
+	db, err := connect(connStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var role, ownerRole string
+	if err := db.QueryRow(ctx, "SELECT current_user").Scan(&role); err != nil {
+		db.Close(ctx)
+		return nil, fmt.Errorf("failed to get database role: %w", err)
+	}
+	if err := owner.QueryRow(ctx, "SELECT current_user").Scan(&ownerRole); err != nil {
+		db.Close(ctx)
+		return nil, fmt.Errorf("failed to get database role: %w", err)
+	}
+
+	// Та же роль уже владеет схемой: отдельных прав ей не нужно
+	if role == ownerRole {
+		return db, nil
+	}
+
+	if err := grantTenantRole(ctx, owner, role); err != nil {
+		db.Close(ctx)
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// migrateDatabase - подключиться к базе данных (создав её при необходимости) и применить миграции
+func migrateDatabase(connStr string) (*pgx.Conn, error) {
+	db, err := InitDatabase(connStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запускаем миграции
+	if err := migrations.NewMigrator(db).Migrate(context.Background()); err != nil {
+		db.Close(context.Background())
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+// grantTenantRole - выдать роли сервера права на данные схемы, не делая её владельцем: чтение и изменение строк всех таблиц
+// (таблицу миграций - только читать) и счётчики идентификаторов. Права выдаются при каждом запуске после миграций,
+// поэтому распространяются и на новые таблицы
+func grantTenantRole(ctx context.Context, owner *pgx.Conn, role string) error {
+	tx, err := owner.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	var schema string
+	if err := tx.QueryRow(ctx, "SELECT current_schema()").Scan(&schema); err != nil {
+		return fmt.Errorf("failed to get schema: %w", err)
+	}
+
+	schemaName, roleName := pgx.Identifier{schema}.Sanitize(), pgx.Identifier{role}.Sanitize()
+	statements := []string{
+		"GRANT USAGE ON SCHEMA " + schemaName + " TO " + roleName,
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA " + schemaName + " TO " + roleName,
+		"REVOKE INSERT, UPDATE, DELETE ON " + migrationsTable + " FROM " + roleName,
+		"GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA " + schemaName + " TO " + roleName,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to grant privileges to role %s: %w", role, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
 // Только чтобы не возиться с кучей параметров при запуске приложения
 // extractDBName - получить название базы данных из строки подключения
 func extractConnectionConfig(connStr string) *connectionConfig {
@@ -0,0 +1,147 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// emergencyAccessColumns - поля экстренного доступа в порядке сканирования
+const emergencyAccessColumns = "id, grantor_id, grantee_id, status, wait_days, encrypted_key, requested_at, created_at"
+
+// PgEmergencyAccessRepo - экстренный доступ доверенных лиц к хранилищам пользователей
+type PgEmergencyAccessRepo struct {
+	db *tenantConn
+}
+
+// NewPgEmergencyAccessRepo - инициализация репозитория
+func NewPgEmergencyAccessRepo(db *pgx.Conn) (*PgEmergencyAccessRepo, error) {
+	return &PgEmergencyAccessRepo{db: newTenantConn(db)}, nil
+}
+
+// Create - назначить доверенное лицо от имени текущего пользователя (nil, если оно уже назначено)
+func (r *PgEmergencyAccessRepo) Create(ctx context.Context, access *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	query := `
+		INSERT INTO emergency_access (grantor_id, grantee_id, wait_days, encrypted_key) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (grantor_id, grantee_id) DO NOTHING
+		RETURNING ` + emergencyAccessColumns
+
+	created, err := scanEmergencyAccess(r.db.QueryRow(ctx, query, userID, access.GranteeID, access.WaitDays, access.EncryptedKey))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Доверенное лицо уже назначено
+		}
+		return nil, fmt.Errorf("failed to create emergency access: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetAll - получить экстренные доступы, в которых текущий пользователь доверитель или доверенное лицо
+func (r *PgEmergencyAccessRepo) GetAll(ctx context.Context) ([]entities.EmergencyAccess, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	rows, err := r.db.Query(ctx, "SELECT "+emergencyAccessColumns+" FROM emergency_access WHERE grantor_id = $1 OR grantee_id = $1 ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emergency access: %w", err)
+	}
+
+	return scanEmergencyAccessRows(rows)
+}
+
+// Get - получить экстренный доступ по ИД (nil, если его нет)
+func (r *PgEmergencyAccessRepo) Get(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	access, err := scanEmergencyAccess(r.db.QueryRow(ctx, "SELECT "+emergencyAccessColumns+" FROM emergency_access WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Экстренный доступ не найден
+		}
+		return nil, fmt.Errorf("failed to get emergency access: %w", err)
+	}
+
+	return access, nil
+}
+
+// SetStatus - перевести доступ из состояния from в состояние to (nil, если он уже не в состоянии from).
+// При запросе доступа запоминается время запроса, при возврате к согласию оно сбрасывается
+func (r *PgEmergencyAccessRepo) SetStatus(ctx context.Context, id, from, to string) (*entities.EmergencyAccess, error) {
+	query := `
+		UPDATE emergency_access SET status = $3,
+			requested_at = CASE $3 WHEN 'requested' THEN NOW() WHEN 'accepted' THEN NULL ELSE requested_at END
+		WHERE id = $1 AND status = $2
+		RETURNING ` + emergencyAccessColumns
+
+	access, err := scanEmergencyAccess(r.db.QueryRow(ctx, query, id, from, to))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Доступ не найден или уже в другом состоянии
+		}
+		return nil, fmt.Errorf("failed to update emergency access: %w", err)
+	}
+
+	return access, nil
+}
+
+// GrantElapsed - предоставить доступ по запросам, период ожидания которых истёк к моменту now
+func (r *PgEmergencyAccessRepo) GrantElapsed(ctx context.Context, now time.Time) ([]entities.EmergencyAccess, error) {
+	query := `
+		UPDATE emergency_access SET status = 'granted'
+		WHERE status = 'requested' AND requested_at + make_interval(days => wait_days) <= $1
+		RETURNING ` + emergencyAccessColumns
+
+	rows, err := r.db.Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant emergency access: %w", err)
+	}
+
+	return scanEmergencyAccessRows(rows)
+}
+
+// Delete - удалить экстренный доступ
+func (r *PgEmergencyAccessRepo) Delete(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	access, err := scanEmergencyAccess(r.db.QueryRow(ctx, "DELETE FROM emergency_access WHERE id = $1 RETURNING "+emergencyAccessColumns, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Экстренный доступ не найден
+		}
+		return nil, fmt.Errorf("failed to delete emergency access: %w", err)
+	}
+
+	return access, nil
+}
+
+// scanEmergencyAccess - прочитать экстренный доступ из строки результата
+func scanEmergencyAccess(row pgx.Row) (*entities.EmergencyAccess, error) {
+	var access entities.EmergencyAccess
+	err := row.Scan(&access.ID, &access.GrantorID, &access.GranteeID, &access.Status, &access.WaitDays, &access.EncryptedKey, &access.RequestedAt, &access.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &access, nil
+}
+
+// scanEmergencyAccessRows - прочитать все экстренные доступы из результата запроса
+func scanEmergencyAccessRows(rows pgx.Rows) ([]entities.EmergencyAccess, error) {
+	defer rows.Close()
+
+	var result []entities.EmergencyAccess
+	for rows.Next() {
+		access, err := scanEmergencyAccess(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan emergency access: %w", err)
+		}
+		result = append(result, *access)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,146 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// historyContent - поля записи каждого типа, сохраняемые в версии, в виде JSON с именами полей сущности
+var historyContent = map[string]string{
+	"binary":      "jsonb_build_object('data', encode(e.data, 'base64'))",
+	"card":        "jsonb_build_object('number', e.number, 'card_holder', e.cardholder, 'expiration_date', e.expirationdate::text, 'cvv', e.cvv)",
+	"credentials": "jsonb_build_object('login', e.login, 'password', e.password)",
+	"text":        "jsonb_build_object('data', e.data)",
+}
+
+// PgHistoryRepo - история записей всех типов
+type PgHistoryRepo struct {
+	db *tenantConn
+}
+
+// NewPgHistoryRepo - инициализация репозитория
+func NewPgHistoryRepo(db *pgx.Conn) (*PgHistoryRepo, error) {
+	return &PgHistoryRepo{db: newTenantConn(db)}, nil
+}
+
+// historyQuery - версии записи $2 таблицы table в представлении пользователя $1: владельцу личной записи версия отдаётся
+// с ключом, которым она зашифрована, получателю - с ключом из приглашения, если версия зашифрована текущим ключом записи,
+// участнику организации - с ключом коллекции нужной версии. Если расшифровать версию пользователю нечем, entrykey - NULL.
+// Столбцы идут в порядке полей scanRevision
+func historyQuery(entityType, table string) string {
+	return `
+	SELECT h.revision, h.content, COALESCE(h.metadata, '') AS metadata,
+		CASE
+			WHEN e.collectionid IS NOT NULL THEN k.encrypted_key
+			WHEN e.ownerid = $1 THEN COALESCE(h.entrykey, '')
+			WHEN COALESCE(h.entrykey, '') <> '' AND h.entrykey = e.entrykey THEN g.entrykey
+		END AS entrykey,
+		h.keyversion,
+		CASE
+			WHEN e.collectionid IS NOT NULL THEN h.keyversion = e.keyversion
+			ELSE COALESCE(h.entrykey, '') = COALESCE(e.entrykey, '')
+		END AS restorable,
+		h.replaced_by, h.replaced_at
+	FROM entry_history h
+	JOIN ` + table + ` e ON e.id = h.entity_id AND e.deleted_at IS NULL
+	LEFT JOIN share_grants g ON g.entity_type = h.entity_type AND g.entity_id = e.id AND g.recipient_id = $1 AND g.status = 'accepted'
+	LEFT JOIN collections col ON col.id = e.collectionid
+	LEFT JOIN org_members m ON m.org_id = col.org_id AND m.login = $1 AND m.status = 'active'
+	LEFT JOIN collection_keys k ON k.collection_id = col.id AND k.login = m.login AND k.version = h.keyversion
+	WHERE h.entity_type = '` + entityType + `' AND h.entity_id = $2`
+}
+
+// Append - сохранить текущее состояние записи как новую версию, удалить версии сверх ограничений и изменить запись.
+// Версия и изменение записи фиксируются в одной транзакции (в транзакции задачи, если она открыта)
+func (r *PgHistoryRepo) Append(ctx context.Context, entityType, id string, maxVersions int, notBefore time.Time, update func(ctx context.Context) error) error {
+	table, known := sharableTables[entityType]
+	if !known {
+		return fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	query := `
+		WITH archived AS (
+			INSERT INTO entry_history (entity_type, entity_id, revision, owner_id, content, metadata, entrykey, keyversion, replaced_by)
+			SELECT $1, e.id,
+				COALESCE((SELECT MAX(h.revision) FROM entry_history h WHERE h.entity_type = $1 AND h.entity_id = e.id), 0) + 1,
+				e.ownerid, ` + historyContent[entityType] + `, e.metadata, e.entrykey, e.keyversion, $3
+			FROM ` + table + ` e WHERE e.id = $2 AND e.deleted_at IS NULL
+			RETURNING revision
+		)
+		DELETE FROM entry_history h USING archived a
+		WHERE h.entity_type = $1 AND h.entity_id = $2
+			AND (($4 > 0 AND h.revision <= a.revision - $4) OR h.replaced_at < $5)`
+
+	return inTransaction(ctx, r.db.conn, func(ctx context.Context) error {
+		if _, err := r.db.Exec(ctx, query, entityType, id, customcontext.GetUserID(ctx), maxVersions, notBefore); err != nil {
+			return fmt.Errorf("failed to append revision: %w", err)
+		}
+
+		return update(ctx)
+	})
+}
+
+// GetAll - версии записи, которые текущий пользователь может расшифровать (сначала последние)
+func (r *PgHistoryRepo) GetAll(ctx context.Context, entityType, id string) ([]entities.Revision, error) {
+	table, known := sharableTables[entityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	query := "SELECT * FROM (" + historyQuery(entityType, table) + ") AS history WHERE entrykey IS NOT NULL ORDER BY revision DESC"
+
+	rows, err := r.db.Query(ctx, query, customcontext.GetUserID(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []entities.Revision
+	for rows.Next() {
+		revision, err := scanRevision(rows, entityType, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %w", err)
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// Get - версия записи в представлении текущего пользователя
+func (r *PgHistoryRepo) Get(ctx context.Context, entityType, id string, revision int) (*entities.Revision, error) {
+	table, known := sharableTables[entityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	query := "SELECT * FROM (" + historyQuery(entityType, table) + ") AS history WHERE entrykey IS NOT NULL AND revision = $3"
+
+	found, err := scanRevision(r.db.QueryRow(ctx, query, customcontext.GetUserID(ctx), id, revision), entityType, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Версии нет
+		}
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+
+	return found, nil
+}
+
+// scanRevision - прочитать версию записи из строки результата historyQuery
+func scanRevision(row pgx.Row, entityType, id string) (*entities.Revision, error) {
+	revision := entities.Revision{EntityType: entityType, EntityID: id}
+	err := row.Scan(&revision.Revision, &revision.Content, &revision.Metadata, &revision.EntryKey, &revision.KeyVersion,
+		&revision.Restorable, &revision.ReplacedBy, &revision.ReplacedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
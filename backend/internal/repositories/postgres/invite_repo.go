@@ -0,0 +1,113 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// inviteColumns - столбцы приглашения в порядке полей scanInvite
+const inviteColumns = "code, max_uses, uses, login_pattern, expires_at, created_at"
+
+// PgInviteRepo - коды приглашений для регистрации
+type PgInviteRepo struct {
+	db *tenantConn
+}
+
+// NewPgInviteRepo - инициализация репозитория
+func NewPgInviteRepo(db *pgx.Conn) (*PgInviteRepo, error) {
+	return &PgInviteRepo{db: newTenantConn(db)}, nil
+}
+
+// Create - сохранить приглашение с кодом, выпущенным сервисом
+func (r *PgInviteRepo) Create(ctx context.Context, code string, invite *dtos.NewInvite) (*entities.Invite, error) {
+	query := `
+		INSERT INTO invites (code, max_uses, login_pattern, expires_at) VALUES ($1, $2, $3, $4)
+		RETURNING ` + inviteColumns
+
+	created, err := scanInvite(r.db.QueryRow(ctx, query, code, invite.MaxUses, invite.LoginPattern, invite.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetAll - получить все приглашения (в порядке выпуска)
+func (r *PgInviteRepo) GetAll(ctx context.Context) ([]entities.Invite, error) {
+	rows, err := r.db.Query(ctx, "SELECT "+inviteColumns+" FROM invites ORDER BY created_at, code")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invites: %w", err)
+	}
+	defer rows.Close()
+
+	var invites []entities.Invite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+		invites = append(invites, *invite)
+	}
+
+	return invites, rows.Err()
+}
+
+// Get - получить приглашение по коду
+func (r *PgInviteRepo) Get(ctx context.Context, code string) (*entities.Invite, error) {
+	invite, err := scanInvite(r.db.QueryRow(ctx, "SELECT "+inviteColumns+" FROM invites WHERE code = $1", code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Приглашения нет
+		}
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+// Use - засчитать использование приглашения одним запросом: два экземпляра сервера не израсходуют одно использование дважды
+func (r *PgInviteRepo) Use(ctx context.Context, code string, now time.Time) (*entities.Invite, error) {
+	query := `
+		UPDATE invites SET uses = uses + 1
+		WHERE code = $1 AND uses < max_uses AND expires_at > $2
+		RETURNING ` + inviteColumns
+
+	invite, err := scanInvite(r.db.QueryRow(ctx, query, code, now))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Приглашение истекло, исчерпано или отозвано
+		}
+		return nil, fmt.Errorf("failed to use invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+// Delete - отозвать приглашение
+func (r *PgInviteRepo) Delete(ctx context.Context, code string) (*entities.Invite, error) {
+	invite, err := scanInvite(r.db.QueryRow(ctx, "DELETE FROM invites WHERE code = $1 RETURNING "+inviteColumns, code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to delete invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+// scanInvite - прочитать приглашение из строки результата
+func scanInvite(row pgx.Row) (*entities.Invite, error) {
+	var invite entities.Invite
+	if err := row.Scan(&invite.Code, &invite.MaxUses, &invite.Uses, &invite.LoginPattern, &invite.ExpiresAt, &invite.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS Texts;
+DROP TABLE IF EXISTS Credentials;
+DROP TABLE IF EXISTS Cards;
+DROP TABLE IF EXISTS Binaries;
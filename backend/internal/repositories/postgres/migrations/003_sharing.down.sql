@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS share_grants;
+DROP TABLE IF EXISTS user_keys;
+
+ALTER TABLE Binaries DROP COLUMN IF EXISTS entrykey;
+ALTER TABLE Cards DROP COLUMN IF EXISTS entrykey;
+ALTER TABLE Credentials DROP COLUMN IF EXISTS entrykey;
+ALTER TABLE Texts DROP COLUMN IF EXISTS entrykey;
@@ -0,0 +1,31 @@
+-- Ключ записи, зашифрованный открытым ключом владельца. Пустой - поля зашифрованы ключом хранилища владельца
+ALTER TABLE Binaries ADD COLUMN IF NOT EXISTS entrykey TEXT;
+ALTER TABLE Cards ADD COLUMN IF NOT EXISTS entrykey TEXT;
+ALTER TABLE Credentials ADD COLUMN IF NOT EXISTS entrykey TEXT;
+ALTER TABLE Texts ADD COLUMN IF NOT EXISTS entrykey TEXT;
+
+-- Пары ключей X25519 пользователей. Закрытый ключ хранится зашифрованным ключом хранилища пользователя
+CREATE TABLE IF NOT EXISTS user_keys (
+	login TEXT NOT NULL PRIMARY KEY REFERENCES users (login) ON DELETE CASCADE,
+	public_key TEXT NOT NULL,
+	encrypted_private_key TEXT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Права пользователей на чужие записи
+CREATE TABLE IF NOT EXISTS share_grants (
+	id SERIAL PRIMARY KEY,
+	entity_type TEXT NOT NULL CHECK (entity_type IN ('binary', 'card', 'credentials', 'text')),
+	entity_id INTEGER NOT NULL,
+	owner_id TEXT NOT NULL,
+	recipient_id TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	permission TEXT NOT NULL CHECK (permission IN ('read', 'write')),
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted')),
+	entrykey TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (entity_type, entity_id, recipient_id),
+	CHECK (owner_id <> recipient_id)
+);
+
+CREATE INDEX IF NOT EXISTS share_grants_recipient_idx ON share_grants (recipient_id, entity_type);
+CREATE INDEX IF NOT EXISTS share_grants_owner_idx ON share_grants (owner_id);
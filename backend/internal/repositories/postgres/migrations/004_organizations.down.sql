@@ -0,0 +1,15 @@
+-- Записи коллекций зашифрованы ключом коллекции: без организаций их никто не расшифрует
+DELETE FROM Binaries WHERE collectionid IS NOT NULL;
+DELETE FROM Cards WHERE collectionid IS NOT NULL;
+DELETE FROM Credentials WHERE collectionid IS NOT NULL;
+DELETE FROM Texts WHERE collectionid IS NOT NULL;
+
+ALTER TABLE Binaries DROP COLUMN IF EXISTS collectionid, DROP COLUMN IF EXISTS keyversion;
+ALTER TABLE Cards DROP COLUMN IF EXISTS collectionid, DROP COLUMN IF EXISTS keyversion;
+ALTER TABLE Credentials DROP COLUMN IF EXISTS collectionid, DROP COLUMN IF EXISTS keyversion;
+ALTER TABLE Texts DROP COLUMN IF EXISTS collectionid, DROP COLUMN IF EXISTS keyversion;
+
+DROP TABLE IF EXISTS collection_keys;
+DROP TABLE IF EXISTS collections;
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS organizations;
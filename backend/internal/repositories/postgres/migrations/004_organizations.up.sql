@@ -0,0 +1,52 @@
+-- Организации с общими коллекциями записей
+CREATE TABLE IF NOT EXISTS organizations (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	owner_id TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS org_members (
+	org_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+	login TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'readonly')),
+	status TEXT NOT NULL DEFAULT 'invited' CHECK (status IN ('invited', 'active')),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (org_id, login)
+);
+
+CREATE INDEX IF NOT EXISTS org_members_login_idx ON org_members (login);
+
+-- Коллекции записей. Ключ коллекции версии key_version хранится зашифрованным для каждого участника в collection_keys
+CREATE TABLE IF NOT EXISTS collections (
+	id SERIAL PRIMARY KEY,
+	org_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	key_version INTEGER NOT NULL DEFAULT 1,
+	rotation_required BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (org_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS collection_keys (
+	collection_id INTEGER NOT NULL REFERENCES collections (id) ON DELETE CASCADE,
+	login TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	version INTEGER NOT NULL,
+	encrypted_key TEXT NOT NULL,
+	PRIMARY KEY (collection_id, login, version)
+);
+
+-- Записи коллекций зашифрованы ключом коллекции версии keyversion
+ALTER TABLE Binaries ADD COLUMN IF NOT EXISTS collectionid INTEGER REFERENCES collections (id) ON DELETE CASCADE;
+ALTER TABLE Binaries ADD COLUMN IF NOT EXISTS keyversion INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE Cards ADD COLUMN IF NOT EXISTS collectionid INTEGER REFERENCES collections (id) ON DELETE CASCADE;
+ALTER TABLE Cards ADD COLUMN IF NOT EXISTS keyversion INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE Credentials ADD COLUMN IF NOT EXISTS collectionid INTEGER REFERENCES collections (id) ON DELETE CASCADE;
+ALTER TABLE Credentials ADD COLUMN IF NOT EXISTS keyversion INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE Texts ADD COLUMN IF NOT EXISTS collectionid INTEGER REFERENCES collections (id) ON DELETE CASCADE;
+ALTER TABLE Texts ADD COLUMN IF NOT EXISTS keyversion INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS binaries_collection_idx ON Binaries (collectionid) WHERE collectionid IS NOT NULL;
+CREATE INDEX IF NOT EXISTS cards_collection_idx ON Cards (collectionid) WHERE collectionid IS NOT NULL;
+CREATE INDEX IF NOT EXISTS credentials_collection_idx ON Credentials (collectionid) WHERE collectionid IS NOT NULL;
+CREATE INDEX IF NOT EXISTS texts_collection_idx ON Texts (collectionid) WHERE collectionid IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Экстренный доступ: доверенное лицо получает ключ хранилища доверителя, зашифрованный своим открытым ключом,
+-- когда доверитель одобрит запрос или истечёт период ожидания
+CREATE TABLE IF NOT EXISTS emergency_access (
+	id SERIAL PRIMARY KEY,
+	grantor_id TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	grantee_id TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	status TEXT NOT NULL DEFAULT 'invited' CHECK (status IN ('invited', 'accepted', 'requested', 'granted')),
+	wait_days INTEGER NOT NULL CHECK (wait_days > 0),
+	encrypted_key TEXT NOT NULL,
+	requested_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (grantor_id, grantee_id),
+	CHECK (grantor_id <> grantee_id)
+);
+
+CREATE INDEX IF NOT EXISTS emergency_access_grantee_idx ON emergency_access (grantee_id);
+CREATE INDEX IF NOT EXISTS emergency_access_requested_idx ON emergency_access (requested_at) WHERE status = 'requested';
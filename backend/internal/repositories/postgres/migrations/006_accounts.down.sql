@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS audit_log_actor_created_at_idx;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS actor;
+
+ALTER TABLE users DROP COLUMN IF EXISTS session_version;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled;
@@ -0,0 +1,8 @@
+-- Блокировка учётных записей и отзыв сессий администратором: версия сессий записывается в токен
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS session_version INTEGER NOT NULL DEFAULT 0;
+
+-- Действия администратора: actor - кто выполнил действие, userid - над чьей учётной записью
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS actor TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_created_at_idx ON audit_log (actor, created_at) WHERE actor <> '';
@@ -0,0 +1,6 @@
+-- Индивидуальные квоты хранилища: для пользователей без строки действует квота по умолчанию из конфигурации
+CREATE TABLE IF NOT EXISTS user_quotas (
+	login TEXT NOT NULL PRIMARY KEY REFERENCES users (login) ON DELETE CASCADE,
+	max_entries INTEGER NOT NULL CHECK (max_entries >= 0),
+	max_bytes BIGINT NOT NULL CHECK (max_bytes >= 0)
+);
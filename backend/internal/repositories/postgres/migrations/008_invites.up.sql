@@ -0,0 +1,9 @@
+-- Коды приглашений для регистрации в режиме invite
+CREATE TABLE IF NOT EXISTS invites (
+	code TEXT NOT NULL PRIMARY KEY,
+	max_uses INTEGER NOT NULL CHECK (max_uses > 0),
+	uses INTEGER NOT NULL DEFAULT 0 CHECK (uses >= 0),
+	login_pattern TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,15 @@
+-- Без корзины удалённые записи удаляются сразу, вместе с выданными на них правами
+DELETE FROM share_grants g USING Binaries e WHERE g.entity_type = 'binary' AND g.entity_id = e.id AND e.deleted_at IS NOT NULL;
+DELETE FROM share_grants g USING Cards e WHERE g.entity_type = 'card' AND g.entity_id = e.id AND e.deleted_at IS NOT NULL;
+DELETE FROM share_grants g USING Credentials e WHERE g.entity_type = 'credentials' AND g.entity_id = e.id AND e.deleted_at IS NOT NULL;
+DELETE FROM share_grants g USING Texts e WHERE g.entity_type = 'text' AND g.entity_id = e.id AND e.deleted_at IS NOT NULL;
+
+DELETE FROM Binaries WHERE deleted_at IS NOT NULL;
+DELETE FROM Cards WHERE deleted_at IS NOT NULL;
+DELETE FROM Credentials WHERE deleted_at IS NOT NULL;
+DELETE FROM Texts WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE Binaries DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE Cards DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE Credentials DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE Texts DROP COLUMN IF EXISTS deleted_at;
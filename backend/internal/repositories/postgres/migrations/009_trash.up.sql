@@ -0,0 +1,10 @@
+-- Корзина: удалённые записи скрываются, но хранятся до восстановления или очистки по истечении срока хранения
+ALTER TABLE Binaries ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE Cards ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE Credentials ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE Texts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS binaries_deleted_idx ON Binaries (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS cards_deleted_idx ON Cards (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS credentials_deleted_idx ON Credentials (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS texts_deleted_idx ON Texts (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,17 @@
+-- История записей: при каждом изменении прежнее состояние записи копируется сюда.
+-- content - поля записи своего типа в том виде, в каком они хранились (зашифрованными), entrykey и keyversion - ключ, которым они зашифрованы
+CREATE TABLE IF NOT EXISTS entry_history (
+	entity_type TEXT NOT NULL CHECK (entity_type IN ('binary', 'card', 'credentials', 'text')),
+	entity_id INTEGER NOT NULL,
+	revision INTEGER NOT NULL,
+	owner_id TEXT NOT NULL,
+	content JSONB NOT NULL,
+	metadata TEXT,
+	entrykey TEXT,
+	keyversion INTEGER NOT NULL DEFAULT 0,
+	replaced_by TEXT NOT NULL,
+	replaced_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (entity_type, entity_id, revision)
+);
+
+CREATE INDEX IF NOT EXISTS entry_history_owner_idx ON entry_history (owner_id);
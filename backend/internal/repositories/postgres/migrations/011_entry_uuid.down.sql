@@ -0,0 +1,60 @@
+-- Возврат к числовым идентификаторам SERIAL: записи получают новые номера, ссылки на них переносятся
+ALTER TABLE Binaries ADD COLUMN old_id SERIAL;
+ALTER TABLE Cards ADD COLUMN old_id SERIAL;
+ALTER TABLE Credentials ADD COLUMN old_id SERIAL;
+ALTER TABLE Texts ADD COLUMN old_id SERIAL;
+
+-- Приглашения
+ALTER TABLE share_grants ADD COLUMN old_entity_id INTEGER;
+
+UPDATE share_grants g SET old_entity_id = e.old_id FROM Binaries e WHERE g.entity_type = 'binary' AND g.entity_id = e.id;
+UPDATE share_grants g SET old_entity_id = e.old_id FROM Cards e WHERE g.entity_type = 'card' AND g.entity_id = e.id;
+UPDATE share_grants g SET old_entity_id = e.old_id FROM Credentials e WHERE g.entity_type = 'credentials' AND g.entity_id = e.id;
+UPDATE share_grants g SET old_entity_id = e.old_id FROM Texts e WHERE g.entity_type = 'text' AND g.entity_id = e.id;
+
+DELETE FROM share_grants WHERE old_entity_id IS NULL;
+
+ALTER TABLE share_grants DROP COLUMN entity_id;
+ALTER TABLE share_grants RENAME COLUMN old_entity_id TO entity_id;
+ALTER TABLE share_grants ALTER COLUMN entity_id SET NOT NULL;
+ALTER TABLE share_grants ADD UNIQUE (entity_type, entity_id, recipient_id);
+
+-- История
+ALTER TABLE entry_history ADD COLUMN old_entity_id INTEGER;
+
+UPDATE entry_history h SET old_entity_id = e.old_id FROM Binaries e WHERE h.entity_type = 'binary' AND h.entity_id = e.id;
+UPDATE entry_history h SET old_entity_id = e.old_id FROM Cards e WHERE h.entity_type = 'card' AND h.entity_id = e.id;
+UPDATE entry_history h SET old_entity_id = e.old_id FROM Credentials e WHERE h.entity_type = 'credentials' AND h.entity_id = e.id;
+UPDATE entry_history h SET old_entity_id = e.old_id FROM Texts e WHERE h.entity_type = 'text' AND h.entity_id = e.id;
+
+DELETE FROM entry_history WHERE old_entity_id IS NULL;
+
+ALTER TABLE entry_history DROP COLUMN entity_id;
+ALTER TABLE entry_history RENAME COLUMN old_entity_id TO entity_id;
+ALTER TABLE entry_history ALTER COLUMN entity_id SET NOT NULL;
+ALTER TABLE entry_history ADD PRIMARY KEY (entity_type, entity_id, revision);
+
+-- Журнал аудита
+ALTER TABLE audit_log DISABLE TRIGGER audit_log_append_only;
+UPDATE audit_log a SET entity_id = e.old_id::text FROM Binaries e WHERE a.entity_type = 'binary' AND a.entity_id = e.id::text;
+UPDATE audit_log a SET entity_id = e.old_id::text FROM Cards e WHERE a.entity_type = 'card' AND a.entity_id = e.id::text;
+UPDATE audit_log a SET entity_id = e.old_id::text FROM Credentials e WHERE a.entity_type = 'credentials' AND a.entity_id = e.id::text;
+UPDATE audit_log a SET entity_id = e.old_id::text FROM Texts e WHERE a.entity_type = 'text' AND a.entity_id = e.id::text;
+ALTER TABLE audit_log ENABLE TRIGGER audit_log_append_only;
+
+-- Сами записи (последовательности переходят к столбцу вместе с переименованием)
+ALTER TABLE Binaries DROP COLUMN id;
+ALTER TABLE Binaries RENAME COLUMN old_id TO id;
+ALTER TABLE Binaries ADD PRIMARY KEY (id);
+
+ALTER TABLE Cards DROP COLUMN id;
+ALTER TABLE Cards RENAME COLUMN old_id TO id;
+ALTER TABLE Cards ADD PRIMARY KEY (id);
+
+ALTER TABLE Credentials DROP COLUMN id;
+ALTER TABLE Credentials RENAME COLUMN old_id TO id;
+ALTER TABLE Credentials ADD PRIMARY KEY (id);
+
+ALTER TABLE Texts DROP COLUMN id;
+ALTER TABLE Texts RENAME COLUMN old_id TO id;
+ALTER TABLE Texts ADD PRIMARY KEY (id);
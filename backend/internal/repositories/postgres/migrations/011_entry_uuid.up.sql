@@ -0,0 +1,81 @@
+-- Идентификаторы записей - UUIDv7, которые выбирает клиент (вместо SERIAL).
+-- Существующие записи получают идентификаторы, вычисляемые из типа и прежнего номера, - так же их переводит
+-- локальная база клиента (миграция 004). Ссылки на записи в приглашениях, истории и журнале аудита переносятся
+
+-- legacy_id - UUIDv8 записи, созданной до перехода: 00000000-0000-8000-800T-NNNNNNNNNNNN, где T - код типа
+-- (1 - binary, 2 - card, 3 - credentials, 4 - text), N - прежний номер в шестнадцатеричном виде.
+-- Нулевая метка времени ставит такие записи раньше всех созданных с UUIDv7
+CREATE FUNCTION pg_temp.legacy_id(kind INTEGER, id BIGINT) RETURNS UUID AS $$
+	SELECT ('00000000-0000-8000-800' || kind || '-' || lpad(to_hex(id), 12, '0'))::uuid
+$$ LANGUAGE SQL IMMUTABLE;
+
+ALTER TABLE Binaries ADD COLUMN new_id UUID;
+ALTER TABLE Cards ADD COLUMN new_id UUID;
+ALTER TABLE Credentials ADD COLUMN new_id UUID;
+ALTER TABLE Texts ADD COLUMN new_id UUID;
+
+UPDATE Binaries SET new_id = pg_temp.legacy_id(1, id);
+UPDATE Cards SET new_id = pg_temp.legacy_id(2, id);
+UPDATE Credentials SET new_id = pg_temp.legacy_id(3, id);
+UPDATE Texts SET new_id = pg_temp.legacy_id(4, id);
+
+-- Приглашения
+ALTER TABLE share_grants ADD COLUMN new_entity_id UUID;
+
+UPDATE share_grants g SET new_entity_id = e.new_id FROM Binaries e WHERE g.entity_type = 'binary' AND g.entity_id = e.id;
+UPDATE share_grants g SET new_entity_id = e.new_id FROM Cards e WHERE g.entity_type = 'card' AND g.entity_id = e.id;
+UPDATE share_grants g SET new_entity_id = e.new_id FROM Credentials e WHERE g.entity_type = 'credentials' AND g.entity_id = e.id;
+UPDATE share_grants g SET new_entity_id = e.new_id FROM Texts e WHERE g.entity_type = 'text' AND g.entity_id = e.id;
+
+-- Приглашения на уже удалённые записи не нужны
+DELETE FROM share_grants WHERE new_entity_id IS NULL;
+
+ALTER TABLE share_grants DROP COLUMN entity_id;
+ALTER TABLE share_grants RENAME COLUMN new_entity_id TO entity_id;
+ALTER TABLE share_grants ALTER COLUMN entity_id SET NOT NULL;
+ALTER TABLE share_grants ADD UNIQUE (entity_type, entity_id, recipient_id);
+
+-- История
+ALTER TABLE entry_history ADD COLUMN new_entity_id UUID;
+
+UPDATE entry_history h SET new_entity_id = e.new_id FROM Binaries e WHERE h.entity_type = 'binary' AND h.entity_id = e.id;
+UPDATE entry_history h SET new_entity_id = e.new_id FROM Cards e WHERE h.entity_type = 'card' AND h.entity_id = e.id;
+UPDATE entry_history h SET new_entity_id = e.new_id FROM Credentials e WHERE h.entity_type = 'credentials' AND h.entity_id = e.id;
+UPDATE entry_history h SET new_entity_id = e.new_id FROM Texts e WHERE h.entity_type = 'text' AND h.entity_id = e.id;
+
+DELETE FROM entry_history WHERE new_entity_id IS NULL;
+
+ALTER TABLE entry_history DROP COLUMN entity_id;
+ALTER TABLE entry_history RENAME COLUMN new_entity_id TO entity_id;
+ALTER TABLE entry_history ALTER COLUMN entity_id SET NOT NULL;
+ALTER TABLE entry_history ADD PRIMARY KEY (entity_type, entity_id, revision);
+
+-- Журнал аудита хранит идентификатор текстом. Идентификатор вычисляется из номера, поэтому переводятся и события
+-- уже удалённых записей. Журнал только дополняется, поэтому запрет изменений на время переноса снимается
+ALTER TABLE audit_log DISABLE TRIGGER audit_log_append_only;
+UPDATE audit_log SET entity_id = pg_temp.legacy_id(1, entity_id::bigint)::text WHERE entity_type = 'binary' AND entity_id ~ '^[0-9]{1,10}$';
+UPDATE audit_log SET entity_id = pg_temp.legacy_id(2, entity_id::bigint)::text WHERE entity_type = 'card' AND entity_id ~ '^[0-9]{1,10}$';
+UPDATE audit_log SET entity_id = pg_temp.legacy_id(3, entity_id::bigint)::text WHERE entity_type = 'credentials' AND entity_id ~ '^[0-9]{1,10}$';
+UPDATE audit_log SET entity_id = pg_temp.legacy_id(4, entity_id::bigint)::text WHERE entity_type = 'text' AND entity_id ~ '^[0-9]{1,10}$';
+ALTER TABLE audit_log ENABLE TRIGGER audit_log_append_only;
+
+-- Сами записи (последовательности SERIAL удаляются вместе со столбцами)
+ALTER TABLE Binaries DROP COLUMN id;
+ALTER TABLE Binaries RENAME COLUMN new_id TO id;
+ALTER TABLE Binaries ALTER COLUMN id SET NOT NULL;
+ALTER TABLE Binaries ADD PRIMARY KEY (id);
+
+ALTER TABLE Cards DROP COLUMN id;
+ALTER TABLE Cards RENAME COLUMN new_id TO id;
+ALTER TABLE Cards ALTER COLUMN id SET NOT NULL;
+ALTER TABLE Cards ADD PRIMARY KEY (id);
+
+ALTER TABLE Credentials DROP COLUMN id;
+ALTER TABLE Credentials RENAME COLUMN new_id TO id;
+ALTER TABLE Credentials ALTER COLUMN id SET NOT NULL;
+ALTER TABLE Credentials ADD PRIMARY KEY (id);
+
+ALTER TABLE Texts DROP COLUMN id;
+ALTER TABLE Texts RENAME COLUMN new_id TO id;
+ALTER TABLE Texts ALTER COLUMN id SET NOT NULL;
+ALTER TABLE Texts ADD PRIMARY KEY (id);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS binaries_owner_idx;
+DROP INDEX IF EXISTS cards_owner_idx;
+DROP INDEX IF EXISTS credentials_owner_idx;
+DROP INDEX IF EXISTS texts_owner_idx;
+
+ALTER TABLE Binaries DROP CONSTRAINT IF EXISTS binaries_ownerid_fkey;
+ALTER TABLE Cards DROP CONSTRAINT IF EXISTS cards_ownerid_fkey;
+ALTER TABLE Credentials DROP CONSTRAINT IF EXISTS credentials_ownerid_fkey;
+ALTER TABLE Texts DROP CONSTRAINT IF EXISTS texts_ownerid_fkey;
+ALTER TABLE share_grants DROP CONSTRAINT IF EXISTS share_grants_owner_id_fkey;
+ALTER TABLE entry_history DROP CONSTRAINT IF EXISTS entry_history_owner_id_fkey;
@@ -0,0 +1,21 @@
+-- Записи, права и история принадлежат пользователю: при удалении пользователя они удаляются каскадно.
+-- Строки, оставшиеся от уже удалённых пользователей, удаляются до создания ограничений
+DELETE FROM Binaries WHERE ownerid NOT IN (SELECT login FROM users);
+DELETE FROM Cards WHERE ownerid NOT IN (SELECT login FROM users);
+DELETE FROM Credentials WHERE ownerid NOT IN (SELECT login FROM users);
+DELETE FROM Texts WHERE ownerid NOT IN (SELECT login FROM users);
+DELETE FROM share_grants WHERE owner_id NOT IN (SELECT login FROM users);
+DELETE FROM entry_history WHERE owner_id NOT IN (SELECT login FROM users);
+
+ALTER TABLE Binaries ADD CONSTRAINT binaries_ownerid_fkey FOREIGN KEY (ownerid) REFERENCES users (login) ON DELETE CASCADE;
+ALTER TABLE Cards ADD CONSTRAINT cards_ownerid_fkey FOREIGN KEY (ownerid) REFERENCES users (login) ON DELETE CASCADE;
+ALTER TABLE Credentials ADD CONSTRAINT credentials_ownerid_fkey FOREIGN KEY (ownerid) REFERENCES users (login) ON DELETE CASCADE;
+ALTER TABLE Texts ADD CONSTRAINT texts_ownerid_fkey FOREIGN KEY (ownerid) REFERENCES users (login) ON DELETE CASCADE;
+ALTER TABLE share_grants ADD CONSTRAINT share_grants_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users (login) ON DELETE CASCADE;
+ALTER TABLE entry_history ADD CONSTRAINT entry_history_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users (login) ON DELETE CASCADE;
+
+-- Списки записей пользователя и проверки владельца читают индекс вместо полного просмотра таблицы
+CREATE INDEX IF NOT EXISTS binaries_owner_idx ON Binaries (ownerid, id);
+CREATE INDEX IF NOT EXISTS cards_owner_idx ON Cards (ownerid, id);
+CREATE INDEX IF NOT EXISTS credentials_owner_idx ON Credentials (ownerid, id);
+CREATE INDEX IF NOT EXISTS texts_owner_idx ON Texts (ownerid, id);
@@ -0,0 +1,47 @@
+DROP FUNCTION IF EXISTS purge_trash(TEXT, TIMESTAMPTZ);
+DROP FUNCTION IF EXISTS storage_usage(TEXT);
+DROP FUNCTION IF EXISTS entry_id_taken(TEXT, UUID);
+
+DROP POLICY IF EXISTS entry_history_delete ON entry_history;
+DROP POLICY IF EXISTS entry_history_update ON entry_history;
+DROP POLICY IF EXISTS entry_history_insert ON entry_history;
+DROP POLICY IF EXISTS entry_history_select ON entry_history;
+ALTER TABLE entry_history NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE entry_history DISABLE ROW LEVEL SECURITY;
+DROP FUNCTION IF EXISTS history_writable(TEXT, UUID);
+
+DROP POLICY IF EXISTS texts_delete ON Texts;
+DROP POLICY IF EXISTS texts_update ON Texts;
+DROP POLICY IF EXISTS texts_insert ON Texts;
+DROP POLICY IF EXISTS texts_select ON Texts;
+ALTER TABLE Texts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Texts DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS credentials_delete ON Credentials;
+DROP POLICY IF EXISTS credentials_update ON Credentials;
+DROP POLICY IF EXISTS credentials_insert ON Credentials;
+DROP POLICY IF EXISTS credentials_select ON Credentials;
+ALTER TABLE Credentials NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Credentials DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS cards_delete ON Cards;
+DROP POLICY IF EXISTS cards_update ON Cards;
+DROP POLICY IF EXISTS cards_insert ON Cards;
+DROP POLICY IF EXISTS cards_select ON Cards;
+ALTER TABLE Cards NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Cards DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS binaries_delete ON Binaries;
+DROP POLICY IF EXISTS binaries_update ON Binaries;
+DROP POLICY IF EXISTS binaries_insert ON Binaries;
+DROP POLICY IF EXISTS binaries_select ON Binaries;
+ALTER TABLE Binaries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Binaries DISABLE ROW LEVEL SECURITY;
+
+DROP FUNCTION IF EXISTS entry_owner_kept(TEXT, UUID, TEXT, INTEGER);
+DROP FUNCTION IF EXISTS entry_creatable(TEXT, INTEGER);
+DROP FUNCTION IF EXISTS entry_deletable(TEXT, INTEGER);
+DROP FUNCTION IF EXISTS entry_writable(TEXT, UUID, TEXT, INTEGER);
+DROP FUNCTION IF EXISTS entry_visible(TEXT, UUID, TEXT, INTEGER);
+DROP FUNCTION IF EXISTS collection_role(INTEGER);
+DROP FUNCTION IF EXISTS rls_bypassed();
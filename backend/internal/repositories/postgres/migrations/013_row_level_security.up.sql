@@ -0,0 +1,230 @@
+-- Политики строк (RLS) - вторая линия изоляции пользователей после условий в запросах репозиториев.
+-- Репозиторий в каждой транзакции записывает логин пользователя в app.current_user, и запрос видит и меняет только
+-- доступные пользователю записи, даже если в нём забыто условие на владельца. Читать запись можно по любому отношению к ней,
+-- менять - по правам на запись (владелец, приглашение с правом write, роль owner, admin или member), удалять - владельцу
+-- личной записи и owner или admin коллекции.
+-- Политики обходит только владелец таблиц, включивший app.bypass_rls (миграции, резервное копирование и функции
+-- с SECURITY DEFINER ниже). Сервер должен подключаться под ролью, которая не владеет таблицами
+-- (database.owner_dsn): от имени владельца политики отключаются и без переменной. На суперпользователя и роли с BYPASSRLS
+-- политики не действуют вовсе
+
+-- rls_bypassed - служебная транзакция владельца таблиц. Другой роли переменная app.bypass_rls ничего не даёт
+CREATE OR REPLACE FUNCTION rls_bypassed() RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT current_setting('app.bypass_rls', TRUE) IS NOT DISTINCT FROM 'on'
+		AND current_user = (SELECT pg_get_userbyid(relowner) FROM pg_class WHERE oid = 'binaries'::regclass)
+$$;
+
+-- collection_role - роль пользователя транзакции в организации коллекции ('' - не состоит в ней)
+CREATE OR REPLACE FUNCTION collection_role(collection INTEGER) RETURNS TEXT
+LANGUAGE sql STABLE AS $$
+	SELECT COALESCE((SELECT m.role FROM collections c JOIN org_members m ON m.org_id = c.org_id
+		WHERE c.id = collection AND m.login = current_setting('app.current_user', TRUE) AND m.status = 'active'), '')
+$$;
+
+-- entry_visible - запись доступна пользователю транзакции: собственная, открытая ему принятым приглашением
+-- или лежащая в коллекции организации, в которой он состоит
+CREATE OR REPLACE FUNCTION entry_visible(kind TEXT, entry_id UUID, owner TEXT, collection INTEGER) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT rls_bypassed()
+		OR owner = current_setting('app.current_user', TRUE)
+		OR EXISTS (SELECT 1 FROM share_grants g
+			WHERE g.entity_type = kind AND g.entity_id = entry_id
+				AND g.recipient_id = current_setting('app.current_user', TRUE) AND g.status = 'accepted')
+		OR collection_role(collection) <> ''
+$$;
+
+-- entry_writable - пользователь транзакции может менять запись: владелец или получатель приглашения с правом write
+-- для личной записи, участник с ролью не ниже member для записи коллекции
+CREATE OR REPLACE FUNCTION entry_writable(kind TEXT, entry_id UUID, owner TEXT, collection INTEGER) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT rls_bypassed() OR CASE WHEN collection IS NULL THEN
+			owner = current_setting('app.current_user', TRUE)
+			OR EXISTS (SELECT 1 FROM share_grants g
+				WHERE g.entity_type = kind AND g.entity_id = entry_id AND g.permission = 'write'
+					AND g.recipient_id = current_setting('app.current_user', TRUE) AND g.status = 'accepted')
+		ELSE collection_role(collection) IN ('owner', 'admin', 'member')
+	END
+$$;
+
+-- entry_deletable - пользователь транзакции может удалить запись: владелец личной записи, owner или admin коллекции
+CREATE OR REPLACE FUNCTION entry_deletable(owner TEXT, collection INTEGER) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT rls_bypassed() OR CASE WHEN collection IS NULL THEN owner = current_setting('app.current_user', TRUE)
+		ELSE collection_role(collection) IN ('owner', 'admin')
+	END
+$$;
+
+-- entry_creatable - пользователь транзакции создаёт запись от своего имени: личную или в коллекции, где может создавать записи
+CREATE OR REPLACE FUNCTION entry_creatable(owner TEXT, collection INTEGER) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT rls_bypassed() OR (owner = current_setting('app.current_user', TRUE)
+		AND (collection IS NULL OR collection_role(collection) IN ('owner', 'admin', 'member')))
+$$;
+
+-- entry_owner_kept - изменение не переносит запись к другому владельцу или в другую коллекцию: функция сравнивает
+-- новые значения со строкой, какой её видит изменяющий запрос. Иначе получатель приглашения с правом write
+-- мог бы присвоить чужую запись
+CREATE OR REPLACE FUNCTION entry_owner_kept(kind TEXT, entry_id UUID, owner TEXT, collection INTEGER) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT rls_bypassed() OR CASE kind
+		WHEN 'binary' THEN EXISTS (SELECT 1 FROM Binaries e
+			WHERE e.id = entry_id AND e.ownerid = owner AND e.collectionid IS NOT DISTINCT FROM collection)
+		WHEN 'card' THEN EXISTS (SELECT 1 FROM Cards e
+			WHERE e.id = entry_id AND e.ownerid = owner AND e.collectionid IS NOT DISTINCT FROM collection)
+		WHEN 'credentials' THEN EXISTS (SELECT 1 FROM Credentials e
+			WHERE e.id = entry_id AND e.ownerid = owner AND e.collectionid IS NOT DISTINCT FROM collection)
+		WHEN 'text' THEN EXISTS (SELECT 1 FROM Texts e
+			WHERE e.id = entry_id AND e.ownerid = owner AND e.collectionid IS NOT DISTINCT FROM collection)
+		ELSE FALSE
+	END
+$$;
+
+ALTER TABLE Binaries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Binaries FORCE ROW LEVEL SECURITY;
+CREATE POLICY binaries_select ON Binaries FOR SELECT
+	USING (entry_visible('binary', id, ownerid, collectionid));
+CREATE POLICY binaries_insert ON Binaries FOR INSERT
+	WITH CHECK (entry_creatable(ownerid, collectionid));
+CREATE POLICY binaries_update ON Binaries FOR UPDATE
+	USING (entry_writable('binary', id, ownerid, collectionid))
+	WITH CHECK (entry_writable('binary', id, ownerid, collectionid) AND entry_owner_kept('binary', id, ownerid, collectionid));
+CREATE POLICY binaries_delete ON Binaries FOR DELETE
+	USING (entry_deletable(ownerid, collectionid));
+
+ALTER TABLE Cards ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Cards FORCE ROW LEVEL SECURITY;
+CREATE POLICY cards_select ON Cards FOR SELECT
+	USING (entry_visible('card', id, ownerid, collectionid));
+CREATE POLICY cards_insert ON Cards FOR INSERT
+	WITH CHECK (entry_creatable(ownerid, collectionid));
+CREATE POLICY cards_update ON Cards FOR UPDATE
+	USING (entry_writable('card', id, ownerid, collectionid))
+	WITH CHECK (entry_writable('card', id, ownerid, collectionid) AND entry_owner_kept('card', id, ownerid, collectionid));
+CREATE POLICY cards_delete ON Cards FOR DELETE
+	USING (entry_deletable(ownerid, collectionid));
+
+ALTER TABLE Credentials ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Credentials FORCE ROW LEVEL SECURITY;
+CREATE POLICY credentials_select ON Credentials FOR SELECT
+	USING (entry_visible('credentials', id, ownerid, collectionid));
+CREATE POLICY credentials_insert ON Credentials FOR INSERT
+	WITH CHECK (entry_creatable(ownerid, collectionid));
+CREATE POLICY credentials_update ON Credentials FOR UPDATE
+	USING (entry_writable('credentials', id, ownerid, collectionid))
+	WITH CHECK (entry_writable('credentials', id, ownerid, collectionid) AND entry_owner_kept('credentials', id, ownerid, collectionid));
+CREATE POLICY credentials_delete ON Credentials FOR DELETE
+	USING (entry_deletable(ownerid, collectionid));
+
+ALTER TABLE Texts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Texts FORCE ROW LEVEL SECURITY;
+CREATE POLICY texts_select ON Texts FOR SELECT
+	USING (entry_visible('text', id, ownerid, collectionid));
+CREATE POLICY texts_insert ON Texts FOR INSERT
+	WITH CHECK (entry_creatable(ownerid, collectionid));
+CREATE POLICY texts_update ON Texts FOR UPDATE
+	USING (entry_writable('text', id, ownerid, collectionid))
+	WITH CHECK (entry_writable('text', id, ownerid, collectionid) AND entry_owner_kept('text', id, ownerid, collectionid));
+CREATE POLICY texts_delete ON Texts FOR DELETE
+	USING (entry_deletable(ownerid, collectionid));
+
+-- history_writable - версии записи сохраняет и удаляет тот, кто может менять саму запись.
+-- Подзапросы к таблицам записей проходят через их политики чтения
+CREATE OR REPLACE FUNCTION history_writable(kind TEXT, entry_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT rls_bypassed() OR CASE kind
+		WHEN 'binary' THEN EXISTS (SELECT 1 FROM Binaries e WHERE e.id = entry_id AND entry_writable(kind, e.id, e.ownerid, e.collectionid))
+		WHEN 'card' THEN EXISTS (SELECT 1 FROM Cards e WHERE e.id = entry_id AND entry_writable(kind, e.id, e.ownerid, e.collectionid))
+		WHEN 'credentials' THEN EXISTS (SELECT 1 FROM Credentials e WHERE e.id = entry_id AND entry_writable(kind, e.id, e.ownerid, e.collectionid))
+		WHEN 'text' THEN EXISTS (SELECT 1 FROM Texts e WHERE e.id = entry_id AND entry_writable(kind, e.id, e.ownerid, e.collectionid))
+		ELSE FALSE
+	END
+$$;
+
+-- Версия записи видна тем, кому видна сама запись
+ALTER TABLE entry_history ENABLE ROW LEVEL SECURITY;
+ALTER TABLE entry_history FORCE ROW LEVEL SECURITY;
+CREATE POLICY entry_history_select ON entry_history FOR SELECT
+	USING (rls_bypassed() OR CASE entity_type
+		WHEN 'binary' THEN EXISTS (SELECT 1 FROM Binaries e WHERE e.id = entity_id)
+		WHEN 'card' THEN EXISTS (SELECT 1 FROM Cards e WHERE e.id = entity_id)
+		WHEN 'credentials' THEN EXISTS (SELECT 1 FROM Credentials e WHERE e.id = entity_id)
+		WHEN 'text' THEN EXISTS (SELECT 1 FROM Texts e WHERE e.id = entity_id)
+	END);
+CREATE POLICY entry_history_insert ON entry_history FOR INSERT
+	WITH CHECK (history_writable(entity_type, entity_id));
+CREATE POLICY entry_history_update ON entry_history FOR UPDATE
+	USING (history_writable(entity_type, entity_id))
+	WITH CHECK (history_writable(entity_type, entity_id));
+CREATE POLICY entry_history_delete ON entry_history FOR DELETE
+	USING (history_writable(entity_type, entity_id));
+
+-- Служебные запросы, которым по назначению нужны записи всех пользователей, выполняются функциями от имени владельца
+-- таблиц. Роль сервера вызывает их, но не может выполнить через них произвольный запрос
+
+-- entry_id_taken - занят ли ИД записью типа kind (в том числе чужой записью или записью в корзине)
+CREATE OR REPLACE FUNCTION entry_id_taken(kind TEXT, entry_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE SECURITY DEFINER SET app.bypass_rls = 'on' AS $$
+	SELECT CASE kind
+		WHEN 'binary' THEN EXISTS (SELECT 1 FROM Binaries e WHERE e.id = entry_id)
+		WHEN 'card' THEN EXISTS (SELECT 1 FROM Cards e WHERE e.id = entry_id)
+		WHEN 'credentials' THEN EXISTS (SELECT 1 FROM Credentials e WHERE e.id = entry_id)
+		WHEN 'text' THEN EXISTS (SELECT 1 FROM Texts e WHERE e.id = entry_id)
+	END
+$$;
+
+-- storage_usage - количество и объём записей каждого типа по владельцам (for_owner - только его записи, NULL - всех).
+-- Учитываются и записи, которые владелец открыл другим, и записи в корзине. Объём - сумма длин хранимых полей:
+-- с шифрованием на сервере это длины зашифрованных значений. В тех же единицах сервис оценивает изменение объёма
+-- перед записью (StoredSize зашифрованных репозиториев, без шифрования - методы Size сущностей)
+CREATE OR REPLACE FUNCTION storage_usage(for_owner TEXT)
+RETURNS TABLE (owner TEXT, entity_type TEXT, entries BIGINT, bytes BIGINT)
+LANGUAGE sql STABLE SECURITY DEFINER SET app.bypass_rls = 'on' AS $$
+	SELECT e.ownerid, 'binary', COUNT(*), COALESCE(SUM(octet_length(e.data) + COALESCE(octet_length(e.metadata), 0)), 0)::BIGINT
+	FROM Binaries e WHERE for_owner IS NULL OR e.ownerid = for_owner GROUP BY e.ownerid
+	UNION ALL
+	SELECT e.ownerid, 'card', COUNT(*), COALESCE(SUM(octet_length(e.number) + octet_length(e.cardholder) + octet_length(e.cvv)
+		+ COALESCE(octet_length(e.metadata), 0)), 0)::BIGINT
+	FROM Cards e WHERE for_owner IS NULL OR e.ownerid = for_owner GROUP BY e.ownerid
+	UNION ALL
+	SELECT e.ownerid, 'credentials', COUNT(*), COALESCE(SUM(octet_length(e.login) + octet_length(e.password)
+		+ COALESCE(octet_length(e.metadata), 0)), 0)::BIGINT
+	FROM Credentials e WHERE for_owner IS NULL OR e.ownerid = for_owner GROUP BY e.ownerid
+	UNION ALL
+	SELECT e.ownerid, 'text', COUNT(*), COALESCE(SUM(octet_length(e.data) + COALESCE(octet_length(e.metadata), 0)), 0)::BIGINT
+	FROM Texts e WHERE for_owner IS NULL OR e.ownerid = for_owner GROUP BY e.ownerid
+$$;
+
+-- purge_trash - окончательно удалить записи типа kind, попавшие в корзину раньше before, вместе с правами на них и историей
+CREATE OR REPLACE FUNCTION purge_trash(kind TEXT, before TIMESTAMPTZ)
+RETURNS TABLE (id UUID, metadata TEXT, ownerid TEXT, collectionid TEXT, keyversion INTEGER, deleted_at TIMESTAMPTZ)
+LANGUAGE plpgsql VOLATILE SECURITY DEFINER SET app.bypass_rls = 'on' AS $$
+DECLARE
+	entry_table TEXT := CASE kind WHEN 'binary' THEN 'binaries' WHEN 'card' THEN 'cards'
+		WHEN 'credentials' THEN 'credentials' WHEN 'text' THEN 'texts' END;
+BEGIN
+	IF entry_table IS NULL THEN
+		RAISE EXCEPTION 'unknown entity type %', kind;
+	END IF;
+
+	RETURN QUERY EXECUTE format($purge$
+		WITH purged AS (
+			DELETE FROM %I WHERE deleted_at < $1
+			RETURNING id, COALESCE(metadata, '') AS metadata, ownerid, COALESCE(collectionid::text, '') AS collectionid, keyversion, deleted_at
+		), revoked AS (
+			DELETE FROM share_grants g USING purged p WHERE g.entity_type = $2 AND g.entity_id = p.id
+		), history AS (
+			DELETE FROM entry_history h USING purged p WHERE h.entity_type = $2 AND h.entity_id = p.id
+		)
+		SELECT * FROM purged$purge$, entry_table) USING before, kind;
+END
+$$;
+
+-- Функции владельца ищут таблицы только в схеме миграций: временная таблица вызывающего с тем же именем их не подменит
+DO $$
+BEGIN
+	EXECUTE format('ALTER FUNCTION entry_id_taken(TEXT, UUID) SET search_path = %I, pg_temp', current_schema());
+	EXECUTE format('ALTER FUNCTION storage_usage(TEXT) SET search_path = %I, pg_temp', current_schema());
+	EXECUTE format('ALTER FUNCTION purge_trash(TEXT, TIMESTAMPTZ) SET search_path = %I, pg_temp', current_schema());
+END
+$$;
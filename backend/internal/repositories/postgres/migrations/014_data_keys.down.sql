@@ -0,0 +1,4 @@
+-- Откат возможен, только пока сроки действия карт не зашифрованы
+ALTER TABLE Cards ALTER COLUMN ExpirationDate TYPE DATE USING ExpirationDate::date;
+
+DROP TABLE IF EXISTS data_keys;
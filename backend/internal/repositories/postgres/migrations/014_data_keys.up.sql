@@ -0,0 +1,11 @@
+-- Ключи данных пользователей для шифрования хранимых полей на сервере (зашифрованы мастер-ключом).
+-- Внешнего ключа на users нет: ключ создаётся до пользователя, чтобы зашифровать хэш его пароля, и удаляется вместе с ним сервером
+CREATE TABLE IF NOT EXISTS data_keys (
+	login TEXT NOT NULL PRIMARY KEY,
+	wrapped_key BYTEA NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	rotated_at TIMESTAMPTZ
+);
+
+-- Срок действия карты хранится текстом, как остальные поля: зашифрованное значение не является датой
+ALTER TABLE Cards ALTER COLUMN ExpirationDate TYPE TEXT USING ExpirationDate::text;
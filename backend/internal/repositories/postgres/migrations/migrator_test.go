@@ -0,0 +1,32 @@
+// migrations пакет для миграции данных в приложении
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "версии миграций идут подряд с 1")
+		assert.NotEmpty(t, migration.up, migration.Name)
+		assert.True(t, migration.Reversible(), "у миграции %s есть файл отката", migration.Name)
+	}
+
+	assert.Equal(t, "001_init", migrations[0].Name)
+}
+
+func TestExtractVersion(t *testing.T) {
+	version, err := extractVersion("012_integrity.down.sql")
+	require.NoError(t, err)
+	assert.Equal(t, 12, version)
+
+	_, err = extractVersion("init.up.sql")
+	assert.Error(t, err)
+}
@@ -0,0 +1,315 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// orgMemberColumns - поля участника в порядке сканирования
+const orgMemberColumns = "org_id, login, role, status, created_at"
+
+// PgOrganizationRepo - организации, их участники и коллекции
+type PgOrganizationRepo struct {
+	db *tenantConn
+}
+
+// NewPgOrganizationRepo - инициализация репозитория
+func NewPgOrganizationRepo(db *pgx.Conn) (*PgOrganizationRepo, error) {
+	return &PgOrganizationRepo{db: newTenantConn(db)}, nil
+}
+
+// Create - создать организацию, текущий пользователь становится её владельцем
+func (r *PgOrganizationRepo) Create(ctx context.Context, org *dtos.NewOrganization) (*entities.Organization, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	query := `
+		WITH org AS (
+			INSERT INTO organizations (name, owner_id) VALUES ($1, $2) RETURNING id, name, owner_id, created_at
+		), owner AS (
+			INSERT INTO org_members (org_id, login, role, status) SELECT id, owner_id, 'owner', 'active' FROM org
+		)
+		SELECT id, name, owner_id, 'owner', 'active', created_at FROM org`
+
+	var created entities.Organization
+	err := r.db.QueryRow(ctx, query, org.Name, userID).Scan(&created.ID, &created.Name, &created.OwnerID, &created.Role, &created.Status, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetAll - получить организации, в которых состоит или куда приглашён текущий пользователь
+func (r *PgOrganizationRepo) GetAll(ctx context.Context) ([]entities.Organization, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	query := `
+		SELECT o.id, o.name, o.owner_id, m.role, m.status, o.created_at
+		FROM organizations o JOIN org_members m ON m.org_id = o.id
+		WHERE m.login = $1 ORDER BY o.created_at, o.id`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organizations: %w", err)
+	}
+
+	defer rows.Close()
+
+	var orgs []entities.Organization
+	for rows.Next() {
+		var org entities.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.OwnerID, &org.Role, &org.Status, &org.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+
+	return orgs, rows.Err()
+}
+
+// GetMember - получить участника организации
+func (r *PgOrganizationRepo) GetMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error) {
+	member, err := scanOrgMember(r.db.QueryRow(ctx, "SELECT "+orgMemberColumns+" FROM org_members WHERE org_id = $1 AND login = $2", orgID, login))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Пользователь в организации не состоит
+		}
+		return nil, fmt.Errorf("failed to get organization member: %w", err)
+	}
+
+	return member, nil
+}
+
+// GetMembers - получить участников организации (в порядке приглашения)
+func (r *PgOrganizationRepo) GetMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error) {
+	rows, err := r.db.Query(ctx, "SELECT "+orgMemberColumns+" FROM org_members WHERE org_id = $1 ORDER BY created_at, login", orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization members: %w", err)
+	}
+
+	defer rows.Close()
+
+	var members []entities.OrgMember
+	for rows.Next() {
+		member, err := scanOrgMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		members = append(members, *member)
+	}
+
+	return members, rows.Err()
+}
+
+// AddMember - пригласить пользователя и сохранить для него ключи коллекций
+func (r *PgOrganizationRepo) AddMember(ctx context.Context, member *dtos.NewOrgMember) (*entities.OrgMember, error) {
+	keys, err := json.Marshal(member.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH member AS (
+			INSERT INTO org_members (org_id, login, role) VALUES ($1, $2, $3)
+			ON CONFLICT (org_id, login) DO NOTHING
+			RETURNING ` + orgMemberColumns + `
+		), member_keys AS (
+			INSERT INTO collection_keys (collection_id, login, version, encrypted_key)
+			SELECT k.collection_id, m.login, k.version, k.encrypted_key
+			FROM member m, jsonb_to_recordset($4::jsonb) AS k(collection_id INTEGER, version INTEGER, encrypted_key TEXT)
+			ON CONFLICT (collection_id, login, version) DO UPDATE SET encrypted_key = EXCLUDED.encrypted_key
+		)
+		SELECT ` + orgMemberColumns + ` FROM member`
+
+	added, err := scanOrgMember(r.db.QueryRow(ctx, query, member.OrgID, member.Login, member.Role, string(keys)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Пользователь уже состоит в организации
+		}
+		return nil, fmt.Errorf("failed to add organization member: %w", err)
+	}
+
+	return added, nil
+}
+
+// ActivateMember - принять приглашение в организацию
+func (r *PgOrganizationRepo) ActivateMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error) {
+	query := "UPDATE org_members SET status = 'active' WHERE org_id = $1 AND login = $2 AND status = 'invited' RETURNING " + orgMemberColumns
+
+	member, err := scanOrgMember(r.db.QueryRow(ctx, query, orgID, login))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Приглашения нет
+		}
+		return nil, fmt.Errorf("failed to activate organization member: %w", err)
+	}
+
+	return member, nil
+}
+
+// RemoveMember - исключить участника: удалить его ключи коллекций и отметить коллекции организации для замены ключа
+func (r *PgOrganizationRepo) RemoveMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error) {
+	query := `
+		WITH removed AS (
+			DELETE FROM org_members WHERE org_id = $1 AND login = $2 RETURNING ` + orgMemberColumns + `
+		), revoked AS (
+			DELETE FROM collection_keys k USING collections c, removed r
+			WHERE k.collection_id = c.id AND c.org_id = r.org_id AND k.login = r.login
+		), flagged AS (
+			UPDATE collections c SET rotation_required = TRUE FROM removed r WHERE c.org_id = r.org_id
+		)
+		SELECT ` + orgMemberColumns + ` FROM removed`
+
+	member, err := scanOrgMember(r.db.QueryRow(ctx, query, orgID, login))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Пользователь в организации не состоит
+		}
+		return nil, fmt.Errorf("failed to remove organization member: %w", err)
+	}
+
+	return member, nil
+}
+
+// CreateCollection - создать коллекцию с ключом версии 1, зашифрованным для каждого участника (nil, если имя занято)
+func (r *PgOrganizationRepo) CreateCollection(ctx context.Context, collection *dtos.NewCollection) (*entities.Collection, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	keys, err := json.Marshal(collection.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH created AS (
+			INSERT INTO collections (org_id, name) VALUES ($1, $2)
+			ON CONFLICT (org_id, name) DO NOTHING
+			RETURNING id, org_id, name, key_version, rotation_required, created_at
+		), collection_keys_added AS (
+			INSERT INTO collection_keys (collection_id, login, version, encrypted_key)
+			SELECT c.id, k.login, c.key_version, k.encrypted_key
+			FROM created c, jsonb_to_recordset($3::jsonb) AS k(login TEXT, encrypted_key TEXT)
+		)
+		SELECT c.id, c.org_id, c.name, c.key_version, c.rotation_required,
+			COALESCE((SELECT k.encrypted_key FROM jsonb_to_recordset($3::jsonb) AS k(login TEXT, encrypted_key TEXT) WHERE k.login = $4), ''),
+			c.created_at
+		FROM created c`
+
+	created, err := scanCollection(r.db.QueryRow(ctx, query, collection.OrgID, collection.Name, string(keys), userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Коллекция с таким именем уже есть
+		}
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	return created, nil
+}
+
+// collectionsQuery - коллекции с текущим ключом, зашифрованным для пользователя $2
+const collectionsQuery = `
+	SELECT c.id, c.org_id, c.name, c.key_version, c.rotation_required, COALESCE(k.encrypted_key, ''), c.created_at
+	FROM collections c
+	LEFT JOIN collection_keys k ON k.collection_id = c.id AND k.login = $2 AND k.version = c.key_version`
+
+// GetCollections - получить коллекции организации
+func (r *PgOrganizationRepo) GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	rows, err := r.db.Query(ctx, collectionsQuery+" WHERE c.org_id = $1 ORDER BY c.created_at, c.id", orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+
+	defer rows.Close()
+
+	var collections []entities.Collection
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		collections = append(collections, *collection)
+	}
+
+	return collections, rows.Err()
+}
+
+// GetCollection - получить коллекцию по ИД
+func (r *PgOrganizationRepo) GetCollection(ctx context.Context, id string) (*entities.Collection, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	collection, err := scanCollection(r.db.QueryRow(ctx, collectionsQuery+" WHERE c.id = $1", id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Коллекция не найдена
+		}
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+// RotateCollectionKey - заменить ключ коллекции следующей версией и снять отметку о необходимости замены
+func (r *PgOrganizationRepo) RotateCollectionKey(ctx context.Context, rotation *dtos.CollectionRotation) (*entities.Collection, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	keys, err := json.Marshal(rotation.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH rotated AS (
+			UPDATE collections SET key_version = $2, rotation_required = FALSE
+			WHERE id = $1 AND key_version = $2 - 1
+			RETURNING id, org_id, name, key_version, rotation_required, created_at
+		), rotated_keys AS (
+			INSERT INTO collection_keys (collection_id, login, version, encrypted_key)
+			SELECT c.id, k.login, c.key_version, k.encrypted_key
+			FROM rotated c, jsonb_to_recordset($3::jsonb) AS k(login TEXT, encrypted_key TEXT)
+		)
+		SELECT c.id, c.org_id, c.name, c.key_version, c.rotation_required,
+			COALESCE((SELECT k.encrypted_key FROM jsonb_to_recordset($3::jsonb) AS k(login TEXT, encrypted_key TEXT) WHERE k.login = $4), ''),
+			c.created_at
+		FROM rotated c`
+
+	rotated, err := scanCollection(r.db.QueryRow(ctx, query, rotation.CollectionID, rotation.Version, string(keys), userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Коллекции нет или версия ключа не следующая за текущей
+		}
+		return nil, fmt.Errorf("failed to rotate collection key: %w", err)
+	}
+
+	return rotated, nil
+}
+
+// scanOrgMember - прочитать участника организации из строки результата
+func scanOrgMember(row pgx.Row) (*entities.OrgMember, error) {
+	var member entities.OrgMember
+	if err := row.Scan(&member.OrgID, &member.Login, &member.Role, &member.Status, &member.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// scanCollection - прочитать коллекцию из строки результата
+func scanCollection(row pgx.Row) (*entities.Collection, error) {
+	var collection entities.Collection
+	err := row.Scan(&collection.ID, &collection.OrgID, &collection.Name, &collection.KeyVersion, &collection.RotationRequired, &collection.EncryptedKey, &collection.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &collection, nil
+}
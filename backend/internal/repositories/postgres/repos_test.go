@@ -0,0 +1,206 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/repotest"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// testDatabaseEnv - строка подключения к серверу PostgreSQL для тестов (в формате "host=... user=... dbname=...").
+// Если не задана, тесты запускают временный сервер через initdb и pg_ctl, а без них пропускаются
+const testDatabaseEnv = "GOPHKEEPER_TEST_DATABASE_URI"
+
+// Роли без прав суперпользователя, от имени которых тесты работают с базой, как сервер с database.owner_dsn:
+// testRole владеет базой и применяет миграции, testTenantRole выполняет запросы репозиториев. На суперпользователя
+// и владельца таблиц политики строк (RLS) не действуют
+const (
+	testRole       = "gophkeeper_test"
+	testTenantRole = "gophkeeper_test_tenant"
+)
+
+// testServer - сервер PostgreSQL, общий для тестов пакета
+var testServer struct {
+	once    sync.Once
+	connStr string // строка подключения к базе postgres с правами на создание баз
+	role    string // владелец тестовых баз (пусто - роли создать нельзя, тесты работают от имени роли из строки подключения)
+	err     error  // причина, по которой сервер недоступен
+	stop    func() // остановить временный сервер (nil - сервер внешний)
+	seq     atomic.Int64
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testServer.stop != nil {
+		testServer.stop()
+	}
+	os.Exit(code)
+}
+
+// newTestManager - менеджер пустой базы данных с применёнными миграциями. База удаляется после теста
+func newTestManager(t *testing.T) *DatabaseManager {
+	testServer.once.Do(func() {
+		if connStr := os.Getenv(testDatabaseEnv); connStr != "" {
+			testServer.connStr = withParam(connStr, "dbname", "postgres")
+		} else if testServer.connStr, testServer.stop, testServer.err = startPostgres(); testServer.err != nil {
+			return
+		}
+		testServer.role = createTestRole(testServer.connStr)
+	})
+	if testServer.err != nil {
+		t.Skipf("PostgreSQL is not available (set %s to run against a server): %v", testDatabaseEnv, testServer.err)
+	}
+
+	dbName := fmt.Sprintf("gophkeeper_test_%d_%d", os.Getpid(), testServer.seq.Add(1))
+	connStr, ownerConnStr := withParam(testServer.connStr, "dbname", dbName), ""
+	if testServer.role != "" {
+		// База принадлежит тестовой роли: миграции создают таблицы от её имени
+		admin, err := pgx.Connect(context.Background(), testServer.connStr)
+		require.NoError(t, err)
+		_, err = admin.Exec(context.Background(), "CREATE DATABASE "+dbName+" OWNER "+testServer.role)
+		admin.Close(context.Background())
+		require.NoError(t, err)
+
+		ownerConnStr = asRole(connStr, testServer.role)
+		connStr = asRole(connStr, testTenantRole)
+	}
+
+	manager, err := NewDatabaseManager(connStr, ownerConnStr)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		manager.DB.Close(context.Background())
+
+		conn, err := pgx.Connect(context.Background(), testServer.connStr)
+		if err != nil {
+			t.Logf("failed to drop test database %s: %v", dbName, err)
+			return
+		}
+		defer conn.Close(context.Background())
+
+		if _, err := conn.Exec(context.Background(), "DROP DATABASE IF EXISTS "+dbName); err != nil {
+			t.Logf("failed to drop test database %s: %v", dbName, err)
+		}
+	})
+
+	return manager
+}
+
+// createTestRole - создать роли testRole и testTenantRole (если их ещё нет). Пустая строка - роли создать нельзя
+func createTestRole(connStr string) string {
+	conn, err := pgx.Connect(context.Background(), connStr)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close(context.Background())
+
+	for _, role := range []string{testRole, testTenantRole} {
+		query := `DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = '` + role + `') THEN
+				CREATE ROLE ` + role + ` LOGIN PASSWORD '` + role + `';
+			END IF;
+		END $$`
+		if _, err := conn.Exec(context.Background(), query); err != nil {
+			return ""
+		}
+	}
+
+	return testRole
+}
+
+// ownerConn - подключение к базе менеджера от имени владельца схемы (его транзакции видят записи всех пользователей)
+func ownerConn(t *testing.T, manager *DatabaseManager) *pgx.Conn {
+	if testServer.role == "" {
+		return manager.DB
+	}
+
+	connStr := asRole(withParam(testServer.connStr, "dbname", manager.DB.Config().Database), testServer.role)
+	conn, err := pgx.Connect(context.Background(), connStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close(context.Background()) })
+
+	return conn
+}
+
+// asRole - строка подключения от имени тестовой роли (пароль совпадает с именем)
+func asRole(connStr, role string) string {
+	return withParam(withParam(connStr, "user", role), "password", role)
+}
+
+// startPostgres - запустить временный сервер во временной директории. Сервер слушает только unix-сокет в ней же
+func startPostgres() (string, func(), error) {
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "gophkeeper-pg-")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+
+	if output, err := exec.Command(initdb, "-D", data, "-U", "postgres", "--auth=trust", "-E", "UTF8").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	options := fmt.Sprintf("-k %s -c listen_addresses='' -c fsync=off", dir)
+	if output, err := exec.Command(pgCtl, "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	stop := func() {
+		exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+
+	// При доверительной аутентификации пароль не проверяется, но строка подключения должна его содержать
+	connStr := fmt.Sprintf("host=%s port=5432 user=postgres password=postgres dbname=postgres sslmode=disable", dir)
+	return connStr, stop, nil
+}
+
+// withParam - строка подключения с другим значением параметра (например, к другой базе того же сервера)
+func withParam(connStr, key, value string) string {
+	parts := strings.Fields(connStr)
+	for i, part := range parts {
+		if strings.HasPrefix(part, key+"=") {
+			parts[i] = key + "=" + value
+			return strings.Join(parts, " ")
+		}
+	}
+	return strings.Join(append(parts, key+"="+value), " ")
+}
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		manager := newTestManager(t)
+		return repotest.Repos{
+			Users:       manager.UsersRepo,
+			Binaries:    manager.BinariesRepo,
+			Cards:       manager.CardsRepo,
+			Credentials: manager.CredentialsRepo,
+			Texts:       manager.TextsRepo,
+		}
+	}, repotest.Options{Serialized: true})
+}
+
+func TestWithParam(t *testing.T) {
+	require.Equal(t, "host=db user=app dbname=other sslmode=disable", withParam("host=db user=app dbname=gophkeeper sslmode=disable", "dbname", "other"))
+	require.Equal(t, "host=db user=app", withParam("host=db", "user", "app"))
+}
@@ -0,0 +1,258 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRowLevelSecurity - политики строк не пропускают чужие записи даже в запросах без условия на владельца.
+// Запросы выполняются от имени отдельной роли без прав суперпользователя, не владеющей таблицами, - как у сервера
+// с database.owner_dsn
+func TestRowLevelSecurity(t *testing.T) {
+	manager := newTestManager(t)
+	if testServer.role == "" {
+		t.Skip("roles cannot be created on the test server: row-level security does not apply to the table owner")
+	}
+	ctx := context.Background()
+
+	t.Run("Роль сервера", func(t *testing.T) {
+		var role string
+		var privileged bool
+		require.NoError(t, manager.DB.QueryRow(ctx, "SELECT current_user, rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&role, &privileged))
+		assert.Equal(t, testTenantRole, role)
+		assert.False(t, privileged)
+	})
+
+	alice := customcontext.WithUserID(ctx, "alice")
+	bob := customcontext.WithUserID(ctx, "bob")
+	for _, login := range []string{"alice", "bob"} {
+		_, err := manager.UsersRepo.Create(ctx, &dtos.NewUser{Login: login, Password: "password"})
+		require.NoError(t, err)
+	}
+
+	db := newTenantConn(manager.DB)
+
+	// У alice три личные карты: одну она открыла bob для чтения, другую - для изменения. У bob - своя заметка
+	private, shared, writable, note := uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()
+	for _, id := range []string{private, shared, writable} {
+		card, err := manager.CardsRepo.Create(alice, &dtos.NewCardInformation{NewSecureEntity: dtos.NewSecureEntity{ID: id}, Number: "4111111111111111"})
+		require.NoError(t, err)
+		require.NotNil(t, card)
+	}
+	_, err := manager.TextsRepo.Create(bob, &dtos.NewTextData{NewSecureEntity: dtos.NewSecureEntity{ID: note}, Data: "note"})
+	require.NoError(t, err)
+
+	for id, permission := range map[string]string{shared: entities.PermissionRead, writable: entities.PermissionWrite} {
+		// Поделиться можно только записью, переведённой на ключ записи
+		_, err = db.Exec(alice, "UPDATE Cards SET entrykey = 'key' WHERE id = $1", id)
+		require.NoError(t, err)
+		grant, err := manager.ShareRepo.Create(alice, &dtos.NewShareGrant{EntityType: "card", EntityID: id, RecipientID: "bob", Permission: permission, EntryKey: "key for bob"})
+		require.NoError(t, err)
+		require.NotNil(t, grant)
+		_, err = manager.ShareRepo.Accept(bob, grant.ID)
+		require.NoError(t, err)
+	}
+
+	// В коллекции организации alice лежит карта. bob состоит в организации с ролью только для чтения
+	org, err := manager.OrgRepo.Create(alice, &dtos.NewOrganization{Name: "team"})
+	require.NoError(t, err)
+	collection, err := manager.OrgRepo.CreateCollection(alice, &dtos.NewCollection{OrgID: org.ID, Name: "cards",
+		Keys: []entities.CollectionKey{{Login: "alice", EncryptedKey: "collection key"}}})
+	require.NoError(t, err)
+	require.NotNil(t, collection)
+	_, err = manager.OrgRepo.AddMember(alice, &dtos.NewOrgMember{OrgID: org.ID, Login: "bob", Role: entities.RoleReadOnly})
+	require.NoError(t, err)
+	_, err = manager.OrgRepo.ActivateMember(bob, org.ID, "bob")
+	require.NoError(t, err)
+
+	team := uuid.NewString()
+	_, err = manager.CardsRepo.Create(alice, &dtos.NewCardInformation{NewSecureEntity: dtos.NewSecureEntity{ID: team, CollectionID: collection.ID, KeyVersion: 1}, Number: "5500"})
+	require.NoError(t, err)
+
+	ids := func(ctx context.Context, query string) []string {
+		rows, err := db.Query(ctx, query)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	t.Run("Чтение без условия на владельца", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{private, shared, writable, team}, ids(alice, "SELECT id::text FROM Cards"))
+		assert.Empty(t, ids(alice, "SELECT id::text FROM Texts"))
+
+		assert.ElementsMatch(t, []string{shared, writable, team}, ids(bob, "SELECT id::text FROM Cards"), "bob видит открытые ему карты и карту коллекции")
+		assert.ElementsMatch(t, []string{note}, ids(bob, "SELECT id::text FROM Texts"))
+	})
+
+	t.Run("Запрос без пользователя", func(t *testing.T) {
+		assert.Empty(t, ids(ctx, "SELECT id::text FROM Cards"))
+
+		// Подключение без настройки транзакции тоже не видит записей
+		var count int
+		require.NoError(t, manager.DB.QueryRow(ctx, "SELECT COUNT(*) FROM Cards").Scan(&count))
+		assert.Zero(t, count)
+	})
+
+	t.Run("Переменная обхода у роли сервера", func(t *testing.T) {
+		tx, err := manager.DB.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, "SELECT set_config('"+bypassRLSSetting+"', 'on', TRUE), set_config('"+currentUserSetting+"', 'bob', TRUE)")
+		require.NoError(t, err)
+
+		var count int
+		require.NoError(t, tx.QueryRow(ctx, "SELECT COUNT(*) FROM Cards").Scan(&count))
+		assert.Equal(t, 3, count, "app.bypass_rls действует только для владельца таблиц")
+	})
+
+	t.Run("Изменение без условия на владельца", func(t *testing.T) {
+		tag, err := db.Exec(bob, "UPDATE Texts SET metadata = 'changed'")
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, tag.RowsAffected())
+
+		tag, err = db.Exec(alice, "DELETE FROM Texts")
+		require.NoError(t, err)
+		assert.Zero(t, tag.RowsAffected(), "alice не может удалить запись bob")
+		assert.ElementsMatch(t, []string{note}, ids(bob, "SELECT id::text FROM Texts"))
+	})
+
+	t.Run("Права на изменение и удаление", func(t *testing.T) {
+		// Из видимых bob карт менять он может только открытую ему для изменения
+		tag, err := db.Exec(bob, "UPDATE Cards SET metadata = 'changed by bob'")
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, tag.RowsAffected())
+		assert.ElementsMatch(t, []string{writable}, ids(alice, "SELECT id::text FROM Cards WHERE metadata = 'changed by bob'"))
+
+		// Удалять чужие личные записи и записи коллекции с ролью только для чтения нельзя
+		tag, err = db.Exec(bob, "DELETE FROM Cards")
+		require.NoError(t, err)
+		assert.Zero(t, tag.RowsAffected())
+		assert.Len(t, ids(alice, "SELECT id::text FROM Cards"), 4)
+	})
+
+	t.Run("Присвоение чужой записи", func(t *testing.T) {
+		_, err := db.Exec(bob, "UPDATE Cards SET ownerid = 'bob' WHERE id = $1", writable)
+		assert.Error(t, err)
+
+		_, err = db.Exec(alice, "UPDATE Cards SET collectionid = NULL WHERE id = $1", team)
+		assert.Error(t, err, "запись не выносится из коллекции")
+	})
+
+	t.Run("Запись от имени другого пользователя", func(t *testing.T) {
+		_, err := db.Exec(bob, "INSERT INTO Texts (id, data, metadata, ownerid) VALUES ($1, 'forged', '', 'alice')", uuid.NewString())
+		assert.Error(t, err)
+		assert.Empty(t, ids(alice, "SELECT id::text FROM Texts"))
+
+		// Роль только для чтения не позволяет создавать записи в коллекции
+		_, err = db.Exec(bob, "INSERT INTO Texts (id, data, metadata, ownerid, collectionid, keyversion) VALUES ($1, 'note', '', 'bob', NULLIF($2, '')::integer, 1)", uuid.NewString(), collection.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("История записей", func(t *testing.T) {
+		require.NoError(t, manager.HistoryRepo.Append(alice, "card", private, 0, time.Time{}, func(context.Context) error { return nil }))
+
+		assert.Len(t, ids(alice, "SELECT entity_id::text FROM entry_history"), 1)
+		assert.Empty(t, ids(bob, "SELECT entity_id::text FROM entry_history"))
+
+		// Версии сохраняет тот, кто может менять запись
+		assert.NoError(t, manager.HistoryRepo.Append(bob, "card", writable, 0, time.Time{}, func(context.Context) error { return nil }))
+		assert.Error(t, manager.HistoryRepo.Append(bob, "card", shared, 0, time.Time{}, func(context.Context) error { return nil }))
+	})
+
+	t.Run("Служебные функции", func(t *testing.T) {
+		// ИД чужой записи занят, хотя сама запись bob не видна
+		taken, err := manager.AccessRepo.EntryExists(bob, "card", private)
+		require.NoError(t, err)
+		assert.True(t, taken)
+		taken, err = manager.AccessRepo.EntryExists(bob, "card", uuid.NewString())
+		require.NoError(t, err)
+		assert.False(t, taken)
+
+		usage, err := manager.AccountRepo.Usage(bob, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, 4, usage.Cards)
+
+		deleted, err := manager.TextsRepo.Delete(bob, note)
+		require.NoError(t, err)
+		require.NotNil(t, deleted)
+
+		purged, err := manager.TrashRepo.Purge(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+		assert.Equal(t, note, purged[0].ID)
+		assert.Empty(t, ids(bob, "SELECT id::text FROM Texts"))
+	})
+}
+
+// TestInTransaction - операции задачи фиксируются вместе, а пользователь транзакции меняется вместе с контекстом
+func TestInTransaction(t *testing.T) {
+	manager := newTestManager(t)
+	ctx := context.Background()
+
+	alice := customcontext.WithUserID(ctx, "alice")
+	for _, login := range []string{"alice", "bob"} {
+		_, err := manager.UsersRepo.Create(ctx, &dtos.NewUser{Login: login, Password: "password"})
+		require.NoError(t, err)
+	}
+
+	t.Run("Откат при ошибке", func(t *testing.T) {
+		id := uuid.NewString()
+		failure := errors.New("task failed")
+		err := manager.InTransaction(alice, func(ctx context.Context) error {
+			_, err := manager.TextsRepo.Create(ctx, &dtos.NewTextData{NewSecureEntity: dtos.NewSecureEntity{ID: id}, Data: "note"})
+			require.NoError(t, err)
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		text, err := manager.TextsRepo.Get(alice, id)
+		require.NoError(t, err)
+		assert.Nil(t, text)
+	})
+
+	t.Run("Фиксация", func(t *testing.T) {
+		id := uuid.NewString()
+		err := manager.InTransaction(alice, func(ctx context.Context) error {
+			if _, err := manager.TextsRepo.Create(ctx, &dtos.NewTextData{NewSecureEntity: dtos.NewSecureEntity{ID: id}, Data: "note"}); err != nil {
+				return err
+			}
+
+			// Несохранённая запись видна внутри транзакции
+			text, err := manager.TextsRepo.Get(ctx, id)
+			require.NoError(t, err)
+			assert.NotNil(t, text)
+
+			if testServer.role != "" {
+				// Запрос от имени другого пользователя в той же транзакции проходит через его политики
+				text, err = manager.TextsRepo.Get(customcontext.WithUserID(ctx, "bob"), id)
+				require.NoError(t, err)
+				assert.Nil(t, text)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		text, err := manager.TextsRepo.Get(alice, id)
+		require.NoError(t, err)
+		assert.NotNil(t, text)
+	})
+}
@@ -0,0 +1,32 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSchemaOnlyInMigrations - схему базы данных меняют только миграции, репозитории её не создают
+func TestSchemaOnlyInMigrations(t *testing.T) {
+	ddl := regexp.MustCompile(`(?i)\b(CREATE|ALTER|DROP)\s+(TABLE|INDEX)\b`)
+
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		source, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.False(t, ddl.Match(source), "%s changes the schema outside of migrations", file)
+	}
+}
@@ -0,0 +1,124 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// shareGrantColumns - поля права в порядке сканирования
+const shareGrantColumns = "id, entity_type, entity_id, owner_id, recipient_id, permission, status, entrykey, created_at"
+
+// sharableTables - таблицы записей, которыми можно поделиться, по типу сущности
+var sharableTables = map[string]string{
+	"binary":      "Binaries",
+	"card":        "Cards",
+	"credentials": "Credentials",
+	"text":        "Texts",
+}
+
+// PgShareRepo - права пользователей на чужие записи
+type PgShareRepo struct {
+	db *tenantConn
+}
+
+// NewPgShareRepo - инициализация репозитория
+func NewPgShareRepo(db *pgx.Conn) (*PgShareRepo, error) {
+	return &PgShareRepo{db: newTenantConn(db)}, nil
+}
+
+// Create - поделиться записью, уже переведённой на ключ записи (право владельца проверяется политикой доступа до вызова).
+// Повторная выдача права тому же получателю заменяет права и ключ
+func (r *PgShareRepo) Create(ctx context.Context, grant *dtos.NewShareGrant) (*entities.ShareGrant, error) {
+	table, known := sharableTables[grant.EntityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", grant.EntityType)
+	}
+
+	query := `
+		INSERT INTO share_grants (entity_type, entity_id, owner_id, recipient_id, permission, entrykey)
+		SELECT $1, e.id, e.ownerid, $3, $4, $5 FROM ` + table + ` e
+		WHERE e.id = $2 AND e.collectionid IS NULL AND e.entrykey IS NOT NULL AND e.deleted_at IS NULL
+		ON CONFLICT (entity_type, entity_id, recipient_id) DO UPDATE SET permission = EXCLUDED.permission, entrykey = EXCLUDED.entrykey
+		RETURNING ` + shareGrantColumns
+
+	created, err := scanShareGrant(r.db.QueryRow(ctx, query, grant.EntityType, grant.EntityID, grant.RecipientID, grant.Permission, grant.EntryKey))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Запись не найдена или ещё не переведена на ключ записи
+		}
+		return nil, fmt.Errorf("failed to create share grant: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetAll - получить права, выданные текущим пользователем и выданные ему
+func (r *PgShareRepo) GetAll(ctx context.Context) ([]entities.ShareGrant, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	rows, err := r.db.Query(ctx, "SELECT "+shareGrantColumns+" FROM share_grants WHERE owner_id = $1 OR recipient_id = $1 ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share grants: %w", err)
+	}
+
+	defer rows.Close()
+
+	var grants []entities.ShareGrant
+	for rows.Next() {
+		grant, err := scanShareGrant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share grant: %w", err)
+		}
+		grants = append(grants, *grant)
+	}
+
+	return grants, rows.Err()
+}
+
+// Accept - принять приглашение, адресованное текущему пользователю
+func (r *PgShareRepo) Accept(ctx context.Context, id string) (*entities.ShareGrant, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	grant, err := scanShareGrant(r.db.QueryRow(ctx, "UPDATE share_grants SET status = 'accepted' WHERE id = $1 AND recipient_id = $2 RETURNING "+shareGrantColumns, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Приглашение не найдено
+		}
+		return nil, fmt.Errorf("failed to accept share grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+// Delete - отозвать право (владелец) или отказаться от него (получатель)
+func (r *PgShareRepo) Delete(ctx context.Context, id string) (*entities.ShareGrant, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	grant, err := scanShareGrant(r.db.QueryRow(ctx, "DELETE FROM share_grants WHERE id = $1 AND (owner_id = $2 OR recipient_id = $2) RETURNING "+shareGrantColumns, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Право не найдено
+		}
+		return nil, fmt.Errorf("failed to delete share grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+// scanShareGrant - прочитать право из строки результата
+func scanShareGrant(row pgx.Row) (*entities.ShareGrant, error) {
+	var grant entities.ShareGrant
+	err := row.Scan(&grant.ID, &grant.EntityType, &grant.EntityID, &grant.OwnerID, &grant.RecipientID, &grant.Permission, &grant.Status, &grant.EntryKey, &grant.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grant, nil
+}
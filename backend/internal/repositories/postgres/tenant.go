@@ -0,0 +1,281 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Переменные транзакции, по которым политики строк (RLS) таблиц записей отбирают строки пользователя (миграция 013)
+const (
+	currentUserSetting = "app.current_user" // логин пользователя, от имени которого выполняется транзакция
+	bypassRLSSetting   = "app.bypass_rls"   // "on" - транзакция владельца таблиц видит записи всех пользователей
+)
+
+// bypassRLS - открыть транзакции записи всех пользователей (резервное копирование и восстановление).
+// Переменная действует только для владельца таблиц, поэтому под другой ролью копия вышла бы неполной - это ошибка
+func bypassRLS(ctx context.Context, tx pgx.Tx) error {
+	var role string
+	var bypassed bool
+	query := `
+		SELECT current_user, set_config('` + bypassRLSSetting + `', 'on', TRUE) = 'on' AND (
+			EXISTS (SELECT 1 FROM pg_roles WHERE rolname = current_user AND (rolsuper OR rolbypassrls))
+			OR EXISTS (SELECT 1 FROM pg_class WHERE oid = to_regclass('binaries') AND pg_get_userbyid(relowner) = current_user)
+			OR to_regclass('binaries') IS NULL)`
+	if err := tx.QueryRow(ctx, query).Scan(&role, &bypassed); err != nil {
+		return fmt.Errorf("failed to configure transaction: %w", err)
+	}
+	if !bypassed {
+		return fmt.Errorf("role %s does not own the tables and cannot access all users' entries: connect as the schema owner (database.owner_dsn)", role)
+	}
+	return nil
+}
+
+// tenantConn - подключение, которое выполняет запросы от имени пользователя из контекста: в транзакции задачи
+// сервиса хранения (см. InTransaction), а вне задачи - каждый запрос в отдельной транзакции.
+// Политики строк пропускают только записи, доступные этому пользователю, поэтому запрос, в котором забыто условие
+// на владельца, не увидит чужих записей. Запрос без пользователя в контексте не видит ни одной записи.
+// Служебные запросы по записям всех пользователей вызывают функции владельца таблиц (entry_id_taken, storage_usage, purge_trash)
+type tenantConn struct {
+	conn *pgx.Conn
+}
+
+// newTenantConn - обернуть подключение
+func newTenantConn(conn *pgx.Conn) *tenantConn {
+	return &tenantConn{conn: conn}
+}
+
+// taskTxKey - ключ контекста, под которым лежит транзакция задачи
+type taskTxKey struct{}
+
+// taskTx - транзакция задачи сервиса хранения
+type taskTx struct {
+	tx   pgx.Tx
+	user string // пользователь, записанный в транзакцию последним
+}
+
+// useUser - записать в транзакцию задачи пользователя из контекста, если задача обращается к данным другого пользователя
+// (например, к хранилищу доверителя при экстренном доступе). Обычно пользователь один на всю задачу и запрос не нужен
+func (t *taskTx) useUser(ctx context.Context) error {
+	user := customcontext.GetUserID(ctx)
+	if user == t.user {
+		return nil
+	}
+
+	if err := setCurrentUser(ctx, t.tx, user); err != nil {
+		return err
+	}
+	t.user = user
+	return nil
+}
+
+// currentTaskTx - транзакция задачи из контекста (nil вне задачи)
+func currentTaskTx(ctx context.Context) *taskTx {
+	t, _ := ctx.Value(taskTxKey{}).(*taskTx)
+	return t
+}
+
+// setCurrentUser - записать пользователя в транзакцию. Значение действует до конца транзакции
+func setCurrentUser(ctx context.Context, tx pgx.Tx, user string) error {
+	_, err := tx.Exec(ctx, "SELECT set_config('"+currentUserSetting+"', $1, TRUE)", user)
+	return err
+}
+
+// inTransaction - выполнить fn в одной транзакции от имени пользователя из контекста.
+// Вложенный вызов выполняется в уже открытой транзакции
+func inTransaction(ctx context.Context, conn *pgx.Conn, fn func(ctx context.Context) error) error {
+	if currentTaskTx(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	user := customcontext.GetUserID(ctx)
+	if err := setCurrentUser(ctx, tx, user); err != nil {
+		tx.Rollback(context.Background())
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, taskTxKey{}, &taskTx{tx: tx, user: user})); err != nil {
+		tx.Rollback(context.Background())
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// isolated - выполнить fn так, чтобы её ошибка не прерывала транзакцию задачи: в точке сохранения, к которой
+// транзакция откатывается при ошибке. Вне задачи fn выполняется как есть
+func (c *tenantConn) isolated(ctx context.Context, fn func(ctx context.Context) error) error {
+	t := currentTaskTx(ctx)
+	if t == nil {
+		return fn(ctx)
+	}
+
+	savepoint, err := t.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	nested := &taskTx{tx: savepoint, user: t.user}
+	if err := fn(context.WithValue(ctx, taskTxKey{}, nested)); err != nil {
+		savepoint.Rollback(context.Background())
+		return err
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return err
+	}
+
+	t.user = nested.user
+	return nil
+}
+
+// begin - начать транзакцию для одного запроса и записать в неё пользователя из контекста
+func (c *tenantConn) begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := c.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setCurrentUser(ctx, tx, customcontext.GetUserID(ctx)); err != nil {
+		tx.Rollback(context.Background())
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Exec - выполнить запрос
+func (c *tenantConn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if t := currentTaskTx(ctx); t != nil {
+		if err := t.useUser(ctx); err != nil {
+			return pgconn.CommandTag{}, err
+		}
+		return t.tx.Exec(ctx, sql, args...)
+	}
+
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		tx.Rollback(context.Background())
+		return tag, err
+	}
+
+	return tag, tx.Commit(ctx)
+}
+
+// Query - выполнить запрос, возвращающий строки. Отдельная транзакция запроса завершается, когда строки прочитаны или закрыты
+func (c *tenantConn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if t := currentTaskTx(ctx); t != nil {
+		if err := t.useUser(ctx); err != nil {
+			return nil, err
+		}
+		return t.tx.Query(ctx, sql, args...)
+	}
+
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		tx.Rollback(context.Background())
+		return nil, err
+	}
+
+	return &tenantRows{Rows: rows, ctx: ctx, tx: tx}, nil
+}
+
+// QueryRow - выполнить запрос, возвращающий одну строку. Запрос выполняется при чтении строки
+func (c *tenantConn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &tenantRow{conn: c, ctx: ctx, sql: sql, args: args}
+}
+
+// tenantRows - строки результата запроса в транзакции пользователя
+type tenantRows struct {
+	pgx.Rows
+	ctx  context.Context
+	tx   pgx.Tx
+	done bool
+	err  error // ошибка завершения транзакции
+}
+
+// Next - перейти к следующей строке (после последней строки транзакция завершается)
+func (r *tenantRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	r.finish()
+	return false
+}
+
+// Close - закрыть строки и завершить транзакцию
+func (r *tenantRows) Close() {
+	r.finish()
+}
+
+// Err - ошибка чтения строк или завершения транзакции
+func (r *tenantRows) Err() error {
+	if err := r.Rows.Err(); err != nil {
+		return err
+	}
+	return r.err
+}
+
+// finish - зафиксировать транзакцию (или откатить её, если чтение строк завершилось ошибкой)
+func (r *tenantRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+
+	r.Rows.Close()
+	if r.Rows.Err() != nil {
+		r.tx.Rollback(context.Background())
+		return
+	}
+	r.err = r.tx.Commit(r.ctx)
+}
+
+// tenantRow - строка результата запроса в транзакции пользователя
+type tenantRow struct {
+	conn *tenantConn
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+// Scan - выполнить запрос и прочитать строку
+func (r *tenantRow) Scan(dest ...any) error {
+	if t := currentTaskTx(r.ctx); t != nil {
+		if err := t.useUser(r.ctx); err != nil {
+			return err
+		}
+		return t.tx.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	}
+
+	tx, err := r.conn.begin(r.ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...); err != nil {
+		tx.Rollback(context.Background())
+		return err
+	}
+
+	return tx.Commit(r.ctx)
+}
@@ -14,19 +14,37 @@ import (
 
 // PgTextsRepo - репозиторий с текстовыми данными
 type PgTextsRepo struct {
-	db *pgx.Conn
+	db *tenantConn
 }
 
 // NewPgTextsRepo - инициализация репозитория
 func NewPgTextsRepo(db *pgx.Conn) (*PgTextsRepo, error) {
-	return &PgTextsRepo{db: db}, nil
+	return &PgTextsRepo{db: newTenantConn(db)}, nil
 }
 
-// GetAll - получить все сущности (при наличии прав у текущего пользователя)
+// textsVisibleQuery - тексты, доступные пользователю $1 (кроме записей в корзине): собственные личные записи, чужие записи, которыми с ним поделились
+// (приглашение принято), и записи коллекций организаций, в которых он состоит. Для чужих записей возвращаются ключ,
+// зашифрованный для пользователя (ключ записи или ключ коллекции нужной версии), и права пользователя
+const textsVisibleQuery = `
+	SELECT id, data, metadata, ownerid, COALESCE(entrykey, '') AS entrykey, '' AS permission, '' AS collectionid, keyversion
+	FROM Texts WHERE ownerid = $1 AND collectionid IS NULL AND deleted_at IS NULL
+	UNION ALL
+	SELECT t.id, t.data, t.metadata, t.ownerid, g.entrykey, g.permission, '', t.keyversion
+	FROM Texts t JOIN share_grants g ON g.entity_type = 'text' AND g.entity_id = t.id
+	WHERE g.recipient_id = $1 AND g.status = 'accepted' AND t.deleted_at IS NULL
+	UNION ALL
+	SELECT t.id, t.data, t.metadata, t.ownerid, k.encrypted_key,
+		CASE WHEN m.role = 'readonly' THEN 'read' ELSE 'write' END, t.collectionid::text, t.keyversion
+	FROM Texts t JOIN collections col ON col.id = t.collectionid
+	JOIN org_members m ON m.org_id = col.org_id AND m.login = $1 AND m.status = 'active'
+	JOIN collection_keys k ON k.collection_id = col.id AND k.login = $1 AND k.version = t.keyversion
+	WHERE t.deleted_at IS NULL`
+
+// GetAll - получить все доступные текущему пользователю сущности
 func (r *PgTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
 	userID := customcontext.GetUserID((ctx))
 
-	rows, err := r.db.Query(ctx, "SELECT id, data, metadata, ownerid FROM Texts WHERE ownerid = $1", userID)
+	rows, err := r.db.Query(ctx, textsVisibleQuery, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get texts: %w", err)
 	}
@@ -40,7 +58,7 @@ func (r *PgTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
 	var texts []entities.TextData
 	for rows.Next() {
 		var text entities.TextData
-		err := rows.Scan(&text.ID, &text.Data, &text.Metadata, &text.OwnerID)
+		err := rows.Scan(&text.ID, &text.Data, &text.Metadata, &text.OwnerID, &text.EntryKey, &text.Permission, &text.CollectionID, &text.KeyVersion)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan text: %w", err)
 		}
@@ -50,12 +68,12 @@ func (r *PgTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
 	return texts, nil
 }
 
-// Get - получить сущность по ИД (при наличии прав у текущего пользователя)
+// Get - получить сущность по ИД (если она доступна текущему пользователю)
 func (r *PgTextsRepo) Get(ctx context.Context, id string) (*entities.TextData, error) {
 	userID := customcontext.GetUserID((ctx))
 
 	var text entities.TextData
-	err := r.db.QueryRow(ctx, "SELECT id, data, metadata, ownerid FROM Texts WHERE id = $1 AND ownerid = $2", id, userID).Scan(&text.ID, &text.Data, &text.Metadata, &text.OwnerID)
+	err := r.db.QueryRow(ctx, "SELECT * FROM ("+textsVisibleQuery+") AS visible WHERE id = $2", userID, id).Scan(&text.ID, &text.Data, &text.Metadata, &text.OwnerID, &text.EntryKey, &text.Permission, &text.CollectionID, &text.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -67,25 +85,33 @@ func (r *PgTextsRepo) Get(ctx context.Context, id string) (*entities.TextData, e
 	return &text, nil
 }
 
-// Create - создать сущность
+// Create - создать сущность (личную или в коллекции организации) и вернуть её в представлении текущего пользователя
 func (r *PgTextsRepo) Create(ctx context.Context, text *dtos.NewTextData) (*entities.TextData, error) {
 	userID := customcontext.GetUserID(ctx)
 
-	var entity entities.TextData
-	err := r.db.QueryRow(ctx, "INSERT INTO Texts (data, metadata, ownerid) VALUES ($1, $2, $3) RETURNING id, data, metadata, ownerid", text.Data, text.Metadata, userID).Scan(&entity.ID, &entity.Data, &entity.Metadata, &entity.OwnerID)
+	query := "INSERT INTO Texts (id, data, metadata, ownerid, collectionid, keyversion) VALUES ($1, $2, $3, $4, NULLIF($5, '')::integer, $6) RETURNING id"
 
+	var id string
+	err := r.db.QueryRow(ctx, query, text.ID, text.Data, text.Metadata, userID, text.CollectionID, text.KeyVersion).Scan(&id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create text: %w", err)
 	}
-	return &entity, nil
+
+	return r.Get(ctx, id)
 }
 
-// Update - изменить сущность
+// Update - изменить сущность. Права проверяются политикой доступа до вызова:
+// пустой ключ записи оставляет прежний, версия ключа меняется только у записей коллекций
 func (r *PgTextsRepo) Update(ctx context.Context, text *entities.TextData) (*entities.TextData, error) {
-	userID := customcontext.GetUserID(ctx)
+	query := `
+		UPDATE Texts SET data = $2, metadata = $3,
+			entrykey = COALESCE(NULLIF($4, ''), entrykey),
+			keyversion = CASE WHEN collectionid IS NULL THEN keyversion ELSE $5 END
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id`
 
-	var updatedEntity entities.TextData
-	err := r.db.QueryRow(ctx, "UPDATE Texts SET data = $2, metadata = $3 WHERE id = $1 AND ownerid = $4 RETURNING id, data, metadata, ownerid", text.ID, text.Data, text.Metadata, userID).Scan(&updatedEntity.ID, &updatedEntity.Data, &updatedEntity.Metadata, &updatedEntity.OwnerID)
+	var id string
+	err := r.db.QueryRow(ctx, query, text.ID, text.Data, text.Metadata, text.EntryKey, text.KeyVersion).Scan(&id)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -94,15 +120,18 @@ func (r *PgTextsRepo) Update(ctx context.Context, text *entities.TextData) (*ent
 		return nil, err
 	}
 
-	return &updatedEntity, nil
+	return r.Get(ctx, id)
 }
 
-// Delete - удалить сущность
+// Delete - переместить сущность в корзину. Выданные на неё права сохраняются, но не действуют, пока запись в корзине.
+// Права проверяются политикой доступа до вызова
 func (r *PgTextsRepo) Delete(ctx context.Context, id string) (*entities.TextData, error) {
-	userID := customcontext.GetUserID((ctx))
+	query := `
+		UPDATE Texts SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, data, metadata, ownerid, COALESCE(entrykey, '') AS entrykey, '' AS permission, COALESCE(collectionid::text, '') AS collectionid, keyversion`
 
-	var deletedText entities.TextData
-	err := r.db.QueryRow(ctx, "DELETE FROM Texts WHERE id = $1 AND ownerid = $2 RETURNING id, data, metadata, ownerid", id, userID).Scan(&deletedText.ID, &deletedText.Data, &deletedText.Metadata, &deletedText.OwnerID)
+	var deleted entities.TextData
+	err := r.db.QueryRow(ctx, query, id).Scan(&deleted.ID, &deleted.Data, &deleted.Metadata, &deleted.OwnerID, &deleted.EntryKey, &deleted.Permission, &deleted.CollectionID, &deleted.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -111,5 +140,5 @@ func (r *PgTextsRepo) Delete(ctx context.Context, id string) (*entities.TextData
 		return nil, err
 	}
 
-	return &deletedText, err
+	return &deleted, nil
 }
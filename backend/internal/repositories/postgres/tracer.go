@@ -0,0 +1,49 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer - реализация pgx.QueryTracer: создаёт спан на каждый запрос к БД.
+// В спан попадает только текст запроса - значения параметров (в том числе зашифрованные данные) не записываются
+type queryTracer struct{}
+
+// Ключ контекста для передачи спана от начала запроса к его завершению
+type querySpanKey struct{}
+
+// TraceQueryStart - вызывается pgx перед выполнением запроса
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, span := tracing.Tracer().Start(ctx, "pgx.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", data.SQL),
+		),
+	)
+
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+// TraceQueryEnd - вызывается pgx после выполнения запроса
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(querySpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+
+	span.End()
+}
@@ -0,0 +1,187 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// PgTrashRepo - корзина: записи всех типов с заполненным deleted_at
+type PgTrashRepo struct {
+	db *tenantConn
+}
+
+// NewPgTrashRepo - инициализация репозитория
+func NewPgTrashRepo(db *pgx.Conn) (*PgTrashRepo, error) {
+	return &PgTrashRepo{db: newTenantConn(db)}, nil
+}
+
+// trashQuery - записи таблицы table в корзине в представлении пользователя $1: у личной записи - ключ записи владельца,
+// у записи коллекции - ключ коллекции нужной версии, зашифрованный для пользователя, и его роль в организации.
+// Столбцы идут в порядке полей scanTrashItem
+func trashQuery(entityType, table string) string {
+	return `
+	SELECT '` + entityType + `' AS entity_type, e.id, COALESCE(e.metadata, '') AS metadata, e.ownerid,
+		CASE WHEN e.collectionid IS NULL THEN COALESCE(e.entrykey, '') ELSE COALESCE(k.encrypted_key, '') END AS entrykey,
+		COALESCE(e.collectionid::text, '') AS collectionid, e.keyversion, e.deleted_at, COALESCE(m.role, '') AS role
+	FROM ` + table + ` e
+	LEFT JOIN collections col ON col.id = e.collectionid
+	LEFT JOIN org_members m ON m.org_id = col.org_id AND m.login = $1 AND m.status = 'active'
+	LEFT JOIN collection_keys k ON k.collection_id = col.id AND k.login = $1 AND k.version = e.keyversion
+	WHERE e.deleted_at IS NOT NULL`
+}
+
+// GetAll - записи в корзине, созданные текущим пользователем лично или лежащие в коллекциях его организаций
+func (r *PgTrashRepo) GetAll(ctx context.Context) ([]entities.TrashItem, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	parts := make([]string, 0, len(sharableTables))
+	for entityType, table := range sharableTables {
+		parts = append(parts, trashQuery(entityType, table))
+	}
+
+	query := "SELECT * FROM (" + strings.Join(parts, "\n\tUNION ALL") + `) AS trash
+		WHERE (collectionid = '' AND ownerid = $1) OR role <> ''
+		ORDER BY deleted_at DESC, entity_type, id`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trash: %w", err)
+	}
+	defer rows.Close()
+
+	var items []entities.TrashItem
+	for rows.Next() {
+		item, err := scanTrashItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trash item: %w", err)
+		}
+		items = append(items, *item)
+	}
+
+	return items, rows.Err()
+}
+
+// Get - запись в корзине с ролью текущего пользователя в организации её коллекции
+func (r *PgTrashRepo) Get(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	table, known := sharableTables[entityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	query := "SELECT * FROM (" + trashQuery(entityType, table) + ") AS trash WHERE id = $2"
+
+	item, err := scanTrashItem(r.db.QueryRow(ctx, query, customcontext.GetUserID(ctx), id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Записи в корзине нет
+		}
+		return nil, fmt.Errorf("failed to get trash item: %w", err)
+	}
+
+	return item, nil
+}
+
+// Restore - вернуть запись из корзины. Права на неё не удалялись и снова начинают действовать
+func (r *PgTrashRepo) Restore(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	table, known := sharableTables[entityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	query := `
+		WITH item AS (SELECT * FROM (` + trashQuery(entityType, table) + `) AS trash WHERE id = $2)
+		UPDATE ` + table + ` e SET deleted_at = NULL FROM item WHERE e.id = item.id
+		RETURNING item.*`
+
+	item, err := scanTrashItem(r.db.QueryRow(ctx, query, customcontext.GetUserID(ctx), id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Записи в корзине нет
+		}
+		return nil, fmt.Errorf("failed to restore trash item: %w", err)
+	}
+
+	return item, nil
+}
+
+// Delete - окончательно удалить запись из корзины вместе с выданными на неё правами и историей
+func (r *PgTrashRepo) Delete(ctx context.Context, entityType, id string) (*entities.TrashItem, error) {
+	table, known := sharableTables[entityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	query := `
+		WITH item AS (SELECT * FROM (` + trashQuery(entityType, table) + `) AS trash WHERE id = $2),
+		revoked AS (
+			DELETE FROM share_grants g USING item WHERE g.entity_type = item.entity_type AND g.entity_id = item.id
+		), history AS (
+			DELETE FROM entry_history h USING item WHERE h.entity_type = item.entity_type AND h.entity_id = item.id
+		)
+		DELETE FROM ` + table + ` e USING item WHERE e.id = item.id
+		RETURNING item.*`
+
+	item, err := scanTrashItem(r.db.QueryRow(ctx, query, customcontext.GetUserID(ctx), id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Записи в корзине нет
+		}
+		return nil, fmt.Errorf("failed to delete trash item: %w", err)
+	}
+
+	return item, nil
+}
+
+// Purge - окончательно удалить записи, попавшие в корзину раньше before, вместе с правами на них и историей.
+// Каждая таблица очищается отдельным запросом: прерванная очистка продолжится при следующем запуске.
+// Очистка выполняется в фоне для записей всех пользователей функцией владельца таблиц purge_trash (миграция 013)
+func (r *PgTrashRepo) Purge(ctx context.Context, before time.Time) ([]entities.TrashItem, error) {
+	var purged []entities.TrashItem
+
+	for entityType, table := range sharableTables {
+		rows, err := r.db.Query(ctx, "SELECT * FROM purge_trash($1, $2)", entityType, before)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+
+		for rows.Next() {
+			item := entities.TrashItem{EntityType: entityType}
+			if err := rows.Scan(&item.ID, &item.Metadata, &item.OwnerID, &item.CollectionID, &item.KeyVersion, &item.DeletedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan purged %s: %w", table, err)
+			}
+			purged = append(purged, item)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+	}
+
+	sort.Slice(purged, func(i, j int) bool {
+		return purged[i].DeletedAt.Before(purged[j].DeletedAt)
+	})
+
+	return purged, nil
+}
+
+// scanTrashItem - прочитать запись в корзине из строки результата trashQuery
+func scanTrashItem(row pgx.Row) (*entities.TrashItem, error) {
+	var item entities.TrashItem
+	err := row.Scan(&item.EntityType, &item.ID, &item.Metadata, &item.OwnerID, &item.EntryKey, &item.CollectionID,
+		&item.KeyVersion, &item.DeletedAt, &item.OrgRole)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
@@ -0,0 +1,52 @@
+// Репозиторий postgres
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+// PgUserKeysRepo - ключи пользователей для обмена записями
+type PgUserKeysRepo struct {
+	db *tenantConn
+}
+
+// NewPgUserKeysRepo - инициализация репозитория
+func NewPgUserKeysRepo(db *pgx.Conn) (*PgUserKeysRepo, error) {
+	return &PgUserKeysRepo{db: newTenantConn(db)}, nil
+}
+
+// Set - сохранить (или заменить) ключи пользователя
+func (r *PgUserKeysRepo) Set(ctx context.Context, keys *entities.UserKeys) (*entities.UserKeys, error) {
+	query := `
+		INSERT INTO user_keys (login, public_key, encrypted_private_key) VALUES ($1, $2, $3)
+		ON CONFLICT (login) DO UPDATE SET public_key = EXCLUDED.public_key, encrypted_private_key = EXCLUDED.encrypted_private_key, updated_at = NOW()
+		RETURNING login, public_key, encrypted_private_key`
+
+	var saved entities.UserKeys
+	err := r.db.QueryRow(ctx, query, keys.Login, keys.PublicKey, keys.EncryptedPrivateKey).Scan(&saved.Login, &saved.PublicKey, &saved.EncryptedPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save user keys: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// Get - получить ключи пользователя по логину
+func (r *PgUserKeysRepo) Get(ctx context.Context, login string) (*entities.UserKeys, error) {
+	var keys entities.UserKeys
+	err := r.db.QueryRow(ctx, "SELECT login, public_key, encrypted_private_key FROM user_keys WHERE login = $1", login).Scan(&keys.Login, &keys.PublicKey, &keys.EncryptedPrivateKey)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Ключей нет
+		}
+		return nil, fmt.Errorf("failed to get user keys: %w", err)
+	}
+
+	return &keys, nil
+}
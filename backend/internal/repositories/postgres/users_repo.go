@@ -13,17 +13,30 @@ import (
 
 // PgUsersRepo - репозиторий пользователями
 type PgUsersRepo struct {
-	db *pgx.Conn
+	db *tenantConn
 }
 
+// userColumns - столбцы пользователя в порядке сканирования scanUser
+const userColumns = "login, password, disabled, session_version"
+
 // NewPgUsersRepo - инициализация репозитория
 func NewPgUsersRepo(db *pgx.Conn) (*PgUsersRepo, error) {
-	return &PgUsersRepo{db: db}, nil
+	return &PgUsersRepo{db: newTenantConn(db)}, nil
+}
+
+// scanUser - прочитать пользователя из строки результата
+func scanUser(row pgx.Row) (*entities.User, error) {
+	var user entities.User
+	if err := row.Scan(&user.Login, &user.Password, &user.Disabled, &user.SessionVersion); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
 }
 
 // GetAll - получить все сущности
 func (r *PgUsersRepo) GetAll(ctx context.Context) ([]entities.User, error) {
-	rows, err := r.db.Query(ctx, "SELECT login, password FROM users")
+	rows, err := r.db.Query(ctx, "SELECT "+userColumns+" FROM users ORDER BY login")
 	if err != nil {
 		return nil, err
 	}
@@ -36,12 +49,11 @@ func (r *PgUsersRepo) GetAll(ctx context.Context) ([]entities.User, error) {
 
 	var users []entities.User
 	for rows.Next() {
-		var user entities.User
-		err := rows.Scan(&user.Login, &user.Password)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
-		users = append(users, user)
+		users = append(users, *user)
 	}
 
 	return users, nil
@@ -49,8 +61,7 @@ func (r *PgUsersRepo) GetAll(ctx context.Context) ([]entities.User, error) {
 
 // Get - получить сущность по ИД
 func (r *PgUsersRepo) Get(ctx context.Context, login string) (*entities.User, error) {
-	var user entities.User
-	err := r.db.QueryRow(ctx, "SELECT login, password FROM users WHERE login = $1", login).Scan(&user.Login, &user.Password)
+	user, err := scanUser(r.db.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE login = $1", login))
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -59,24 +70,23 @@ func (r *PgUsersRepo) Get(ctx context.Context, login string) (*entities.User, er
 		return nil, fmt.Errorf("failed to get entity: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // Create - создать сущность
 func (r *PgUsersRepo) Create(ctx context.Context, user *dtos.NewUser) (*entities.User, error) {
-	var entity entities.User
-	err := r.db.QueryRow(ctx, "INSERT INTO users (login, password) VALUES ($1, $2) RETURNING login, password", user.Login, user.Password).Scan(&entity.Login, &entity.Password)
+	entity, err := scanUser(r.db.QueryRow(ctx, "INSERT INTO users (login, password) VALUES ($1, $2) RETURNING "+userColumns, user.Login, user.Password))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return &entity, nil
+	return entity, nil
 }
 
-// Update - изменить сущность
+// Update - изменить сущность (пароль, блокировку и версию сессий)
 func (r *PgUsersRepo) Update(ctx context.Context, user *entities.User) (*entities.User, error) {
-	var updatedEntity entities.User
-	err := r.db.QueryRow(ctx, "UPDATE users SET password = $2 WHERE login = $1 RETURNING login, password", user.Login, user.Password).Scan(&updatedEntity.Login, &updatedEntity.Password)
+	updatedEntity, err := scanUser(r.db.QueryRow(ctx, "UPDATE users SET password = $2, disabled = $3, session_version = $4 WHERE login = $1 RETURNING "+userColumns,
+		user.Login, user.Password, user.Disabled, user.SessionVersion))
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -85,13 +95,12 @@ func (r *PgUsersRepo) Update(ctx context.Context, user *entities.User) (*entitie
 		return nil, err
 	}
 
-	return &updatedEntity, nil
+	return updatedEntity, nil
 }
 
 // Delete - удалить сущность
 func (r *PgUsersRepo) Delete(ctx context.Context, login string) (*entities.User, error) {
-	var deletedUser entities.User
-	err := r.db.QueryRow(ctx, "DELETE FROM users WHERE login = $1 RETURNING login, password", login).Scan(&deletedUser.Login, &deletedUser.Password)
+	deletedUser, err := scanUser(r.db.QueryRow(ctx, "DELETE FROM users WHERE login = $1 RETURNING "+userColumns, login))
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -100,5 +109,5 @@ func (r *PgUsersRepo) Delete(ctx context.Context, login string) (*entities.User,
 		return nil, err
 	}
 
-	return &deletedUser, err
+	return deletedUser, err
 }
@@ -0,0 +1,467 @@
+// repotest - общий набор тестов, которые должна проходить каждая реализация репозиториев сервера
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Repos - проверяемые репозитории
+type Repos struct {
+	Users       repositories.IRepository[entities.User, dtos.NewUser]
+	Binaries    repositories.IRepository[entities.BinaryData, dtos.NewBinaryData]
+	Cards       repositories.IRepository[entities.CardInformation, dtos.NewCardInformation]
+	Credentials repositories.IRepository[entities.Credentials, dtos.NewCredentials]
+	Texts       repositories.IRepository[entities.TextData, dtos.NewTextData]
+}
+
+// Options - особенности проверяемой реализации
+type Options struct {
+	// Serialized - реализация не допускает конкурентных вызовов: их упорядочивает очередь задач сервиса хранилища
+	Serialized bool
+}
+
+// entries - репозиторий записей одного типа и способ работы с его данными
+type entries[E, D any] struct {
+	repo   func(repos Repos) repositories.IRepository[E, D]
+	newDTO func(id string) *D
+	secure func(entity *E) *entities.SecureEntity
+}
+
+// Run - проверить репозитории. newRepos вызывается для каждого теста и возвращает пустые репозитории одного хранилища
+func Run(t *testing.T, newRepos func(t *testing.T) Repos, opts Options) {
+	t.Run("users", func(t *testing.T) {
+		runUsers(t, newRepos, opts)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		runEntries(t, newRepos, opts, entries[entities.BinaryData, dtos.NewBinaryData]{
+			repo: func(repos Repos) repositories.IRepository[entities.BinaryData, dtos.NewBinaryData] {
+				return repos.Binaries
+			},
+			newDTO: func(id string) *dtos.NewBinaryData {
+				return &dtos.NewBinaryData{NewSecureEntity: newSecureEntity(id), Data: []byte("data of " + id)}
+			},
+			secure: func(entity *entities.BinaryData) *entities.SecureEntity { return &entity.SecureEntity },
+		})
+	})
+
+	t.Run("card", func(t *testing.T) {
+		runEntries(t, newRepos, opts, entries[entities.CardInformation, dtos.NewCardInformation]{
+			repo: func(repos Repos) repositories.IRepository[entities.CardInformation, dtos.NewCardInformation] {
+				return repos.Cards
+			},
+			newDTO: func(id string) *dtos.NewCardInformation {
+				return &dtos.NewCardInformation{NewSecureEntity: newSecureEntity(id), Number: "4111111111111111", CardHolder: "ALICE", ExpirationDate: "12/30", CVV: "123"}
+			},
+			secure: func(entity *entities.CardInformation) *entities.SecureEntity { return &entity.SecureEntity },
+		})
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		runEntries(t, newRepos, opts, entries[entities.Credentials, dtos.NewCredentials]{
+			repo: func(repos Repos) repositories.IRepository[entities.Credentials, dtos.NewCredentials] {
+				return repos.Credentials
+			},
+			newDTO: func(id string) *dtos.NewCredentials {
+				return &dtos.NewCredentials{NewSecureEntity: newSecureEntity(id), Login: "alice", Password: "password of " + id}
+			},
+			secure: func(entity *entities.Credentials) *entities.SecureEntity { return &entity.SecureEntity },
+		})
+	})
+
+	t.Run("text", func(t *testing.T) {
+		runEntries(t, newRepos, opts, entries[entities.TextData, dtos.NewTextData]{
+			repo: func(repos Repos) repositories.IRepository[entities.TextData, dtos.NewTextData] { return repos.Texts },
+			newDTO: func(id string) *dtos.NewTextData {
+				return &dtos.NewTextData{NewSecureEntity: newSecureEntity(id), Data: "text of " + id}
+			},
+			secure: func(entity *entities.TextData) *entities.SecureEntity { return &entity.SecureEntity },
+		})
+	})
+}
+
+// runUsers - проверить репозиторий пользователей
+func runUsers(t *testing.T, newRepos func(t *testing.T) Repos, opts Options) {
+	ctx := context.Background()
+
+	t.Run("CRUD", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		created, err := repo.Create(ctx, &dtos.NewUser{Login: "alice", Password: "hash"})
+		require.NoError(t, err)
+		assert.Equal(t, "alice", created.Login)
+
+		got, err := repo.Get(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, created, got)
+
+		got.Password, got.SessionVersion = "new hash", 2
+		_, err = repo.Update(ctx, got)
+		require.NoError(t, err)
+
+		updated, err := repo.Get(ctx, "alice")
+		require.NoError(t, err)
+		require.NotNil(t, updated)
+		assert.Equal(t, "new hash", updated.Password)
+		assert.Equal(t, 2, updated.SessionVersion)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+
+		deleted, err := repo.Delete(ctx, "alice")
+		require.NoError(t, err)
+		require.NotNil(t, deleted)
+		assert.Equal(t, "alice", deleted.Login)
+
+		got, err = repo.Get(ctx, "alice")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Повторный логин", func(t *testing.T) {
+		repo := newRepos(t).Users
+		_, err := repo.Create(ctx, &dtos.NewUser{Login: "alice", Password: "hash"})
+		require.NoError(t, err)
+
+		_, err = repo.Create(ctx, &dtos.NewUser{Login: "alice", Password: "other"})
+		assert.Error(t, err)
+
+		got, err := repo.Get(ctx, "alice")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "hash", got.Password)
+	})
+
+	t.Run("Отсутствующий пользователь", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		got, err := repo.Get(ctx, "nobody")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+
+		updated, err := repo.Update(ctx, &entities.User{Login: "nobody", Password: "hash"})
+		require.NoError(t, err)
+		assert.Nil(t, updated)
+
+		deleted, err := repo.Delete(ctx, "nobody")
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("Отменённый контекст", func(t *testing.T) {
+		repo := newRepos(t).Users
+		_, err := repo.Create(ctx, &dtos.NewUser{Login: "alice", Password: "hash"})
+		require.NoError(t, err)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err = repo.GetAll(cancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Get(cancelled, "alice")
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Create(cancelled, &dtos.NewUser{Login: "bob", Password: "hash"})
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Update(cancelled, &entities.User{Login: "alice", Password: "changed"})
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Delete(cancelled, "alice")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1, "операции с отменённым контекстом не выполняются")
+		assert.Equal(t, "hash", all[0].Password)
+	})
+
+	t.Run("Конкурентный доступ", func(t *testing.T) {
+		if opts.Serialized {
+			t.Skip("вызовы реализации упорядочивает сервис хранилища")
+		}
+
+		repo := newRepos(t).Users
+		const workers = 8
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				login := fmt.Sprintf("user-%d", w)
+				if _, err := repo.Create(ctx, &dtos.NewUser{Login: login, Password: "hash"}); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := repo.Update(ctx, &entities.User{Login: login, Password: "hash", SessionVersion: 1}); err != nil {
+					errs <- err
+					return
+				}
+				_, err := repo.GetAll(ctx)
+				errs <- err
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, workers)
+	})
+}
+
+// runEntries - проверить репозиторий записей одного типа
+func runEntries[E, D any](t *testing.T, newRepos func(t *testing.T) Repos, opts Options, e entries[E, D]) {
+	alice := customcontext.WithUserID(context.Background(), "alice")
+	bob := customcontext.WithUserID(context.Background(), "bob")
+
+	// newRepo - пустой репозиторий записей и пользователи alice и bob (записи ссылаются на владельца)
+	newRepo := func(t *testing.T) repositories.IRepository[E, D] {
+		repos := newRepos(t)
+		for _, login := range []string{"alice", "bob"} {
+			_, err := repos.Users.Create(context.Background(), &dtos.NewUser{Login: login, Password: "hash"})
+			require.NoError(t, err)
+		}
+		return e.repo(repos)
+	}
+
+	t.Run("CRUD", func(t *testing.T) {
+		repo := newRepo(t)
+		id := newEntryID(t)
+
+		created, err := repo.Create(alice, e.newDTO(id))
+		require.NoError(t, err)
+		require.NotNil(t, created)
+		assert.Equal(t, id, e.secure(created).ID)
+		assert.Equal(t, "alice", e.secure(created).OwnerID)
+		assert.Equal(t, "metadata of "+id, e.secure(created).Metadata)
+
+		got, err := repo.Get(alice, id)
+		require.NoError(t, err)
+		assert.Equal(t, created, got)
+
+		all, err := repo.GetAll(alice)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+
+		e.secure(got).Metadata = "updated"
+		_, err = repo.Update(alice, got)
+		require.NoError(t, err)
+
+		updated, err := repo.Get(alice, id)
+		require.NoError(t, err)
+		require.NotNil(t, updated)
+		assert.Equal(t, got, updated)
+
+		deleted, err := repo.Delete(alice, id)
+		require.NoError(t, err)
+		require.NotNil(t, deleted)
+		assert.Equal(t, id, e.secure(deleted).ID)
+
+		got, err = repo.Get(alice, id)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+
+		all, err = repo.GetAll(alice)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("Повторный ИД", func(t *testing.T) {
+		repo := newRepo(t)
+		id := newEntryID(t)
+		_, err := repo.Create(alice, e.newDTO(id))
+		require.NoError(t, err)
+
+		// ИД записи выбирает клиент, поэтому чужой ИД не должен перезаписывать запись другого пользователя
+		_, err = repo.Create(bob, e.newDTO(id))
+		assert.Error(t, err)
+
+		got, err := repo.Get(alice, id)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "alice", e.secure(got).OwnerID)
+	})
+
+	t.Run("Записи других пользователей", func(t *testing.T) {
+		repo := newRepo(t)
+		id := newEntryID(t)
+		created, err := repo.Create(alice, e.newDTO(id))
+		require.NoError(t, err)
+
+		foreign, err := repo.Get(bob, id)
+		require.NoError(t, err)
+		assert.Nil(t, foreign)
+
+		all, err := repo.GetAll(bob)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+
+		// Изменение с разрешения политики доступа (например, по праву записи) не меняет владельца
+		changed := *created
+		e.secure(&changed).OwnerID = "bob"
+		e.secure(&changed).Metadata = "changed by bob"
+		_, err = repo.Update(bob, &changed)
+		require.NoError(t, err)
+
+		got, err := repo.Get(alice, id)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "alice", e.secure(got).OwnerID)
+		assert.Equal(t, "changed by bob", e.secure(got).Metadata)
+
+		all, err = repo.GetAll(bob)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("Отсутствующая запись", func(t *testing.T) {
+		repo := newRepo(t)
+		id := newEntryID(t)
+
+		got, err := repo.Get(alice, id)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+
+		missing := new(E)
+		e.secure(missing).ID = id
+		updated, err := repo.Update(alice, missing)
+		require.NoError(t, err)
+		assert.Nil(t, updated)
+
+		deleted, err := repo.Delete(alice, id)
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+
+		all, err := repo.GetAll(alice)
+		require.NoError(t, err)
+		assert.Empty(t, all, "изменение отсутствующей записи её не создаёт")
+	})
+
+	t.Run("Отменённый контекст", func(t *testing.T) {
+		repo := newRepo(t)
+		id := newEntryID(t)
+		created, err := repo.Create(alice, e.newDTO(id))
+		require.NoError(t, err)
+
+		cancelled, cancel := context.WithCancel(alice)
+		cancel()
+
+		_, err = repo.GetAll(cancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Get(cancelled, id)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Create(cancelled, e.newDTO(newEntryID(t)))
+		assert.ErrorIs(t, err, context.Canceled)
+		changed := *created
+		e.secure(&changed).Metadata = "changed"
+		_, err = repo.Update(cancelled, &changed)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Delete(cancelled, id)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		all, err := repo.GetAll(alice)
+		require.NoError(t, err)
+		require.Len(t, all, 1, "операции с отменённым контекстом не выполняются")
+		assert.Equal(t, created, &all[0])
+	})
+
+	t.Run("Конкурентный доступ", func(t *testing.T) {
+		if opts.Serialized {
+			t.Skip("вызовы реализации упорядочивает сервис хранилища")
+		}
+
+		repo := newRepo(t)
+		const workers, perWorker = 8, 10
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- concurrentWork(alice, repo, e, perWorker)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		all, err := repo.GetAll(alice)
+		require.NoError(t, err)
+		assert.Len(t, all, workers*perWorker/2, "каждый поток удаляет половину созданных записей")
+	})
+}
+
+// concurrentWork - создать, прочитать, изменить и удалить записи одного потока
+func concurrentWork[E, D any](ctx context.Context, repo repositories.IRepository[E, D], e entries[E, D], count int) error {
+	for i := 0; i < count; i++ {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+
+		if _, err := repo.Create(ctx, e.newDTO(id.String())); err != nil {
+			return err
+		}
+
+		got, err := repo.Get(ctx, id.String())
+		if err != nil {
+			return err
+		}
+		if got == nil {
+			return fmt.Errorf("entry %s not found after create", id)
+		}
+
+		e.secure(got).Metadata = "updated"
+		if _, err := repo.Update(ctx, got); err != nil {
+			return err
+		}
+
+		if _, err := repo.GetAll(ctx); err != nil {
+			return err
+		}
+
+		if i%2 == 1 {
+			if _, err := repo.Delete(ctx, id.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// newSecureEntity - общая часть новой записи
+func newSecureEntity(id string) dtos.NewSecureEntity {
+	return dtos.NewSecureEntity{ID: id, Metadata: "metadata of " + id}
+}
+
+// newEntryID - идентификатор новой записи (UUIDv7, как у клиента)
+func newEntryID(t *testing.T) string {
+	id, err := uuid.NewV7()
+	require.NoError(t, err)
+	return id.String()
+}
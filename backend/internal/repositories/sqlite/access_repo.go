@@ -0,0 +1,57 @@
+// sqlite - репозитории сервера на SQLite
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// SQLiteAccessRepo - отношения пользователей к записям для политики доступа.
+// Записями не делятся и коллекций нет, поэтому отношение к записи определяется только её владельцем
+type SQLiteAccessRepo struct {
+	db *sql.DB
+}
+
+// NewSQLiteAccessRepo - инициализация репозитория
+func NewSQLiteAccessRepo(db *sql.DB) (*SQLiteAccessRepo, error) {
+	return &SQLiteAccessRepo{db: db}, nil
+}
+
+// EntryAccess - владелец записи (nil, если записи нет)
+func (r *SQLiteAccessRepo) EntryAccess(ctx context.Context, entityType, id string) (*entities.EntryAccess, error) {
+	table, known := entryTables[entityType]
+	if !known {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	access := entities.EntryAccess{EntityType: entityType, EntityID: id}
+	err := r.db.QueryRowContext(ctx, "SELECT ownerid FROM "+table+" WHERE id = ?", id).Scan(&access.OwnerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, fmt.Errorf("failed to get entry access: %w", err)
+	}
+
+	return &access, nil
+}
+
+// EntryExists - занят ли идентификатор записью указанного типа (в том числе чужой записью)
+func (r *SQLiteAccessRepo) EntryExists(ctx context.Context, entityType, id string) (bool, error) {
+	table, known := entryTables[entityType]
+	if !known {
+		return false, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = ?)", id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check entry id: %w", err)
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,125 @@
+// sqlite - репозитории сервера на SQLite
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// SQLiteBinariesRepo - репозиторий с бинарными данными
+type SQLiteBinariesRepo struct {
+	db *sql.DB
+}
+
+// binaryColumns - столбцы бинарных данных в порядке сканирования scanBinary
+const binaryColumns = "id, data, metadata, ownerid, COALESCE(entrykey, ''), keyversion"
+
+// NewSQLiteBinariesRepo - инициализация репозитория
+func NewSQLiteBinariesRepo(db *sql.DB) (*SQLiteBinariesRepo, error) {
+	return &SQLiteBinariesRepo{db: db}, nil
+}
+
+// scanBinary - прочитать бинарные данные из строки результата
+func scanBinary(row scanner) (*entities.BinaryData, error) {
+	var binary entities.BinaryData
+	err := row.Scan(&binary.ID, &binary.Data, &binary.Metadata, &binary.OwnerID, &binary.EntryKey, &binary.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &binary, nil
+}
+
+// GetAll - получить все сущности текущего пользователя
+func (r *SQLiteBinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryData, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+binaryColumns+" FROM binaries WHERE ownerid = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get binaries: %w", err)
+	}
+
+	defer rows.Close()
+
+	var binaries []entities.BinaryData
+	for rows.Next() {
+		binary, err := scanBinary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan binary: %w", err)
+		}
+		binaries = append(binaries, *binary)
+	}
+
+	return binaries, rows.Err()
+}
+
+// Get - получить сущность по ИД (если она принадлежит текущему пользователю)
+func (r *SQLiteBinariesRepo) Get(ctx context.Context, id string) (*entities.BinaryData, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	binary, err := scanBinary(r.db.QueryRowContext(ctx, "SELECT "+binaryColumns+" FROM binaries WHERE id = ? AND ownerid = ?", id, userID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, fmt.Errorf("failed to get binary: %w", err)
+	}
+
+	return binary, nil
+}
+
+// Create - создать сущность
+func (r *SQLiteBinariesRepo) Create(ctx context.Context, binary *dtos.NewBinaryData) (*entities.BinaryData, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	query := "INSERT INTO binaries (id, data, metadata, ownerid, keyversion) VALUES (?, ?, ?, ?, ?) RETURNING " + binaryColumns
+
+	created, err := scanBinary(r.db.QueryRowContext(ctx, query, binary.ID, binary.Data, binary.Metadata, userID, binary.KeyVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create binary: %w", err)
+	}
+
+	return created, nil
+}
+
+// Update - изменить сущность. Права проверяются политикой доступа до вызова: пустой ключ записи оставляет прежний
+func (r *SQLiteBinariesRepo) Update(ctx context.Context, binary *entities.BinaryData) (*entities.BinaryData, error) {
+	query := `
+		UPDATE binaries SET data = ?, metadata = ?,
+			entrykey = COALESCE(NULLIF(?, ''), entrykey)
+		WHERE id = ?
+		RETURNING ` + binaryColumns
+
+	updated, err := scanBinary(r.db.QueryRowContext(ctx, query, binary.Data, binary.Metadata, binary.EntryKey, binary.ID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Delete - удалить сущность. Корзины в SQLite нет, поэтому запись удаляется сразу.
+// Права проверяются политикой доступа до вызова
+func (r *SQLiteBinariesRepo) Delete(ctx context.Context, id string) (*entities.BinaryData, error) {
+	deleted, err := scanBinary(r.db.QueryRowContext(ctx, "DELETE FROM binaries WHERE id = ? RETURNING "+binaryColumns, id))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,125 @@
+// sqlite - репозитории сервера на SQLite
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// SQLiteCardsRepo - репозиторий с данными банковских карт
+type SQLiteCardsRepo struct {
+	db *sql.DB
+}
+
+// cardColumns - столбцы карты в порядке сканирования scanCard
+const cardColumns = "id, number, cardholder, expirationdate, cvv, metadata, ownerid, COALESCE(entrykey, ''), keyversion"
+
+// NewSQLiteCardsRepo - инициализация репозитория
+func NewSQLiteCardsRepo(db *sql.DB) (*SQLiteCardsRepo, error) {
+	return &SQLiteCardsRepo{db: db}, nil
+}
+
+// scanCard - прочитать карту из строки результата
+func scanCard(row scanner) (*entities.CardInformation, error) {
+	var card entities.CardInformation
+	err := row.Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata, &card.OwnerID, &card.EntryKey, &card.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &card, nil
+}
+
+// GetAll - получить все сущности текущего пользователя
+func (r *SQLiteCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+cardColumns+" FROM cards WHERE ownerid = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cards: %w", err)
+	}
+
+	defer rows.Close()
+
+	var cards []entities.CardInformation
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan card: %w", err)
+		}
+		cards = append(cards, *card)
+	}
+
+	return cards, rows.Err()
+}
+
+// Get - получить сущность по ИД (если она принадлежит текущему пользователю)
+func (r *SQLiteCardsRepo) Get(ctx context.Context, id string) (*entities.CardInformation, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	card, err := scanCard(r.db.QueryRowContext(ctx, "SELECT "+cardColumns+" FROM cards WHERE id = ? AND ownerid = ?", id, userID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, fmt.Errorf("failed to get card: %w", err)
+	}
+
+	return card, nil
+}
+
+// Create - создать сущность
+func (r *SQLiteCardsRepo) Create(ctx context.Context, card *dtos.NewCardInformation) (*entities.CardInformation, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	query := "INSERT INTO cards (id, number, cardholder, expirationdate, cvv, metadata, ownerid, keyversion) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING " + cardColumns
+
+	created, err := scanCard(r.db.QueryRowContext(ctx, query, card.ID, card.Number, card.CardHolder, card.ExpirationDate, card.CVV, card.Metadata, userID, card.KeyVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create card: %w", err)
+	}
+
+	return created, nil
+}
+
+// Update - изменить сущность. Права проверяются политикой доступа до вызова: пустой ключ записи оставляет прежний
+func (r *SQLiteCardsRepo) Update(ctx context.Context, card *entities.CardInformation) (*entities.CardInformation, error) {
+	query := `
+		UPDATE cards SET number = ?, cardholder = ?, expirationdate = ?, cvv = ?, metadata = ?,
+			entrykey = COALESCE(NULLIF(?, ''), entrykey)
+		WHERE id = ?
+		RETURNING ` + cardColumns
+
+	updated, err := scanCard(r.db.QueryRowContext(ctx, query, card.Number, card.CardHolder, card.ExpirationDate, card.CVV, card.Metadata, card.EntryKey, card.ID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Delete - удалить сущность. Корзины в SQLite нет, поэтому запись удаляется сразу.
+// Права проверяются политикой доступа до вызова
+func (r *SQLiteCardsRepo) Delete(ctx context.Context, id string) (*entities.CardInformation, error) {
+	deleted, err := scanCard(r.db.QueryRowContext(ctx, "DELETE FROM cards WHERE id = ? RETURNING "+cardColumns, id))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return deleted, nil
+}
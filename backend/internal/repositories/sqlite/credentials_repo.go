@@ -0,0 +1,125 @@
+// sqlite - репозитории сервера на SQLite
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// SQLiteCredentialsRepo - репозиторий с учётными данными
+type SQLiteCredentialsRepo struct {
+	db *sql.DB
+}
+
+// credentialsColumns - столбцы учётных данных в порядке сканирования scanCredentials
+const credentialsColumns = "id, login, password, metadata, ownerid, COALESCE(entrykey, ''), keyversion"
+
+// NewSQLiteCredentialsRepo - инициализация репозитория
+func NewSQLiteCredentialsRepo(db *sql.DB) (*SQLiteCredentialsRepo, error) {
+	return &SQLiteCredentialsRepo{db: db}, nil
+}
+
+// scanCredentials - прочитать учётные данные из строки результата
+func scanCredentials(row scanner) (*entities.Credentials, error) {
+	var credentials entities.Credentials
+	err := row.Scan(&credentials.ID, &credentials.Login, &credentials.Password, &credentials.Metadata, &credentials.OwnerID, &credentials.EntryKey, &credentials.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credentials, nil
+}
+
+// GetAll - получить все сущности текущего пользователя
+func (r *SQLiteCredentialsRepo) GetAll(ctx context.Context) ([]entities.Credentials, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+credentialsColumns+" FROM credentials WHERE ownerid = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	defer rows.Close()
+
+	var credentialsList []entities.Credentials
+	for rows.Next() {
+		credentials, err := scanCredentials(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan credentials: %w", err)
+		}
+		credentialsList = append(credentialsList, *credentials)
+	}
+
+	return credentialsList, rows.Err()
+}
+
+// Get - получить сущность по ИД (если она принадлежит текущему пользователю)
+func (r *SQLiteCredentialsRepo) Get(ctx context.Context, id string) (*entities.Credentials, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	credentials, err := scanCredentials(r.db.QueryRowContext(ctx, "SELECT "+credentialsColumns+" FROM credentials WHERE id = ? AND ownerid = ?", id, userID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// Create - создать сущность
+func (r *SQLiteCredentialsRepo) Create(ctx context.Context, credentials *dtos.NewCredentials) (*entities.Credentials, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	query := "INSERT INTO credentials (id, login, password, metadata, ownerid, keyversion) VALUES (?, ?, ?, ?, ?, ?) RETURNING " + credentialsColumns
+
+	created, err := scanCredentials(r.db.QueryRowContext(ctx, query, credentials.ID, credentials.Login, credentials.Password, credentials.Metadata, userID, credentials.KeyVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credentials: %w", err)
+	}
+
+	return created, nil
+}
+
+// Update - изменить сущность. Права проверяются политикой доступа до вызова: пустой ключ записи оставляет прежний
+func (r *SQLiteCredentialsRepo) Update(ctx context.Context, credentials *entities.Credentials) (*entities.Credentials, error) {
+	query := `
+		UPDATE credentials SET login = ?, password = ?, metadata = ?,
+			entrykey = COALESCE(NULLIF(?, ''), entrykey)
+		WHERE id = ?
+		RETURNING ` + credentialsColumns
+
+	updated, err := scanCredentials(r.db.QueryRowContext(ctx, query, credentials.Login, credentials.Password, credentials.Metadata, credentials.EntryKey, credentials.ID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Delete - удалить сущность. Корзины в SQLite нет, поэтому запись удаляется сразу.
+// Права проверяются политикой доступа до вызова
+func (r *SQLiteCredentialsRepo) Delete(ctx context.Context, id string) (*entities.Credentials, error) {
+	deleted, err := scanCredentials(r.db.QueryRowContext(ctx, "DELETE FROM credentials WHERE id = ? RETURNING "+credentialsColumns, id))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,90 @@
+// sqlite - репозитории сервера на SQLite
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// SQLiteDataKeysRepo - ключи данных пользователей, зашифрованные мастер-ключом
+type SQLiteDataKeysRepo struct {
+	db *sql.DB
+}
+
+// NewSQLiteDataKeysRepo - инициализация репозитория
+func NewSQLiteDataKeysRepo(db *sql.DB) (*SQLiteDataKeysRepo, error) {
+	return &SQLiteDataKeysRepo{db: db}, nil
+}
+
+// Get - ключ данных пользователя
+func (r *SQLiteDataKeysRepo) Get(ctx context.Context, login string) (*entities.DataKey, error) {
+	var key entities.DataKey
+	err := r.db.QueryRowContext(ctx, "SELECT login, wrapped_key FROM data_keys WHERE login = ?", login).Scan(&key.Login, &key.WrappedKey)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Ключа ещё нет
+		}
+		return nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// GetAll - ключи данных всех пользователей
+func (r *SQLiteDataKeysRepo) GetAll(ctx context.Context) ([]entities.DataKey, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT login, wrapped_key FROM data_keys ORDER BY login")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []entities.DataKey
+	for rows.Next() {
+		var key entities.DataKey
+		if err := rows.Scan(&key.Login, &key.WrappedKey); err != nil {
+			return nil, fmt.Errorf("failed to scan data key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Create - сохранить ключ данных, если у пользователя его ещё нет (иначе вернуть существующий)
+func (r *SQLiteDataKeysRepo) Create(ctx context.Context, key *entities.DataKey) (*entities.DataKey, error) {
+	if _, err := r.db.ExecContext(ctx, "INSERT INTO data_keys (login, wrapped_key) VALUES (?, ?) ON CONFLICT (login) DO NOTHING", key.Login, key.WrappedKey); err != nil {
+		return nil, fmt.Errorf("failed to create data key: %w", err)
+	}
+
+	return r.Get(ctx, key.Login)
+}
+
+// Update - заменить зашифрованный ключ данных
+func (r *SQLiteDataKeysRepo) Update(ctx context.Context, key *entities.DataKey) (*entities.DataKey, error) {
+	var updated entities.DataKey
+	err := r.db.QueryRowContext(ctx, "UPDATE data_keys SET wrapped_key = ?, rotated_at = CURRENT_TIMESTAMP WHERE login = ? RETURNING login, wrapped_key",
+		key.WrappedKey, key.Login).Scan(&updated.Login, &updated.WrappedKey)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Ключа нет
+		}
+		return nil, fmt.Errorf("failed to update data key: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// Delete - удалить ключ данных пользователя
+func (r *SQLiteDataKeysRepo) Delete(ctx context.Context, login string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM data_keys WHERE login = ?", login); err != nil {
+		return fmt.Errorf("failed to delete data key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+// sqlite - репозитории сервера на SQLite: пользователи и их личные записи в одном файле, без отдельного сервера БД.
+// Общий доступ, организации, экстренный доступ, журнал аудита, корзина и история хранятся только в PostgreSQL
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/sqlite/migrations"
+	_ "modernc.org/sqlite"
+)
+
+// DatabaseManager - подключение к базе данных SQLite и репозитории
+type DatabaseManager struct {
+	DB              *sql.DB
+	BinariesRepo    *SQLiteBinariesRepo
+	CardsRepo       *SQLiteCardsRepo
+	CredentialsRepo *SQLiteCredentialsRepo
+	TextsRepo       *SQLiteTextsRepo
+	UsersRepo       *SQLiteUsersRepo
+	AccessRepo      *SQLiteAccessRepo
+	DataKeysRepo    *SQLiteDataKeysRepo
+}
+
+// entryTables - таблицы записей по типу сущности
+var entryTables = map[string]string{
+	"binary":      "binaries",
+	"card":        "cards",
+	"credentials": "credentials",
+	"text":        "texts",
+}
+
+// scanner - строка результата запроса (*sql.Row или *sql.Rows)
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Open - открыть (или создать) базу данных без применения миграций.
+// dsn - путь к файлу (в том числе со схемой sqlite://) или URI вида file:путь?параметры
+func Open(dsn string) (*sql.DB, error) {
+	uri := dsn
+	if !strings.HasPrefix(dsn, "file:") {
+		path := strings.TrimPrefix(strings.TrimPrefix(dsn, "sqlite://"), "sqlite:")
+		if path == "" {
+			return nil, errors.New("sqlite database path is empty")
+		}
+
+		// Создаем директорию для БД, если ее нет
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+		uri = "file:" + path
+	}
+
+	// Внешние ключи и ожидание блокировки задаются для каждого подключения, которое откроет пул
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+	uri += separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	db, err := sql.Open("sqlite", uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Одно соединение: задачи сервиса и так выполняются по очереди, а проверка готовности дождётся своей очереди
+	db.SetMaxOpenConns(1)
+
+	// Проверяем подключение
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	if _, err := db.Exec("PRAGMA journal_mode = WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set pragmas: %w", err)
+	}
+
+	return db, nil
+}
+
+// NewDatabaseManager - открыть базу данных, применить миграции и создать репозитории
+func NewDatabaseManager(dsn string) (*DatabaseManager, error) {
+	db, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запускаем миграции
+	migrator := migrations.NewMigrator(db)
+	if err := migrator.Migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	binariesRepo, err := NewSQLiteBinariesRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	cardsRepo, err := NewSQLiteCardsRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	credentialsRepo, err := NewSQLiteCredentialsRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	textsRepo, err := NewSQLiteTextsRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	usersRepo, err := NewSQLiteUsersRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	accessRepo, err := NewSQLiteAccessRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	dataKeysRepo, err := NewSQLiteDataKeysRepo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseManager{
+		DB:              db,
+		BinariesRepo:    binariesRepo,
+		CardsRepo:       cardsRepo,
+		CredentialsRepo: credentialsRepo,
+		TextsRepo:       textsRepo,
+		UsersRepo:       usersRepo,
+		AccessRepo:      accessRepo,
+		DataKeysRepo:    dataKeysRepo,
+	}, nil
+}
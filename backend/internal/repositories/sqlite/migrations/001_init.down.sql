@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS texts;
+DROP TABLE IF EXISTS credentials;
+DROP TABLE IF EXISTS cards;
+DROP TABLE IF EXISTS binaries;
+DROP TABLE IF EXISTS users;
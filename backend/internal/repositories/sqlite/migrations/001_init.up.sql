@@ -0,0 +1,53 @@
+-- Схема сервера на SQLite: пользователи и их личные записи.
+-- Идентификаторы записей - UUIDv7, которые выбирает клиент; записи удаляются вместе с владельцем
+CREATE TABLE users (
+	login TEXT NOT NULL PRIMARY KEY,
+	password TEXT,
+	disabled INTEGER NOT NULL DEFAULT 0,
+	session_version INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE binaries (
+	id TEXT NOT NULL PRIMARY KEY,
+	data BLOB NOT NULL,
+	metadata TEXT,
+	ownerid TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	entrykey TEXT,
+	keyversion INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE cards (
+	id TEXT NOT NULL PRIMARY KEY,
+	number TEXT NOT NULL,
+	cardholder TEXT NOT NULL,
+	expirationdate TEXT NOT NULL,
+	cvv TEXT NOT NULL,
+	metadata TEXT,
+	ownerid TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	entrykey TEXT,
+	keyversion INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE credentials (
+	id TEXT NOT NULL PRIMARY KEY,
+	login TEXT NOT NULL,
+	password TEXT NOT NULL,
+	metadata TEXT,
+	ownerid TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	entrykey TEXT,
+	keyversion INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE texts (
+	id TEXT NOT NULL PRIMARY KEY,
+	data TEXT NOT NULL,
+	metadata TEXT,
+	ownerid TEXT NOT NULL REFERENCES users (login) ON DELETE CASCADE,
+	entrykey TEXT,
+	keyversion INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX binaries_owner_idx ON binaries (ownerid, id);
+CREATE INDEX cards_owner_idx ON cards (ownerid, id);
+CREATE INDEX credentials_owner_idx ON credentials (ownerid, id);
+CREATE INDEX texts_owner_idx ON texts (ownerid, id);
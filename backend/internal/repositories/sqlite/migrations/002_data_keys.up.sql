@@ -0,0 +1,8 @@
+-- Ключи данных пользователей для шифрования хранимых полей на сервере (зашифрованы мастер-ключом).
+-- Внешнего ключа на users нет: ключ создаётся до пользователя, чтобы зашифровать хэш его пароля
+CREATE TABLE data_keys (
+	login TEXT NOT NULL PRIMARY KEY,
+	wrapped_key BLOB NOT NULL,
+	created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	rotated_at TEXT
+);
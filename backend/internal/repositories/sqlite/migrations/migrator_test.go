@@ -0,0 +1,103 @@
+// migrations - миграции схемы базы данных SQLite сервера
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB - пустая база данных во временной директории
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// appliedCount - число применённых миграций
+func appliedCount(t *testing.T, migrator *Migrator) int {
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+
+	count := 0
+	for _, status := range statuses {
+		if status.Applied {
+			count++
+			assert.False(t, status.AppliedAt.IsZero(), status.Name)
+		}
+	}
+	return count
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "версии миграций идут подряд с 1")
+		assert.True(t, migration.Reversible(), "у миграции %s есть файл отката", migration.Name)
+	}
+
+	assert.Equal(t, "001_init", migrations[0].Name)
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	migrator := NewMigrator(db)
+
+	all, err := Load()
+	require.NoError(t, err)
+
+	t.Run("Пробный запуск ничего не меняет", func(t *testing.T) {
+		pending, err := migrator.Up(ctx, true)
+		require.NoError(t, err)
+		assert.Len(t, pending, len(all))
+		assert.Equal(t, 0, appliedCount(t, migrator))
+	})
+
+	t.Run("Применение всех миграций", func(t *testing.T) {
+		applied, err := migrator.Up(ctx, false)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(all))
+		assert.Equal(t, len(all), appliedCount(t, migrator))
+
+		_, err = db.ExecContext(ctx, "INSERT INTO users (login, password) VALUES ('alice', 'hash')")
+		require.NoError(t, err)
+		_, err = db.ExecContext(ctx, "INSERT INTO texts (id, data, metadata, ownerid) VALUES ('0190a0b2-0000-7000-8000-000000000000', 'note', '', 'alice')")
+		require.NoError(t, err)
+
+		// Повторный запуск ничего не применяет
+		applied, err = migrator.Up(ctx, false)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("Полный откат и повторное применение", func(t *testing.T) {
+		rollback, err := migrator.Down(ctx, len(all), true)
+		require.NoError(t, err)
+		require.Len(t, rollback, len(all))
+		assert.Equal(t, all[len(all)-1].Name, rollback[0].Name, "сначала откатывается последняя миграция")
+		assert.Equal(t, len(all), appliedCount(t, migrator))
+
+		rolledBack, err := migrator.Down(ctx, len(all), false)
+		require.NoError(t, err)
+		assert.Equal(t, rollback, rolledBack)
+		assert.Equal(t, 0, appliedCount(t, migrator))
+
+		_, err = migrator.Down(ctx, 0, false)
+		assert.Error(t, err)
+
+		applied, err := migrator.Up(ctx, false)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(all))
+	})
+}
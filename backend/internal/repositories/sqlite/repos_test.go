@@ -0,0 +1,199 @@
+// sqlite_test - тесты репозиториев SQLite
+package sqlite_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/repotest"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/sqlite"
+	"github.com/JustScorpio/GophKeeper/backend/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestManager - база данных с применёнными миграциями во временной директории
+func newTestManager(t *testing.T) *sqlite.DatabaseManager {
+	manager, err := sqlite.NewDatabaseManager("sqlite://" + filepath.Join(t.TempDir(), "data", "gophkeeper.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { manager.DB.Close() })
+	return manager
+}
+
+// newEntryID - идентификатор новой записи
+func newEntryID(t *testing.T) string {
+	id, err := uuid.NewV7()
+	require.NoError(t, err)
+	return id.String()
+}
+
+func TestSQLiteUsersRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestManager(t).UsersRepo
+
+	created, err := repo.Create(ctx, &dtos.NewUser{Login: "alice", Password: "hash"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", created.Login)
+	assert.False(t, created.Disabled)
+
+	_, err = repo.Create(ctx, &dtos.NewUser{Login: "alice", Password: "other"})
+	assert.Error(t, err, "логин уникален")
+
+	created.Disabled, created.SessionVersion = true, 3
+	updated, err := repo.Update(ctx, created)
+	require.NoError(t, err)
+	assert.True(t, updated.Disabled)
+	assert.Equal(t, 3, updated.SessionVersion)
+
+	users, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	deleted, err := repo.Delete(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, updated, deleted)
+
+	missing, err := repo.Get(ctx, "alice")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestSQLiteEntries(t *testing.T) {
+	manager := newTestManager(t)
+	alice := customcontext.WithUserID(context.Background(), "alice")
+	bob := customcontext.WithUserID(context.Background(), "bob")
+
+	for _, login := range []string{"alice", "bob"} {
+		_, err := manager.UsersRepo.Create(context.Background(), &dtos.NewUser{Login: login, Password: "hash"})
+		require.NoError(t, err)
+	}
+
+	card, err := manager.CardsRepo.Create(alice, &dtos.NewCardInformation{
+		Number: "4111111111111111", CardHolder: "ALICE", ExpirationDate: "12/30", CVV: "123",
+		NewSecureEntity: dtos.NewSecureEntity{ID: newEntryID(t), Metadata: "card"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", card.OwnerID)
+
+	text, err := manager.TextsRepo.Create(bob, &dtos.NewTextData{Data: "note", NewSecureEntity: dtos.NewSecureEntity{ID: newEntryID(t)}})
+	require.NoError(t, err)
+
+	t.Run("Пользователь видит только свои записи", func(t *testing.T) {
+		cards, err := manager.CardsRepo.GetAll(alice)
+		require.NoError(t, err)
+		assert.Len(t, cards, 1)
+
+		cards, err = manager.CardsRepo.GetAll(bob)
+		require.NoError(t, err)
+		assert.Empty(t, cards)
+
+		foreign, err := manager.CardsRepo.Get(bob, card.ID)
+		require.NoError(t, err)
+		assert.Nil(t, foreign)
+
+		access, err := manager.AccessRepo.EntryAccess(bob, "card", card.ID)
+		require.NoError(t, err)
+		require.NotNil(t, access)
+		assert.Equal(t, "alice", access.OwnerID, "политика доступа решает по владельцу")
+
+		exists, err := manager.AccessRepo.EntryExists(bob, "card", card.ID)
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("Изменение сохраняет прежний ключ записи", func(t *testing.T) {
+		changed := *card
+		changed.CVV, changed.EntryKey = "456", "wrapped-key"
+		updated, err := manager.CardsRepo.Update(alice, &changed)
+		require.NoError(t, err)
+		assert.Equal(t, "456", updated.CVV)
+
+		changed.EntryKey = ""
+		updated, err = manager.CardsRepo.Update(alice, &changed)
+		require.NoError(t, err)
+		assert.Equal(t, "wrapped-key", updated.EntryKey)
+
+		changed.ID = newEntryID(t)
+		missing, err := manager.CardsRepo.Update(alice, &changed)
+		require.NoError(t, err)
+		assert.Nil(t, missing)
+	})
+
+	t.Run("Удаление записи", func(t *testing.T) {
+		deleted, err := manager.CardsRepo.Delete(alice, card.ID)
+		require.NoError(t, err)
+		require.NotNil(t, deleted)
+		assert.Equal(t, card.ID, deleted.ID)
+
+		access, err := manager.AccessRepo.EntryAccess(alice, "card", card.ID)
+		require.NoError(t, err)
+		assert.Nil(t, access)
+	})
+
+	t.Run("Записи удаляются вместе с владельцем", func(t *testing.T) {
+		_, err := manager.UsersRepo.Delete(context.Background(), "bob")
+		require.NoError(t, err)
+
+		exists, err := manager.AccessRepo.EntryExists(alice, "text", text.ID)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+func TestSQLiteStorageService(t *testing.T) {
+	manager := newTestManager(t)
+	service := services.NewStorageService(manager.UsersRepo, manager.BinariesRepo, manager.CardsRepo, manager.CredentialsRepo, manager.TextsRepo, manager.AccessRepo)
+	t.Cleanup(service.Shutdown)
+
+	alice := customcontext.WithUserID(context.Background(), "alice")
+	bob := customcontext.WithUserID(context.Background(), "bob")
+
+	_, err := service.CreateUser(context.Background(), dtos.NewUser{Login: "alice", Password: "password"})
+	require.NoError(t, err)
+	_, err = service.CreateUser(context.Background(), dtos.NewUser{Login: "bob", Password: "password"})
+	require.NoError(t, err)
+
+	created, err := service.CreateCredentials(alice, &dtos.NewCredentials{Login: "login", Password: "secret"})
+	require.NoError(t, err)
+	require.NoError(t, uuid.Validate(created.ID), "идентификатор выдаётся, если клиент его не выбрал")
+
+	_, err = service.CreateCredentials(bob, &dtos.NewCredentials{Login: "login", NewSecureEntity: dtos.NewSecureEntity{ID: created.ID}})
+	var httpErr *customerrors.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+
+	foreign, err := service.DeleteCredentials(bob, created.ID)
+	require.NoError(t, err)
+	assert.Nil(t, foreign, "чужая запись не видна и не удаляется")
+
+	deleted, err := service.DeleteCredentials(alice, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, deleted.ID)
+
+	all, err := service.GetAllCredentials(alice)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	_, err = service.GetTrash(alice)
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotImplemented, httpErr.Code, "корзины в SQLite нет")
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		manager := newTestManager(t)
+		return repotest.Repos{
+			Users:       manager.UsersRepo,
+			Binaries:    manager.BinariesRepo,
+			Cards:       manager.CardsRepo,
+			Credentials: manager.CredentialsRepo,
+			Texts:       manager.TextsRepo,
+		}
+	}, repotest.Options{})
+}
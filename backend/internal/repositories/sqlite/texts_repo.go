@@ -0,0 +1,125 @@
+// sqlite - репозитории сервера на SQLite
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// SQLiteTextsRepo - репозиторий с текстовыми данными
+type SQLiteTextsRepo struct {
+	db *sql.DB
+}
+
+// textColumns - столбцы текста в порядке сканирования scanText
+const textColumns = "id, data, metadata, ownerid, COALESCE(entrykey, ''), keyversion"
+
+// NewSQLiteTextsRepo - инициализация репозитория
+func NewSQLiteTextsRepo(db *sql.DB) (*SQLiteTextsRepo, error) {
+	return &SQLiteTextsRepo{db: db}, nil
+}
+
+// scanText - прочитать текст из строки результата
+func scanText(row scanner) (*entities.TextData, error) {
+	var text entities.TextData
+	err := row.Scan(&text.ID, &text.Data, &text.Metadata, &text.OwnerID, &text.EntryKey, &text.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &text, nil
+}
+
+// GetAll - получить все сущности текущего пользователя
+func (r *SQLiteTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+textColumns+" FROM texts WHERE ownerid = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get texts: %w", err)
+	}
+
+	defer rows.Close()
+
+	var texts []entities.TextData
+	for rows.Next() {
+		text, err := scanText(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan text: %w", err)
+		}
+		texts = append(texts, *text)
+	}
+
+	return texts, rows.Err()
+}
+
+// Get - получить сущность по ИД (если она принадлежит текущему пользователю)
+func (r *SQLiteTextsRepo) Get(ctx context.Context, id string) (*entities.TextData, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	text, err := scanText(r.db.QueryRowContext(ctx, "SELECT "+textColumns+" FROM texts WHERE id = ? AND ownerid = ?", id, userID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, fmt.Errorf("failed to get text: %w", err)
+	}
+
+	return text, nil
+}
+
+// Create - создать сущность
+func (r *SQLiteTextsRepo) Create(ctx context.Context, text *dtos.NewTextData) (*entities.TextData, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	query := "INSERT INTO texts (id, data, metadata, ownerid, keyversion) VALUES (?, ?, ?, ?, ?) RETURNING " + textColumns
+
+	created, err := scanText(r.db.QueryRowContext(ctx, query, text.ID, text.Data, text.Metadata, userID, text.KeyVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create text: %w", err)
+	}
+
+	return created, nil
+}
+
+// Update - изменить сущность. Права проверяются политикой доступа до вызова: пустой ключ записи оставляет прежний
+func (r *SQLiteTextsRepo) Update(ctx context.Context, text *entities.TextData) (*entities.TextData, error) {
+	query := `
+		UPDATE texts SET data = ?, metadata = ?,
+			entrykey = COALESCE(NULLIF(?, ''), entrykey)
+		WHERE id = ?
+		RETURNING ` + textColumns
+
+	updated, err := scanText(r.db.QueryRowContext(ctx, query, text.Data, text.Metadata, text.EntryKey, text.ID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Delete - удалить сущность. Корзины в SQLite нет, поэтому запись удаляется сразу.
+// Права проверяются политикой доступа до вызова
+func (r *SQLiteTextsRepo) Delete(ctx context.Context, id string) (*entities.TextData, error) {
+	deleted, err := scanText(r.db.QueryRowContext(ctx, "DELETE FROM texts WHERE id = ? RETURNING "+textColumns, id))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return deleted, nil
+}
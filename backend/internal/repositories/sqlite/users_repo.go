@@ -0,0 +1,109 @@
+// sqlite - репозитории сервера на SQLite
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
+	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+)
+
+// SQLiteUsersRepo - репозиторий пользователей
+type SQLiteUsersRepo struct {
+	db *sql.DB
+}
+
+// userColumns - столбцы пользователя в порядке сканирования scanUser
+const userColumns = "login, password, disabled, session_version"
+
+// NewSQLiteUsersRepo - инициализация репозитория
+func NewSQLiteUsersRepo(db *sql.DB) (*SQLiteUsersRepo, error) {
+	return &SQLiteUsersRepo{db: db}, nil
+}
+
+// scanUser - прочитать пользователя из строки результата
+func scanUser(row scanner) (*entities.User, error) {
+	var user entities.User
+	if err := row.Scan(&user.Login, &user.Password, &user.Disabled, &user.SessionVersion); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetAll - получить все сущности
+func (r *SQLiteUsersRepo) GetAll(ctx context.Context) ([]entities.User, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY login")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var users []entities.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+// Get - получить сущность по ИД
+func (r *SQLiteUsersRepo) Get(ctx context.Context, login string) (*entities.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE login = ?", login))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, fmt.Errorf("failed to get entity: %w", err)
+	}
+
+	return user, nil
+}
+
+// Create - создать сущность
+func (r *SQLiteUsersRepo) Create(ctx context.Context, user *dtos.NewUser) (*entities.User, error) {
+	entity, err := scanUser(r.db.QueryRowContext(ctx, "INSERT INTO users (login, password) VALUES (?, ?) RETURNING "+userColumns, user.Login, user.Password))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return entity, nil
+}
+
+// Update - изменить сущность (пароль, блокировку и версию сессий)
+func (r *SQLiteUsersRepo) Update(ctx context.Context, user *entities.User) (*entities.User, error) {
+	updatedEntity, err := scanUser(r.db.QueryRowContext(ctx, "UPDATE users SET password = ?, disabled = ?, session_version = ? WHERE login = ? RETURNING "+userColumns,
+		user.Password, user.Disabled, user.SessionVersion, user.Login))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return updatedEntity, nil
+}
+
+// Delete - удалить сущность вместе с её записями (внешние ключи с каскадным удалением)
+func (r *SQLiteUsersRepo) Delete(ctx context.Context, login string) (*entities.User, error) {
+	deletedUser, err := scanUser(r.db.QueryRowContext(ctx, "DELETE FROM users WHERE login = ? RETURNING "+userColumns, login))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Запись не найдена
+		}
+		return nil, err
+	}
+
+	return deletedUser, nil
+}
@@ -3,16 +3,69 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/JustScorpio/GophKeeper/backend/internal/authz"
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
 	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
+	"github.com/JustScorpio/GophKeeper/backend/internal/metrics"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
 	"github.com/JustScorpio/GophKeeper/backend/internal/repositories"
+	"github.com/JustScorpio/GophKeeper/backend/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var (
+	errOutdatedCollectionKey = customerrors.NewAlreadyExistsError(errors.New("collection key version is outdated"))
+	errCollectionKeys        = customerrors.NewHTTPError(errors.New("collection keys must cover every member at the current key version"), http.StatusBadRequest)
+	errEmergencyContactSelf  = customerrors.NewHTTPError(errors.New("cannot designate yourself as emergency contact"), http.StatusBadRequest)
+	errEmergencyWaitDays     = customerrors.NewHTTPError(fmt.Errorf("wait period must be from 1 to %d days", maxEmergencyWaitDays), http.StatusBadRequest)
+	errEmergencyKey          = customerrors.NewHTTPError(errors.New("encrypted vault key is required"), http.StatusBadRequest)
+	errEmergencyExists       = customerrors.NewAlreadyExistsError(errors.New("emergency contact is already designated"))
+	errEmergencyChanged      = customerrors.NewAlreadyExistsError(errors.New("emergency access state has changed, retry"))
+	errAccountNotFound       = customerrors.NewNotFoundError(errors.New("account not found"))
+	errAccountDisabled       = customerrors.NewForbiddenError(errors.New("account is disabled"))
+	errSessionRevoked        = customerrors.NewHTTPError(errors.New("session has been revoked, login again"), http.StatusUnauthorized)
+	errInvalidQuota          = customerrors.NewHTTPError(errors.New("quota limits cannot be negative"), http.StatusBadRequest)
+	errRegistrationClosed    = customerrors.NewForbiddenError(errors.New("registration is closed"))
+	errInviteRequired        = customerrors.NewForbiddenError(errors.New("registration requires an invitation code"))
+	errInvalidInvite         = customerrors.NewForbiddenError(errors.New("invitation code is invalid, expired or used up"))
+	errInviteLogin           = customerrors.NewForbiddenError(errors.New("login is not allowed by the invitation"))
+	errInviteNotFound        = customerrors.NewNotFoundError(errors.New("invitation not found"))
+	errInviteUses            = customerrors.NewHTTPError(errors.New("max uses cannot be negative"), http.StatusBadRequest)
+	errInviteExpired         = customerrors.NewHTTPError(errors.New("invitation expiry must be in the future"), http.StatusBadRequest)
+	errEntryNotFound         = customerrors.NewNotFoundError(errors.New("entry not found"))
+	errRevisionNotFound      = customerrors.NewNotFoundError(errors.New("revision not found"))
+	errRevisionKeyChanged    = customerrors.NewAlreadyExistsError(errors.New("revision is encrypted with a previous entry key and cannot be restored"))
+	errInvalidEntryID        = customerrors.NewHTTPError(errors.New("entry id must be a UUID"), http.StatusBadRequest)
+	errEntryIDTaken          = customerrors.NewAlreadyExistsError(errors.New("entry id is already taken"))
+)
+
+// maxEmergencyWaitDays - наибольший период ожидания экстренного доступа
+const maxEmergencyWaitDays = 90
+
+// defaultInviteLifetime - срок действия приглашения, если администратор его не указал
+const defaultInviteLifetime = 7 * 24 * time.Hour
+
+// AdminActor - исполнитель действий администратора в журнале аудита
+const AdminActor = "admin"
+
 // StorageService - сервис для взаимодействия с хранилищем
 type StorageService struct {
 	binariesRepo    repositories.IRepository[entities.BinaryData, dtos.NewBinaryData]
@@ -20,10 +73,35 @@ type StorageService struct {
 	credentialsRepo repositories.IRepository[entities.Credentials, dtos.NewCredentials]
 	textsRepo       repositories.IRepository[entities.TextData, dtos.NewTextData]
 	usersRepo       repositories.IRepository[entities.User, dtos.NewUser]
+	accessRepo      repositories.IAccessRepository
+	policy          *authz.Policy
+	auditRepo       repositories.IAuditRepository    // необязательный, без него журнал аудита не ведётся
+	notifier        ChangeNotifier                   // необязательный, без него клиенты не получают уведомления об изменениях
+	userKeysRepo    repositories.IUserKeysRepository // необязательный, без него записями нельзя делиться
+	shareRepo       repositories.IShareRepository
+	orgRepo         repositories.IOrganizationRepository    // необязательный, без него организации недоступны
+	emergencyRepo   repositories.IEmergencyAccessRepository // необязательный, без него экстренный доступ недоступен
+	accountRepo     repositories.IAccountRepository         // необязательный, без него администрирование учётных записей недоступно
+	quota           *entities.Quota                         // квота по умолчанию (nil - объём хранилища не ограничен)
+	registration    string                                  // режим регистрации (пустой - открытая регистрация)
+	inviteRepo      repositories.IInviteRepository          // необязательный, без него приглашения недоступны
+	trashRepo       repositories.ITrashRepository           // необязательный, без него корзина недоступна
+	historyRepo     repositories.IHistoryRepository         // необязательный, без него история записей не ведётся
+	transactor      repositories.ITransactor                // необязательный, без него операции задачи не объединяются в транзакцию
+	sessions        sessionCache                            // состояния учётных записей для проверки токенов
+
+	emergencyCheckInterval time.Duration // период проверки истёкших ожиданий экстренного доступа (0 - не проверять)
+	trashRetention         time.Duration // срок хранения записей в корзине
+	trashPurgeInterval     time.Duration // период очистки корзины от записей старше trashRetention (0 - не очищать)
+	historyMaxVersions     int           // сколько версий записи хранить (0 - без ограничения)
+	historyMaxAge          time.Duration // сколько хранить версию после её замены (0 - без ограничения)
 
 	taskQueue      chan Task // канал-очередь задач
 	tasksInProcess sync.WaitGroup
 
+	stopBackground  chan struct{} // закрывается при shutdown, останавливает фоновые задачи
+	backgroundTasks sync.WaitGroup
+
 	isShuttingDown atomic.Bool //Использование вместо Bool помогает избежать гонки данных при её обновлении
 }
 
@@ -36,8 +114,59 @@ const (
 	TaskCreate
 	TaskUpdate
 	TaskDelete
+	TaskAccept
+	TaskRotate
+	TaskRequest
+	TaskApprove
+	TaskReject
+	TaskExpire
+	TaskDisable
+	TaskEnable
+	TaskRevoke
+	TaskRestore
+	TaskPurge
 )
 
+// String - название типа задачи (используется в журнале аудита)
+func (t TaskType) String() string {
+	switch t {
+	case TaskGetAll:
+		return "list"
+	case TaskGet:
+		return "read"
+	case TaskCreate:
+		return "create"
+	case TaskUpdate:
+		return "update"
+	case TaskDelete:
+		return "delete"
+	case TaskAccept:
+		return "accept"
+	case TaskRotate:
+		return "rotate"
+	case TaskRequest:
+		return "request"
+	case TaskApprove:
+		return "approve"
+	case TaskReject:
+		return "reject"
+	case TaskExpire:
+		return "expire"
+	case TaskDisable:
+		return "disable"
+	case TaskEnable:
+		return "enable"
+	case TaskRevoke:
+		return "revoke"
+	case TaskRestore:
+		return "restore"
+	case TaskPurge:
+		return "purge"
+	default:
+		return "unknown"
+	}
+}
+
 // EntityType - типы сущностей
 type EntityType int
 
@@ -47,8 +176,76 @@ const (
 	EntityCard
 	EntityCredentials
 	EntityText
+	EntityAudit
+	EntityShare
+	EntityUserKeys
+	EntityOrganization
+	EntityOrgMember
+	EntityCollection
+	EntityEmergency
+	EntityAccount
+	EntityStats
+	EntityQuota
+	EntityInvite
+	EntityTrash
+	EntityHistory
 )
 
+// String - название типа сущности (используется в журнале аудита)
+func (e EntityType) String() string {
+	switch e {
+	case EntityUser:
+		return "user"
+	case EntityBinary:
+		return "binary"
+	case EntityCard:
+		return "card"
+	case EntityCredentials:
+		return "credentials"
+	case EntityText:
+		return "text"
+	case EntityAudit:
+		return "audit"
+	case EntityShare:
+		return "share"
+	case EntityUserKeys:
+		return "keys"
+	case EntityOrganization:
+		return "organization"
+	case EntityOrgMember:
+		return "member"
+	case EntityCollection:
+		return "collection"
+	case EntityEmergency:
+		return "emergency"
+	case EntityAccount:
+		return "account"
+	case EntityStats:
+		return "stats"
+	case EntityQuota:
+		return "quota"
+	case EntityInvite:
+		return "invite"
+	case EntityTrash:
+		return "trash"
+	case EntityHistory:
+		return "history"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseEntityType - получить тип сущности по названию
+func ParseEntityType(name string) (EntityType, bool) {
+	for e := EntityUser; e <= EntityHistory; e++ {
+		if e.String() == name {
+			return e, true
+		}
+	}
+
+	return 0, false
+}
+
 // Task - задача в очереди задач на обработку сервисом
 type Task struct {
 	Context    context.Context
@@ -56,6 +253,8 @@ type Task struct {
 	ResultCh   chan TaskResult
 	TaskType   TaskType
 	EntityType EntityType
+
+	queueSpan trace.Span // спан ожидания задачи в очереди (завершается при начале обработки)
 }
 
 // TaskResult - результат обработки задачи Task
@@ -64,23 +263,147 @@ type TaskResult struct {
 	Err    error
 }
 
+// ChangeNotifier - получатель событий об изменении сущностей пользователя
+type ChangeNotifier interface {
+	Publish(userID string, event notifications.Event)
+}
+
+// Option - необязательная настройка сервиса
+type Option func(*StorageService)
+
+// WithAuditRepo - вести журнал аудита в указанном репозитории
+func WithAuditRepo(auditRepo repositories.IAuditRepository) Option {
+	return func(s *StorageService) {
+		s.auditRepo = auditRepo
+	}
+}
+
+// WithNotifier - уведомлять об успешных созданиях, изменениях и удалениях сущностей
+func WithNotifier(notifier ChangeNotifier) Option {
+	return func(s *StorageService) {
+		s.notifier = notifier
+	}
+}
+
+// WithSharing - разрешить пользователям делиться записями
+func WithSharing(userKeysRepo repositories.IUserKeysRepository, shareRepo repositories.IShareRepository) Option {
+	return func(s *StorageService) {
+		s.userKeysRepo = userKeysRepo
+		s.shareRepo = shareRepo
+	}
+}
+
+// WithOrganizations - разрешить общие хранилища организаций (требует ключей пользователей, см. WithSharing)
+func WithOrganizations(orgRepo repositories.IOrganizationRepository) Option {
+	return func(s *StorageService) {
+		s.orgRepo = orgRepo
+	}
+}
+
+// WithEmergencyAccess - разрешить экстренный доступ доверенных лиц к хранилищам (требует ключей пользователей, см. WithSharing).
+// Раз в checkInterval запросы с истёкшим периодом ожидания переводятся в предоставленный доступ (0 - не проверять)
+func WithEmergencyAccess(emergencyRepo repositories.IEmergencyAccessRepository, checkInterval time.Duration) Option {
+	return func(s *StorageService) {
+		s.emergencyRepo = emergencyRepo
+		s.emergencyCheckInterval = checkInterval
+	}
+}
+
+// WithAccounts - разрешить администрирование учётных записей: список с объёмом данных, статистику и удаление со всеми данными
+func WithAccounts(accountRepo repositories.IAccountRepository) Option {
+	return func(s *StorageService) {
+		s.accountRepo = accountRepo
+	}
+}
+
+// WithQuota - ограничить количество записей каждого типа и объём хранилища пользователя квотой по умолчанию.
+// Индивидуальные квоты и текущее использование берутся из репозитория учётных записей (требует WithAccounts)
+func WithQuota(defaultQuota entities.Quota) Option {
+	return func(s *StorageService) {
+		s.quota = &defaultQuota
+	}
+}
+
+// WithRegistration - задать режим регистрации (entities.RegistrationOpen, RegistrationInvite или RegistrationClosed)
+// и репозиторий приглашений. Приглашения можно выпускать в любом режиме, но проверяются они только в режиме приглашений
+func WithRegistration(mode string, inviteRepo repositories.IInviteRepository) Option {
+	return func(s *StorageService) {
+		s.registration = mode
+		s.inviteRepo = inviteRepo
+	}
+}
+
+// WithTrash - подключить корзину: удалённые записи можно восстановить или удалить окончательно.
+// Раз в purgeInterval записи, пролежавшие в корзине дольше retention, удаляются окончательно (0 - не очищать)
+func WithTrash(trashRepo repositories.ITrashRepository, retention, purgeInterval time.Duration) Option {
+	return func(s *StorageService) {
+		s.trashRepo = trashRepo
+		s.trashRetention = retention
+		s.trashPurgeInterval = purgeInterval
+	}
+}
+
+// WithHistory - вести историю записей: при каждом изменении прежнее состояние записи сохраняется как версия.
+// Хранится не больше maxVersions версий записи и не дольше maxAge (0 - без ограничения)
+func WithHistory(historyRepo repositories.IHistoryRepository, maxVersions int, maxAge time.Duration) Option {
+	return func(s *StorageService) {
+		s.historyRepo = historyRepo
+		s.historyMaxVersions = maxVersions
+		s.historyMaxAge = maxAge
+	}
+}
+
+// WithTransactor - выполнять каждую задачу в одной транзакции хранилища: изменения задачи фиксируются вместе
+// или не фиксируются вовсе
+func WithTransactor(transactor repositories.ITransactor) Option {
+	return func(s *StorageService) {
+		s.transactor = transactor
+	}
+}
+
+// WithQueueSize - задать ёмкость очереди задач
+func WithQueueSize(size int) Option {
+	return func(s *StorageService) {
+		s.taskQueue = make(chan Task, size)
+	}
+}
+
 // NewStorageService - инициализация сервиса
 func NewStorageService(usersRepo repositories.IRepository[entities.User, dtos.NewUser],
 	binariesRepo repositories.IRepository[entities.BinaryData, dtos.NewBinaryData],
 	cardsRepo repositories.IRepository[entities.CardInformation, dtos.NewCardInformation],
 	credentialsRepo repositories.IRepository[entities.Credentials, dtos.NewCredentials],
-	textsRepo repositories.IRepository[entities.TextData, dtos.NewTextData]) *StorageService {
+	textsRepo repositories.IRepository[entities.TextData, dtos.NewTextData],
+	accessRepo repositories.IAccessRepository,
+	opts ...Option) *StorageService {
 	service := &StorageService{
 		usersRepo:       usersRepo,
 		binariesRepo:    binariesRepo,
 		cardsRepo:       cardsRepo,
 		credentialsRepo: credentialsRepo,
 		textsRepo:       textsRepo,
+		accessRepo:      accessRepo,
+		policy:          authz.NewPolicy(),
 		taskQueue:       make(chan Task, 256),
+		stopBackground:  make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(service)
 	}
 
 	go service.taskProcessor()
 
+	if service.emergencyRepo != nil && service.emergencyCheckInterval > 0 {
+		service.backgroundTasks.Add(1)
+		go service.emergencyTimer()
+	}
+
+	if service.trashRepo != nil && service.trashPurgeInterval > 0 && service.trashRetention > 0 {
+		service.backgroundTasks.Add(1)
+		go service.trashTimer()
+	}
+
 	return service
 }
 
@@ -90,6 +413,10 @@ func (s *StorageService) taskProcessor() {
 		var result interface{}
 		var err error
 
+		if task.queueSpan != nil {
+			task.queueSpan.End()
+		}
+
 		//Если происходит shutdown - прерываем задачи которые уже стоят в очереди
 		if s.isShuttingDown.Load() {
 			if task.ResultCh != nil {
@@ -102,17 +429,47 @@ func (s *StorageService) taskProcessor() {
 			continue
 		}
 
-		switch task.EntityType {
-		case EntityBinary:
-			result, err = s.processBinaryTask(task)
-		case EntityCard:
-			result, err = s.processCardTask(task)
-		case EntityCredentials:
-			result, err = s.processCredentialsTask(task)
-		case EntityText:
-			result, err = s.processTextTask(task)
-		case EntityUser:
-			result, err = s.processUserTask(task)
+		start := time.Now()
+
+		var span trace.Span
+		task.Context, span = tracing.Tracer().Start(task.Context, "storage.process",
+			trace.WithAttributes(taskAttributes(task)...))
+
+		err = s.inTransaction(task.Context, func(ctx context.Context) error {
+			// Контекст транзакции нужен только обработчику: журнал и уведомления пишутся после её фиксации
+			txTask := task
+			txTask.Context = ctx
+			result, err = s.processTask(txTask)
+			return err
+		})
+
+		outcome := "success"
+		if err != nil {
+			outcome = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			// Ожидаемые ошибки (конфликт, запрет и т.п.) отдаются клиенту, остальные логируем для разбора по идентификатору запроса
+			var httpErr *customerrors.HTTPError
+			if !errors.As(err, &httpErr) {
+				log.Printf("storage task %s %s failed (request_id=%s): %v",
+					task.TaskType, task.EntityType, customcontext.GetRequestID(task.Context), err)
+			}
+		}
+		span.End()
+		metrics.TaskDuration.
+			WithLabelValues(task.TaskType.String(), task.EntityType.String(), outcome).
+			Observe(time.Since(start).Seconds())
+
+		if err == nil {
+			s.recordAudit(task, result)
+			s.notifyChange(task, result)
+		}
+
+		// Задача могла заблокировать, удалить учётную запись или отозвать её сессии. Кэш сбрасывается до ответа,
+		// поэтому после возврата из DisableAccount и подобных методов старые токены уже не проходят проверку
+		if (task.EntityType == EntityUser || task.EntityType == EntityAccount) && task.TaskType != TaskGet && task.TaskType != TaskGetAll {
+			s.sessions.reset()
 		}
 
 		if task.ResultCh != nil {
@@ -125,10 +482,76 @@ func (s *StorageService) taskProcessor() {
 	}
 }
 
+// processTask - обработать задачу обработчиком её типа сущности
+func (s *StorageService) processTask(task Task) (interface{}, error) {
+	switch task.EntityType {
+	case EntityBinary:
+		return s.processBinaryTask(task)
+	case EntityCard:
+		return s.processCardTask(task)
+	case EntityCredentials:
+		return s.processCredentialsTask(task)
+	case EntityText:
+		return s.processTextTask(task)
+	case EntityUser:
+		return s.processUserTask(task)
+	case EntityAudit:
+		return s.processAuditTask(task)
+	case EntityShare:
+		return s.processShareTask(task)
+	case EntityUserKeys:
+		return s.processUserKeysTask(task)
+	case EntityOrganization:
+		return s.processOrganizationTask(task)
+	case EntityOrgMember:
+		return s.processOrgMemberTask(task)
+	case EntityCollection:
+		return s.processCollectionTask(task)
+	case EntityEmergency:
+		return s.processEmergencyTask(task)
+	case EntityAccount:
+		return s.processAccountTask(task)
+	case EntityStats:
+		return s.processStatsTask(task)
+	case EntityQuota:
+		return s.processQuotaTask(task)
+	case EntityInvite:
+		return s.processInviteTask(task)
+	case EntityTrash:
+		return s.processTrashTask(task)
+	case EntityHistory:
+		return s.processHistoryTask(task)
+	}
+
+	return nil, nil
+}
+
+// inTransaction - выполнить fn в транзакции хранилища (без транзакции, если хранилище их не поддерживает)
+func (s *StorageService) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.transactor == nil {
+		return fn(ctx)
+	}
+	return s.transactor.InTransaction(ctx, fn)
+}
+
 func (s *StorageService) processBinaryTask(task Task) (interface{}, error) {
 	switch task.TaskType {
 	case TaskCreate:
 		dto := task.Payload.(*dtos.NewBinaryData)
+		if dto != nil {
+			// Идентификатор выдаётся копии, dto вызывающей стороны не меняется
+			created := *dto
+			dto = &created
+			if err := s.authorizeCreateEntry(task.Context, &dto.NewSecureEntity); err != nil {
+				return nil, err
+			}
+			if err := s.checkQuota(task.Context, customcontext.GetUserID(task.Context), task.EntityType, 1, s.storedSize(task.EntityType, dto)); err != nil {
+				return nil, err
+			}
+			if err := s.assignEntryID(task.Context, task.EntityType, &dto.NewSecureEntity); err != nil {
+				return nil, err
+			}
+		}
 		return s.binariesRepo.Create(task.Context, dto)
 	case TaskGet:
 		id := task.Payload.(string)
@@ -137,10 +560,10 @@ func (s *StorageService) processBinaryTask(task Task) (interface{}, error) {
 		return s.binariesRepo.GetAll(task.Context)
 	case TaskUpdate:
 		entity := task.Payload.(*entities.BinaryData)
-		return s.binariesRepo.Update(task.Context, entity)
+		return updateEntry(s, task.Context, task.EntityType, entity, &entity.SecureEntity, s.binariesRepo.Get, s.binariesRepo.Update)
 	case TaskDelete:
 		id := task.Payload.(string)
-		return s.binariesRepo.Delete(task.Context, id)
+		return deleteEntry(s, task.Context, task.EntityType, id, s.binariesRepo.Delete)
 	default:
 		return nil, customerrors.UnsupportedOperation
 	}
@@ -150,6 +573,20 @@ func (s *StorageService) processCardTask(task Task) (interface{}, error) {
 	switch task.TaskType {
 	case TaskCreate:
 		dto := task.Payload.(*dtos.NewCardInformation)
+		if dto != nil {
+			// Идентификатор выдаётся копии, dto вызывающей стороны не меняется
+			created := *dto
+			dto = &created
+			if err := s.authorizeCreateEntry(task.Context, &dto.NewSecureEntity); err != nil {
+				return nil, err
+			}
+			if err := s.checkQuota(task.Context, customcontext.GetUserID(task.Context), task.EntityType, 1, s.storedSize(task.EntityType, dto)); err != nil {
+				return nil, err
+			}
+			if err := s.assignEntryID(task.Context, task.EntityType, &dto.NewSecureEntity); err != nil {
+				return nil, err
+			}
+		}
 		return s.cardsRepo.Create(task.Context, dto)
 	case TaskGet:
 		id := task.Payload.(string)
@@ -158,10 +595,10 @@ func (s *StorageService) processCardTask(task Task) (interface{}, error) {
 		return s.cardsRepo.GetAll(task.Context)
 	case TaskUpdate:
 		entity := task.Payload.(*entities.CardInformation)
-		return s.cardsRepo.Update(task.Context, entity)
+		return updateEntry(s, task.Context, task.EntityType, entity, &entity.SecureEntity, s.cardsRepo.Get, s.cardsRepo.Update)
 	case TaskDelete:
 		id := task.Payload.(string)
-		return s.cardsRepo.Delete(task.Context, id)
+		return deleteEntry(s, task.Context, task.EntityType, id, s.cardsRepo.Delete)
 	default:
 		return nil, customerrors.UnsupportedOperation
 	}
@@ -171,6 +608,20 @@ func (s *StorageService) processCredentialsTask(task Task) (interface{}, error)
 	switch task.TaskType {
 	case TaskCreate:
 		dto := task.Payload.(*dtos.NewCredentials)
+		if dto != nil {
+			// Идентификатор выдаётся копии, dto вызывающей стороны не меняется
+			created := *dto
+			dto = &created
+			if err := s.authorizeCreateEntry(task.Context, &dto.NewSecureEntity); err != nil {
+				return nil, err
+			}
+			if err := s.checkQuota(task.Context, customcontext.GetUserID(task.Context), task.EntityType, 1, s.storedSize(task.EntityType, dto)); err != nil {
+				return nil, err
+			}
+			if err := s.assignEntryID(task.Context, task.EntityType, &dto.NewSecureEntity); err != nil {
+				return nil, err
+			}
+		}
 		return s.credentialsRepo.Create(task.Context, dto)
 	case TaskGet:
 		id := task.Payload.(string)
@@ -179,10 +630,10 @@ func (s *StorageService) processCredentialsTask(task Task) (interface{}, error)
 		return s.credentialsRepo.GetAll(task.Context)
 	case TaskUpdate:
 		entity := task.Payload.(*entities.Credentials)
-		return s.credentialsRepo.Update(task.Context, entity)
+		return updateEntry(s, task.Context, task.EntityType, entity, &entity.SecureEntity, s.credentialsRepo.Get, s.credentialsRepo.Update)
 	case TaskDelete:
 		id := task.Payload.(string)
-		return s.credentialsRepo.Delete(task.Context, id)
+		return deleteEntry(s, task.Context, task.EntityType, id, s.credentialsRepo.Delete)
 	default:
 		return nil, customerrors.UnsupportedOperation
 	}
@@ -192,6 +643,20 @@ func (s *StorageService) processTextTask(task Task) (interface{}, error) {
 	switch task.TaskType {
 	case TaskCreate:
 		dto := task.Payload.(*dtos.NewTextData)
+		if dto != nil {
+			// Идентификатор выдаётся копии, dto вызывающей стороны не меняется
+			created := *dto
+			dto = &created
+			if err := s.authorizeCreateEntry(task.Context, &dto.NewSecureEntity); err != nil {
+				return nil, err
+			}
+			if err := s.checkQuota(task.Context, customcontext.GetUserID(task.Context), task.EntityType, 1, s.storedSize(task.EntityType, dto)); err != nil {
+				return nil, err
+			}
+			if err := s.assignEntryID(task.Context, task.EntityType, &dto.NewSecureEntity); err != nil {
+				return nil, err
+			}
+		}
 		return s.textsRepo.Create(task.Context, dto)
 	case TaskGet:
 		id := task.Payload.(string)
@@ -200,10 +665,10 @@ func (s *StorageService) processTextTask(task Task) (interface{}, error) {
 		return s.textsRepo.GetAll(task.Context)
 	case TaskUpdate:
 		entity := task.Payload.(*entities.TextData)
-		return s.textsRepo.Update(task.Context, entity)
+		return updateEntry(s, task.Context, task.EntityType, entity, &entity.SecureEntity, s.textsRepo.Get, s.textsRepo.Update)
 	case TaskDelete:
 		id := task.Payload.(string)
-		return s.textsRepo.Delete(task.Context, id)
+		return deleteEntry(s, task.Context, task.EntityType, id, s.textsRepo.Delete)
 	default:
 		return nil, customerrors.UnsupportedOperation
 	}
@@ -224,346 +689,2853 @@ func (s *StorageService) processUserTask(task Task) (interface{}, error) {
 		return s.updateUser(task.Context, entity)
 	case TaskDelete:
 		id := task.Payload.(string)
+		// Без записей пользователя в таблицах остались бы строки без владельца
+		if s.accountRepo != nil {
+			return s.accountRepo.Delete(task.Context, id)
+		}
 		return s.usersRepo.Delete(task.Context, id)
 	default:
 		return nil, customerrors.UnsupportedOperation
 	}
 }
 
-// enqueueTask - поставить задачу в очередь
-func (s *StorageService) enqueueTask(task Task) (interface{}, error) {
-	// Проверяем, не начался ли shutdown
-	if s.isShuttingDown.Load() {
-		return nil, customerrors.ServiceUnavailableError
-	}
-
-	if task.ResultCh == nil {
-		task.ResultCh = make(chan TaskResult, 1)
+func (s *StorageService) processAuditTask(task Task) (interface{}, error) {
+	if s.auditRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("audit log is disabled"))
 	}
 
-	s.tasksInProcess.Add(1) // Увеличиваем счетчик
-	s.taskQueue <- task
+	switch task.TaskType {
+	case TaskGetAll:
+		filter := *task.Payload.(*dtos.AuditFilter)
+		if customcontext.IsAdmin(task.Context) {
+			// Администратор видит действия администраторов (при необходимости - над одной учётной записью)
+			filter.Actor = AdminActor
+			return s.auditRepo.Query(task.Context, &filter)
+		}
 
-	select {
-	case <-task.Context.Done():
-		s.tasksInProcess.Done() // Уменьшаем счётчик
-		return nil, task.Context.Err()
-	case res := <-task.ResultCh:
-		s.tasksInProcess.Done() // Уменьшаем счётчик
-		return res.Result, res.Err
+		// Пользователь видит только собственную историю
+		filter.UserID = customcontext.GetUserID(task.Context)
+		if filter.UserID == "" {
+			return nil, customerrors.ForbiddenError
+		}
+		return s.auditRepo.Query(task.Context, &filter)
+	default:
+		return nil, customerrors.UnsupportedOperation
 	}
 }
 
-// CreateUser - создать пользователя
-func (s *StorageService) CreateUser(ctx context.Context, newUser dtos.NewUser) (*entities.User, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskCreate,
-		EntityType: EntityUser,
-		Context:    ctx,
-		Payload:    &newUser,
-	})
+func (s *StorageService) processShareTask(task Task) (interface{}, error) {
+	if s.shareRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("sharing is disabled"))
+	}
 
-	return res.(*entities.User), err
+	switch task.TaskType {
+	case TaskCreate:
+		dto := task.Payload.(*dtos.NewShareGrant)
+		return s.createShare(task.Context, dto)
+	case TaskGetAll:
+		return s.shareRepo.GetAll(task.Context)
+	case TaskAccept:
+		id := task.Payload.(string)
+		return s.shareRepo.Accept(task.Context, id)
+	case TaskDelete:
+		id := task.Payload.(string)
+		return s.shareRepo.Delete(task.Context, id)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
 }
 
-// GetUser - получить пользователя по логину
-func (s *StorageService) GetUser(ctx context.Context, login string) (*entities.User, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGet,
-		EntityType: EntityUser,
-		Context:    ctx,
-		Payload:    login,
-	})
+func (s *StorageService) processUserKeysTask(task Task) (interface{}, error) {
+	if s.userKeysRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("sharing is disabled"))
+	}
 
-	return res.(*entities.User), err
+	switch task.TaskType {
+	case TaskUpdate:
+		// Пользователь задаёт только собственные ключи
+		keys := *task.Payload.(*entities.UserKeys)
+		keys.Login = customcontext.GetUserID(task.Context)
+		return s.userKeysRepo.Set(task.Context, &keys)
+	case TaskGet:
+		login := task.Payload.(string)
+		return s.getUserKeys(task.Context, login)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
 }
 
-// GetAllUsers - получить всех пользователей
-func (s *StorageService) GetAllUsers(ctx context.Context) ([]entities.User, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGetAll,
-		EntityType: EntityUser,
-		Context:    ctx,
-	})
+func (s *StorageService) processOrganizationTask(task Task) (interface{}, error) {
+	if s.orgRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("organizations are disabled"))
+	}
 
-	return res.([]entities.User), err
+	switch task.TaskType {
+	case TaskCreate:
+		dto := task.Payload.(*dtos.NewOrganization)
+		return s.orgRepo.Create(task.Context, dto)
+	case TaskGetAll:
+		return s.orgRepo.GetAll(task.Context)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
 }
 
-// UpdateUser - изменить пользователя
-func (s *StorageService) UpdateUser(ctx context.Context, user *entities.User) (*entities.User, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskUpdate,
-		EntityType: EntityUser,
-		Context:    ctx,
-		Payload:    user,
-	})
+func (s *StorageService) processOrgMemberTask(task Task) (interface{}, error) {
+	if s.orgRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("organizations are disabled"))
+	}
 
-	return res.(*entities.User), err
+	switch task.TaskType {
+	case TaskCreate:
+		dto := task.Payload.(*dtos.NewOrgMember)
+		return s.inviteMember(task.Context, dto)
+	case TaskGetAll:
+		orgID := task.Payload.(string)
+		return s.getOrgMembers(task.Context, orgID)
+	case TaskAccept:
+		// Принять можно только собственное приглашение
+		orgID := task.Payload.(string)
+		return s.orgRepo.ActivateMember(task.Context, orgID, customcontext.GetUserID(task.Context))
+	case TaskDelete:
+		member := task.Payload.(*entities.OrgMember)
+		return s.removeMember(task.Context, member.OrgID, member.Login)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
 }
 
-// DeleteUser - удалить пользователя
-func (s *StorageService) DeleteUser(ctx context.Context, login string) (*entities.User, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskDelete,
-		EntityType: EntityUser,
-		Context:    ctx,
-		Payload:    login,
-	})
+func (s *StorageService) processCollectionTask(task Task) (interface{}, error) {
+	if s.orgRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("organizations are disabled"))
+	}
 
-	return res.(*entities.User), err
+	switch task.TaskType {
+	case TaskCreate:
+		dto := task.Payload.(*dtos.NewCollection)
+		return s.createCollection(task.Context, dto)
+	case TaskGetAll:
+		orgID := task.Payload.(string)
+		return s.getCollections(task.Context, orgID)
+	case TaskRotate:
+		rotation := task.Payload.(*dtos.CollectionRotation)
+		return s.rotateCollectionKey(task.Context, rotation)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
 }
 
-// CreateBinary - создать бинарные данные
-func (s *StorageService) CreateBinary(ctx context.Context, newBinary *dtos.NewBinaryData) (*entities.BinaryData, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskCreate,
-		EntityType: EntityBinary,
-		Context:    ctx,
-		Payload:    newBinary,
-	})
-	return res.(*entities.BinaryData), err
-}
+func (s *StorageService) processEmergencyTask(task Task) (interface{}, error) {
+	if s.emergencyRepo == nil || s.userKeysRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("emergency access is disabled"))
+	}
 
-// GetBinary - получить бинарные данные
-func (s *StorageService) GetBinary(ctx context.Context, id string) (*entities.BinaryData, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGet,
-		EntityType: EntityBinary,
-		Context:    ctx,
-		Payload:    id,
-	})
-	return res.(*entities.BinaryData), err
+	switch task.TaskType {
+	case TaskCreate:
+		dto := task.Payload.(*dtos.NewEmergencyAccess)
+		return s.inviteEmergencyContact(task.Context, dto)
+	case TaskGetAll:
+		return s.getEmergencyAccess(task.Context)
+	case TaskGet:
+		id := task.Payload.(string)
+		return s.openEmergencyVault(task.Context, id)
+	case TaskAccept:
+		id := task.Payload.(string)
+		return s.moveEmergencyAccess(task.Context, id, authz.ActionEmergencyAccept)
+	case TaskRequest:
+		id := task.Payload.(string)
+		return s.moveEmergencyAccess(task.Context, id, authz.ActionEmergencyRequest)
+	case TaskApprove:
+		id := task.Payload.(string)
+		return s.moveEmergencyAccess(task.Context, id, authz.ActionEmergencyApprove)
+	case TaskReject:
+		id := task.Payload.(string)
+		return s.moveEmergencyAccess(task.Context, id, authz.ActionEmergencyReject)
+	case TaskDelete:
+		id := task.Payload.(string)
+		return s.deleteEmergencyAccess(task.Context, id)
+	case TaskExpire:
+		now := task.Payload.(time.Time)
+		return s.grantElapsedEmergencyAccess(task.Context, now)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
 }
 
-// GetAllBinaries - получить все бинарные данные
-func (s *StorageService) GetAllBinaries(ctx context.Context) ([]entities.BinaryData, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGetAll,
-		EntityType: EntityBinary,
-		Context:    ctx,
-	})
-	return res.([]entities.BinaryData), err
-}
+func (s *StorageService) processAccountTask(task Task) (interface{}, error) {
+	if s.accountRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("account administration is disabled"))
+	}
 
-// UpdateBinary - изменить бинарные данные
-func (s *StorageService) UpdateBinary(ctx context.Context, binary *entities.BinaryData) (*entities.BinaryData, error) {
+	// Пользователь может выгрузить или удалить только собственную учётную запись
+	if !customcontext.IsAdmin(task.Context) {
+		login := customcontext.GetUserID(task.Context)
+		if login == "" {
+			return nil, customerrors.ForbiddenError
+		}
+
+		switch task.TaskType {
+		case TaskGet:
+			return s.exportAccount(task.Context, login)
+		case TaskDelete:
+			if task.Payload.(string) != login {
+				return nil, customerrors.ForbiddenError
+			}
+			return s.deleteAccount(task.Context, login)
+		default:
+			return nil, customerrors.ForbiddenError
+		}
+	}
+
+	switch task.TaskType {
+	case TaskGetAll:
+		return s.getAccounts(task.Context)
+	case TaskDisable:
+		login := task.Payload.(string)
+		return s.changeAccount(task.Context, login, func(user *entities.User) {
+			// Блокировка сразу завершает сессии: после разблокировки нужно войти заново
+			user.Disabled = true
+			user.SessionVersion++
+		})
+	case TaskEnable:
+		login := task.Payload.(string)
+		return s.changeAccount(task.Context, login, func(user *entities.User) {
+			user.Disabled = false
+		})
+	case TaskRevoke:
+		login := task.Payload.(string)
+		return s.changeAccount(task.Context, login, func(user *entities.User) {
+			user.SessionVersion++
+		})
+	case TaskDelete:
+		login := task.Payload.(string)
+		return s.deleteAccount(task.Context, login)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
+}
+
+func (s *StorageService) processStatsTask(task Task) (interface{}, error) {
+	if s.accountRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("account administration is disabled"))
+	}
+
+	if !customcontext.IsAdmin(task.Context) {
+		return nil, customerrors.ForbiddenError
+	}
+
+	switch task.TaskType {
+	case TaskGet:
+		stats, err := s.accountRepo.Stats(task.Context)
+		if err != nil {
+			return nil, err
+		}
+		stats.QueueLength, stats.QueueCapacity = s.QueueLength(), s.QueueCapacity()
+		return stats, nil
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
+}
+
+func (s *StorageService) processQuotaTask(task Task) (interface{}, error) {
+	if s.accountRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("storage usage is not tracked"))
+	}
+
+	switch task.TaskType {
+	case TaskGet:
+		// Пользователь видит только собственное использование
+		login := customcontext.GetUserID(task.Context)
+		if login == "" {
+			return nil, customerrors.ForbiddenError
+		}
+		return s.getQuotaUsage(task.Context, login)
+	case TaskUpdate:
+		if !customcontext.IsAdmin(task.Context) {
+			return nil, customerrors.ForbiddenError
+		}
+		change := task.Payload.(*dtos.QuotaChange)
+		return s.setQuota(task.Context, change.Login, change.Quota)
+	case TaskDelete:
+		if !customcontext.IsAdmin(task.Context) {
+			return nil, customerrors.ForbiddenError
+		}
+		login := task.Payload.(string)
+		return s.setQuota(task.Context, login, nil)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
+}
+
+func (s *StorageService) processInviteTask(task Task) (interface{}, error) {
+	if s.inviteRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("invitations are disabled"))
+	}
+
+	if !customcontext.IsAdmin(task.Context) {
+		return nil, customerrors.ForbiddenError
+	}
+
+	switch task.TaskType {
+	case TaskCreate:
+		dto := task.Payload.(*dtos.NewInvite)
+		return s.createInvite(task.Context, dto)
+	case TaskGetAll:
+		return s.inviteRepo.GetAll(task.Context)
+	case TaskDelete:
+		code := task.Payload.(string)
+		invite, err := s.inviteRepo.Delete(task.Context, code)
+		if err != nil {
+			return nil, err
+		}
+		if invite == nil {
+			return nil, errInviteNotFound
+		}
+		return invite, nil
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
+}
+
+// trashTarget - запись в корзине, над которой выполняется задача
+type trashTarget struct {
+	entityType EntityType
+	id         string
+}
+
+func (s *StorageService) processTrashTask(task Task) (interface{}, error) {
+	if s.trashRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("trash is disabled"))
+	}
+
+	switch task.TaskType {
+	case TaskGetAll:
+		return s.getTrash(task.Context)
+	case TaskRestore:
+		target := task.Payload.(trashTarget)
+		return s.changeTrashItem(task.Context, target, s.trashRepo.Restore)
+	case TaskPurge:
+		target := task.Payload.(trashTarget)
+		return s.changeTrashItem(task.Context, target, s.trashRepo.Delete)
+	case TaskExpire:
+		now := task.Payload.(time.Time)
+		return s.purgeTrash(task.Context, now)
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
+}
+
+// historyTarget - запись (и её версия при восстановлении), над историей которой выполняется задача
+type historyTarget struct {
+	entityType EntityType
+	id         string
+	revision   int
+}
+
+func (s *StorageService) processHistoryTask(task Task) (interface{}, error) {
+	if s.historyRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("history is disabled"))
+	}
+
+	target := task.Payload.(historyTarget)
+	switch target.entityType {
+	case EntityBinary, EntityCard, EntityCredentials, EntityText:
+	default:
+		return nil, customerrors.NewHTTPError(errors.New("entity type has no history"), http.StatusBadRequest)
+	}
+
+	switch task.TaskType {
+	case TaskGetAll:
+		return s.getHistory(task.Context, target)
+	case TaskRestore:
+		switch target.entityType {
+		case EntityBinary:
+			entity := &entities.BinaryData{}
+			return restoreRevision(s, task.Context, target, entity, &entity.SecureEntity, s.binariesRepo.Get, s.binariesRepo.Update)
+		case EntityCard:
+			entity := &entities.CardInformation{}
+			return restoreRevision(s, task.Context, target, entity, &entity.SecureEntity, s.cardsRepo.Get, s.cardsRepo.Update)
+		case EntityCredentials:
+			entity := &entities.Credentials{}
+			return restoreRevision(s, task.Context, target, entity, &entity.SecureEntity, s.credentialsRepo.Get, s.credentialsRepo.Update)
+		case EntityText:
+			entity := &entities.TextData{}
+			return restoreRevision(s, task.Context, target, entity, &entity.SecureEntity, s.textsRepo.Get, s.textsRepo.Update)
+		default:
+			return nil, customerrors.UnsupportedOperation
+		}
+	default:
+		return nil, customerrors.UnsupportedOperation
+	}
+}
+
+// recordAudit - записать в журнал аудита выполненную задачу.
+// Не пишутся: обращения к самому журналу, чтение пользователей (проверка при входе) и операции над несуществующими сущностями
+func (s *StorageService) recordAudit(task Task, result interface{}) {
+	if s.auditRepo == nil || task.EntityType == EntityAudit {
+		return
+	}
+
+	if task.EntityType == EntityUser && (task.TaskType == TaskGet || task.TaskType == TaskGetAll) {
+		return
+	}
+
+	// Фоновые проверки пишут события сами: сторонам каждого экстренного доступа и владельцам очищенных записей
+	if (task.EntityType == EntityEmergency || task.EntityType == EntityTrash) && task.TaskType == TaskExpire {
+		return
+	}
+
+	event := entities.AuditEvent{
+		UserID:     customcontext.GetUserID(task.Context),
+		Action:     task.TaskType.String(),
+		EntityType: task.EntityType.String(),
+		SessionID:  customcontext.GetSessionID(task.Context),
+		DeviceID:   customcontext.GetDeviceID(task.Context),
+		IP:         customcontext.GetClientIP(task.Context),
+	}
+
+	// Действия администратора пишутся от его имени в историю учётной записи, над которой они выполнены
+	if customcontext.IsAdmin(task.Context) {
+		event.Actor = AdminActor
+		if account, ok := result.(*entities.Account); ok && account != nil {
+			event.UserID = account.Login
+		}
+	}
+
+	// Восстановление и окончательное удаление попадают в историю записи вместе с её удалением
+	if item, ok := result.(*entities.TrashItem); ok && item != nil {
+		event.EntityType = item.EntityType
+	}
+
+	// Просмотр истории и восстановление версии попадают в историю самой записи
+	if target, ok := task.Payload.(historyTarget); ok {
+		event.EntityType = target.entityType.String()
+		event.EntityID = target.id
+	}
+
+	if user, ok := result.(*entities.User); ok {
+		if user == nil {
+			return
+		}
+		// При регистрации пользователя в контексте ещё нет
+		event.UserID = user.Login
+		event.EntityID = user.Login
+	} else if id, isEntity := resultEntityID(result); isEntity {
+		if id == "" {
+			return
+		}
+		event.EntityID = id
+	}
+
+	// Ошибка журнала не должна отменять уже выполненную операцию
+	if err := s.auditRepo.Append(task.Context, &event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
+
+// notifyChange - уведомить клиентов пользователя об изменении его сущности
+func (s *StorageService) notifyChange(task Task, result interface{}) {
+	if s.notifier == nil {
+		return
+	}
+
+	action, entityType := task.TaskType.String(), task.EntityType.String()
+	var entity entities.SecureEntity
+
+	switch task.EntityType {
+	case EntityBinary, EntityCard, EntityCredentials, EntityText:
+		switch task.TaskType {
+		case TaskCreate, TaskUpdate, TaskDelete:
+		default:
+			return
+		}
+		entity, _ = resultSecureEntity(result)
+	case EntityHistory:
+		// Для клиентов восстановление версии - обычное изменение записи
+		if task.TaskType != TaskRestore {
+			return
+		}
+		action, entityType = TaskUpdate.String(), task.Payload.(historyTarget).entityType.String()
+		entity, _ = resultSecureEntity(result)
+	case EntityTrash:
+		// Для клиентов восстановленная запись появляется заново
+		item, ok := result.(*entities.TrashItem)
+		if task.TaskType != TaskRestore || !ok || item == nil {
+			return
+		}
+		action, entityType, entity = TaskCreate.String(), item.EntityType, item.SecureEntity
+	default:
+		return
+	}
+
+	if entity.ID == "" {
+		return
+	}
+
+	event := notifications.Event{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entity.ID,
+		OccurredAt: time.Now().UTC(),
+		SessionID:  customcontext.GetSessionID(task.Context),
+	}
+
+	userID := customcontext.GetUserID(task.Context)
+	s.notifier.Publish(userID, event)
+
+	// Чужую запись изменил получатель с правом записи - владельцу тоже нужно об этом узнать
+	if entity.OwnerID != "" && entity.OwnerID != userID {
+		s.notifier.Publish(entity.OwnerID, event)
+	}
+}
+
+// resultEntityID - идентификатор сущности из результата задачи (защищённой сущности, права на запись, ключей пользователя,
+// организации, её участника, коллекции или экстренного доступа).
+// isEntity - результат является сущностью (для несуществующей сущности идентификатор пустой)
+func resultEntityID(result interface{}) (id string, isEntity bool) {
+	switch entity := result.(type) {
+	case *entities.ShareGrant:
+		if entity != nil {
+			id = entity.ID
+		}
+	case *entities.UserKeys:
+		if entity != nil {
+			id = entity.Login
+		}
+	case *entities.Organization:
+		if entity != nil {
+			id = entity.ID
+		}
+	case *entities.OrgMember:
+		if entity != nil {
+			id = entity.Login
+		}
+	case *entities.Collection:
+		if entity != nil {
+			id = entity.ID
+		}
+	case *entities.EmergencyAccess:
+		if entity != nil {
+			id = entity.ID
+		}
+	case *entities.EmergencyVault:
+		if entity != nil {
+			id = entity.Access.ID
+		}
+	case *entities.Account:
+		if entity != nil {
+			id = entity.Login
+		}
+	case *entities.Invite:
+		if entity != nil {
+			id = entity.Code
+		}
+	case *entities.TrashItem:
+		if entity != nil {
+			id = entity.ID
+		}
+	default:
+		secure, isSecure := resultSecureEntity(result)
+		return secure.ID, isSecure
+	}
+
+	return id, true
+}
+
+// resultSecureEntity - общие поля защищённой сущности из результата задачи.
+// isEntity - результат является защищённой сущностью (для несуществующей сущности поля пустые)
+func resultSecureEntity(result interface{}) (secure entities.SecureEntity, isEntity bool) {
+	switch entity := result.(type) {
+	case *entities.BinaryData:
+		if entity != nil {
+			secure = entity.SecureEntity
+		}
+	case *entities.CardInformation:
+		if entity != nil {
+			secure = entity.SecureEntity
+		}
+	case *entities.Credentials:
+		if entity != nil {
+			secure = entity.SecureEntity
+		}
+	case *entities.TextData:
+		if entity != nil {
+			secure = entity.SecureEntity
+		}
+	default:
+		return secure, false
+	}
+
+	return secure, true
+}
+
+// enqueueTask - поставить задачу в очередь
+func (s *StorageService) enqueueTask(task Task) (interface{}, error) {
+	// Проверяем, не начался ли shutdown
+	if s.isShuttingDown.Load() {
+		return nil, customerrors.ServiceUnavailableError
+	}
+
+	if task.ResultCh == nil {
+		task.ResultCh = make(chan TaskResult, 1)
+	}
+
+	_, task.queueSpan = tracing.Tracer().Start(task.Context, "storage.queue_wait",
+		trace.WithAttributes(taskAttributes(task)...))
+
+	s.tasksInProcess.Add(1) // Увеличиваем счетчик
+	s.taskQueue <- task
+
+	select {
+	case <-task.Context.Done():
+		s.tasksInProcess.Done() // Уменьшаем счётчик
+		return nil, task.Context.Err()
+	case res := <-task.ResultCh:
+		s.tasksInProcess.Done() // Уменьшаем счётчик
+		return res.Result, res.Err
+	}
+}
+
+// taskAttributes - атрибуты спанов задачи
+func taskAttributes(task Task) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("task.type", task.TaskType.String()),
+		attribute.String("task.entity_type", task.EntityType.String()),
+	}
+}
+
+// CreateUser - создать пользователя
+func (s *StorageService) CreateUser(ctx context.Context, newUser dtos.NewUser) (*entities.User, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityUser,
+		Context:    ctx,
+		Payload:    &newUser,
+	})
+
+	return res.(*entities.User), err
+}
+
+// GetUser - получить пользователя по логину
+func (s *StorageService) GetUser(ctx context.Context, login string) (*entities.User, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityUser,
+		Context:    ctx,
+		Payload:    login,
+	})
+
+	return res.(*entities.User), err
+}
+
+// GetAllUsers - получить всех пользователей
+func (s *StorageService) GetAllUsers(ctx context.Context) ([]entities.User, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityUser,
+		Context:    ctx,
+	})
+
+	return res.([]entities.User), err
+}
+
+// UpdateUser - изменить пользователя
+func (s *StorageService) UpdateUser(ctx context.Context, user *entities.User) (*entities.User, error) {
 	res, err := s.enqueueTask(Task{
 		TaskType:   TaskUpdate,
-		EntityType: EntityBinary,
+		EntityType: EntityUser,
 		Context:    ctx,
-		Payload:    binary,
+		Payload:    user,
 	})
-	return res.(*entities.BinaryData), err
+
+	return res.(*entities.User), err
 }
 
-// DeleteBinary - удалить бинарные данные
-func (s *StorageService) DeleteBinary(ctx context.Context, id string) (*entities.BinaryData, error) {
+// DeleteUser - удалить пользователя
+func (s *StorageService) DeleteUser(ctx context.Context, login string) (*entities.User, error) {
 	res, err := s.enqueueTask(Task{
 		TaskType:   TaskDelete,
-		EntityType: EntityBinary,
+		EntityType: EntityUser,
 		Context:    ctx,
-		Payload:    id,
+		Payload:    login,
 	})
-	return res.(*entities.BinaryData), err
+
+	return res.(*entities.User), err
 }
 
-// CreateCard - создать данные банковской карты
-func (s *StorageService) CreateCard(ctx context.Context, newCard *dtos.NewCardInformation) (*entities.CardInformation, error) {
+// CreateBinary - создать бинарные данные
+func (s *StorageService) CreateBinary(ctx context.Context, newBinary *dtos.NewBinaryData) (*entities.BinaryData, error) {
 	res, err := s.enqueueTask(Task{
 		TaskType:   TaskCreate,
-		EntityType: EntityCard,
+		EntityType: EntityBinary,
 		Context:    ctx,
-		Payload:    newCard,
+		Payload:    newBinary,
 	})
-	return res.(*entities.CardInformation), err
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.BinaryData), nil
 }
 
-// GetCard - получить данные банковской карты
-func (s *StorageService) GetCard(ctx context.Context, id string) (*entities.CardInformation, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGet,
-		EntityType: EntityCard,
-		Context:    ctx,
-		Payload:    id,
-	})
-	return res.(*entities.CardInformation), err
+// GetBinary - получить бинарные данные
+func (s *StorageService) GetBinary(ctx context.Context, id string) (*entities.BinaryData, error) {
+	if !isEntryID(id) {
+		return nil, nil
+	}
+
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityBinary,
+		Context:    ctx,
+		Payload:    id,
+	})
+	return res.(*entities.BinaryData), err
+}
+
+// GetAllBinaries - получить все бинарные данные
+func (s *StorageService) GetAllBinaries(ctx context.Context) ([]entities.BinaryData, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityBinary,
+		Context:    ctx,
+	})
+	return res.([]entities.BinaryData), err
+}
+
+// UpdateBinary - изменить бинарные данные
+func (s *StorageService) UpdateBinary(ctx context.Context, binary *entities.BinaryData) (*entities.BinaryData, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskUpdate,
+		EntityType: EntityBinary,
+		Context:    ctx,
+		Payload:    binary,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.BinaryData), nil
+}
+
+// DeleteBinary - удалить бинарные данные
+func (s *StorageService) DeleteBinary(ctx context.Context, id string) (*entities.BinaryData, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityBinary,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.BinaryData), nil
+}
+
+// CreateCard - создать данные банковской карты
+func (s *StorageService) CreateCard(ctx context.Context, newCard *dtos.NewCardInformation) (*entities.CardInformation, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityCard,
+		Context:    ctx,
+		Payload:    newCard,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.CardInformation), nil
+}
+
+// GetCard - получить данные банковской карты
+func (s *StorageService) GetCard(ctx context.Context, id string) (*entities.CardInformation, error) {
+	if !isEntryID(id) {
+		return nil, nil
+	}
+
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityCard,
+		Context:    ctx,
+		Payload:    id,
+	})
+	return res.(*entities.CardInformation), err
+}
+
+// GetAllCards - получить все данные банковской карты
+func (s *StorageService) GetAllCards(ctx context.Context) ([]entities.CardInformation, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityCard,
+		Context:    ctx,
+	})
+	return res.([]entities.CardInformation), err
+}
+
+// UpdateCard - изменить данные банковской карты
+func (s *StorageService) UpdateCard(ctx context.Context, card *entities.CardInformation) (*entities.CardInformation, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskUpdate,
+		EntityType: EntityCard,
+		Context:    ctx,
+		Payload:    card,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.CardInformation), nil
+}
+
+// DeleteCard - удалить данные банковской карты
+func (s *StorageService) DeleteCard(ctx context.Context, id string) (*entities.CardInformation, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityCard,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.CardInformation), nil
+}
+
+// CreateCredentials - создать учётные данные
+func (s *StorageService) CreateCredentials(ctx context.Context, newCreds *dtos.NewCredentials) (*entities.Credentials, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityCredentials,
+		Context:    ctx,
+		Payload:    newCreds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Credentials), nil
+}
+
+// GetCredentials - получить учётные данные
+func (s *StorageService) GetCredentials(ctx context.Context, id string) (*entities.Credentials, error) {
+	if !isEntryID(id) {
+		return nil, nil
+	}
+
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityCredentials,
+		Context:    ctx,
+		Payload:    id,
+	})
+	return res.(*entities.Credentials), err
+}
+
+// GetAllCredentials - получить все учётные данные
+func (s *StorageService) GetAllCredentials(ctx context.Context) ([]entities.Credentials, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityCredentials,
+		Context:    ctx,
+	})
+	return res.([]entities.Credentials), err
+}
+
+// UpdateCredentials - изменить учётные данные
+func (s *StorageService) UpdateCredentials(ctx context.Context, creds *entities.Credentials) (*entities.Credentials, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskUpdate,
+		EntityType: EntityCredentials,
+		Context:    ctx,
+		Payload:    creds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Credentials), nil
+}
+
+// DeleteCredentials - удалить учётные данные
+func (s *StorageService) DeleteCredentials(ctx context.Context, id string) (*entities.Credentials, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityCredentials,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Credentials), nil
+}
+
+// CreateText - создать текстовые данные
+func (s *StorageService) CreateText(ctx context.Context, newText *dtos.NewTextData) (*entities.TextData, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityText,
+		Context:    ctx,
+		Payload:    newText,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.TextData), nil
+}
+
+// GetText - получить текстовые данные
+func (s *StorageService) GetText(ctx context.Context, id string) (*entities.TextData, error) {
+	if !isEntryID(id) {
+		return nil, nil
+	}
+
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityText,
+		Context:    ctx,
+		Payload:    id,
+	})
+	return res.(*entities.TextData), err
+}
+
+// GetAllTexts - получить все текстовые данные
+func (s *StorageService) GetAllTexts(ctx context.Context) ([]entities.TextData, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityText,
+		Context:    ctx,
+	})
+	return res.([]entities.TextData), err
+}
+
+// UpdateText - изменить текстовые данные
+func (s *StorageService) UpdateText(ctx context.Context, text *entities.TextData) (*entities.TextData, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskUpdate,
+		EntityType: EntityText,
+		Context:    ctx,
+		Payload:    text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.TextData), nil
+}
+
+// DeleteText - удалить текстовые данные
+func (s *StorageService) DeleteText(ctx context.Context, id string) (*entities.TextData, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityText,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.TextData), nil
+}
+
+// GetAuditLog - получить журнал аудита текущего пользователя
+func (s *StorageService) GetAuditLog(ctx context.Context, filter *dtos.AuditFilter) ([]entities.AuditEvent, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityAudit,
+		Context:    ctx,
+		Payload:    filter,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.AuditEvent), nil
+}
+
+// SetUserKeys - сохранить пару ключей текущего пользователя
+func (s *StorageService) SetUserKeys(ctx context.Context, keys *entities.UserKeys) (*entities.UserKeys, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskUpdate,
+		EntityType: EntityUserKeys,
+		Context:    ctx,
+		Payload:    keys,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.UserKeys), nil
+}
+
+// GetUserKeys - получить ключи пользователя. Зашифрованный закрытый ключ отдаётся только его владельцу
+func (s *StorageService) GetUserKeys(ctx context.Context, login string) (*entities.UserKeys, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityUserKeys,
+		Context:    ctx,
+		Payload:    login,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.UserKeys), nil
+}
+
+// ShareEntry - поделиться собственной записью с другим пользователем
+func (s *StorageService) ShareEntry(ctx context.Context, grant *dtos.NewShareGrant) (*entities.ShareGrant, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityShare,
+		Context:    ctx,
+		Payload:    grant,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.ShareGrant), nil
+}
+
+// GetShares - получить права, выданные текущим пользователем и выданные ему
+func (s *StorageService) GetShares(ctx context.Context) ([]entities.ShareGrant, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityShare,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.ShareGrant), nil
+}
+
+// AcceptShare - принять приглашение к чужой записи
+func (s *StorageService) AcceptShare(ctx context.Context, id string) (*entities.ShareGrant, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskAccept,
+		EntityType: EntityShare,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.ShareGrant), nil
+}
+
+// DeleteShare - отозвать право на запись (владелец) или отказаться от него (получатель)
+func (s *StorageService) DeleteShare(ctx context.Context, id string) (*entities.ShareGrant, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityShare,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.ShareGrant), nil
+}
+
+// CreateOrganization - создать организацию, текущий пользователь становится её владельцем
+func (s *StorageService) CreateOrganization(ctx context.Context, org *dtos.NewOrganization) (*entities.Organization, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityOrganization,
+		Context:    ctx,
+		Payload:    org,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Organization), nil
+}
+
+// GetOrganizations - получить организации текущего пользователя (в том числе те, куда он приглашён)
+func (s *StorageService) GetOrganizations(ctx context.Context) ([]entities.Organization, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityOrganization,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.Organization), nil
+}
+
+// GetOrgMembers - получить участников организации
+func (s *StorageService) GetOrgMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityOrgMember,
+		Context:    ctx,
+		Payload:    orgID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.OrgMember), nil
+}
+
+// InviteMember - пригласить пользователя в организацию
+func (s *StorageService) InviteMember(ctx context.Context, member *dtos.NewOrgMember) (*entities.OrgMember, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityOrgMember,
+		Context:    ctx,
+		Payload:    member,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.OrgMember), nil
+}
+
+// AcceptInvite - принять приглашение в организацию
+func (s *StorageService) AcceptInvite(ctx context.Context, orgID string) (*entities.OrgMember, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskAccept,
+		EntityType: EntityOrgMember,
+		Context:    ctx,
+		Payload:    orgID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.OrgMember), nil
+}
+
+// RemoveMember - исключить участника из организации или выйти из неё
+func (s *StorageService) RemoveMember(ctx context.Context, member *entities.OrgMember) (*entities.OrgMember, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityOrgMember,
+		Context:    ctx,
+		Payload:    member,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.OrgMember), nil
+}
+
+// CreateCollection - создать коллекцию организации
+func (s *StorageService) CreateCollection(ctx context.Context, collection *dtos.NewCollection) (*entities.Collection, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityCollection,
+		Context:    ctx,
+		Payload:    collection,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Collection), nil
+}
+
+// GetCollections - получить коллекции организации с ключами, зашифрованными для текущего пользователя
+func (s *StorageService) GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityCollection,
+		Context:    ctx,
+		Payload:    orgID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.Collection), nil
+}
+
+// RotateCollectionKey - заменить ключ коллекции
+func (s *StorageService) RotateCollectionKey(ctx context.Context, rotation *dtos.CollectionRotation) (*entities.Collection, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskRotate,
+		EntityType: EntityCollection,
+		Context:    ctx,
+		Payload:    rotation,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Collection), nil
+}
+
+// InviteEmergencyContact - назначить доверенное лицо для экстренного доступа к хранилищу текущего пользователя
+func (s *StorageService) InviteEmergencyContact(ctx context.Context, access *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+		Payload:    access,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.EmergencyAccess), nil
+}
+
+// GetEmergencyAccess - получить экстренные доступы, в которых текущий пользователь доверитель или доверенное лицо
+func (s *StorageService) GetEmergencyAccess(ctx context.Context) ([]entities.EmergencyAccess, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.EmergencyAccess), nil
+}
+
+// AcceptEmergencyAccess - согласиться быть доверенным лицом
+func (s *StorageService) AcceptEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskAccept,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.EmergencyAccess), nil
+}
+
+// RequestEmergencyAccess - запросить доступ к хранилищу доверителя (начинается период ожидания)
+func (s *StorageService) RequestEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskRequest,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.EmergencyAccess), nil
+}
+
+// ApproveEmergencyAccess - предоставить запрошенный доступ, не дожидаясь окончания ожидания
+func (s *StorageService) ApproveEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskApprove,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.EmergencyAccess), nil
+}
+
+// RejectEmergencyAccess - отклонить запрос доступа или закрыть предоставленный доступ
+func (s *StorageService) RejectEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskReject,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.EmergencyAccess), nil
+}
+
+// DeleteEmergencyAccess - отменить экстренный доступ (любой из сторон)
+func (s *StorageService) DeleteEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.EmergencyAccess), nil
+}
+
+// OpenEmergencyVault - открыть хранилище доверителя по предоставленному экстренному доступу
+func (s *StorageService) OpenEmergencyVault(ctx context.Context, id string) (*entities.EmergencyVault, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+		Payload:    id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.EmergencyVault), nil
+}
+
+// GrantElapsedEmergencyAccess - предоставить доступ по запросам, период ожидания которых истёк к моменту now
+func (s *StorageService) GrantElapsedEmergencyAccess(ctx context.Context, now time.Time) ([]entities.EmergencyAccess, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskExpire,
+		EntityType: EntityEmergency,
+		Context:    ctx,
+		Payload:    now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.EmergencyAccess), nil
+}
+
+// CheckSession - проверить, что учётная запись существует и не заблокирована, а версия сессий из токена не отозвана.
+// Проверка выполняется на каждый запрос, поэтому состояние учётной записи берётся из кэша, а в очередь задача ставится только при промахе
+func (s *StorageService) CheckSession(ctx context.Context, login string, sessionVersion int) error {
+	state, generation, cached := s.sessions.get(login, time.Now())
+	if !cached {
+		res, err := s.enqueueTask(Task{
+			TaskType:   TaskGet,
+			EntityType: EntityUser,
+			Context:    ctx,
+			Payload:    login,
+		})
+		if err != nil {
+			return err
+		}
+
+		user := res.(*entities.User)
+		if user == nil {
+			return errSessionRevoked
+		}
+
+		state = sessionState{version: user.SessionVersion, disabled: user.Disabled, expiresAt: time.Now().Add(sessionCacheTTL)}
+		s.sessions.put(login, state, generation)
+	}
+
+	switch {
+	case state.version != sessionVersion:
+		return errSessionRevoked
+	case state.disabled:
+		return errAccountDisabled
+	}
+
+	return nil
+}
+
+// sessionCacheTTL - сколько CheckSession доверяет сохранённому состоянию учётной записи. Изменения на этом экземпляре сервера
+// сбрасывают кэш сразу, а блокировка или отзыв сессий на другом экземпляре вступают здесь в силу не позже чем через этот срок
+const sessionCacheTTL = 10 * time.Second
+
+// sessionState - состояние учётной записи, от которого зависит действительность её токенов
+type sessionState struct {
+	version   int
+	disabled  bool
+	expiresAt time.Time
+}
+
+// sessionCache - состояния учётных записей по логину. Нулевое значение готово к использованию
+type sessionCache struct {
+	mu     sync.Mutex
+	states map[string]sessionState
+	// generation - номер сброса: состояние, прочитанное из хранилища до сброса, в кэш уже не попадает
+	generation uint64
+}
+
+// get - состояние учётной записи, если оно сохранено и не устарело к моменту now, и номер сброса для последующего put
+func (c *sessionCache) get(login string, now time.Time) (sessionState, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.states[login]
+	if !ok || !now.Before(state.expiresAt) {
+		return sessionState{}, c.generation, false
+	}
+	return state, c.generation, true
+}
+
+// put - сохранить состояние, прочитанное после get с номером сброса generation (если с тех пор кэш не сбрасывали)
+func (c *sessionCache) put(login string, state sessionState, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if c.states == nil {
+		c.states = make(map[string]sessionState)
+	}
+	c.states[login] = state
+}
+
+// reset - забыть состояния всех учётных записей
+func (c *sessionCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.states = nil
+	c.generation++
+}
+
+// GetAccounts - получить учётные записи с объёмом хранимых данных (только администратор)
+func (s *StorageService) GetAccounts(ctx context.Context) ([]entities.Account, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityAccount,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.Account), nil
+}
+
+// DisableAccount - заблокировать учётную запись и отозвать её сессии (только администратор)
+func (s *StorageService) DisableAccount(ctx context.Context, login string) (*entities.Account, error) {
+	return s.accountTask(ctx, TaskDisable, login)
+}
+
+// EnableAccount - разблокировать учётную запись (только администратор)
+func (s *StorageService) EnableAccount(ctx context.Context, login string) (*entities.Account, error) {
+	return s.accountTask(ctx, TaskEnable, login)
+}
+
+// RevokeSessions - завершить все сессии пользователя: выданные ему токены перестают действовать (только администратор)
+func (s *StorageService) RevokeSessions(ctx context.Context, login string) (*entities.Account, error) {
+	return s.accountTask(ctx, TaskRevoke, login)
+}
+
+// DeleteAccount - удалить учётную запись со всеми данными (только администратор)
+func (s *StorageService) DeleteAccount(ctx context.Context, login string) (*entities.Account, error) {
+	return s.accountTask(ctx, TaskDelete, login)
+}
+
+// DeleteOwnAccount - удалить учётную запись текущего пользователя со всеми данными.
+// Пароль проверяется до вызова: сервис не работает с паролями
+func (s *StorageService) DeleteOwnAccount(ctx context.Context) (*entities.Account, error) {
+	return s.accountTask(ctx, TaskDelete, customcontext.GetUserID(ctx))
+}
+
+// ExportAccount - выгрузить учётную запись текущего пользователя с его записями в зашифрованном виде
+func (s *StorageService) ExportAccount(ctx context.Context) (*entities.AccountExport, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityAccount,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.AccountExport), nil
+}
+
+// CreateInvite - выпустить код приглашения для регистрации (только администратор)
+func (s *StorageService) CreateInvite(ctx context.Context, invite *dtos.NewInvite) (*entities.Invite, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskCreate,
+		EntityType: EntityInvite,
+		Context:    ctx,
+		Payload:    invite,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Invite), nil
+}
+
+// GetInvites - получить все приглашения (только администратор)
+func (s *StorageService) GetInvites(ctx context.Context) ([]entities.Invite, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityInvite,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.Invite), nil
+}
+
+// DeleteInvite - отозвать приглашение (только администратор)
+func (s *StorageService) DeleteInvite(ctx context.Context, code string) (*entities.Invite, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityInvite,
+		Context:    ctx,
+		Payload:    code,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Invite), nil
+}
+
+// GetTrash - получить записи в корзине, которые текущий пользователь может восстановить
+func (s *StorageService) GetTrash(ctx context.Context) ([]entities.TrashItem, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityTrash,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.TrashItem), nil
+}
+
+// RestoreTrashItem - вернуть запись из корзины (nil, если записи в корзине нет)
+func (s *StorageService) RestoreTrashItem(ctx context.Context, entityType EntityType, id string) (*entities.TrashItem, error) {
+	return s.trashTask(ctx, TaskRestore, entityType, id)
+}
+
+// PurgeTrashItem - окончательно удалить запись из корзины (nil, если записи в корзине нет)
+func (s *StorageService) PurgeTrashItem(ctx context.Context, entityType EntityType, id string) (*entities.TrashItem, error) {
+	return s.trashTask(ctx, TaskPurge, entityType, id)
+}
+
+// PurgeExpiredTrash - окончательно удалить записи, пролежавшие в корзине дольше срока хранения к моменту now
+func (s *StorageService) PurgeExpiredTrash(ctx context.Context, now time.Time) ([]entities.TrashItem, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskExpire,
+		EntityType: EntityTrash,
+		Context:    ctx,
+		Payload:    now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.TrashItem), nil
+}
+
+// trashTask - выполнить действие над записью в корзине
+func (s *StorageService) trashTask(ctx context.Context, taskType TaskType, entityType EntityType, id string) (*entities.TrashItem, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   taskType,
+		EntityType: EntityTrash,
+		Context:    ctx,
+		Payload:    trashTarget{entityType: entityType, id: id},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.TrashItem), nil
+}
+
+// GetHistory - версии записи, которые текущий пользователь может расшифровать (сначала последние)
+func (s *StorageService) GetHistory(ctx context.Context, entityType EntityType, id string) ([]entities.Revision, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGetAll,
+		EntityType: EntityHistory,
+		Context:    ctx,
+		Payload:    historyTarget{entityType: entityType, id: id},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]entities.Revision), nil
+}
+
+// RestoreRevision - вернуть запись к версии revision. Текущее состояние записи при этом тоже сохраняется в истории.
+// Возвращает изменённую запись своего типа
+func (s *StorageService) RestoreRevision(ctx context.Context, entityType EntityType, id string, revision int) (interface{}, error) {
+	return s.enqueueTask(Task{
+		TaskType:   TaskRestore,
+		EntityType: EntityHistory,
+		Context:    ctx,
+		Payload:    historyTarget{entityType: entityType, id: id, revision: revision},
+	})
+}
+
+// GetServerStats - получить статистику сервера (только администратор)
+func (s *StorageService) GetServerStats(ctx context.Context) (*entities.ServerStats, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityStats,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.ServerStats), nil
+}
+
+// GetUsage - получить использование хранилища текущим пользователем и действующую для него квоту
+func (s *StorageService) GetUsage(ctx context.Context) (*entities.QuotaUsage, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskGet,
+		EntityType: EntityQuota,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.QuotaUsage), nil
+}
+
+// SetQuota - задать индивидуальную квоту пользователя (только администратор)
+func (s *StorageService) SetQuota(ctx context.Context, login string, quota entities.Quota) (*entities.Account, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskUpdate,
+		EntityType: EntityQuota,
+		Context:    ctx,
+		Payload:    &dtos.QuotaChange{Login: login, Quota: &quota},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Account), nil
+}
+
+// ResetQuota - вернуть пользователю квоту по умолчанию (только администратор)
+func (s *StorageService) ResetQuota(ctx context.Context, login string) (*entities.Account, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   TaskDelete,
+		EntityType: EntityQuota,
+		Context:    ctx,
+		Payload:    login,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Account), nil
+}
+
+// accountTask - выполнить действие над учётной записью
+func (s *StorageService) accountTask(ctx context.Context, taskType TaskType, login string) (*entities.Account, error) {
+	res, err := s.enqueueTask(Task{
+		TaskType:   taskType,
+		EntityType: EntityAccount,
+		Context:    ctx,
+		Payload:    login,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Account), nil
+}
+
+// createUser - создать пользователя (инкапсулирует все проверки и бизнес-логику)
+func (s *StorageService) createUser(ctx context.Context, newUser *dtos.NewUser) (*entities.User, error) {
+	// Проверка наличие пользователя в БД
+	existedUser, err := s.usersRepo.Get(ctx, newUser.Login)
+	if err != nil {
+		return nil, err
+	}
+	if existedUser != nil {
+		return nil, customerrors.AlreadyExistsError
+	}
+
+	if err := s.checkRegistration(ctx, newUser); err != nil {
+		return nil, err
+	}
+
+	user, err := s.usersRepo.Create(ctx, newUser)
+	if err != nil {
+		return nil, err
+	}
+
+	// Приглашение расходуется только созданным пользователем. Если его успели исчерпать, пользователь удаляется
+	// (в транзакции задачи вместе с ним откатилось бы и удаление)
+	if err := s.useInvite(ctx, newUser); err != nil {
+		if _, deleteErr := s.usersRepo.Delete(ctx, newUser.Login); deleteErr != nil {
+			return nil, errors.Join(err, deleteErr)
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// checkRegistration - проверить, разрешена ли регистрация в текущем режиме.
+// В режиме приглашений использование приглашения засчитывает useInvite после создания пользователя
+func (s *StorageService) checkRegistration(ctx context.Context, newUser *dtos.NewUser) error {
+	switch s.registration {
+	case entities.RegistrationClosed:
+		return errRegistrationClosed
+	case entities.RegistrationInvite:
+		if newUser.InviteCode == "" {
+			return errInviteRequired
+		}
+
+		invite, err := s.inviteRepo.Get(ctx, newUser.InviteCode)
+		if err != nil {
+			return err
+		}
+		if invite == nil || !invite.Usable(time.Now()) {
+			return errInvalidInvite
+		}
+
+		if invite.LoginPattern != "" {
+			// Шаблон проверен при выпуске приглашения и должен совпадать с логином целиком
+			if matched, _ := regexp.MatchString("^(?:"+invite.LoginPattern+")$", newUser.Login); !matched {
+				return errInviteLogin
+			}
+		}
+	}
+
+	return nil
+}
+
+// useInvite - засчитать использование приглашения, по которому зарегистрирован пользователь (в режиме приглашений)
+func (s *StorageService) useInvite(ctx context.Context, newUser *dtos.NewUser) error {
+	if s.registration != entities.RegistrationInvite {
+		return nil
+	}
+
+	// Приглашение могли исчерпать параллельно на другом экземпляре сервера
+	used, err := s.inviteRepo.Use(ctx, newUser.InviteCode, time.Now())
+	if err != nil {
+		return err
+	}
+	if used == nil {
+		return errInvalidInvite
+	}
+
+	return nil
+}
+
+// createInvite - выпустить приглашение со случайным кодом
+func (s *StorageService) createInvite(ctx context.Context, dto *dtos.NewInvite) (*entities.Invite, error) {
+	invite := *dto
+	if invite.MaxUses < 0 {
+		return nil, errInviteUses
+	}
+	if invite.MaxUses == 0 {
+		invite.MaxUses = 1
+	}
+
+	if invite.ExpiresAt.IsZero() {
+		invite.ExpiresAt = time.Now().Add(defaultInviteLifetime)
+	} else if !invite.ExpiresAt.After(time.Now()) {
+		return nil, errInviteExpired
+	}
+
+	if invite.LoginPattern != "" {
+		if _, err := regexp.Compile("^(?:" + invite.LoginPattern + ")$"); err != nil {
+			return nil, customerrors.NewHTTPError(fmt.Errorf("invalid login pattern: %w", err), http.StatusBadRequest)
+		}
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate invitation code: %w", err)
+	}
+
+	return s.inviteRepo.Create(ctx, hex.EncodeToString(buf), &invite)
+}
+
+// createUser - создать пользователя (инкапсулирует все проверки и бизнес-логику)
+func (s *StorageService) updateUser(ctx context.Context, user *entities.User) (*entities.User, error) {
+
+	var curUserLogin = customcontext.GetUserID(ctx)
+
+	//Login используется как ключ, его невозможно изменить
+	if curUserLogin != user.Login {
+		return nil, customerrors.ForbiddenError
+	}
+
+	// Блокировку и версию сессий меняет только администратор
+	existing, err := s.usersRepo.Get(ctx, user.Login)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	changed := *user
+	changed.Disabled = existing.Disabled
+	changed.SessionVersion = existing.SessionVersion
+
+	return s.usersRepo.Update(ctx, &changed)
+}
+
+// getAccounts - получить учётные записи (по логину) с количеством и объёмом записей
+func (s *StorageService) getAccounts(ctx context.Context) ([]entities.Account, error) {
+	users, err := s.usersRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.accountRepo.AllUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	quotas, err := s.accountRepo.AllQuotas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]entities.Account, 0, len(users))
+	for _, user := range users {
+		account := entities.Account{
+			Login:          user.Login,
+			Disabled:       user.Disabled,
+			SessionVersion: user.SessionVersion,
+			Usage:          usage[user.Login],
+		}
+		if quota, exists := quotas[user.Login]; exists {
+			account.Quota = &quota
+		}
+		accounts = append(accounts, account)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Login < accounts[j].Login
+	})
+
+	return accounts, nil
+}
+
+// changeAccount - изменить блокировку или версию сессий пользователя
+func (s *StorageService) changeAccount(ctx context.Context, login string, change func(user *entities.User)) (*entities.Account, error) {
+	user, err := s.usersRepo.Get(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errAccountNotFound
+	}
+
+	change(user)
+	if user, err = s.usersRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errAccountNotFound
+	}
+
+	return s.account(ctx, user)
+}
+
+// account - учётная запись пользователя с количеством и объёмом записей и индивидуальной квотой
+func (s *StorageService) account(ctx context.Context, user *entities.User) (*entities.Account, error) {
+	usage, err := s.accountRepo.Usage(ctx, user.Login)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := s.accountRepo.GetQuota(ctx, user.Login)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.Account{Login: user.Login, Disabled: user.Disabled, SessionVersion: user.SessionVersion, Usage: *usage, Quota: quota}, nil
+}
+
+// deleteAccount - удалить учётную запись со всеми данными. Возвращается состояние учётной записи перед удалением
+func (s *StorageService) deleteAccount(ctx context.Context, login string) (*entities.Account, error) {
+	usage, err := s.accountRepo.Usage(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.accountRepo.Delete(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errAccountNotFound
+	}
+
+	return &entities.Account{Login: user.Login, Disabled: user.Disabled, SessionVersion: user.SessionVersion, Usage: *usage}, nil
+}
+
+// exportAccount - собрать выгрузку учётной записи: записи, которыми с пользователем поделились, в неё не входят
+func (s *StorageService) exportAccount(ctx context.Context, login string) (*entities.AccountExport, error) {
+	user, err := s.usersRepo.Get(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errAccountNotFound
+	}
+
+	account, err := s.account(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &entities.AccountExport{Account: *account, ExportedAt: time.Now().UTC()}
+
+	if s.userKeysRepo != nil {
+		if export.Keys, err = s.userKeysRepo.Get(ctx, login); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.shareRepo != nil {
+		if export.Shares, err = s.shareRepo.GetAll(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	binaries, err := s.binariesRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	export.Binaries = ownedEntries(binaries, login, func(entry *entities.BinaryData) string { return entry.OwnerID })
+
+	cards, err := s.cardsRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	export.Cards = ownedEntries(cards, login, func(entry *entities.CardInformation) string { return entry.OwnerID })
+
+	credentials, err := s.credentialsRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	export.Credentials = ownedEntries(credentials, login, func(entry *entities.Credentials) string { return entry.OwnerID })
+
+	texts, err := s.textsRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	export.Texts = ownedEntries(texts, login, func(entry *entities.TextData) string { return entry.OwnerID })
+
+	return export, nil
+}
+
+// ownedEntries - записи, владельцем которых является пользователь (без записей, к которым ему открыт доступ)
+func ownedEntries[T any](entries []T, login string, owner func(entry *T) string) []T {
+	owned := make([]T, 0, len(entries))
+	for i := range entries {
+		if owner(&entries[i]) == login {
+			owned = append(owned, entries[i])
+		}
+	}
+	return owned
+}
+
+// personalEntries - личные записи пользователя: он владелец и запись не лежит в коллекции организации
+func personalEntries[T any](entries []T, login string, entity func(entry *T) *entities.SecureEntity) []T {
+	personal := make([]T, 0, len(entries))
+	for i := range entries {
+		if e := entity(&entries[i]); e.OwnerID == login && e.CollectionID == "" {
+			personal = append(personal, entries[i])
+		}
+	}
+	return personal
+}
+
+// setQuota - задать индивидуальную квоту пользователя (nil - вернуть квоту по умолчанию)
+func (s *StorageService) setQuota(ctx context.Context, login string, quota *entities.Quota) (*entities.Account, error) {
+	if quota != nil && (quota.MaxEntries < 0 || quota.MaxBytes < 0) {
+		return nil, errInvalidQuota
+	}
+
+	user, err := s.usersRepo.Get(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errAccountNotFound
+	}
+
+	if err := s.accountRepo.SetQuota(ctx, login, quota); err != nil {
+		return nil, err
+	}
+
+	return s.account(ctx, user)
+}
+
+// getQuotaUsage - использование хранилища пользователем и действующая для него квота
+func (s *StorageService) getQuotaUsage(ctx context.Context, login string) (*entities.QuotaUsage, error) {
+	usage, err := s.accountRepo.Usage(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := s.effectiveQuota(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.QuotaUsage{Usage: *usage, Quota: quota}, nil
+}
+
+// quotaEnabled - ограничен ли объём хранилища пользователей
+func (s *StorageService) quotaEnabled() bool {
+	return s.quota != nil && s.accountRepo != nil
+}
+
+// effectiveQuota - квота пользователя: индивидуальная, если задана, иначе квота по умолчанию
+func (s *StorageService) effectiveQuota(ctx context.Context, login string) (entities.Quota, error) {
+	quota, err := s.accountRepo.GetQuota(ctx, login)
+	if err != nil {
+		return entities.Quota{}, err
+	}
+	if quota != nil {
+		return *quota, nil
+	}
+	if s.quota != nil {
+		return *s.quota, nil
+	}
+
+	return entities.Quota{}, nil
+}
+
+// checkQuota - проверить, что операция не выведет пользователя за пределы квоты.
+// added - сколько записей типа entityType добавляет операция, delta - на сколько байт меняется объём хранилища
+func (s *StorageService) checkQuota(ctx context.Context, owner string, entityType EntityType, added int, delta int64) error {
+	if !s.quotaEnabled() || (added <= 0 && delta <= 0) {
+		return nil
+	}
+
+	quota, err := s.effectiveQuota(ctx, owner)
+	if err != nil {
+		return err
+	}
+
+	usage, err := s.accountRepo.Usage(ctx, owner)
+	if err != nil {
+		return err
+	}
+
+	if resource, count := entryCount(usage, entityType); added > 0 && quota.MaxEntries > 0 && count+added > quota.MaxEntries {
+		return customerrors.NewQuotaError(resource, int64(quota.MaxEntries), int64(count), int64(added))
+	}
+
+	if delta > 0 && quota.MaxBytes > 0 && usage.Bytes+delta > quota.MaxBytes {
+		return customerrors.NewQuotaError("bytes", quota.MaxBytes, usage.Bytes, delta)
+	}
+
+	return nil
+}
+
+// entryCount - количество записей типа entityType и название этого ресурса в квоте
+func entryCount(usage *entities.StorageUsage, entityType EntityType) (resource string, count int) {
+	switch entityType {
+	case EntityBinary:
+		return "binaries", usage.Binaries
+	case EntityCard:
+		return "cards", usage.Cards
+	case EntityCredentials:
+		return "credentials", usage.Credentials
+	case EntityText:
+		return "texts", usage.Texts
+	default:
+		return entityType.String(), 0
+	}
+}
+
+// storedSize - объём записи или DTO типа entityType в хранилище: в тех же единицах accountRepo считает использование.
+// Репозиторий, который хранит поля не в открытом виде (например, зашифрованными), сообщает объём сам
+func (s *StorageService) storedSize(entityType EntityType, entry interface{}) int64 {
+	var repo interface{}
+	switch entityType {
+	case EntityBinary:
+		repo = s.binariesRepo
+	case EntityCard:
+		repo = s.cardsRepo
+	case EntityCredentials:
+		repo = s.credentialsRepo
+	case EntityText:
+		repo = s.textsRepo
+	}
+
+	if stored, ok := repo.(interface{ StoredSize(entry interface{}) int64 }); ok {
+		return stored.StoredSize(entry)
+	}
+	if sized, ok := entry.(interface{ Size() int64 }); ok {
+		return sized.Size()
+	}
+	return 0
+}
+
+// getUserKeys - получить ключи пользователя, скрыв закрытый ключ от остальных пользователей
+func (s *StorageService) getUserKeys(ctx context.Context, login string) (*entities.UserKeys, error) {
+	keys, err := s.userKeysRepo.Get(ctx, login)
+	if err != nil || keys == nil {
+		return keys, err
+	}
+
+	if login != customcontext.GetUserID(ctx) {
+		keys.EncryptedPrivateKey = ""
+	}
+
+	return keys, nil
+}
+
+// createShare - поделиться записью (получатель должен опубликовать открытый ключ: им зашифрован ключ записи)
+func (s *StorageService) createShare(ctx context.Context, grant *dtos.NewShareGrant) (*entities.ShareGrant, error) {
+	if grant.RecipientID == customcontext.GetUserID(ctx) {
+		return nil, customerrors.NewHTTPError(errors.New("cannot share entry with yourself"), http.StatusBadRequest)
+	}
+
+	if !isEntryID(grant.EntityID) {
+		return nil, nil
+	}
+
+	access, err := s.accessRepo.EntryAccess(ctx, grant.EntityType, grant.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Entry(customcontext.GetUserID(ctx), access, authz.ActionShare); err != nil {
+		if errors.Is(err, authz.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	recipientKeys, err := s.userKeysRepo.Get(ctx, grant.RecipientID)
+	if err != nil {
+		return nil, err
+	}
+	if recipientKeys == nil {
+		return nil, customerrors.NewNotFoundError(errors.New("recipient not found or has no public key"))
+	}
+
+	return s.shareRepo.Create(ctx, grant)
+}
+
+// authorizeEntry - проверить по политике доступа право текущего пользователя на действие над записью.
+// Если запись пользователю не видна, возвращается nil без ошибки
+func (s *StorageService) authorizeEntry(ctx context.Context, entityType EntityType, id string, action authz.Action) (*entities.EntryAccess, error) {
+	if !isEntryID(id) {
+		return nil, nil
+	}
+
+	access, err := s.accessRepo.EntryAccess(ctx, entityType.String(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.policy.Entry(customcontext.GetUserID(ctx), access, action); err != nil {
+		if errors.Is(err, authz.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return access, nil
+}
+
+// updateEntry - изменить запись, если политика доступа это разрешает и квота владельца записи позволяет её новый объём
+// (nil, если запись пользователю не видна)
+func updateEntry[T any](s *StorageService, ctx context.Context, entityType EntityType, entity *T, secure *entities.SecureEntity,
+	get func(context.Context, string) (*T, error), update func(context.Context, *T) (*T, error)) (*T, error) {
+	access, err := s.authorizeEntry(ctx, entityType, secure.ID, authz.ActionUpdate)
+	if err != nil || access == nil {
+		return nil, err
+	}
+
+	// Ключ записи меняет только тот, кто вправе ею делиться; прочие изменения его сохраняют
+	if s.policy.Entry(customcontext.GetUserID(ctx), access, authz.ActionShare) != nil {
+		secure.EntryKey = ""
+	}
+
+	// Запись коллекции должна быть зашифрована текущим ключом коллекции
+	if access.CollectionID != "" {
+		collection, err := s.getCollection(ctx, access.CollectionID)
+		if err != nil {
+			return nil, err
+		}
+		if secure.KeyVersion != collection.KeyVersion {
+			return nil, errOutdatedCollectionKey
+		}
+	}
+
+	// Объём записи учитывается в квоте её владельца, даже если запись изменяет получатель или участник организации
+	if s.quotaEnabled() {
+		current, err := get(ctx, secure.ID)
+		if err != nil || current == nil {
+			return nil, err
+		}
+
+		if err := s.checkQuota(ctx, access.OwnerID, entityType, 0, s.storedSize(entityType, entity)-s.storedSize(entityType, current)); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.historyRepo == nil {
+		return update(ctx, entity)
+	}
+
+	// Прежнее состояние попадает в историю, только если запись удалось изменить
+	var updated *T
+	err = s.historyRepo.Append(ctx, entityType.String(), secure.ID, s.historyMaxVersions, s.historyNotBefore(time.Now()),
+		func(ctx context.Context) error {
+			var err error
+			updated, err = update(ctx, entity)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// deleteEntry - удалить запись, если политика доступа это разрешает (nil, если запись пользователю не видна)
+func deleteEntry[T any](s *StorageService, ctx context.Context, entityType EntityType, id string,
+	del func(context.Context, string) (*T, error)) (*T, error) {
+	access, err := s.authorizeEntry(ctx, entityType, id, authz.ActionDelete)
+	if err != nil || access == nil {
+		return nil, err
+	}
+
+	return del(ctx, id)
+}
+
+// assignEntryID - проверить идентификатор, выбранный клиентом для новой записи, или выдать новый (UUIDv7), если клиент его не выбрал.
+// Идентификатор не должен быть занят другой записью того же типа, в том числе чужой или лежащей в корзине
+func (s *StorageService) assignEntryID(ctx context.Context, entityType EntityType, dto *dtos.NewSecureEntity) error {
+	if dto.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("failed to generate entry id: %w", err)
+		}
+		dto.ID = id.String()
+		return nil
+	}
+
+	id, err := uuid.Parse(dto.ID)
+	if err != nil {
+		return errInvalidEntryID
+	}
+	dto.ID = id.String()
+
+	exists, err := s.accessRepo.EntryExists(ctx, entityType.String(), dto.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errEntryIDTaken
+	}
+
+	return nil
+}
+
+// isEntryID - похож ли идентификатор на идентификатор записи. Записи с другим идентификатором заведомо нет,
+// а postgres отклонил бы такой запрос по столбцу uuid
+func isEntryID(id string) bool {
+	return uuid.Validate(id) == nil
+}
+
+// authorizeCreateEntry - проверить право создать запись. Личную запись может создать любой пользователь,
+// запись коллекции - участник организации с подходящей ролью, зашифровав её текущим ключом коллекции
+func (s *StorageService) authorizeCreateEntry(ctx context.Context, dto *dtos.NewSecureEntity) error {
+	if dto.CollectionID == "" {
+		return nil
+	}
+
+	if s.orgRepo == nil {
+		return customerrors.NewNotImplementedError(errors.New("organizations are disabled"))
+	}
+
+	collection, err := s.getCollection(ctx, dto.CollectionID)
+	if err != nil {
+		return err
+	}
+
+	role, err := s.orgRole(ctx, collection.OrgID)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Organization(role, authz.ActionCreateEntry); err != nil {
+		return err
+	}
+
+	if dto.KeyVersion != collection.KeyVersion {
+		return errOutdatedCollectionKey
+	}
+
+	return nil
+}
+
+// getCollection - получить коллекцию (404, если её нет)
+func (s *StorageService) getCollection(ctx context.Context, id string) (*entities.Collection, error) {
+	collection, err := s.orgRepo.GetCollection(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if collection == nil {
+		return nil, customerrors.NewNotFoundError(errors.New("collection not found"))
+	}
+
+	return collection, nil
+}
+
+// orgRole - роль текущего пользователя в организации (пустая, если он не участник или ещё не принял приглашение)
+func (s *StorageService) orgRole(ctx context.Context, orgID string) (string, error) {
+	member, err := s.orgRepo.GetMember(ctx, orgID, customcontext.GetUserID(ctx))
+	if err != nil || member == nil || member.Status != entities.MemberStatusActive {
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+// getOrgMembers - получить участников организации (только для её участников)
+func (s *StorageService) getOrgMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error) {
+	role, err := s.orgRole(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Organization(role, authz.ActionViewMembers); err != nil {
+		return nil, err
+	}
+
+	return s.orgRepo.GetMembers(ctx, orgID)
+}
+
+// inviteMember - пригласить пользователя в организацию. Приглашённый должен опубликовать открытый ключ:
+// им зашифрованы текущие ключи всех коллекций организации
+func (s *StorageService) inviteMember(ctx context.Context, dto *dtos.NewOrgMember) (*entities.OrgMember, error) {
+	role, err := s.orgRole(ctx, dto.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.AssignRole(role, dto.Role); err != nil {
+		return nil, err
+	}
+
+	if s.userKeysRepo == nil {
+		return nil, customerrors.NewNotImplementedError(errors.New("sharing is disabled"))
+	}
+	inviteeKeys, err := s.userKeysRepo.Get(ctx, dto.Login)
+	if err != nil {
+		return nil, err
+	}
+	if inviteeKeys == nil {
+		return nil, customerrors.NewNotFoundError(errors.New("invitee not found or has no public key"))
+	}
+
+	collections, err := s.orgRepo.GetCollections(ctx, dto.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]int, len(collections))
+	for _, collection := range collections {
+		versions[collection.ID] = collection.KeyVersion
+	}
+	if err := checkCollectionKeys(dto.Keys, versions, []string{dto.Login}); err != nil {
+		return nil, err
+	}
+
+	member, err := s.orgRepo.AddMember(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, customerrors.NewAlreadyExistsError(errors.New("user is already a member of the organization"))
+	}
+
+	return member, nil
+}
+
+// removeMember - исключить участника из организации (или выйти из неё самому).
+// Исключённый мог сохранить ключи коллекций, поэтому их нужно заменить - коллекции отмечаются для замены ключа
+func (s *StorageService) removeMember(ctx context.Context, orgID, login string) (*entities.OrgMember, error) {
+	role, err := s.orgRole(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.orgRepo.GetMember(ctx, orgID, login)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.RemoveMember(customcontext.GetUserID(ctx), role, target); err != nil {
+		return nil, err
+	}
+
+	return s.orgRepo.RemoveMember(ctx, orgID, login)
+}
+
+// createCollection - создать коллекцию; её ключ должен быть зашифрован для каждого участника организации
+func (s *StorageService) createCollection(ctx context.Context, dto *dtos.NewCollection) (*entities.Collection, error) {
+	logins, err := s.manageCollections(ctx, dto.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Идентификатор коллекции назначит хранилище
+	for i := range dto.Keys {
+		dto.Keys[i].CollectionID = ""
+	}
+	if err := checkCollectionKeys(dto.Keys, map[string]int{"": 1}, logins); err != nil {
+		return nil, err
+	}
+
+	collection, err := s.orgRepo.CreateCollection(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+	if collection == nil {
+		return nil, customerrors.NewAlreadyExistsError(errors.New("collection with this name already exists"))
+	}
+
+	return collection, nil
+}
+
+// getCollections - получить коллекции организации (только для её участников)
+func (s *StorageService) getCollections(ctx context.Context, orgID string) ([]entities.Collection, error) {
+	role, err := s.orgRole(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Organization(role, authz.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return s.orgRepo.GetCollections(ctx, orgID)
+}
+
+// rotateCollectionKey - заменить ключ коллекции следующей версией, зашифрованной для каждого участника.
+// Записи коллекции после этого перешифровывает клиент: изменения записей со старой версией ключа отклоняются
+func (s *StorageService) rotateCollectionKey(ctx context.Context, rotation *dtos.CollectionRotation) (*entities.Collection, error) {
+	collection, err := s.getCollection(ctx, rotation.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	logins, err := s.manageCollections(ctx, collection.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotation.Version != collection.KeyVersion+1 {
+		return nil, errOutdatedCollectionKey
+	}
+	if err := checkCollectionKeys(rotation.Keys, map[string]int{collection.ID: rotation.Version}, logins); err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.orgRepo.RotateCollectionKey(ctx, rotation)
+	if err != nil {
+		return nil, err
+	}
+	if rotated == nil {
+		return nil, errOutdatedCollectionKey
+	}
+
+	return rotated, nil
+}
+
+// manageCollections - проверить право управлять коллекциями организации и получить логины её участников
+func (s *StorageService) manageCollections(ctx context.Context, orgID string) ([]string, error) {
+	role, err := s.orgRole(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Organization(role, authz.ActionManageCollections); err != nil {
+		return nil, err
+	}
+
+	members, err := s.orgRepo.GetMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	logins := make([]string, 0, len(members))
+	for _, member := range members {
+		logins = append(logins, member.Login)
+	}
+
+	return logins, nil
+}
+
+// checkCollectionKeys - ключи должны быть выданы ровно по одному на каждую пару коллекции и участника
+// (versions - версия ключа каждой коллекции)
+func checkCollectionKeys(keys []entities.CollectionKey, versions map[string]int, logins []string) error {
+	expected := make(map[entities.CollectionKey]bool, len(versions)*len(logins))
+	for collectionID, version := range versions {
+		for _, login := range logins {
+			expected[entities.CollectionKey{CollectionID: collectionID, Login: login, Version: version}] = true
+		}
+	}
+
+	if len(keys) != len(expected) {
+		return errCollectionKeys
+	}
+
+	for _, key := range keys {
+		ref := entities.CollectionKey{CollectionID: key.CollectionID, Login: key.Login, Version: key.Version}
+		if !expected[ref] || key.EncryptedKey == "" {
+			return errCollectionKeys
+		}
+		delete(expected, ref)
+	}
+
+	return nil
+}
+
+// inviteEmergencyContact - назначить доверенное лицо. Оно должно опубликовать открытый ключ:
+// им зашифрован ключ хранилища доверителя
+func (s *StorageService) inviteEmergencyContact(ctx context.Context, dto *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error) {
+	userID := customcontext.GetUserID(ctx)
+	if dto.GranteeID == userID {
+		return nil, errEmergencyContactSelf
+	}
+	if dto.WaitDays < 1 || dto.WaitDays > maxEmergencyWaitDays {
+		return nil, errEmergencyWaitDays
+	}
+	if dto.EncryptedKey == "" {
+		return nil, errEmergencyKey
+	}
+
+	granteeKeys, err := s.userKeysRepo.Get(ctx, dto.GranteeID)
+	if err != nil {
+		return nil, err
+	}
+	if granteeKeys == nil {
+		return nil, customerrors.NewNotFoundError(errors.New("emergency contact not found or has no public key"))
+	}
+
+	access, err := s.emergencyRepo.Create(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+	if access == nil {
+		return nil, errEmergencyExists
+	}
+
+	return hideEmergencyKey(userID, access), nil
+}
+
+// getEmergencyAccess - получить экстренные доступы текущего пользователя
+func (s *StorageService) getEmergencyAccess(ctx context.Context) ([]entities.EmergencyAccess, error) {
+	accesses, err := s.emergencyRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := customcontext.GetUserID(ctx)
+	for i := range accesses {
+		hideEmergencyKey(userID, &accesses[i])
+	}
+
+	return accesses, nil
+}
+
+// moveEmergencyAccess - перевести экстренный доступ в следующее состояние, если политика доступа это разрешает
+func (s *StorageService) moveEmergencyAccess(ctx context.Context, id string, action authz.Action) (*entities.EmergencyAccess, error) {
+	userID := customcontext.GetUserID(ctx)
+
+	access, err := s.emergencyRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.policy.Emergency(userID, access, action)
+	if err != nil {
+		return nil, err
+	}
+
+	// Состояние могло измениться с момента чтения (например, истекло ожидание)
+	moved, err := s.emergencyRepo.SetStatus(ctx, id, access.Status, to)
+	if err != nil {
+		return nil, err
+	}
+	if moved == nil {
+		return nil, errEmergencyChanged
+	}
+
+	return hideEmergencyKey(userID, moved), nil
 }
 
-// GetAllCards - получить все данные банковской карты
-func (s *StorageService) GetAllCards(ctx context.Context) ([]entities.CardInformation, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGetAll,
-		EntityType: EntityCard,
-		Context:    ctx,
-	})
-	return res.([]entities.CardInformation), err
-}
+// deleteEmergencyAccess - отменить экстренный доступ (доверитель или доверенное лицо)
+func (s *StorageService) deleteEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	access, err := s.emergencyRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-// UpdateCard - изменить данные банковской карты
-func (s *StorageService) UpdateCard(ctx context.Context, card *entities.CardInformation) (*entities.CardInformation, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskUpdate,
-		EntityType: EntityCard,
-		Context:    ctx,
-		Payload:    card,
-	})
-	return res.(*entities.CardInformation), err
-}
+	if _, err := s.policy.Emergency(customcontext.GetUserID(ctx), access, authz.ActionDelete); err != nil {
+		return nil, err
+	}
 
-// DeleteCard - удалить данные банковской карты
-func (s *StorageService) DeleteCard(ctx context.Context, id string) (*entities.CardInformation, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskDelete,
-		EntityType: EntityCard,
-		Context:    ctx,
-		Payload:    id,
-	})
-	return res.(*entities.CardInformation), err
-}
+	deleted, err := s.emergencyRepo.Delete(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-// CreateCredentials - создать учётные данные
-func (s *StorageService) CreateCredentials(ctx context.Context, newCreds *dtos.NewCredentials) (*entities.Credentials, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskCreate,
-		EntityType: EntityCredentials,
-		Context:    ctx,
-		Payload:    newCreds,
-	})
-	return res.(*entities.Credentials), err
+	return hideEmergencyKey(customcontext.GetUserID(ctx), deleted), nil
 }
 
-// GetCredentials - получить учётные данные
-func (s *StorageService) GetCredentials(ctx context.Context, id string) (*entities.Credentials, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGet,
-		EntityType: EntityCredentials,
-		Context:    ctx,
-		Payload:    id,
-	})
-	return res.(*entities.Credentials), err
-}
+// openEmergencyVault - получить личные записи доверителя, ключ его хранилища и закрытый ключ
+func (s *StorageService) openEmergencyVault(ctx context.Context, id string) (*entities.EmergencyVault, error) {
+	access, err := s.emergencyRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-// GetAllCredentials - получить все учётные данные
-func (s *StorageService) GetAllCredentials(ctx context.Context) ([]entities.Credentials, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGetAll,
-		EntityType: EntityCredentials,
-		Context:    ctx,
-	})
-	return res.([]entities.Credentials), err
-}
+	if _, err := s.policy.Emergency(customcontext.GetUserID(ctx), access, authz.ActionEmergencyView); err != nil {
+		return nil, err
+	}
 
-// UpdateCredentials - изменить учётные данные
-func (s *StorageService) UpdateCredentials(ctx context.Context, creds *entities.Credentials) (*entities.Credentials, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskUpdate,
-		EntityType: EntityCredentials,
-		Context:    ctx,
-		Payload:    creds,
-	})
-	return res.(*entities.Credentials), err
-}
+	vault := &entities.EmergencyVault{Access: *access}
 
-// DeleteCredentials - удалить учётные данные
-func (s *StorageService) DeleteCredentials(ctx context.Context, id string) (*entities.Credentials, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskDelete,
-		EntityType: EntityCredentials,
-		Context:    ctx,
-		Payload:    id,
-	})
-	return res.(*entities.Credentials), err
+	grantorKeys, err := s.userKeysRepo.Get(ctx, access.GrantorID)
+	if err != nil {
+		return nil, err
+	}
+	if grantorKeys != nil {
+		vault.EncryptedPrivateKey = grantorKeys.EncryptedPrivateKey
+	}
+
+	// Доверитель вправе передать только свои записи: чужие, которыми с ним поделились,
+	// и записи коллекций организаций в хранилище не входят
+	grantorCtx := customcontext.WithUserID(ctx, access.GrantorID)
+	binaries, err := s.binariesRepo.GetAll(grantorCtx)
+	if err != nil {
+		return nil, err
+	}
+	vault.Binaries = personalEntries(binaries, access.GrantorID, func(entry *entities.BinaryData) *entities.SecureEntity { return &entry.SecureEntity })
+
+	cards, err := s.cardsRepo.GetAll(grantorCtx)
+	if err != nil {
+		return nil, err
+	}
+	vault.Cards = personalEntries(cards, access.GrantorID, func(entry *entities.CardInformation) *entities.SecureEntity { return &entry.SecureEntity })
+
+	credentials, err := s.credentialsRepo.GetAll(grantorCtx)
+	if err != nil {
+		return nil, err
+	}
+	vault.Credentials = personalEntries(credentials, access.GrantorID, func(entry *entities.Credentials) *entities.SecureEntity { return &entry.SecureEntity })
+
+	texts, err := s.textsRepo.GetAll(grantorCtx)
+	if err != nil {
+		return nil, err
+	}
+	vault.Texts = personalEntries(texts, access.GrantorID, func(entry *entities.TextData) *entities.SecureEntity { return &entry.SecureEntity })
+
+	return vault, nil
 }
 
-// CreateText - создать текстовые данные
-func (s *StorageService) CreateText(ctx context.Context, newText *dtos.NewTextData) (*entities.TextData, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskCreate,
-		EntityType: EntityText,
-		Context:    ctx,
-		Payload:    newText,
-	})
-	return res.(*entities.TextData), err
+// grantElapsedEmergencyAccess - предоставить доступ по запросам с истёкшим периодом ожидания.
+// Событие записывается в журнал аудита обеих сторон: доверитель должен узнать, что его хранилище открыто
+func (s *StorageService) grantElapsedEmergencyAccess(ctx context.Context, now time.Time) ([]entities.EmergencyAccess, error) {
+	granted, err := s.emergencyRepo.GrantElapsed(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range granted {
+		if s.auditRepo != nil {
+			for _, userID := range []string{granted[i].GrantorID, granted[i].GranteeID} {
+				event := entities.AuditEvent{
+					UserID:     userID,
+					Action:     TaskExpire.String(),
+					EntityType: EntityEmergency.String(),
+					EntityID:   granted[i].ID,
+				}
+				if err := s.auditRepo.Append(ctx, &event); err != nil {
+					log.Printf("failed to record audit event: %v", err)
+				}
+			}
+		}
+
+		granted[i].EncryptedKey = ""
+	}
+
+	return granted, nil
 }
 
-// GetText - получить текстовые данные
-func (s *StorageService) GetText(ctx context.Context, id string) (*entities.TextData, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGet,
-		EntityType: EntityText,
-		Context:    ctx,
-		Payload:    id,
-	})
-	return res.(*entities.TextData), err
+// getTrash - записи в корзине, которые пользователь вправе удалить (а значит и восстановить)
+func (s *StorageService) getTrash(ctx context.Context) ([]entities.TrashItem, error) {
+	items, err := s.trashRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := customcontext.GetUserID(ctx)
+	allowed := make([]entities.TrashItem, 0, len(items))
+	for _, item := range items {
+		if s.policy.Entry(userID, item.Access(), authz.ActionDelete) == nil {
+			allowed = append(allowed, item)
+		}
+	}
+
+	return allowed, nil
 }
 
-// GetAllTexts - получить все текстовые данные
-func (s *StorageService) GetAllTexts(ctx context.Context) ([]entities.TextData, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskGetAll,
-		EntityType: EntityText,
-		Context:    ctx,
-	})
-	return res.([]entities.TextData), err
+// changeTrashItem - восстановить или окончательно удалить запись в корзине, если пользователь был вправе её удалить
+// (nil, если запись в корзине пользователю не видна)
+func (s *StorageService) changeTrashItem(ctx context.Context, target trashTarget,
+	change func(context.Context, string, string) (*entities.TrashItem, error)) (*entities.TrashItem, error) {
+	switch target.entityType {
+	case EntityBinary, EntityCard, EntityCredentials, EntityText:
+	default:
+		return nil, customerrors.NewHTTPError(errors.New("entity type has no trash"), http.StatusBadRequest)
+	}
+
+	if !isEntryID(target.id) {
+		return nil, nil
+	}
+
+	item, err := s.trashRepo.Get(ctx, target.entityType.String(), target.id)
+	if err != nil || item == nil {
+		return nil, err
+	}
+
+	if err := s.policy.Entry(customcontext.GetUserID(ctx), item.Access(), authz.ActionDelete); err != nil {
+		if errors.Is(err, authz.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return change(ctx, target.entityType.String(), target.id)
 }
 
-// UpdateText - изменить текстовые данные
-func (s *StorageService) UpdateText(ctx context.Context, text *entities.TextData) (*entities.TextData, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskUpdate,
-		EntityType: EntityText,
-		Context:    ctx,
-		Payload:    text,
-	})
-	return res.(*entities.TextData), err
+// purgeTrash - окончательно удалить записи, пролежавшие в корзине дольше срока хранения.
+// Событие записывается в журнал аудита владельца каждой записи
+func (s *StorageService) purgeTrash(ctx context.Context, now time.Time) ([]entities.TrashItem, error) {
+	purged, err := s.trashRepo.Purge(ctx, now.Add(-s.trashRetention))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.auditRepo != nil {
+		for _, item := range purged {
+			event := entities.AuditEvent{
+				UserID:     item.OwnerID,
+				Action:     TaskPurge.String(),
+				EntityType: item.EntityType,
+				EntityID:   item.ID,
+			}
+			if err := s.auditRepo.Append(ctx, &event); err != nil {
+				log.Printf("failed to record audit event: %v", err)
+			}
+		}
+	}
+
+	return purged, nil
 }
 
-// DeleteText - удалить текстовые данные
-func (s *StorageService) DeleteText(ctx context.Context, id string) (*entities.TextData, error) {
-	res, err := s.enqueueTask(Task{
-		TaskType:   TaskDelete,
-		EntityType: EntityText,
-		Context:    ctx,
-		Payload:    id,
-	})
-	return res.(*entities.TextData), err
+// getHistory - версии записи, если пользователю разрешено её читать (404, если запись ему не видна)
+func (s *StorageService) getHistory(ctx context.Context, target historyTarget) ([]entities.Revision, error) {
+	access, err := s.authorizeEntry(ctx, target.entityType, target.id, authz.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+	if access == nil {
+		return nil, errEntryNotFound
+	}
+
+	revisions, err := s.historyRepo.GetAll(ctx, target.entityType.String(), target.id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Устаревшие версии удаляются только при следующем изменении записи, но отдавать их уже не нужно
+	notBefore := s.historyNotBefore(time.Now())
+	result := make([]entities.Revision, 0, len(revisions))
+	for _, revision := range revisions {
+		if !revision.ReplacedAt.Before(notBefore) {
+			result = append(result, revision)
+		}
+	}
+
+	return result, nil
 }
 
-// createUser - создать пользователя (инкапсулирует все проверки и бизнес-логику)
-func (s *StorageService) createUser(ctx context.Context, newUser *dtos.NewUser) (*entities.User, error) {
-	// Проверка наличие пользователя в БД
-	existedUser, err := s.usersRepo.Get(ctx, newUser.Login)
+// restoreRevision - вернуть запись к сохранённой версии обычным изменением записи (404, если запись пользователю не видна).
+// Сервер не может перешифровать версию, поэтому восстанавливаются только версии, зашифрованные текущим ключом записи
+func restoreRevision[T any](s *StorageService, ctx context.Context, target historyTarget, entity *T, secure *entities.SecureEntity,
+	get func(context.Context, string) (*T, error), update func(context.Context, *T) (*T, error)) (*T, error) {
+	access, err := s.authorizeEntry(ctx, target.entityType, target.id, authz.ActionUpdate)
 	if err != nil {
 		return nil, err
 	}
-	if existedUser != nil {
-		return nil, customerrors.AlreadyExistsError
+	if access == nil {
+		return nil, errEntryNotFound
 	}
 
-	user, err := s.usersRepo.Create(ctx, newUser)
+	revision, err := s.historyRepo.Get(ctx, target.entityType.String(), target.id, target.revision)
 	if err != nil {
 		return nil, err
 	}
+	if revision == nil || revision.ReplacedAt.Before(s.historyNotBefore(time.Now())) {
+		return nil, errRevisionNotFound
+	}
+	if !revision.Restorable {
+		return nil, errRevisionKeyChanged
+	}
 
-	return user, nil
+	if err := json.Unmarshal(revision.Content, entity); err != nil {
+		return nil, fmt.Errorf("failed to decode revision: %w", err)
+	}
+	// Ключ записи не меняется: версия зашифрована текущим ключом
+	*secure = entities.SecureEntity{ID: target.id, Metadata: revision.Metadata, KeyVersion: revision.KeyVersion}
+
+	return updateEntry(s, ctx, target.entityType, entity, secure, get, update)
 }
 
-// createUser - создать пользователя (инкапсулирует все проверки и бизнес-логику)
-func (s *StorageService) updateUser(ctx context.Context, user *entities.User) (*entities.User, error) {
+// historyNotBefore - время, раньше которого заменённые версии уже не хранятся (нулевое время - хранятся без ограничения)
+func (s *StorageService) historyNotBefore(now time.Time) time.Time {
+	if s.historyMaxAge <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-s.historyMaxAge)
+}
 
-	var curUserLogin = customcontext.GetUserID(ctx)
+// hideEmergencyKey - скрыть ключ хранилища доверителя, если пользователю он не положен
+// (ключ получает только доверенное лицо и только после предоставления доступа)
+func hideEmergencyKey(userID string, access *entities.EmergencyAccess) *entities.EmergencyAccess {
+	if access != nil && (access.GranteeID != userID || access.Status != entities.EmergencyStatusGranted) {
+		access.EncryptedKey = ""
+	}
 
-	//Login используется как ключ, его невозможно изменить
-	if curUserLogin != user.Login {
-		return nil, customerrors.ForbiddenError
+	return access
+}
+
+// emergencyTimer - фоновая задача: периодически предоставлять доступ по запросам с истёкшим периодом ожидания
+func (s *StorageService) emergencyTimer() {
+	defer s.backgroundTasks.Done()
+
+	ticker := time.NewTicker(s.emergencyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopBackground:
+			return
+		case now := <-ticker.C:
+			granted, err := s.GrantElapsedEmergencyAccess(context.Background(), now)
+			if err != nil {
+				if !errors.Is(err, customerrors.ServiceUnavailableError) {
+					log.Printf("failed to grant elapsed emergency access: %v", err)
+				}
+				continue
+			}
+			for _, access := range granted {
+				log.Printf("emergency access %s to vault of %s granted to %s after waiting period", access.ID, access.GrantorID, access.GranteeID)
+			}
+		}
 	}
+}
 
-	user, err := s.usersRepo.Update(ctx, user)
-	if err != nil {
-		return nil, err
+// trashTimer - фоновая задача: периодически удалять записи, срок хранения которых в корзине истёк
+func (s *StorageService) trashTimer() {
+	defer s.backgroundTasks.Done()
+
+	ticker := time.NewTicker(s.trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopBackground:
+			return
+		case now := <-ticker.C:
+			purged, err := s.PurgeExpiredTrash(context.Background(), now)
+			if err != nil {
+				if !errors.Is(err, customerrors.ServiceUnavailableError) {
+					log.Printf("failed to purge trash: %v", err)
+				}
+				continue
+			}
+			if len(purged) > 0 {
+				log.Printf("%d entries purged from trash after retention period", len(purged))
+			}
+		}
 	}
+}
 
-	return user, nil
+// QueueLength - количество задач, ожидающих обработки
+func (s *StorageService) QueueLength() int {
+	return len(s.taskQueue)
+}
+
+// QueueCapacity - ёмкость очереди задач
+func (s *StorageService) QueueCapacity() int {
+	return cap(s.taskQueue)
+}
+
+// IsShuttingDown - сервис завершает работу и не принимает новые задачи
+func (s *StorageService) IsShuttingDown() bool {
+	return s.isShuttingDown.Load()
 }
 
 // Shutdown - инициирует graceful shutdown сервиса
@@ -571,6 +3543,10 @@ func (s *StorageService) Shutdown() {
 	//Помечаем сервис как завершающий работу
 	s.isShuttingDown.Store(true)
 
+	//Останавливаем фоновые задачи, пока очередь ещё открыта
+	close(s.stopBackground)
+	s.backgroundTasks.Wait()
+
 	//Ждем завершения всех задач
 	s.tasksInProcess.Wait()
 
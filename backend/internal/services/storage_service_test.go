@@ -2,18 +2,27 @@
 package services_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
 	"github.com/JustScorpio/GophKeeper/backend/internal/customerrors"
+	"github.com/JustScorpio/GophKeeper/backend/internal/envelope"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/backend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/backend/internal/notifications"
+	"github.com/JustScorpio/GophKeeper/backend/internal/repositories"
 	"github.com/JustScorpio/GophKeeper/backend/internal/repositories/inmemory"
 	"github.com/JustScorpio/GophKeeper/backend/internal/services"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -66,6 +75,8 @@ func createTestService() (*services.StorageService, *inmemory.DatabaseManager) {
 		dbManager.Cards,
 		dbManager.Credentials,
 		dbManager.Texts,
+		dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
 	)
 	return service, dbManager
 }
@@ -84,6 +95,7 @@ func TestNewStorageService(t *testing.T) {
 		dbManager.Cards,
 		dbManager.Credentials,
 		dbManager.Texts,
+		dbManager.Access,
 	)
 
 	assert.NotNil(t, service)
@@ -754,3 +766,1866 @@ func TestStorageService_Performance(t *testing.T) {
 	// Проверяем, что производительность приемлемая
 	assert.Less(t, elapsed, 5*time.Second, "Operations took too long")
 }
+
+// TestStorageService_AuditLog тестирует ведение журнала аудита
+func TestStorageService_AuditLog(t *testing.T) {
+	service, _ := createTestService()
+	defer service.Shutdown()
+
+	testData := createTestData()
+	ctx := createTestContext("audituser")
+	ctx = customcontext.WithSessionID(ctx, "session-1")
+	ctx = customcontext.WithDeviceID(ctx, "laptop")
+	ctx = customcontext.WithClientIP(ctx, "10.0.0.1")
+
+	t.Run("Запись операций над сущностью", func(t *testing.T) {
+		created, err := service.CreateText(ctx, &testData.Text)
+		require.NoError(t, err)
+
+		_, err = service.GetText(ctx, created.ID)
+		require.NoError(t, err)
+
+		created.Data = "updated"
+		_, err = service.UpdateText(ctx, created)
+		require.NoError(t, err)
+
+		_, err = service.DeleteText(ctx, created.ID)
+		require.NoError(t, err)
+
+		events, err := service.GetAuditLog(ctx, &dtos.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 4)
+
+		actions := make([]string, 0, len(events))
+		for _, event := range events {
+			actions = append(actions, event.Action)
+			assert.Equal(t, "audituser", event.UserID)
+			assert.Equal(t, "text", event.EntityType)
+			assert.Equal(t, created.ID, event.EntityID)
+			assert.Equal(t, "session-1", event.SessionID)
+			assert.Equal(t, "laptop", event.DeviceID)
+			assert.Equal(t, "10.0.0.1", event.IP)
+			assert.False(t, event.CreatedAt.IsZero())
+		}
+		assert.Equal(t, []string{"create", "read", "update", "delete"}, actions)
+	})
+
+	t.Run("Операции над несуществующими сущностями не пишутся", func(t *testing.T) {
+		before, err := service.GetAuditLog(ctx, &dtos.AuditFilter{})
+		require.NoError(t, err)
+
+		_, err = service.DeleteCard(ctx, "999")
+		require.NoError(t, err)
+
+		after, err := service.GetAuditLog(ctx, &dtos.AuditFilter{})
+		require.NoError(t, err)
+		assert.Len(t, after, len(before))
+	})
+
+	t.Run("Фильтр по типу и периоду", func(t *testing.T) {
+		_, err := service.CreateCard(ctx, &testData.Card)
+		require.NoError(t, err)
+
+		events, err := service.GetAuditLog(ctx, &dtos.AuditFilter{EntityType: "card"})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "create", events[0].Action)
+
+		events, err = service.GetAuditLog(ctx, &dtos.AuditFilter{From: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Пользователь видит только свою историю", func(t *testing.T) {
+		otherCtx := createTestContext("otheruser")
+		events, err := service.GetAuditLog(otherCtx, &dtos.AuditFilter{UserID: "audituser"})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Журнал отключён", func(t *testing.T) {
+		dbManager := inmemory.NewDatabaseManager()
+		plainService := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access)
+		defer plainService.Shutdown()
+
+		_, err := plainService.GetAuditLog(ctx, &dtos.AuditFilter{})
+		assert.Error(t, err)
+	})
+}
+
+// TestStorageService_QueueState тестирует сведения о состоянии очереди задач
+func TestStorageService_QueueState(t *testing.T) {
+	service, _ := createTestService()
+
+	assert.Equal(t, 256, service.QueueCapacity())
+	assert.Equal(t, 0, service.QueueLength())
+	assert.False(t, service.IsShuttingDown())
+
+	service.Shutdown()
+	assert.True(t, service.IsShuttingDown())
+}
+
+// TestStorageService_Notifications тестирует уведомления об изменениях сущностей
+func TestStorageService_Notifications(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	hub := notifications.NewHub()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithNotifier(hub))
+	defer service.Shutdown()
+
+	testData := createTestData()
+	ctx := customcontext.WithSessionID(createTestContext("notifyuser"), "laptop-session")
+
+	// Подписка другого устройства того же пользователя
+	events, unsubscribe := hub.Subscribe("notifyuser", "phone-session")
+	defer unsubscribe()
+
+	// Подписка сессии, в которой выполняются изменения, и другого пользователя
+	ownEvents, unsubscribeOwn := hub.Subscribe("notifyuser", "laptop-session")
+	defer unsubscribeOwn()
+	otherEvents, unsubscribeOther := hub.Subscribe("otheruser", "other-session")
+	defer unsubscribeOther()
+
+	created, err := service.CreateCard(ctx, &testData.Card)
+	require.NoError(t, err)
+
+	_, err = service.GetCard(ctx, created.ID)
+	require.NoError(t, err)
+	_, err = service.GetAllCards(ctx)
+	require.NoError(t, err)
+
+	created.Metadata = "updated"
+	_, err = service.UpdateCard(ctx, created)
+	require.NoError(t, err)
+
+	_, err = service.DeleteCard(ctx, created.ID)
+	require.NoError(t, err)
+
+	// Удаление несуществующей сущности уведомления не порождает
+	_, _ = service.DeleteCard(ctx, "missing")
+
+	var actions []string
+	for len(actions) < 3 {
+		select {
+		case event := <-events:
+			assert.Equal(t, "card", event.EntityType)
+			assert.Equal(t, created.ID, event.EntityID)
+			assert.False(t, event.OccurredAt.IsZero())
+			actions = append(actions, event.Action)
+		case <-time.After(time.Second):
+			t.Fatalf("expected 3 events, got %v", actions)
+		}
+	}
+	assert.Equal(t, []string{"create", "update", "delete"}, actions)
+
+	assert.Empty(t, events)
+	assert.Empty(t, ownEvents)
+	assert.Empty(t, otherEvents)
+}
+
+// TestStorageService_Sharing тестирует обмен записями между пользователями
+func TestStorageService_Sharing(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithTrash(dbManager.Trash, time.Hour, 0))
+	defer service.Shutdown()
+
+	testData := createTestData()
+	ownerCtx := createTestContext("owner")
+	recipientCtx := createTestContext("recipient")
+	strangerCtx := createTestContext("stranger")
+
+	for _, ctx := range []context.Context{ownerCtx, recipientCtx} {
+		_, err := service.SetUserKeys(ctx, &entities.UserKeys{PublicKey: "public", EncryptedPrivateKey: "private"})
+		require.NoError(t, err)
+	}
+
+	text, err := service.CreateText(ownerCtx, &testData.Text)
+	require.NoError(t, err)
+
+	newGrant := func(permission string) *dtos.NewShareGrant {
+		return &dtos.NewShareGrant{EntityType: "text", EntityID: text.ID, RecipientID: "recipient", Permission: permission, EntryKey: "recipient-envelope"}
+	}
+
+	t.Run("Ключи пользователей", func(t *testing.T) {
+		own, err := service.GetUserKeys(ownerCtx, "owner")
+		require.NoError(t, err)
+		assert.Equal(t, "private", own.EncryptedPrivateKey)
+
+		// Закрытый ключ другого пользователя не отдаётся
+		foreign, err := service.GetUserKeys(recipientCtx, "owner")
+		require.NoError(t, err)
+		assert.Equal(t, "public", foreign.PublicKey)
+		assert.Empty(t, foreign.EncryptedPrivateKey)
+
+		missing, err := service.GetUserKeys(ownerCtx, "stranger")
+		require.NoError(t, err)
+		assert.Nil(t, missing)
+	})
+
+	t.Run("Запись без ключа записи", func(t *testing.T) {
+		grant, err := service.ShareEntry(ownerCtx, newGrant(entities.PermissionRead))
+		require.NoError(t, err)
+		assert.Nil(t, grant)
+	})
+
+	// Владелец переводит запись на ключ записи
+	text.EntryKey = "owner-envelope"
+	text, err = service.UpdateText(ownerCtx, text)
+	require.NoError(t, err)
+	require.Equal(t, "owner-envelope", text.EntryKey)
+
+	t.Run("Некорректный получатель", func(t *testing.T) {
+		invalid := newGrant(entities.PermissionRead)
+		invalid.RecipientID = "owner"
+		_, err := service.ShareEntry(ownerCtx, invalid)
+		assert.Error(t, err)
+
+		// Получатель без опубликованного ключа
+		invalid.RecipientID = "stranger"
+		_, err = service.ShareEntry(ownerCtx, invalid)
+		assert.Error(t, err)
+
+		// Чужой записью делиться нельзя
+		grant, err := service.ShareEntry(recipientCtx, &dtos.NewShareGrant{EntityType: "text", EntityID: text.ID, RecipientID: "owner", Permission: entities.PermissionRead, EntryKey: "key"})
+		require.NoError(t, err)
+		assert.Nil(t, grant)
+	})
+
+	grant, err := service.ShareEntry(ownerCtx, newGrant(entities.PermissionRead))
+	require.NoError(t, err)
+	require.NotNil(t, grant)
+	assert.Equal(t, entities.ShareStatusPending, grant.Status)
+	assert.Equal(t, "owner", grant.OwnerID)
+
+	t.Run("Приглашение до принятия", func(t *testing.T) {
+		texts, err := service.GetAllTexts(recipientCtx)
+		require.NoError(t, err)
+		assert.Empty(t, texts)
+
+		// Принять приглашение может только получатель
+		accepted, err := service.AcceptShare(ownerCtx, grant.ID)
+		require.NoError(t, err)
+		assert.Nil(t, accepted)
+	})
+
+	t.Run("Чтение принятой записи", func(t *testing.T) {
+		accepted, err := service.AcceptShare(recipientCtx, grant.ID)
+		require.NoError(t, err)
+		require.NotNil(t, accepted)
+		assert.Equal(t, entities.ShareStatusAccepted, accepted.Status)
+
+		texts, err := service.GetAllTexts(recipientCtx)
+		require.NoError(t, err)
+		require.Len(t, texts, 1)
+		assert.Equal(t, "owner", texts[0].OwnerID)
+		assert.Equal(t, "recipient-envelope", texts[0].EntryKey)
+		assert.Equal(t, entities.PermissionRead, texts[0].Permission)
+
+		shared, err := service.GetText(recipientCtx, text.ID)
+		require.NoError(t, err)
+		require.NotNil(t, shared)
+
+		// Третий пользователь запись не видит
+		hidden, err := service.GetText(strangerCtx, text.ID)
+		require.NoError(t, err)
+		assert.Nil(t, hidden)
+	})
+
+	t.Run("Изменение получателем", func(t *testing.T) {
+		changed := *text
+		changed.Data = "changed by recipient"
+		changed.EntryKey = "forged-envelope"
+
+		// Только чтение
+		updated, err := service.UpdateText(recipientCtx, &changed)
+		assertHTTPCode(t, err, 403)
+		assert.Nil(t, updated)
+
+		// Повторная выдача права заменяет его, не сбрасывая принятие
+		regrant, err := service.ShareEntry(ownerCtx, newGrant(entities.PermissionWrite))
+		require.NoError(t, err)
+		assert.Equal(t, grant.ID, regrant.ID)
+		assert.Equal(t, entities.ShareStatusAccepted, regrant.Status)
+
+		updated, err = service.UpdateText(recipientCtx, &changed)
+		require.NoError(t, err)
+		require.NotNil(t, updated)
+		assert.Equal(t, "recipient-envelope", updated.EntryKey)
+		assert.Equal(t, entities.PermissionWrite, updated.Permission)
+
+		// Ключ записи у владельца не изменился
+		own, err := service.GetText(ownerCtx, text.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "changed by recipient", own.Data)
+		assert.Equal(t, "owner-envelope", own.EntryKey)
+		assert.Empty(t, own.Permission)
+
+		// Удалить запись может только владелец
+		deleted, err := service.DeleteText(recipientCtx, text.ID)
+		assertHTTPCode(t, err, 403)
+		assert.Nil(t, deleted)
+
+		// Для остальных запись не существует
+		deleted, err = service.DeleteText(strangerCtx, text.ID)
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+	})
+
+	t.Run("Список прав", func(t *testing.T) {
+		for _, ctx := range []context.Context{ownerCtx, recipientCtx} {
+			grants, err := service.GetShares(ctx)
+			require.NoError(t, err)
+			assert.Len(t, grants, 1)
+		}
+
+		grants, err := service.GetShares(strangerCtx)
+		require.NoError(t, err)
+		assert.Empty(t, grants)
+	})
+
+	t.Run("Отзыв права", func(t *testing.T) {
+		deleted, err := service.DeleteShare(strangerCtx, grant.ID)
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+
+		deleted, err = service.DeleteShare(ownerCtx, grant.ID)
+		require.NoError(t, err)
+		require.NotNil(t, deleted)
+
+		texts, err := service.GetAllTexts(recipientCtx)
+		require.NoError(t, err)
+		assert.Empty(t, texts)
+	})
+
+	t.Run("Окончательное удаление записи удаляет права", func(t *testing.T) {
+		grant, err := service.ShareEntry(ownerCtx, newGrant(entities.PermissionRead))
+		require.NoError(t, err)
+		_, err = service.AcceptShare(recipientCtx, grant.ID)
+		require.NoError(t, err)
+
+		_, err = service.DeleteText(ownerCtx, text.ID)
+		require.NoError(t, err)
+
+		// Пока запись в корзине, права сохраняются, но получатель её не видит
+		shared, err := service.GetText(recipientCtx, text.ID)
+		require.NoError(t, err)
+		assert.Nil(t, shared)
+
+		grants, err := service.GetShares(recipientCtx)
+		require.NoError(t, err)
+		assert.Len(t, grants, 1)
+
+		_, err = service.PurgeTrashItem(ownerCtx, services.EntityText, text.ID)
+		require.NoError(t, err)
+
+		grants, err = service.GetShares(recipientCtx)
+		require.NoError(t, err)
+		assert.Empty(t, grants)
+	})
+
+	t.Run("Без поддержки обмена", func(t *testing.T) {
+		plain, _ := createTestService()
+		defer plain.Shutdown()
+
+		_, err := plain.GetShares(ownerCtx)
+		var httpErr *customerrors.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, 501, httpErr.Code)
+	})
+}
+
+// TestStorageService_Organizations тестирует организации: роли участников, коллекции и замену ключа коллекции
+func TestStorageService_Organizations(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithOrganizations(dbManager.Orgs))
+	defer service.Shutdown()
+
+	ownerCtx := createTestContext("owner")
+	memberCtx := createTestContext("member")
+	readerCtx := createTestContext("reader")
+	strangerCtx := createTestContext("stranger")
+
+	for _, ctx := range []context.Context{ownerCtx, memberCtx, readerCtx} {
+		_, err := service.SetUserKeys(ctx, &entities.UserKeys{PublicKey: "public", EncryptedPrivateKey: "private"})
+		require.NoError(t, err)
+	}
+
+	org, err := service.CreateOrganization(ownerCtx, &dtos.NewOrganization{Name: "team"})
+	require.NoError(t, err)
+	assert.Equal(t, entities.RoleOwner, org.Role)
+
+	collection, err := service.CreateCollection(ownerCtx, &dtos.NewCollection{OrgID: org.ID, Name: "shared",
+		Keys: []entities.CollectionKey{{Login: "owner", Version: 1, EncryptedKey: "owner-key-1"}}})
+	require.NoError(t, err)
+	require.NotNil(t, collection)
+	assert.Equal(t, 1, collection.KeyVersion)
+	assert.Equal(t, "owner-key-1", collection.EncryptedKey)
+
+	invite := func(ctx context.Context, login, role string) (*entities.OrgMember, error) {
+		return service.InviteMember(ctx, &dtos.NewOrgMember{OrgID: org.ID, Login: login, Role: role,
+			Keys: []entities.CollectionKey{{CollectionID: collection.ID, Login: login, Version: 1, EncryptedKey: login + "-key-1"}}})
+	}
+
+	t.Run("Приглашение участников", func(t *testing.T) {
+		// Ключи должны быть выданы для каждой коллекции
+		_, err := service.InviteMember(ownerCtx, &dtos.NewOrgMember{OrgID: org.ID, Login: "member", Role: entities.RoleMember})
+		assertHTTPCode(t, err, 400)
+
+		// Приглашённый должен опубликовать открытый ключ
+		_, err = invite(ownerCtx, "stranger", entities.RoleMember)
+		assertHTTPCode(t, err, 404)
+
+		// Владельца назначить нельзя
+		_, err = invite(ownerCtx, "member", entities.RoleOwner)
+		assertHTTPCode(t, err, 403)
+
+		member, err := invite(ownerCtx, "member", entities.RoleMember)
+		require.NoError(t, err)
+		assert.Equal(t, entities.MemberStatusInvited, member.Status)
+
+		_, err = invite(ownerCtx, "member", entities.RoleMember)
+		assertHTTPCode(t, err, 409)
+
+		// До принятия приглашения участник не видит организацию изнутри
+		_, err = service.GetCollections(memberCtx, org.ID)
+		assertHTTPCode(t, err, 404)
+
+		accepted, err := service.AcceptInvite(memberCtx, org.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.MemberStatusActive, accepted.Status)
+
+		// Участник не может приглашать
+		_, err = invite(memberCtx, "reader", entities.RoleReadOnly)
+		assertHTTPCode(t, err, 403)
+
+		_, err = invite(ownerCtx, "reader", entities.RoleReadOnly)
+		require.NoError(t, err)
+		_, err = service.AcceptInvite(readerCtx, org.ID)
+		require.NoError(t, err)
+
+		members, err := service.GetOrgMembers(readerCtx, org.ID)
+		require.NoError(t, err)
+		assert.Len(t, members, 3)
+
+		_, err = service.GetOrgMembers(strangerCtx, org.ID)
+		assertHTTPCode(t, err, 404)
+	})
+
+	newText := func(version int) *dtos.NewTextData {
+		return &dtos.NewTextData{Data: "shared secret", NewSecureEntity: dtos.NewSecureEntity{CollectionID: collection.ID, KeyVersion: version}}
+	}
+
+	text, err := service.CreateText(memberCtx, newText(1))
+	require.NoError(t, err)
+	require.NotNil(t, text)
+
+	t.Run("Записи коллекции", func(t *testing.T) {
+		_, err := service.CreateText(readerCtx, newText(1))
+		assertHTTPCode(t, err, 403)
+
+		_, err = service.CreateText(strangerCtx, newText(1))
+		assertHTTPCode(t, err, 404)
+
+		_, err = service.CreateText(memberCtx, newText(2))
+		assertHTTPCode(t, err, 409)
+
+		// Каждый участник получает ключ коллекции, зашифрованный для него
+		texts, err := service.GetAllTexts(readerCtx)
+		require.NoError(t, err)
+		require.Len(t, texts, 1)
+		assert.Equal(t, "reader-key-1", texts[0].EntryKey)
+		assert.Equal(t, entities.PermissionRead, texts[0].Permission)
+
+		hidden, err := service.GetText(strangerCtx, text.ID)
+		require.NoError(t, err)
+		assert.Nil(t, hidden)
+
+		changed := *text
+		changed.Data = "changed"
+		_, err = service.UpdateText(readerCtx, &changed)
+		assertHTTPCode(t, err, 403)
+
+		updated, err := service.UpdateText(memberCtx, &changed)
+		require.NoError(t, err)
+		assert.Equal(t, "changed", updated.Data)
+
+		// Удаляют записи коллекции только владелец и администраторы
+		_, err = service.DeleteText(memberCtx, text.ID)
+		assertHTTPCode(t, err, 403)
+
+		// Записью коллекции нельзя поделиться лично
+		_, err = service.ShareEntry(memberCtx, &dtos.NewShareGrant{EntityType: "text", EntityID: text.ID, RecipientID: "reader", Permission: entities.PermissionRead, EntryKey: "key"})
+		assertHTTPCode(t, err, 403)
+	})
+
+	t.Run("Исключение участника и замена ключа", func(t *testing.T) {
+		// Участник не может исключать других, но может выйти сам
+		_, err := service.RemoveMember(memberCtx, &entities.OrgMember{OrgID: org.ID, Login: "reader"})
+		assertHTTPCode(t, err, 403)
+
+		// Владельца исключить нельзя
+		_, err = service.RemoveMember(ownerCtx, &entities.OrgMember{OrgID: org.ID, Login: "owner"})
+		assertHTTPCode(t, err, 403)
+
+		removed, err := service.RemoveMember(ownerCtx, &entities.OrgMember{OrgID: org.ID, Login: "reader"})
+		require.NoError(t, err)
+		require.NotNil(t, removed)
+
+		texts, err := service.GetAllTexts(readerCtx)
+		require.NoError(t, err)
+		assert.Empty(t, texts)
+
+		collections, err := service.GetCollections(ownerCtx, org.ID)
+		require.NoError(t, err)
+		require.Len(t, collections, 1)
+		assert.True(t, collections[0].RotationRequired)
+
+		rotation := &dtos.CollectionRotation{CollectionID: collection.ID, Version: 2, Keys: []entities.CollectionKey{
+			{CollectionID: collection.ID, Login: "owner", Version: 2, EncryptedKey: "owner-key-2"},
+			{CollectionID: collection.ID, Login: "reader", Version: 2, EncryptedKey: "reader-key-2"},
+		}}
+
+		// Ключ нельзя выдать исключённому участнику, а участник коллекциями не управляет
+		_, err = service.RotateCollectionKey(ownerCtx, rotation)
+		assertHTTPCode(t, err, 400)
+
+		rotation.Keys[1] = entities.CollectionKey{CollectionID: collection.ID, Login: "member", Version: 2, EncryptedKey: "member-key-2"}
+		_, err = service.RotateCollectionKey(memberCtx, rotation)
+		assertHTTPCode(t, err, 403)
+
+		rotated, err := service.RotateCollectionKey(ownerCtx, rotation)
+		require.NoError(t, err)
+		assert.Equal(t, 2, rotated.KeyVersion)
+		assert.False(t, rotated.RotationRequired)
+
+		_, err = service.RotateCollectionKey(ownerCtx, rotation)
+		assertHTTPCode(t, err, 409)
+
+		// Запись со старой версией ключа изменить нельзя - её нужно перешифровать новым ключом
+		current, err := service.GetText(memberCtx, text.ID)
+		require.NoError(t, err)
+		_, err = service.UpdateText(memberCtx, current)
+		assertHTTPCode(t, err, 409)
+
+		current.KeyVersion = 2
+		reencrypted, err := service.UpdateText(memberCtx, current)
+		require.NoError(t, err)
+		assert.Equal(t, "member-key-2", reencrypted.EntryKey)
+
+		deleted, err := service.DeleteText(ownerCtx, text.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, deleted)
+	})
+
+	t.Run("Без поддержки организаций", func(t *testing.T) {
+		plain, _ := createTestService()
+		defer plain.Shutdown()
+
+		_, err := plain.GetOrganizations(ownerCtx)
+		assertHTTPCode(t, err, 501)
+	})
+}
+
+// TestStorageService_EmergencyAccess тестирует экстренный доступ: переходы состояний, период ожидания и открытие хранилища
+func TestStorageService_EmergencyAccess(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithEmergencyAccess(dbManager.Emergency, 0))
+	defer service.Shutdown()
+
+	grantorCtx := createTestContext("grantor")
+	trusteeCtx := createTestContext("trustee")
+	strangerCtx := createTestContext("stranger")
+
+	for _, ctx := range []context.Context{grantorCtx, trusteeCtx} {
+		_, err := service.SetUserKeys(ctx, &entities.UserKeys{PublicKey: "public", EncryptedPrivateKey: "private-" + customcontext.GetUserID(ctx)})
+		require.NoError(t, err)
+	}
+
+	text, err := service.CreateText(grantorCtx, &dtos.NewTextData{Data: "secret", NewSecureEntity: dtos.NewSecureEntity{Metadata: "will"}})
+	require.NoError(t, err)
+
+	newAccess := &dtos.NewEmergencyAccess{GranteeID: "trustee", WaitDays: 7, EncryptedKey: "vault-key-for-trustee"}
+
+	t.Run("Назначение доверенного лица", func(t *testing.T) {
+		_, err := service.InviteEmergencyContact(grantorCtx, &dtos.NewEmergencyAccess{GranteeID: "grantor", WaitDays: 7, EncryptedKey: "key"})
+		assertHTTPCode(t, err, 400)
+
+		_, err = service.InviteEmergencyContact(grantorCtx, &dtos.NewEmergencyAccess{GranteeID: "trustee", WaitDays: 0, EncryptedKey: "key"})
+		assertHTTPCode(t, err, 400)
+
+		// Доверенное лицо должно опубликовать открытый ключ
+		_, err = service.InviteEmergencyContact(grantorCtx, &dtos.NewEmergencyAccess{GranteeID: "stranger", WaitDays: 7, EncryptedKey: "key"})
+		assertHTTPCode(t, err, 404)
+	})
+
+	access, err := service.InviteEmergencyContact(grantorCtx, newAccess)
+	require.NoError(t, err)
+	assert.Equal(t, entities.EmergencyStatusInvited, access.Status)
+	assert.Empty(t, access.EncryptedKey, "key is not returned before access is granted")
+
+	_, err = service.InviteEmergencyContact(grantorCtx, newAccess)
+	assertHTTPCode(t, err, 409)
+
+	t.Run("Переходы состояний", func(t *testing.T) {
+		// Запросить доступ до согласия нельзя, согласиться за доверенное лицо - тоже
+		_, err := service.RequestEmergencyAccess(trusteeCtx, access.ID)
+		assertHTTPCode(t, err, 409)
+		_, err = service.AcceptEmergencyAccess(grantorCtx, access.ID)
+		assertHTTPCode(t, err, 403)
+		_, err = service.AcceptEmergencyAccess(strangerCtx, access.ID)
+		assertHTTPCode(t, err, 404)
+
+		accepted, err := service.AcceptEmergencyAccess(trusteeCtx, access.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.EmergencyStatusAccepted, accepted.Status)
+
+		requested, err := service.RequestEmergencyAccess(trusteeCtx, access.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.EmergencyStatusRequested, requested.Status)
+		require.NotNil(t, requested.GrantAt())
+
+		// Хранилище закрыто, пока идёт ожидание
+		_, err = service.OpenEmergencyVault(trusteeCtx, access.ID)
+		assertHTTPCode(t, err, 409)
+
+		// Доверитель отклоняет запрос - доступ возвращается к согласию
+		rejected, err := service.RejectEmergencyAccess(grantorCtx, access.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.EmergencyStatusAccepted, rejected.Status)
+		assert.Nil(t, rejected.RequestedAt)
+
+		// Одобрить может только доверитель
+		_, err = service.RequestEmergencyAccess(trusteeCtx, access.ID)
+		require.NoError(t, err)
+		_, err = service.ApproveEmergencyAccess(trusteeCtx, access.ID)
+		assertHTTPCode(t, err, 403)
+
+		approved, err := service.ApproveEmergencyAccess(grantorCtx, access.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.EmergencyStatusGranted, approved.Status)
+		assert.Empty(t, approved.EncryptedKey, "grantor does not receive the wrapped key")
+
+		// Доверитель закрывает доступ
+		_, err = service.RejectEmergencyAccess(grantorCtx, access.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("Доступ по истечении ожидания", func(t *testing.T) {
+		requested, err := service.RequestEmergencyAccess(trusteeCtx, access.ID)
+		require.NoError(t, err)
+
+		granted, err := service.GrantElapsedEmergencyAccess(context.Background(), requested.GrantAt().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, granted, "waiting period has not elapsed yet")
+
+		granted, err = service.GrantElapsedEmergencyAccess(context.Background(), *requested.GrantAt())
+		require.NoError(t, err)
+		require.Len(t, granted, 1)
+		assert.Equal(t, entities.EmergencyStatusGranted, granted[0].Status)
+		assert.Empty(t, granted[0].EncryptedKey)
+
+		// Доверитель узнаёт об открытии хранилища из журнала
+		events, err := service.GetAuditLog(grantorCtx, &dtos.AuditFilter{EntityType: "emergency"})
+		require.NoError(t, err)
+		require.NotEmpty(t, events)
+		assert.Equal(t, "expire", events[len(events)-1].Action)
+
+		vault, err := service.OpenEmergencyVault(trusteeCtx, access.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "vault-key-for-trustee", vault.Access.EncryptedKey)
+		assert.Equal(t, "private-grantor", vault.EncryptedPrivateKey)
+		require.Len(t, vault.Texts, 1)
+		assert.Equal(t, text.ID, vault.Texts[0].ID)
+
+		// Доверитель не может открыть хранилище сам через доступ
+		_, err = service.OpenEmergencyVault(grantorCtx, access.ID)
+		assertHTTPCode(t, err, 403)
+
+		accesses, err := service.GetEmergencyAccess(trusteeCtx)
+		require.NoError(t, err)
+		require.Len(t, accesses, 1)
+		assert.Equal(t, "vault-key-for-trustee", accesses[0].EncryptedKey)
+	})
+
+	t.Run("Отмена доступа", func(t *testing.T) {
+		_, err := service.DeleteEmergencyAccess(strangerCtx, access.ID)
+		assertHTTPCode(t, err, 404)
+
+		deleted, err := service.DeleteEmergencyAccess(grantorCtx, access.ID)
+		require.NoError(t, err)
+		require.NotNil(t, deleted)
+
+		_, err = service.OpenEmergencyVault(trusteeCtx, access.ID)
+		assertHTTPCode(t, err, 404)
+	})
+
+	t.Run("Фоновая проверка ожиданий", func(t *testing.T) {
+		timed := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+			services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+			services.WithEmergencyAccess(dbManager.Emergency, 10*time.Millisecond))
+
+		// Завершение работы останавливает фоновую задачу до закрытия очереди
+		time.Sleep(50 * time.Millisecond)
+		timed.Shutdown()
+	})
+}
+
+// TestStorageService_EmergencyVaultScope проверяет, что доверенное лицо получает только личные записи доверителя
+func TestStorageService_EmergencyVaultScope(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithOrganizations(dbManager.Orgs),
+		services.WithEmergencyAccess(dbManager.Emergency, 0))
+	defer service.Shutdown()
+
+	grantorCtx := createTestContext("grantor")
+	trusteeCtx := createTestContext("trustee")
+	friendCtx := createTestContext("friend")
+
+	for _, ctx := range []context.Context{grantorCtx, trusteeCtx, friendCtx} {
+		_, err := service.SetUserKeys(ctx, &entities.UserKeys{PublicKey: "public", EncryptedPrivateKey: "private"})
+		require.NoError(t, err)
+	}
+
+	own, err := service.CreateText(grantorCtx, &dtos.NewTextData{Data: "own"})
+	require.NoError(t, err)
+
+	// Запись третьего лица, которой оно поделилось с доверителем
+	foreign, err := service.CreateText(friendCtx, &dtos.NewTextData{Data: "friend's secret"})
+	require.NoError(t, err)
+	foreign.EntryKey = "friend-envelope"
+	_, err = service.UpdateText(friendCtx, foreign)
+	require.NoError(t, err)
+	grant, err := service.ShareEntry(friendCtx, &dtos.NewShareGrant{EntityType: "text", EntityID: foreign.ID, RecipientID: "grantor",
+		Permission: entities.PermissionWrite, EntryKey: "grantor-envelope"})
+	require.NoError(t, err)
+	require.NotNil(t, grant)
+	_, err = service.AcceptShare(grantorCtx, grant.ID)
+	require.NoError(t, err)
+
+	// Запись коллекции организации доверителя
+	org, err := service.CreateOrganization(grantorCtx, &dtos.NewOrganization{Name: "team"})
+	require.NoError(t, err)
+	collection, err := service.CreateCollection(grantorCtx, &dtos.NewCollection{OrgID: org.ID, Name: "shared",
+		Keys: []entities.CollectionKey{{Login: "grantor", Version: 1, EncryptedKey: "grantor-key-1"}}})
+	require.NoError(t, err)
+	_, err = service.CreateText(grantorCtx, &dtos.NewTextData{Data: "team secret", NewSecureEntity: dtos.NewSecureEntity{CollectionID: collection.ID, KeyVersion: 1}})
+	require.NoError(t, err)
+
+	// Доверитель видит все три записи
+	visible, err := service.GetAllTexts(grantorCtx)
+	require.NoError(t, err)
+	require.Len(t, visible, 3)
+
+	access, err := service.InviteEmergencyContact(grantorCtx, &dtos.NewEmergencyAccess{GranteeID: "trustee", WaitDays: 1, EncryptedKey: "vault-key"})
+	require.NoError(t, err)
+	_, err = service.AcceptEmergencyAccess(trusteeCtx, access.ID)
+	require.NoError(t, err)
+	requested, err := service.RequestEmergencyAccess(trusteeCtx, access.ID)
+	require.NoError(t, err)
+	_, err = service.GrantElapsedEmergencyAccess(context.Background(), requested.GrantAt().Add(time.Minute))
+	require.NoError(t, err)
+
+	vault, err := service.OpenEmergencyVault(trusteeCtx, access.ID)
+	require.NoError(t, err)
+	require.Len(t, vault.Texts, 1)
+	assert.Equal(t, own.ID, vault.Texts[0].ID)
+}
+
+func TestStorageService_Accounts(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithOrganizations(dbManager.Orgs),
+		services.WithEmergencyAccess(dbManager.Emergency, 0),
+		services.WithAccounts(dbManager.Accounts))
+	defer service.Shutdown()
+
+	adminCtx := customcontext.WithAdmin(context.Background())
+	aliceCtx := createTestContext("alice")
+	bobCtx := createTestContext("bob")
+
+	for _, login := range []string{"alice", "bob"} {
+		_, err := service.CreateUser(context.Background(), dtos.NewUser{Login: login, Password: "password"})
+		require.NoError(t, err)
+		_, err = service.SetUserKeys(createTestContext(login), &entities.UserKeys{PublicKey: "public", EncryptedPrivateKey: "private"})
+		require.NoError(t, err)
+	}
+
+	text, err := service.CreateText(aliceCtx, &dtos.NewTextData{Data: "secret", NewSecureEntity: dtos.NewSecureEntity{Metadata: "note"}})
+	require.NoError(t, err)
+	_, err = service.CreateBinary(aliceCtx, &dtos.NewBinaryData{Data: []byte("12345")})
+	require.NoError(t, err)
+	_, err = service.CreateText(bobCtx, &dtos.NewTextData{Data: "bob"})
+	require.NoError(t, err)
+
+	_, err = service.ShareEntry(aliceCtx, &dtos.NewShareGrant{EntityType: "text", EntityID: text.ID, RecipientID: "bob", Permission: entities.PermissionRead, EntryKey: "key"})
+	require.NoError(t, err)
+	_, err = service.CreateOrganization(aliceCtx, &dtos.NewOrganization{Name: "team"})
+	require.NoError(t, err)
+	_, err = service.InviteEmergencyContact(aliceCtx, &dtos.NewEmergencyAccess{GranteeID: "bob", WaitDays: 7, EncryptedKey: "key"})
+	require.NoError(t, err)
+
+	t.Run("Только администратор", func(t *testing.T) {
+		_, err := service.GetAccounts(aliceCtx)
+		assertHTTPCode(t, err, 403)
+		_, err = service.DisableAccount(aliceCtx, "bob")
+		assertHTTPCode(t, err, 403)
+		_, err = service.GetServerStats(aliceCtx)
+		assertHTTPCode(t, err, 403)
+	})
+
+	t.Run("Учётные записи и объём данных", func(t *testing.T) {
+		accounts, err := service.GetAccounts(adminCtx)
+		require.NoError(t, err)
+		require.Len(t, accounts, 2)
+
+		assert.Equal(t, "alice", accounts[0].Login)
+		assert.Equal(t, 1, accounts[0].Usage.Texts)
+		assert.Equal(t, 1, accounts[0].Usage.Binaries)
+		assert.Equal(t, int64(len("secret")+len("note")+len("12345")), accounts[0].Usage.Bytes)
+		assert.Equal(t, 1, accounts[1].Usage.Texts)
+
+		_, err = service.DisableAccount(adminCtx, "nobody")
+		assertHTTPCode(t, err, 404)
+	})
+
+	t.Run("Блокировка и завершение сессий", func(t *testing.T) {
+		require.NoError(t, service.CheckSession(context.Background(), "bob", 0))
+
+		disabled, err := service.DisableAccount(adminCtx, "bob")
+		require.NoError(t, err)
+		assert.True(t, disabled.Disabled)
+		assert.Equal(t, 1, disabled.SessionVersion)
+
+		assertHTTPCode(t, service.CheckSession(context.Background(), "bob", 1), 403)
+		assertHTTPCode(t, service.CheckSession(context.Background(), "bob", 0), 401)
+
+		enabled, err := service.EnableAccount(adminCtx, "bob")
+		require.NoError(t, err)
+		assert.False(t, enabled.Disabled)
+		require.NoError(t, service.CheckSession(context.Background(), "bob", 1))
+
+		revoked, err := service.RevokeSessions(adminCtx, "bob")
+		require.NoError(t, err)
+		assert.Equal(t, 2, revoked.SessionVersion)
+		assertHTTPCode(t, service.CheckSession(context.Background(), "bob", 1), 401)
+	})
+
+	t.Run("Удаление со всеми данными", func(t *testing.T) {
+		deleted, err := service.DeleteAccount(adminCtx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted.Usage.Texts+deleted.Usage.Binaries)
+
+		assertHTTPCode(t, service.CheckSession(context.Background(), "alice", 0), 401)
+
+		texts, err := service.GetAllTexts(aliceCtx)
+		require.NoError(t, err)
+		assert.Empty(t, texts)
+
+		shares, err := service.GetShares(bobCtx)
+		require.NoError(t, err)
+		assert.Empty(t, shares)
+
+		accesses, err := service.GetEmergencyAccess(bobCtx)
+		require.NoError(t, err)
+		assert.Empty(t, accesses)
+
+		orgs, err := service.GetOrganizations(aliceCtx)
+		require.NoError(t, err)
+		assert.Empty(t, orgs)
+
+		keys, err := service.GetUserKeys(bobCtx, "alice")
+		require.NoError(t, err)
+		assert.Nil(t, keys)
+
+		// Данные других пользователей не затронуты
+		texts, err = service.GetAllTexts(bobCtx)
+		require.NoError(t, err)
+		assert.Len(t, texts, 1)
+	})
+
+	t.Run("Статистика сервера", func(t *testing.T) {
+		stats, err := service.GetServerStats(adminCtx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.Users)
+		assert.Equal(t, 0, stats.DisabledUsers)
+		assert.Equal(t, 0, stats.Organizations)
+		assert.Equal(t, 0, stats.Shares)
+		assert.Equal(t, 1, stats.Usage.Texts)
+		assert.Positive(t, stats.QueueCapacity)
+	})
+
+	t.Run("Журнал действий администратора", func(t *testing.T) {
+		events, err := service.GetAuditLog(adminCtx, &dtos.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 6)
+		for _, event := range events {
+			assert.Equal(t, services.AdminActor, event.Actor)
+		}
+		assert.Equal(t, "list", events[0].Action)
+		assert.Equal(t, "delete", events[4].Action)
+		assert.Equal(t, "alice", events[4].UserID)
+		assert.Equal(t, "stats", events[5].EntityType)
+
+		events, err = service.GetAuditLog(adminCtx, &dtos.AuditFilter{UserID: "bob"})
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+
+		// В журнале пользователя действия администратора тоже видны
+		events, err = service.GetAuditLog(bobCtx, &dtos.AuditFilter{EntityType: "account"})
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+	})
+}
+
+// TestStorageService_OwnAccount тестирует выгрузку и удаление пользователем собственной учётной записи
+func TestStorageService_SessionCache(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	users := &countingGets[entities.User, dtos.NewUser]{IRepository: dbManager.Users}
+	service := services.NewStorageService(users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithAccounts(dbManager.Accounts))
+	defer service.Shutdown()
+
+	adminCtx := customcontext.WithAdmin(context.Background())
+
+	_, err := service.CreateUser(context.Background(), dtos.NewUser{Login: "bob", Password: "password"})
+	require.NoError(t, err)
+
+	t.Run("Повторная проверка не обращается к хранилищу", func(t *testing.T) {
+		before := users.gets.Load()
+		for i := 0; i < 3; i++ {
+			require.NoError(t, service.CheckSession(context.Background(), "bob", 0))
+		}
+		assert.Equal(t, before+1, users.gets.Load())
+	})
+
+	t.Run("Отзыв сессий сбрасывает кэш", func(t *testing.T) {
+		_, err := service.RevokeSessions(adminCtx, "bob")
+		require.NoError(t, err)
+
+		before := users.gets.Load()
+		assertHTTPCode(t, service.CheckSession(context.Background(), "bob", 0), 401)
+		require.NoError(t, service.CheckSession(context.Background(), "bob", 1))
+		assert.Equal(t, before+1, users.gets.Load())
+	})
+
+	t.Run("Блокировка сбрасывает кэш", func(t *testing.T) {
+		_, err := service.DisableAccount(adminCtx, "bob")
+		require.NoError(t, err)
+		assertHTTPCode(t, service.CheckSession(context.Background(), "bob", 2), 403)
+	})
+
+	t.Run("Удаление сбрасывает кэш", func(t *testing.T) {
+		_, err := service.EnableAccount(adminCtx, "bob")
+		require.NoError(t, err)
+		require.NoError(t, service.CheckSession(context.Background(), "bob", 2))
+
+		_, err = service.DeleteAccount(adminCtx, "bob")
+		require.NoError(t, err)
+		assertHTTPCode(t, service.CheckSession(context.Background(), "bob", 2), 401)
+	})
+}
+
+// countingGets - репозиторий, считающий чтения сущностей по ИД
+type countingGets[Entity any, DTO any] struct {
+	repositories.IRepository[Entity, DTO]
+	gets atomic.Int32
+}
+
+// Get - посчитать чтение и прочитать сущность
+func (r *countingGets[Entity, DTO]) Get(ctx context.Context, id string) (*Entity, error) {
+	r.gets.Add(1)
+	return r.IRepository.Get(ctx, id)
+}
+
+func TestStorageService_OwnAccount(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithAccounts(dbManager.Accounts))
+	defer service.Shutdown()
+
+	aliceCtx := createTestContext("alice")
+	bobCtx := createTestContext("bob")
+
+	for _, login := range []string{"alice", "bob"} {
+		_, err := service.CreateUser(context.Background(), dtos.NewUser{Login: login, Password: "password"})
+		require.NoError(t, err)
+		_, err = service.SetUserKeys(createTestContext(login), &entities.UserKeys{PublicKey: "public-" + login, EncryptedPrivateKey: "private-" + login})
+		require.NoError(t, err)
+	}
+
+	aliceText, err := service.CreateText(aliceCtx, &dtos.NewTextData{Data: "alice"})
+	require.NoError(t, err)
+	aliceText.EntryKey = "owner-envelope"
+	_, err = service.UpdateText(aliceCtx, aliceText)
+	require.NoError(t, err)
+	grant, err := service.ShareEntry(aliceCtx, &dtos.NewShareGrant{EntityType: "text", EntityID: aliceText.ID, RecipientID: "bob", Permission: entities.PermissionRead, EntryKey: "key"})
+	require.NoError(t, err)
+	require.NotNil(t, grant)
+	bobText, err := service.CreateText(bobCtx, &dtos.NewTextData{Data: "bob", NewSecureEntity: dtos.NewSecureEntity{Metadata: "note"}})
+	require.NoError(t, err)
+	_, err = service.CreateCard(bobCtx, &dtos.NewCardInformation{Number: "4111", CardHolder: "BOB", ExpirationDate: "12/30", CVV: "123"})
+	require.NoError(t, err)
+
+	t.Run("Выгрузка содержит только собственные записи", func(t *testing.T) {
+		export, err := service.ExportAccount(bobCtx)
+		require.NoError(t, err)
+
+		assert.Equal(t, "bob", export.Account.Login)
+		assert.Equal(t, 1, export.Account.Usage.Texts)
+		assert.Equal(t, 1, export.Account.Usage.Cards)
+		require.NotNil(t, export.Keys)
+		assert.Equal(t, "private-bob", export.Keys.EncryptedPrivateKey)
+		assert.Len(t, export.Shares, 1, "полученное право")
+		require.Len(t, export.Texts, 1)
+		assert.Equal(t, bobText.ID, export.Texts[0].ID)
+		assert.Len(t, export.Cards, 1)
+		assert.Empty(t, export.Binaries)
+		assert.False(t, export.ExportedAt.IsZero())
+	})
+
+	t.Run("Только собственная учётная запись", func(t *testing.T) {
+		_, err := service.DeleteAccount(bobCtx, "alice")
+		assertHTTPCode(t, err, 403)
+
+		_, err = service.DeleteOwnAccount(context.Background())
+		assertHTTPCode(t, err, 403)
+
+		user, err := service.GetUser(context.Background(), "alice")
+		require.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+
+	t.Run("Удаление вместе с данными", func(t *testing.T) {
+		account, err := service.DeleteOwnAccount(bobCtx)
+		require.NoError(t, err)
+		assert.Equal(t, "bob", account.Login)
+		assert.Equal(t, 1, account.Usage.Texts)
+
+		user, err := service.GetUser(context.Background(), "bob")
+		require.NoError(t, err)
+		assert.Nil(t, user)
+
+		usage, err := dbManager.Accounts.Usage(context.Background(), "bob")
+		require.NoError(t, err)
+		assert.Equal(t, entities.StorageUsage{}, *usage)
+
+		shares, err := service.GetShares(aliceCtx)
+		require.NoError(t, err)
+		assert.Empty(t, shares, "право, выданное удалённому пользователю")
+
+		text, err := service.GetText(aliceCtx, aliceText.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", text.Data)
+	})
+}
+
+func TestStorageService_Quotas(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithAccounts(dbManager.Accounts),
+		services.WithQuota(entities.Quota{MaxEntries: 2, MaxBytes: 100}))
+	defer service.Shutdown()
+
+	adminCtx := customcontext.WithAdmin(context.Background())
+	ownerCtx := createTestContext("owner")
+	writerCtx := createTestContext("writer")
+
+	for _, login := range []string{"owner", "writer"} {
+		_, err := service.CreateUser(context.Background(), dtos.NewUser{Login: login, Password: "password"})
+		require.NoError(t, err)
+		_, err = service.SetUserKeys(createTestContext(login), &entities.UserKeys{PublicKey: "public", EncryptedPrivateKey: "private"})
+		require.NoError(t, err)
+	}
+
+	// assertQuotaError - операция отклонена из-за квоты на ресурс
+	assertQuotaError := func(t *testing.T, err error, resource string) {
+		t.Helper()
+		assertHTTPCode(t, err, 403)
+
+		var quotaErr *customerrors.QuotaError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, resource, quotaErr.Resource)
+	}
+
+	first, err := service.CreateText(ownerCtx, &dtos.NewTextData{Data: "0123456789"})
+	require.NoError(t, err)
+	_, err = service.CreateText(ownerCtx, &dtos.NewTextData{Data: "0123456789"})
+	require.NoError(t, err)
+
+	t.Run("Количество записей каждого типа", func(t *testing.T) {
+		_, err := service.CreateText(ownerCtx, &dtos.NewTextData{Data: "third"})
+		assertQuotaError(t, err, "texts")
+
+		// Квота на количество действует для каждого типа отдельно
+		_, err = service.CreateCredentials(ownerCtx, &dtos.NewCredentials{Login: "login", Password: "password"})
+		require.NoError(t, err)
+	})
+
+	t.Run("Общий объём", func(t *testing.T) {
+		_, err := service.CreateBinary(ownerCtx, &dtos.NewBinaryData{Data: make([]byte, 100)})
+		assertQuotaError(t, err, "bytes")
+
+		// Уменьшение записи разрешено, увеличение сверх квоты - нет
+		first.Data = "0"
+		_, err = service.UpdateText(ownerCtx, first)
+		require.NoError(t, err)
+
+		first.Data = string(make([]byte, 90))
+		_, err = service.UpdateText(ownerCtx, first)
+		assertQuotaError(t, err, "bytes")
+	})
+
+	t.Run("Запись учитывается в квоте владельца", func(t *testing.T) {
+		// Делиться можно только записью, переведённой на ключ записи
+		first.Data = "0"
+		first.EntryKey = "owner-envelope"
+		_, err := service.UpdateText(ownerCtx, first)
+		require.NoError(t, err)
+
+		grant, err := service.ShareEntry(ownerCtx, &dtos.NewShareGrant{EntityType: "text", EntityID: first.ID, RecipientID: "writer", Permission: entities.PermissionWrite, EntryKey: "key"})
+		require.NoError(t, err)
+		require.NotNil(t, grant)
+		_, err = service.AcceptShare(writerCtx, grant.ID)
+		require.NoError(t, err)
+
+		shared := *first
+		shared.Data = string(make([]byte, 90))
+		_, err = service.UpdateText(writerCtx, &shared)
+		assertQuotaError(t, err, "bytes")
+
+		usage, err := service.GetUsage(writerCtx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, usage.Usage.Texts)
+	})
+
+	t.Run("Использование и квота пользователя", func(t *testing.T) {
+		usage, err := service.GetUsage(ownerCtx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, usage.Usage.Texts)
+		assert.Equal(t, 1, usage.Usage.Credentials)
+		assert.Equal(t, int64(1+10+len("login")+len("password")), usage.Usage.Bytes)
+		assert.Equal(t, entities.Quota{MaxEntries: 2, MaxBytes: 100}, usage.Quota)
+	})
+
+	t.Run("Индивидуальная квота", func(t *testing.T) {
+		_, err := service.SetQuota(ownerCtx, "owner", entities.Quota{})
+		assertHTTPCode(t, err, 403)
+		_, err = service.SetQuota(adminCtx, "owner", entities.Quota{MaxEntries: -1})
+		assertHTTPCode(t, err, 400)
+		_, err = service.SetQuota(adminCtx, "nobody", entities.Quota{})
+		assertHTTPCode(t, err, 404)
+
+		account, err := service.SetQuota(adminCtx, "owner", entities.Quota{MaxEntries: 3})
+		require.NoError(t, err)
+		require.NotNil(t, account.Quota)
+		assert.Equal(t, 3, account.Quota.MaxEntries)
+
+		// Нулевой объём в индивидуальной квоте - без ограничения
+		_, err = service.CreateText(ownerCtx, &dtos.NewTextData{Data: string(make([]byte, 200))})
+		require.NoError(t, err)
+		_, err = service.CreateText(ownerCtx, &dtos.NewTextData{Data: "fourth"})
+		assertQuotaError(t, err, "texts")
+
+		accounts, err := service.GetAccounts(adminCtx)
+		require.NoError(t, err)
+		require.NotNil(t, accounts[0].Quota)
+		assert.Nil(t, accounts[1].Quota)
+
+		account, err = service.ResetQuota(adminCtx, "owner")
+		require.NoError(t, err)
+		assert.Nil(t, account.Quota)
+
+		usage, err := service.GetUsage(ownerCtx)
+		require.NoError(t, err)
+		assert.Equal(t, entities.Quota{MaxEntries: 2, MaxBytes: 100}, usage.Quota)
+	})
+
+	t.Run("Без квоты по умолчанию объём не ограничен", func(t *testing.T) {
+		unlimited := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+			services.WithAccounts(dbManager.Accounts))
+		defer unlimited.Shutdown()
+
+		_, err := unlimited.CreateText(ownerCtx, &dtos.NewTextData{Data: "beyond"})
+		require.NoError(t, err)
+
+		usage, err := unlimited.GetUsage(ownerCtx)
+		require.NoError(t, err)
+		assert.Equal(t, entities.Quota{}, usage.Quota)
+	})
+}
+
+func TestStorageService_QuotasSealed(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	keys, err := envelope.NewLocalKeyManager(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	sealer := envelope.NewSealer(keys, dbManager.DataKeys)
+
+	service := services.NewStorageService(dbManager.Users,
+		envelope.Binaries(sealer, dbManager.Binaries, dbManager.Access),
+		envelope.Cards(sealer, dbManager.Cards, dbManager.Access),
+		envelope.Credentials(sealer, dbManager.Credentials, dbManager.Access),
+		envelope.Texts(sealer, dbManager.Texts, dbManager.Access),
+		dbManager.Access,
+		services.WithAccounts(envelope.Accounts(sealer, dbManager.Accounts)),
+		services.WithQuota(entities.Quota{MaxBytes: 150}))
+	defer service.Shutdown()
+
+	ownerCtx := createTestContext("owner")
+	_, err = service.CreateUser(context.Background(), dtos.NewUser{Login: "owner", Password: "password"})
+	require.NoError(t, err)
+
+	// assertBytesQuota - операция отклонена квотой на объём, изменение объёма посчитано по зашифрованным полям
+	assertBytesQuota := func(t *testing.T, err error, used, requested int64) {
+		t.Helper()
+		var quotaErr *customerrors.QuotaError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, "bytes", quotaErr.Resource)
+		assert.Equal(t, used, quotaErr.Used)
+		assert.Equal(t, requested, quotaErr.Requested)
+	}
+
+	// Открытые поля занимают 10 байт, зашифрованные - 106: данные 59 байт и пустое описание 47
+	text, err := service.CreateText(ownerCtx, &dtos.NewTextData{Data: "0123456789"})
+	require.NoError(t, err)
+
+	usage, err := dbManager.Accounts.Usage(context.Background(), "owner")
+	require.NoError(t, err)
+	require.Equal(t, int64(106), usage.Bytes)
+
+	t.Run("Создание", func(t *testing.T) {
+		_, err := service.CreateText(ownerCtx, &dtos.NewTextData{Data: "0"})
+		assertBytesQuota(t, err, 106, 94)
+	})
+
+	t.Run("Изменение", func(t *testing.T) {
+		// Открытые данные растут на 40 байт и уложились бы в квоту, зашифрованные - на 52
+		text.Data = strings.Repeat("0", 50)
+		_, err := service.UpdateText(ownerCtx, text)
+		assertBytesQuota(t, err, 106, 52)
+
+		text.Data = strings.Repeat("0", 40)
+		_, err = service.UpdateText(ownerCtx, text)
+		require.NoError(t, err)
+
+		usage, err := dbManager.Accounts.Usage(context.Background(), "owner")
+		require.NoError(t, err)
+		assert.Equal(t, int64(146), usage.Bytes)
+	})
+}
+
+// TestStorageService_Registration тестирует режимы регистрации и коды приглашений
+func TestStorageService_Registration(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	newService := func(mode string) *services.StorageService {
+		return services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+			services.WithRegistration(mode, dbManager.Invites))
+	}
+
+	adminCtx := customcontext.WithAdmin(context.Background())
+	register := func(service *services.StorageService, login, code string) error {
+		_, err := service.CreateUser(context.Background(), dtos.NewUser{Login: login, Password: "password", InviteCode: code})
+		return err
+	}
+
+	t.Run("Свободная регистрация", func(t *testing.T) {
+		service := newService(entities.RegistrationOpen)
+		defer service.Shutdown()
+
+		require.NoError(t, register(service, "open-user", ""))
+	})
+
+	t.Run("Регистрация закрыта", func(t *testing.T) {
+		service := newService(entities.RegistrationClosed)
+		defer service.Shutdown()
+
+		assertHTTPCode(t, register(service, "closed-user", ""), 403)
+
+		user, err := service.GetUser(context.Background(), "closed-user")
+		require.NoError(t, err)
+		assert.Nil(t, user)
+	})
+
+	service := newService(entities.RegistrationInvite)
+	defer service.Shutdown()
+
+	t.Run("Приглашения выпускает только администратор", func(t *testing.T) {
+		_, err := service.CreateInvite(createTestContext("open-user"), &dtos.NewInvite{})
+		assertHTTPCode(t, err, 403)
+
+		_, err = service.GetInvites(createTestContext("open-user"))
+		assertHTTPCode(t, err, 403)
+	})
+
+	t.Run("Проверка параметров приглашения", func(t *testing.T) {
+		_, err := service.CreateInvite(adminCtx, &dtos.NewInvite{MaxUses: -1})
+		assertHTTPCode(t, err, 400)
+
+		_, err = service.CreateInvite(adminCtx, &dtos.NewInvite{ExpiresAt: time.Now().Add(-time.Minute)})
+		assertHTTPCode(t, err, 400)
+
+		_, err = service.CreateInvite(adminCtx, &dtos.NewInvite{LoginPattern: "team-("})
+		assertHTTPCode(t, err, 400)
+	})
+
+	t.Run("Без кода и с неизвестным кодом", func(t *testing.T) {
+		assertHTTPCode(t, register(service, "stranger", ""), 403)
+		assertHTTPCode(t, register(service, "stranger", "unknown"), 403)
+	})
+
+	t.Run("Одноразовое приглашение", func(t *testing.T) {
+		invite, err := service.CreateInvite(adminCtx, &dtos.NewInvite{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, invite.MaxUses)
+		assert.Len(t, invite.Code, 32)
+		assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), invite.ExpiresAt, time.Minute)
+
+		require.NoError(t, register(service, "first", invite.Code))
+		assertHTTPCode(t, register(service, "second", invite.Code), 403)
+	})
+
+	t.Run("Многоразовое приглашение с шаблоном логина", func(t *testing.T) {
+		invite, err := service.CreateInvite(adminCtx, &dtos.NewInvite{MaxUses: 2, LoginPattern: "team-[a-z]+"})
+		require.NoError(t, err)
+
+		assertHTTPCode(t, register(service, "outsider", invite.Code), 403)
+		assertHTTPCode(t, register(service, "xteam-a", invite.Code), 403)
+		require.NoError(t, register(service, "team-a", invite.Code))
+		require.NoError(t, register(service, "team-b", invite.Code))
+		assertHTTPCode(t, register(service, "team-c", invite.Code), 403)
+
+		invites, err := service.GetInvites(adminCtx)
+		require.NoError(t, err)
+		for _, listed := range invites {
+			if listed.Code == invite.Code {
+				assert.Equal(t, 2, listed.Uses)
+			}
+		}
+	})
+
+	t.Run("Отозванное приглашение", func(t *testing.T) {
+		invite, err := service.CreateInvite(adminCtx, &dtos.NewInvite{MaxUses: 5})
+		require.NoError(t, err)
+
+		_, err = service.DeleteInvite(adminCtx, invite.Code)
+		require.NoError(t, err)
+		_, err = service.DeleteInvite(adminCtx, invite.Code)
+		assertHTTPCode(t, err, 404)
+
+		assertHTTPCode(t, register(service, "late", invite.Code), 403)
+	})
+
+	t.Run("Отказ не расходует приглашение", func(t *testing.T) {
+		invite, err := service.CreateInvite(adminCtx, &dtos.NewInvite{})
+		require.NoError(t, err)
+
+		assertHTTPCode(t, register(service, "first", invite.Code), 409)
+		require.NoError(t, register(service, "third", invite.Code))
+	})
+
+	t.Run("Ошибка создания пользователя не расходует приглашение", func(t *testing.T) {
+		invite, err := service.CreateInvite(adminCtx, &dtos.NewInvite{})
+		require.NoError(t, err)
+
+		users := &failingCreates[entities.User, dtos.NewUser]{IRepository: dbManager.Users, err: errors.New("create failed")}
+		failing := services.NewStorageService(users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+			services.WithRegistration(entities.RegistrationInvite, dbManager.Invites))
+		defer failing.Shutdown()
+
+		assert.ErrorIs(t, register(failing, "fourth", invite.Code), users.err)
+		require.NoError(t, register(service, "fourth", invite.Code))
+	})
+}
+
+// failingCreates - репозиторий, создание сущностей в котором завершается ошибкой err
+type failingCreates[Entity any, DTO any] struct {
+	repositories.IRepository[Entity, DTO]
+	err error
+}
+
+// Create - вернуть err
+func (r *failingCreates[Entity, DTO]) Create(ctx context.Context, dto *DTO) (*Entity, error) {
+	return nil, r.err
+}
+
+// TestStorageService_Trash тестирует корзину: мягкое удаление, восстановление, окончательное удаление и очистку по сроку хранения
+func TestStorageService_Trash(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
+		services.WithTrash(dbManager.Trash, time.Hour, 0))
+	defer service.Shutdown()
+
+	testData := createTestData()
+	ownerCtx := createTestContext("owner")
+	strangerCtx := createTestContext("stranger")
+
+	text, err := service.CreateText(ownerCtx, &testData.Text)
+	require.NoError(t, err)
+
+	t.Run("Удалённая запись попадает в корзину", func(t *testing.T) {
+		_, err := service.DeleteText(ownerCtx, text.ID)
+		require.NoError(t, err)
+
+		texts, err := service.GetAllTexts(ownerCtx)
+		require.NoError(t, err)
+		assert.Empty(t, texts)
+
+		// Повторно удалить запись из корзины нельзя
+		deleted, err := service.DeleteText(ownerCtx, text.ID)
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+
+		items, err := service.GetTrash(ownerCtx)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, text.ID, items[0].ID)
+		assert.Equal(t, "text", items[0].EntityType)
+		assert.Equal(t, "text metadata", items[0].Metadata)
+		assert.False(t, items[0].DeletedAt.IsZero())
+
+		items, err = service.GetTrash(strangerCtx)
+		require.NoError(t, err)
+		assert.Empty(t, items)
+	})
+
+	t.Run("Восстановление", func(t *testing.T) {
+		restored, err := service.RestoreTrashItem(strangerCtx, services.EntityText, text.ID)
+		require.NoError(t, err)
+		assert.Nil(t, restored)
+
+		_, err = service.RestoreTrashItem(ownerCtx, services.EntityUser, text.ID)
+		assertHTTPCode(t, err, 400)
+
+		restored, err = service.RestoreTrashItem(ownerCtx, services.EntityText, text.ID)
+		require.NoError(t, err)
+		require.NotNil(t, restored)
+
+		got, err := service.GetText(ownerCtx, text.ID)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, testData.Text.Data, got.Data)
+
+		items, err := service.GetTrash(ownerCtx)
+		require.NoError(t, err)
+		assert.Empty(t, items)
+	})
+
+	t.Run("Окончательное удаление", func(t *testing.T) {
+		_, err := service.DeleteText(ownerCtx, text.ID)
+		require.NoError(t, err)
+
+		purged, err := service.PurgeTrashItem(strangerCtx, services.EntityText, text.ID)
+		require.NoError(t, err)
+		assert.Nil(t, purged)
+
+		purged, err = service.PurgeTrashItem(ownerCtx, services.EntityText, text.ID)
+		require.NoError(t, err)
+		require.NotNil(t, purged)
+
+		restored, err := service.RestoreTrashItem(ownerCtx, services.EntityText, text.ID)
+		require.NoError(t, err)
+		assert.Nil(t, restored)
+
+		// Восстановление и окончательное удаление попадают в историю записи
+		events, err := service.GetAuditLog(ownerCtx, &dtos.AuditFilter{EntityType: "text"})
+		require.NoError(t, err)
+		var actions []string
+		for _, event := range events {
+			if event.EntityID == text.ID && event.Action != "read" {
+				actions = append(actions, event.Action)
+			}
+		}
+		assert.Equal(t, []string{"create", "delete", "restore", "delete", "purge"}, actions)
+	})
+
+	t.Run("Очистка по сроку хранения", func(t *testing.T) {
+		card, err := service.CreateCard(ownerCtx, &testData.Card)
+		require.NoError(t, err)
+		_, err = service.DeleteCard(ownerCtx, card.ID)
+		require.NoError(t, err)
+
+		purged, err := service.PurgeExpiredTrash(context.Background(), time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, purged, "retention has not elapsed yet")
+
+		purged, err = service.PurgeExpiredTrash(context.Background(), time.Now().Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+		assert.Equal(t, card.ID, purged[0].ID)
+
+		items, err := service.GetTrash(ownerCtx)
+		require.NoError(t, err)
+		assert.Empty(t, items)
+
+		// Владелец узнаёт об очистке из журнала
+		events, err := service.GetAuditLog(ownerCtx, &dtos.AuditFilter{EntityType: "card"})
+		require.NoError(t, err)
+		require.NotEmpty(t, events)
+		assert.Equal(t, "purge", events[len(events)-1].Action)
+	})
+
+	t.Run("Без поддержки корзины", func(t *testing.T) {
+		plain, _ := createTestService()
+		defer plain.Shutdown()
+
+		_, err := plain.GetTrash(ownerCtx)
+		assertHTTPCode(t, err, 501)
+	})
+}
+
+// TestStorageService_History тестирует историю изменений записей и восстановление прежних версий
+func TestStorageService_History(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
+		services.WithSharing(dbManager.UserKeys, dbManager.Shares),
+		services.WithHistory(dbManager.History, 3, time.Hour))
+	defer service.Shutdown()
+
+	testData := createTestData()
+	ownerCtx := createTestContext("owner")
+	recipientCtx := createTestContext("recipient")
+	strangerCtx := createTestContext("stranger")
+
+	creds, err := service.CreateCredentials(ownerCtx, &testData.Credentials)
+	require.NoError(t, err)
+
+	setPassword := func(ctx context.Context, password string) *entities.Credentials {
+		t.Helper()
+		current, err := service.GetCredentials(ctx, creds.ID)
+		require.NoError(t, err)
+		require.NotNil(t, current)
+		current.Password = password
+		updated, err := service.UpdateCredentials(ctx, current)
+		require.NoError(t, err)
+		require.NotNil(t, updated)
+		return updated
+	}
+
+	// passwordOf - пароль, сохранённый в версии
+	passwordOf := func(revision entities.Revision) string {
+		t.Helper()
+		var content entities.Credentials
+		require.NoError(t, json.Unmarshal(revision.Content, &content))
+		return content.Password
+	}
+
+	t.Run("Изменение сохраняет прежнюю версию", func(t *testing.T) {
+		revisions, err := service.GetHistory(ownerCtx, services.EntityCredentials, creds.ID)
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+
+		setPassword(ownerCtx, "password-1")
+		setPassword(ownerCtx, "password-2")
+
+		revisions, err = service.GetHistory(ownerCtx, services.EntityCredentials, creds.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, 2, revisions[0].Revision)
+		assert.Equal(t, "password-1", passwordOf(revisions[0]))
+		assert.Equal(t, testData.Credentials.Password, passwordOf(revisions[1]))
+		assert.Equal(t, "credentials metadata", revisions[1].Metadata)
+		assert.Equal(t, "owner", revisions[0].ReplacedBy)
+		assert.True(t, revisions[0].Restorable)
+	})
+
+	t.Run("Ограничение числа версий", func(t *testing.T) {
+		setPassword(ownerCtx, "password-3")
+		setPassword(ownerCtx, "password-4")
+
+		revisions, err := service.GetHistory(ownerCtx, services.EntityCredentials, creds.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 3)
+		assert.Equal(t, []int{4, 3, 2}, []int{revisions[0].Revision, revisions[1].Revision, revisions[2].Revision})
+	})
+
+	t.Run("Восстановление версии", func(t *testing.T) {
+		restored, err := service.RestoreRevision(ownerCtx, services.EntityCredentials, creds.ID, 2)
+		require.NoError(t, err)
+		require.IsType(t, &entities.Credentials{}, restored)
+		assert.Equal(t, "password-1", restored.(*entities.Credentials).Password)
+
+		got, err := service.GetCredentials(ownerCtx, creds.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "password-1", got.Password)
+		assert.Equal(t, testData.Credentials.Login, got.Login)
+
+		// Заменённое восстановлением состояние тоже попадает в историю
+		revisions, err := service.GetHistory(ownerCtx, services.EntityCredentials, creds.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, revisions)
+		assert.Equal(t, 5, revisions[0].Revision)
+		assert.Equal(t, "password-4", passwordOf(revisions[0]))
+
+		// Восстановление попадает в журнал как действие над самой записью
+		events, err := service.GetAuditLog(ownerCtx, &dtos.AuditFilter{EntityType: "credentials"})
+		require.NoError(t, err)
+		var restores []string
+		for _, event := range events {
+			if event.Action == "restore" {
+				restores = append(restores, event.EntityID)
+			}
+		}
+		assert.Equal(t, []string{creds.ID}, restores)
+	})
+
+	t.Run("Некорректные запросы", func(t *testing.T) {
+		_, err := service.GetHistory(strangerCtx, services.EntityCredentials, creds.ID)
+		assertHTTPCode(t, err, 404)
+
+		_, err = service.RestoreRevision(strangerCtx, services.EntityCredentials, creds.ID, 2)
+		assertHTTPCode(t, err, 404)
+
+		// Версия удалена ограничением числа версий
+		_, err = service.RestoreRevision(ownerCtx, services.EntityCredentials, creds.ID, 1)
+		assertHTTPCode(t, err, 404)
+
+		_, err = service.GetHistory(ownerCtx, services.EntityUser, creds.ID)
+		assertHTTPCode(t, err, 400)
+	})
+
+	t.Run("Смена ключа записи", func(t *testing.T) {
+		for _, ctx := range []context.Context{ownerCtx, recipientCtx} {
+			_, err := service.SetUserKeys(ctx, &entities.UserKeys{PublicKey: "public", EncryptedPrivateKey: "private"})
+			require.NoError(t, err)
+		}
+
+		current, err := service.GetCredentials(ownerCtx, creds.ID)
+		require.NoError(t, err)
+		current.EntryKey = "owner-envelope"
+		_, err = service.UpdateCredentials(ownerCtx, current)
+		require.NoError(t, err)
+
+		// Версии, зашифрованные ключом хранилища владельца, сервер восстановить не может
+		revisions, err := service.GetHistory(ownerCtx, services.EntityCredentials, creds.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, revisions)
+		assert.False(t, revisions[0].Restorable)
+		assert.Empty(t, revisions[0].EntryKey)
+
+		_, err = service.RestoreRevision(ownerCtx, services.EntityCredentials, creds.ID, revisions[0].Revision)
+		assertHTTPCode(t, err, 409)
+
+		grant, err := service.ShareEntry(ownerCtx, &dtos.NewShareGrant{EntityType: "credentials", EntityID: creds.ID, RecipientID: "recipient",
+			Permission: entities.PermissionWrite, EntryKey: "recipient-envelope"})
+		require.NoError(t, err)
+		_, err = service.AcceptShare(recipientCtx, grant.ID)
+		require.NoError(t, err)
+
+		// Получатель видит только версии, зашифрованные текущим ключом записи
+		revisions, err = service.GetHistory(recipientCtx, services.EntityCredentials, creds.ID)
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+
+		setPassword(recipientCtx, "recipient-password")
+
+		revisions, err = service.GetHistory(recipientCtx, services.EntityCredentials, creds.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "recipient-envelope", revisions[0].EntryKey)
+		assert.Equal(t, "recipient", revisions[0].ReplacedBy)
+		assert.True(t, revisions[0].Restorable)
+
+		_, err = service.RestoreRevision(recipientCtx, services.EntityCredentials, creds.ID, revisions[0].Revision)
+		require.NoError(t, err)
+
+		// Восстановление не меняет ключ записи
+		got, err := service.GetCredentials(ownerCtx, creds.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "owner-envelope", got.EntryKey)
+		assert.Equal(t, "password-1", got.Password)
+	})
+
+	t.Run("Без поддержки истории", func(t *testing.T) {
+		plain, _ := createTestService()
+		defer plain.Shutdown()
+
+		_, err := plain.GetHistory(ownerCtx, services.EntityCredentials, creds.ID)
+		assertHTTPCode(t, err, 501)
+	})
+}
+
+// failingUpdates - репозиторий, изменение записей в котором завершается ошибкой err (nil - изменения проходят)
+type failingUpdates[Entity any, DTO any] struct {
+	repositories.IRepository[Entity, DTO]
+	err error
+}
+
+// Update - изменить запись или вернуть err
+func (r *failingUpdates[Entity, DTO]) Update(ctx context.Context, entity *Entity) (*Entity, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.IRepository.Update(ctx, entity)
+}
+
+// TestStorageService_HistoryFailedUpdate - неудачное изменение записи не добавляет версию и не удаляет прежние
+func TestStorageService_HistoryFailedUpdate(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	texts := &failingUpdates[entities.TextData, dtos.NewTextData]{IRepository: dbManager.Texts}
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, texts, dbManager.Access,
+		services.WithHistory(dbManager.History, 1, 0))
+	defer service.Shutdown()
+
+	ctx := createTestContext("owner")
+	text, err := service.CreateText(ctx, &dtos.NewTextData{Data: "first"})
+	require.NoError(t, err)
+
+	text.Data = "second"
+	text, err = service.UpdateText(ctx, text)
+	require.NoError(t, err)
+
+	before, err := service.GetHistory(ctx, services.EntityText, text.ID)
+	require.NoError(t, err)
+	require.Len(t, before, 1)
+
+	texts.err = errors.New("update failed")
+	text.Data = "third"
+	_, err = service.UpdateText(ctx, text)
+	assert.ErrorIs(t, err, texts.err)
+
+	after, err := service.GetHistory(ctx, services.EntityText, text.ID)
+	require.NoError(t, err)
+	assert.Equal(t, before, after, "версия сверх ограничения не вытеснила сохранённую")
+}
+
+// assertHTTPCode проверяет, что операция завершилась ошибкой с указанным HTTP-кодом
+func assertHTTPCode(t *testing.T, err error, code int) {
+	t.Helper()
+
+	var httpErr *customerrors.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, code, httpErr.Code)
+}
+
+func TestStorageService_EntryIDs(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithTrash(dbManager.Trash, time.Hour, 0))
+	defer service.Shutdown()
+
+	testData := createTestData()
+	ownerCtx := createTestContext("owner")
+	strangerCtx := createTestContext("stranger")
+
+	t.Run("Без идентификатора клиента сервер выдаёт UUIDv7", func(t *testing.T) {
+		dto := testData.Text
+		text, err := service.CreateText(ownerCtx, &dto)
+		require.NoError(t, err)
+
+		id, err := uuid.Parse(text.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), id.Version())
+		assert.Empty(t, dto.ID, "dto вызывающей стороны не меняется")
+	})
+
+	t.Run("Идентификатор клиента сохраняется", func(t *testing.T) {
+		dto := testData.Card
+		dto.ID = uuid.Must(uuid.NewV7()).String()
+
+		card, err := service.CreateCard(ownerCtx, &dto)
+		require.NoError(t, err)
+		assert.Equal(t, dto.ID, card.ID)
+
+		stored, err := service.GetCard(ownerCtx, dto.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		assert.Equal(t, testData.Card.Number, stored.Number)
+	})
+
+	t.Run("Идентификатор не UUID отклоняется", func(t *testing.T) {
+		dto := testData.Credentials
+		dto.ID = "42"
+
+		_, err := service.CreateCredentials(ownerCtx, &dto)
+		var httpErr *customerrors.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, 400, httpErr.Code)
+
+		// Запросы с таким идентификатором просто не находят запись
+		creds, err := service.GetCredentials(ownerCtx, "42")
+		require.NoError(t, err)
+		assert.Nil(t, creds)
+	})
+
+	t.Run("Занятый идентификатор отклоняется", func(t *testing.T) {
+		dto := testData.Text
+		dto.ID = uuid.Must(uuid.NewV7()).String()
+
+		text, err := service.CreateText(ownerCtx, &dto)
+		require.NoError(t, err)
+
+		// Идентификатор занят и для других пользователей
+		_, err = service.CreateText(strangerCtx, &dto)
+		var httpErr *customerrors.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, 409, httpErr.Code)
+
+		// и пока запись лежит в корзине
+		_, err = service.DeleteText(ownerCtx, text.ID)
+		require.NoError(t, err)
+		_, err = service.CreateText(ownerCtx, &dto)
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, 409, httpErr.Code)
+
+		// Тот же идентификатор допустим для записи другого типа
+		binary := testData.Binary
+		binary.ID = dto.ID
+		_, err = service.CreateBinary(ownerCtx, &binary)
+		require.NoError(t, err)
+	})
+}
+
+// taskTransactor - транзакции задач для проверки сервиса: считает задачи и может не зафиксировать транзакцию
+type taskTransactor struct {
+	tasks     int
+	commitErr error
+}
+
+// InTransaction - выполнить fn и "зафиксировать" транзакцию с ошибкой commitErr
+func (t *taskTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.tasks++
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return t.commitErr
+}
+
+// TestStorageService_Transactions - каждая задача выполняется в транзакции, и ошибка её фиксации отдаётся вызывающему
+func TestStorageService_Transactions(t *testing.T) {
+	dbManager := inmemory.NewDatabaseManager()
+	transactor := &taskTransactor{}
+	service := services.NewStorageService(dbManager.Users, dbManager.Binaries, dbManager.Cards, dbManager.Credentials, dbManager.Texts, dbManager.Access,
+		services.WithAuditRepo(dbManager.Audit),
+		services.WithTransactor(transactor))
+	defer service.Shutdown()
+
+	ctx := createTestContext("alice")
+	_, err := service.CreateText(ctx, &dtos.NewTextData{Data: "note"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, transactor.tasks)
+
+	transactor.commitErr = fmt.Errorf("commit failed")
+	_, err = service.CreateText(ctx, &dtos.NewTextData{Data: "lost"})
+	assert.ErrorIs(t, err, transactor.commitErr)
+	assert.Equal(t, 2, transactor.tasks)
+
+	// Событие аудита пишется только после фиксации транзакции
+	transactor.commitErr = nil
+	events, err := service.GetAuditLog(ctx, &dtos.AuditFilter{})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
@@ -0,0 +1,71 @@
+// Пакет tracing содержит инициализацию OpenTelemetry и middleware для трассировки запросов
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/customcontext"
+	"github.com/go-chi/chi"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDAttribute - атрибут спана с идентификатором запроса (X-Request-ID)
+const RequestIDAttribute = attribute.Key("request.id")
+
+// HTTPMiddleware - middleware, создающее корневой спан для каждого входящего запроса.
+// Должно подключаться после middleware идентификатора запроса
+func HTTPMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			ctx, span := Tracer().Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					RequestIDAttribute.String(customcontext.GetRequestID(r.Context())),
+				),
+			)
+			defer span.End()
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			// Шаблон маршрута известен только после маршрутизации
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil {
+				if pattern := routeCtx.RoutePattern(); pattern != "" {
+					span.SetName(r.Method + " " + pattern)
+					span.SetAttributes(semconv.HTTPRoute(pattern))
+				}
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+			if rw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rw.status))
+			}
+		})
+	}
+}
+
+// responseWriter - обертка (встраивание) для ResponseWriter, захватывающая код ответа
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader - отправить HTTP-заголовок и захватить код статуса
+func (r *responseWriter) WriteHeader(statusCode int) {
+	r.ResponseWriter.WriteHeader(statusCode)
+	r.status = statusCode
+}
+
+// Unwrap - исходный ResponseWriter (позволяет http.ResponseController добраться до Flush и других возможностей)
+func (r *responseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,121 @@
+// tracing_test - тесты экспорта трасс (приёмник OTLP подменяется тестовым HTTP-сервером)
+package tracing_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/backend/internal/middleware/requestid"
+	"github.com/JustScorpio/GophKeeper/backend/internal/tracing"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector - заглушка приёмника OTLP/HTTP, сохраняющая полученные спаны
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracev1.Span
+}
+
+// ServeHTTP - принять выгрузку спанов
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var request collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, resourceSpans := range request.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			c.spans = append(c.spans, scopeSpans.Spans...)
+		}
+	}
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+// spanByName - найти спан по имени
+func (c *collector) spanByName(name string) *tracev1.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+// attributeValue - получить строковое значение атрибута спана
+func attributeValue(span *tracev1.Span, key string) string {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestTracingExport(t *testing.T) {
+	stub := &collector{}
+	collectorServer := httptest.NewServer(stub)
+	defer collectorServer.Close()
+
+	shutdown, err := tracing.Init(context.Background(), collectorServer.URL, "gophkeeper-test", "test")
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(requestid.RequestIDMiddleware())
+	router.Use(tracing.HTTPMiddleware())
+	router.Get("/api/user/texts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Tracer().Start(r.Context(), "storage.process")
+		span.End()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/texts/42", nil)
+	req.Header.Set(requestid.Header, "test-request-id")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Завершение выгружает накопленные спаны
+	require.NoError(t, shutdown(context.Background()))
+
+	root := stub.spanByName("GET /api/user/texts/{id}")
+	require.NotNil(t, root, "root span was not exported")
+	assert.Equal(t, "test-request-id", attributeValue(root, "request.id"))
+	assert.Equal(t, "/api/user/texts/{id}", attributeValue(root, "http.route"))
+
+	child := stub.spanByName("storage.process")
+	require.NotNil(t, child, "child span was not exported")
+	assert.Equal(t, root.TraceId, child.TraceId)
+	assert.Equal(t, root.SpanId, child.ParentSpanId)
+}
+
+func TestTracingDisabled(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), "", "gophkeeper-test", "test")
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
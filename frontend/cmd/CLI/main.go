@@ -10,6 +10,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -36,6 +37,9 @@ var configContent []byte
 type AppConfiguration struct {
 	DbPath     string `json:"db_path"`
 	ServerAddr string `json:"server_addr"`
+	CACertPath string `json:"ca_cert_path"`
+	DeviceID   string `json:"device_id"`
+	LogPath    string `json:"log_path"`
 }
 
 // App - приложение
@@ -47,10 +51,21 @@ type App struct {
 	appService   *services.GophkeeperService
 	isLoggedIn   bool
 	currentUser  string
+
+	logger       *log.Logger        // лог запросов к серверу (nil, если отключён)
+	stopWatching context.CancelFunc // остановка фонового получения изменений с сервера
 }
 
 // main - точка входа
 func main() {
+	// Подкоманда migrate работает с локальной базой данных без запуска интерактивного клиента
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	fmt.Printf("%s v.%s %s\n", "GophKeeper", buildVersion, buildDate)
 	fmt.Println("==========================")
 
@@ -78,285 +93,1430 @@ func main() {
 	app.run(ctx)
 }
 
-// initializeApp - инициализация приложения
-func initializeApp() (*App, error) {
-	var conf AppConfiguration
-	if err := json.Unmarshal(configContent, &conf); err != nil {
-		return nil, fmt.Errorf("failed to decode config: %w", err)
+// loadConfiguration - конфигурация из встроенного config.json
+func loadConfiguration() (AppConfiguration, error) {
+	var conf AppConfiguration
+	if err := json.Unmarshal(configContent, &conf); err != nil {
+		return conf, fmt.Errorf("failed to decode config: %w", err)
+	}
+	return conf, nil
+}
+
+// initializeApp - инициализация приложения
+func initializeApp() (*App, error) {
+	conf, err := loadConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Using database: %s\n", conf.DbPath)
+	fmt.Printf("Connecting to server: %s\n", conf.ServerAddr)
+
+	// Инициализация базы данных
+	dbManager, err := sqlite.NewDatabaseManager(conf.DbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// Инициализация сервисов
+	apiClient := clients.NewAPIClient(conf.ServerAddr)
+
+	// Доверенный CA (например, сгенерированный сервером для разработки)
+	if conf.CACertPath != "" {
+		fmt.Printf("Trusting CA certificate: %s\n", conf.CACertPath)
+		if err := apiClient.SetCACertificate(conf.CACertPath); err != nil {
+			return nil, fmt.Errorf("failed to load CA certificate: %w", err)
+		}
+	}
+
+	// Идентификатор устройства попадает в журнал операций на сервере (по умолчанию - имя хоста)
+	deviceID := conf.DeviceID
+	if deviceID == "" {
+		deviceID, _ = os.Hostname()
+	}
+	apiClient.SetDeviceID(deviceID)
+
+	// Лог запросов к серверу (с идентификаторами запросов для сопоставления с логами сервера)
+	var logger *log.Logger
+	if conf.LogPath != "" {
+		logFile, err := os.OpenFile(conf.LogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		logger = log.New(logFile, "", log.LstdFlags)
+		apiClient.SetLogger(logger)
+	}
+
+	localStorage := services.NewStorageService(
+		dbManager.BinariesRepo,
+		dbManager.CardsRepo,
+		dbManager.CredentialsRepo,
+		dbManager.TextsRepo,
+	)
+
+	syncService := services.NewSyncService(apiClient, localStorage)
+
+	appService := services.NewGophkeeperService(apiClient, localStorage, syncService)
+
+	return &App{
+		dbManager:    dbManager,
+		apiClient:    apiClient,
+		localStorage: localStorage,
+		syncService:  syncService,
+		appService:   appService,
+		logger:       logger,
+	}, nil
+}
+
+// gracefulShutdown - завершение работы приложения
+func (a *App) gracefulShutdown() {
+	a.stopWatchingChanges()
+	if a.dbManager != nil {
+		a.dbManager.Close()
+	}
+	fmt.Println("\nGoodbye!")
+}
+
+// run - запуск приложения
+func (a *App) run(ctx context.Context) {
+	reader := bufio.NewReader(os.Stdin)
+
+	for {
+		// Проверяем, не отменен ли контекст
+		select {
+		case <-ctx.Done():
+			fmt.Println("Shutdown initiated, exiting...")
+			return
+		default:
+		}
+
+		a.showMainMenu()
+
+		fmt.Print("\n> ")
+		input, err := a.readInputWithContext(reader, ctx)
+		if err != nil {
+			fmt.Printf("Error reading input: %v\n", err)
+			continue
+		}
+
+		input = strings.TrimSpace(input)
+
+		switch input {
+		case "1":
+			a.handleLogin(reader, ctx)
+		case "2":
+			a.handleRegister(reader, ctx)
+		case "3":
+			if a.isLoggedIn {
+				a.handleDataMenu(reader, ctx)
+			} else {
+				fmt.Println("Please login first!")
+			}
+		case "4":
+			if a.isLoggedIn {
+				a.handleSync(ctx)
+			} else {
+				fmt.Println("Please login first!")
+			}
+		case "5":
+			if a.isLoggedIn {
+				a.handleActivity(reader, ctx)
+			} else {
+				fmt.Println("Please login first!")
+			}
+		case "6":
+			if a.isLoggedIn {
+				a.handleSharing(reader, ctx)
+			} else {
+				fmt.Println("Please login first!")
+			}
+		case "7":
+			if a.isLoggedIn {
+				a.handleOrganizations(reader, ctx)
+			} else {
+				fmt.Println("Please login first!")
+			}
+		case "8":
+			if a.isLoggedIn {
+				a.handleEmergencyAccess(reader, ctx)
+			} else {
+				fmt.Println("Please login first!")
+			}
+		case "9":
+			if a.isLoggedIn {
+				a.handleTrash(reader, ctx)
+			} else {
+				fmt.Println("Please login first!")
+			}
+		case "10":
+			if a.isLoggedIn {
+				a.handleAccount(reader, ctx)
+			} else {
+				fmt.Println("Please login first!")
+			}
+		case "11":
+			if a.isLoggedIn {
+				a.handleLogout()
+			} else {
+				fmt.Println("You are not logged in!")
+			}
+		case "12":
+			fmt.Println("Exiting...")
+			return
+		case "help":
+			a.showHelp()
+		default:
+			fmt.Println("Unknown command. Type 'help' for available commands.")
+		}
+	}
+}
+
+// readInputWithContext - чтение ввода с поддержкой контекста
+func (a *App) readInputWithContext(reader *bufio.Reader, ctx context.Context) (string, error) {
+	inputChan := make(chan string, 1)
+	errChan := make(chan error, 1)
+
+	// Читаем ввод в отдельной горутине
+	go func() {
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			errChan <- err
+			return
+		}
+		inputChan <- input
+	}()
+
+	// Ждем либо ввод, либо отмену контекста
+	select {
+	case <-ctx.Done():
+		return "", context.Canceled
+	case err := <-errChan:
+		return "", err
+	case input := <-inputChan:
+		return input, nil
+	}
+}
+
+// showMainMenu - вывод главного меню
+func (a *App) showMainMenu() {
+	fmt.Println("\n=== Main Menu ===")
+	if a.isLoggedIn {
+		fmt.Printf("Logged in as: %s\n", a.currentUser)
+		fmt.Println("1. Login (switch user)")
+		fmt.Println("2. Register")
+		fmt.Println("3. Manage Data")
+		fmt.Println("4. Sync Data")
+		fmt.Println("5. Activity")
+		fmt.Println("6. Sharing")
+		fmt.Println("7. Organizations")
+		fmt.Println("8. Emergency access")
+		fmt.Println("9. Trash")
+		fmt.Println("10. Account")
+		fmt.Println("11. Logout")
+		fmt.Println("12. Exit")
+	} else {
+		fmt.Println("1. Login")
+		fmt.Println("2. Register")
+		fmt.Println("3. Manage Data (requires login)")
+		fmt.Println("4. Sync Data (requires login)")
+		fmt.Println("5. Activity (requires login)")
+		fmt.Println("6. Sharing (requires login)")
+		fmt.Println("7. Organizations (requires login)")
+		fmt.Println("8. Emergency access (requires login)")
+		fmt.Println("9. Trash (requires login)")
+		fmt.Println("10. Account (requires login)")
+		fmt.Println("11. Logout")
+		fmt.Println("12. Exit")
+	}
+}
+
+// showHelp - вывод подсказки
+func (a *App) showHelp() {
+	fmt.Println("\n=== Available Commands ===")
+	fmt.Println("login    - Login to your account")
+	fmt.Println("register - Create a new account")
+	fmt.Println("data     - Manage your data (binaries, cards, etc.)")
+	fmt.Println("sync     - Synchronize data with server and show storage usage")
+	fmt.Println("activity - Show history of operations with your data")
+	fmt.Println("sharing  - Share entries with other users, accept or revoke access")
+	fmt.Println("orgs     - Organizations: members, roles and shared collections")
+	fmt.Println("emergency - Trusted contacts who can request access to your vault")
+	fmt.Println("trash    - Restore deleted entries or delete them permanently")
+	fmt.Println("account  - Export all your data or delete your account")
+	fmt.Println("logout   - Logout from current account")
+	fmt.Println("exit     - Exit the application")
+	fmt.Println("help     - Show this help message")
+}
+
+// handleLogin - обработка аутентификации в приложении
+func (a *App) handleLogin(reader *bufio.Reader, ctx context.Context) {
+	// Проверяем, не отменен ли контекст
+	select {
+	case <-ctx.Done():
+		fmt.Println("Operation cancelled due to shutdown")
+		return
+	default:
+	}
+
+	fmt.Println("\n=== Login ===")
+
+	fmt.Print("Username: ")
+	username, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	username = strings.TrimSpace(username)
+
+	fmt.Print("Password: ")
+	password, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	password = strings.TrimSpace(password)
+
+	// Используем контекст с таймаутом, но связанный с общим контекстом
+	loginCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fmt.Print("Logging in... ")
+	err = a.appService.Login(loginCtx, username, password)
+	if err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+
+	a.isLoggedIn = true
+	a.currentUser = username
+	a.startWatchingChanges(ctx)
+	fmt.Println("SUCCESS")
+	fmt.Printf("Welcome, %s!\n", username)
+}
+
+// handleRegister - обработка регистрации в приложении
+func (a *App) handleRegister(reader *bufio.Reader, ctx context.Context) {
+	// Проверяем, не отменен ли контекст
+	select {
+	case <-ctx.Done():
+		fmt.Println("Operation cancelled due to shutdown")
+		return
+	default:
+	}
+
+	fmt.Println("\n=== Register ===")
+
+	fmt.Print("Username: ")
+	username, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	username = strings.TrimSpace(username)
+
+	fmt.Print("Password: ")
+	password, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	password = strings.TrimSpace(password)
+
+	fmt.Print("Invitation code (empty if not required): ")
+	inviteCode, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	inviteCode = strings.TrimSpace(inviteCode)
+
+	registerCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fmt.Print("Registering... ")
+	err = a.appService.Register(registerCtx, username, password, inviteCode)
+	if err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+
+	a.isLoggedIn = true
+	a.currentUser = username
+	a.startWatchingChanges(ctx)
+	fmt.Println("SUCCESS")
+	fmt.Printf("Account created. Welcome, %s!\n", username)
+}
+
+// handleLogout - обработка выхода из приложении
+func (a *App) handleLogout() {
+	fmt.Printf("\nLogging out %s...\n", a.currentUser)
+	a.stopWatchingChanges()
+	a.isLoggedIn = false
+	a.currentUser = ""
+	fmt.Println("Logged out successfully.")
+}
+
+// startWatchingChanges - в фоне получать изменения, сделанные на других устройствах (перезапускается при смене пользователя)
+func (a *App) startWatchingChanges(ctx context.Context) {
+	a.stopWatchingChanges()
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	a.stopWatching = cancel
+
+	// Ошибки пишутся в лог, чтобы не перебивать ввод пользователя
+	go a.appService.WatchChanges(watchCtx, func(err error) {
+		if a.logger != nil {
+			a.logger.Printf("change notifications: %v", err)
+		}
+	})
+}
+
+// stopWatchingChanges - остановить фоновое получение изменений
+func (a *App) stopWatchingChanges() {
+	if a.stopWatching != nil {
+		a.stopWatching()
+		a.stopWatching = nil
+	}
+}
+
+// handleSync - обработка синхронизации данных с сервером
+func (a *App) handleSync(ctx context.Context) {
+	// Проверяем, не отменен ли контекст
+	select {
+	case <-ctx.Done():
+		fmt.Println("Operation cancelled due to shutdown")
+		return
+	default:
+	}
+
+	fmt.Println("\n=== Sync Data ===")
+
+	syncCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	fmt.Print("Synchronizing with server... ")
+	err := a.appService.ForceSync(syncCtx)
+	if err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+	} else {
+		fmt.Println("SUCCESS")
+		fmt.Println("All data synchronized successfully.")
+	}
+
+	usage, err := a.appService.GetUsage(syncCtx)
+	if err != nil {
+		fmt.Printf("Failed to get storage usage: %v\n", err)
+		return
+	}
+	printUsage(usage)
+}
+
+// printUsage - вывод использования хранилища на сервере относительно квоты
+func printUsage(usage *entities.QuotaUsage) {
+	fmt.Println("\n=== Storage Usage ===")
+	fmt.Printf("Binaries:    %s\n", usageDescription(int64(usage.Usage.Binaries), int64(usage.Quota.MaxEntries)))
+	fmt.Printf("Cards:       %s\n", usageDescription(int64(usage.Usage.Cards), int64(usage.Quota.MaxEntries)))
+	fmt.Printf("Credentials: %s\n", usageDescription(int64(usage.Usage.Credentials), int64(usage.Quota.MaxEntries)))
+	fmt.Printf("Texts:       %s\n", usageDescription(int64(usage.Usage.Texts), int64(usage.Quota.MaxEntries)))
+	fmt.Printf("Bytes:       %s\n", usageDescription(usage.Usage.Bytes, usage.Quota.MaxBytes))
+}
+
+// usageDescription - использование относительно ограничения (0 - без ограничения)
+func usageDescription(used, limit int64) string {
+	if limit == 0 {
+		return fmt.Sprintf("%d (unlimited)", used)
+	}
+	return fmt.Sprintf("%d of %d (%d%%)", used, limit, used*100/limit)
+}
+
+// handleActivity - просмотр журнала операций пользователя
+func (a *App) handleActivity(reader *bufio.Reader, ctx context.Context) {
+	// Проверяем, не отменен ли контекст
+	select {
+	case <-ctx.Done():
+		fmt.Println("Operation cancelled due to shutdown")
+		return
+	default:
+	}
+
+	fmt.Println("\n=== Activity ===")
+
+	fmt.Print("Show last N days (default 7): ")
+	input, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+
+	days := 7
+	if input = strings.TrimSpace(input); input != "" {
+		days, err = strconv.Atoi(input)
+		if err != nil || days <= 0 {
+			fmt.Println("Invalid number of days")
+			return
+		}
+	}
+
+	fmt.Print("Entity type (binary, card, credentials, text, user; empty for all): ")
+	entityType, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	entityType = strings.TrimSpace(entityType)
+
+	activityCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	from := time.Now().AddDate(0, 0, -days)
+	events, err := a.appService.GetActivity(activityCtx, from, time.Time{}, entityType)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(events) == 0 {
+		fmt.Println("No activity found.")
+		return
+	}
+
+	for _, event := range events {
+		fmt.Printf("%s  %-6s %-11s ID: %-6s Device: %s, IP: %s\n",
+			event.CreatedAt.Local().Format("2006-01-02 15:04:05"), event.Action, event.EntityType, event.EntityID, event.DeviceID, event.IP)
+	}
+}
+
+// handleSharing - обмен записями с другими пользователями
+func (a *App) handleSharing(reader *bufio.Reader, ctx context.Context) {
+	for {
+		// Проверяем, не отменен ли контекст
+		select {
+		case <-ctx.Done():
+			fmt.Println("Operation cancelled due to shutdown")
+			return
+		default:
+		}
+
+		fmt.Println("\n=== Sharing ===")
+		fmt.Println("1. List shares")
+		fmt.Println("2. Share an entry")
+		fmt.Println("3. Accept invitation")
+		fmt.Println("4. Revoke or decline access")
+		fmt.Println("5. Back")
+
+		fmt.Print("\nSelect action: ")
+		input, err := a.readInputWithContext(reader, ctx)
+		if err != nil {
+			return
+		}
+		input = strings.TrimSpace(input)
+
+		switch input {
+		case "1":
+			a.listShares(ctx)
+		case "2":
+			a.shareEntry(reader, ctx)
+		case "3":
+			a.acceptShare(reader, ctx)
+		case "4":
+			a.deleteShare(reader, ctx)
+		case "5":
+			return
+		default:
+			fmt.Println("Invalid selection")
+		}
+	}
+}
+
+// listShares - вывод выданных и полученных прав на записи
+func (a *App) listShares(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	grants, err := a.appService.GetShares(listCtx)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(grants) == 0 {
+		fmt.Println("No shares found.")
+		return
+	}
+
+	fmt.Println("\n=== Shares ===")
+	for _, grant := range grants {
+		if grant.OwnerID == a.currentUser {
+			fmt.Printf("ID: %s, %s %s -> %s (%s, %s)\n",
+				grant.ID, grant.EntityType, grant.EntityID, grant.RecipientID, grant.Permission, grant.Status)
+		} else {
+			fmt.Printf("ID: %s, %s %s <- %s (%s, %s)\n",
+				grant.ID, grant.EntityType, grant.EntityID, grant.OwnerID, grant.Permission, grant.Status)
+		}
+	}
+}
+
+// shareEntry - предоставить пользователю доступ к записи
+func (a *App) shareEntry(reader *bufio.Reader, ctx context.Context) {
+	fmt.Print("Entity type (binary, card, credentials, text): ")
+	entityType, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	entityType = strings.TrimSpace(entityType)
+
+	fmt.Print("Entry ID: ")
+	id, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	id = strings.TrimSpace(id)
+
+	fmt.Print("Recipient login: ")
+	recipient, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	recipient = strings.TrimSpace(recipient)
+
+	fmt.Print("Permission (read, write; default read): ")
+	permission, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	permission = strings.TrimSpace(permission)
+	if permission == "" {
+		permission = entities.PermissionRead
+	}
+
+	shareCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fmt.Print("\nSharing entry... ")
+	grant, err := a.appService.ShareEntry(shareCtx, entityType, id, recipient, permission)
+	if err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+
+	fmt.Println("SUCCESS")
+	fmt.Printf("Invitation %s sent to %s\n", grant.ID, recipient)
+}
+
+// acceptShare - принять приглашение к записи
+func (a *App) acceptShare(reader *bufio.Reader, ctx context.Context) {
+	fmt.Print("Share ID to accept: ")
+	id, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	id = strings.TrimSpace(id)
+
+	acceptCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	fmt.Print("Accepting... ")
+	if err := a.appService.AcceptShare(acceptCtx, id); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// deleteShare - отозвать выданное право или отказаться от полученного
+func (a *App) deleteShare(reader *bufio.Reader, ctx context.Context) {
+	fmt.Print("Share ID to revoke: ")
+	id, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	id = strings.TrimSpace(id)
+
+	deleteCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	fmt.Print("Revoking... ")
+	if err := a.appService.DeleteShare(deleteCtx, id); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// printSharedBy - вывести владельца и права для чужой записи или коллекцию для записи организации
+func printSharedBy(entity *entities.SecureEntity) {
+	if entity.CollectionID != "" {
+		fmt.Printf("Collection: %s (%s)\n", entity.CollectionID, entity.Permission)
+		return
+	}
+
+	if entity.IsShared() {
+		fmt.Printf("Shared by: %s (%s)\n", entity.OwnerID, entity.Permission)
+	}
+}
+
+// offerHistory - после просмотра записи предложить показать её прежние версии
+func (a *App) offerHistory(reader *bufio.Reader, ctx context.Context, entityType, id string, current []entities.Field) {
+	choice, err := a.readLine(reader, ctx, "\nShow history? (y/n): ")
+	if err != nil {
+		return
+	}
+
+	choice = strings.ToLower(choice)
+	if choice == "y" || choice == "yes" {
+		a.showHistory(reader, ctx, entityType, id, current)
+	}
+}
+
+// showHistory - вывести прежние версии записи с изменениями, внесёнными следующей версией, и предложить восстановить одну из них
+func (a *App) showHistory(reader *bufio.Reader, ctx context.Context, entityType, id string, current []entities.Field) {
+	historyCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	revisions, err := a.appService.GetHistory(historyCtx, entityType, id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(revisions) == 0 {
+		fmt.Println("No previous versions.")
+		return
+	}
+
+	// Версии идут от последней к первой: каждую сравниваем с версией, которая её заменила
+	fmt.Println("\n=== History ===")
+	newer := current
+	for _, revision := range revisions {
+		fmt.Printf("\nRevision %d, replaced by %s at %s\n",
+			revision.Revision, revision.ReplacedBy, revision.ReplacedAt.Local().Format("2006-01-02 15:04"))
+		printFieldChanges(revision.Fields, newer)
+		if !revision.Restorable {
+			fmt.Println("  (encrypted with a previous entry key, cannot be restored)")
+		}
+		newer = revision.Fields
+	}
+
+	input, err := a.readLine(reader, ctx, "\nRevision to restore (empty to skip): ")
+	if err != nil || input == "" {
+		return
+	}
+
+	revision, err := strconv.Atoi(input)
+	if err != nil {
+		fmt.Println("Invalid revision number")
+		return
+	}
+
+	restoreCtx, restoreCancel := context.WithTimeout(ctx, 30*time.Second)
+	defer restoreCancel()
+
+	fmt.Print("Restoring... ")
+	if err := a.appService.RestoreRevision(restoreCtx, entityType, id, revision); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// printFieldChanges - вывести поля версии, изменённые следующей версией записи, в виде "было -> стало"
+func printFieldChanges(fields, newer []entities.Field) {
+	changed := false
+	for i, field := range fields {
+		next := ""
+		if i < len(newer) {
+			next = newer[i].Value
+		}
+		if next == field.Value {
+			continue
+		}
+
+		fmt.Printf("  %s: %s -> %s\n", field.Name, field.Value, next)
+		changed = true
+	}
+
+	if !changed {
+		fmt.Println("  no changes in fields")
+	}
+}
+
+// handleOrganizations - работа с организациями, их участниками и коллекциями
+func (a *App) handleOrganizations(reader *bufio.Reader, ctx context.Context) {
+	for {
+		// Проверяем, не отменен ли контекст
+		select {
+		case <-ctx.Done():
+			fmt.Println("Operation cancelled due to shutdown")
+			return
+		default:
+		}
+
+		fmt.Println("\n=== Organizations ===")
+		fmt.Println("1. List organizations")
+		fmt.Println("2. Create organization")
+		fmt.Println("3. List members")
+		fmt.Println("4. Invite member")
+		fmt.Println("5. Accept invitation")
+		fmt.Println("6. Remove member or leave")
+		fmt.Println("7. List collections")
+		fmt.Println("8. Create collection")
+		fmt.Println("9. Rotate collection keys")
+		fmt.Println("10. Back")
+
+		fmt.Print("\nSelect action: ")
+		input, err := a.readInputWithContext(reader, ctx)
+		if err != nil {
+			return
+		}
+		input = strings.TrimSpace(input)
+
+		switch input {
+		case "1":
+			a.listOrganizations(ctx)
+		case "2":
+			a.createOrganization(reader, ctx)
+		case "3":
+			a.listOrgMembers(reader, ctx)
+		case "4":
+			a.inviteMember(reader, ctx)
+		case "5":
+			a.acceptInvite(reader, ctx)
+		case "6":
+			a.removeMember(reader, ctx)
+		case "7":
+			a.listCollections(reader, ctx)
+		case "8":
+			a.createCollection(reader, ctx)
+		case "9":
+			a.rotateCollections(reader, ctx)
+		case "10":
+			return
+		default:
+			fmt.Println("Invalid selection")
+		}
+	}
+}
+
+// readLine - запросить строку у пользователя
+func (a *App) readLine(reader *bufio.Reader, ctx context.Context, prompt string) (string, error) {
+	fmt.Print(prompt)
+	input, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// readCollectionID - запросить коллекцию организации для новой записи (пусто - личное хранилище)
+func (a *App) readCollectionID(reader *bufio.Reader, ctx context.Context) (string, error) {
+	return a.readLine(reader, ctx, "Collection ID (empty for personal vault): ")
+}
+
+// listOrganizations - вывод организаций пользователя
+func (a *App) listOrganizations(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	orgs, err := a.appService.GetOrganizations(listCtx)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(orgs) == 0 {
+		fmt.Println("No organizations found.")
+		return
+	}
+
+	fmt.Println("\n=== Organizations ===")
+	for _, org := range orgs {
+		fmt.Printf("ID: %s, %s (owner: %s, role: %s, %s)\n", org.ID, org.Name, org.OwnerID, org.Role, org.Status)
+	}
+}
+
+// createOrganization - создать организацию
+func (a *App) createOrganization(reader *bufio.Reader, ctx context.Context) {
+	name, err := a.readLine(reader, ctx, "Organization name: ")
+	if err != nil {
+		return
+	}
+
+	createCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fmt.Print("Creating organization... ")
+	org, err := a.appService.CreateOrganization(createCtx, name)
+	if err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+	fmt.Printf("Created organization with ID: %s\n", org.ID)
+}
+
+// listOrgMembers - вывод участников организации
+func (a *App) listOrgMembers(reader *bufio.Reader, ctx context.Context) {
+	orgID, err := a.readLine(reader, ctx, "Organization ID: ")
+	if err != nil {
+		return
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	members, err := a.appService.GetOrgMembers(listCtx, orgID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n=== Members ===")
+	for _, member := range members {
+		fmt.Printf("%s (%s, %s)\n", member.Login, member.Role, member.Status)
+	}
+}
+
+// inviteMember - пригласить пользователя в организацию
+func (a *App) inviteMember(reader *bufio.Reader, ctx context.Context) {
+	orgID, err := a.readLine(reader, ctx, "Organization ID: ")
+	if err != nil {
+		return
+	}
+
+	login, err := a.readLine(reader, ctx, "User login: ")
+	if err != nil {
+		return
+	}
+
+	role, err := a.readLine(reader, ctx, "Role (admin, member, readonly; default member): ")
+	if err != nil {
+		return
+	}
+	if role == "" {
+		role = entities.RoleMember
+	}
+
+	inviteCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fmt.Print("Inviting... ")
+	if _, err := a.appService.InviteMember(inviteCtx, orgID, login, role); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// acceptInvite - принять приглашение в организацию
+func (a *App) acceptInvite(reader *bufio.Reader, ctx context.Context) {
+	orgID, err := a.readLine(reader, ctx, "Organization ID to join: ")
+	if err != nil {
+		return
+	}
+
+	acceptCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	fmt.Print("Accepting... ")
+	if err := a.appService.AcceptInvite(acceptCtx, orgID); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// removeMember - исключить участника (ключи коллекций заменяются, записи перешифровываются) или выйти из организации
+func (a *App) removeMember(reader *bufio.Reader, ctx context.Context) {
+	orgID, err := a.readLine(reader, ctx, "Organization ID: ")
+	if err != nil {
+		return
+	}
+
+	login, err := a.readLine(reader, ctx, "Member login (your own login to leave): ")
+	if err != nil {
+		return
+	}
+
+	removeCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	fmt.Print("Removing member and rotating collection keys... ")
+	if err := a.appService.RemoveMember(removeCtx, orgID, login); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// listCollections - вывод коллекций организации
+func (a *App) listCollections(reader *bufio.Reader, ctx context.Context) {
+	orgID, err := a.readLine(reader, ctx, "Organization ID: ")
+	if err != nil {
+		return
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	collections, err := a.appService.GetCollections(listCtx, orgID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(collections) == 0 {
+		fmt.Println("No collections found.")
+		return
+	}
+
+	fmt.Println("\n=== Collections ===")
+	for _, collection := range collections {
+		fmt.Printf("ID: %s, %s (key version %d)", collection.ID, collection.Name, collection.KeyVersion)
+		if collection.RotationRequired {
+			fmt.Print(" - key rotation required")
+		}
+		fmt.Println()
+	}
+}
+
+// createCollection - создать коллекцию организации
+func (a *App) createCollection(reader *bufio.Reader, ctx context.Context) {
+	orgID, err := a.readLine(reader, ctx, "Organization ID: ")
+	if err != nil {
+		return
+	}
+
+	name, err := a.readLine(reader, ctx, "Collection name: ")
+	if err != nil {
+		return
+	}
+
+	createCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fmt.Print("Creating collection... ")
+	collection, err := a.appService.CreateCollection(createCtx, orgID, name)
+	if err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+	fmt.Printf("Created collection with ID: %s\n", collection.ID)
+}
+
+// rotateCollections - заменить ключи коллекций, отмеченных для замены, или завершить прерванную замену
+func (a *App) rotateCollections(reader *bufio.Reader, ctx context.Context) {
+	orgID, err := a.readLine(reader, ctx, "Organization ID: ")
+	if err != nil {
+		return
+	}
+
+	rotateCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	fmt.Print("Rotating collection keys... ")
+	if err := a.appService.RotateCollections(rotateCtx, orgID); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// handleTrash - работа с корзиной: восстановление и окончательное удаление записей
+func (a *App) handleTrash(reader *bufio.Reader, ctx context.Context) {
+	for {
+		// Проверяем, не отменен ли контекст
+		select {
+		case <-ctx.Done():
+			fmt.Println("Operation cancelled due to shutdown")
+			return
+		default:
+		}
+
+		fmt.Println("\n=== Trash ===")
+		fmt.Println("1. List deleted entries")
+		fmt.Println("2. Restore an entry")
+		fmt.Println("3. Delete an entry permanently")
+		fmt.Println("4. Back")
+
+		fmt.Print("\nSelect action: ")
+		input, err := a.readInputWithContext(reader, ctx)
+		if err != nil {
+			return
+		}
+		input = strings.TrimSpace(input)
+
+		switch input {
+		case "1":
+			a.listTrash(ctx)
+		case "2":
+			a.restoreTrashItem(reader, ctx)
+		case "3":
+			a.deleteTrashItem(reader, ctx)
+		case "4":
+			return
+		default:
+			fmt.Println("Invalid selection")
+		}
+	}
+}
+
+// listTrash - вывод записей в корзине
+func (a *App) listTrash(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	items, err := a.appService.GetTrash(listCtx)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(items) == 0 {
+		fmt.Println("Trash is empty.")
+		return
+	}
+
+	fmt.Println("\n=== Trash ===")
+	for _, item := range items {
+		fmt.Printf("ID: %s, %s, deleted %s, Metadata: %s\n",
+			item.ID, item.EntityType, item.DeletedAt.Local().Format("2006-01-02 15:04"), item.Metadata)
+	}
+}
+
+// readTrashItem - запросить тип и идентификатор записи в корзине
+func (a *App) readTrashItem(reader *bufio.Reader, ctx context.Context) (string, string, error) {
+	entityType, err := a.readLine(reader, ctx, "Entity type (binary, card, credentials, text): ")
+	if err != nil {
+		return "", "", err
+	}
+
+	id, err := a.readLine(reader, ctx, "Entry ID: ")
+	if err != nil {
+		return "", "", err
+	}
+
+	return entityType, id, nil
+}
+
+// restoreTrashItem - вернуть запись из корзины
+func (a *App) restoreTrashItem(reader *bufio.Reader, ctx context.Context) {
+	entityType, id, err := a.readTrashItem(reader, ctx)
+	if err != nil {
+		return
+	}
+
+	restoreCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fmt.Print("Restoring... ")
+	if err := a.appService.RestoreTrashItem(restoreCtx, entityType, id); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// deleteTrashItem - окончательно удалить запись из корзины после подтверждения
+func (a *App) deleteTrashItem(reader *bufio.Reader, ctx context.Context) {
+	entityType, id, err := a.readTrashItem(reader, ctx)
+	if err != nil {
+		return
+	}
+
+	fmt.Println("The entry will be deleted permanently and cannot be restored.")
+	confirm, err := a.readLine(reader, ctx, "Are you sure? (yes/no): ")
+	if err != nil {
+		return
+	}
+
+	if strings.ToLower(confirm) != "yes" {
+		fmt.Println("Deletion cancelled")
+		return
+	}
+
+	deleteCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fmt.Print("Deleting... ")
+	if err := a.appService.DeleteTrashItem(deleteCtx, entityType, id); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+}
+
+// handleAccount - выгрузка данных и удаление учётной записи
+func (a *App) handleAccount(reader *bufio.Reader, ctx context.Context) {
+	for {
+		// Проверяем, не отменен ли контекст
+		select {
+		case <-ctx.Done():
+			fmt.Println("Operation cancelled due to shutdown")
+			return
+		default:
+		}
+
+		fmt.Println("\n=== Account ===")
+		fmt.Println("1. Export all data")
+		fmt.Println("2. Delete account")
+		fmt.Println("3. Back")
+
+		fmt.Print("\nSelect action: ")
+		input, err := a.readInputWithContext(reader, ctx)
+		if err != nil {
+			return
+		}
+		input = strings.TrimSpace(input)
+
+		switch input {
+		case "1":
+			a.exportAccount(reader, ctx)
+		case "2":
+			if a.deleteAccount(reader, ctx) {
+				return
+			}
+		case "3":
+			return
+		default:
+			fmt.Println("Invalid selection")
+		}
+	}
+}
+
+// exportAccount - сохранить архив со всеми данными пользователя в файл
+func (a *App) exportAccount(reader *bufio.Reader, ctx context.Context) {
+	defaultPath := fmt.Sprintf("gophkeeper-%s-%s.zip", a.currentUser, time.Now().Format("20060102"))
+	fmt.Printf("File path (default %s): ", defaultPath)
+	path, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return
+	}
+	if path = strings.TrimSpace(path); path == "" {
+		path = defaultPath
+	}
+
+	exportCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	fmt.Print("Exporting... ")
+	written, err := a.appService.ExportAccount(exportCtx, path)
+	if err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return
+	}
+	fmt.Println("SUCCESS")
+	fmt.Printf("Saved %d bytes to %s. Entries stay encrypted with your password.\n", written, path)
+}
+
+// deleteAccount - удалить учётную запись со всеми данными. Возвращает true, если учётная запись удалена
+func (a *App) deleteAccount(reader *bufio.Reader, ctx context.Context) bool {
+	fmt.Println("All your entries, keys, shares and organizations you own will be deleted permanently.")
+	fmt.Print("Password: ")
+	password, err := a.readInputWithContext(reader, ctx)
+	if err != nil {
+		return false
 	}
+	password = strings.TrimSpace(password)
 
-	fmt.Printf("Using database: %s\n", conf.DbPath)
-	fmt.Printf("Connecting to server: %s\n", conf.ServerAddr)
-
-	// Инициализация базы данных
-	dbManager, err := sqlite.NewDatabaseManager(conf.DbPath)
+	fmt.Print("Are you sure? (yes/no): ")
+	confirm, err := a.readInputWithContext(reader, ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize database: %w", err)
+		return false
 	}
+	confirm = strings.TrimSpace(strings.ToLower(confirm))
 
-	// Инициализация сервисов
-	apiClient := clients.NewAPIClient(conf.ServerAddr)
-
-	localStorage := services.NewStorageService(
-		dbManager.BinariesRepo,
-		dbManager.CardsRepo,
-		dbManager.CredentialsRepo,
-		dbManager.TextsRepo,
-	)
-
-	syncService := services.NewSyncService(apiClient, localStorage)
-
-	appService := services.NewGophkeeperService(apiClient, localStorage, syncService)
+	if confirm != "yes" {
+		fmt.Println("Deletion cancelled")
+		return false
+	}
 
-	return &App{
-		dbManager:    dbManager,
-		apiClient:    apiClient,
-		localStorage: localStorage,
-		syncService:  syncService,
-		appService:   appService,
-	}, nil
-}
+	deleteCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
 
-// gracefulShutdown - завершение работы приложения
-func (a *App) gracefulShutdown() {
-	if a.dbManager != nil {
-		a.dbManager.Close()
+	fmt.Print("Deleting account... ")
+	if err := a.appService.DeleteAccount(deleteCtx, password); err != nil {
+		fmt.Printf("FAILED: %v\n", err)
+		return false
 	}
-	fmt.Println("\nGoodbye!")
-}
+	fmt.Println("SUCCESS")
 
-// run - запуск приложения
-func (a *App) run(ctx context.Context) {
-	reader := bufio.NewReader(os.Stdin)
+	a.handleLogout()
+	return true
+}
 
+// handleEmergencyAccess - работа с экстренным доступом: доверенные лица и доступ к чужим хранилищам
+func (a *App) handleEmergencyAccess(reader *bufio.Reader, ctx context.Context) {
 	for {
 		// Проверяем, не отменен ли контекст
 		select {
 		case <-ctx.Done():
-			fmt.Println("Shutdown initiated, exiting...")
+			fmt.Println("Operation cancelled due to shutdown")
 			return
 		default:
 		}
 
-		a.showMainMenu()
+		fmt.Println("\n=== Emergency Access ===")
+		fmt.Println("1. List emergency access")
+		fmt.Println("2. Invite trusted contact")
+		fmt.Println("3. Accept invitation")
+		fmt.Println("4. Request access")
+		fmt.Println("5. Approve request")
+		fmt.Println("6. Reject request or revoke granted access")
+		fmt.Println("7. Remove emergency access")
+		fmt.Println("8. Open vault")
+		fmt.Println("9. Back")
 
-		fmt.Print("\n> ")
+		fmt.Print("\nSelect action: ")
 		input, err := a.readInputWithContext(reader, ctx)
 		if err != nil {
-			fmt.Printf("Error reading input: %v\n", err)
-			continue
+			return
 		}
-
 		input = strings.TrimSpace(input)
 
 		switch input {
 		case "1":
-			a.handleLogin(reader, ctx)
+			a.listEmergencyAccess(ctx)
 		case "2":
-			a.handleRegister(reader, ctx)
+			a.inviteEmergencyContact(reader, ctx)
 		case "3":
-			if a.isLoggedIn {
-				a.handleDataMenu(reader, ctx)
-			} else {
-				fmt.Println("Please login first!")
-			}
+			a.moveEmergencyAccess(reader, ctx, "Accepting", a.appService.AcceptEmergencyAccess)
 		case "4":
-			if a.isLoggedIn {
-				a.handleSync(ctx)
-			} else {
-				fmt.Println("Please login first!")
-			}
+			a.moveEmergencyAccess(reader, ctx, "Requesting access", a.appService.RequestEmergencyAccess)
 		case "5":
-			if a.isLoggedIn {
-				a.handleLogout()
-			} else {
-				fmt.Println("You are not logged in!")
-			}
+			a.moveEmergencyAccess(reader, ctx, "Approving", a.appService.ApproveEmergencyAccess)
 		case "6":
-			fmt.Println("Exiting...")
+			a.moveEmergencyAccess(reader, ctx, "Rejecting", a.appService.RejectEmergencyAccess)
+		case "7":
+			a.deleteEmergencyAccess(reader, ctx)
+		case "8":
+			a.openEmergencyVault(reader, ctx)
+		case "9":
 			return
-		case "help":
-			a.showHelp()
 		default:
-			fmt.Println("Unknown command. Type 'help' for available commands.")
+			fmt.Println("Invalid selection")
 		}
 	}
 }
 
-// readInputWithContext - чтение ввода с поддержкой контекста
-func (a *App) readInputWithContext(reader *bufio.Reader, ctx context.Context) (string, error) {
-	inputChan := make(chan string, 1)
-	errChan := make(chan error, 1)
-
-	// Читаем ввод в отдельной горутине
-	go func() {
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			errChan <- err
-			return
-		}
-		inputChan <- input
-	}()
+// listEmergencyAccess - вывод экстренных доступов, где пользователь доверитель или доверенное лицо
+func (a *App) listEmergencyAccess(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-	// Ждем либо ввод, либо отмену контекста
-	select {
-	case <-ctx.Done():
-		return "", context.Canceled
-	case err := <-errChan:
-		return "", err
-	case input := <-inputChan:
-		return input, nil
+	accesses, err := a.appService.GetEmergencyAccess(listCtx)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
 	}
-}
 
-// showMainMenu - вывод главного меню
-func (a *App) showMainMenu() {
-	fmt.Println("\n=== Main Menu ===")
-	if a.isLoggedIn {
-		fmt.Printf("Logged in as: %s\n", a.currentUser)
-		fmt.Println("1. Login (switch user)")
-		fmt.Println("2. Register")
-		fmt.Println("3. Manage Data")
-		fmt.Println("4. Sync Data")
-		fmt.Println("5. Logout")
-		fmt.Println("6. Exit")
-	} else {
-		fmt.Println("1. Login")
-		fmt.Println("2. Register")
-		fmt.Println("3. Manage Data (requires login)")
-		fmt.Println("4. Sync Data (requires login)")
-		fmt.Println("5. Logout")
-		fmt.Println("6. Exit")
+	if len(accesses) == 0 {
+		fmt.Println("No emergency access found.")
+		return
 	}
-}
 
-// showHelp - вывод подсказки
-func (a *App) showHelp() {
-	fmt.Println("\n=== Available Commands ===")
-	fmt.Println("login    - Login to your account")
-	fmt.Println("register - Create a new account")
-	fmt.Println("data     - Manage your data (binaries, cards, etc.)")
-	fmt.Println("sync     - Synchronize data with server")
-	fmt.Println("logout   - Logout from current account")
-	fmt.Println("exit     - Exit the application")
-	fmt.Println("help     - Show this help message")
+	fmt.Println("\n=== Emergency Access ===")
+	for _, access := range accesses {
+		if access.GrantorID == a.currentUser {
+			fmt.Printf("ID: %s, trusted contact: %s (%s, wait %d days)", access.ID, access.GranteeID, access.Status, access.WaitDays)
+		} else {
+			fmt.Printf("ID: %s, vault of: %s (%s, wait %d days)", access.ID, access.GrantorID, access.Status, access.WaitDays)
+		}
+		if grantAt := access.GrantAt(); grantAt != nil && access.Status == entities.EmergencyStatusRequested {
+			fmt.Printf(" - granted automatically at %s", grantAt.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Println()
+	}
 }
 
-// handleLogin - обработка аутентификации в приложении
-func (a *App) handleLogin(reader *bufio.Reader, ctx context.Context) {
-	// Проверяем, не отменен ли контекст
-	select {
-	case <-ctx.Done():
-		fmt.Println("Operation cancelled due to shutdown")
+// inviteEmergencyContact - назначить доверенное лицо
+func (a *App) inviteEmergencyContact(reader *bufio.Reader, ctx context.Context) {
+	login, err := a.readLine(reader, ctx, "Trusted contact login: ")
+	if err != nil {
 		return
-	default:
 	}
 
-	fmt.Println("\n=== Login ===")
-
-	fmt.Print("Username: ")
-	username, err := a.readInputWithContext(reader, ctx)
+	input, err := a.readLine(reader, ctx, "Wait period in days: ")
 	if err != nil {
 		return
 	}
-	username = strings.TrimSpace(username)
-
-	fmt.Print("Password: ")
-	password, err := a.readInputWithContext(reader, ctx)
+	waitDays, err := strconv.Atoi(input)
 	if err != nil {
+		fmt.Println("Invalid number of days")
 		return
 	}
-	password = strings.TrimSpace(password)
 
-	// Используем контекст с таймаутом, но связанный с общим контекстом
-	loginCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	inviteCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	fmt.Print("Logging in... ")
-	err = a.appService.Login(loginCtx, username, password)
+	fmt.Print("Inviting... ")
+	access, err := a.appService.InviteEmergencyContact(inviteCtx, login, waitDays)
 	if err != nil {
 		fmt.Printf("FAILED: %v\n", err)
 		return
 	}
-
-	a.isLoggedIn = true
-	a.currentUser = username
 	fmt.Println("SUCCESS")
-	fmt.Printf("Welcome, %s!\n", username)
+	fmt.Printf("Created emergency access with ID: %s\n", access.ID)
 }
 
-// handleRegister - обработка регистрации в приложении
-func (a *App) handleRegister(reader *bufio.Reader, ctx context.Context) {
-	// Проверяем, не отменен ли контекст
-	select {
-	case <-ctx.Done():
-		fmt.Println("Operation cancelled due to shutdown")
+// moveEmergencyAccess - перевести экстренный доступ в следующее состояние
+func (a *App) moveEmergencyAccess(reader *bufio.Reader, ctx context.Context, title string, move func(context.Context, string) (*entities.EmergencyAccess, error)) {
+	id, err := a.readLine(reader, ctx, "Emergency access ID: ")
+	if err != nil {
 		return
-	default:
 	}
 
-	fmt.Println("\n=== Register ===")
+	moveCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
-	fmt.Print("Username: ")
-	username, err := a.readInputWithContext(reader, ctx)
+	fmt.Printf("%s... ", title)
+	access, err := move(moveCtx, id)
 	if err != nil {
+		fmt.Printf("FAILED: %v\n", err)
 		return
 	}
-	username = strings.TrimSpace(username)
+	fmt.Printf("SUCCESS (%s)\n", access.Status)
+}
 
-	fmt.Print("Password: ")
-	password, err := a.readInputWithContext(reader, ctx)
+// deleteEmergencyAccess - отменить экстренный доступ (доступно обеим сторонам)
+func (a *App) deleteEmergencyAccess(reader *bufio.Reader, ctx context.Context) {
+	id, err := a.readLine(reader, ctx, "Emergency access ID: ")
 	if err != nil {
 		return
 	}
-	password = strings.TrimSpace(password)
 
-	registerCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	deleteCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	fmt.Print("Registering... ")
-	err = a.appService.Register(registerCtx, username, password)
-	if err != nil {
+	fmt.Print("Removing... ")
+	if err := a.appService.DeleteEmergencyAccess(deleteCtx, id); err != nil {
 		fmt.Printf("FAILED: %v\n", err)
 		return
 	}
-
-	a.isLoggedIn = true
-	a.currentUser = username
 	fmt.Println("SUCCESS")
-	fmt.Printf("Account created. Welcome, %s!\n", username)
 }
 
-// handleLogout - обработка выхода из приложении
-func (a *App) handleLogout() {
-	fmt.Printf("\nLogging out %s...\n", a.currentUser)
-	a.isLoggedIn = false
-	a.currentUser = ""
-	fmt.Println("Logged out successfully.")
-}
-
-// handleSync - обработка синхронизации данных с сервером
-func (a *App) handleSync(ctx context.Context) {
-	// Проверяем, не отменен ли контекст
-	select {
-	case <-ctx.Done():
-		fmt.Println("Operation cancelled due to shutdown")
+// openEmergencyVault - просмотр хранилища доверителя после предоставления доступа
+func (a *App) openEmergencyVault(reader *bufio.Reader, ctx context.Context) {
+	id, err := a.readLine(reader, ctx, "Emergency access ID: ")
+	if err != nil {
 		return
-	default:
 	}
 
-	fmt.Println("\n=== Sync Data ===")
-
-	syncCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	openCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	fmt.Print("Synchronizing with server... ")
-	err := a.appService.ForceSync(syncCtx)
+	vault, err := a.appService.OpenEmergencyVault(openCtx, id)
 	if err != nil {
-		fmt.Printf("FAILED: %v\n", err)
-	} else {
-		fmt.Println("SUCCESS")
-		fmt.Println("All data synchronized successfully.")
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\n=== Vault of %s (read only) ===\n", vault.Access.GrantorID)
+	for _, binary := range vault.Binaries {
+		fmt.Printf("Binary %s: %s (%d bytes)\n", binary.ID, binary.Metadata, len(binary.Data))
+	}
+	for _, card := range vault.Cards {
+		fmt.Printf("Card %s: %s, %s, expires %s, CVV %s, holder %s\n", card.ID, card.Metadata, card.Number, card.ExpirationDate, card.CVV, card.CardHolder)
+	}
+	for _, credentials := range vault.Credentials {
+		fmt.Printf("Credentials %s: %s, login %s, password %s\n", credentials.ID, credentials.Metadata, credentials.Login, credentials.Password)
+	}
+	for _, text := range vault.Texts {
+		fmt.Printf("Text %s: %s\n%s\n", text.ID, text.Metadata, text.Data)
 	}
 }
 
@@ -497,6 +1657,11 @@ func (a *App) createBinary(reader *bufio.Reader, ctx context.Context) {
 	}
 	metadata = strings.TrimSpace(metadata)
 
+	collectionID, err := a.readCollectionID(reader, ctx)
+	if err != nil {
+		return
+	}
+
 	fmt.Print("Enter file path to load binary data (or press Enter to skip): ")
 	filePath, err := a.readInputWithContext(reader, ctx)
 	if err != nil {
@@ -531,7 +1696,7 @@ func (a *App) createBinary(reader *bufio.Reader, ctx context.Context) {
 
 	dto := &dtos.NewBinaryData{
 		Data:            data,
-		NewSecureEntity: dtos.NewSecureEntity{Metadata: metadata},
+		NewSecureEntity: dtos.NewSecureEntity{Metadata: metadata, CollectionID: collectionID},
 	}
 
 	fmt.Print("Creating binary data... ")
@@ -577,6 +1742,7 @@ func (a *App) viewBinary(reader *bufio.Reader, ctx context.Context) {
 
 	fmt.Println("\n=== Binary Details ===")
 	fmt.Printf("ID: %s\n", binary.ID)
+	printSharedBy(&binary.SecureEntity)
 	fmt.Printf("Metadata: %s\n", binary.Metadata)
 	fmt.Printf("Size: %d bytes\n", len(binary.Data))
 
@@ -601,6 +1767,8 @@ func (a *App) viewBinary(reader *bufio.Reader, ctx context.Context) {
 			fmt.Printf("Data saved to %s\n", filename)
 		}
 	}
+
+	a.offerHistory(reader, ctx, "binary", binary.ID, binary.Fields())
 }
 
 // updateBinary - изменение бинарных данных
@@ -635,6 +1803,11 @@ func (a *App) updateBinary(reader *bufio.Reader, ctx context.Context) {
 		return
 	}
 
+	if !existing.CanEdit() {
+		fmt.Printf("Binary is shared by %s read-only\n", existing.OwnerID)
+		return
+	}
+
 	fmt.Println("\n=== Current Binary Data ===")
 	fmt.Printf("ID: %s\n", existing.ID)
 	fmt.Printf("Metadata: %s\n", existing.Metadata)
@@ -724,7 +1897,7 @@ func (a *App) updateBinary(reader *bufio.Reader, ctx context.Context) {
 
 	updatedBinary := &entities.BinaryData{
 		Data:         data,
-		SecureEntity: entities.SecureEntity{ID: id, Metadata: metadata},
+		SecureEntity: entities.SecureEntity{ID: id, Metadata: metadata, OwnerID: existing.OwnerID, EntryKey: existing.EntryKey, Permission: existing.Permission},
 	}
 
 	fmt.Print("\nUpdating binary... ")
@@ -908,6 +2081,11 @@ func (a *App) createCard(reader *bufio.Reader, ctx context.Context) {
 	}
 	metadata = strings.TrimSpace(metadata)
 
+	collectionID, err := a.readCollectionID(reader, ctx)
+	if err != nil {
+		return
+	}
+
 	createCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
@@ -916,7 +2094,7 @@ func (a *App) createCard(reader *bufio.Reader, ctx context.Context) {
 		CardHolder:      cardHolder,
 		ExpirationDate:  expirationDate,
 		CVV:             cvv,
-		NewSecureEntity: dtos.NewSecureEntity{Metadata: metadata},
+		NewSecureEntity: dtos.NewSecureEntity{Metadata: metadata, CollectionID: collectionID},
 	}
 
 	fmt.Print("Creating card... ")
@@ -962,11 +2140,14 @@ func (a *App) viewCard(reader *bufio.Reader, ctx context.Context) {
 
 	fmt.Println("\n=== Card Details ===")
 	fmt.Printf("ID: %s\n", card.ID)
+	printSharedBy(&card.SecureEntity)
 	fmt.Printf("Card number: %s\n", card.Number)
 	fmt.Printf("Card holder: %s\n", card.CardHolder)
 	fmt.Printf("Expiration date: %s\n", card.ExpirationDate)
 	fmt.Printf("CVV: %s\n", card.CVV)
 	fmt.Printf("Metadata: %s\n", card.Metadata)
+
+	a.offerHistory(reader, ctx, "card", card.ID, card.Fields())
 }
 
 // updateCard - изменить карту
@@ -1000,6 +2181,11 @@ func (a *App) updateCard(reader *bufio.Reader, ctx context.Context) {
 		return
 	}
 
+	if !existing.CanEdit() {
+		fmt.Printf("Card is shared by %s read-only\n", existing.OwnerID)
+		return
+	}
+
 	fmt.Println("\n=== Current Card Data ===")
 	fmt.Printf("ID: %s\n", existing.ID)
 	fmt.Printf("Card holder: %s\n", existing.CardHolder)
@@ -1063,7 +2249,7 @@ func (a *App) updateCard(reader *bufio.Reader, ctx context.Context) {
 		CardHolder:     cardHolder,
 		ExpirationDate: expirationDate,
 		CVV:            cvv,
-		SecureEntity:   entities.SecureEntity{ID: id, Metadata: metadata},
+		SecureEntity:   entities.SecureEntity{ID: id, Metadata: metadata, OwnerID: existing.OwnerID, EntryKey: existing.EntryKey, Permission: existing.Permission},
 	}
 
 	fmt.Print("\nUpdating card... ")
@@ -1227,13 +2413,18 @@ func (a *App) createCredentials(reader *bufio.Reader, ctx context.Context) {
 	}
 	metadata = strings.TrimSpace(metadata)
 
+	collectionID, err := a.readCollectionID(reader, ctx)
+	if err != nil {
+		return
+	}
+
 	createCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	dto := &dtos.NewCredentials{
 		Login:           login,
 		Password:        password,
-		NewSecureEntity: dtos.NewSecureEntity{Metadata: metadata},
+		NewSecureEntity: dtos.NewSecureEntity{Metadata: metadata, CollectionID: collectionID},
 	}
 
 	fmt.Print("Creating credentials... ")
@@ -1279,9 +2470,12 @@ func (a *App) viewCredentials(reader *bufio.Reader, ctx context.Context) {
 
 	fmt.Println("\n=== Credentials Details ===")
 	fmt.Printf("ID: %s\n", creds.ID)
+	printSharedBy(&creds.SecureEntity)
 	fmt.Printf("Login: %s\n", creds.Login)
 	fmt.Printf("Password: %s\n", creds.Password)
 	fmt.Printf("Metadata: %s\n", creds.Metadata)
+
+	a.offerHistory(reader, ctx, "credentials", creds.ID, creds.Fields())
 }
 
 // updateCredentials - обновить учётные данные
@@ -1315,6 +2509,11 @@ func (a *App) updateCredentials(reader *bufio.Reader, ctx context.Context) {
 		return
 	}
 
+	if !existing.CanEdit() {
+		fmt.Printf("Credentials is shared by %s read-only\n", existing.OwnerID)
+		return
+	}
+
 	fmt.Println("\n=== Current Credentials ===")
 	fmt.Printf("ID: %s\n", existing.ID)
 	fmt.Printf("Login: %s\n", existing.Login)
@@ -1355,7 +2554,7 @@ func (a *App) updateCredentials(reader *bufio.Reader, ctx context.Context) {
 	updatedCreds := &entities.Credentials{
 		Login:        login,
 		Password:     password,
-		SecureEntity: entities.SecureEntity{ID: id, Metadata: metadata},
+		SecureEntity: entities.SecureEntity{ID: id, Metadata: metadata, OwnerID: existing.OwnerID, EntryKey: existing.EntryKey, Permission: existing.Permission},
 	}
 
 	fmt.Print("\nUpdating credentials... ")
@@ -1510,6 +2709,11 @@ func (a *App) createText(reader *bufio.Reader, ctx context.Context) {
 	}
 	metadata = strings.TrimSpace(metadata)
 
+	collectionID, err := a.readCollectionID(reader, ctx)
+	if err != nil {
+		return
+	}
+
 	fmt.Println("Enter text content (end with empty line):")
 	var lines []string
 	for {
@@ -1532,7 +2736,7 @@ func (a *App) createText(reader *bufio.Reader, ctx context.Context) {
 
 	dto := &dtos.NewTextData{
 		Data:            content,
-		NewSecureEntity: dtos.NewSecureEntity{Metadata: metadata},
+		NewSecureEntity: dtos.NewSecureEntity{Metadata: metadata, CollectionID: collectionID},
 	}
 
 	fmt.Print("Creating text... ")
@@ -1578,8 +2782,11 @@ func (a *App) viewText(reader *bufio.Reader, ctx context.Context) {
 
 	fmt.Println("\n=== Text Details ===")
 	fmt.Printf("ID: %s\n", text.ID)
+	printSharedBy(&text.SecureEntity)
 	fmt.Printf("Metadata: %s\n", text.Metadata)
 	fmt.Printf("\n=== Content ===\n%s\n", text.Data)
+
+	a.offerHistory(reader, ctx, "text", text.ID, text.Fields())
 }
 
 // updateText - изменить текстовые данные
@@ -1613,6 +2820,11 @@ func (a *App) updateText(reader *bufio.Reader, ctx context.Context) {
 		return
 	}
 
+	if !existing.CanEdit() {
+		fmt.Printf("Text is shared by %s read-only\n", existing.OwnerID)
+		return
+	}
+
 	fmt.Println("\n=== Current Text ===")
 	fmt.Printf("ID: %s\n", existing.ID)
 	fmt.Printf("Metadata: %s\n", existing.Metadata)
@@ -1693,7 +2905,7 @@ func (a *App) updateText(reader *bufio.Reader, ctx context.Context) {
 
 	updatedText := &entities.TextData{
 		Data:         content,
-		SecureEntity: entities.SecureEntity{ID: id, Metadata: metadata},
+		SecureEntity: entities.SecureEntity{ID: id, Metadata: metadata, OwnerID: existing.OwnerID, EntryKey: existing.EntryKey, Permission: existing.Permission},
 	}
 
 	fmt.Print("\nUpdating text... ")
@@ -0,0 +1,142 @@
+// main - точка входа в приложение GophKeeper
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories/sqlite"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories/sqlite/migrations"
+)
+
+// migrateUsage - описание подкоманды migrate
+const migrateUsage = `Usage: CLI migrate <command> [-dry-run]
+
+Commands:
+  up        apply all pending migrations to the local database
+  down N    roll back the last N applied migrations
+  status    list migrations and whether they are applied
+
+Flags:
+  -dry-run  only list migrations that would be applied or rolled back
+`
+
+// runMigrate - применить, откатить или показать миграции локальной базы данных из конфигурации
+func runMigrate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "only list migrations that would be applied or rolled back")
+
+	command, steps, err := parseMigrateArgs(fs, args)
+	if err != nil {
+		fmt.Fprint(out, migrateUsage)
+		return err
+	}
+
+	conf, err := loadConfiguration()
+	if err != nil {
+		return err
+	}
+
+	db, err := sqlite.Open(conf.DbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fmt.Fprintf(out, "Using database: %s\n", conf.DbPath)
+
+	migrator := migrations.NewMigrator(db)
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx, *dryRun)
+		printMigrations(out, applied, *dryRun, "apply", "applied")
+		return err
+	case "down":
+		rolledBack, err := migrator.Down(ctx, steps, *dryRun)
+		printMigrations(out, rolledBack, *dryRun, "roll back", "rolled back")
+		return err
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(out, statuses)
+		return nil
+	}
+}
+
+// parseMigrateArgs - разобрать подкоманду migrate: команду, число миграций для down и флаги до или после команды
+func parseMigrateArgs(fs *flag.FlagSet, args []string) (string, int, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", 0, err
+	}
+	if fs.NArg() == 0 {
+		return "", 0, errors.New("migrate command is required")
+	}
+
+	command, rest := fs.Arg(0), fs.Args()[1:]
+
+	var steps int
+	switch command {
+	case "up", "status":
+	case "down":
+		if len(rest) == 0 {
+			return "", 0, errors.New("number of migrations to roll back is required")
+		}
+		n, err := strconv.Atoi(rest[0])
+		if err != nil || n <= 0 {
+			return "", 0, fmt.Errorf("invalid number of migrations %q", rest[0])
+		}
+		steps, rest = n, rest[1:]
+	default:
+		return "", 0, fmt.Errorf("unknown migrate command %q", command)
+	}
+
+	// Флаги после команды
+	if err := fs.Parse(rest); err != nil {
+		return "", 0, err
+	}
+	if fs.NArg() > 0 {
+		return "", 0, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	return command, steps, nil
+}
+
+// printMigrations - вывести применённые или откаченные миграции (при dryRun - те, что были бы затронуты)
+func printMigrations(out io.Writer, list []migrations.Migration, dryRun bool, action, done string) {
+	if len(list) == 0 {
+		fmt.Fprintf(out, "Nothing to %s\n", action)
+		return
+	}
+
+	for _, migration := range list {
+		if dryRun {
+			fmt.Fprintf(out, "Would %s: %s\n", action, migration.Name)
+		} else {
+			fmt.Fprintf(out, "Migration %s: %s\n", done, migration.Name)
+		}
+	}
+}
+
+// printStatus - вывести миграции с отметкой о применении
+func printStatus(out io.Writer, statuses []migrations.Status) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT\tREVERSIBLE")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\n", status.Version, status.Name, state, appliedAt, status.Reversible())
+	}
+	w.Flush()
+}
@@ -4,36 +4,196 @@ package clients
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
 )
 
+const (
+	// DeviceIDHeader - заголовок, в котором серверу передаётся идентификатор устройства
+	DeviceIDHeader = "X-Device-ID"
+	// RequestIDHeader - заголовок с идентификатором запроса (сервер возвращает его в ответе и пишет в свои логи)
+	RequestIDHeader = "X-Request-ID"
+)
+
 // ApiClient - клиент для взаимодействия с апи сервера
 type APIClient struct {
 	baseURL    string
 	httpClient *http.Client
+	transport  *headersTransport
+}
+
+// headersTransport - добавляет служебные заголовки ко всем запросам клиента и логирует их
+type headersTransport struct {
+	base     http.RoundTripper
+	deviceID string
+	logger   *log.Logger // nil - запросы не логируются
+}
+
+// RoundTrip - реализация интерфейса http.RoundTripper
+func (t *headersTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if t.deviceID != "" {
+		req.Header.Set(DeviceIDHeader, t.deviceID)
+	}
+
+	requestID := req.Header.Get(RequestIDHeader)
+	if requestID == "" {
+		requestID = newRequestID()
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+
+	if t.logger != nil {
+		if err != nil {
+			t.logger.Printf("%s %s failed (request_id=%s): %v", req.Method, req.URL.Path, requestID, err)
+		} else {
+			t.logger.Printf("%s %s -> %d (request_id=%s)", req.Method, req.URL.Path, resp.StatusCode, requestID)
+		}
+	}
+
+	return resp, err
+}
+
+// newRequestID - сгенерировать идентификатор запроса
+func newRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// statusError - ошибка неуспешного ответа сервера. Содержит идентификатор запроса для поиска в логах сервера
+func statusError(operation string, resp *http.Response) error {
+	requestID := resp.Header.Get(RequestIDHeader)
+	if requestID == "" && resp.Request != nil {
+		requestID = resp.Request.Header.Get(RequestIDHeader)
+	}
+
+	var quota *QuotaError
+	if resp.StatusCode == http.StatusForbidden {
+		quota = decodeQuotaError(resp)
+	}
+
+	var err error
+	if requestID == "" {
+		err = fmt.Errorf("%s failed with status: %d", operation, resp.StatusCode)
+	} else {
+		err = fmt.Errorf("%s failed with status: %d (request id: %s)", operation, resp.StatusCode, requestID)
+	}
+
+	if quota != nil {
+		return fmt.Errorf("%w: %w", err, quota)
+	}
+
+	return err
+}
+
+// QuotaError - превышение квоты хранилища пользователя на сервере
+type QuotaError struct {
+	Resource  string `json:"resource"` // binaries, cards, credentials, texts или bytes
+	Limit     int64  `json:"limit"`
+	Used      int64  `json:"used"`
+	Requested int64  `json:"requested"`
+}
+
+// Error - реализация интерфейса error
+func (e *QuotaError) Error() string {
+	if e.Resource == "bytes" {
+		return fmt.Sprintf("storage quota exceeded: %d of %d bytes used, %d more requested", e.Used, e.Limit, e.Requested)
+	}
+	return fmt.Sprintf("storage quota exceeded: %d of %d %s used", e.Used, e.Limit, e.Resource)
+}
+
+// decodeQuotaError - прочитать из тела ответа описание превышенной квоты (nil - ответ не о квоте)
+func decodeQuotaError(resp *http.Response) *QuotaError {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	var body struct {
+		Quota *QuotaError `json:"quota"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil
+	}
+
+	return body.Quota
 }
 
 // NewAPIClient - создать клиент для взаимодействия с апи сервера
 func NewAPIClient(baseURL string) *APIClient {
 	jar, _ := cookiejar.New(nil)
+	transport := &headersTransport{base: http.DefaultTransport}
 	return &APIClient{
 		baseURL:    baseURL,
-		httpClient: &http.Client{Jar: jar},
+		httpClient: &http.Client{Jar: jar, Transport: transport},
+		transport:  transport,
+	}
+}
+
+// SetLogger - логировать запросы к серверу (метод, путь, код ответа и идентификатор запроса)
+func (c *APIClient) SetLogger(logger *log.Logger) {
+	c.transport.logger = logger
+}
+
+// SetDeviceID - задать идентификатор устройства, который передаётся серверу с каждым запросом (попадает в журнал аудита)
+func (c *APIClient) SetDeviceID(deviceID string) {
+	c.transport.deviceID = deviceID
+}
+
+// SetCACertificate - доверять сертификатам, подписанным указанным CA (например, сгенерированным сервером для разработки)
+func (c *APIClient) SetCACertificate(caPath string) error {
+	caPEM, err := os.ReadFile(caPath)
+	if err != nil {
+		return fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	// Системные корневые сертификаты остаются доверенными
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return errors.New("no certificates found in " + caPath)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
 	}
+	c.transport.base = transport
+
+	return nil
 }
 
-// Register - регистрация пользователя
-func (s *APIClient) Register(ctx context.Context, login, password string) error {
+// Register - регистрация пользователя. inviteCode - код приглашения (пустой, если сервер не требует приглашений)
+func (s *APIClient) Register(ctx context.Context, login, password, inviteCode string) error {
 	reqBody := map[string]string{
 		"login":    login,
 		"password": password,
 	}
+	if inviteCode != "" {
+		reqBody["invite_code"] = inviteCode
+	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -53,7 +213,7 @@ func (s *APIClient) Register(ctx context.Context, login, password string) error
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("registration failed with status: %d", resp.StatusCode)
+		return statusError("registration", resp)
 	}
 
 	return nil
@@ -84,7 +244,7 @@ func (s *APIClient) Login(ctx context.Context, login, password string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("login failed with status: %d", resp.StatusCode)
+		return statusError("login", resp)
 	}
 
 	return nil
@@ -110,7 +270,7 @@ func (c *APIClient) CreateBinary(ctx context.Context, dto *dtos.NewBinaryData) (
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("create binary failed with status: %d", resp.StatusCode)
+		return nil, statusError("create binary", resp)
 	}
 
 	var binary entities.BinaryData
@@ -121,6 +281,35 @@ func (c *APIClient) CreateBinary(ctx context.Context, dto *dtos.NewBinaryData) (
 	return &binary, nil
 }
 
+// GetBinary - получить бинарные данные по идентификатору (nil, если на сервере их нет)
+func (c *APIClient) GetBinary(ctx context.Context, id string) (*entities.BinaryData, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/binaries/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get binary", resp)
+	}
+
+	var entity entities.BinaryData
+	if err := json.NewDecoder(resp.Body).Decode(&entity); err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
 // GetAllBinaries - получить все бинарные данные
 func (c *APIClient) GetAllBinaries(ctx context.Context) ([]entities.BinaryData, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/binaries", nil)
@@ -135,7 +324,7 @@ func (c *APIClient) GetAllBinaries(ctx context.Context) ([]entities.BinaryData,
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get binaries failed with status: %d", resp.StatusCode)
+		return nil, statusError("get binaries", resp)
 	}
 
 	var binaries []entities.BinaryData
@@ -166,7 +355,7 @@ func (c *APIClient) UpdateBinary(ctx context.Context, entity *entities.BinaryDat
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("update binary failed with status: %d", resp.StatusCode)
+		return nil, statusError("update binary", resp)
 	}
 
 	var updatedBinary entities.BinaryData
@@ -191,7 +380,7 @@ func (c *APIClient) DeleteBinary(ctx context.Context, id string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusGone {
-		return fmt.Errorf("delete binary failed with status: %d", resp.StatusCode)
+		return statusError("delete binary", resp)
 	}
 
 	return nil
@@ -217,7 +406,7 @@ func (c *APIClient) CreateCard(ctx context.Context, dto *dtos.NewCardInformation
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("create card failed with status: %d", resp.StatusCode)
+		return nil, statusError("create card", resp)
 	}
 
 	var card entities.CardInformation
@@ -228,6 +417,35 @@ func (c *APIClient) CreateCard(ctx context.Context, dto *dtos.NewCardInformation
 	return &card, nil
 }
 
+// GetCard - получить данные карты по идентификатору (nil, если на сервере их нет)
+func (c *APIClient) GetCard(ctx context.Context, id string) (*entities.CardInformation, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/cards/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get card", resp)
+	}
+
+	var entity entities.CardInformation
+	if err := json.NewDecoder(resp.Body).Decode(&entity); err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
 // GetAllCards - получить данные всех карт
 func (c *APIClient) GetAllCards(ctx context.Context) ([]entities.CardInformation, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/cards", nil)
@@ -242,7 +460,7 @@ func (c *APIClient) GetAllCards(ctx context.Context) ([]entities.CardInformation
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get cards failed with status: %d", resp.StatusCode)
+		return nil, statusError("get cards", resp)
 	}
 
 	var cards []entities.CardInformation
@@ -273,7 +491,7 @@ func (c *APIClient) UpdateCard(ctx context.Context, entity *entities.CardInforma
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("update card failed with status: %d", resp.StatusCode)
+		return nil, statusError("update card", resp)
 	}
 
 	var updatedCard entities.CardInformation
@@ -298,7 +516,7 @@ func (c *APIClient) DeleteCard(ctx context.Context, id string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusGone {
-		return fmt.Errorf("delete card failed with status: %d", resp.StatusCode)
+		return statusError("delete card", resp)
 	}
 
 	return nil
@@ -324,7 +542,7 @@ func (c *APIClient) CreateCredentials(ctx context.Context, dto *dtos.NewCredenti
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("create credentials failed with status: %d", resp.StatusCode)
+		return nil, statusError("create credentials", resp)
 	}
 
 	var credentials entities.Credentials
@@ -335,6 +553,35 @@ func (c *APIClient) CreateCredentials(ctx context.Context, dto *dtos.NewCredenti
 	return &credentials, nil
 }
 
+// GetCredentials - получить учётные данные по идентификатору (nil, если на сервере их нет)
+func (c *APIClient) GetCredentials(ctx context.Context, id string) (*entities.Credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/credentials/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get credentials", resp)
+	}
+
+	var entity entities.Credentials
+	if err := json.NewDecoder(resp.Body).Decode(&entity); err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
 // GetAllCredentials - получить все учётные данные
 func (c *APIClient) GetAllCredentials(ctx context.Context) ([]entities.Credentials, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/credentials", nil)
@@ -349,7 +596,7 @@ func (c *APIClient) GetAllCredentials(ctx context.Context) ([]entities.Credentia
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get credentials failed with status: %d", resp.StatusCode)
+		return nil, statusError("get credentials", resp)
 	}
 
 	var credentials []entities.Credentials
@@ -380,7 +627,7 @@ func (c *APIClient) UpdateCredentials(ctx context.Context, entity *entities.Cred
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("update credentials failed with status: %d", resp.StatusCode)
+		return nil, statusError("update credentials", resp)
 	}
 
 	var updatedCredentials entities.Credentials
@@ -405,7 +652,7 @@ func (c *APIClient) DeleteCredentials(ctx context.Context, id string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusGone {
-		return fmt.Errorf("delete credentials failed with status: %d", resp.StatusCode)
+		return statusError("delete credentials", resp)
 	}
 
 	return nil
@@ -431,7 +678,7 @@ func (c *APIClient) CreateText(ctx context.Context, dto *dtos.NewTextData) (*ent
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("create text failed with status: %d", resp.StatusCode)
+		return nil, statusError("create text", resp)
 	}
 
 	var text entities.TextData
@@ -442,6 +689,35 @@ func (c *APIClient) CreateText(ctx context.Context, dto *dtos.NewTextData) (*ent
 	return &text, nil
 }
 
+// GetText - получить текстовые данные по идентификатору (nil, если на сервере их нет)
+func (c *APIClient) GetText(ctx context.Context, id string) (*entities.TextData, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/texts/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get text", resp)
+	}
+
+	var entity entities.TextData
+	if err := json.NewDecoder(resp.Body).Decode(&entity); err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
 // GetAllTexts - получить все текстовые данные
 func (c *APIClient) GetAllTexts(ctx context.Context) ([]entities.TextData, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/texts", nil)
@@ -456,7 +732,7 @@ func (c *APIClient) GetAllTexts(ctx context.Context) ([]entities.TextData, error
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get texts failed with status: %d", resp.StatusCode)
+		return nil, statusError("get texts", resp)
 	}
 
 	var texts []entities.TextData
@@ -487,7 +763,7 @@ func (c *APIClient) UpdateText(ctx context.Context, entity *entities.TextData) (
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("update text failed with status: %d", resp.StatusCode)
+		return nil, statusError("update text", resp)
 	}
 
 	var updatedTextData entities.TextData
@@ -512,8 +788,772 @@ func (c *APIClient) DeleteText(ctx context.Context, id string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusGone {
-		return fmt.Errorf("delete text failed with status: %d", resp.StatusCode)
+		return statusError("delete text", resp)
+	}
+
+	return nil
+}
+
+// GetAuditLog - получить журнал операций пользователя за период (нулевые границы и пустой тип не ограничивают выборку)
+func (c *APIClient) GetAuditLog(ctx context.Context, from, to time.Time, entityType string) ([]entities.AuditEvent, error) {
+	query := url.Values{}
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339))
+	}
+	if entityType != "" {
+		query.Set("type", entityType)
+	}
+
+	endpoint := c.baseURL + "/api/user/audit"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get audit log", resp)
+	}
+
+	var events []entities.AuditEvent
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetTrash - получить записи в корзине, которые пользователь может восстановить
+func (c *APIClient) GetTrash(ctx context.Context) ([]entities.TrashItem, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/trash", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get trash", resp)
+	}
+
+	var items []entities.TrashItem
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// RestoreTrashItem - вернуть запись из корзины
+func (c *APIClient) RestoreTrashItem(ctx context.Context, entityType, id string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/trash/"+url.PathEscape(entityType)+"/"+url.PathEscape(id)+"/restore", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("restore trash item", resp)
+	}
+
+	return nil
+}
+
+// DeleteTrashItem - окончательно удалить запись из корзины
+func (c *APIClient) DeleteTrashItem(ctx context.Context, entityType, id string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.baseURL+"/api/user/trash/"+url.PathEscape(entityType)+"/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGone {
+		return statusError("delete trash item", resp)
+	}
+
+	return nil
+}
+
+// GetHistory - получить прежние версии записи (сначала последние)
+func (c *APIClient) GetHistory(ctx context.Context, entityType, id string) ([]entities.Revision, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/"+url.PathEscape(entityType)+"/"+url.PathEscape(id)+"/history", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get history", resp)
+	}
+
+	var revisions []entities.Revision
+	if err := json.NewDecoder(resp.Body).Decode(&revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// RestoreRevision - вернуть запись к прежней версии
+func (c *APIClient) RestoreRevision(ctx context.Context, entityType, id string, revision int) error {
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/api/user/"+url.PathEscape(entityType)+"/"+url.PathEscape(id)+"/history/"+strconv.Itoa(revision)+"/restore", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("restore revision", resp)
 	}
 
 	return nil
 }
+
+// GetUsage - получить текущее использование хранилища пользователя и действующую квоту
+func (c *APIClient) GetUsage(ctx context.Context) (*entities.QuotaUsage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/usage", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get usage", resp)
+	}
+
+	var usage entities.QuotaUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// ExportAccount - скачать архив с учётной записью и всеми записями пользователя (поля записей зашифрованы) и записать его в dst
+func (c *APIClient) ExportAccount(ctx context.Context, dst io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/export", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, statusError("export account", resp)
+	}
+
+	return io.Copy(dst, resp.Body)
+}
+
+// DeleteAccount - удалить учётную запись пользователя со всеми данными (пароль подтверждается повторно)
+func (c *APIClient) DeleteAccount(ctx context.Context, password string) error {
+	jsonData, err := json.Marshal(map[string]string{"password": password})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.baseURL+"/api/user", bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return statusError("delete account", resp)
+	}
+
+	return nil
+}
+
+// SetUserKeys - опубликовать пару ключей пользователя (закрытый ключ передаётся зашифрованным)
+func (c *APIClient) SetUserKeys(ctx context.Context, keys *entities.UserKeys) error {
+	jsonData, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.baseURL+"/api/user/keys", bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("set user keys", resp)
+	}
+
+	return nil
+}
+
+// GetUserKeys - получить ключи пользователя (nil, если ключей нет). Пустой login - собственные ключи
+func (c *APIClient) GetUserKeys(ctx context.Context, login string) (*entities.UserKeys, error) {
+	endpoint := c.baseURL + "/api/user/keys"
+	if login != "" {
+		endpoint += "/" + url.PathEscape(login)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get user keys", resp)
+	}
+
+	var keys entities.UserKeys
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return nil, err
+	}
+
+	return &keys, nil
+}
+
+// ShareEntry - поделиться записью с другим пользователем
+func (c *APIClient) ShareEntry(ctx context.Context, dto *dtos.NewShareGrant) (*entities.ShareGrant, error) {
+	jsonData, err := json.Marshal(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/shares", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, statusError("share entry", resp)
+	}
+
+	var grant entities.ShareGrant
+	if err := json.NewDecoder(resp.Body).Decode(&grant); err != nil {
+		return nil, err
+	}
+
+	return &grant, nil
+}
+
+// GetShares - получить права, выданные пользователем и выданные ему
+func (c *APIClient) GetShares(ctx context.Context) ([]entities.ShareGrant, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/shares", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get shares", resp)
+	}
+
+	var grants []entities.ShareGrant
+	if err := json.NewDecoder(resp.Body).Decode(&grants); err != nil {
+		return nil, err
+	}
+
+	return grants, nil
+}
+
+// AcceptShare - принять приглашение к чужой записи
+func (c *APIClient) AcceptShare(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/shares/"+url.PathEscape(id)+"/accept", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("accept share", resp)
+	}
+
+	return nil
+}
+
+// DeleteShare - отозвать право на запись или отказаться от него
+func (c *APIClient) DeleteShare(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.baseURL+"/api/user/shares/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGone {
+		return statusError("delete share", resp)
+	}
+
+	return nil
+}
+
+// CreateOrganization - создать организацию
+func (c *APIClient) CreateOrganization(ctx context.Context, dto *dtos.NewOrganization) (*entities.Organization, error) {
+	jsonData, err := json.Marshal(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/orgs", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, statusError("create organization", resp)
+	}
+
+	var result entities.Organization
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetOrganizations - получить организации пользователя (в том числе те, куда он приглашён)
+func (c *APIClient) GetOrganizations(ctx context.Context) ([]entities.Organization, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/orgs", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get organizations", resp)
+	}
+
+	var orgs []entities.Organization
+	if err := json.NewDecoder(resp.Body).Decode(&orgs); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+// GetOrgMembers - получить участников организации
+func (c *APIClient) GetOrgMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/orgs/"+url.PathEscape(orgID)+"/members", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get organization members", resp)
+	}
+
+	var members []entities.OrgMember
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// InviteMember - пригласить пользователя в организацию
+func (c *APIClient) InviteMember(ctx context.Context, orgID string, dto *dtos.NewOrgMember) (*entities.OrgMember, error) {
+	jsonData, err := json.Marshal(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/orgs/"+url.PathEscape(orgID)+"/members", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, statusError("invite member", resp)
+	}
+
+	var result entities.OrgMember
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// AcceptInvite - принять приглашение в организацию
+func (c *APIClient) AcceptInvite(ctx context.Context, orgID string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/orgs/"+url.PathEscape(orgID)+"/members/accept", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError("accept invite", resp)
+	}
+
+	return nil
+}
+
+// RemoveMember - исключить участника из организации или выйти из неё
+func (c *APIClient) RemoveMember(ctx context.Context, orgID, login string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.baseURL+"/api/user/orgs/"+url.PathEscape(orgID)+"/members/"+url.PathEscape(login), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGone {
+		return statusError("remove member", resp)
+	}
+
+	return nil
+}
+
+// CreateCollection - создать коллекцию организации
+func (c *APIClient) CreateCollection(ctx context.Context, orgID string, dto *dtos.NewCollection) (*entities.Collection, error) {
+	jsonData, err := json.Marshal(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/orgs/"+url.PathEscape(orgID)+"/collections", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, statusError("create collection", resp)
+	}
+
+	var result entities.Collection
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetCollections - получить коллекции организации с ключами, зашифрованными для пользователя
+func (c *APIClient) GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/orgs/"+url.PathEscape(orgID)+"/collections", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get collections", resp)
+	}
+
+	var collections []entities.Collection
+	if err := json.NewDecoder(resp.Body).Decode(&collections); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// RotateCollectionKey - заменить ключ коллекции
+func (c *APIClient) RotateCollectionKey(ctx context.Context, collectionID string, dto *dtos.CollectionRotation) (*entities.Collection, error) {
+	jsonData, err := json.Marshal(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/collections/"+url.PathEscape(collectionID)+"/rotate", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("rotate collection key", resp)
+	}
+
+	var result entities.Collection
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// InviteEmergencyContact - назначить доверенное лицо для экстренного доступа к хранилищу
+func (c *APIClient) InviteEmergencyContact(ctx context.Context, dto *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error) {
+	jsonData, err := json.Marshal(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/emergency", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, statusError("invite emergency contact", resp)
+	}
+
+	var access entities.EmergencyAccess
+	if err := json.NewDecoder(resp.Body).Decode(&access); err != nil {
+		return nil, err
+	}
+
+	return &access, nil
+}
+
+// GetEmergencyAccess - получить экстренные доступы, в которых пользователь доверитель или доверенное лицо
+func (c *APIClient) GetEmergencyAccess(ctx context.Context) ([]entities.EmergencyAccess, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/emergency", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("get emergency access", resp)
+	}
+
+	var accesses []entities.EmergencyAccess
+	if err := json.NewDecoder(resp.Body).Decode(&accesses); err != nil {
+		return nil, err
+	}
+
+	return accesses, nil
+}
+
+// AcceptEmergencyAccess - согласиться быть доверенным лицом
+func (c *APIClient) AcceptEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	return c.moveEmergencyAccess(ctx, id, "accept")
+}
+
+// RequestEmergencyAccess - запросить доступ к хранилищу доверителя
+func (c *APIClient) RequestEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	return c.moveEmergencyAccess(ctx, id, "request")
+}
+
+// ApproveEmergencyAccess - предоставить запрошенный доступ, не дожидаясь окончания ожидания
+func (c *APIClient) ApproveEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	return c.moveEmergencyAccess(ctx, id, "approve")
+}
+
+// RejectEmergencyAccess - отклонить запрос доступа или закрыть предоставленный доступ
+func (c *APIClient) RejectEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	return c.moveEmergencyAccess(ctx, id, "reject")
+}
+
+// moveEmergencyAccess - выполнить переход экстренного доступа (accept, request, approve, reject)
+func (c *APIClient) moveEmergencyAccess(ctx context.Context, id, action string) (*entities.EmergencyAccess, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/user/emergency/"+url.PathEscape(id)+"/"+action, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(action+" emergency access", resp)
+	}
+
+	var access entities.EmergencyAccess
+	if err := json.NewDecoder(resp.Body).Decode(&access); err != nil {
+		return nil, err
+	}
+
+	return &access, nil
+}
+
+// DeleteEmergencyAccess - отменить экстренный доступ
+func (c *APIClient) DeleteEmergencyAccess(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.baseURL+"/api/user/emergency/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGone {
+		return statusError("delete emergency access", resp)
+	}
+
+	return nil
+}
+
+// OpenEmergencyVault - получить хранилище доверителя по предоставленному экстренному доступу
+func (c *APIClient) OpenEmergencyVault(ctx context.Context, id string) (*entities.EmergencyVault, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/emergency/"+url.PathEscape(id)+"/vault", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("open emergency vault", resp)
+	}
+
+	var vault entities.EmergencyVault
+	if err := json.NewDecoder(resp.Body).Decode(&vault); err != nil {
+		return nil, err
+	}
+
+	return &vault, nil
+}
@@ -2,11 +2,17 @@
 package clients_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -32,6 +38,7 @@ func TestAPIClient_Register(t *testing.T) {
 		name           string
 		login          string
 		password       string
+		inviteCode     string
 		serverStatus   int
 		serverResponse string
 		wantErr        bool
@@ -44,6 +51,23 @@ func TestAPIClient_Register(t *testing.T) {
 			serverStatus: http.StatusCreated,
 			wantErr:      false,
 		},
+		{
+			name:         "Successful registration with invitation code",
+			login:        "testuser",
+			password:     "testpass",
+			inviteCode:   "0123456789abcdef",
+			serverStatus: http.StatusOK,
+			wantErr:      false,
+		},
+		{
+			name:           "Failed registration - invitation required",
+			login:          "testuser",
+			password:       "testpass",
+			serverStatus:   http.StatusForbidden,
+			serverResponse: "registration requires an invitation code",
+			wantErr:        true,
+			errorContains:  "registration failed with status: 403",
+		},
 		{
 			name:         "Successful registration with OK status",
 			login:        "testuser",
@@ -93,6 +117,7 @@ func TestAPIClient_Register(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.login, body["login"])
 				assert.Equal(t, tt.password, body["password"])
+				assert.Equal(t, tt.inviteCode, body["invite_code"])
 
 				w.WriteHeader(tt.serverStatus)
 				if tt.serverResponse != "" {
@@ -105,7 +130,7 @@ func TestAPIClient_Register(t *testing.T) {
 			client := clients.NewAPIClient(server.URL)
 
 			// Выполняем тест
-			err := client.Register(ctx, tt.login, tt.password)
+			err := client.Register(ctx, tt.login, tt.password, tt.inviteCode)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -955,6 +980,49 @@ func TestAPIClient_GetAllTexts(t *testing.T) {
 	})
 }
 
+// TestAPIClient_GetText - получение текстовых данных по идентификатору
+func TestAPIClient_GetText(t *testing.T) {
+	ctx := context.Background()
+
+	server := testServer(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+
+		switch r.URL.Path {
+		case "/api/user/texts/text1":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entities.TextData{
+				SecureEntity: entities.SecureEntity{ID: "text1", Metadata: "note"},
+				Data:         "content",
+			})
+		case "/api/user/texts/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	defer server.Close()
+
+	client := clients.NewAPIClient(server.URL)
+
+	t.Run("Existing text", func(t *testing.T) {
+		text, err := client.GetText(ctx, "text1")
+		require.NoError(t, err)
+		require.NotNil(t, text)
+		assert.Equal(t, "content", text.Data)
+	})
+
+	t.Run("Missing text", func(t *testing.T) {
+		text, err := client.GetText(ctx, "missing")
+		assert.NoError(t, err)
+		assert.Nil(t, text)
+	})
+
+	t.Run("Server error", func(t *testing.T) {
+		_, err := client.GetText(ctx, "broken")
+		assert.ErrorContains(t, err, "get text failed with status: 500")
+	})
+}
+
 // TestAPIClient_UpdateText - тест обновления текстовых данных
 func TestAPIClient_UpdateText(t *testing.T) {
 	ctx := context.Background()
@@ -1042,6 +1110,334 @@ func TestAPIClient_DeleteText(t *testing.T) {
 	})
 }
 
+// TestAPIClient_GetAuditLog - тесты получения журнала операций
+func TestAPIClient_GetAuditLog(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Successful get with filters", func(t *testing.T) {
+		expected := []entities.AuditEvent{
+			{ID: "1", UserID: "user", Action: "create", EntityType: "card", EntityID: "10", DeviceID: "laptop"},
+		}
+
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/audit", r.URL.Path)
+			assert.Equal(t, "GET", r.Method)
+			assert.Equal(t, from.Format(time.RFC3339), r.URL.Query().Get("from"))
+			assert.Equal(t, to.Format(time.RFC3339), r.URL.Query().Get("to"))
+			assert.Equal(t, "card", r.URL.Query().Get("type"))
+			assert.Equal(t, "laptop", r.Header.Get(clients.DeviceIDHeader))
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(expected)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+		client.SetDeviceID("laptop")
+
+		events, err := client.GetAuditLog(ctx, from, to, "card")
+		require.NoError(t, err)
+		assert.Equal(t, expected, events)
+	})
+
+	t.Run("Without filters", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Empty(t, r.URL.RawQuery)
+			assert.Empty(t, r.Header.Get(clients.DeviceIDHeader))
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode([]entities.AuditEvent{})
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		events, err := client.GetAuditLog(ctx, time.Time{}, time.Time{}, "")
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Server error", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotImplemented)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		_, err := client.GetAuditLog(ctx, from, to, "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "get audit log failed with status: 501")
+	})
+}
+
+// TestAPIClient_Trash - тесты работы с корзиной
+func TestAPIClient_Trash(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("List", func(t *testing.T) {
+		deletedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/trash", r.URL.Path)
+			assert.Equal(t, "GET", r.Method)
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"id": "1", "metadata": "encrypted", "entity_type": "text", "deleted_at": deletedAt},
+			})
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		items, err := client.GetTrash(ctx)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "text", items[0].EntityType)
+		assert.Equal(t, "encrypted", items[0].Metadata)
+		assert.True(t, deletedAt.Equal(items[0].DeletedAt))
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/trash/card/1/restore", r.URL.Path)
+			assert.Equal(t, "POST", r.Method)
+
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id":"1","entity_type":"card"}`))
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+		require.NoError(t, client.RestoreTrashItem(ctx, "card", "1"))
+	})
+
+	t.Run("Delete permanently", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/trash/card/1", r.URL.Path)
+			assert.Equal(t, "DELETE", r.Method)
+
+			w.WriteHeader(http.StatusGone)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+		require.NoError(t, client.DeleteTrashItem(ctx, "card", "1"))
+	})
+
+	t.Run("Not in trash", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+		assert.Error(t, client.RestoreTrashItem(ctx, "card", "1"))
+		assert.Error(t, client.DeleteTrashItem(ctx, "card", "1"))
+	})
+}
+
+// TestAPIClient_History - тесты получения истории записи и восстановления версии
+func TestAPIClient_History(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("List", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/credentials/7/history", r.URL.Path)
+			assert.Equal(t, "GET", r.Method)
+
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`[{"entity_type":"credentials","entity_id":"7","revision":2,"content":{"login":"l","password":"p"},"metadata":"m","restorable":true,"replaced_by":"user"}]`))
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		revisions, err := client.GetHistory(ctx, "credentials", "7")
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, 2, revisions[0].Revision)
+		assert.JSONEq(t, `{"login":"l","password":"p"}`, string(revisions[0].Content))
+		assert.True(t, revisions[0].Restorable)
+		assert.Equal(t, "user", revisions[0].ReplacedBy)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/text/3/history/2/restore", r.URL.Path)
+			assert.Equal(t, "POST", r.Method)
+
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id":"3"}`))
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+		require.NoError(t, client.RestoreRevision(ctx, "text", "3", 2))
+	})
+
+	t.Run("Key changed", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+		assert.Error(t, client.RestoreRevision(ctx, "text", "3", 1))
+	})
+}
+
+// TestAPIClient_GetUsage - тесты получения использования хранилища и ошибок превышения квоты
+func TestAPIClient_GetUsage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Successful get", func(t *testing.T) {
+		expected := &entities.QuotaUsage{
+			Usage: entities.StorageUsage{Texts: 2, Bytes: 512},
+			Quota: entities.Quota{MaxEntries: 10, MaxBytes: 1024},
+		}
+
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/usage", r.URL.Path)
+			assert.Equal(t, "GET", r.Method)
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(expected)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		usage, err := client.GetUsage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected, usage)
+	})
+
+	t.Run("Quota exceeded on create", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error": "storage quota exceeded",
+				"quota": map[string]any{"resource": "bytes", "limit": 1024, "used": 1000, "requested": 100},
+			})
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		_, err := client.CreateText(ctx, &dtos.NewTextData{Data: "text"})
+		require.Error(t, err)
+
+		var quotaErr *clients.QuotaError
+		require.True(t, errors.As(err, &quotaErr), err)
+		assert.Equal(t, clients.QuotaError{Resource: "bytes", Limit: 1024, Used: 1000, Requested: 100}, *quotaErr)
+		assert.Contains(t, err.Error(), "1000 of 1024 bytes used")
+	})
+
+	t.Run("Forbidden without quota", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		_, err := client.GetUsage(ctx)
+		require.Error(t, err)
+
+		var quotaErr *clients.QuotaError
+		assert.False(t, errors.As(err, &quotaErr))
+		assert.Contains(t, err.Error(), "get usage failed with status: 403")
+	})
+}
+
+// TestAPIClient_Account - тесты выгрузки и удаления учётной записи
+func TestAPIClient_Account(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Export streams archive", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/export", r.URL.Path)
+			assert.Equal(t, "GET", r.Method)
+
+			w.Header().Set("Content-Type", "application/zip")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("zip-content"))
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		var buf bytes.Buffer
+		written, err := client.ExportAccount(ctx, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len("zip-content")), written)
+		assert.Equal(t, "zip-content", buf.String())
+	})
+
+	t.Run("Delete sends password", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user", r.URL.Path)
+			assert.Equal(t, "DELETE", r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			if body["password"] != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+
+		require.NoError(t, client.DeleteAccount(ctx, "secret"))
+
+		err := client.DeleteAccount(ctx, "wrong")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "delete account failed with status: 401")
+	})
+}
+
+// TestAPIClient_RequestID - тест передачи и логирования идентификатора запроса
+func TestAPIClient_RequestID(t *testing.T) {
+	ctx := context.Background()
+
+	var received []string
+	server := testServer(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(clients.RequestIDHeader)
+		received = append(received, requestID)
+
+		// Сервер возвращает идентификатор в ответе
+		w.Header().Set(clients.RequestIDHeader, requestID)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := clients.NewAPIClient(server.URL)
+	client.SetLogger(log.New(&logs, "", 0))
+
+	_, err := client.GetAllTexts(ctx)
+	require.Error(t, err)
+	_, err = client.GetAllCards(ctx)
+	require.Error(t, err)
+
+	require.Len(t, received, 2)
+	assert.Len(t, received[0], 32)
+	assert.NotEqual(t, received[0], received[1], "each request must get its own id")
+
+	// Один и тот же идентификатор виден в ошибке, в логе клиента и на сервере
+	assert.Contains(t, err.Error(), "get cards failed with status: 500 (request id: "+received[1]+")")
+	assert.Contains(t, logs.String(), "GET /api/user/texts -> 500 (request_id="+received[0]+")")
+	assert.Contains(t, logs.String(), "request_id="+received[1])
+}
+
 // TestAPIClient_CookieJar - тест кук
 func TestAPIClient_CookieJar(t *testing.T) {
 	ctx := context.Background()
@@ -1084,6 +1480,54 @@ func TestAPIClient_CookieJar(t *testing.T) {
 	assert.Equal(t, "test-session-value", loginCookie)
 }
 
+// TestAPIClient_CACertificate - тест доверия к пользовательскому CA
+func TestAPIClient_CACertificate(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Сертификат тестового сервера самоподписанный - используем его как CA
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caPath, caPEM, 0644))
+
+	t.Run("Без доверенного CA соединение отклоняется", func(t *testing.T) {
+		client := clients.NewAPIClient(server.URL)
+
+		err := client.Login(ctx, "test", "pass")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "certificate")
+	})
+
+	t.Run("С доверенным CA соединение устанавливается", func(t *testing.T) {
+		client := clients.NewAPIClient(server.URL)
+		require.NoError(t, client.SetCACertificate(caPath))
+
+		err := client.Login(ctx, "test", "pass")
+		require.NoError(t, err)
+	})
+
+	t.Run("Несуществующий файл CA", func(t *testing.T) {
+		client := clients.NewAPIClient(server.URL)
+
+		err := client.SetCACertificate(filepath.Join(t.TempDir(), "missing.pem"))
+		require.Error(t, err)
+	})
+
+	t.Run("Файл без сертификатов", func(t *testing.T) {
+		emptyPath := filepath.Join(t.TempDir(), "empty.pem")
+		require.NoError(t, os.WriteFile(emptyPath, []byte("not a certificate"), 0644))
+
+		client := clients.NewAPIClient(server.URL)
+		err := client.SetCACertificate(emptyPath)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no certificates found")
+	})
+}
+
 // TestAPIClient_InvalidURL - тест с невалидным адресом
 func TestAPIClient_InvalidURL(t *testing.T) {
 	ctx := context.Background()
@@ -1091,14 +1535,14 @@ func TestAPIClient_InvalidURL(t *testing.T) {
 	t.Run("Malformed base URL", func(t *testing.T) {
 		client := clients.NewAPIClient("://invalid-url")
 
-		err := client.Register(ctx, "test", "pass")
+		err := client.Register(ctx, "test", "pass", "")
 		require.Error(t, err)
 	})
 
 	t.Run("Empty base URL", func(t *testing.T) {
 		client := clients.NewAPIClient("")
 
-		err := client.Register(ctx, "test", "pass")
+		err := client.Register(ctx, "test", "pass", "")
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "unsupported protocol scheme")
 	})
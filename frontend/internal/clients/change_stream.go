@@ -0,0 +1,103 @@
+// clients - клиенты для взаимодействия с сервером
+package clients
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+)
+
+// changeEventName - имя события Server-Sent Events, которым сервер отправляет изменения
+const changeEventName = "change"
+
+// ChangeStream - поток событий об изменении данных пользователя (Server-Sent Events)
+type ChangeStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+}
+
+// NewChangeStream - читать события из тела ответа сервера
+func NewChangeStream(body io.ReadCloser) *ChangeStream {
+	return &ChangeStream{
+		body:   body,
+		reader: bufio.NewReader(body),
+	}
+}
+
+// Next - дождаться следующего изменения. Возвращает io.EOF, если сервер закрыл поток
+func (s *ChangeStream) Next() (entities.ChangeEvent, error) {
+	var name string
+	var data []string
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return entities.ChangeEvent{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// Пустая строка завершает событие
+		if line == "" {
+			if name == changeEventName && len(data) > 0 {
+				var event entities.ChangeEvent
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+					return entities.ChangeEvent{}, fmt.Errorf("invalid change event: %w", err)
+				}
+				return event, nil
+			}
+
+			name, data = "", nil
+			continue
+		}
+
+		// Комментарии (сервер отправляет их для поддержания соединения)
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// Close - закрыть поток
+func (s *ChangeStream) Close() error {
+	return s.body.Close()
+}
+
+// SubscribeChanges - подписаться на изменения данных пользователя, сделанные на других устройствах.
+// Поток действует до отмены контекста, закрытия или остановки сервера
+func (c *APIClient) SubscribeChanges(ctx context.Context) (*ChangeStream, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/user/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	// Без сжатия события приходят сразу, а не по мере заполнения буфера компрессора
+	req.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError("subscribe to changes", resp)
+	}
+
+	return NewChangeStream(resp.Body), nil
+}
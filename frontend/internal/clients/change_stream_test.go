@@ -0,0 +1,88 @@
+// clients_test - тесты для клиента
+package clients_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/frontend/internal/clients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChangeStream_Next - разбор потока событий
+func TestChangeStream_Next(t *testing.T) {
+	body := ": connected\n\n" +
+		": ping\n\n" +
+		"event: change\ndata: {\"action\":\"create\",\"entity_type\":\"text\",\"entity_id\":\"1\"}\n\n" +
+		"event: unknown\ndata: {}\n\n" +
+		"event:change\r\ndata:{\"action\":\"delete\",\"entity_type\":\"card\",\"entity_id\":\"2\"}\r\n\r\n" +
+		"event: change\ndata: not json\n\n"
+
+	stream := clients.NewChangeStream(io.NopCloser(strings.NewReader(body)))
+	defer stream.Close()
+
+	event, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "create", event.Action)
+	assert.Equal(t, "text", event.EntityType)
+	assert.Equal(t, "1", event.EntityID)
+
+	// Неизвестные события пропускаются, допускаются поля без пробела и переводы строк \r\n
+	event, err = stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "delete", event.Action)
+	assert.Equal(t, "card", event.EntityType)
+	assert.Equal(t, "2", event.EntityID)
+
+	_, err = stream.Next()
+	assert.ErrorContains(t, err, "invalid change event")
+
+	_, err = stream.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// TestAPIClient_SubscribeChanges - подписка на изменения
+func TestAPIClient_SubscribeChanges(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Successful subscription", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/user/events", r.URL.Path)
+			assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+			assert.Equal(t, "identity", r.Header.Get("Accept-Encoding"))
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, ": connected\n\nevent: change\ndata: {\"action\":\"update\",\"entity_type\":\"binary\",\"entity_id\":\"7\"}\n\n")
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+		stream, err := client.SubscribeChanges(ctx)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		event, err := stream.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "update", event.Action)
+		assert.Equal(t, "7", event.EntityID)
+
+		_, err = stream.Next()
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		server := testServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+		defer server.Close()
+
+		client := clients.NewAPIClient(server.URL)
+		_, err := client.SubscribeChanges(ctx)
+		assert.ErrorContains(t, err, "subscribe to changes failed with status: 401")
+	})
+}
@@ -3,6 +3,8 @@ package clients
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
@@ -10,30 +12,85 @@ import (
 
 // IAPIClient - интерфейс для API клиента
 type IAPIClient interface {
-	Register(ctx context.Context, login, password string) error
+	Register(ctx context.Context, login, password, inviteCode string) error
 	Login(ctx context.Context, login, password string) error
 
 	// Binary methods
 	CreateBinary(ctx context.Context, dto *dtos.NewBinaryData) (*entities.BinaryData, error)
+	GetBinary(ctx context.Context, id string) (*entities.BinaryData, error)
 	GetAllBinaries(ctx context.Context) ([]entities.BinaryData, error)
 	UpdateBinary(ctx context.Context, entity *entities.BinaryData) (*entities.BinaryData, error)
 	DeleteBinary(ctx context.Context, id string) error
 
 	// Card methods
 	CreateCard(ctx context.Context, dto *dtos.NewCardInformation) (*entities.CardInformation, error)
+	GetCard(ctx context.Context, id string) (*entities.CardInformation, error)
 	GetAllCards(ctx context.Context) ([]entities.CardInformation, error)
 	UpdateCard(ctx context.Context, entity *entities.CardInformation) (*entities.CardInformation, error)
 	DeleteCard(ctx context.Context, id string) error
 
 	// Credentials methods
 	CreateCredentials(ctx context.Context, dto *dtos.NewCredentials) (*entities.Credentials, error)
+	GetCredentials(ctx context.Context, id string) (*entities.Credentials, error)
 	GetAllCredentials(ctx context.Context) ([]entities.Credentials, error)
 	UpdateCredentials(ctx context.Context, entity *entities.Credentials) (*entities.Credentials, error)
 	DeleteCredentials(ctx context.Context, id string) error
 
 	// Text methods
 	CreateText(ctx context.Context, dto *dtos.NewTextData) (*entities.TextData, error)
+	GetText(ctx context.Context, id string) (*entities.TextData, error)
 	GetAllTexts(ctx context.Context) ([]entities.TextData, error)
 	UpdateText(ctx context.Context, entity *entities.TextData) (*entities.TextData, error)
 	DeleteText(ctx context.Context, id string) error
+
+	// Audit methods
+	GetAuditLog(ctx context.Context, from, to time.Time, entityType string) ([]entities.AuditEvent, error)
+
+	// Trash methods
+	GetTrash(ctx context.Context) ([]entities.TrashItem, error)
+	RestoreTrashItem(ctx context.Context, entityType, id string) error
+	DeleteTrashItem(ctx context.Context, entityType, id string) error
+
+	// History methods
+	GetHistory(ctx context.Context, entityType, id string) ([]entities.Revision, error)
+	RestoreRevision(ctx context.Context, entityType, id string, revision int) error
+
+	// Quota methods
+	GetUsage(ctx context.Context) (*entities.QuotaUsage, error)
+
+	// Account methods
+	ExportAccount(ctx context.Context, dst io.Writer) (int64, error)
+	DeleteAccount(ctx context.Context, password string) error
+
+	// Change notifications
+	SubscribeChanges(ctx context.Context) (*ChangeStream, error)
+
+	// Sharing methods
+	SetUserKeys(ctx context.Context, keys *entities.UserKeys) error
+	GetUserKeys(ctx context.Context, login string) (*entities.UserKeys, error)
+	ShareEntry(ctx context.Context, dto *dtos.NewShareGrant) (*entities.ShareGrant, error)
+	GetShares(ctx context.Context) ([]entities.ShareGrant, error)
+	AcceptShare(ctx context.Context, id string) error
+	DeleteShare(ctx context.Context, id string) error
+
+	// Organization methods
+	CreateOrganization(ctx context.Context, dto *dtos.NewOrganization) (*entities.Organization, error)
+	GetOrganizations(ctx context.Context) ([]entities.Organization, error)
+	GetOrgMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error)
+	InviteMember(ctx context.Context, orgID string, dto *dtos.NewOrgMember) (*entities.OrgMember, error)
+	AcceptInvite(ctx context.Context, orgID string) error
+	RemoveMember(ctx context.Context, orgID, login string) error
+	CreateCollection(ctx context.Context, orgID string, dto *dtos.NewCollection) (*entities.Collection, error)
+	GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error)
+	RotateCollectionKey(ctx context.Context, collectionID string, dto *dtos.CollectionRotation) (*entities.Collection, error)
+
+	// Emergency access methods
+	InviteEmergencyContact(ctx context.Context, dto *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error)
+	GetEmergencyAccess(ctx context.Context) ([]entities.EmergencyAccess, error)
+	AcceptEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error)
+	RequestEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error)
+	ApproveEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error)
+	RejectEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error)
+	DeleteEmergencyAccess(ctx context.Context, id string) error
+	OpenEmergencyVault(ctx context.Context, id string) (*entities.EmergencyVault, error)
 }
@@ -0,0 +1,135 @@
+// encryption - пакет для шифрования данных
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// entryKeySize - размер ключа записи (AES-256)
+const entryKeySize = 32
+
+// sealInfo - контекст вывода ключа шифрования из общего секрета X25519
+const sealInfo = "gophkeeper entry key"
+
+// NewCryptoServiceFromKey - создать сервис шифрования с готовым ключом (например, ключом записи)
+func NewCryptoServiceFromKey(key []byte) *CryptoService {
+	return &CryptoService{key: key}
+}
+
+// SealFor - зашифровать ключ этого сервиса (например, ключ хранилища) открытым ключом получателя
+func (c *CryptoService) SealFor(recipientPublicKey []byte) (string, error) {
+	return SealKey(recipientPublicKey, c.key)
+}
+
+// GenerateKeyPair - сгенерировать пару ключей X25519 для обмена записями
+func GenerateKeyPair() (publicKey, privateKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key.PublicKey().Bytes(), key.Bytes(), nil
+}
+
+// GenerateEntryKey - сгенерировать случайный ключ записи
+func GenerateEntryKey() ([]byte, error) {
+	key := make([]byte, entryKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// SealKey - зашифровать ключ записи открытым ключом получателя.
+// Результат (base64): одноразовый открытый ключ || nonce || шифртекст AES-GCM на ключе, выведенном HKDF из общего секрета
+func SealKey(recipientPublicKey, key []byte) (string, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(recipientPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := sealCipher(ephemeral, recipient, append(ephemeral.PublicKey().Bytes(), recipient.Bytes()...))
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := append(ephemeral.PublicKey().Bytes(), nonce...)
+	sealed = gcm.Seal(sealed, nonce, key, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenKey - расшифровать ключ записи закрытым ключом получателя
+func OpenKey(privateKey []byte, sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	own, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeySize := len(own.PublicKey().Bytes())
+	if len(data) < publicKeySize {
+		return nil, errors.New("sealed key too short")
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(data[:publicKeySize])
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := sealCipher(own, ephemeral, append(ephemeral.Bytes(), own.PublicKey().Bytes()...))
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[publicKeySize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("sealed key too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// sealCipher - AES-GCM на ключе, выведенном из общего секрета пары ключей X25519.
+// Соль - открытые ключи отправителя и получателя, чтобы ключ был привязан к обоим
+func sealCipher(private *ecdh.PrivateKey, public *ecdh.PublicKey, salt []byte) (cipher.AEAD, error) {
+	secret, err := private.ECDH(public)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hkdf.Key(sha256.New, secret, salt, sealInfo, entryKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
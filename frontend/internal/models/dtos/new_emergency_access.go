@@ -0,0 +1,9 @@
+// dtos - объекты для передачи данных
+package dtos
+
+// NewEmergencyAccess - назначить доверенное лицо для экстренного доступа к хранилищу
+type NewEmergencyAccess struct {
+	GranteeID    string `json:"grantee_id"`
+	WaitDays     int    `json:"wait_days"`
+	EncryptedKey string `json:"encrypted_key"` // ключ хранилища, зашифрованный открытым ключом доверенного лица
+}
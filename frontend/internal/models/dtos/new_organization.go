@@ -0,0 +1,30 @@
+// dtos - объекты для передачи данных
+package dtos
+
+import "github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+
+// NewOrganization - организация (dto - новая запись). Создатель становится её владельцем
+type NewOrganization struct {
+	Name string `json:"name"`
+}
+
+// NewOrgMember - приглашение пользователя в организацию.
+// Keys - текущие ключи всех коллекций организации, зашифрованные открытым ключом приглашённого
+type NewOrgMember struct {
+	Login string                   `json:"login"`
+	Role  string                   `json:"role"`
+	Keys  []entities.CollectionKey `json:"keys"`
+}
+
+// NewCollection - коллекция организации (dto - новая запись).
+// Keys - ключ коллекции, зашифрованный для каждого участника организации
+type NewCollection struct {
+	Name string                   `json:"name"`
+	Keys []entities.CollectionKey `json:"keys"`
+}
+
+// CollectionRotation - замена ключа коллекции: новый ключ версии Version, зашифрованный для каждого участника
+type CollectionRotation struct {
+	Version int                      `json:"version"`
+	Keys    []entities.CollectionKey `json:"keys"`
+}
@@ -1,7 +1,33 @@
 // dtos - объекты для передачи данных
 package dtos
 
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
 // NewSecureEntity - хранимая в менеджере паролей сущность (dto - новая запись)
 type NewSecureEntity struct {
+	// ID - идентификатор записи (UUIDv7), выбирается клиентом до отправки на сервер
+	ID       string `json:"id,omitempty"`
 	Metadata string `json:"metadata"`
+	// CollectionID - создать запись в коллекции организации (поля шифруются ключом коллекции версии KeyVersion)
+	CollectionID string `json:"collection_id,omitempty"`
+	KeyVersion   int    `json:"key_version,omitempty"`
+}
+
+// AssignID - выбрать идентификатор новой записи, если он ещё не выбран
+func (e *NewSecureEntity) AssignID() error {
+	if e.ID != "" {
+		return nil
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate entry id: %w", err)
+	}
+
+	e.ID = id.String()
+	return nil
 }
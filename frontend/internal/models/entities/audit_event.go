@@ -0,0 +1,17 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// AuditEvent - запись журнала операций пользователя (хранится только на сервере)
+type AuditEvent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	SessionID  string    `json:"session_id"`
+	DeviceID   string    `json:"device_id"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+}
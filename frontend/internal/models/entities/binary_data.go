@@ -4,6 +4,7 @@ package entities
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 
 	crypto "github.com/JustScorpio/GophKeeper/frontend/internal/encryption"
 )
@@ -63,7 +64,20 @@ func (entity *BinaryData) GetHash() string {
 	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.Metadata))
 	hasher.Write([]byte{0})
+	hasher.Write([]byte(entity.EntryKey))
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(entity.Permission))
+	hasher.Write([]byte{0})
 	hasher.Write(entity.Data)
 
 	return hex.EncodeToString(hasher.Sum(nil))
 }
+
+// Fields - поля бинарных данных для показа и сравнения версий (вместо содержимого - размер и отпечаток)
+func (entity *BinaryData) Fields() []Field {
+	sum := sha256.Sum256(entity.Data)
+	return []Field{
+		{Name: "Data", Value: fmt.Sprintf("%d bytes, sha256 %s", len(entity.Data), hex.EncodeToString(sum[:6]))},
+		{Name: "Metadata", Value: entity.Metadata},
+	}
+}
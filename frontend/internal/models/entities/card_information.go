@@ -114,6 +114,10 @@ func (entity *CardInformation) GetHash() string {
 	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.Metadata))
 	hasher.Write([]byte{0})
+	hasher.Write([]byte(entity.EntryKey))
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(entity.Permission))
+	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.Number))
 	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.CardHolder))
@@ -124,3 +128,14 @@ func (entity *CardInformation) GetHash() string {
 
 	return hex.EncodeToString(hasher.Sum(nil))
 }
+
+// Fields - поля карты для показа и сравнения версий
+func (entity *CardInformation) Fields() []Field {
+	return []Field{
+		{Name: "Card number", Value: entity.Number},
+		{Name: "Card holder", Value: entity.CardHolder},
+		{Name: "Expiration date", Value: entity.ExpirationDate},
+		{Name: "CVV", Value: entity.CVV},
+		{Name: "Metadata", Value: entity.Metadata},
+	}
+}
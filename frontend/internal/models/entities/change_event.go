@@ -0,0 +1,12 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// ChangeEvent - событие об изменении сущности на сервере (приходит в потоке событий, локально не хранится)
+type ChangeEvent struct {
+	Action     string    `json:"action"`      // create, update или delete
+	EntityType string    `json:"entity_type"` // binary, card, credentials или text
+	EntityID   string    `json:"entity_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
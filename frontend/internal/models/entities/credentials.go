@@ -80,9 +80,22 @@ func (entity *Credentials) GetHash() string {
 	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.Metadata))
 	hasher.Write([]byte{0})
+	hasher.Write([]byte(entity.EntryKey))
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(entity.Permission))
+	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.Login))
 	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.Password))
 
 	return hex.EncodeToString(hasher.Sum(nil))
 }
+
+// Fields - поля учётных данных для показа и сравнения версий
+func (entity *Credentials) Fields() []Field {
+	return []Field{
+		{Name: "Login", Value: entity.Login},
+		{Name: "Password", Value: entity.Password},
+		{Name: "Metadata", Value: entity.Metadata},
+	}
+}
@@ -0,0 +1,46 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// Состояния экстренного доступа
+const (
+	EmergencyStatusInvited   = "invited"   // доверитель назначил доверенное лицо
+	EmergencyStatusAccepted  = "accepted"  // доверенное лицо согласилось
+	EmergencyStatusRequested = "requested" // доверенное лицо запросило доступ, идёт период ожидания
+	EmergencyStatusGranted   = "granted"   // доступ предоставлен (доверителем или по истечении ожидания)
+)
+
+// EmergencyAccess - экстренный доступ доверенного лица GranteeID к хранилищу доверителя GrantorID
+type EmergencyAccess struct {
+	ID        string `json:"id"`
+	GrantorID string `json:"grantor_id"`
+	GranteeID string `json:"grantee_id"`
+	Status    string `json:"status"`
+	WaitDays  int    `json:"wait_days"`
+	// EncryptedKey - ключ хранилища доверителя, зашифрованный открытым ключом доверенного лица
+	// (сервер отдаёт его только доверенному лицу после предоставления доступа)
+	EncryptedKey string     `json:"encrypted_key,omitempty"`
+	RequestedAt  *time.Time `json:"requested_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// GrantAt - когда запрошенный доступ будет предоставлен автоматически (nil, если доступ не запрошен)
+func (a *EmergencyAccess) GrantAt() *time.Time {
+	if a.RequestedAt == nil {
+		return nil
+	}
+
+	grantAt := a.RequestedAt.Add(time.Duration(a.WaitDays) * 24 * time.Hour)
+	return &grantAt
+}
+
+// EmergencyVault - хранилище доверителя, открытое доверенному лицу
+type EmergencyVault struct {
+	Access              EmergencyAccess   `json:"access"`
+	EncryptedPrivateKey string            `json:"encrypted_private_key,omitempty"` // закрытый ключ доверителя, зашифрованный ключом его хранилища
+	Binaries            []BinaryData      `json:"binaries"`
+	Cards               []CardInformation `json:"cards"`
+	Credentials         []Credentials     `json:"credentials"`
+	Texts               []TextData        `json:"texts"`
+}
@@ -0,0 +1,58 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// Роли участников организации
+const (
+	RoleOwner    = "owner"
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "readonly"
+)
+
+// Состояния участия в организации
+const (
+	MemberStatusInvited = "invited"
+	MemberStatusActive  = "active"
+)
+
+// Organization - организация с общими коллекциями записей
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   string    `json:"owner_id"`
+	Role      string    `json:"role,omitempty"`   // роль текущего пользователя
+	Status    string    `json:"status,omitempty"` // участие текущего пользователя
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrgMember - участник организации
+type OrgMember struct {
+	OrgID     string    `json:"org_id"`
+	Login     string    `json:"login"`
+	Role      string    `json:"role"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Collection - коллекция записей организации, зашифрованных общим ключом коллекции
+type Collection struct {
+	ID         string `json:"id"`
+	OrgID      string `json:"org_id"`
+	Name       string `json:"name"`
+	KeyVersion int    `json:"key_version"`
+	// RotationRequired - из организации удалён участник: ключ коллекции нужно заменить, а записи перешифровать
+	RotationRequired bool `json:"rotation_required"`
+	// EncryptedKey - текущий ключ коллекции, зашифрованный открытым ключом текущего пользователя
+	EncryptedKey string    `json:"encrypted_key,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CollectionKey - ключ коллекции версии Version, зашифрованный открытым ключом участника Login
+type CollectionKey struct {
+	CollectionID string `json:"collection_id"`
+	Login        string `json:"login"`
+	Version      int    `json:"version"`
+	EncryptedKey string `json:"encrypted_key"`
+}
@@ -0,0 +1,32 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision - прежняя версия записи на сервере (локально не хранится). Content - поля записи своего типа
+// в зашифрованном виде, EntryKey и KeyVersion - ключ, которым они зашифрованы (как у самой записи)
+type Revision struct {
+	EntityType string          `json:"entity_type"` // binary, card, credentials или text
+	EntityID   string          `json:"entity_id"`
+	Revision   int             `json:"revision"`
+	Content    json.RawMessage `json:"content"`
+	Metadata   string          `json:"metadata"`
+	EntryKey   string          `json:"entry_key,omitempty"`
+	KeyVersion int             `json:"key_version,omitempty"`
+	// Restorable - версия зашифрована текущим ключом записи, и сервер может её восстановить
+	Restorable bool      `json:"restorable"`
+	ReplacedBy string    `json:"replaced_by"`
+	ReplacedAt time.Time `json:"replaced_at"`
+
+	// Fields - расшифрованные поля версии для показа (заполняет сервис при получении истории)
+	Fields []Field `json:"-"`
+}
+
+// Field - поле записи в виде для показа пользователю
+type Field struct {
+	Name  string
+	Value string
+}
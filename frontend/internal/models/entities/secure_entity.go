@@ -1,8 +1,38 @@
 // entities содержит модели сущностей которые хранятся в БД
 package entities
 
+// Права доступа к чужой записи
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+)
+
 // SecureEntity - хранимая в менеджере паролей сущность
 type SecureEntity struct {
 	ID       string `json:"id"`
 	Metadata string `json:"metadata"`
+	OwnerID  string `json:"owner_id,omitempty"`
+	// EntryKey - ключ записи, зашифрованный открытым ключом пользователя. Пустой - поля зашифрованы ключом хранилища
+	EntryKey string `json:"entry_key,omitempty"`
+	// Permission - права на чужую запись (пусто для собственных записей)
+	Permission string `json:"permission,omitempty"`
+	// CollectionID - коллекция организации, к которой относится запись. EntryKey такой записи -
+	// ключ коллекции версии KeyVersion, зашифрованный открытым ключом пользователя
+	CollectionID string `json:"collection_id,omitempty"`
+	KeyVersion   int    `json:"key_version,omitempty"`
+}
+
+// IsShared - запись принадлежит другому пользователю
+func (e *SecureEntity) IsShared() bool {
+	return e.Permission != ""
+}
+
+// CanEdit - запись можно изменять (собственная или чужая с правом записи)
+func (e *SecureEntity) CanEdit() bool {
+	return e.Permission == "" || e.Permission == PermissionWrite
+}
+
+// Secure - общая часть сущности (для обобщённой обработки записей разных типов)
+func (e *SecureEntity) Secure() *SecureEntity {
+	return e
 }
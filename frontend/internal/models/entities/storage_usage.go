@@ -0,0 +1,23 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+// StorageUsage - количество записей пользователя на сервере по типам и объём их хранимых полей в байтах
+type StorageUsage struct {
+	Binaries    int   `json:"binaries"`
+	Cards       int   `json:"cards"`
+	Credentials int   `json:"credentials"`
+	Texts       int   `json:"texts"`
+	Bytes       int64 `json:"bytes"`
+}
+
+// Quota - ограничения хранилища пользователя: количество записей каждого типа и общий объём в байтах (0 - без ограничения)
+type Quota struct {
+	MaxEntries int   `json:"max_entries"`
+	MaxBytes   int64 `json:"max_bytes"`
+}
+
+// QuotaUsage - текущее использование хранилища пользователя и действующая для него квота (хранится только на сервере)
+type QuotaUsage struct {
+	Usage StorageUsage `json:"usage"`
+	Quota Quota        `json:"quota"`
+}
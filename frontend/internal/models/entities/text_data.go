@@ -63,7 +63,19 @@ func (entity *TextData) GetHash() string {
 	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.Metadata))
 	hasher.Write([]byte{0})
+	hasher.Write([]byte(entity.EntryKey))
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(entity.Permission))
+	hasher.Write([]byte{0})
 	hasher.Write([]byte(entity.Data))
 
 	return hex.EncodeToString(hasher.Sum(nil))
 }
+
+// Fields - поля текста для показа и сравнения версий
+func (entity *TextData) Fields() []Field {
+	return []Field{
+		{Name: "Data", Value: entity.Data},
+		{Name: "Metadata", Value: entity.Metadata},
+	}
+}
@@ -0,0 +1,12 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+import "time"
+
+// TrashItem - удалённая запись в корзине на сервере (локально не хранится). Сервер возвращает только общие поля записи:
+// по описанию её можно узнать, а содержимое снова станет доступно после восстановления
+type TrashItem struct {
+	SecureEntity
+	EntityType string    `json:"entity_type"` // binary, card, credentials или text
+	DeletedAt  time.Time `json:"deleted_at"`
+}
@@ -0,0 +1,9 @@
+// entities содержит модели сущностей которые хранятся в БД
+package entities
+
+// UserKeys - пара ключей X25519 пользователя (base64). Закрытый ключ передаётся только зашифрованным ключом хранилища
+type UserKeys struct {
+	Login               string `json:"login"`
+	PublicKey           string `json:"public_key"`
+	EncryptedPrivateKey string `json:"encrypted_private_key,omitempty"`
+}
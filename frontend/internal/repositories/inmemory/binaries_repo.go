@@ -5,12 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // InMemoryBinariesRepo - репозиторий с бинарными данными в памяти
 type InMemoryBinariesRepo struct {
+	mu      sync.RWMutex
 	storage map[string]entities.BinaryData
 }
 
@@ -23,6 +26,12 @@ func NewInMemoryBinariesRepo() *InMemoryBinariesRepo {
 
 // GetAll - получить все сущности
 func (r *InMemoryBinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	binaries := make([]entities.BinaryData, 0, len(r.storage))
 	for _, binary := range r.storage {
 		binaries = append(binaries, binary)
@@ -33,6 +42,12 @@ func (r *InMemoryBinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryDat
 
 // Get - получить сущность по ИД
 func (r *InMemoryBinariesRepo) Get(ctx context.Context, id string) (*entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	binary, exists := r.storage[id]
 	if !exists {
 		return nil, nil
@@ -43,6 +58,12 @@ func (r *InMemoryBinariesRepo) Get(ctx context.Context, id string) (*entities.Bi
 
 // Create - создать сущность
 func (r *InMemoryBinariesRepo) Create(ctx context.Context, entity *entities.BinaryData) (*entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -62,13 +83,19 @@ func (r *InMemoryBinariesRepo) Create(ctx context.Context, entity *entities.Bina
 
 // Update - изменить сущность
 func (r *InMemoryBinariesRepo) Update(ctx context.Context, entity *entities.BinaryData) (*entities.BinaryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
 
 	// Проверяем существование
 	if _, exists := r.storage[entity.ID]; !exists {
-		return nil, fmt.Errorf("binary with ID %s not found", entity.ID)
+		return nil, fmt.Errorf("binary with ID %s %w", entity.ID, repositories.ErrNotFound)
 	}
 
 	r.storage[entity.ID] = *entity
@@ -77,8 +104,14 @@ func (r *InMemoryBinariesRepo) Update(ctx context.Context, entity *entities.Bina
 
 // Delete - удалить сущность
 func (r *InMemoryBinariesRepo) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.storage[id]; !exists {
-		return fmt.Errorf("binary with ID %s not found", id)
+		return fmt.Errorf("binary with ID %s %w", id, repositories.ErrNotFound)
 	}
 
 	delete(r.storage, id)
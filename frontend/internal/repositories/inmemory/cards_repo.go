@@ -5,12 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // InMemoryCardsRepo - репозиторий с данными банковских карт в памяти
 type InMemoryCardsRepo struct {
+	mu      sync.RWMutex
 	storage map[string]entities.CardInformation
 }
 
@@ -23,6 +26,12 @@ func NewInMemoryCardsRepo() *InMemoryCardsRepo {
 
 // GetAll - получить все сущности
 func (r *InMemoryCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	cards := make([]entities.CardInformation, 0, len(r.storage))
 	for _, card := range r.storage {
 		cards = append(cards, card)
@@ -33,6 +42,12 @@ func (r *InMemoryCardsRepo) GetAll(ctx context.Context) ([]entities.CardInformat
 
 // Get - получить сущность по ИД
 func (r *InMemoryCardsRepo) Get(ctx context.Context, id string) (*entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	card, exists := r.storage[id]
 	if !exists {
 		return nil, nil
@@ -43,6 +58,12 @@ func (r *InMemoryCardsRepo) Get(ctx context.Context, id string) (*entities.CardI
 
 // Create - создать сущность
 func (r *InMemoryCardsRepo) Create(ctx context.Context, entity *entities.CardInformation) (*entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -62,13 +83,19 @@ func (r *InMemoryCardsRepo) Create(ctx context.Context, entity *entities.CardInf
 
 // Update - изменить сущность
 func (r *InMemoryCardsRepo) Update(ctx context.Context, entity *entities.CardInformation) (*entities.CardInformation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
 
 	// Проверяем существование
 	if _, exists := r.storage[entity.ID]; !exists {
-		return nil, fmt.Errorf("card with ID %s not found", entity.ID)
+		return nil, fmt.Errorf("card with ID %s %w", entity.ID, repositories.ErrNotFound)
 	}
 
 	r.storage[entity.ID] = *entity
@@ -77,8 +104,14 @@ func (r *InMemoryCardsRepo) Update(ctx context.Context, entity *entities.CardInf
 
 // Delete - удалить сущность
 func (r *InMemoryCardsRepo) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.storage[id]; !exists {
-		return fmt.Errorf("card with ID %s not found", id)
+		return fmt.Errorf("card with ID %s %w", id, repositories.ErrNotFound)
 	}
 
 	delete(r.storage, id)
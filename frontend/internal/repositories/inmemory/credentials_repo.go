@@ -5,12 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // InMemoryCredentialsRepo - репозиторий с учётными данными в памяти
 type InMemoryCredentialsRepo struct {
+	mu      sync.RWMutex
 	storage map[string]entities.Credentials
 }
 
@@ -23,6 +26,12 @@ func NewInMemoryCredentialsRepo() *InMemoryCredentialsRepo {
 
 // GetAll - получить все сущности
 func (r *InMemoryCredentialsRepo) GetAll(ctx context.Context) ([]entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	creds := make([]entities.Credentials, 0, len(r.storage))
 	for _, cred := range r.storage {
 		creds = append(creds, cred)
@@ -33,6 +42,12 @@ func (r *InMemoryCredentialsRepo) GetAll(ctx context.Context) ([]entities.Creden
 
 // Get - получить сущность по ИД
 func (r *InMemoryCredentialsRepo) Get(ctx context.Context, id string) (*entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	cred, exists := r.storage[id]
 	if !exists {
 		return nil, nil
@@ -43,6 +58,12 @@ func (r *InMemoryCredentialsRepo) Get(ctx context.Context, id string) (*entities
 
 // Create - создать сущность
 func (r *InMemoryCredentialsRepo) Create(ctx context.Context, entity *entities.Credentials) (*entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -62,13 +83,19 @@ func (r *InMemoryCredentialsRepo) Create(ctx context.Context, entity *entities.C
 
 // Update - изменить сущность
 func (r *InMemoryCredentialsRepo) Update(ctx context.Context, entity *entities.Credentials) (*entities.Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
 
 	// Проверяем существование
 	if _, exists := r.storage[entity.ID]; !exists {
-		return nil, fmt.Errorf("credentials with ID %s not found", entity.ID)
+		return nil, fmt.Errorf("credentials with ID %s %w", entity.ID, repositories.ErrNotFound)
 	}
 
 	r.storage[entity.ID] = *entity
@@ -77,8 +104,14 @@ func (r *InMemoryCredentialsRepo) Update(ctx context.Context, entity *entities.C
 
 // Delete - удалить сущность
 func (r *InMemoryCredentialsRepo) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.storage[id]; !exists {
-		return fmt.Errorf("credentials with ID %s not found", id)
+		return fmt.Errorf("credentials with ID %s %w", id, repositories.ErrNotFound)
 	}
 
 	delete(r.storage, id)
@@ -0,0 +1,20 @@
+// inmemory - репозиторий хранящий данные воперативной памяти
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories/repotest"
+)
+
+func TestRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		manager := NewDatabaseManager()
+		return repotest.Repos{
+			Binaries:    manager.BinariesRepo,
+			Cards:       manager.CardsRepo,
+			Credentials: manager.CredentialsRepo,
+			Texts:       manager.TextsRepo,
+		}
+	})
+}
@@ -5,12 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // InMemoryTextsRepo - репозиторий с текстовыми данными в памяти
 type InMemoryTextsRepo struct {
+	mu      sync.RWMutex
 	storage map[string]entities.TextData
 }
 
@@ -23,6 +26,12 @@ func NewInMemoryTextsRepo() *InMemoryTextsRepo {
 
 // GetAll - получить все сущности
 func (r *InMemoryTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	texts := make([]entities.TextData, 0, len(r.storage))
 	for _, text := range r.storage {
 		texts = append(texts, text)
@@ -33,6 +42,12 @@ func (r *InMemoryTextsRepo) GetAll(ctx context.Context) ([]entities.TextData, er
 
 // Get - получить сущность по ИД
 func (r *InMemoryTextsRepo) Get(ctx context.Context, id string) (*entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	text, exists := r.storage[id]
 	if !exists {
 		return nil, nil
@@ -43,6 +58,12 @@ func (r *InMemoryTextsRepo) Get(ctx context.Context, id string) (*entities.TextD
 
 // Create - создать сущность
 func (r *InMemoryTextsRepo) Create(ctx context.Context, entity *entities.TextData) (*entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
@@ -62,13 +83,19 @@ func (r *InMemoryTextsRepo) Create(ctx context.Context, entity *entities.TextDat
 
 // Update - изменить сущность
 func (r *InMemoryTextsRepo) Update(ctx context.Context, entity *entities.TextData) (*entities.TextData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
 	}
 
 	// Проверяем существование
 	if _, exists := r.storage[entity.ID]; !exists {
-		return nil, fmt.Errorf("text with ID %s not found", entity.ID)
+		return nil, fmt.Errorf("text with ID %s %w", entity.ID, repositories.ErrNotFound)
 	}
 
 	r.storage[entity.ID] = *entity
@@ -77,8 +104,14 @@ func (r *InMemoryTextsRepo) Update(ctx context.Context, entity *entities.TextDat
 
 // Delete - удалить сущность
 func (r *InMemoryTextsRepo) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.storage[id]; !exists {
-		return fmt.Errorf("text with ID %s not found", id)
+		return fmt.Errorf("text with ID %s %w", id, repositories.ErrNotFound)
 	}
 
 	delete(r.storage, id)
@@ -3,18 +3,24 @@ package repositories
 
 import (
 	"context"
+	"errors"
 )
 
-// Interface реализация паттерна "репозиторий"
+// ErrNotFound - изменяемой или удаляемой сущности нет
+var ErrNotFound = errors.New("not found")
+
+// Interface реализация паттерна "репозиторий".
+// Реализации допускают конкурентные вызовы и прерывают операцию при отмене контекста.
+// Поведение проверяет общий набор тестов repotest
 type IRepository[Entity any] interface {
 	// GetAll - получить все сущности
 	GetAll(ctx context.Context) ([]Entity, error)
-	// Get - получить сущность по ИД
+	// Get - получить сущность по ИД (nil, если её нет)
 	Get(ctx context.Context, id string) (*Entity, error)
-	// Create - создать сущность
+	// Create - создать сущность (ошибка, если ИД занят)
 	Create(ctx context.Context, entity *Entity) (*Entity, error)
-	// Update - изменить сущность
+	// Update - изменить сущность (ErrNotFound, если её нет)
 	Update(ctx context.Context, entity *Entity) (*Entity, error)
-	// Delete - удалить сущность
+	// Delete - удалить сущность (ErrNotFound, если её нет)
 	Delete(ctx context.Context, id string) error
 }
@@ -0,0 +1,258 @@
+// repotest - общий набор тестов, которые должна проходить каждая реализация репозиториев клиента
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Repos - проверяемые репозитории записей
+type Repos struct {
+	Binaries    repositories.IRepository[entities.BinaryData]
+	Cards       repositories.IRepository[entities.CardInformation]
+	Credentials repositories.IRepository[entities.Credentials]
+	Texts       repositories.IRepository[entities.TextData]
+}
+
+// Entry - запись, с общей частью которой работает набор тестов
+type Entry[T any] interface {
+	*T
+	Secure() *entities.SecureEntity
+}
+
+// Run - проверить все репозитории записей. newRepos вызывается для каждого теста и возвращает пустые репозитории
+func Run(t *testing.T, newRepos func(t *testing.T) Repos) {
+	t.Run("binary", func(t *testing.T) {
+		RunEntries(t, func(t *testing.T) repositories.IRepository[entities.BinaryData] { return newRepos(t).Binaries })
+	})
+	t.Run("card", func(t *testing.T) {
+		RunEntries(t, func(t *testing.T) repositories.IRepository[entities.CardInformation] { return newRepos(t).Cards })
+	})
+	t.Run("credentials", func(t *testing.T) {
+		RunEntries(t, func(t *testing.T) repositories.IRepository[entities.Credentials] { return newRepos(t).Credentials })
+	})
+	t.Run("text", func(t *testing.T) {
+		RunEntries(t, func(t *testing.T) repositories.IRepository[entities.TextData] { return newRepos(t).Texts })
+	})
+}
+
+// RunEntries - проверить репозиторий записей одного типа
+func RunEntries[T any, PT Entry[T]](t *testing.T, newRepo func(t *testing.T) repositories.IRepository[T]) {
+	ctx := context.Background()
+
+	t.Run("CRUD", func(t *testing.T) {
+		repo := newRepo(t)
+		entity := newEntry[T, PT]("entry-1", "alice")
+
+		created, err := repo.Create(ctx, entity)
+		require.NoError(t, err)
+		assert.Equal(t, entity, created)
+
+		// Изменение переданной сущности после создания не затрагивает хранилище
+		PT(entity).Secure().Metadata = "changed outside"
+
+		got, err := repo.Get(ctx, "entry-1")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "metadata of entry-1", PT(got).Secure().Metadata)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+
+		PT(got).Secure().Metadata = "updated"
+		updated, err := repo.Update(ctx, got)
+		require.NoError(t, err)
+		assert.Equal(t, "updated", PT(updated).Secure().Metadata)
+
+		got, err = repo.Get(ctx, "entry-1")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "updated", PT(got).Secure().Metadata)
+
+		require.NoError(t, repo.Delete(ctx, "entry-1"))
+
+		got, err = repo.Get(ctx, "entry-1")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+
+		all, err = repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("Повторный ИД", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Create(ctx, newEntry[T, PT]("entry-1", "alice"))
+		require.NoError(t, err)
+
+		duplicate := newEntry[T, PT]("entry-1", "alice")
+		PT(duplicate).Secure().Metadata = "duplicate"
+		_, err = repo.Create(ctx, duplicate)
+		assert.Error(t, err)
+
+		got, err := repo.Get(ctx, "entry-1")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "metadata of entry-1", PT(got).Secure().Metadata)
+	})
+
+	t.Run("Отсутствующая запись", func(t *testing.T) {
+		repo := newRepo(t)
+
+		got, err := repo.Get(ctx, "missing")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+
+		_, err = repo.Update(ctx, newEntry[T, PT]("missing", "alice"))
+		assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+		assert.ErrorIs(t, repo.Delete(ctx, "missing"), repositories.ErrNotFound)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all, "изменение отсутствующей записи её не создаёт")
+	})
+
+	// Локальное хранилище содержит и собственные, и чужие (доступные пользователю) записи
+	t.Run("Записи разных владельцев", func(t *testing.T) {
+		repo := newRepo(t)
+		own := newEntry[T, PT]("own", "alice")
+		shared := newEntry[T, PT]("shared", "bob")
+		PT(shared).Secure().Permission = entities.PermissionRead
+		PT(shared).Secure().EntryKey = "key for alice"
+
+		for _, entity := range []*T{own, shared} {
+			_, err := repo.Create(ctx, entity)
+			require.NoError(t, err)
+		}
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+
+		got, err := repo.Get(ctx, "shared")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, shared, got)
+
+		require.NoError(t, repo.Delete(ctx, "own"))
+		got, err = repo.Get(ctx, "shared")
+		require.NoError(t, err)
+		assert.NotNil(t, got, "удаление одной записи не затрагивает другие")
+	})
+
+	t.Run("Отменённый контекст", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Create(ctx, newEntry[T, PT]("existing", "alice"))
+		require.NoError(t, err)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err = repo.GetAll(cancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Get(cancelled, "existing")
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Create(cancelled, newEntry[T, PT]("new", "alice"))
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = repo.Update(cancelled, newEntry[T, PT]("existing", "alice"))
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, repo.Delete(cancelled, "existing"), context.Canceled)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1, "операции с отменённым контекстом не выполняются")
+		assert.Equal(t, "existing", PT(&all[0]).Secure().ID)
+	})
+
+	t.Run("Конкурентный доступ", func(t *testing.T) {
+		repo := newRepo(t)
+		const workers, perWorker = 8, 10
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				errs <- concurrentWork[T, PT](ctx, repo, w, perWorker)
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, workers*perWorker/2, "каждый поток удаляет половину созданных записей")
+	})
+}
+
+// concurrentWork - создать, прочитать, изменить и удалить записи одного потока
+func concurrentWork[T any, PT Entry[T]](ctx context.Context, repo repositories.IRepository[T], worker, count int) error {
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("entry-%d-%d", worker, i)
+		if _, err := repo.Create(ctx, newEntry[T, PT](id, "alice")); err != nil {
+			return err
+		}
+
+		got, err := repo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if got == nil {
+			return fmt.Errorf("entry %s not found after create", id)
+		}
+
+		PT(got).Secure().Metadata = "updated"
+		if _, err := repo.Update(ctx, got); err != nil {
+			return err
+		}
+
+		if _, err := repo.GetAll(ctx); err != nil {
+			return err
+		}
+
+		if i%2 == 1 {
+			if err := repo.Delete(ctx, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// newEntry - новая запись с указанным ИД и владельцем
+func newEntry[T any, PT Entry[T]](id, owner string) *T {
+	entity := new(T)
+	secure := PT(entity).Secure()
+	secure.ID = id
+	secure.OwnerID = owner
+	secure.Metadata = "metadata of " + id
+
+	// Данные самой записи, чтобы проверить их сохранение вместе с общей частью
+	switch entry := any(entity).(type) {
+	case *entities.BinaryData:
+		entry.Data = []byte("data of " + id)
+	case *entities.CardInformation:
+		entry.Number, entry.CardHolder, entry.ExpirationDate, entry.CVV = "4111111111111111", owner, "12/30", "123"
+	case *entities.Credentials:
+		entry.Login, entry.Password = owner, "password of "+id
+	case *entities.TextData:
+		entry.Data = "text of " + id
+	}
+
+	return entity
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // BinariesRepo - репозиторий с бинарными данными
@@ -22,7 +23,7 @@ func NewBinariesRepo(db *sql.DB) (*BinariesRepo, error) {
 
 // GetAll - получить все сущности
 func (r *BinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryData, error) {
-	rows, err := r.db.Query("SELECT id, data, metadata FROM binaries")
+	rows, err := r.db.QueryContext(ctx, "SELECT id, data, metadata, owner_id, entry_key, permission, collection_id, key_version FROM binaries")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entities: %w", err)
 	}
@@ -36,7 +37,7 @@ func (r *BinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryData, error
 	var binaries []entities.BinaryData
 	for rows.Next() {
 		var binary entities.BinaryData
-		err := rows.Scan(&binary.ID, &binary.Data, &binary.Metadata)
+		err := rows.Scan(&binary.ID, &binary.Data, &binary.Metadata, &binary.OwnerID, &binary.EntryKey, &binary.Permission, &binary.CollectionID, &binary.KeyVersion)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan entity: %w", err)
 		}
@@ -49,7 +50,7 @@ func (r *BinariesRepo) GetAll(ctx context.Context) ([]entities.BinaryData, error
 // Get - получить сущность по ИД
 func (r *BinariesRepo) Get(ctx context.Context, id string) (*entities.BinaryData, error) {
 	var binaryData entities.BinaryData
-	err := r.db.QueryRow("SELECT id, data, metadata FROM binaries WHERE id = ?", id).Scan(&binaryData.ID, &binaryData.Data, &binaryData.Metadata)
+	err := r.db.QueryRowContext(ctx, "SELECT id, data, metadata, owner_id, entry_key, permission, collection_id, key_version FROM binaries WHERE id = ?", id).Scan(&binaryData.ID, &binaryData.Data, &binaryData.Metadata, &binaryData.OwnerID, &binaryData.EntryKey, &binaryData.Permission, &binaryData.CollectionID, &binaryData.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -64,7 +65,7 @@ func (r *BinariesRepo) Get(ctx context.Context, id string) (*entities.BinaryData
 // Create - создать сущность
 func (r *BinariesRepo) Create(ctx context.Context, entity *entities.BinaryData) (*entities.BinaryData, error) {
 	var binary entities.BinaryData
-	err := r.db.QueryRow("INSERT INTO binaries (id, data, metadata) VALUES (?, ?, ?) RETURNING id, data, metadata", entity.ID, entity.Data, entity.Metadata).Scan(&binary.ID, &binary.Data, &binary.Metadata)
+	err := r.db.QueryRowContext(ctx, "INSERT INTO binaries (id, data, metadata, owner_id, entry_key, permission, collection_id, key_version) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, data, metadata, owner_id, entry_key, permission, collection_id, key_version", entity.ID, entity.Data, entity.Metadata, entity.OwnerID, entity.EntryKey, entity.Permission, entity.CollectionID, entity.KeyVersion).Scan(&binary.ID, &binary.Data, &binary.Metadata, &binary.OwnerID, &binary.EntryKey, &binary.Permission, &binary.CollectionID, &binary.KeyVersion)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create entity: %w", err)
@@ -76,9 +77,12 @@ func (r *BinariesRepo) Create(ctx context.Context, entity *entities.BinaryData)
 // Update - изменить сущность
 func (r *BinariesRepo) Update(ctx context.Context, entity *entities.BinaryData) (*entities.BinaryData, error) {
 	var updatedBinary entities.BinaryData
-	err := r.db.QueryRow("UPDATE binaries SET data = ?, metadata = ? WHERE id = ? RETURNING id, data, metadata", entity.Data, entity.Metadata, entity.ID).Scan(&updatedBinary.ID, &updatedBinary.Data, &updatedBinary.Metadata)
+	err := r.db.QueryRowContext(ctx, "UPDATE binaries SET data = ?, metadata = ?, owner_id = ?, entry_key = ?, permission = ?, collection_id = ?, key_version = ? WHERE id = ? RETURNING id, data, metadata, owner_id, entry_key, permission, collection_id, key_version", entity.Data, entity.Metadata, entity.OwnerID, entity.EntryKey, entity.Permission, entity.CollectionID, entity.KeyVersion, entity.ID).Scan(&updatedBinary.ID, &updatedBinary.Data, &updatedBinary.Metadata, &updatedBinary.OwnerID, &updatedBinary.EntryKey, &updatedBinary.Permission, &updatedBinary.CollectionID, &updatedBinary.KeyVersion)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("binary with ID %s %w", entity.ID, repositories.ErrNotFound)
+		}
 		return nil, fmt.Errorf("failed to update entity: %w", err)
 	}
 
@@ -87,6 +91,18 @@ func (r *BinariesRepo) Update(ctx context.Context, entity *entities.BinaryData)
 
 // Delete - удалить сущность
 func (r *BinariesRepo) Delete(ctx context.Context, id string) error {
-	_, err := r.db.Exec("DELETE FROM binaries WHERE id = ?", id)
-	return err
+	result, err := r.db.ExecContext(ctx, "DELETE FROM binaries WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete binary: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete binary: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("binary with ID %s %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
 }
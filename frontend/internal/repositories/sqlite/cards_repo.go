@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // CardsRepo - репозиторий с данными банковских карт
@@ -22,7 +23,7 @@ func NewCardsRepo(db *sql.DB) (*CardsRepo, error) {
 
 // GetAll - получить все сущности
 func (r *CardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, error) {
-	rows, err := r.db.Query("SELECT id, number, card_holder, expiration_date, cvv, metadata FROM cards")
+	rows, err := r.db.QueryContext(ctx, "SELECT id, number, card_holder, expiration_date, cvv, metadata, owner_id, entry_key, permission, collection_id, key_version FROM cards")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cards: %w", err)
 	}
@@ -36,7 +37,7 @@ func (r *CardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, err
 	var cards []entities.CardInformation
 	for rows.Next() {
 		var card entities.CardInformation
-		err := rows.Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata)
+		err := rows.Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata, &card.OwnerID, &card.EntryKey, &card.Permission, &card.CollectionID, &card.KeyVersion)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan card: %w", err)
 		}
@@ -49,7 +50,7 @@ func (r *CardsRepo) GetAll(ctx context.Context) ([]entities.CardInformation, err
 // Get - получить сущность по ИД
 func (r *CardsRepo) Get(ctx context.Context, id string) (*entities.CardInformation, error) {
 	var card entities.CardInformation
-	err := r.db.QueryRow("SELECT id, number, card_holder, expiration_date, cvv, metadata FROM cards WHERE id = ?", id).Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata)
+	err := r.db.QueryRowContext(ctx, "SELECT id, number, card_holder, expiration_date, cvv, metadata, owner_id, entry_key, permission, collection_id, key_version FROM cards WHERE id = ?", id).Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata, &card.OwnerID, &card.EntryKey, &card.Permission, &card.CollectionID, &card.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -64,9 +65,9 @@ func (r *CardsRepo) Get(ctx context.Context, id string) (*entities.CardInformati
 // Create - создать сущность
 func (r *CardsRepo) Create(ctx context.Context, entity *entities.CardInformation) (*entities.CardInformation, error) {
 	var card entities.CardInformation
-	err := r.db.QueryRow(
-		"INSERT INTO cards (id, number, card_holder, expiration_date, cvv, metadata) VALUES (?, ?, ?, ?, ?, ?) RETURNING id, number, card_holder, expiration_date, cvv, metadata", entity.ID, entity.Number, entity.CardHolder, entity.ExpirationDate, entity.CVV, entity.Metadata,
-	).Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata)
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO cards (id, number, card_holder, expiration_date, cvv, metadata, owner_id, entry_key, permission, collection_id, key_version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, number, card_holder, expiration_date, cvv, metadata, owner_id, entry_key, permission, collection_id, key_version", entity.ID, entity.Number, entity.CardHolder, entity.ExpirationDate, entity.CVV, entity.Metadata, entity.OwnerID, entity.EntryKey, entity.Permission, entity.CollectionID, entity.KeyVersion,
+	).Scan(&card.ID, &card.Number, &card.CardHolder, &card.ExpirationDate, &card.CVV, &card.Metadata, &card.OwnerID, &card.EntryKey, &card.Permission, &card.CollectionID, &card.KeyVersion)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create card: %w", err)
@@ -78,9 +79,12 @@ func (r *CardsRepo) Create(ctx context.Context, entity *entities.CardInformation
 // Update - изменить сущность
 func (r *CardsRepo) Update(ctx context.Context, entity *entities.CardInformation) (*entities.CardInformation, error) {
 	var updatedCard entities.CardInformation
-	err := r.db.QueryRow("UPDATE cards SET number = ?, card_holder = ?, expiration_date = ?, cvv = ?, metadata = ? WHERE id = ? RETURNING id, number, card_holder, expiration_date, cvv, metadata", entity.Number, entity.CardHolder, entity.ExpirationDate, entity.CVV, entity.Metadata, entity.ID).Scan(&updatedCard.ID, &updatedCard.Number, &updatedCard.CardHolder, &updatedCard.ExpirationDate, &updatedCard.CVV, &updatedCard.Metadata)
+	err := r.db.QueryRowContext(ctx, "UPDATE cards SET number = ?, card_holder = ?, expiration_date = ?, cvv = ?, metadata = ?, owner_id = ?, entry_key = ?, permission = ?, collection_id = ?, key_version = ? WHERE id = ? RETURNING id, number, card_holder, expiration_date, cvv, metadata, owner_id, entry_key, permission, collection_id, key_version", entity.Number, entity.CardHolder, entity.ExpirationDate, entity.CVV, entity.Metadata, entity.OwnerID, entity.EntryKey, entity.Permission, entity.CollectionID, entity.KeyVersion, entity.ID).Scan(&updatedCard.ID, &updatedCard.Number, &updatedCard.CardHolder, &updatedCard.ExpirationDate, &updatedCard.CVV, &updatedCard.Metadata, &updatedCard.OwnerID, &updatedCard.EntryKey, &updatedCard.Permission, &updatedCard.CollectionID, &updatedCard.KeyVersion)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("card with ID %s %w", entity.ID, repositories.ErrNotFound)
+		}
 		return nil, fmt.Errorf("failed to update card: %w", err)
 	}
 	return &updatedCard, nil
@@ -88,6 +92,18 @@ func (r *CardsRepo) Update(ctx context.Context, entity *entities.CardInformation
 
 // Delete - удалить сущность
 func (r *CardsRepo) Delete(ctx context.Context, id string) error {
-	_, err := r.db.Exec("DELETE FROM cards WHERE id = ?", id)
-	return err
+	result, err := r.db.ExecContext(ctx, "DELETE FROM cards WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete card: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete card: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("card with ID %s %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
 }
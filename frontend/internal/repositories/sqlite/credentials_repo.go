@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // CredentialsRepo - репозиторий с учётными данными
@@ -22,7 +23,7 @@ func NewCredentialsRepo(db *sql.DB) (*CredentialsRepo, error) {
 
 // GetAll - получить все сущности
 func (r *CredentialsRepo) GetAll(ctx context.Context) ([]entities.Credentials, error) {
-	rows, err := r.db.Query("SELECT id, login, password, metadata FROM credentials")
+	rows, err := r.db.QueryContext(ctx, "SELECT id, login, password, metadata, owner_id, entry_key, permission, collection_id, key_version FROM credentials")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get credentials: %w", err)
 	}
@@ -36,7 +37,7 @@ func (r *CredentialsRepo) GetAll(ctx context.Context) ([]entities.Credentials, e
 	var credentials []entities.Credentials
 	for rows.Next() {
 		var cred entities.Credentials
-		err := rows.Scan(&cred.ID, &cred.Login, &cred.Password, &cred.Metadata)
+		err := rows.Scan(&cred.ID, &cred.Login, &cred.Password, &cred.Metadata, &cred.OwnerID, &cred.EntryKey, &cred.Permission, &cred.CollectionID, &cred.KeyVersion)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan credentials: %w", err)
 		}
@@ -49,8 +50,8 @@ func (r *CredentialsRepo) GetAll(ctx context.Context) ([]entities.Credentials, e
 // Get - получить сущность по ИД
 func (r *CredentialsRepo) Get(ctx context.Context, id string) (*entities.Credentials, error) {
 	var cred entities.Credentials
-	err := r.db.QueryRow(
-		"SELECT id, login, password, metadata FROM credentials WHERE id = ?", id).Scan(&cred.ID, &cred.Login, &cred.Password, &cred.Metadata)
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, login, password, metadata, owner_id, entry_key, permission, collection_id, key_version FROM credentials WHERE id = ?", id).Scan(&cred.ID, &cred.Login, &cred.Password, &cred.Metadata, &cred.OwnerID, &cred.EntryKey, &cred.Permission, &cred.CollectionID, &cred.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -65,7 +66,7 @@ func (r *CredentialsRepo) Get(ctx context.Context, id string) (*entities.Credent
 // Create - создать сущность
 func (r *CredentialsRepo) Create(ctx context.Context, entity *entities.Credentials) (*entities.Credentials, error) {
 	var cred entities.Credentials
-	err := r.db.QueryRow("INSERT INTO credentials (id, login, password, metadata) VALUES (?, ?, ?, ?) RETURNING id, login, password, metadata", entity.ID, entity.Login, entity.Password, entity.Metadata).Scan(&cred.ID, &cred.Login, &cred.Password, &cred.Metadata)
+	err := r.db.QueryRowContext(ctx, "INSERT INTO credentials (id, login, password, metadata, owner_id, entry_key, permission, collection_id, key_version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, login, password, metadata, owner_id, entry_key, permission, collection_id, key_version", entity.ID, entity.Login, entity.Password, entity.Metadata, entity.OwnerID, entity.EntryKey, entity.Permission, entity.CollectionID, entity.KeyVersion).Scan(&cred.ID, &cred.Login, &cred.Password, &cred.Metadata, &cred.OwnerID, &cred.EntryKey, &cred.Permission, &cred.CollectionID, &cred.KeyVersion)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create credentials: %w", err)
@@ -77,9 +78,12 @@ func (r *CredentialsRepo) Create(ctx context.Context, entity *entities.Credentia
 // Update - изменить сущность
 func (r *CredentialsRepo) Update(ctx context.Context, entity *entities.Credentials) (*entities.Credentials, error) {
 	var updatedCred entities.Credentials
-	err := r.db.QueryRow("UPDATE credentials SET login = ?, password = ?, metadata = ? WHERE id = ? RETURNING id, login, password, metadata", entity.Login, entity.Password, entity.Metadata, entity.ID).Scan(&updatedCred.ID, &updatedCred.Login, &updatedCred.Password, &updatedCred.Metadata)
+	err := r.db.QueryRowContext(ctx, "UPDATE credentials SET login = ?, password = ?, metadata = ?, owner_id = ?, entry_key = ?, permission = ?, collection_id = ?, key_version = ? WHERE id = ? RETURNING id, login, password, metadata, owner_id, entry_key, permission, collection_id, key_version", entity.Login, entity.Password, entity.Metadata, entity.OwnerID, entity.EntryKey, entity.Permission, entity.CollectionID, entity.KeyVersion, entity.ID).Scan(&updatedCred.ID, &updatedCred.Login, &updatedCred.Password, &updatedCred.Metadata, &updatedCred.OwnerID, &updatedCred.EntryKey, &updatedCred.Permission, &updatedCred.CollectionID, &updatedCred.KeyVersion)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("credentials with ID %s %w", entity.ID, repositories.ErrNotFound)
+		}
 		return nil, fmt.Errorf("failed to update credentials: %w", err)
 	}
 
@@ -88,6 +92,18 @@ func (r *CredentialsRepo) Update(ctx context.Context, entity *entities.Credentia
 
 // Delete - удалить сущность
 func (r *CredentialsRepo) Delete(ctx context.Context, id string) error {
-	_, err := r.db.Exec("DELETE FROM credentials WHERE id = ?", id)
-	return err
+	result, err := r.db.ExecContext(ctx, "DELETE FROM credentials WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("credentials with ID %s %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
 }
@@ -20,7 +20,8 @@ type DatabaseManager struct {
 	TextsRepo       *TextsRepo
 }
 
-func NewDatabaseManager(dbPath string) (*DatabaseManager, error) {
+// Open - открыть (или создать) локальную базу данных без применения миграций
+func Open(dbPath string) (*sql.DB, error) {
 	// Создаем директорию для БД, если ее нет
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
@@ -32,16 +33,30 @@ func NewDatabaseManager(dbPath string) (*DatabaseManager, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	// Одно соединение: запись из фоновой синхронизации и из интерфейса не конкурирует за блокировку файла (SQLITE_BUSY)
+	db.SetMaxOpenConns(1)
+
 	// Проверяем подключение
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	// Включаем foreign keys и другие настройки SQLite
 	if _, err := db.Exec("PRAGMA foreign_keys = ON; PRAGMA journal_mode = WAL;"); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to set pragmas: %w", err)
 	}
 
+	return db, nil
+}
+
+func NewDatabaseManager(dbPath string) (*DatabaseManager, error) {
+	db, err := Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Запускаем миграции
 	migrator := migrations.NewMigrator(db)
 	if err := migrator.Migrate(context.Background()); err != nil {
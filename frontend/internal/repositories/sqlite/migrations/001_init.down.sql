@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS texts;
+DROP TABLE IF EXISTS credentials;
+DROP TABLE IF EXISTS cards;
+DROP TABLE IF EXISTS binaries;
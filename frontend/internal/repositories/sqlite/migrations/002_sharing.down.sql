@@ -0,0 +1,15 @@
+ALTER TABLE binaries DROP COLUMN owner_id;
+ALTER TABLE binaries DROP COLUMN entry_key;
+ALTER TABLE binaries DROP COLUMN permission;
+
+ALTER TABLE cards DROP COLUMN owner_id;
+ALTER TABLE cards DROP COLUMN entry_key;
+ALTER TABLE cards DROP COLUMN permission;
+
+ALTER TABLE credentials DROP COLUMN owner_id;
+ALTER TABLE credentials DROP COLUMN entry_key;
+ALTER TABLE credentials DROP COLUMN permission;
+
+ALTER TABLE texts DROP COLUMN owner_id;
+ALTER TABLE texts DROP COLUMN entry_key;
+ALTER TABLE texts DROP COLUMN permission;
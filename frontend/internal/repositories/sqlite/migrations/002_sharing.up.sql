@@ -0,0 +1,15 @@
+ALTER TABLE binaries ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE binaries ADD COLUMN entry_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE binaries ADD COLUMN permission TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE cards ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE cards ADD COLUMN entry_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE cards ADD COLUMN permission TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE credentials ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE credentials ADD COLUMN entry_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE credentials ADD COLUMN permission TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE texts ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE texts ADD COLUMN entry_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE texts ADD COLUMN permission TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+ALTER TABLE binaries DROP COLUMN collection_id;
+ALTER TABLE binaries DROP COLUMN key_version;
+
+ALTER TABLE cards DROP COLUMN collection_id;
+ALTER TABLE cards DROP COLUMN key_version;
+
+ALTER TABLE credentials DROP COLUMN collection_id;
+ALTER TABLE credentials DROP COLUMN key_version;
+
+ALTER TABLE texts DROP COLUMN collection_id;
+ALTER TABLE texts DROP COLUMN key_version;
@@ -0,0 +1,11 @@
+ALTER TABLE binaries ADD COLUMN collection_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE binaries ADD COLUMN key_version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE cards ADD COLUMN collection_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE cards ADD COLUMN key_version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE credentials ADD COLUMN collection_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE credentials ADD COLUMN key_version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE texts ADD COLUMN collection_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE texts ADD COLUMN key_version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,61 @@
+-- Возврат к числовым идентификаторам. Записи с идентификаторами UUID в числовые не переводятся:
+-- локальная база - кэш, такие записи вернутся при следующей синхронизации
+CREATE TABLE binaries_new (
+	id INTEGER PRIMARY KEY,
+	data BLOB NOT NULL,
+	metadata TEXT,
+	owner_id TEXT NOT NULL DEFAULT '',
+	entry_key TEXT NOT NULL DEFAULT '',
+	permission TEXT NOT NULL DEFAULT '',
+	collection_id TEXT NOT NULL DEFAULT '',
+	key_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO binaries_new SELECT CAST(id AS INTEGER), data, metadata, owner_id, entry_key, permission, collection_id, key_version FROM binaries WHERE id NOT GLOB '*[^0-9]*';
+DROP TABLE binaries;
+ALTER TABLE binaries_new RENAME TO binaries;
+
+CREATE TABLE cards_new (
+	id INTEGER PRIMARY KEY,
+	number TEXT NOT NULL,
+	card_holder TEXT NOT NULL,
+	expiration_date TEXT NOT NULL,
+	cvv TEXT NOT NULL,
+	metadata TEXT,
+	owner_id TEXT NOT NULL DEFAULT '',
+	entry_key TEXT NOT NULL DEFAULT '',
+	permission TEXT NOT NULL DEFAULT '',
+	collection_id TEXT NOT NULL DEFAULT '',
+	key_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO cards_new SELECT CAST(id AS INTEGER), number, card_holder, expiration_date, cvv, metadata, owner_id, entry_key, permission, collection_id, key_version FROM cards WHERE id NOT GLOB '*[^0-9]*';
+DROP TABLE cards;
+ALTER TABLE cards_new RENAME TO cards;
+
+CREATE TABLE credentials_new (
+	id INTEGER PRIMARY KEY,
+	login TEXT NOT NULL,
+	password TEXT NOT NULL,
+	metadata TEXT,
+	owner_id TEXT NOT NULL DEFAULT '',
+	entry_key TEXT NOT NULL DEFAULT '',
+	permission TEXT NOT NULL DEFAULT '',
+	collection_id TEXT NOT NULL DEFAULT '',
+	key_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO credentials_new SELECT CAST(id AS INTEGER), login, password, metadata, owner_id, entry_key, permission, collection_id, key_version FROM credentials WHERE id NOT GLOB '*[^0-9]*';
+DROP TABLE credentials;
+ALTER TABLE credentials_new RENAME TO credentials;
+
+CREATE TABLE texts_new (
+	id INTEGER PRIMARY KEY,
+	data TEXT NOT NULL,
+	metadata TEXT,
+	owner_id TEXT NOT NULL DEFAULT '',
+	entry_key TEXT NOT NULL DEFAULT '',
+	permission TEXT NOT NULL DEFAULT '',
+	collection_id TEXT NOT NULL DEFAULT '',
+	key_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO texts_new SELECT CAST(id AS INTEGER), data, metadata, owner_id, entry_key, permission, collection_id, key_version FROM texts WHERE id NOT GLOB '*[^0-9]*';
+DROP TABLE texts;
+ALTER TABLE texts_new RENAME TO texts;
@@ -0,0 +1,62 @@
+-- Идентификаторы записей - UUID, которые выбирает клиент. SQLite не меняет тип столбца, поэтому таблицы пересоздаются.
+-- Записи кэша переводятся на те же идентификаторы, что выдал им сервер (миграция 011):
+-- 00000000-0000-8000-800T-NNNNNNNNNNNN, где T - код типа, N - прежний номер в шестнадцатеричном виде
+CREATE TABLE binaries_new (
+	id TEXT PRIMARY KEY,
+	data BLOB NOT NULL,
+	metadata TEXT,
+	owner_id TEXT NOT NULL DEFAULT '',
+	entry_key TEXT NOT NULL DEFAULT '',
+	permission TEXT NOT NULL DEFAULT '',
+	collection_id TEXT NOT NULL DEFAULT '',
+	key_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO binaries_new SELECT '00000000-0000-8000-8001-' || printf('%012x', id), data, metadata, owner_id, entry_key, permission, collection_id, key_version FROM binaries;
+DROP TABLE binaries;
+ALTER TABLE binaries_new RENAME TO binaries;
+
+CREATE TABLE cards_new (
+	id TEXT PRIMARY KEY,
+	number TEXT NOT NULL,
+	card_holder TEXT NOT NULL,
+	expiration_date TEXT NOT NULL,
+	cvv TEXT NOT NULL,
+	metadata TEXT,
+	owner_id TEXT NOT NULL DEFAULT '',
+	entry_key TEXT NOT NULL DEFAULT '',
+	permission TEXT NOT NULL DEFAULT '',
+	collection_id TEXT NOT NULL DEFAULT '',
+	key_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO cards_new SELECT '00000000-0000-8000-8002-' || printf('%012x', id), number, card_holder, expiration_date, cvv, metadata, owner_id, entry_key, permission, collection_id, key_version FROM cards;
+DROP TABLE cards;
+ALTER TABLE cards_new RENAME TO cards;
+
+CREATE TABLE credentials_new (
+	id TEXT PRIMARY KEY,
+	login TEXT NOT NULL,
+	password TEXT NOT NULL,
+	metadata TEXT,
+	owner_id TEXT NOT NULL DEFAULT '',
+	entry_key TEXT NOT NULL DEFAULT '',
+	permission TEXT NOT NULL DEFAULT '',
+	collection_id TEXT NOT NULL DEFAULT '',
+	key_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO credentials_new SELECT '00000000-0000-8000-8003-' || printf('%012x', id), login, password, metadata, owner_id, entry_key, permission, collection_id, key_version FROM credentials;
+DROP TABLE credentials;
+ALTER TABLE credentials_new RENAME TO credentials;
+
+CREATE TABLE texts_new (
+	id TEXT PRIMARY KEY,
+	data TEXT NOT NULL,
+	metadata TEXT,
+	owner_id TEXT NOT NULL DEFAULT '',
+	entry_key TEXT NOT NULL DEFAULT '',
+	permission TEXT NOT NULL DEFAULT '',
+	collection_id TEXT NOT NULL DEFAULT '',
+	key_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO texts_new SELECT '00000000-0000-8000-8004-' || printf('%012x', id), data, metadata, owner_id, entry_key, permission, collection_id, key_version FROM texts;
+DROP TABLE texts;
+ALTER TABLE texts_new RENAME TO texts;
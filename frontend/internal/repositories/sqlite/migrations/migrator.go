@@ -4,15 +4,42 @@ package migrations
 import (
 	"context"
 	"database/sql"
+	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
-	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// files - SQL-файлы миграций, встроенные в исполняемый файл: NNN_name.up.sql применяет миграцию, NNN_name.down.sql откатывает её
+//
+//go:embed *.sql
+var files embed.FS
+
+// Migration - миграция схемы локальной базы данных
+type Migration struct {
+	Version int
+	Name    string // имя файла без суффикса .up.sql
+	up      string
+	down    string
+}
+
+// Reversible - есть ли у миграции файл отката
+func (m Migration) Reversible() bool {
+	return m.down != ""
+}
+
+// Status - миграция и сведения о её применении
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
 // Migrator управляет миграциями базы данных
 type Migrator struct {
 	db *sql.DB
@@ -25,97 +52,159 @@ func NewMigrator(db *sql.DB) *Migrator {
 
 // Migrate - применяет все миграции
 func (m *Migrator) Migrate(ctx context.Context) error {
-	// Создаем таблицу для отслеживания миграций
-	query := `
-	CREATE TABLE IF NOT EXISTS migrations (
-		version INTEGER PRIMARY KEY,
-		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	)`
+	if _, err := m.Up(ctx, false); err != nil {
+		return err
+	}
+
+	log.Println("All migrations completed successfully")
+	return nil
+}
 
-	_, err := m.db.ExecContext(ctx, query)
+// Up - применить по порядку все миграции, которые ещё не применены, и вернуть их.
+// dryRun - только вернуть миграции, которые были бы применены
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+		return nil, err
 	}
 
-	// Получаем список примененных миграций
-	applied, err := m.getAppliedMigrations(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get applied migrations: %w", err)
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
 	}
 
-	// Читаем все файлы в директории с миграциями
-	migrationsDir := "../../internal/repositories/sqlite/migrations"
-	files, err := os.ReadDir(migrationsDir)
-	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
+	if dryRun {
+		return pending, nil
 	}
 
-	migrations := make(map[int]os.DirEntry)
-	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".up.sql") {
-			version, err := extractVersion(file.Name())
-			if err != nil {
-				return err
-			}
+	if err := m.createTable(ctx); err != nil {
+		return nil, err
+	}
 
-			migrations[version] = file
+	for i, migration := range pending {
+		if err := m.apply(ctx, migration.up, "INSERT INTO migrations (version) VALUES (?)", migration.Version); err != nil {
+			return pending[:i], fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
 		}
+
+		log.Printf("Migration applied: %s", migration.Name)
 	}
 
-	// Сортируем версии по порядку
-	versions := make([]int, 0, len(migrations))
-	for k := range migrations {
-		versions = append(versions, k)
+	return pending, nil
+}
+
+// Down - откатить steps последних применённых миграций (начиная с последней) и вернуть их.
+// dryRun - только вернуть миграции, которые были бы откачены
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, errors.New("number of migrations to roll back must be positive")
 	}
 
-	sort.Ints(versions)
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	// Применяем миграции по порядку
-	for _, version := range versions {
-		if !applied[version] {
-			migration := migrations[version]
-			if err := m.applyMigration(ctx, filepath.Join(migrationsDir, migration.Name()), version); err != nil {
-				return fmt.Errorf("failed to apply migration %s: %w", migration.Name(), err)
-			}
+	var rollback []Migration
+	for i := len(statuses) - 1; i >= 0 && len(rollback) < steps; i-- {
+		if statuses[i].Applied {
+			rollback = append(rollback, statuses[i].Migration)
+		}
+	}
 
-			log.Printf("Migration applied: %s", migration.Name())
+	// Миграция без файла отката останавливает откат до того, как что-либо изменится
+	for _, migration := range rollback {
+		if !migration.Reversible() {
+			return nil, fmt.Errorf("migration %s cannot be rolled back: no down file", migration.Name)
 		}
 	}
 
-	log.Println("All migrations completed successfully")
+	if dryRun {
+		return rollback, nil
+	}
+
+	for i, migration := range rollback {
+		if err := m.apply(ctx, migration.down, "DELETE FROM migrations WHERE version = ?", migration.Version); err != nil {
+			return rollback[:i], fmt.Errorf("failed to roll back migration %s: %w", migration.Name, err)
+		}
+
+		log.Printf("Migration rolled back: %s", migration.Name)
+	}
+
+	return rollback, nil
+}
+
+// Status - все известные миграции по порядку версий с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+
+	return statuses, nil
+}
+
+// createTable - создать таблицу для отслеживания миграций
+func (m *Migrator) createTable(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`
+
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
 	return nil
 }
 
-// getAppliedMigrations - возвращает map примененных миграций
-func (m *Migrator) getAppliedMigrations(ctx context.Context) (map[int]bool, error) {
-	applied := make(map[int]bool)
+// getAppliedMigrations - возвращает время применения примененных миграций
+func (m *Migrator) getAppliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
 
-	rows, err := m.db.QueryContext(ctx, "SELECT version FROM migrations")
+	var exists bool
+	err := m.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'migrations')").Scan(&exists)
 	if err != nil {
-		// Если таблицы нет, возвращаем пустой map
+		return nil, err
+	}
+	if !exists {
+		// Таблицы ещё нет - ни одна миграция не применена
 		return applied, nil
 	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM migrations")
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var version int
-		if err := rows.Scan(&version); err != nil {
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
 			return nil, err
 		}
-		applied[version] = true
+		applied[version] = appliedAt
 	}
 
 	return applied, rows.Err()
 }
 
-// applyMigration - применяет миграцию
-func (m *Migrator) applyMigration(ctx context.Context, filePath string, version int) error {
-	// Читаем SQL файл
-	sqlBytes, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
-	}
-
+// apply - выполнить SQL миграции и изменить отметку о её применении в одной транзакции
+func (m *Migrator) apply(ctx context.Context, sql, record string, version int) error {
 	// Начинаем транзакцию
 	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -123,14 +212,13 @@ func (m *Migrator) applyMigration(ctx context.Context, filePath string, version
 	}
 
 	// Выполняем SQL миграции
-	if _, err := tx.ExecContext(ctx, string(sqlBytes)); err != nil {
+	if _, err := tx.ExecContext(ctx, sql); err != nil {
 		tx.Rollback() // Откатываем при ошибке
 		return fmt.Errorf("failed to execute migration: %w", err)
 	}
 
-	// Записываем факт применения миграции
-	_, err = tx.ExecContext(ctx, "INSERT INTO migrations (version) VALUES (?)", version)
-	if err != nil {
+	// Записываем факт применения (или отката) миграции
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
 		tx.Rollback() // Откатываем при ошибке
 		return fmt.Errorf("failed to record migration: %w", err)
 	}
@@ -143,6 +231,69 @@ func (m *Migrator) applyMigration(ctx context.Context, filePath string, version
 	return nil
 }
 
+// Load - миграции, встроенные в исполняемый файл, по порядку версий
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var name string
+		var down bool
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			name = strings.TrimSuffix(fileName, ".up.sql")
+		case strings.HasSuffix(fileName, ".down.sql"):
+			name, down = strings.TrimSuffix(fileName, ".down.sql"), true
+		default:
+			continue
+		}
+
+		version, err := extractVersion(fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, migration.Name, name)
+		}
+
+		content, err := fs.ReadFile(files, fileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file: %w", err)
+		}
+		if down {
+			migration.down = string(content)
+		} else {
+			migration.up = string(content)
+		}
+	}
+
+	// Сортируем версии по порядку
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" {
+			return nil, fmt.Errorf("migration %s has no up file", migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
 // extractVersion - извлекает номер миграции из имени файла
 func extractVersion(filename string) (int, error) {
 	parts := strings.Split(filename, "_")
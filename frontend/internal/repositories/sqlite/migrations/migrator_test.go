@@ -0,0 +1,162 @@
+// migrations - пакет для миграции данных
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB - пустая база данных во временной директории
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// appliedCount - число применённых миграций
+func appliedCount(t *testing.T, migrator *Migrator) int {
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+
+	count := 0
+	for _, status := range statuses {
+		if status.Applied {
+			count++
+			assert.False(t, status.AppliedAt.IsZero(), status.Name)
+		}
+	}
+	return count
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "версии миграций идут подряд с 1")
+		assert.True(t, migration.Reversible(), "у миграции %s есть файл отката", migration.Name)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	migrator := NewMigrator(db)
+
+	all, err := Load()
+	require.NoError(t, err)
+
+	t.Run("Пробный запуск ничего не меняет", func(t *testing.T) {
+		pending, err := migrator.Up(ctx, true)
+		require.NoError(t, err)
+		assert.Len(t, pending, len(all))
+		assert.Equal(t, 0, appliedCount(t, migrator))
+	})
+
+	t.Run("Применение всех миграций", func(t *testing.T) {
+		applied, err := migrator.Up(ctx, false)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(all))
+		assert.Equal(t, len(all), appliedCount(t, migrator))
+
+		_, err = db.ExecContext(ctx, "INSERT INTO texts (id, data) VALUES ('0190a0b2-0000-7000-8000-000000000000', 'note')")
+		require.NoError(t, err)
+
+		// Повторный запуск ничего не применяет
+		applied, err = migrator.Up(ctx, false)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("Откат последних миграций", func(t *testing.T) {
+		rollback, err := migrator.Down(ctx, 2, true)
+		require.NoError(t, err)
+		require.Len(t, rollback, 2)
+		assert.Equal(t, all[len(all)-1].Name, rollback[0].Name, "сначала откатывается последняя миграция")
+		assert.Equal(t, len(all), appliedCount(t, migrator))
+
+		rolledBack, err := migrator.Down(ctx, 2, false)
+		require.NoError(t, err)
+		assert.Equal(t, rollback, rolledBack)
+		assert.Equal(t, len(all)-2, appliedCount(t, migrator))
+
+		_, err = migrator.Down(ctx, 0, false)
+		assert.Error(t, err)
+	})
+
+	t.Run("Полный откат и повторное применение", func(t *testing.T) {
+		rolledBack, err := migrator.Down(ctx, len(all), false)
+		require.NoError(t, err)
+		assert.Len(t, rolledBack, len(all)-2)
+		assert.Equal(t, 0, appliedCount(t, migrator))
+
+		applied, err := migrator.Up(ctx, false)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(all))
+	})
+}
+
+func TestMigrator_EntryUUID(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	migrator := NewMigrator(db)
+
+	all, err := Load()
+	require.NoError(t, err)
+
+	// База клиента версии 3 с записями под числовыми идентификаторами
+	_, err = migrator.Up(ctx, false)
+	require.NoError(t, err)
+	_, err = migrator.Down(ctx, len(all)-3, false)
+	require.NoError(t, err)
+	require.Equal(t, 3, appliedCount(t, migrator))
+
+	for _, query := range []string{
+		"INSERT INTO binaries (id, data, metadata, owner_id) VALUES (17, x'00ff', 'photo', 'alice')",
+		"INSERT INTO cards (id, number, card_holder, expiration_date, cvv, owner_id) VALUES (17, '4111', 'ALICE', '12/30', '123', 'alice')",
+		"INSERT INTO credentials (id, login, password, owner_id) VALUES (17, 'alice', 'secret', 'alice')",
+		"INSERT INTO texts (id, data, owner_id, collection_id, key_version) VALUES (17, 'note', 'alice', 'team', 1)",
+	} {
+		_, err := db.ExecContext(ctx, query)
+		require.NoError(t, err)
+	}
+
+	_, err = migrator.Up(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, len(all), appliedCount(t, migrator))
+
+	// Записи получают те же идентификаторы, что и на сервере, остальные поля не меняются
+	for table, id := range map[string]string{
+		"binaries":    "00000000-0000-8000-8001-000000000011",
+		"cards":       "00000000-0000-8000-8002-000000000011",
+		"credentials": "00000000-0000-8000-8003-000000000011",
+		"texts":       "00000000-0000-8000-8004-000000000011",
+	} {
+		var storedID, ownerID string
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT id, owner_id FROM "+table).Scan(&storedID, &ownerID))
+		assert.Equal(t, id, storedID, table)
+		assert.Equal(t, "alice", ownerID, table)
+	}
+
+	var data []byte
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT data FROM binaries").Scan(&data))
+	assert.Equal(t, []byte{0x00, 0xff}, data)
+
+	var collectionID string
+	var keyVersion int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT collection_id, key_version FROM texts").Scan(&collectionID, &keyVersion))
+	assert.Equal(t, "team", collectionID)
+	assert.Equal(t, 1, keyVersion)
+
+	_, err = db.ExecContext(ctx, "INSERT INTO texts (id, data) VALUES ('0190a0b2-0000-7000-8000-000000000000', 'note')")
+	require.NoError(t, err)
+}
@@ -0,0 +1,25 @@
+// sqlite - SQLite Репозиторий
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories/repotest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		manager, err := NewDatabaseManager(filepath.Join(t.TempDir(), "gophkeeper.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { manager.Close() })
+
+		return repotest.Repos{
+			Binaries:    manager.BinariesRepo,
+			Cards:       manager.CardsRepo,
+			Credentials: manager.CredentialsRepo,
+			Texts:       manager.TextsRepo,
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // TextsRepo - репозиторий с текстовыми данными
@@ -22,7 +23,7 @@ func NewTextsRepo(db *sql.DB) (*TextsRepo, error) {
 
 // GetAll - получить все сущности
 func (r *TextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
-	rows, err := r.db.Query("SELECT id, data, metadata FROM texts")
+	rows, err := r.db.QueryContext(ctx, "SELECT id, data, metadata, owner_id, entry_key, permission, collection_id, key_version FROM texts")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get texts: %w", err)
 	}
@@ -35,7 +36,7 @@ func (r *TextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
 	var texts []entities.TextData
 	for rows.Next() {
 		var text entities.TextData
-		err := rows.Scan(&text.ID, &text.Data, &text.Metadata)
+		err := rows.Scan(&text.ID, &text.Data, &text.Metadata, &text.OwnerID, &text.EntryKey, &text.Permission, &text.CollectionID, &text.KeyVersion)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan text: %w", err)
 		}
@@ -48,7 +49,7 @@ func (r *TextsRepo) GetAll(ctx context.Context) ([]entities.TextData, error) {
 // Get - получить сущность по ИД
 func (r *TextsRepo) Get(ctx context.Context, id string) (*entities.TextData, error) {
 	var text entities.TextData
-	err := r.db.QueryRow("SELECT id, data, metadata FROM texts WHERE id = ?", id).Scan(&text.ID, &text.Data, &text.Metadata)
+	err := r.db.QueryRowContext(ctx, "SELECT id, data, metadata, owner_id, entry_key, permission, collection_id, key_version FROM texts WHERE id = ?", id).Scan(&text.ID, &text.Data, &text.Metadata, &text.OwnerID, &text.EntryKey, &text.Permission, &text.CollectionID, &text.KeyVersion)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -63,7 +64,7 @@ func (r *TextsRepo) Get(ctx context.Context, id string) (*entities.TextData, err
 // Create - создать сущность
 func (r *TextsRepo) Create(ctx context.Context, entity *entities.TextData) (*entities.TextData, error) {
 	var text entities.TextData
-	err := r.db.QueryRow("INSERT INTO texts (id, data, metadata) VALUES (?, ?, ?) RETURNING id, data, metadata", entity.ID, entity.Data, entity.Metadata).Scan(&text.ID, &text.Data, &text.Metadata)
+	err := r.db.QueryRowContext(ctx, "INSERT INTO texts (id, data, metadata, owner_id, entry_key, permission, collection_id, key_version) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, data, metadata, owner_id, entry_key, permission, collection_id, key_version", entity.ID, entity.Data, entity.Metadata, entity.OwnerID, entity.EntryKey, entity.Permission, entity.CollectionID, entity.KeyVersion).Scan(&text.ID, &text.Data, &text.Metadata, &text.OwnerID, &text.EntryKey, &text.Permission, &text.CollectionID, &text.KeyVersion)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create text: %w", err)
@@ -75,9 +76,12 @@ func (r *TextsRepo) Create(ctx context.Context, entity *entities.TextData) (*ent
 // Update - изменить сущность
 func (r *TextsRepo) Update(ctx context.Context, entity *entities.TextData) (*entities.TextData, error) {
 	var updatedText entities.TextData
-	err := r.db.QueryRow("UPDATE texts SET data = ?, metadata = ? WHERE id = ? RETURNING id, data, metadata", entity.Data, entity.Metadata, entity.ID).Scan(&updatedText.ID, &updatedText.Data, &updatedText.Metadata)
+	err := r.db.QueryRowContext(ctx, "UPDATE texts SET data = ?, metadata = ?, owner_id = ?, entry_key = ?, permission = ?, collection_id = ?, key_version = ? WHERE id = ? RETURNING id, data, metadata, owner_id, entry_key, permission, collection_id, key_version", entity.Data, entity.Metadata, entity.OwnerID, entity.EntryKey, entity.Permission, entity.CollectionID, entity.KeyVersion, entity.ID).Scan(&updatedText.ID, &updatedText.Data, &updatedText.Metadata, &updatedText.OwnerID, &updatedText.EntryKey, &updatedText.Permission, &updatedText.CollectionID, &updatedText.KeyVersion)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("text with ID %s %w", entity.ID, repositories.ErrNotFound)
+		}
 		return nil, fmt.Errorf("failed to update text: %w", err)
 	}
 
@@ -85,6 +89,18 @@ func (r *TextsRepo) Update(ctx context.Context, entity *entities.TextData) (*ent
 }
 
 func (r *TextsRepo) Delete(ctx context.Context, id string) error {
-	_, err := r.db.Exec("DELETE FROM texts WHERE id = ?", id)
-	return err
+	result, err := r.db.ExecContext(ctx, "DELETE FROM texts WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete text: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete text: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("text with ID %s %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
 }
@@ -3,12 +3,18 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/clients"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/encryption"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
+	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories"
 )
 
 // GophkeeperService - главный сервис клиентской части приложения
@@ -17,6 +23,10 @@ type GophkeeperService struct {
 	localStorage  *StorageService
 	syncService   *SyncService
 	cryptoService *encryption.CryptoService
+
+	// Пара ключей X25519 для обмена записями (загружается при входе или при первом обращении)
+	publicKey  []byte
+	privateKey []byte
 }
 
 // NewGophkeeperService - создать главный сервис клиентской части приложения
@@ -35,6 +45,7 @@ func NewGophkeeperService(
 // SetEncryption - установить сервис шифрование
 func (s *GophkeeperService) SetEncryption(password string) error {
 	s.cryptoService = encryption.NewCryptoService(password)
+	s.publicKey, s.privateKey = nil, nil
 	return nil
 }
 
@@ -43,14 +54,21 @@ func (s *GophkeeperService) IsEncryptionSet() bool {
 	return s.cryptoService != nil
 }
 
-// Register - регистрация
-func (s *GophkeeperService) Register(ctx context.Context, login, password string) error {
-	err := s.apiClient.Register(ctx, login, password)
+// Register - регистрация (inviteCode - код приглашения, если сервер регистрирует только по приглашениям)
+func (s *GophkeeperService) Register(ctx context.Context, login, password, inviteCode string) error {
+	err := s.apiClient.Register(ctx, login, password, inviteCode)
 	if err != nil {
 		return err
 	}
 
-	return s.SetEncryption(password)
+	if err := s.SetEncryption(password); err != nil {
+		return err
+	}
+
+	// Ключи публикуются сразу, чтобы с пользователем можно было поделиться записью.
+	// Ошибка не мешает работе: ключи загрузятся повторно при обращении к общим записям
+	_ = s.loadKeys(ctx)
+	return nil
 }
 
 // Login - аутентификация
@@ -65,13 +83,28 @@ func (s *GophkeeperService) Login(ctx context.Context, login, password string) e
 		return fmt.Errorf("sync failed: %w", err)
 	}
 
-	return s.SetEncryption(password)
+	if err := s.SetEncryption(password); err != nil {
+		return err
+	}
+
+	// Ошибка загрузки ключей не мешает работе с собственными записями
+	_ = s.loadKeys(ctx)
+	return nil
 }
 
 // CreateBinary - создать бинарные данные (на клиенте и сервере)
 func (s *GophkeeperService) CreateBinary(ctx context.Context, dto *dtos.NewBinaryData) (*entities.BinaryData, error) {
-	// Шифруем DTO перед отправкой на сервер
-	if err := dto.EncryptFields(s.cryptoService); err != nil {
+	// Идентификатор записи выбирает клиент
+	if err := dto.AssignID(); err != nil {
+		return nil, err
+	}
+
+	// Шифруем DTO перед отправкой на сервер (запись коллекции - ключом коллекции)
+	cryptoService, err := s.newEntryCrypto(ctx, &dto.NewSecureEntity)
+	if err != nil {
+		return nil, err
+	}
+	if err := dto.EncryptFields(cryptoService); err != nil {
 		return nil, fmt.Errorf("failed to encrypt binary DTO: %w", err)
 	}
 
@@ -88,7 +121,7 @@ func (s *GophkeeperService) CreateBinary(ctx context.Context, dto *dtos.NewBinar
 	}
 
 	// Дешифруем локальную сущность для возврата
-	if err := localBinary.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &localBinary.SecureEntity, localBinary.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt local binary: %w", err)
 	}
 
@@ -97,8 +130,17 @@ func (s *GophkeeperService) CreateBinary(ctx context.Context, dto *dtos.NewBinar
 
 // CreateCard - создать данные карты (на клиенте и сервере)
 func (s *GophkeeperService) CreateCard(ctx context.Context, dto *dtos.NewCardInformation) (*entities.CardInformation, error) {
-	// Шифруем DTO перед отправкой на сервер
-	if err := dto.EncryptFields(s.cryptoService); err != nil {
+	// Идентификатор записи выбирает клиент
+	if err := dto.AssignID(); err != nil {
+		return nil, err
+	}
+
+	// Шифруем DTO перед отправкой на сервер (запись коллекции - ключом коллекции)
+	cryptoService, err := s.newEntryCrypto(ctx, &dto.NewSecureEntity)
+	if err != nil {
+		return nil, err
+	}
+	if err := dto.EncryptFields(cryptoService); err != nil {
 		return nil, fmt.Errorf("failed to encrypt card DTO: %w", err)
 	}
 
@@ -113,7 +155,7 @@ func (s *GophkeeperService) CreateCard(ctx context.Context, dto *dtos.NewCardInf
 	}
 
 	// Дешифруем локальную сущность для возврата
-	if err := localCard.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &localCard.SecureEntity, localCard.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt local card: %w", err)
 	}
 
@@ -122,8 +164,17 @@ func (s *GophkeeperService) CreateCard(ctx context.Context, dto *dtos.NewCardInf
 
 // CreateCredentials - создать учётные данные (на клиенте и сервере)
 func (s *GophkeeperService) CreateCredentials(ctx context.Context, dto *dtos.NewCredentials) (*entities.Credentials, error) {
-	// Шифруем только метаданные в DTO
-	if err := dto.EncryptFields(s.cryptoService); err != nil {
+	// Идентификатор записи выбирает клиент
+	if err := dto.AssignID(); err != nil {
+		return nil, err
+	}
+
+	// Шифруем только метаданные в DTO (запись коллекции - ключом коллекции)
+	cryptoService, err := s.newEntryCrypto(ctx, &dto.NewSecureEntity)
+	if err != nil {
+		return nil, err
+	}
+	if err := dto.EncryptFields(cryptoService); err != nil {
 		return nil, fmt.Errorf("failed to encrypt credentials DTO: %w", err)
 	}
 
@@ -138,7 +189,7 @@ func (s *GophkeeperService) CreateCredentials(ctx context.Context, dto *dtos.New
 	}
 
 	// Дешифруем локальную сущность для возврата
-	if err := localCredentials.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &localCredentials.SecureEntity, localCredentials.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt local credentials: %w", err)
 	}
 
@@ -147,8 +198,17 @@ func (s *GophkeeperService) CreateCredentials(ctx context.Context, dto *dtos.New
 
 // CreateText - создать текстовые данные (на клиенте и сервере)
 func (s *GophkeeperService) CreateText(ctx context.Context, dto *dtos.NewTextData) (*entities.TextData, error) {
-	// Шифруем DTO перед отправкой на сервер
-	if err := dto.EncryptFields(s.cryptoService); err != nil {
+	// Идентификатор записи выбирает клиент
+	if err := dto.AssignID(); err != nil {
+		return nil, err
+	}
+
+	// Шифруем DTO перед отправкой на сервер (запись коллекции - ключом коллекции)
+	cryptoService, err := s.newEntryCrypto(ctx, &dto.NewSecureEntity)
+	if err != nil {
+		return nil, err
+	}
+	if err := dto.EncryptFields(cryptoService); err != nil {
 		return nil, fmt.Errorf("failed to encrypt text DTO: %w", err)
 	}
 
@@ -163,7 +223,7 @@ func (s *GophkeeperService) CreateText(ctx context.Context, dto *dtos.NewTextDat
 	}
 
 	// Дешифруем локальную сущность для возврата
-	if err := localText.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &localText.SecureEntity, localText.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt local text: %w", err)
 	}
 
@@ -178,7 +238,7 @@ func (s *GophkeeperService) GetBinary(ctx context.Context, id string) (*entities
 	}
 
 	// Дешифруем данные из локального хранилища
-	if err := binary.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &binary.SecureEntity, binary.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt binary: %w", err)
 	}
 
@@ -194,7 +254,7 @@ func (s *GophkeeperService) GetAllBinaries(ctx context.Context) ([]entities.Bina
 
 	// Дешифруем все бинарные данные
 	for i := range binaries {
-		if err := binaries[i].DecryptFields(s.cryptoService); err != nil {
+		if err := s.withEntryCrypto(ctx, &binaries[i].SecureEntity, binaries[i].DecryptFields); err != nil {
 			return nil, fmt.Errorf("failed to decrypt binary %s: %w", binaries[i].ID, err)
 		}
 	}
@@ -210,7 +270,7 @@ func (s *GophkeeperService) GetCard(ctx context.Context, id string) (*entities.C
 	}
 
 	// Дешифруем данные из локального хранилища
-	if err := card.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &card.SecureEntity, card.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt card: %w", err)
 	}
 
@@ -226,7 +286,7 @@ func (s *GophkeeperService) GetAllCards(ctx context.Context) ([]entities.CardInf
 
 	// Дешифруем все данные карт
 	for i := range cards {
-		if err := cards[i].DecryptFields(s.cryptoService); err != nil {
+		if err := s.withEntryCrypto(ctx, &cards[i].SecureEntity, cards[i].DecryptFields); err != nil {
 			return nil, fmt.Errorf("failed to decrypt card %s: %w", cards[i].ID, err)
 		}
 	}
@@ -242,7 +302,7 @@ func (s *GophkeeperService) GetCredentials(ctx context.Context, id string) (*ent
 	}
 
 	// Дешифруем данные из локального хранилища
-	if err := credentials.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &credentials.SecureEntity, credentials.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt credentials: %w", err)
 	}
 
@@ -258,7 +318,7 @@ func (s *GophkeeperService) GetAllCredentials(ctx context.Context) ([]entities.C
 
 	// Дешифруем все учётные данные
 	for i := range credentials {
-		if err := credentials[i].DecryptFields(s.cryptoService); err != nil {
+		if err := s.withEntryCrypto(ctx, &credentials[i].SecureEntity, credentials[i].DecryptFields); err != nil {
 			return nil, fmt.Errorf("failed to decrypt credentials %s: %w", credentials[i].ID, err)
 		}
 	}
@@ -274,7 +334,7 @@ func (s *GophkeeperService) GetText(ctx context.Context, id string) (*entities.T
 	}
 
 	// Дешифруем данные из локального хранилища
-	if err := text.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &text.SecureEntity, text.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt text: %w", err)
 	}
 
@@ -290,7 +350,7 @@ func (s *GophkeeperService) GetAllTexts(ctx context.Context) ([]entities.TextDat
 
 	// Дешифруем все текстовые данные
 	for i := range texts {
-		if err := texts[i].DecryptFields(s.cryptoService); err != nil {
+		if err := s.withEntryCrypto(ctx, &texts[i].SecureEntity, texts[i].DecryptFields); err != nil {
 			return nil, fmt.Errorf("failed to decrypt text %s: %w", texts[i].ID, err)
 		}
 	}
@@ -301,7 +361,7 @@ func (s *GophkeeperService) GetAllTexts(ctx context.Context) ([]entities.TextDat
 // UpdateBinary - обновить бинарные данные
 func (s *GophkeeperService) UpdateBinary(ctx context.Context, entity *entities.BinaryData) (*entities.BinaryData, error) {
 	// Шифруем перед отправкой на сервер
-	if err := entity.EncryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &entity.SecureEntity, entity.EncryptFields); err != nil {
 		return nil, fmt.Errorf("failed to encrypt binary for update: %w", err)
 	}
 
@@ -316,7 +376,7 @@ func (s *GophkeeperService) UpdateBinary(ctx context.Context, entity *entities.B
 	}
 
 	// Дешифруем локальную сущность для возврата
-	if err := localBinary.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &localBinary.SecureEntity, localBinary.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt local binary: %w", err)
 	}
 
@@ -326,7 +386,7 @@ func (s *GophkeeperService) UpdateBinary(ctx context.Context, entity *entities.B
 // UpdateCard - обновить данные карты
 func (s *GophkeeperService) UpdateCard(ctx context.Context, entity *entities.CardInformation) (*entities.CardInformation, error) {
 	// Шифруем перед отправкой на сервер
-	if err := entity.EncryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &entity.SecureEntity, entity.EncryptFields); err != nil {
 		return nil, fmt.Errorf("failed to encrypt card for update: %w", err)
 	}
 
@@ -341,7 +401,7 @@ func (s *GophkeeperService) UpdateCard(ctx context.Context, entity *entities.Car
 	}
 
 	// Дешифруем локальную сущность для возврата
-	if err := localCard.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &localCard.SecureEntity, localCard.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt local card: %w", err)
 	}
 
@@ -351,7 +411,7 @@ func (s *GophkeeperService) UpdateCard(ctx context.Context, entity *entities.Car
 // UpdateCredentials - обновить учётные данные
 func (s *GophkeeperService) UpdateCredentials(ctx context.Context, entity *entities.Credentials) (*entities.Credentials, error) {
 	// Шифруем метаданные перед отправкой на сервер
-	if err := entity.EncryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &entity.SecureEntity, entity.EncryptFields); err != nil {
 		return nil, fmt.Errorf("failed to encrypt credentials for update: %w", err)
 	}
 
@@ -366,7 +426,7 @@ func (s *GophkeeperService) UpdateCredentials(ctx context.Context, entity *entit
 	}
 
 	// Дешифруем локальную сущность для возврата
-	if err := localCredentials.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &localCredentials.SecureEntity, localCredentials.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt local credentials: %w", err)
 	}
 
@@ -376,7 +436,7 @@ func (s *GophkeeperService) UpdateCredentials(ctx context.Context, entity *entit
 // UpdateText - обновить текстовые данные
 func (s *GophkeeperService) UpdateText(ctx context.Context, entity *entities.TextData) (*entities.TextData, error) {
 	// Шифруем перед отправкой на сервер
-	if err := entity.EncryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &entity.SecureEntity, entity.EncryptFields); err != nil {
 		return nil, fmt.Errorf("failed to encrypt text for update: %w", err)
 	}
 
@@ -391,7 +451,7 @@ func (s *GophkeeperService) UpdateText(ctx context.Context, entity *entities.Tex
 	}
 
 	// Дешифруем локальную сущность для возврата
-	if err := localText.DecryptFields(s.cryptoService); err != nil {
+	if err := s.withEntryCrypto(ctx, &localText.SecureEntity, localText.DecryptFields); err != nil {
 		return nil, fmt.Errorf("failed to decrypt local text: %w", err)
 	}
 	return localText, nil
@@ -403,7 +463,8 @@ func (s *GophkeeperService) DeleteBinary(ctx context.Context, id string) error {
 		return err
 	}
 
-	if err := s.localStorage.DeleteBinary(ctx, id); err != nil {
+	// Локальную копию могло уже удалить уведомление сервера об изменении
+	if err := s.localStorage.DeleteBinary(ctx, id); err != nil && !errors.Is(err, repositories.ErrNotFound) {
 		return fmt.Errorf("deleted on server but local failed: %w", err)
 	}
 
@@ -416,7 +477,7 @@ func (s *GophkeeperService) DeleteCard(ctx context.Context, id string) error {
 		return err
 	}
 
-	if err := s.localStorage.DeleteCard(ctx, id); err != nil {
+	if err := s.localStorage.DeleteCard(ctx, id); err != nil && !errors.Is(err, repositories.ErrNotFound) {
 		return fmt.Errorf("deleted on server but local failed: %w", err)
 	}
 
@@ -429,7 +490,7 @@ func (s *GophkeeperService) DeleteCredentials(ctx context.Context, id string) er
 		return err
 	}
 
-	if err := s.localStorage.DeleteCredentials(ctx, id); err != nil {
+	if err := s.localStorage.DeleteCredentials(ctx, id); err != nil && !errors.Is(err, repositories.ErrNotFound) {
 		return fmt.Errorf("deleted on server but local failed: %w", err)
 	}
 
@@ -442,7 +503,7 @@ func (s *GophkeeperService) DeleteText(ctx context.Context, id string) error {
 		return err
 	}
 
-	if err := s.localStorage.DeleteText(ctx, id); err != nil {
+	if err := s.localStorage.DeleteText(ctx, id); err != nil && !errors.Is(err, repositories.ErrNotFound) {
 		return fmt.Errorf("deleted on server but local failed: %w", err)
 	}
 
@@ -453,3 +514,838 @@ func (s *GophkeeperService) DeleteText(ctx context.Context, id string) error {
 func (s *GophkeeperService) ForceSync(ctx context.Context) error {
 	return s.syncService.Sync(ctx)
 }
+
+// GetActivity - получить журнал операций пользователя с сервера (журнал не кэшируется локально)
+func (s *GophkeeperService) GetActivity(ctx context.Context, from, to time.Time, entityType string) ([]entities.AuditEvent, error) {
+	return s.apiClient.GetAuditLog(ctx, from, to, entityType)
+}
+
+// GetTrash - получить записи в корзине на сервере с расшифрованными описаниями
+func (s *GophkeeperService) GetTrash(ctx context.Context) ([]entities.TrashItem, error) {
+	items, err := s.apiClient.GetTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.Metadata == "" {
+			continue
+		}
+
+		err := s.withEntryCrypto(ctx, &item.SecureEntity, func(cryptoService *encryption.CryptoService) error {
+			metadata, err := cryptoService.Decrypt(item.Metadata)
+			if err != nil {
+				return err
+			}
+			item.Metadata = metadata
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt trash item %s: %w", item.ID, err)
+		}
+	}
+
+	return items, nil
+}
+
+// RestoreTrashItem - вернуть запись из корзины на сервере и сохранить её в локальное хранилище
+func (s *GophkeeperService) RestoreTrashItem(ctx context.Context, entityType, id string) error {
+	if err := s.apiClient.RestoreTrashItem(ctx, entityType, id); err != nil {
+		return err
+	}
+
+	event := entities.ChangeEvent{Action: "create", EntityType: entityType, EntityID: id}
+	if err := s.syncService.ApplyChange(ctx, event); err != nil {
+		return fmt.Errorf("restored on server but local failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTrashItem - окончательно удалить запись из корзины на сервере (локальной копии у записи в корзине уже нет)
+func (s *GophkeeperService) DeleteTrashItem(ctx context.Context, entityType, id string) error {
+	return s.apiClient.DeleteTrashItem(ctx, entityType, id)
+}
+
+// GetHistory - получить с сервера прежние версии записи и расшифровать их (сначала последние)
+func (s *GophkeeperService) GetHistory(ctx context.Context, entityType, id string) ([]entities.Revision, error) {
+	revisions, err := s.apiClient.GetHistory(ctx, entityType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range revisions {
+		if err := s.decryptRevision(ctx, &revisions[i]); err != nil {
+			return nil, fmt.Errorf("failed to decrypt revision %d: %w", revisions[i].Revision, err)
+		}
+	}
+
+	return revisions, nil
+}
+
+// RestoreRevision - вернуть запись к прежней версии на сервере и обновить локальную копию
+func (s *GophkeeperService) RestoreRevision(ctx context.Context, entityType, id string, revision int) error {
+	if err := s.apiClient.RestoreRevision(ctx, entityType, id, revision); err != nil {
+		return err
+	}
+
+	event := entities.ChangeEvent{Action: "update", EntityType: entityType, EntityID: id}
+	if err := s.syncService.ApplyChange(ctx, event); err != nil {
+		return fmt.Errorf("restored on server but local failed: %w", err)
+	}
+
+	return nil
+}
+
+// decryptRevision - расшифровать версию записи ключом, которым она зашифрована, и заполнить её поля для показа
+func (s *GophkeeperService) decryptRevision(ctx context.Context, revision *entities.Revision) error {
+	secure := entities.SecureEntity{ID: revision.EntityID, Metadata: revision.Metadata, EntryKey: revision.EntryKey, KeyVersion: revision.KeyVersion}
+
+	var entity interface {
+		sharableEntity
+		Fields() []entities.Field
+	}
+	switch revision.EntityType {
+	case "binary":
+		entity = &entities.BinaryData{SecureEntity: secure}
+	case "card":
+		entity = &entities.CardInformation{SecureEntity: secure}
+	case "credentials":
+		entity = &entities.Credentials{SecureEntity: secure}
+	case "text":
+		entity = &entities.TextData{SecureEntity: secure}
+	default:
+		return fmt.Errorf("unknown entity type %q", revision.EntityType)
+	}
+
+	if err := json.Unmarshal(revision.Content, entity); err != nil {
+		return err
+	}
+	if err := s.withEntryCrypto(ctx, entity.Secure(), entity.DecryptFields); err != nil {
+		return err
+	}
+
+	revision.Fields = entity.Fields()
+	return nil
+}
+
+// GetUsage - получить использование хранилища на сервере и действующую квоту пользователя
+func (s *GophkeeperService) GetUsage(ctx context.Context) (*entities.QuotaUsage, error) {
+	return s.apiClient.GetUsage(ctx)
+}
+
+// ExportAccount - сохранить в файл архив с учётной записью и всеми записями пользователя с сервера.
+// Записи в архиве остаются зашифрованными ключом хранилища, поэтому открыть их можно только с паролем пользователя
+func (s *GophkeeperService) ExportAccount(ctx context.Context, path string) (int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	written, err := s.apiClient.ExportAccount(ctx, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	return written, nil
+}
+
+// DeleteAccount - удалить учётную запись на сервере со всеми данными и очистить локальную базу
+func (s *GophkeeperService) DeleteAccount(ctx context.Context, password string) error {
+	if err := s.apiClient.DeleteAccount(ctx, password); err != nil {
+		return err
+	}
+
+	if err := s.localStorage.Clear(ctx); err != nil {
+		return fmt.Errorf("deleted on server but local failed: %w", err)
+	}
+
+	s.cryptoService = nil
+	s.publicKey, s.privateKey = nil, nil
+	return nil
+}
+
+// WatchChanges - в фоне применять к локальному хранилищу изменения, сделанные на других устройствах (до отмены контекста)
+func (s *GophkeeperService) WatchChanges(ctx context.Context, onError func(error)) {
+	s.syncService.Watch(ctx, onError)
+}
+
+// GetShares - получить выданные и полученные права на записи
+func (s *GophkeeperService) GetShares(ctx context.Context) ([]entities.ShareGrant, error) {
+	return s.apiClient.GetShares(ctx)
+}
+
+// ShareEntry - предоставить пользователю recipient доступ к собственной записи.
+// При первом обмене поля записи перешифровываются отдельным ключом записи, который затем передаётся получателю
+// зашифрованным его открытым ключом
+func (s *GophkeeperService) ShareEntry(ctx context.Context, entityType, id, recipient, permission string) (*entities.ShareGrant, error) {
+	if err := s.loadKeys(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load keys: %w", err)
+	}
+
+	recipientPublicKey, err := s.userPublicKey(ctx, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	var entryKey []byte
+	switch entityType {
+	case "binary":
+		entryKey, err = ensureEntryKey(ctx, s, id, s.localStorage.GetBinary, s.apiClient.UpdateBinary, s.localStorage.UpdateBinary)
+	case "card":
+		entryKey, err = ensureEntryKey(ctx, s, id, s.localStorage.GetCard, s.apiClient.UpdateCard, s.localStorage.UpdateCard)
+	case "credentials":
+		entryKey, err = ensureEntryKey(ctx, s, id, s.localStorage.GetCredentials, s.apiClient.UpdateCredentials, s.localStorage.UpdateCredentials)
+	case "text":
+		entryKey, err = ensureEntryKey(ctx, s, id, s.localStorage.GetText, s.apiClient.UpdateText, s.localStorage.UpdateText)
+	default:
+		return nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := encryption.SealKey(recipientPublicKey, entryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal entry key: %w", err)
+	}
+
+	return s.apiClient.ShareEntry(ctx, &dtos.NewShareGrant{
+		EntityType:  entityType,
+		EntityID:    id,
+		RecipientID: recipient,
+		Permission:  permission,
+		EntryKey:    sealed,
+	})
+}
+
+// AcceptShare - принять приглашение к записи и загрузить её
+func (s *GophkeeperService) AcceptShare(ctx context.Context, id string) error {
+	if err := s.apiClient.AcceptShare(ctx, id); err != nil {
+		return err
+	}
+
+	return s.syncService.Sync(ctx)
+}
+
+// DeleteShare - отозвать выданное право или отказаться от полученного
+func (s *GophkeeperService) DeleteShare(ctx context.Context, id string) error {
+	if err := s.apiClient.DeleteShare(ctx, id); err != nil {
+		return err
+	}
+
+	return s.syncService.Sync(ctx)
+}
+
+// GetOrganizations - получить организации пользователя (в том числе те, куда он приглашён)
+func (s *GophkeeperService) GetOrganizations(ctx context.Context) ([]entities.Organization, error) {
+	return s.apiClient.GetOrganizations(ctx)
+}
+
+// CreateOrganization - создать организацию, пользователь становится её владельцем
+func (s *GophkeeperService) CreateOrganization(ctx context.Context, name string) (*entities.Organization, error) {
+	return s.apiClient.CreateOrganization(ctx, &dtos.NewOrganization{Name: name})
+}
+
+// GetOrgMembers - получить участников организации
+func (s *GophkeeperService) GetOrgMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error) {
+	return s.apiClient.GetOrgMembers(ctx, orgID)
+}
+
+// GetCollections - получить коллекции организации
+func (s *GophkeeperService) GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error) {
+	return s.apiClient.GetCollections(ctx, orgID)
+}
+
+// InviteMember - пригласить пользователя в организацию с ролью role.
+// Приглашённому передаются ключи всех коллекций, зашифрованные его открытым ключом
+func (s *GophkeeperService) InviteMember(ctx context.Context, orgID, login, role string) (*entities.OrgMember, error) {
+	publicKey, err := s.userPublicKey(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	collections, err := s.apiClient.GetCollections(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]entities.CollectionKey, 0, len(collections))
+	for i := range collections {
+		collectionKey, err := s.openCollectionKey(ctx, &collections[i])
+		if err != nil {
+			return nil, err
+		}
+
+		sealed, err := encryption.SealKey(publicKey, collectionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seal collection key: %w", err)
+		}
+
+		keys = append(keys, entities.CollectionKey{
+			CollectionID: collections[i].ID,
+			Login:        login,
+			Version:      collections[i].KeyVersion,
+			EncryptedKey: sealed,
+		})
+	}
+
+	return s.apiClient.InviteMember(ctx, orgID, &dtos.NewOrgMember{Login: login, Role: role, Keys: keys})
+}
+
+// AcceptInvite - принять приглашение в организацию и загрузить записи её коллекций
+func (s *GophkeeperService) AcceptInvite(ctx context.Context, orgID string) error {
+	if err := s.apiClient.AcceptInvite(ctx, orgID); err != nil {
+		return err
+	}
+
+	return s.syncService.Sync(ctx)
+}
+
+// RemoveMember - исключить участника из организации (или выйти из неё) и заменить ключи коллекций,
+// которые мог сохранить исключённый
+func (s *GophkeeperService) RemoveMember(ctx context.Context, orgID, login string) error {
+	if err := s.apiClient.RemoveMember(ctx, orgID, login); err != nil {
+		return err
+	}
+
+	return s.RotateCollections(ctx, orgID)
+}
+
+// CreateCollection - создать коллекцию организации с новым ключом, зашифрованным для каждого участника
+func (s *GophkeeperService) CreateCollection(ctx context.Context, orgID, name string) (*entities.Collection, error) {
+	collectionKey, err := encryption.GenerateEntryKey()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.sealForMembers(ctx, orgID, collectionKey, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.apiClient.CreateCollection(ctx, orgID, &dtos.NewCollection{Name: name, Keys: keys})
+}
+
+// RotateCollections - заменить ключи коллекций организации, отмеченных для замены, и перешифровать их записи
+// новым ключом. Прерванная ранее замена продолжается: перешифровываются все записи со старой версией ключа.
+// Управлять коллекциями могут только владелец и администраторы - для остальных выполняется только синхронизация
+func (s *GophkeeperService) RotateCollections(ctx context.Context, orgID string) error {
+	// Перешифровываются записи из локального хранилища, поэтому оно должно быть актуальным
+	if err := s.syncService.Sync(ctx); err != nil {
+		return err
+	}
+
+	orgs, err := s.apiClient.GetOrganizations(ctx)
+	if err != nil {
+		return err
+	}
+
+	var manager bool
+	for _, org := range orgs {
+		if org.ID == orgID && org.Status == entities.MemberStatusActive {
+			manager = org.Role == entities.RoleOwner || org.Role == entities.RoleAdmin
+		}
+	}
+	if !manager {
+		return nil
+	}
+
+	collections, err := s.apiClient.GetCollections(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	for i := range collections {
+		collection := &collections[i]
+		if collection.RotationRequired {
+			if collection, err = s.rotateCollectionKey(ctx, collection); err != nil {
+				return fmt.Errorf("failed to rotate key of collection %s: %w", collections[i].Name, err)
+			}
+		}
+
+		if err := s.reencryptCollection(ctx, collection); err != nil {
+			return fmt.Errorf("failed to re-encrypt collection %s: %w", collection.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// GetEmergencyAccess - получить экстренные доступы, в которых пользователь доверитель или доверенное лицо
+func (s *GophkeeperService) GetEmergencyAccess(ctx context.Context) ([]entities.EmergencyAccess, error) {
+	return s.apiClient.GetEmergencyAccess(ctx)
+}
+
+// InviteEmergencyContact - назначить пользователя login доверенным лицом. Ключ хранилища передаётся серверу
+// зашифрованным открытым ключом доверенного лица; через waitDays дней после запроса доступа, если доверитель
+// его не отклонит, сервер отдаст этот ключ доверенному лицу
+func (s *GophkeeperService) InviteEmergencyContact(ctx context.Context, login string, waitDays int) (*entities.EmergencyAccess, error) {
+	if s.cryptoService == nil {
+		return nil, errors.New("encryption is not set")
+	}
+
+	granteePublicKey, err := s.userPublicKey(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := s.cryptoService.SealFor(granteePublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal vault key: %w", err)
+	}
+
+	return s.apiClient.InviteEmergencyContact(ctx, &dtos.NewEmergencyAccess{
+		GranteeID:    login,
+		WaitDays:     waitDays,
+		EncryptedKey: sealed,
+	})
+}
+
+// AcceptEmergencyAccess - согласиться быть доверенным лицом
+func (s *GophkeeperService) AcceptEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	return s.apiClient.AcceptEmergencyAccess(ctx, id)
+}
+
+// RequestEmergencyAccess - запросить доступ к хранилищу доверителя (начинается период ожидания)
+func (s *GophkeeperService) RequestEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	return s.apiClient.RequestEmergencyAccess(ctx, id)
+}
+
+// ApproveEmergencyAccess - предоставить запрошенный доступ, не дожидаясь окончания ожидания
+func (s *GophkeeperService) ApproveEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	return s.apiClient.ApproveEmergencyAccess(ctx, id)
+}
+
+// RejectEmergencyAccess - отклонить запрос доступа или закрыть предоставленный доступ
+func (s *GophkeeperService) RejectEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	return s.apiClient.RejectEmergencyAccess(ctx, id)
+}
+
+// DeleteEmergencyAccess - отменить экстренный доступ
+func (s *GophkeeperService) DeleteEmergencyAccess(ctx context.Context, id string) error {
+	return s.apiClient.DeleteEmergencyAccess(ctx, id)
+}
+
+// OpenEmergencyVault - получить и расшифровать хранилище доверителя по предоставленному экстренному доступу.
+// Записи расшифровываются ключом хранилища доверителя, а записи с отдельным ключом - его закрытым ключом.
+// Хранилище доверителя не сохраняется в локальной базе
+func (s *GophkeeperService) OpenEmergencyVault(ctx context.Context, id string) (*entities.EmergencyVault, error) {
+	if err := s.loadKeys(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load keys: %w", err)
+	}
+
+	vault, err := s.apiClient.OpenEmergencyVault(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	vaultKey, err := encryption.OpenKey(s.privateKey, vault.Access.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vault key: %w", err)
+	}
+	grantorCrypto := encryption.NewCryptoServiceFromKey(vaultKey)
+
+	var grantorPrivateKey []byte
+	if vault.EncryptedPrivateKey != "" {
+		encodedPrivateKey, err := grantorCrypto.Decrypt(vault.EncryptedPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key of %s: %w", vault.Access.GrantorID, err)
+		}
+		if grantorPrivateKey, err = base64.StdEncoding.DecodeString(encodedPrivateKey); err != nil {
+			return nil, fmt.Errorf("invalid private key of %s: %w", vault.Access.GrantorID, err)
+		}
+	}
+
+	// entryCrypto - шифрование записи доверителя
+	entryCrypto := func(entity *entities.SecureEntity) (*encryption.CryptoService, error) {
+		if entity.EntryKey == "" {
+			return grantorCrypto, nil
+		}
+		if grantorPrivateKey == nil {
+			return nil, errors.New("grantor has no private key")
+		}
+
+		entryKey, err := encryption.OpenKey(grantorPrivateKey, entity.EntryKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open entry key: %w", err)
+		}
+		return encryption.NewCryptoServiceFromKey(entryKey), nil
+	}
+
+	if err := decryptEntries(vault.Binaries, entryCrypto); err != nil {
+		return nil, err
+	}
+	if err := decryptEntries(vault.Cards, entryCrypto); err != nil {
+		return nil, err
+	}
+	if err := decryptEntries(vault.Credentials, entryCrypto); err != nil {
+		return nil, err
+	}
+	if err := decryptEntries(vault.Texts, entryCrypto); err != nil {
+		return nil, err
+	}
+
+	return vault, nil
+}
+
+// rotateCollectionKey - выпустить следующую версию ключа коллекции для текущих участников
+func (s *GophkeeperService) rotateCollectionKey(ctx context.Context, collection *entities.Collection) (*entities.Collection, error) {
+	collectionKey, err := encryption.GenerateEntryKey()
+	if err != nil {
+		return nil, err
+	}
+
+	version := collection.KeyVersion + 1
+	keys, err := s.sealForMembers(ctx, collection.OrgID, collectionKey, version)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		keys[i].CollectionID = collection.ID
+	}
+
+	return s.apiClient.RotateCollectionKey(ctx, collection.ID, &dtos.CollectionRotation{Version: version, Keys: keys})
+}
+
+// reencryptCollection - перешифровать текущим ключом коллекции записи, зашифрованные предыдущими версиями ключа
+func (s *GophkeeperService) reencryptCollection(ctx context.Context, collection *entities.Collection) error {
+	collectionKey, err := s.openCollectionKey(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	if err := reencryptEntries(ctx, s, collection, collectionKey, s.localStorage.GetAllBinaries, s.apiClient.UpdateBinary, s.localStorage.UpdateBinary); err != nil {
+		return err
+	}
+	if err := reencryptEntries(ctx, s, collection, collectionKey, s.localStorage.GetAllCards, s.apiClient.UpdateCard, s.localStorage.UpdateCard); err != nil {
+		return err
+	}
+	if err := reencryptEntries(ctx, s, collection, collectionKey, s.localStorage.GetAllCredentials, s.apiClient.UpdateCredentials, s.localStorage.UpdateCredentials); err != nil {
+		return err
+	}
+	return reencryptEntries(ctx, s, collection, collectionKey, s.localStorage.GetAllTexts, s.apiClient.UpdateText, s.localStorage.UpdateText)
+}
+
+// newEntryCrypto - шифрование полей новой записи: ключом хранилища или, для записи коллекции,
+// текущим ключом коллекции (его версия сохраняется в dto)
+func (s *GophkeeperService) newEntryCrypto(ctx context.Context, dto *dtos.NewSecureEntity) (*encryption.CryptoService, error) {
+	if dto.CollectionID == "" {
+		return s.cryptoService, nil
+	}
+
+	collection, err := s.findCollection(ctx, dto.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	collectionKey, err := s.openCollectionKey(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	dto.KeyVersion = collection.KeyVersion
+	return encryption.NewCryptoServiceFromKey(collectionKey), nil
+}
+
+// findCollection - найти коллекцию среди коллекций организаций, в которых состоит пользователь
+func (s *GophkeeperService) findCollection(ctx context.Context, id string) (*entities.Collection, error) {
+	orgs, err := s.apiClient.GetOrganizations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, org := range orgs {
+		if org.Status != entities.MemberStatusActive {
+			continue
+		}
+
+		collections, err := s.apiClient.GetCollections(ctx, org.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range collections {
+			if collections[i].ID == id {
+				return &collections[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("collection %s not found", id)
+}
+
+// openCollectionKey - расшифровать текущий ключ коллекции закрытым ключом пользователя
+func (s *GophkeeperService) openCollectionKey(ctx context.Context, collection *entities.Collection) ([]byte, error) {
+	if collection.EncryptedKey == "" {
+		return nil, fmt.Errorf("no key of collection %s for current user", collection.Name)
+	}
+
+	if err := s.loadKeys(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load keys: %w", err)
+	}
+
+	collectionKey, err := encryption.OpenKey(s.privateKey, collection.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open collection key: %w", err)
+	}
+
+	return collectionKey, nil
+}
+
+// sealForMembers - зашифровать ключ коллекции версии version открытым ключом каждого участника организации
+func (s *GophkeeperService) sealForMembers(ctx context.Context, orgID string, collectionKey []byte, version int) ([]entities.CollectionKey, error) {
+	members, err := s.apiClient.GetOrgMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]entities.CollectionKey, 0, len(members))
+	for _, member := range members {
+		publicKey, err := s.userPublicKey(ctx, member.Login)
+		if err != nil {
+			return nil, err
+		}
+
+		sealed, err := encryption.SealKey(publicKey, collectionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seal collection key: %w", err)
+		}
+
+		keys = append(keys, entities.CollectionKey{Login: member.Login, Version: version, EncryptedKey: sealed})
+	}
+
+	return keys, nil
+}
+
+// userPublicKey - получить опубликованный открытый ключ пользователя
+func (s *GophkeeperService) userPublicKey(ctx context.Context, login string) ([]byte, error) {
+	keys, err := s.apiClient.GetUserKeys(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("user %s has not published keys yet", login)
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(keys.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key of user %s: %w", login, err)
+	}
+
+	return publicKey, nil
+}
+
+// loadKeys - загрузить пару ключей пользователя с сервера (при первом обращении - сгенерировать и опубликовать)
+func (s *GophkeeperService) loadKeys(ctx context.Context) error {
+	if s.privateKey != nil {
+		return nil
+	}
+
+	if s.cryptoService == nil {
+		return errors.New("encryption is not set")
+	}
+
+	keys, err := s.apiClient.GetUserKeys(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	if keys == nil {
+		publicKey, privateKey, err := encryption.GenerateKeyPair()
+		if err != nil {
+			return err
+		}
+
+		// Закрытый ключ хранится на сервере зашифрованным ключом хранилища
+		encryptedPrivateKey, err := s.cryptoService.Encrypt(base64.StdEncoding.EncodeToString(privateKey))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt private key: %w", err)
+		}
+
+		err = s.apiClient.SetUserKeys(ctx, &entities.UserKeys{
+			PublicKey:           base64.StdEncoding.EncodeToString(publicKey),
+			EncryptedPrivateKey: encryptedPrivateKey,
+		})
+		if err != nil {
+			return err
+		}
+
+		s.publicKey, s.privateKey = publicKey, privateKey
+		return nil
+	}
+
+	encodedPrivateKey, err := s.cryptoService.Decrypt(keys.EncryptedPrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	privateKey, err := base64.StdEncoding.DecodeString(encodedPrivateKey)
+	if err != nil {
+		return fmt.Errorf("invalid private key: %w", err)
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(keys.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	s.publicKey, s.privateKey = publicKey, privateKey
+	return nil
+}
+
+// withEntryCrypto - выполнить шифрование/дешифрование полей записи ключом записи (если он есть) или ключом хранилища
+func (s *GophkeeperService) withEntryCrypto(ctx context.Context, entity *entities.SecureEntity, fn func(*encryption.CryptoService) error) error {
+	if entity.EntryKey == "" {
+		return fn(s.cryptoService)
+	}
+
+	if err := s.loadKeys(ctx); err != nil {
+		return fmt.Errorf("failed to load keys: %w", err)
+	}
+
+	entryKey, err := encryption.OpenKey(s.privateKey, entity.EntryKey)
+	if err != nil {
+		return fmt.Errorf("failed to open entry key: %w", err)
+	}
+
+	return fn(encryption.NewCryptoServiceFromKey(entryKey))
+}
+
+// sharableEntity - запись, доступом к которой можно поделиться
+type sharableEntity interface {
+	Secure() *entities.SecureEntity
+	EncryptFields(cryptoService *encryption.CryptoService) error
+	DecryptFields(cryptoService *encryption.CryptoService) error
+}
+
+// ensureEntryKey - получить ключ собственной записи. Если записи ещё не выдавался отдельный ключ,
+// он генерируется, а поля записи перешифровываются им на сервере и локально
+func ensureEntryKey[T any, PT interface {
+	*T
+	sharableEntity
+}](
+	ctx context.Context,
+	s *GophkeeperService,
+	id string,
+	getLocal func(context.Context, string) (*T, error),
+	updateServer func(context.Context, *T) (*T, error),
+	updateLocal func(context.Context, *T) (*T, error),
+) ([]byte, error) {
+	entity, err := getLocal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, fmt.Errorf("entry %s not found", id)
+	}
+
+	secure := PT(entity).Secure()
+	if secure.IsShared() {
+		return nil, errors.New("only the owner can share an entry")
+	}
+
+	if secure.EntryKey != "" {
+		return encryption.OpenKey(s.privateKey, secure.EntryKey)
+	}
+
+	entryKey, err := encryption.GenerateEntryKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := PT(entity).DecryptFields(s.cryptoService); err != nil {
+		return nil, fmt.Errorf("failed to decrypt entry: %w", err)
+	}
+	if err := PT(entity).EncryptFields(encryption.NewCryptoServiceFromKey(entryKey)); err != nil {
+		return nil, fmt.Errorf("failed to encrypt entry: %w", err)
+	}
+
+	if secure.EntryKey, err = encryption.SealKey(s.publicKey, entryKey); err != nil {
+		return nil, fmt.Errorf("failed to seal entry key: %w", err)
+	}
+
+	serverEntity, err := updateServer(ctx, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := updateLocal(ctx, serverEntity); err != nil {
+		return nil, fmt.Errorf("updated on server but local failed: %w", err)
+	}
+
+	return entryKey, nil
+}
+
+// reencryptEntries - перешифровать записи коллекции с устаревшей версией ключа новым ключом коллекции
+// на сервере и локально
+func reencryptEntries[T any, PT interface {
+	*T
+	sharableEntity
+}](
+	ctx context.Context,
+	s *GophkeeperService,
+	collection *entities.Collection,
+	collectionKey []byte,
+	getAllLocal func(context.Context) ([]T, error),
+	updateServer func(context.Context, *T) (*T, error),
+	updateLocal func(context.Context, *T) (*T, error),
+) error {
+	entries, err := getAllLocal(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entity := PT(&entries[i])
+		secure := entity.Secure()
+		if secure.CollectionID != collection.ID || secure.KeyVersion >= collection.KeyVersion {
+			continue
+		}
+
+		// Старая версия ключа остаётся у участников, поэтому запись читается ею
+		if err := s.withEntryCrypto(ctx, secure, entity.DecryptFields); err != nil {
+			return fmt.Errorf("failed to decrypt entry %s: %w", secure.ID, err)
+		}
+		if err := entity.EncryptFields(encryption.NewCryptoServiceFromKey(collectionKey)); err != nil {
+			return fmt.Errorf("failed to encrypt entry %s: %w", secure.ID, err)
+		}
+		secure.KeyVersion = collection.KeyVersion
+
+		serverEntity, err := updateServer(ctx, &entries[i])
+		if err != nil {
+			return err
+		}
+
+		if _, err := updateLocal(ctx, serverEntity); err != nil {
+			return fmt.Errorf("updated on server but local failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// decryptEntries - расшифровать записи, выбирая шифрование каждой записи функцией entryCrypto
+func decryptEntries[T any, PT interface {
+	*T
+	sharableEntity
+}](entries []T, entryCrypto func(*entities.SecureEntity) (*encryption.CryptoService, error)) error {
+	for i := range entries {
+		entity := PT(&entries[i])
+
+		cryptoService, err := entryCrypto(entity.Secure())
+		if err != nil {
+			return fmt.Errorf("entry %s: %w", entity.Secure().ID, err)
+		}
+		if err := entity.DecryptFields(cryptoService); err != nil {
+			return fmt.Errorf("failed to decrypt entry %s: %w", entity.Secure().ID, err)
+		}
+	}
+
+	return nil
+}
@@ -3,14 +3,23 @@ package services_test
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/JustScorpio/GophKeeper/frontend/internal/clients"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/encryption"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories/inmemory"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/services"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -21,8 +30,8 @@ type MockGophKeeperAPIClient struct {
 	mock.Mock
 }
 
-func (m *MockGophKeeperAPIClient) Register(ctx context.Context, login, password string) error {
-	args := m.Called(ctx, login, password)
+func (m *MockGophKeeperAPIClient) Register(ctx context.Context, login, password, inviteCode string) error {
+	args := m.Called(ctx, login, password, inviteCode)
 	return args.Error(0)
 }
 
@@ -179,6 +188,237 @@ func (m *MockGophKeeperAPIClient) DeleteText(ctx context.Context, id string) err
 	return args.Error(0)
 }
 
+func (m *MockGophKeeperAPIClient) GetAuditLog(ctx context.Context, from, to time.Time, entityType string) ([]entities.AuditEvent, error) {
+	args := m.Called(ctx, from, to, entityType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.AuditEvent), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) GetTrash(ctx context.Context) ([]entities.TrashItem, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.TrashItem), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) RestoreTrashItem(ctx context.Context, entityType, id string) error {
+	args := m.Called(ctx, entityType, id)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) DeleteTrashItem(ctx context.Context, entityType, id string) error {
+	args := m.Called(ctx, entityType, id)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) GetHistory(ctx context.Context, entityType, id string) ([]entities.Revision, error) {
+	args := m.Called(ctx, entityType, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.Revision), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) RestoreRevision(ctx context.Context, entityType, id string, revision int) error {
+	args := m.Called(ctx, entityType, id, revision)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) GetUsage(ctx context.Context) (*entities.QuotaUsage, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.QuotaUsage), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) ExportAccount(ctx context.Context, dst io.Writer) (int64, error) {
+	args := m.Called(ctx, dst)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) DeleteAccount(ctx context.Context, password string) error {
+	args := m.Called(ctx, password)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) SubscribeChanges(ctx context.Context) (*clients.ChangeStream, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*clients.ChangeStream), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) SetUserKeys(ctx context.Context, keys *entities.UserKeys) error {
+	args := m.Called(ctx, keys)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) GetUserKeys(ctx context.Context, login string) (*entities.UserKeys, error) {
+	args := m.Called(ctx, login)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.UserKeys), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) ShareEntry(ctx context.Context, dto *dtos.NewShareGrant) (*entities.ShareGrant, error) {
+	args := m.Called(ctx, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.ShareGrant), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) GetShares(ctx context.Context) ([]entities.ShareGrant, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.ShareGrant), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) AcceptShare(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) DeleteShare(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) CreateOrganization(ctx context.Context, dto *dtos.NewOrganization) (*entities.Organization, error) {
+	args := m.Called(ctx, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Organization), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) GetOrganizations(ctx context.Context) ([]entities.Organization, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.Organization), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) GetOrgMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.OrgMember), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) InviteMember(ctx context.Context, orgID string, dto *dtos.NewOrgMember) (*entities.OrgMember, error) {
+	args := m.Called(ctx, orgID, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.OrgMember), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) AcceptInvite(ctx context.Context, orgID string) error {
+	args := m.Called(ctx, orgID)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) RemoveMember(ctx context.Context, orgID, login string) error {
+	args := m.Called(ctx, orgID, login)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) CreateCollection(ctx context.Context, orgID string, dto *dtos.NewCollection) (*entities.Collection, error) {
+	args := m.Called(ctx, orgID, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Collection), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.Collection), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) RotateCollectionKey(ctx context.Context, collectionID string, dto *dtos.CollectionRotation) (*entities.Collection, error) {
+	args := m.Called(ctx, collectionID, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Collection), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) InviteEmergencyContact(ctx context.Context, dto *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) GetEmergencyAccess(ctx context.Context) ([]entities.EmergencyAccess, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) AcceptEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) RequestEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) ApproveEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) RejectEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockGophKeeperAPIClient) DeleteEmergencyAccess(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockGophKeeperAPIClient) OpenEmergencyVault(ctx context.Context, id string) (*entities.EmergencyVault, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyVault), args.Error(1)
+}
+
 func TestGophkeeperService_Encryption(t *testing.T) {
 	ctx := context.Background()
 	testPassword := "testpass123"
@@ -216,9 +456,14 @@ func TestGophkeeperService_Encryption(t *testing.T) {
 		syncService := services.NewSyncService(mockAPI, storageService)
 		gophkeeperService := services.NewGophkeeperService(mockAPI, storageService, syncService)
 
-		mockAPI.On("Register", ctx, "testuser", testPassword).Return(nil)
+		mockAPI.On("Register", ctx, "testuser", testPassword, "invite-code").Return(nil)
+		// Новому пользователю генерируется и публикуется пара ключей
+		mockAPI.On("GetUserKeys", ctx, "").Return(nil, nil)
+		mockAPI.On("SetUserKeys", ctx, mock.MatchedBy(func(keys *entities.UserKeys) bool {
+			return keys.PublicKey != "" && keys.EncryptedPrivateKey != ""
+		})).Return(nil)
 
-		err := gophkeeperService.Register(ctx, "testuser", testPassword)
+		err := gophkeeperService.Register(ctx, "testuser", testPassword, "invite-code")
 		require.NoError(t, err)
 		assert.True(t, gophkeeperService.IsEncryptionSet())
 
@@ -242,6 +487,8 @@ func TestGophkeeperService_Encryption(t *testing.T) {
 		mockAPI.On("GetAllCards", ctx).Return([]entities.CardInformation{}, nil)
 		mockAPI.On("GetAllCredentials", ctx).Return([]entities.Credentials{}, nil)
 		mockAPI.On("GetAllTexts", ctx).Return([]entities.TextData{}, nil)
+		// Ошибка загрузки ключей не прерывает вход
+		mockAPI.On("GetUserKeys", ctx, "").Return(nil, errors.New("keys unavailable"))
 
 		err := gophkeeperService.Login(ctx, "user", testPassword)
 		require.NoError(t, err)
@@ -1341,3 +1588,712 @@ func TestGophkeeperService_TextCRUDOperations(t *testing.T) {
 		mockAPI.AssertExpectations(t)
 	})
 }
+
+func TestGophkeeperService_Sharing(t *testing.T) {
+	ctx := context.Background()
+	testPassword := "testpass123"
+	cryptoService := encryption.NewCryptoService(testPassword)
+
+	newService := func() (*services.GophkeeperService, *services.StorageService, *MockGophKeeperAPIClient) {
+		mockAPI := new(MockGophKeeperAPIClient)
+		dbManager := inmemory.NewDatabaseManager()
+		storageService := services.NewStorageService(
+			dbManager.BinariesRepo,
+			dbManager.CardsRepo,
+			dbManager.CredentialsRepo,
+			dbManager.TextsRepo,
+		)
+		syncService := services.NewSyncService(mockAPI, storageService)
+		gophkeeperService := services.NewGophkeeperService(mockAPI, storageService, syncService)
+		require.NoError(t, gophkeeperService.SetEncryption(testPassword))
+		return gophkeeperService, storageService, mockAPI
+	}
+
+	recipientPublicKey, recipientPrivateKey, err := encryption.GenerateKeyPair()
+	require.NoError(t, err)
+	recipientKeys := &entities.UserKeys{Login: "bob", PublicKey: base64.StdEncoding.EncodeToString(recipientPublicKey)}
+
+	t.Run("ShareEntry - re-encrypts entry with its own key and seals it for recipient", func(t *testing.T) {
+		gophkeeperService, storageService, mockAPI := newService()
+
+		encryptedData, err := cryptoService.Encrypt("secret text")
+		require.NoError(t, err)
+		encryptedMetadata, err := cryptoService.Encrypt("note")
+		require.NoError(t, err)
+		_, err = storageService.CreateText(ctx, &entities.TextData{
+			SecureEntity: entities.SecureEntity{ID: "text-1", Metadata: encryptedMetadata},
+			Data:         encryptedData,
+		})
+		require.NoError(t, err)
+
+		mockAPI.On("GetUserKeys", ctx, "").Return(nil, nil)
+		mockAPI.On("SetUserKeys", ctx, mock.AnythingOfType("*entities.UserKeys")).Return(nil)
+		mockAPI.On("GetUserKeys", ctx, "bob").Return(recipientKeys, nil)
+
+		// Сервер возвращает перешифрованную запись как есть
+		serverText := &entities.TextData{}
+		mockAPI.On("UpdateText", ctx, mock.AnythingOfType("*entities.TextData")).Run(func(args mock.Arguments) {
+			*serverText = *args.Get(1).(*entities.TextData)
+		}).Return(serverText, nil).Once()
+
+		var sharedDTO *dtos.NewShareGrant
+		mockAPI.On("ShareEntry", ctx, mock.AnythingOfType("*dtos.NewShareGrant")).Run(func(args mock.Arguments) {
+			sharedDTO = args.Get(1).(*dtos.NewShareGrant)
+		}).Return(&entities.ShareGrant{ID: "1", Status: entities.ShareStatusPending}, nil)
+
+		grant, err := gophkeeperService.ShareEntry(ctx, "text", "text-1", "bob", entities.PermissionRead)
+		require.NoError(t, err)
+		assert.Equal(t, "1", grant.ID)
+		assert.Equal(t, "text", sharedDTO.EntityType)
+		assert.Equal(t, "text-1", sharedDTO.EntityID)
+		assert.Equal(t, "bob", sharedDTO.RecipientID)
+
+		// Получатель открывает ключ записи своим закрытым ключом и читает запись
+		entryKey, err := encryption.OpenKey(recipientPrivateKey, sharedDTO.EntryKey)
+		require.NoError(t, err)
+		localText, err := storageService.GetText(ctx, "text-1")
+		require.NoError(t, err)
+		require.NotEmpty(t, localText.EntryKey)
+		data, err := encryption.NewCryptoServiceFromKey(entryKey).Decrypt(localText.Data)
+		require.NoError(t, err)
+		assert.Equal(t, "secret text", data)
+
+		// Владелец по-прежнему читает запись
+		text, err := gophkeeperService.GetText(ctx, "text-1")
+		require.NoError(t, err)
+		assert.Equal(t, "secret text", text.Data)
+		assert.Equal(t, "note", text.Metadata)
+
+		// Повторный обмен использует уже выданный ключ записи
+		_, err = gophkeeperService.ShareEntry(ctx, "text", "text-1", "bob", entities.PermissionWrite)
+		require.NoError(t, err)
+		reopened, err := encryption.OpenKey(recipientPrivateKey, sharedDTO.EntryKey)
+		require.NoError(t, err)
+		assert.Equal(t, entryKey, reopened)
+
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("ShareEntry - recipient without keys", func(t *testing.T) {
+		gophkeeperService, _, mockAPI := newService()
+
+		mockAPI.On("GetUserKeys", ctx, "").Return(nil, nil)
+		mockAPI.On("SetUserKeys", ctx, mock.AnythingOfType("*entities.UserKeys")).Return(nil)
+		mockAPI.On("GetUserKeys", ctx, "carol").Return(nil, nil)
+
+		_, err := gophkeeperService.ShareEntry(ctx, "text", "text-1", "carol", entities.PermissionRead)
+		assert.Error(t, err)
+		mockAPI.AssertNotCalled(t, "ShareEntry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Shared entry is decrypted with entry key and cannot be re-shared", func(t *testing.T) {
+		gophkeeperService, storageService, mockAPI := newService()
+
+		// Ключи получателя хранятся на сервере, закрытый - зашифрованным ключом хранилища
+		encryptedPrivateKey, err := cryptoService.Encrypt(base64.StdEncoding.EncodeToString(recipientPrivateKey))
+		require.NoError(t, err)
+		mockAPI.On("GetUserKeys", ctx, "").Return(&entities.UserKeys{
+			Login:               "bob",
+			PublicKey:           recipientKeys.PublicKey,
+			EncryptedPrivateKey: encryptedPrivateKey,
+		}, nil)
+		mockAPI.On("GetUserKeys", ctx, "alice").Return(&entities.UserKeys{Login: "alice", PublicKey: recipientKeys.PublicKey}, nil)
+
+		entryKey, err := encryption.GenerateEntryKey()
+		require.NoError(t, err)
+		entryCrypto := encryption.NewCryptoServiceFromKey(entryKey)
+		sealed, err := encryption.SealKey(recipientPublicKey, entryKey)
+		require.NoError(t, err)
+		encryptedData, err := entryCrypto.Encrypt("shared text")
+		require.NoError(t, err)
+		encryptedMetadata, err := entryCrypto.Encrypt("shared note")
+		require.NoError(t, err)
+		_, err = storageService.CreateText(ctx, &entities.TextData{
+			SecureEntity: entities.SecureEntity{
+				ID:         "text-2",
+				Metadata:   encryptedMetadata,
+				OwnerID:    "alice",
+				EntryKey:   sealed,
+				Permission: entities.PermissionRead,
+			},
+			Data: encryptedData,
+		})
+		require.NoError(t, err)
+
+		text, err := gophkeeperService.GetText(ctx, "text-2")
+		require.NoError(t, err)
+		assert.Equal(t, "shared text", text.Data)
+		assert.Equal(t, "shared note", text.Metadata)
+		assert.False(t, text.CanEdit())
+
+		_, err = gophkeeperService.ShareEntry(ctx, "text", "text-2", "alice", entities.PermissionRead)
+		assert.Error(t, err)
+		mockAPI.AssertNotCalled(t, "ShareEntry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("AcceptShare - syncs accepted entry", func(t *testing.T) {
+		gophkeeperService, _, mockAPI := newService()
+
+		mockAPI.On("AcceptShare", ctx, "7").Return(nil)
+		mockAPI.On("GetAllBinaries", ctx).Return([]entities.BinaryData{}, nil)
+		mockAPI.On("GetAllCards", ctx).Return([]entities.CardInformation{}, nil)
+		mockAPI.On("GetAllCredentials", ctx).Return([]entities.Credentials{}, nil)
+		mockAPI.On("GetAllTexts", ctx).Return([]entities.TextData{}, nil)
+
+		require.NoError(t, gophkeeperService.AcceptShare(ctx, "7"))
+		mockAPI.AssertExpectations(t)
+	})
+}
+
+func TestGophkeeperService_Organizations(t *testing.T) {
+	ctx := context.Background()
+	testPassword := "testpass123"
+	cryptoService := encryption.NewCryptoService(testPassword)
+
+	newService := func() (*services.GophkeeperService, *services.StorageService, *MockGophKeeperAPIClient) {
+		mockAPI := new(MockGophKeeperAPIClient)
+		dbManager := inmemory.NewDatabaseManager()
+		storageService := services.NewStorageService(
+			dbManager.BinariesRepo,
+			dbManager.CardsRepo,
+			dbManager.CredentialsRepo,
+			dbManager.TextsRepo,
+		)
+		syncService := services.NewSyncService(mockAPI, storageService)
+		gophkeeperService := services.NewGophkeeperService(mockAPI, storageService, syncService)
+		require.NoError(t, gophkeeperService.SetEncryption(testPassword))
+		return gophkeeperService, storageService, mockAPI
+	}
+
+	// Ключи текущего пользователя (владельца организации) и второго участника
+	ownerPublicKey, ownerPrivateKey, err := encryption.GenerateKeyPair()
+	require.NoError(t, err)
+	encryptedPrivateKey, err := cryptoService.Encrypt(base64.StdEncoding.EncodeToString(ownerPrivateKey))
+	require.NoError(t, err)
+	ownerKeys := &entities.UserKeys{Login: "alice", PublicKey: base64.StdEncoding.EncodeToString(ownerPublicKey), EncryptedPrivateKey: encryptedPrivateKey}
+
+	memberPublicKey, memberPrivateKey, err := encryption.GenerateKeyPair()
+	require.NoError(t, err)
+	memberKeys := &entities.UserKeys{Login: "bob", PublicKey: base64.StdEncoding.EncodeToString(memberPublicKey)}
+
+	collectionKey, err := encryption.GenerateEntryKey()
+	require.NoError(t, err)
+	sealedForOwner, err := encryption.SealKey(ownerPublicKey, collectionKey)
+	require.NoError(t, err)
+
+	org := entities.Organization{ID: "org-1", Name: "team", Role: entities.RoleOwner, Status: entities.MemberStatusActive}
+	collection := entities.Collection{ID: "col-1", OrgID: "org-1", Name: "shared", KeyVersion: 1, EncryptedKey: sealedForOwner}
+
+	t.Run("CreateText - encrypts collection entry with collection key", func(t *testing.T) {
+		gophkeeperService, _, mockAPI := newService()
+
+		mockAPI.On("GetUserKeys", ctx, "").Return(ownerKeys, nil)
+		mockAPI.On("GetOrganizations", ctx).Return([]entities.Organization{org}, nil)
+		mockAPI.On("GetCollections", ctx, "org-1").Return([]entities.Collection{collection}, nil)
+
+		// Сервер возвращает запись с ключом коллекции, зашифрованным для пользователя
+		var sentDTO dtos.NewTextData
+		serverText := &entities.TextData{}
+		mockAPI.On("CreateText", ctx, mock.AnythingOfType("*dtos.NewTextData")).Run(func(args mock.Arguments) {
+			sentDTO = *args.Get(1).(*dtos.NewTextData)
+			*serverText = entities.TextData{
+				SecureEntity: entities.SecureEntity{ID: "text-1", Metadata: sentDTO.Metadata, CollectionID: sentDTO.CollectionID,
+					KeyVersion: sentDTO.KeyVersion, EntryKey: sealedForOwner, Permission: entities.PermissionWrite},
+				Data: sentDTO.Data,
+			}
+		}).Return(serverText, nil)
+
+		text, err := gophkeeperService.CreateText(ctx, &dtos.NewTextData{Data: "team secret", NewSecureEntity: dtos.NewSecureEntity{CollectionID: "col-1"}})
+		require.NoError(t, err)
+		assert.Equal(t, "team secret", text.Data)
+		assert.Equal(t, 1, sentDTO.KeyVersion)
+
+		data, err := encryption.NewCryptoServiceFromKey(collectionKey).Decrypt(sentDTO.Data)
+		require.NoError(t, err)
+		assert.Equal(t, "team secret", data)
+	})
+
+	t.Run("InviteMember - seals every collection key for invitee", func(t *testing.T) {
+		gophkeeperService, _, mockAPI := newService()
+
+		mockAPI.On("GetUserKeys", ctx, "").Return(ownerKeys, nil)
+		mockAPI.On("GetUserKeys", ctx, "bob").Return(memberKeys, nil)
+		mockAPI.On("GetCollections", ctx, "org-1").Return([]entities.Collection{collection}, nil)
+
+		var invitation *dtos.NewOrgMember
+		mockAPI.On("InviteMember", ctx, "org-1", mock.AnythingOfType("*dtos.NewOrgMember")).Run(func(args mock.Arguments) {
+			invitation = args.Get(2).(*dtos.NewOrgMember)
+		}).Return(&entities.OrgMember{OrgID: "org-1", Login: "bob", Role: entities.RoleMember, Status: entities.MemberStatusInvited}, nil)
+
+		_, err := gophkeeperService.InviteMember(ctx, "org-1", "bob", entities.RoleMember)
+		require.NoError(t, err)
+		require.Len(t, invitation.Keys, 1)
+		assert.Equal(t, "col-1", invitation.Keys[0].CollectionID)
+		assert.Equal(t, 1, invitation.Keys[0].Version)
+
+		opened, err := encryption.OpenKey(memberPrivateKey, invitation.Keys[0].EncryptedKey)
+		require.NoError(t, err)
+		assert.Equal(t, collectionKey, opened)
+	})
+
+	t.Run("RemoveMember - rotates collection key and re-encrypts entries", func(t *testing.T) {
+		gophkeeperService, storageService, mockAPI := newService()
+
+		encryptedData, err := encryption.NewCryptoServiceFromKey(collectionKey).Encrypt("team secret")
+		require.NoError(t, err)
+		encryptedMetadata, err := encryption.NewCryptoServiceFromKey(collectionKey).Encrypt("note")
+		require.NoError(t, err)
+		serverText := entities.TextData{
+			SecureEntity: entities.SecureEntity{ID: "text-1", Metadata: encryptedMetadata, CollectionID: "col-1", KeyVersion: 1,
+				EntryKey: sealedForOwner, Permission: entities.PermissionWrite},
+			Data: encryptedData,
+		}
+
+		pending := collection
+		pending.RotationRequired = true
+
+		mockAPI.On("RemoveMember", ctx, "org-1", "bob").Return(nil)
+		mockAPI.On("GetAllBinaries", ctx).Return([]entities.BinaryData{}, nil)
+		mockAPI.On("GetAllCards", ctx).Return([]entities.CardInformation{}, nil)
+		mockAPI.On("GetAllCredentials", ctx).Return([]entities.Credentials{}, nil)
+		mockAPI.On("GetAllTexts", ctx).Return([]entities.TextData{serverText}, nil)
+		mockAPI.On("GetUserKeys", ctx, "").Return(ownerKeys, nil)
+		mockAPI.On("GetUserKeys", ctx, "alice").Return(ownerKeys, nil)
+		mockAPI.On("GetOrganizations", ctx).Return([]entities.Organization{org}, nil)
+		mockAPI.On("GetCollections", ctx, "org-1").Return([]entities.Collection{pending}, nil)
+		mockAPI.On("GetOrgMembers", ctx, "org-1").Return([]entities.OrgMember{{OrgID: "org-1", Login: "alice", Role: entities.RoleOwner}}, nil)
+
+		var rotation *dtos.CollectionRotation
+		rotated := collection
+		mockAPI.On("RotateCollectionKey", ctx, "col-1", mock.AnythingOfType("*dtos.CollectionRotation")).Run(func(args mock.Arguments) {
+			rotation = args.Get(2).(*dtos.CollectionRotation)
+			rotated.KeyVersion = rotation.Version
+			rotated.EncryptedKey = rotation.Keys[0].EncryptedKey
+		}).Return(&rotated, nil)
+
+		var reencrypted entities.TextData
+		mockAPI.On("UpdateText", ctx, mock.AnythingOfType("*entities.TextData")).Run(func(args mock.Arguments) {
+			reencrypted = *args.Get(1).(*entities.TextData)
+			reencrypted.EntryKey = rotated.EncryptedKey
+		}).Return(&reencrypted, nil)
+
+		require.NoError(t, gophkeeperService.RemoveMember(ctx, "org-1", "bob"))
+
+		// Новый ключ выдан только оставшимся участникам
+		require.Equal(t, 2, rotation.Version)
+		require.Len(t, rotation.Keys, 1)
+		assert.Equal(t, "alice", rotation.Keys[0].Login)
+		assert.Equal(t, "col-1", rotation.Keys[0].CollectionID)
+
+		newKey, err := encryption.OpenKey(ownerPrivateKey, rotation.Keys[0].EncryptedKey)
+		require.NoError(t, err)
+		assert.NotEqual(t, collectionKey, newKey)
+
+		assert.Equal(t, 2, reencrypted.KeyVersion)
+		data, err := encryption.NewCryptoServiceFromKey(newKey).Decrypt(reencrypted.Data)
+		require.NoError(t, err)
+		assert.Equal(t, "team secret", data)
+
+		local, err := storageService.GetText(ctx, "text-1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, local.KeyVersion)
+
+		text, err := gophkeeperService.GetText(ctx, "text-1")
+		require.NoError(t, err)
+		assert.Equal(t, "note", text.Metadata)
+	})
+}
+
+// TestGophkeeperService_EmergencyAccess тестирует передачу ключа хранилища доверенному лицу и открытие хранилища доверителя
+func TestGophkeeperService_EmergencyAccess(t *testing.T) {
+	ctx := context.Background()
+	grantorPassword, trusteePassword := "grantor-pass", "trustee-pass"
+	grantorCrypto := encryption.NewCryptoService(grantorPassword)
+
+	newService := func(password string) (*services.GophkeeperService, *MockGophKeeperAPIClient) {
+		mockAPI := new(MockGophKeeperAPIClient)
+		dbManager := inmemory.NewDatabaseManager()
+		storageService := services.NewStorageService(
+			dbManager.BinariesRepo,
+			dbManager.CardsRepo,
+			dbManager.CredentialsRepo,
+			dbManager.TextsRepo,
+		)
+		syncService := services.NewSyncService(mockAPI, storageService)
+		gophkeeperService := services.NewGophkeeperService(mockAPI, storageService, syncService)
+		require.NoError(t, gophkeeperService.SetEncryption(password))
+		return gophkeeperService, mockAPI
+	}
+
+	grantorPublicKey, grantorPrivateKey, err := encryption.GenerateKeyPair()
+	require.NoError(t, err)
+	trusteePublicKey, trusteePrivateKey, err := encryption.GenerateKeyPair()
+	require.NoError(t, err)
+
+	t.Run("InviteEmergencyContact - seals vault key for trustee", func(t *testing.T) {
+		gophkeeperService, mockAPI := newService(grantorPassword)
+
+		mockAPI.On("GetUserKeys", ctx, "bob").Return(&entities.UserKeys{Login: "bob", PublicKey: base64.StdEncoding.EncodeToString(trusteePublicKey)}, nil)
+
+		var invitation *dtos.NewEmergencyAccess
+		mockAPI.On("InviteEmergencyContact", ctx, mock.AnythingOfType("*dtos.NewEmergencyAccess")).Run(func(args mock.Arguments) {
+			invitation = args.Get(1).(*dtos.NewEmergencyAccess)
+		}).Return(&entities.EmergencyAccess{ID: "1", GrantorID: "alice", GranteeID: "bob", Status: entities.EmergencyStatusInvited, WaitDays: 7}, nil)
+
+		access, err := gophkeeperService.InviteEmergencyContact(ctx, "bob", 7)
+		require.NoError(t, err)
+		assert.Equal(t, entities.EmergencyStatusInvited, access.Status)
+		assert.Equal(t, 7, invitation.WaitDays)
+
+		// Доверенное лицо открывает ключ и расшифровывает им данные доверителя
+		vaultKey, err := encryption.OpenKey(trusteePrivateKey, invitation.EncryptedKey)
+		require.NoError(t, err)
+		encrypted, err := grantorCrypto.Encrypt("secret")
+		require.NoError(t, err)
+		decrypted, err := encryption.NewCryptoServiceFromKey(vaultKey).Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", decrypted)
+	})
+
+	t.Run("OpenEmergencyVault - decrypts grantor entries", func(t *testing.T) {
+		gophkeeperService, mockAPI := newService(trusteePassword)
+
+		encryptedTrusteeKey, err := encryption.NewCryptoService(trusteePassword).Encrypt(base64.StdEncoding.EncodeToString(trusteePrivateKey))
+		require.NoError(t, err)
+		mockAPI.On("GetUserKeys", ctx, "").Return(&entities.UserKeys{Login: "bob", PublicKey: base64.StdEncoding.EncodeToString(trusteePublicKey), EncryptedPrivateKey: encryptedTrusteeKey}, nil)
+
+		sealedVaultKey, err := grantorCrypto.SealFor(trusteePublicKey)
+		require.NoError(t, err)
+		encryptedGrantorKey, err := grantorCrypto.Encrypt(base64.StdEncoding.EncodeToString(grantorPrivateKey))
+		require.NoError(t, err)
+
+		// Одна запись зашифрована ключом хранилища, другая - ключом записи, открытым доверителю
+		personal := entities.TextData{SecureEntity: entities.SecureEntity{ID: "1", OwnerID: "alice", Metadata: "will"}, Data: "personal"}
+		require.NoError(t, personal.EncryptFields(grantorCrypto))
+
+		entryKey, err := encryption.GenerateEntryKey()
+		require.NoError(t, err)
+		sealedEntryKey, err := encryption.SealKey(grantorPublicKey, entryKey)
+		require.NoError(t, err)
+		shared := entities.TextData{SecureEntity: entities.SecureEntity{ID: "2", OwnerID: "alice", Metadata: "shared", EntryKey: sealedEntryKey}, Data: "shared"}
+		require.NoError(t, shared.EncryptFields(encryption.NewCryptoServiceFromKey(entryKey)))
+
+		mockAPI.On("OpenEmergencyVault", ctx, "1").Return(&entities.EmergencyVault{
+			Access:              entities.EmergencyAccess{ID: "1", GrantorID: "alice", GranteeID: "bob", Status: entities.EmergencyStatusGranted, EncryptedKey: sealedVaultKey},
+			EncryptedPrivateKey: encryptedGrantorKey,
+			Texts:               []entities.TextData{personal, shared},
+		}, nil)
+
+		vault, err := gophkeeperService.OpenEmergencyVault(ctx, "1")
+		require.NoError(t, err)
+		require.Len(t, vault.Texts, 2)
+		assert.Equal(t, "personal", vault.Texts[0].Data)
+		assert.Equal(t, "will", vault.Texts[0].Metadata)
+		assert.Equal(t, "shared", vault.Texts[1].Data)
+	})
+
+	t.Run("OpenEmergencyVault - fails without granted key", func(t *testing.T) {
+		gophkeeperService, mockAPI := newService(trusteePassword)
+
+		encryptedTrusteeKey, err := encryption.NewCryptoService(trusteePassword).Encrypt(base64.StdEncoding.EncodeToString(trusteePrivateKey))
+		require.NoError(t, err)
+		mockAPI.On("GetUserKeys", ctx, "").Return(&entities.UserKeys{Login: "bob", PublicKey: base64.StdEncoding.EncodeToString(trusteePublicKey), EncryptedPrivateKey: encryptedTrusteeKey}, nil)
+		mockAPI.On("OpenEmergencyVault", ctx, "1").Return(nil, errors.New("open emergency vault failed with status 409"))
+
+		_, err = gophkeeperService.OpenEmergencyVault(ctx, "1")
+		assert.Error(t, err)
+	})
+}
+
+func TestGophkeeperService_Account(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func() (*services.GophkeeperService, *MockGophKeeperAPIClient, *services.StorageService) {
+		mockAPI := new(MockGophKeeperAPIClient)
+		dbManager := inmemory.NewDatabaseManager()
+		storageService := services.NewStorageService(
+			dbManager.BinariesRepo,
+			dbManager.CardsRepo,
+			dbManager.CredentialsRepo,
+			dbManager.TextsRepo,
+		)
+		syncService := services.NewSyncService(mockAPI, storageService)
+		gophkeeperService := services.NewGophkeeperService(mockAPI, storageService, syncService)
+		require.NoError(t, gophkeeperService.SetEncryption("password"))
+		return gophkeeperService, mockAPI, storageService
+	}
+
+	t.Run("ExportAccount - writes archive to file", func(t *testing.T) {
+		gophkeeperService, mockAPI, _ := newService()
+		path := filepath.Join(t.TempDir(), "export.zip")
+
+		mockAPI.On("ExportAccount", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(io.Writer).Write([]byte("archive"))
+		}).Return(int64(7), nil)
+
+		written, err := gophkeeperService.ExportAccount(ctx, path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), written)
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "archive", string(content))
+
+		_, err = gophkeeperService.ExportAccount(ctx, path)
+		assert.Error(t, err, "existing file is not overwritten")
+	})
+
+	t.Run("ExportAccount - removes file on failure", func(t *testing.T) {
+		gophkeeperService, mockAPI, _ := newService()
+		path := filepath.Join(t.TempDir(), "export.zip")
+
+		mockAPI.On("ExportAccount", ctx, mock.Anything).Return(int64(0), errors.New("export account failed with status: 500"))
+
+		_, err := gophkeeperService.ExportAccount(ctx, path)
+		require.Error(t, err)
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("DeleteAccount - clears local storage", func(t *testing.T) {
+		gophkeeperService, mockAPI, storageService := newService()
+
+		_, err := storageService.CreateText(ctx, &entities.TextData{SecureEntity: entities.SecureEntity{ID: "1"}, Data: "cached"})
+		require.NoError(t, err)
+		_, err = storageService.CreateCard(ctx, &entities.CardInformation{SecureEntity: entities.SecureEntity{ID: "2"}, Number: "4111"})
+		require.NoError(t, err)
+
+		mockAPI.On("DeleteAccount", ctx, "password").Return(nil)
+
+		require.NoError(t, gophkeeperService.DeleteAccount(ctx, "password"))
+		assert.False(t, gophkeeperService.IsEncryptionSet())
+
+		texts, err := storageService.GetAllTexts(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, texts)
+		cards, err := storageService.GetAllCards(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, cards)
+	})
+
+	t.Run("DeleteAccount - wrong password keeps local data", func(t *testing.T) {
+		gophkeeperService, mockAPI, storageService := newService()
+
+		_, err := storageService.CreateText(ctx, &entities.TextData{SecureEntity: entities.SecureEntity{ID: "1"}, Data: "cached"})
+		require.NoError(t, err)
+
+		mockAPI.On("DeleteAccount", ctx, "wrong").Return(errors.New("delete account failed with status: 401"))
+
+		require.Error(t, gophkeeperService.DeleteAccount(ctx, "wrong"))
+		assert.True(t, gophkeeperService.IsEncryptionSet())
+
+		texts, err := storageService.GetAllTexts(ctx)
+		require.NoError(t, err)
+		assert.Len(t, texts, 1)
+	})
+}
+
+func TestGophkeeperService_Trash(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func() (*services.GophkeeperService, *MockGophKeeperAPIClient, *services.StorageService) {
+		mockAPI := new(MockGophKeeperAPIClient)
+		dbManager := inmemory.NewDatabaseManager()
+		storageService := services.NewStorageService(
+			dbManager.BinariesRepo,
+			dbManager.CardsRepo,
+			dbManager.CredentialsRepo,
+			dbManager.TextsRepo,
+		)
+		syncService := services.NewSyncService(mockAPI, storageService)
+		gophkeeperService := services.NewGophkeeperService(mockAPI, storageService, syncService)
+		require.NoError(t, gophkeeperService.SetEncryption("password"))
+		return gophkeeperService, mockAPI, storageService
+	}
+
+	t.Run("GetTrash - decrypts metadata", func(t *testing.T) {
+		gophkeeperService, mockAPI, _ := newService()
+
+		metadata, err := encryption.NewCryptoService("password").Encrypt("old note")
+		require.NoError(t, err)
+		mockAPI.On("GetTrash", ctx).Return([]entities.TrashItem{
+			{SecureEntity: entities.SecureEntity{ID: "1", Metadata: metadata}, EntityType: "text"},
+			{SecureEntity: entities.SecureEntity{ID: "2"}, EntityType: "card"},
+		}, nil)
+
+		items, err := gophkeeperService.GetTrash(ctx)
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		assert.Equal(t, "old note", items[0].Metadata)
+		assert.Empty(t, items[1].Metadata)
+	})
+
+	t.Run("RestoreTrashItem - stores entry locally", func(t *testing.T) {
+		gophkeeperService, mockAPI, storageService := newService()
+
+		text := &entities.TextData{SecureEntity: entities.SecureEntity{ID: "1", Metadata: "note"}, Data: "restored"}
+		mockAPI.On("RestoreTrashItem", ctx, "text", "1").Return(nil)
+		mockAPI.On("GetText", ctx, "1").Return(text, nil)
+
+		require.NoError(t, gophkeeperService.RestoreTrashItem(ctx, "text", "1"))
+
+		local, err := storageService.GetText(ctx, "1")
+		require.NoError(t, err)
+		require.NotNil(t, local)
+		assert.Equal(t, "restored", local.Data)
+	})
+
+	t.Run("RestoreTrashItem - server error", func(t *testing.T) {
+		gophkeeperService, mockAPI, storageService := newService()
+
+		mockAPI.On("RestoreTrashItem", ctx, "text", "1").Return(errors.New("restore trash item failed with status: 404"))
+
+		require.Error(t, gophkeeperService.RestoreTrashItem(ctx, "text", "1"))
+		mockAPI.AssertNotCalled(t, "GetText", ctx, "1")
+
+		local, err := storageService.GetText(ctx, "1")
+		require.NoError(t, err)
+		assert.Nil(t, local)
+	})
+
+	t.Run("DeleteTrashItem", func(t *testing.T) {
+		gophkeeperService, mockAPI, _ := newService()
+
+		mockAPI.On("DeleteTrashItem", ctx, "card", "2").Return(nil)
+
+		require.NoError(t, gophkeeperService.DeleteTrashItem(ctx, "card", "2"))
+		mockAPI.AssertExpectations(t)
+	})
+}
+
+// TestGophkeeperService_History тестирует получение и восстановление прежних версий записей
+func TestGophkeeperService_History(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func() (*services.GophkeeperService, *MockGophKeeperAPIClient, *services.StorageService) {
+		mockAPI := new(MockGophKeeperAPIClient)
+		dbManager := inmemory.NewDatabaseManager()
+		storageService := services.NewStorageService(
+			dbManager.BinariesRepo,
+			dbManager.CardsRepo,
+			dbManager.CredentialsRepo,
+			dbManager.TextsRepo,
+		)
+		syncService := services.NewSyncService(mockAPI, storageService)
+		gophkeeperService := services.NewGophkeeperService(mockAPI, storageService, syncService)
+		require.NoError(t, gophkeeperService.SetEncryption("password"))
+		return gophkeeperService, mockAPI, storageService
+	}
+
+	t.Run("GetHistory - decrypts revisions", func(t *testing.T) {
+		gophkeeperService, mockAPI, _ := newService()
+
+		cryptoService := encryption.NewCryptoService("password")
+		encrypt := func(value string) string {
+			encrypted, err := cryptoService.Encrypt(value)
+			require.NoError(t, err)
+			return encrypted
+		}
+		content, err := json.Marshal(map[string]string{"login": encrypt("user"), "password": encrypt("old password")})
+		require.NoError(t, err)
+
+		mockAPI.On("GetHistory", ctx, "credentials", "1").Return([]entities.Revision{
+			{EntityType: "credentials", EntityID: "1", Revision: 1, Content: content, Metadata: encrypt("site"), Restorable: true},
+		}, nil)
+
+		revisions, err := gophkeeperService.GetHistory(ctx, "credentials", "1")
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, []entities.Field{
+			{Name: "Login", Value: "user"},
+			{Name: "Password", Value: "old password"},
+			{Name: "Metadata", Value: "site"},
+		}, revisions[0].Fields)
+	})
+
+	t.Run("GetHistory - unknown entity type", func(t *testing.T) {
+		gophkeeperService, mockAPI, _ := newService()
+
+		mockAPI.On("GetHistory", ctx, "text", "1").Return([]entities.Revision{
+			{EntityType: "note", EntityID: "1", Revision: 1, Content: json.RawMessage(`{}`)},
+		}, nil)
+
+		_, err := gophkeeperService.GetHistory(ctx, "text", "1")
+		assert.Error(t, err)
+	})
+
+	t.Run("RestoreRevision - updates local copy", func(t *testing.T) {
+		gophkeeperService, mockAPI, storageService := newService()
+
+		text := &entities.TextData{SecureEntity: entities.SecureEntity{ID: "1", Metadata: "note"}, Data: "previous"}
+		mockAPI.On("RestoreRevision", ctx, "text", "1", 2).Return(nil)
+		mockAPI.On("GetText", ctx, "1").Return(text, nil)
+
+		require.NoError(t, gophkeeperService.RestoreRevision(ctx, "text", "1", 2))
+
+		local, err := storageService.GetText(ctx, "1")
+		require.NoError(t, err)
+		require.NotNil(t, local)
+		assert.Equal(t, "previous", local.Data)
+	})
+
+	t.Run("RestoreRevision - server error", func(t *testing.T) {
+		gophkeeperService, mockAPI, _ := newService()
+
+		mockAPI.On("RestoreRevision", ctx, "text", "1", 1).Return(errors.New("restore revision failed with status: 409"))
+
+		require.Error(t, gophkeeperService.RestoreRevision(ctx, "text", "1", 1))
+		mockAPI.AssertNotCalled(t, "GetText", ctx, "1")
+	})
+}
+
+func TestGophkeeperService_EntryIDs(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func() (*services.GophkeeperService, *MockGophKeeperAPIClient) {
+		mockAPI := new(MockGophKeeperAPIClient)
+		dbManager := inmemory.NewDatabaseManager()
+		storageService := services.NewStorageService(
+			dbManager.BinariesRepo,
+			dbManager.CardsRepo,
+			dbManager.CredentialsRepo,
+			dbManager.TextsRepo,
+		)
+		syncService := services.NewSyncService(mockAPI, storageService)
+		gophkeeperService := services.NewGophkeeperService(mockAPI, storageService, syncService)
+		require.NoError(t, gophkeeperService.SetEncryption("password"))
+		return gophkeeperService, mockAPI
+	}
+
+	t.Run("CreateText - client generates UUIDv7", func(t *testing.T) {
+		gophkeeperService, mockAPI := newService()
+
+		var sentID string
+		isUUIDv7 := mock.MatchedBy(func(dto *dtos.NewTextData) bool {
+			id, err := uuid.Parse(dto.ID)
+			sentID = dto.ID
+			return err == nil && id.Version() == 7
+		})
+		mockAPI.On("CreateText", ctx, isUUIDv7).Return(&entities.TextData{SecureEntity: entities.SecureEntity{ID: "text-1"}}, nil)
+
+		dto := &dtos.NewTextData{Data: "note"}
+		_, err := gophkeeperService.CreateText(ctx, dto)
+		require.NoError(t, err)
+		assert.Equal(t, sentID, dto.ID)
+
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("CreateCard - keeps chosen id", func(t *testing.T) {
+		gophkeeperService, mockAPI := newService()
+
+		id := uuid.Must(uuid.NewV7()).String()
+		hasID := mock.MatchedBy(func(dto *dtos.NewCardInformation) bool { return dto.ID == id })
+		mockAPI.On("CreateCard", ctx, hasID).Return(&entities.CardInformation{SecureEntity: entities.SecureEntity{ID: id}}, nil)
+
+		card, err := gophkeeperService.CreateCard(ctx, &dtos.NewCardInformation{Number: "4111", NewSecureEntity: dtos.NewSecureEntity{ID: id}})
+		require.NoError(t, err)
+		assert.Equal(t, id, card.ID)
+
+		mockAPI.AssertExpectations(t)
+	})
+}
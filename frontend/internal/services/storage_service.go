@@ -130,3 +130,33 @@ func (s *StorageService) UpdateText(ctx context.Context, entity *entities.TextDa
 func (s *StorageService) DeleteText(ctx context.Context, id string) error {
 	return s.textsRepo.Delete(ctx, id)
 }
+
+// Clear - удалить из локального хранилища все записи (например, после удаления учётной записи на сервере)
+func (s *StorageService) Clear(ctx context.Context) error {
+	if err := clearRepo(ctx, s.binariesRepo, func(entity *entities.BinaryData) string { return entity.ID }); err != nil {
+		return err
+	}
+	if err := clearRepo(ctx, s.cardsRepo, func(entity *entities.CardInformation) string { return entity.ID }); err != nil {
+		return err
+	}
+	if err := clearRepo(ctx, s.credentialsRepo, func(entity *entities.Credentials) string { return entity.ID }); err != nil {
+		return err
+	}
+	return clearRepo(ctx, s.textsRepo, func(entity *entities.TextData) string { return entity.ID })
+}
+
+// clearRepo - удалить все сущности репозитория
+func clearRepo[T any](ctx context.Context, repo repositories.IRepository[T], id func(entity *T) string) error {
+	all, err := repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range all {
+		if err := repo.Delete(ctx, id(&all[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
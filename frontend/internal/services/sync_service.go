@@ -3,16 +3,27 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/JustScorpio/GophKeeper/frontend/internal/clients"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
 )
 
+// Задержки перед переподключением к потоку изменений
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
 // SyncService - сервис синхронизации данных
 type SyncService struct {
 	apiClient    clients.IAPIClient
 	localStorage *StorageService
+
+	mu sync.Mutex // полная синхронизация и применение отдельных изменений не должны пересекаться
 }
 
 // NewSyncService - создать сервис синхронизации данных
@@ -25,6 +36,9 @@ func NewSyncService(apiClient clients.IAPIClient, localStorage *StorageService)
 
 // Sync - синхронизация данных с сервером
 func (s *SyncService) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if err := s.syncBinaries(ctx); err != nil {
 		return fmt.Errorf("failed to sync binaries: %w", err)
 	}
@@ -228,3 +242,138 @@ func (s *SyncService) syncTexts(ctx context.Context) error {
 
 	return nil
 }
+
+// ApplyChange - привести локальную копию изменённой на сервере сущности к её состоянию на сервере
+func (s *SyncService) ApplyChange(ctx context.Context, event entities.ChangeEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Удалённую сущность запрашивать у сервера незачем
+	deleted := event.Action == "delete"
+
+	switch event.EntityType {
+	case "binary":
+		return refreshEntity(ctx, event.EntityID, deleted, s.apiClient.GetBinary,
+			s.localStorage.GetBinary, s.localStorage.CreateBinary, s.localStorage.UpdateBinary, s.localStorage.DeleteBinary)
+	case "card":
+		return refreshEntity(ctx, event.EntityID, deleted, s.apiClient.GetCard,
+			s.localStorage.GetCard, s.localStorage.CreateCard, s.localStorage.UpdateCard, s.localStorage.DeleteCard)
+	case "credentials":
+		return refreshEntity(ctx, event.EntityID, deleted, s.apiClient.GetCredentials,
+			s.localStorage.GetCredentials, s.localStorage.CreateCredentials, s.localStorage.UpdateCredentials, s.localStorage.DeleteCredentials)
+	case "text":
+		return refreshEntity(ctx, event.EntityID, deleted, s.apiClient.GetText,
+			s.localStorage.GetText, s.localStorage.CreateText, s.localStorage.UpdateText, s.localStorage.DeleteText)
+	default:
+		return fmt.Errorf("unknown entity type %q", event.EntityType)
+	}
+}
+
+// refreshEntity - обновить локальную копию одной сущности по данным сервера (nil - сущности на сервере нет)
+func refreshEntity[T any, PT interface {
+	*T
+	entities.Hashable
+}](
+	ctx context.Context,
+	id string,
+	deleted bool,
+	fetch func(context.Context, string) (*T, error),
+	getLocal func(context.Context, string) (*T, error),
+	create func(context.Context, *T) (*T, error),
+	update func(context.Context, *T) (*T, error),
+	remove func(context.Context, string) error,
+) error {
+	var server *T
+	if !deleted {
+		var err error
+		if server, err = fetch(ctx, id); err != nil {
+			return fmt.Errorf("get server entity %s: %w", id, err)
+		}
+	}
+
+	local, err := getLocal(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get local entity %s: %w", id, err)
+	}
+
+	switch {
+	case server == nil && local == nil:
+		return nil
+	case server == nil:
+		if err := remove(ctx, id); err != nil {
+			return fmt.Errorf("delete entity %s: %w", id, err)
+		}
+	case local == nil:
+		if _, err := create(ctx, server); err != nil {
+			return fmt.Errorf("create entity %s: %w", id, err)
+		}
+	case !entities.Equals(PT(local), PT(server)):
+		if _, err := update(ctx, server); err != nil {
+			return fmt.Errorf("update entity %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// Watch - применять изменения, сделанные на других устройствах, до отмены контекста.
+// Предполагается, что перед запуском данные уже синхронизированы. После обрыва соединения
+// сервис переподключается и синхронизирует данные полностью, чтобы не потерять изменения, сделанные без подписки.
+// Ошибки передаются в onError (может быть nil) и работу не прерывают
+func (s *SyncService) Watch(ctx context.Context, onError func(error)) {
+	report := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+
+	delay := minReconnectDelay
+	reconnect := false
+
+	for {
+		stream, err := s.apiClient.SubscribeChanges(ctx)
+		if err == nil {
+			delay = minReconnectDelay
+
+			if reconnect {
+				if syncErr := s.Sync(ctx); syncErr != nil {
+					report(fmt.Errorf("resync after reconnect: %w", syncErr))
+				}
+			}
+
+			err = s.consume(ctx, stream, report)
+			stream.Close()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		report(fmt.Errorf("change stream: %w", err))
+		reconnect = true
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// consume - применять изменения из потока до его завершения
+func (s *SyncService) consume(ctx context.Context, stream *clients.ChangeStream, report func(error)) error {
+	for {
+		event, err := stream.Next()
+		if err != nil {
+			return err
+		}
+
+		if err := s.ApplyChange(ctx, event); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			report(fmt.Errorf("apply %s %s %s: %w", event.Action, event.EntityType, event.EntityID, err))
+		}
+	}
+}
@@ -4,8 +4,12 @@ package services_test
 import (
 	"context"
 	"errors"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/JustScorpio/GophKeeper/frontend/internal/clients"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/dtos"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/models/entities"
 	"github.com/JustScorpio/GophKeeper/frontend/internal/repositories/inmemory"
@@ -21,8 +25,8 @@ type MockSyncAPIClient struct {
 }
 
 // Register - регистрация пользователя
-func (m *MockSyncAPIClient) Register(ctx context.Context, login, password string) error {
-	args := m.Called(ctx, login, password)
+func (m *MockSyncAPIClient) Register(ctx context.Context, login, password, inviteCode string) error {
+	args := m.Called(ctx, login, password, inviteCode)
 	return args.Error(0)
 }
 
@@ -164,6 +168,283 @@ func (m *MockSyncAPIClient) DeleteText(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
+// GetAuditLog - получить журнал операций пользователя
+func (m *MockSyncAPIClient) GetAuditLog(ctx context.Context, from, to time.Time, entityType string) ([]entities.AuditEvent, error) {
+	args := m.Called(ctx, from, to, entityType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.AuditEvent), args.Error(1)
+}
+
+// GetBinary - получить бинарные данные по идентификатору
+func (m *MockSyncAPIClient) GetBinary(ctx context.Context, id string) (*entities.BinaryData, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BinaryData), args.Error(1)
+}
+
+// GetCard - получить данные карты по идентификатору
+func (m *MockSyncAPIClient) GetCard(ctx context.Context, id string) (*entities.CardInformation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.CardInformation), args.Error(1)
+}
+
+// GetCredentials - получить учётные данные по идентификатору
+func (m *MockSyncAPIClient) GetCredentials(ctx context.Context, id string) (*entities.Credentials, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Credentials), args.Error(1)
+}
+
+// GetText - получить текстовые данные по идентификатору
+func (m *MockSyncAPIClient) GetText(ctx context.Context, id string) (*entities.TextData, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.TextData), args.Error(1)
+}
+
+// GetTrash - получить записи в корзине
+func (m *MockSyncAPIClient) GetTrash(ctx context.Context) ([]entities.TrashItem, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.TrashItem), args.Error(1)
+}
+
+// RestoreTrashItem - вернуть запись из корзины
+func (m *MockSyncAPIClient) RestoreTrashItem(ctx context.Context, entityType, id string) error {
+	args := m.Called(ctx, entityType, id)
+	return args.Error(0)
+}
+
+// DeleteTrashItem - окончательно удалить запись из корзины
+func (m *MockSyncAPIClient) DeleteTrashItem(ctx context.Context, entityType, id string) error {
+	args := m.Called(ctx, entityType, id)
+	return args.Error(0)
+}
+
+// GetHistory - получить прежние версии записи
+func (m *MockSyncAPIClient) GetHistory(ctx context.Context, entityType, id string) ([]entities.Revision, error) {
+	args := m.Called(ctx, entityType, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.Revision), args.Error(1)
+}
+
+// RestoreRevision - вернуть запись к прежней версии
+func (m *MockSyncAPIClient) RestoreRevision(ctx context.Context, entityType, id string, revision int) error {
+	args := m.Called(ctx, entityType, id, revision)
+	return args.Error(0)
+}
+
+// GetUsage - получить использование хранилища
+func (m *MockSyncAPIClient) GetUsage(ctx context.Context) (*entities.QuotaUsage, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.QuotaUsage), args.Error(1)
+}
+
+// ExportAccount - скачать архив учётной записи
+func (m *MockSyncAPIClient) ExportAccount(ctx context.Context, dst io.Writer) (int64, error) {
+	args := m.Called(ctx, dst)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// DeleteAccount - удалить учётную запись
+func (m *MockSyncAPIClient) DeleteAccount(ctx context.Context, password string) error {
+	args := m.Called(ctx, password)
+	return args.Error(0)
+}
+
+// SubscribeChanges - подписаться на изменения
+func (m *MockSyncAPIClient) SubscribeChanges(ctx context.Context) (*clients.ChangeStream, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*clients.ChangeStream), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) SetUserKeys(ctx context.Context, keys *entities.UserKeys) error {
+	args := m.Called(ctx, keys)
+	return args.Error(0)
+}
+
+func (m *MockSyncAPIClient) GetUserKeys(ctx context.Context, login string) (*entities.UserKeys, error) {
+	args := m.Called(ctx, login)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.UserKeys), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) ShareEntry(ctx context.Context, dto *dtos.NewShareGrant) (*entities.ShareGrant, error) {
+	args := m.Called(ctx, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.ShareGrant), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) GetShares(ctx context.Context) ([]entities.ShareGrant, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.ShareGrant), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) AcceptShare(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSyncAPIClient) DeleteShare(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSyncAPIClient) CreateOrganization(ctx context.Context, dto *dtos.NewOrganization) (*entities.Organization, error) {
+	args := m.Called(ctx, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Organization), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) GetOrganizations(ctx context.Context) ([]entities.Organization, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.Organization), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) GetOrgMembers(ctx context.Context, orgID string) ([]entities.OrgMember, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.OrgMember), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) InviteMember(ctx context.Context, orgID string, dto *dtos.NewOrgMember) (*entities.OrgMember, error) {
+	args := m.Called(ctx, orgID, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.OrgMember), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) AcceptInvite(ctx context.Context, orgID string) error {
+	args := m.Called(ctx, orgID)
+	return args.Error(0)
+}
+
+func (m *MockSyncAPIClient) RemoveMember(ctx context.Context, orgID, login string) error {
+	args := m.Called(ctx, orgID, login)
+	return args.Error(0)
+}
+
+func (m *MockSyncAPIClient) CreateCollection(ctx context.Context, orgID string, dto *dtos.NewCollection) (*entities.Collection, error) {
+	args := m.Called(ctx, orgID, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Collection), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) GetCollections(ctx context.Context, orgID string) ([]entities.Collection, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.Collection), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) RotateCollectionKey(ctx context.Context, collectionID string, dto *dtos.CollectionRotation) (*entities.Collection, error) {
+	args := m.Called(ctx, collectionID, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Collection), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) InviteEmergencyContact(ctx context.Context, dto *dtos.NewEmergencyAccess) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) GetEmergencyAccess(ctx context.Context) ([]entities.EmergencyAccess, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) AcceptEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) RequestEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) ApproveEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) RejectEmergencyAccess(ctx context.Context, id string) (*entities.EmergencyAccess, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyAccess), args.Error(1)
+}
+
+func (m *MockSyncAPIClient) DeleteEmergencyAccess(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSyncAPIClient) OpenEmergencyVault(ctx context.Context, id string) (*entities.EmergencyVault, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.EmergencyVault), args.Error(1)
+}
+
 // containsBinaryWithID - проверить наличие бинарных данных в слайсе по ID
 func containsBinaryWithID(binaries []entities.BinaryData, id string) bool {
 	for _, b := range binaries {
@@ -700,3 +981,163 @@ func TestSyncService_EdgeCases(t *testing.T) {
 		mockAPI.AssertExpectations(t)
 	})
 }
+
+// TestSyncService_ApplyChange - применение отдельных изменений с сервера
+func TestSyncService_ApplyChange(t *testing.T) {
+	ctx := context.Background()
+
+	newServices := func() (*MockSyncAPIClient, *services.StorageService, *services.SyncService) {
+		mockAPI := new(MockSyncAPIClient)
+		dbManager := inmemory.NewDatabaseManager()
+		storageService := services.NewStorageService(
+			dbManager.BinariesRepo,
+			dbManager.CardsRepo,
+			dbManager.CredentialsRepo,
+			dbManager.TextsRepo,
+		)
+		return mockAPI, storageService, services.NewSyncService(mockAPI, storageService)
+	}
+
+	serverText := &entities.TextData{
+		SecureEntity: entities.SecureEntity{ID: "text-1", Metadata: "from server"},
+		Data:         "server data",
+	}
+
+	t.Run("Создание", func(t *testing.T) {
+		mockAPI, storageService, syncService := newServices()
+		mockAPI.On("GetText", ctx, "text-1").Return(serverText, nil)
+
+		err := syncService.ApplyChange(ctx, entities.ChangeEvent{Action: "create", EntityType: "text", EntityID: "text-1"})
+		require.NoError(t, err)
+
+		local, err := storageService.GetText(ctx, "text-1")
+		require.NoError(t, err)
+		require.NotNil(t, local)
+		assert.Equal(t, "server data", local.Data)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("Изменение", func(t *testing.T) {
+		mockAPI, storageService, syncService := newServices()
+		_, err := storageService.CreateText(ctx, &entities.TextData{
+			SecureEntity: entities.SecureEntity{ID: "text-1", Metadata: "old"},
+			Data:         "old data",
+		})
+		require.NoError(t, err)
+		mockAPI.On("GetText", ctx, "text-1").Return(serverText, nil)
+
+		err = syncService.ApplyChange(ctx, entities.ChangeEvent{Action: "update", EntityType: "text", EntityID: "text-1"})
+		require.NoError(t, err)
+
+		local, err := storageService.GetText(ctx, "text-1")
+		require.NoError(t, err)
+		assert.Equal(t, "from server", local.Metadata)
+		assert.Equal(t, "server data", local.Data)
+	})
+
+	t.Run("Удаление", func(t *testing.T) {
+		mockAPI, storageService, syncService := newServices()
+		_, err := storageService.CreateCard(ctx, &entities.CardInformation{
+			SecureEntity: entities.SecureEntity{ID: "card-1"},
+			Number:       "4111111111111111",
+		})
+		require.NoError(t, err)
+
+		err = syncService.ApplyChange(ctx, entities.ChangeEvent{Action: "delete", EntityType: "card", EntityID: "card-1"})
+		require.NoError(t, err)
+
+		local, err := storageService.GetCard(ctx, "card-1")
+		require.NoError(t, err)
+		assert.Nil(t, local)
+
+		// Удалённую сущность у сервера не запрашиваем
+		mockAPI.AssertNotCalled(t, "GetCard", mock.Anything, mock.Anything)
+
+		// Повторное удаление ничего не делает
+		assert.NoError(t, syncService.ApplyChange(ctx, entities.ChangeEvent{Action: "delete", EntityType: "card", EntityID: "card-1"}))
+	})
+
+	t.Run("Сущность уже удалена на сервере", func(t *testing.T) {
+		mockAPI, storageService, syncService := newServices()
+		_, err := storageService.CreateCredentials(ctx, &entities.Credentials{
+			SecureEntity: entities.SecureEntity{ID: "cred-1"},
+			Login:        "user",
+		})
+		require.NoError(t, err)
+		mockAPI.On("GetCredentials", ctx, "cred-1").Return(nil, nil)
+
+		err = syncService.ApplyChange(ctx, entities.ChangeEvent{Action: "update", EntityType: "credentials", EntityID: "cred-1"})
+		require.NoError(t, err)
+
+		local, err := storageService.GetCredentials(ctx, "cred-1")
+		require.NoError(t, err)
+		assert.Nil(t, local)
+	})
+
+	t.Run("Ошибка сервера", func(t *testing.T) {
+		mockAPI, _, syncService := newServices()
+		mockAPI.On("GetBinary", ctx, "bin-1").Return(nil, errors.New("server unavailable"))
+
+		err := syncService.ApplyChange(ctx, entities.ChangeEvent{Action: "create", EntityType: "binary", EntityID: "bin-1"})
+		assert.ErrorContains(t, err, "server unavailable")
+	})
+
+	t.Run("Неизвестный тип сущности", func(t *testing.T) {
+		_, _, syncService := newServices()
+		err := syncService.ApplyChange(ctx, entities.ChangeEvent{Action: "create", EntityType: "photo", EntityID: "1"})
+		assert.Error(t, err)
+	})
+}
+
+// TestSyncService_Watch - применение изменений из потока событий
+func TestSyncService_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockAPI := new(MockSyncAPIClient)
+	dbManager := inmemory.NewDatabaseManager()
+	storageService := services.NewStorageService(
+		dbManager.BinariesRepo,
+		dbManager.CardsRepo,
+		dbManager.CredentialsRepo,
+		dbManager.TextsRepo,
+	)
+	syncService := services.NewSyncService(mockAPI, storageService)
+
+	body := ": connected\n\n" +
+		"event: change\ndata: {\"action\":\"create\",\"entity_type\":\"binary\",\"entity_id\":\"bin-1\"}\n\n"
+	stream := clients.NewChangeStream(io.NopCloser(strings.NewReader(body)))
+
+	mockAPI.On("SubscribeChanges", ctx).Return(stream, nil).Once()
+	mockAPI.On("GetBinary", ctx, "bin-1").Return(&entities.BinaryData{
+		SecureEntity: entities.SecureEntity{ID: "bin-1", Metadata: "from other device"},
+		Data:         []byte("data"),
+	}, nil)
+
+	// После применения события поток заканчивается - останавливаем наблюдение
+	var reported []error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		syncService.Watch(ctx, func(err error) {
+			reported = append(reported, err)
+			cancel()
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not stop after context cancellation")
+	}
+
+	require.Len(t, reported, 1)
+	assert.ErrorIs(t, reported[0], io.EOF)
+
+	local, err := storageService.GetBinary(context.Background(), "bin-1")
+	require.NoError(t, err)
+	require.NotNil(t, local)
+	assert.Equal(t, "from other device", local.Metadata)
+
+	mockAPI.AssertExpectations(t)
+}